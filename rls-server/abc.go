@@ -0,0 +1,159 @@
+// abc.go - Classificação ABC dos produtos por valor ou volume de saídas
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Critérios de classificação aceitos
+const (
+	CriterioABCValor  = "valor"
+	CriterioABCVolume = "volume"
+)
+
+type ItemABC struct {
+	ProdutoID           int     `json:"produto_id"`
+	Codigo              string  `json:"codigo"`
+	Nome                string  `json:"nome"`
+	Quantidade          int     `json:"quantidade"`
+	Valor               float64 `json:"valor"`
+	Percentual          float64 `json:"percentual"`
+	PercentualAcumulado float64 `json:"percentual_acumulado"`
+	Classe              string  `json:"classe"`
+}
+
+type RelatorioABC struct {
+	De       time.Time      `json:"de"`
+	Ate      time.Time      `json:"ate"`
+	Criterio string         `json:"criterio"`
+	LimiteA  float64        `json:"limite_a"`
+	LimiteB  float64        `json:"limite_b"`
+	Total    float64        `json:"total"`
+	Resumo   map[string]int `json:"resumo"`
+	Itens    []ItemABC      `json:"itens"`
+}
+
+// Função auxiliar para classificar itens já ordenados pela métrica (decrescente).
+// Um item é A enquanto o acumulado antes dele não atinge limiteA, e B até limiteB.
+func classificarABC(itens []ItemABC, metrica func(ItemABC) float64, limiteA, limiteB float64) float64 {
+	total := 0.0
+	for _, item := range itens {
+		total += metrica(item)
+	}
+
+	acumulado := 0.0
+	for i := range itens {
+		anterior := acumulado
+		if total > 0 {
+			itens[i].Percentual = metrica(itens[i]) * 100 / total
+		}
+		acumulado += itens[i].Percentual
+		itens[i].PercentualAcumulado = acumulado
+
+		switch {
+		case metrica(itens[i]) > 0 && anterior < limiteA:
+			itens[i].Classe = "A"
+		case metrica(itens[i]) > 0 && anterior < limiteB:
+			itens[i].Classe = "B"
+		default:
+			itens[i].Classe = "C"
+		}
+	}
+	return total
+}
+
+// Handler para relatório ABC
+
+func getRelatorioABC(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 90)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	criterio := c.DefaultQuery("criterio", CriterioABCValor)
+	if criterio != CriterioABCValor && criterio != CriterioABCVolume {
+		log.Printf("[ERROR] Critério ABC inválido: %s", criterio)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Critério inválido, use valor ou volume"})
+		return
+	}
+
+	limiteA, errA := strconv.ParseFloat(c.DefaultQuery("limite_a", "80"), 64)
+	limiteB, errB := strconv.ParseFloat(c.DefaultQuery("limite_b", "95"), 64)
+	if errA != nil || errB != nil || limiteA <= 0 || limiteB < limiteA || limiteB > 100 {
+		log.Printf("[ERROR] Limites ABC inválidos: %s / %s", c.Query("limite_a"), c.Query("limite_b"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Limites inválidos, use 0 < limite_a <= limite_b <= 100"})
+		return
+	}
+
+	log.Printf("[DB] Gerando classificação ABC por %s de %s a %s", criterio, de.Format(formatoData), ate.Format(formatoData))
+
+	// Saídas do período; o valor usa o custo da movimentação ou o custo atual do produto
+	rows, err := dbLeitura.Query(c.Request.Context(), `
+		SELECT p.id, p.codigo, p.nome,
+		       COALESCE(SUM(m.quantidade), 0),
+		       COALESCE(SUM(m.quantidade * COALESCE(m.custo_unitario, p.preco_custo)), 0)
+		FROM produtos p
+		LEFT JOIN movimentacoes m
+		       ON m.produto_id = p.id AND m.tipo = 'saida'
+		      AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY p.id, p.codigo, p.nome
+		ORDER BY CASE WHEN $3 = 'volume' THEN COALESCE(SUM(m.quantidade), 0)
+		              ELSE COALESCE(SUM(m.quantidade * COALESCE(m.custo_unitario, p.preco_custo)), 0) END DESC,
+		         p.nome
+	`, de, ate, criterio)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar movimentações para classificação ABC: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar movimentações"})
+		return
+	}
+	defer rows.Close()
+
+	itens := []ItemABC{}
+	for rows.Next() {
+		var item ItemABC
+		if err := rows.Scan(&item.ProdutoID, &item.Codigo, &item.Nome, &item.Quantidade, &item.Valor); err != nil {
+			log.Printf("[ERROR] Erro ao processar produto: %v", err)
+			continue
+		}
+		itens = append(itens, item)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar movimentações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar movimentações"})
+		return
+	}
+
+	metrica := func(i ItemABC) float64 { return i.Valor }
+	if criterio == CriterioABCVolume {
+		metrica = func(i ItemABC) float64 { return float64(i.Quantidade) }
+	}
+
+	relatorio := RelatorioABC{
+		De:       de,
+		Ate:      ate.AddDate(0, 0, -1),
+		Criterio: criterio,
+		LimiteA:  limiteA,
+		LimiteB:  limiteB,
+		Resumo:   map[string]int{"A": 0, "B": 0, "C": 0},
+		Itens:    itens,
+	}
+	relatorio.Total = classificarABC(itens, metrica, limiteA, limiteB)
+	for _, item := range itens {
+		relatorio.Resumo[item.Classe]++
+	}
+
+	log.Printf("[API] Classificação ABC gerada: A=%d, B=%d, C=%d",
+		relatorio.Resumo["A"], relatorio.Resumo["B"], relatorio.Resumo["C"])
+	c.JSON(http.StatusOK, relatorio)
+}
@@ -0,0 +1,417 @@
+// alertas_email.go - Alertas de estoque baixo por e-mail
+//
+// O servidor SMTP é configurado na tabela configuracoes (smtp_host,
+// smtp_porta, smtp_usuario, smtp_senha, smtp_remetente); com smtp_host vazio
+// os alertas ficam desligados. Cada assinante escolhe se recebe o resumo
+// diário (EMAIL_RESUMO_HORARIO, padrão 07:30) dos produtos abaixo do mínimo
+// e/ou o alerta imediato quando uma saída deixa um produto abaixo do mínimo.
+// O envio aparece no painel de integrações como "email".
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var emailResumoHorario = getEnv("EMAIL_RESUMO_HORARIO", "07:30")
+
+var errSMTPNaoConfigurado = errors.New("servidor SMTP não configurado (smtp_host)")
+
+type AssinanteAlerta struct {
+	ID             int       `json:"id,omitempty"`
+	Email          string    `json:"email"`
+	Nome           string    `json:"nome,omitempty"`
+	ResumoDiario   bool      `json:"resumo_diario"`
+	AlertaImediato bool      `json:"alerta_imediato"`
+	Ativo          bool      `json:"ativo"`
+	DataCriacao    time.Time `json:"data_criacao,omitempty"`
+}
+
+type configSMTP struct {
+	host      string
+	porta     string
+	usuario   string
+	senha     string
+	remetente string
+}
+
+// Função auxiliar para ler a configuração SMTP atual
+func lerConfigSMTP(ctx context.Context) configSMTP {
+	cfg := configSMTP{
+		host:      lerConfiguracao(ctx, "smtp_host", ""),
+		porta:     lerConfiguracao(ctx, "smtp_porta", "587"),
+		usuario:   lerConfiguracao(ctx, "smtp_usuario", ""),
+		senha:     lerConfiguracao(ctx, "smtp_senha", ""),
+		remetente: lerConfiguracao(ctx, "smtp_remetente", ""),
+	}
+	if cfg.remetente == "" {
+		cfg.remetente = cfg.usuario
+	}
+	return cfg
+}
+
+func (cfg configSMTP) auth() smtp.Auth {
+	if cfg.usuario == "" {
+		return nil
+	}
+	return smtp.PlainAuth("", cfg.usuario, cfg.senha, cfg.host)
+}
+
+// Integração do painel para o envio de e-mails
+type integracaoEmail struct {
+	metricas MetricasIntegracao
+}
+
+var alertasEmail = &integracaoEmail{}
+
+func (i *integracaoEmail) Nome() string                  { return "email" }
+func (i *integracaoEmail) Tipo() string                  { return "email" }
+func (i *integracaoEmail) FilaPendente() int             { return 0 }
+func (i *integracaoEmail) Metricas() *MetricasIntegracao { return &i.metricas }
+
+func (i *integracaoEmail) Ativa() bool {
+	return lerConfiguracao(context.Background(), "smtp_host", "") != ""
+}
+
+// Conecta e autentica no servidor SMTP, sem enviar mensagem
+func (i *integracaoEmail) Testar(ctx context.Context) error {
+	cfg := lerConfigSMTP(ctx)
+	if cfg.host == "" {
+		return errSMTPNaoConfigurado
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(cfg.host, cfg.porta))
+	if err != nil {
+		return err
+	}
+	cliente, err := smtp.NewClient(conn, cfg.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer cliente.Close()
+
+	if ok, _ := cliente.Extension("STARTTLS"); ok {
+		if err := cliente.StartTLS(nil); err != nil {
+			return err
+		}
+	}
+	if auth := cfg.auth(); auth != nil {
+		if err := cliente.Auth(auth); err != nil {
+			return err
+		}
+	}
+	return cliente.Quit()
+}
+
+// Envia uma mensagem de texto para cada destinatário
+func enviarEmail(ctx context.Context, destinatarios []string, assunto, corpo string) error {
+	cfg := lerConfigSMTP(ctx)
+	if cfg.host == "" {
+		return errSMTPNaoConfigurado
+	}
+
+	var falhas []string
+	for _, para := range destinatarios {
+		msg := "From: " + cfg.remetente + "\r\n" +
+			"To: " + para + "\r\n" +
+			"Subject: " + mime.QEncoding.Encode("utf-8", assunto) + "\r\n" +
+			"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+			"MIME-Version: 1.0\r\n" +
+			"Content-Type: text/plain; charset=UTF-8\r\n" +
+			"\r\n" + strings.ReplaceAll(corpo, "\n", "\r\n")
+
+		err := smtp.SendMail(net.JoinHostPort(cfg.host, cfg.porta), cfg.auth(), cfg.remetente, []string{para}, []byte(msg))
+		alertasEmail.metricas.Registrar(err)
+		if err != nil {
+			log.Printf("[WARN] Erro ao enviar e-mail para %s: %v", para, err)
+			falhas = append(falhas, para)
+		}
+	}
+
+	if len(falhas) > 0 {
+		return fmt.Errorf("falha no envio para %s", strings.Join(falhas, ", "))
+	}
+	return nil
+}
+
+// Função auxiliar para listar os e-mails ativos com a preferência indicada
+func destinatariosAlerta(ctx context.Context, coluna string) ([]string, error) {
+	rows, err := db.Query(ctx, "SELECT email FROM assinantes_alertas WHERE ativo AND "+coluna+" ORDER BY email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// Envia o resumo dos produtos abaixo do mínimo; devolve quantos produtos entraram
+func enviarResumoEstoqueBaixo(ctx context.Context) (int, error) {
+	destinatarios, err := destinatariosAlerta(ctx, "resumo_diario")
+	if err != nil {
+		return 0, err
+	}
+	if len(destinatarios) == 0 {
+		return 0, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT codigo, nome, quantidade, COALESCE(quantidade_minima, 5), unidade_medida
+		FROM produtos
+		WHERE quantidade < COALESCE(quantidade_minima, 5) AND NOT kit
+		ORDER BY quantidade ASC, nome
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var linhas []string
+	for rows.Next() {
+		var codigo, nome, unidade string
+		var quantidade, minimo int
+		if err := rows.Scan(&codigo, &nome, &quantidade, &minimo, &unidade); err != nil {
+			return 0, err
+		}
+		linhas = append(linhas, fmt.Sprintf("- %s %s: %d %s (mínimo %d)", codigo, nome, quantidade, unidade, minimo))
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Sem produtos abaixo do mínimo não há resumo
+	if len(linhas) == 0 {
+		return 0, nil
+	}
+
+	assunto := fmt.Sprintf("Estoque baixo: %d produtos abaixo do mínimo", len(linhas))
+	corpo := "Produtos abaixo da quantidade mínima em " + time.Now().Format("02/01/2006 15:04") + ":\n\n" +
+		strings.Join(linhas, "\n") + "\n"
+	return len(linhas), enviarEmail(ctx, destinatarios, assunto, corpo)
+}
+
+// Alerta imediato de um produto que ficou abaixo do mínimo
+func enviarAlertaEstoqueBaixo(ctx context.Context, a AlertaEstoqueBaixo) error {
+	destinatarios, err := destinatariosAlerta(ctx, "alerta_imediato")
+	if err != nil || len(destinatarios) == 0 {
+		return err
+	}
+
+	assunto := fmt.Sprintf("Estoque baixo: %s %s", a.Codigo, a.Nome)
+	corpo := fmt.Sprintf("O produto %s %s ficou com %d unidades, abaixo do mínimo de %d.\n",
+		a.Codigo, a.Nome, a.Quantidade, a.QuantidadeMinima)
+	return enviarEmail(ctx, destinatarios, assunto, corpo)
+}
+
+// Agenda o resumo diário e envia os alertas imediatos; roda até o contexto ser cancelado
+func iniciarAlertasEmail(ctx context.Context) {
+	registrarIntegracao(alertasEmail)
+
+	ch := eventos.inscrever()
+	go func() {
+		defer eventos.cancelar(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				a, ok := e.Dados.(AlertaEstoqueBaixo)
+				if e.Tipo != EventoEstoqueBaixo || !ok || !alertasEmail.Ativa() {
+					continue
+				}
+				if err := enviarAlertaEstoqueBaixo(ctx, a); err != nil {
+					log.Printf("[ERROR] Erro ao enviar alerta de estoque baixo: %v", err)
+				}
+			}
+		}
+	}()
+
+	for {
+		proximo := proximoHorario(time.Now(), emailResumoHorario, "07:30")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(proximo)):
+		}
+
+		if !alertasEmail.Ativa() {
+			continue
+		}
+		n, err := enviarResumoEstoqueBaixo(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao enviar resumo de estoque baixo: %v", err)
+			continue
+		}
+		log.Printf("[INTEGRACAO] Resumo de estoque baixo enviado: %d produtos", n)
+	}
+}
+
+// Handlers de Assinantes de Alertas
+
+func getAssinantesAlerta(c *gin.Context) {
+	log.Println("[DB] Buscando assinantes de alertas")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, email, nome, resumo_diario, alerta_imediato, ativo, data_criacao
+		FROM assinantes_alertas
+		ORDER BY email
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar assinantes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar assinantes"})
+		return
+	}
+	defer rows.Close()
+
+	assinantes := []AssinanteAlerta{}
+	for rows.Next() {
+		var a AssinanteAlerta
+		var nome *string
+		if err := rows.Scan(&a.ID, &a.Email, &nome, &a.ResumoDiario, &a.AlertaImediato, &a.Ativo, &a.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar assinante: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if nome != nil {
+			a.Nome = *nome
+		}
+		assinantes = append(assinantes, a)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar assinantes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar assinantes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assinantes)
+}
+
+func salvarAssinanteAlerta(c *gin.Context) {
+	// Sem ID na URL é criação; com ID, atualização
+	var id int
+	if idStr := c.Param("id"); idStr != "" {
+		var err error
+		if id, err = strconv.Atoi(idStr); err != nil {
+			log.Printf("[ERROR] ID inválido: %s", idStr)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+			return
+		}
+	}
+
+	a := AssinanteAlerta{ResumoDiario: true, AlertaImediato: true, Ativo: true}
+	if err := c.ShouldBindJSON(&a); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	a.Email = strings.TrimSpace(a.Email)
+	if !strings.Contains(a.Email, "@") {
+		log.Printf("[ERROR] E-mail inválido: %s", a.Email)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "E-mail inválido"})
+		return
+	}
+
+	var err error
+	if id == 0 {
+		err = db.QueryRow(c.Request.Context(), `
+			INSERT INTO assinantes_alertas(email, nome, resumo_diario, alerta_imediato, ativo)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+			RETURNING id, data_criacao
+		`, a.Email, a.Nome, a.ResumoDiario, a.AlertaImediato, a.Ativo).Scan(&a.ID, &a.DataCriacao)
+	} else {
+		err = db.QueryRow(c.Request.Context(), `
+			UPDATE assinantes_alertas SET
+				email = $1, nome = NULLIF($2, ''), resumo_diario = $3, alerta_imediato = $4, ativo = $5
+			WHERE id = $6
+			RETURNING id, data_criacao
+		`, a.Email, a.Nome, a.ResumoDiario, a.AlertaImediato, a.Ativo, id).Scan(&a.ID, &a.DataCriacao)
+	}
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Assinante não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Assinante não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao salvar assinante: %v", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao salvar assinante (verifique se o e-mail já existe)"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Assinante de alertas salvo: %s (ID: %d)", a.Email, a.ID)
+	if id == 0 {
+		c.JSON(http.StatusCreated, a)
+	} else {
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+func deletarAssinanteAlerta(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM assinantes_alertas WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir assinante: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir assinante"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Assinante não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Assinante não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Assinante excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Assinante excluído com sucesso"})
+}
+
+// Handler para enviar o resumo de estoque baixo sob demanda
+
+func enviarResumoAlertas(c *gin.Context) {
+	n, err := enviarResumoEstoqueBaixo(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao enviar resumo de estoque baixo: %v", err)
+		if err == errSMTPNaoConfigurado {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Servidor SMTP não configurado"})
+		} else {
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: "Erro ao enviar resumo: " + err.Error()})
+		}
+		return
+	}
+
+	log.Printf("[INTEGRACAO] Resumo de estoque baixo enviado sob demanda: %d produtos", n)
+	c.JSON(http.StatusOK, gin.H{"produtos": n})
+}
@@ -0,0 +1,213 @@
+// aliases_codigos.go - Códigos antigos que levam ao produto atual
+//
+// Etiquetas impressas continuam circulando depois que um código é renomeado
+// ou que produtos são mesclados. Cada código que deixa de existir vira um alias
+// em aliases_codigos, apontando para o ID do produto que o substituiu; aliases
+// também podem ser cadastrados manualmente. GET /api/produtos/codigo/:codigo e
+// GET /api/scan/*codigo resolvem o alias e devolvem o produto atual com o
+// aviso codigo_obsoleto, para o app orientar a troca da etiqueta. Um código em
+// uso por um produto sempre tem precedência sobre o alias.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rlsautomacao/estoque/internal/repositorio"
+)
+
+// Origens de um alias de código
+const (
+	OrigemAliasRenomeacao = "renomeacao"
+	OrigemAliasMesclagem  = "mesclagem"
+	OrigemAliasManual     = "manual"
+)
+
+type AliasCodigo struct {
+	ID          int       `json:"id,omitempty"`
+	Codigo      string    `json:"codigo"`
+	ProdutoID   int       `json:"produto_id"`
+	Origem      string    `json:"origem"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+// Aviso devolvido quando o produto foi encontrado por um código antigo
+type AvisoCodigoObsoleto struct {
+	CodigoLido  string `json:"codigo_lido"`
+	CodigoAtual string `json:"codigo_atual"`
+	Mensagem    string `json:"mensagem"`
+}
+
+// Produto com o aviso de código obsoleto; os campos do produto continuam no
+// nível principal do JSON
+type ProdutoResolvido struct {
+	Produto
+	CodigoObsoleto *AvisoCodigoObsoleto `json:"codigo_obsoleto,omitempty"`
+}
+
+func novoAvisoCodigoObsoleto(lido, atual string) *AvisoCodigoObsoleto {
+	return &AvisoCodigoObsoleto{
+		CodigoLido:  lido,
+		CodigoAtual: atual,
+		Mensagem:    "O código " + lido + " está obsoleto; use " + atual,
+	}
+}
+
+// Aponta o alias para o produto, substituindo um alias anterior do mesmo código
+func registrarAliasCodigo(ctx context.Context, q querier, codigo string, produtoID int, origem string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO aliases_codigos (codigo, produto_id, origem)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (codigo) DO UPDATE SET
+			produto_id = EXCLUDED.produto_id,
+			origem = EXCLUDED.origem,
+			data_criacao = CURRENT_TIMESTAMP
+	`, codigo, produtoID, origem)
+	return err
+}
+
+// Busca o produto atual de um código antigo; devolve pgx.ErrNoRows quando
+// nenhum dos códigos é alias
+func buscarProdutoPorAlias(ctx context.Context, codigos []string) (Produto, error) {
+	p, err := repositorio.NovoProdutos(db).BuscarPorAlias(ctx, codigos)
+	if errors.Is(err, repositorio.ErrNaoEncontrado) {
+		return p, pgx.ErrNoRows
+	}
+	return p, err
+}
+
+// Handlers de Aliases de Código
+
+func getAliasesPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, codigo, produto_id, origem, data_criacao
+		FROM aliases_codigos
+		WHERE produto_id = $1
+		ORDER BY data_criacao DESC
+	`, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar aliases de código: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar aliases de código"})
+		return
+	}
+	defer rows.Close()
+
+	aliases := []AliasCodigo{}
+	for rows.Next() {
+		var a AliasCodigo
+		if err := rows.Scan(&a.ID, &a.Codigo, &a.ProdutoID, &a.Origem, &a.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar alias de código: %v", err)
+			continue
+		}
+		aliases = append(aliases, a)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar aliases de código: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar aliases de código"})
+		return
+	}
+
+	c.JSON(http.StatusOK, aliases)
+}
+
+func criarAliasCodigo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var a AliasCodigo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	a.ProdutoID = id
+	a.Origem = OrigemAliasManual
+	a.Codigo = strings.TrimSpace(a.Codigo)
+	if a.Codigo == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O código é obrigatório"})
+		return
+	}
+
+	// Um código em uso não pode ser alias, e um alias não é transferido em silêncio
+	var emUso bool
+	err = db.QueryRow(c.Request.Context(), `
+		SELECT EXISTS(SELECT 1 FROM produtos WHERE codigo = $1)
+			OR EXISTS(SELECT 1 FROM aliases_codigos WHERE codigo = $1)
+	`, a.Codigo).Scan(&emUso)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar alias de código: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar alias de código"})
+		return
+	}
+	if emUso {
+		log.Printf("[DB] Código '%s' já está em uso ou já é alias", a.Codigo)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Este código já identifica um produto ou já é alias"})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		INSERT INTO aliases_codigos (codigo, produto_id, origem)
+		VALUES ($1, $2, $3)
+		RETURNING id, data_criacao
+	`, a.Codigo, a.ProdutoID, a.Origem).Scan(&a.ID, &a.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar alias de código: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar alias de código (verifique o produto)"})
+		return
+	}
+
+	log.Printf("[DB] Alias %s cadastrado para o produto ID: %d", a.Codigo, a.ProdutoID)
+	c.JSON(http.StatusCreated, a)
+}
+
+func deletarAliasCodigo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM aliases_codigos WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir alias de código: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir alias de código"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Alias de código não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Alias de código não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Alias de código excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Alias de código excluído com sucesso"})
+}
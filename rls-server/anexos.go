@@ -0,0 +1,558 @@
+// anexos.go - Anexos de documentos (nota fiscal, fotos de entrega)
+//
+// Subsistema genérico de anexos: os arquivos ficam em ANEXOS_DIR, organizados
+// por entidade e registro, e os metadados na tabela anexos. Só são aceitos
+// PDF, JPEG, PNG, WebP, XML, XLSX e CSV até ANEXOS_TAMANHO_MAXIMO_MB; o tipo é
+// detectado pelo conteúdo (magic bytes) e a extensão do nome não pode
+// contradizê-lo. O SHA-256 do arquivo é gravado no upload e conferido a cada
+// download, e o arquivo passa pelo antivírus quando configurado (antivirus.go).
+// O download é feito por URL assinada (HMAC com ANEXOS_SEGREDO) e com
+// validade, para o app abrir o arquivo direto no navegador. Hoje as
+// movimentações são a única entidade com anexos.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Configuração dos anexos - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	anexosDiretorio          = getEnv("ANEXOS_DIR", "anexos")
+	anexosTamanhoMaximoMB    = getEnvAsInt("ANEXOS_TAMANHO_MAXIMO_MB", 10)
+	anexosURLValidadeMinutos = getEnvAsInt("ANEXOS_URL_VALIDADE_MINUTOS", 15)
+	anexosSegredo            = segredoAnexos()
+)
+
+// Tipos aceitos e a extensão usada no armazenamento
+var tiposAnexo = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/xml": ".xml",
+	"text/csv":        ".csv",
+	tipoXLSX:          ".xlsx",
+}
+
+const tipoXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Extensões reconhecidas no nome enviado e o tipo que o conteúdo deve ter
+var extensoesAnexo = map[string]string{
+	".pdf":  "application/pdf",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".xml":  "application/xml",
+	".csv":  "text/csv",
+	".xlsx": tipoXLSX,
+}
+
+// Resultado da verificação antivírus gravado no anexo
+const (
+	VerificacaoNaoVerificado = "nao_verificado"
+	VerificacaoLimpo         = "limpo"
+)
+
+// Entidades que aceitam anexos e a tabela de cada uma
+var entidadesAnexo = map[string]string{
+	"movimentacao": "movimentacoes",
+}
+
+var (
+	errAnexoGrande   = errors.New("arquivo maior que o permitido")
+	errAnexoTipo     = errors.New("tipo de arquivo não permitido ou diferente da extensão (use PDF, JPEG, PNG, WebP, XML, XLSX ou CSV)")
+	errAnexoRegistro = errors.New("registro não encontrado")
+)
+
+type Anexo struct {
+	ID           int       `json:"id"`
+	Entidade     string    `json:"entidade"`
+	EntidadeID   int       `json:"entidade_id"`
+	NomeArquivo  string    `json:"nome_arquivo"`
+	TipoConteudo string    `json:"tipo_conteudo"`
+	Tamanho      int64     `json:"tamanho"`
+	SHA256       string    `json:"sha256,omitempty"`
+	Verificacao  string    `json:"verificacao"`
+	Descricao    string    `json:"descricao,omitempty"`
+	DataCriacao  time.Time `json:"data_criacao"`
+	// URL assinada para download, válida por ANEXOS_URL_VALIDADE_MINUTOS
+	URL string `json:"url,omitempty"`
+
+	caminho string
+}
+
+// Sem ANEXOS_SEGREDO, usa um segredo aleatório: as URLs deixam de valer após reiniciar
+func segredoAnexos() []byte {
+	if s := getEnv("ANEXOS_SEGREDO", ""); s != "" {
+		return []byte(s)
+	}
+	segredo := make([]byte, 32)
+	if _, err := rand.Read(segredo); err != nil {
+		log.Fatalf("[ERROR] Erro ao gerar segredo dos anexos: %v", err)
+	}
+	return segredo
+}
+
+func assinaturaAnexo(id int, expira int64) string {
+	mac := hmac.New(sha256.New, anexosSegredo)
+	fmt.Fprintf(mac, "%d:%d", id, expira)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Monta a URL assinada de download do anexo
+func urlAnexo(id int) string {
+	expira := time.Now().Add(time.Duration(anexosURLValidadeMinutos) * time.Minute).Unix()
+	return fmt.Sprintf("/api/v1/anexos/%d/download?expira=%d&assinatura=%s", id, expira, assinaturaAnexo(id, expira))
+}
+
+// Detecta o tipo pelos primeiros bytes. Formatos de texto (XML e CSV) não têm
+// assinatura e dependem também da extensão; XLSX é um ZIP com a pasta de
+// trabalho do Excel. Retorna "" quando o conteúdo não é de um tipo aceito ou
+// não corresponde à extensão do nome.
+func detectarTipoAnexo(cabecalho []byte, nome string, arquivo io.ReaderAt, tamanho int64) string {
+	extensao := strings.ToLower(filepath.Ext(nome))
+
+	tipo := ""
+	switch {
+	case bytes.HasPrefix(cabecalho, []byte("%PDF-")):
+		tipo = "application/pdf"
+	case bytes.HasPrefix(cabecalho, []byte("\xFF\xD8\xFF")):
+		tipo = "image/jpeg"
+	case bytes.HasPrefix(cabecalho, []byte("\x89PNG\r\n\x1A\n")):
+		tipo = "image/png"
+	case len(cabecalho) >= 12 && bytes.HasPrefix(cabecalho, []byte("RIFF")) && string(cabecalho[8:12]) == "WEBP":
+		tipo = "image/webp"
+	case bytes.HasPrefix(cabecalho, []byte("PK\x03\x04")):
+		z, err := zip.NewReader(arquivo, tamanho)
+		if err != nil {
+			return ""
+		}
+		for _, f := range z.File {
+			if f.Name == "xl/workbook.xml" {
+				tipo = tipoXLSX
+				break
+			}
+		}
+	case textoAnexo(cabecalho):
+		texto := bytes.TrimSpace(bytes.TrimPrefix(cabecalho, []byte("\xEF\xBB\xBF")))
+		if bytes.HasPrefix(texto, []byte("<?xml")) || (extensao == ".xml" && bytes.HasPrefix(texto, []byte("<"))) {
+			tipo = "application/xml"
+		} else if extensao == ".csv" {
+			tipo = "text/csv"
+		}
+	}
+
+	if esperado, ok := extensoesAnexo[extensao]; tipo == "" || (ok && esperado != tipo) {
+		return ""
+	}
+	return tipo
+}
+
+// Texto sem bytes nulos e em UTF-8 (o cabeçalho pode cortar um caractere no fim)
+func textoAnexo(cabecalho []byte) bool {
+	if len(cabecalho) == 0 || bytes.IndexByte(cabecalho, 0) >= 0 {
+		return false
+	}
+	for i := 0; i < utf8.UTFMax && len(cabecalho) > 0 && !utf8.Valid(cabecalho); i++ {
+		cabecalho = cabecalho[:len(cabecalho)-1]
+	}
+	return utf8.Valid(cabecalho)
+}
+
+// Passa o arquivo gravado pelo antivírus, quando configurado
+func verificarAnexo(ctx context.Context, caminho string) (string, error) {
+	if !antivirusAnexos.Ativa() {
+		return VerificacaoNaoVerificado, nil
+	}
+
+	arquivo, err := os.Open(caminho)
+	if err != nil {
+		return "", err
+	}
+	defer arquivo.Close()
+
+	err = antivirusAnexos.verificar(ctx, arquivo)
+	if errors.Is(err, errAntivirusIndisponivel) && !anexosClamAVObrigatorio {
+		log.Printf("[WARN] Anexo aceito sem verificação: %v", err)
+		return VerificacaoNaoVerificado, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return VerificacaoLimpo, nil
+}
+
+// Valida e grava o arquivo enviado e registra o anexo
+func salvarAnexo(ctx context.Context, entidade string, entidadeID int, fh *multipart.FileHeader, descricao string) (Anexo, error) {
+	a := Anexo{Entidade: entidade, EntidadeID: entidadeID, NomeArquivo: filepath.Base(fh.Filename), Descricao: descricao}
+
+	if fh.Size > int64(anexosTamanhoMaximoMB)<<20 {
+		return a, errAnexoGrande
+	}
+
+	var existe bool
+	err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM "+entidadesAnexo[entidade]+" WHERE id = $1)", entidadeID).Scan(&existe)
+	if err != nil {
+		return a, err
+	}
+	if !existe {
+		return a, errAnexoRegistro
+	}
+
+	arquivo, err := fh.Open()
+	if err != nil {
+		return a, err
+	}
+	defer arquivo.Close()
+
+	// Detectar o tipo pelos primeiros bytes
+	cabecalho := make([]byte, 512)
+	n, err := io.ReadFull(arquivo, cabecalho)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return a, err
+	}
+	cabecalho = cabecalho[:n]
+	a.TipoConteudo = detectarTipoAnexo(cabecalho, a.NomeArquivo, arquivo, fh.Size)
+	if a.TipoConteudo == "" {
+		return a, errAnexoTipo
+	}
+	extensao := tiposAnexo[a.TipoConteudo]
+	if _, err := arquivo.Seek(int64(n), io.SeekStart); err != nil {
+		return a, err
+	}
+
+	nome := make([]byte, 16)
+	if _, err := rand.Read(nome); err != nil {
+		return a, err
+	}
+	a.caminho = filepath.Join(entidade, strconv.Itoa(entidadeID), hex.EncodeToString(nome)+extensao)
+	destino := filepath.Join(anexosDiretorio, a.caminho)
+	if err := os.MkdirAll(filepath.Dir(destino), 0o750); err != nil {
+		return a, err
+	}
+
+	saida, err := os.OpenFile(destino, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return a, err
+	}
+	hash := sha256.New()
+	a.Tamanho, err = io.Copy(io.MultiWriter(saida, hash), io.MultiReader(bytes.NewReader(cabecalho), arquivo))
+	if cerr := saida.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(destino)
+		return a, err
+	}
+	a.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	// Antivírus antes de o anexo ficar disponível para download
+	a.Verificacao, err = verificarAnexo(ctx, destino)
+	if err != nil {
+		os.Remove(destino)
+		return a, err
+	}
+
+	err = db.QueryRow(ctx, `
+		INSERT INTO anexos(entidade, entidade_id, nome_arquivo, tipo_conteudo, tamanho, caminho, descricao, sha256, verificacao)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)
+		RETURNING id, data_criacao
+	`, a.Entidade, a.EntidadeID, a.NomeArquivo, a.TipoConteudo, a.Tamanho, a.caminho, a.Descricao, a.SHA256, a.Verificacao).Scan(&a.ID, &a.DataCriacao)
+	if err != nil {
+		os.Remove(destino)
+		return a, err
+	}
+
+	a.URL = urlAnexo(a.ID)
+	return a, nil
+}
+
+// Função auxiliar para listar os anexos de um registro
+func listarAnexos(ctx context.Context, entidade string, entidadeID int) ([]Anexo, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, entidade, entidade_id, nome_arquivo, tipo_conteudo, tamanho, sha256, verificacao, descricao, data_criacao
+		FROM anexos
+		WHERE entidade = $1 AND entidade_id = $2
+		ORDER BY data_criacao, id
+	`, entidade, entidadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anexos := []Anexo{}
+	for rows.Next() {
+		var a Anexo
+		var descricao, hash *string
+		if err := rows.Scan(&a.ID, &a.Entidade, &a.EntidadeID, &a.NomeArquivo, &a.TipoConteudo, &a.Tamanho, &hash, &a.Verificacao, &descricao, &a.DataCriacao); err != nil {
+			return nil, err
+		}
+
+		// Tratar campos nulos
+		if descricao != nil {
+			a.Descricao = *descricao
+		}
+		if hash != nil {
+			a.SHA256 = *hash
+		}
+		a.URL = urlAnexo(a.ID)
+		anexos = append(anexos, a)
+	}
+	return anexos, rows.Err()
+}
+
+// Handlers de Anexos
+
+func getAnexosMovimentacao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	anexos, err := listarAnexos(c.Request.Context(), "movimentacao", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar anexos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar anexos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, anexos)
+}
+
+func criarAnexoMovimentacao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Limitar o corpo antes de ler o multipart (arquivo + campos)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(anexosTamanhoMaximoMB+1)<<20)
+	fh, err := c.FormFile("arquivo")
+	if err != nil {
+		log.Printf("[ERROR] Arquivo ausente ou grande demais: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Envie o arquivo no campo 'arquivo' (até %d MB)", anexosTamanhoMaximoMB),
+		})
+		return
+	}
+
+	log.Printf("[API] Recebendo anexo '%s' (%d bytes) para a movimentação ID: %d", fh.Filename, fh.Size, id)
+
+	a, err := salvarAnexo(c.Request.Context(), "movimentacao", id, fh, c.PostForm("descricao"))
+	if err != nil {
+		var infectado errAnexoInfectado
+		switch {
+		case err == errAnexoGrande, err == errAnexoTipo:
+			log.Printf("[ERROR] Anexo recusado: %v", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Anexo: " + err.Error()})
+		case errors.As(err, &infectado):
+			log.Printf("[WARN] Anexo '%s' recusado pelo antivírus: %s", fh.Filename, infectado.assinatura)
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "Anexo: " + err.Error()})
+		case errors.Is(err, errAntivirusIndisponivel):
+			log.Printf("[ERROR] Anexo não verificado: %v", err)
+			c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "Antivírus indisponível, tente novamente mais tarde"})
+		case err == errAnexoRegistro:
+			log.Printf("[DB] Movimentação não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Movimentação não encontrada"})
+		default:
+			log.Printf("[ERROR] Erro ao salvar anexo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar anexo"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Anexo salvo: %s (%s, ID: %d)", a.NomeArquivo, a.TipoConteudo, a.ID)
+	c.JSON(http.StatusCreated, a)
+}
+
+func baixarAnexo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Conferir assinatura e validade da URL
+	expira, err := strconv.ParseInt(c.Query("expira"), 10, 64)
+	assinatura := c.Query("assinatura")
+	if err != nil || !hmac.Equal([]byte(assinatura), []byte(assinaturaAnexo(id, expira))) {
+		log.Printf("[WARN] Assinatura inválida no download do anexo ID: %d", id)
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "URL de download inválida"})
+		return
+	}
+	if time.Now().Unix() > expira {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "URL de download expirada"})
+		return
+	}
+
+	var nome, tipo, caminho string
+	var hash *string
+	err = db.QueryRow(c.Request.Context(), "SELECT nome_arquivo, tipo_conteudo, caminho, sha256 FROM anexos WHERE id = $1", id).Scan(&nome, &tipo, &caminho, &hash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Anexo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Anexo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar anexo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar anexo"})
+		}
+		return
+	}
+
+	// Tratar campos nulos (anexos anteriores ao registro do hash)
+	esperado := ""
+	if hash != nil {
+		esperado = *hash
+	}
+	servirArquivo(c, filepath.Join(anexosDiretorio, caminho), nome, tipo, esperado)
+}
+
+func deletarAnexo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var caminho string
+	err = db.QueryRow(c.Request.Context(), "DELETE FROM anexos WHERE id = $1 RETURNING caminho", id).Scan(&caminho)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Anexo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Anexo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao excluir anexo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir anexo"})
+		}
+		return
+	}
+
+	if err := os.Remove(filepath.Join(anexosDiretorio, caminho)); err != nil {
+		log.Printf("[WARN] Erro ao remover arquivo do anexo %d: %v", id, err)
+		// Não é um erro crítico, o registro já foi excluído
+	}
+
+	log.Printf("[DB] Anexo excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Anexo excluído com sucesso"})
+}
+
+type AnexoDivergente struct {
+	ID          int    `json:"id"`
+	NomeArquivo string `json:"nome_arquivo"`
+	Motivo      string `json:"motivo"`
+}
+
+type ResultadoVerificacaoAnexos struct {
+	Verificados int `json:"verificados"`
+	// Anexos anteriores ao registro do hash, que passaram a tê-lo
+	Registrados int               `json:"registrados"`
+	Divergentes []AnexoDivergente `json:"divergentes"`
+}
+
+// Confere todos os arquivos com o hash registrado e grava o hash dos anexos
+// que ainda não o têm
+func verificarIntegridadeAnexos(c *gin.Context) {
+	ctx := c.Request.Context()
+	log.Println("[DB] Verificando integridade dos anexos")
+
+	type registro struct {
+		id            int
+		nome, caminho string
+		hash          *string
+	}
+	rows, err := db.Query(ctx, "SELECT id, nome_arquivo, caminho, sha256 FROM anexos ORDER BY id")
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar anexos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar anexos"})
+		return
+	}
+	var registros []registro
+	for rows.Next() {
+		var r registro
+		if err := rows.Scan(&r.id, &r.nome, &r.caminho, &r.hash); err != nil {
+			log.Printf("[ERROR] Erro ao processar anexo: %v", err)
+			continue
+		}
+		registros = append(registros, r)
+	}
+	rows.Close()
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar anexos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar anexos"})
+		return
+	}
+
+	resultado := ResultadoVerificacaoAnexos{Divergentes: []AnexoDivergente{}}
+	for _, r := range registros {
+		arquivo, err := os.Open(filepath.Join(anexosDiretorio, r.caminho))
+		if err != nil {
+			resultado.Divergentes = append(resultado.Divergentes, AnexoDivergente{ID: r.id, NomeArquivo: r.nome, Motivo: "arquivo ausente ou ilegível"})
+			continue
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, arquivo)
+		arquivo.Close()
+		if err != nil {
+			resultado.Divergentes = append(resultado.Divergentes, AnexoDivergente{ID: r.id, NomeArquivo: r.nome, Motivo: "arquivo ilegível"})
+			continue
+		}
+		soma := hex.EncodeToString(hash.Sum(nil))
+		resultado.Verificados++
+
+		if r.hash == nil {
+			if _, err := db.Exec(ctx, "UPDATE anexos SET sha256 = $1 WHERE id = $2", soma, r.id); err != nil {
+				log.Printf("[ERROR] Erro ao registrar hash do anexo ID %d: %v", r.id, err)
+				continue
+			}
+			resultado.Registrados++
+		} else if *r.hash != soma {
+			resultado.Divergentes = append(resultado.Divergentes, AnexoDivergente{ID: r.id, NomeArquivo: r.nome, Motivo: "conteúdo diferente do registrado"})
+		}
+	}
+
+	for _, d := range resultado.Divergentes {
+		log.Printf("[WARN] Anexo ID %d (%s) com problema de integridade: %s", d.ID, d.NomeArquivo, d.Motivo)
+	}
+	log.Printf("[DB] Integridade dos anexos verificada: %d arquivos, %d hashes registrados, %d divergentes",
+		resultado.Verificados, resultado.Registrados, len(resultado.Divergentes))
+	c.JSON(http.StatusOK, resultado)
+}
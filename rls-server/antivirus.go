@@ -0,0 +1,152 @@
+// antivirus.go - Verificação dos anexos pelo ClamAV (opcional)
+//
+// Com ANEXOS_CLAMAV_ENDERECO definido (unix:/run/clamav/clamd.ctl ou
+// tcp:localhost:3310), todo anexo é enviado ao clamd pelo comando INSTREAM
+// antes de ser registrado; arquivo infectado é recusado e apagado. Sem o
+// endereço, os anexos ficam como não verificados. Com
+// ANEXOS_CLAMAV_OBRIGATORIO=true, uploads são recusados enquanto o clamd não
+// responde, em vez de aceitos sem verificação. O ClamAV aparece no painel de
+// integrações.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Configuração do antivírus - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	anexosClamAVEndereco    = getEnv("ANEXOS_CLAMAV_ENDERECO", "")
+	anexosClamAVObrigatorio = getEnv("ANEXOS_CLAMAV_OBRIGATORIO", "false") == "true"
+	anexosClamAVTimeout     = getEnvAsInt("ANEXOS_CLAMAV_TIMEOUT_SEGUNDOS", 30)
+)
+
+// Tamanho dos blocos enviados ao clamd
+const clamAVBloco = 64 << 10
+
+var errAntivirusIndisponivel = errors.New("antivírus indisponível")
+
+// Arquivo recusado pelo antivírus, com o nome da assinatura encontrada
+type errAnexoInfectado struct {
+	assinatura string
+}
+
+func (e errAnexoInfectado) Error() string {
+	return "arquivo recusado pelo antivírus (" + e.assinatura + ")"
+}
+
+type antivirusClamAV struct {
+	endereco string
+	metricas MetricasIntegracao
+}
+
+var antivirusAnexos = &antivirusClamAV{endereco: anexosClamAVEndereco}
+
+func (a *antivirusClamAV) Nome() string                  { return "clamav" }
+func (a *antivirusClamAV) Tipo() string                  { return "antivirus" }
+func (a *antivirusClamAV) Ativa() bool                   { return a.endereco != "" }
+func (a *antivirusClamAV) FilaPendente() int             { return 0 }
+func (a *antivirusClamAV) Metricas() *MetricasIntegracao { return &a.metricas }
+
+// Abre a conexão com o clamd (unix:caminho ou tcp:host:porta)
+func (a *antivirusClamAV) conectar(ctx context.Context) (net.Conn, error) {
+	rede, endereco, ok := strings.Cut(a.endereco, ":")
+	if !ok || (rede != "unix" && rede != "tcp") {
+		return nil, fmt.Errorf("endereço do ClamAV inválido: %s", a.endereco)
+	}
+
+	ctx, cancelar := context.WithTimeout(ctx, time.Duration(anexosClamAVTimeout)*time.Second)
+	defer cancelar()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, rede, endereco)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(time.Duration(anexosClamAVTimeout) * time.Second))
+	return conn, nil
+}
+
+// Envia um comando e lê a resposta (terminada em NUL)
+func (a *antivirusClamAV) comando(ctx context.Context, comando string, conteudo io.Reader) (string, error) {
+	conn, err := a.conectar(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "z"+comando+"\x00"); err != nil {
+		return "", err
+	}
+
+	// INSTREAM: blocos com o tamanho em 4 bytes big-endian, e um bloco vazio no fim
+	if conteudo != nil {
+		w := bufio.NewWriterSize(conn, clamAVBloco+4)
+		bloco := make([]byte, clamAVBloco)
+		for {
+			n, err := conteudo.Read(bloco)
+			if n > 0 {
+				binary.Write(w, binary.BigEndian, uint32(n))
+				if _, err := w.Write(bloco[:n]); err != nil {
+					return "", err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		binary.Write(w, binary.BigEndian, uint32(0))
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+	}
+
+	resposta, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(resposta, "\x00\n"), nil
+}
+
+// Testa a conectividade com o clamd (PING/PONG)
+func (a *antivirusClamAV) Testar(ctx context.Context) error {
+	resposta, err := a.comando(ctx, "PING", nil)
+	if err != nil {
+		return err
+	}
+	if resposta != "PONG" {
+		return fmt.Errorf("resposta inesperada do ClamAV: %q", resposta)
+	}
+	return nil
+}
+
+// Verifica o conteúdo: nil se limpo, errAnexoInfectado se o clamd encontrou
+// algo, errAntivirusIndisponivel (envolvendo a causa) se não foi possível verificar
+func (a *antivirusClamAV) verificar(ctx context.Context, conteudo io.Reader) error {
+	resposta, err := a.comando(ctx, "INSTREAM", conteudo)
+	a.metricas.Registrar(err)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errAntivirusIndisponivel, err)
+	}
+
+	// Respostas: "stream: OK", "stream: <assinatura> FOUND", "<mensagem> ERROR"
+	resposta = strings.TrimPrefix(resposta, "stream: ")
+	switch {
+	case resposta == "OK":
+		return nil
+	case strings.HasSuffix(resposta, " FOUND"):
+		return errAnexoInfectado{assinatura: strings.TrimSuffix(resposta, " FOUND")}
+	default:
+		return fmt.Errorf("%w: %s", errAntivirusIndisponivel, resposta)
+	}
+}
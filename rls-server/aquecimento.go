@@ -0,0 +1,101 @@
+// aquecimento.go - Aquecimento de cache e pré-computação no startup
+//
+// Após um restart o primeiro dashboard pagava o custo de abrir conexões,
+// preparar statements e ler páginas frias do banco. O aquecimento roda essas
+// consultas antes de o servidor aceitar requisições e mantém as configurações
+// em memória. Desligue com AQUECIMENTO_ENABLED=false em desenvolvimento.
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+var aquecimentoEnabled = getEnv("AQUECIMENTO_ENABLED", "true") == "true"
+
+// Cache das configurações, carregado no aquecimento e atualizado pelo PUT
+var (
+	configuracoesCacheMutex sync.RWMutex
+	configuracoesCache      map[string]string
+)
+
+// Retorna a configuração em cache; ok é falso se o cache não foi carregado
+func configuracaoEmCache(chave string) (string, bool) {
+	configuracoesCacheMutex.RLock()
+	defer configuracoesCacheMutex.RUnlock()
+	if configuracoesCache == nil {
+		return "", false
+	}
+	valor, ok := configuracoesCache[chave]
+	return valor, ok
+}
+
+// Atualiza uma configuração no cache, se ele estiver carregado
+func guardarConfiguracao(chave, valor string) {
+	configuracoesCacheMutex.Lock()
+	defer configuracoesCacheMutex.Unlock()
+	if configuracoesCache != nil {
+		configuracoesCache[chave] = valor
+	}
+}
+
+// Função auxiliar para carregar todas as configurações no cache
+func carregarConfiguracoes(ctx context.Context) (int, error) {
+	rows, err := db.Query(ctx, "SELECT chave, valor FROM configuracoes")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cache := map[string]string{}
+	for rows.Next() {
+		var chave, valor string
+		if err := rows.Scan(&chave, &valor); err != nil {
+			return 0, err
+		}
+		cache[chave] = valor
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	configuracoesCacheMutex.Lock()
+	configuracoesCache = cache
+	configuracoesCacheMutex.Unlock()
+	return len(cache), nil
+}
+
+// Executa o aquecimento. Falhas são apenas logadas: o servidor sobe mesmo assim.
+func aquecer(ctx context.Context) {
+	inicio := time.Now()
+	log.Println("[DB] Iniciando aquecimento...")
+
+	// 1. Configurações em memória
+	etapa := time.Now()
+	if n, err := carregarConfiguracoes(ctx); err != nil {
+		log.Printf("[WARN] Aquecimento: erro ao carregar configurações: %v", err)
+	} else {
+		log.Printf("[DB] Aquecimento: %d configurações carregadas em %v", n, time.Since(etapa))
+	}
+
+	// 2. Consultas do dashboard (contadores, últimas movimentações e top
+	// produtos) em paralelo, uma por conexão mínima do pool, para que cada
+	// conexão já tenha os statements preparados
+	etapa = time.Now()
+	conexoes := max(int(db.Config().MinConns), 1)
+	var wg sync.WaitGroup
+	for i := 0; i < conexoes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			montarDashboard(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Printf("[DB] Aquecimento: dashboard pré-computado em %d conexões em %v", conexoes, time.Since(etapa))
+
+	log.Printf("[DB] ✓ Aquecimento concluído em %v", time.Since(inicio))
+}
@@ -0,0 +1,169 @@
+// atividades.go - Feed de atividade global
+//
+// Um inscrito do barramento grava cada evento (movimentações, edições de
+// cadastro, alertas de estoque baixo, comentários) na tabela atividades, com
+// a entidade a que se refere, o usuário quando conhecido e um resumo legível.
+// GET /api/atividades devolve o feed do mais recente para o mais antigo,
+// paginado por cursor (id da última atividade recebida).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tamanho máximo de página do feed
+const atividadesLimiteMaximo = 200
+
+type Atividade struct {
+	ID          int64           `json:"id"`
+	Tipo        string          `json:"tipo"`
+	Entidade    string          `json:"entidade"`
+	EntidadeID  int             `json:"entidade_id"`
+	Usuario     string          `json:"usuario,omitempty"`
+	Resumo      string          `json:"resumo"`
+	Dados       json.RawMessage `json:"dados,omitempty"`
+	DataCriacao time.Time       `json:"data_criacao"`
+}
+
+type PaginaAtividades struct {
+	Itens []Atividade `json:"itens"`
+	// Passar em ?cursor= para buscar a página seguinte; ausente na última
+	ProximoCursor int64 `json:"proximo_cursor,omitempty"`
+}
+
+// Converte um evento do barramento em atividade; ok falso quando não entra no feed
+func atividadeDoEvento(e Evento) (Atividade, bool) {
+	a := Atividade{Tipo: e.Tipo, DataCriacao: e.Data}
+	switch dados := e.Dados.(type) {
+	case Movimentacao:
+		a.Entidade, a.EntidadeID = "produto", dados.ProdutoID
+		a.Resumo = fmt.Sprintf("%s de %d unidades", dados.Tipo, dados.Quantidade)
+		if dados.Notas != "" {
+			a.Resumo += " - " + dados.Notas
+		}
+	case Produto:
+		a.Entidade, a.EntidadeID = "produto", dados.ID
+		a.Resumo = fmt.Sprintf("Cadastro de %s %s atualizado", dados.Codigo, dados.Nome)
+	case AlertaEstoqueBaixo:
+		a.Entidade, a.EntidadeID = "produto", dados.ProdutoID
+		a.Resumo = fmt.Sprintf("Estoque baixo: %s %s com %d unidades (mínimo %d)",
+			dados.Codigo, dados.Nome, dados.Quantidade, dados.QuantidadeMinima)
+	case Comentario:
+		a.Entidade, a.EntidadeID = dados.Entidade, dados.EntidadeID
+		a.Usuario = dados.Autor
+		a.Resumo = dados.Autor + " comentou: " + dados.Texto
+	default:
+		return a, false
+	}
+
+	dados, err := json.Marshal(e.Dados)
+	if err != nil {
+		log.Printf("[WARN] Erro ao serializar dados da atividade %s: %v", e.Tipo, err)
+	}
+	a.Dados = dados
+	return a, true
+}
+
+// Assina o barramento e grava o feed; roda até o contexto ser cancelado
+func iniciarAtividades(ctx context.Context) {
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			a, ok := atividadeDoEvento(e)
+			if !ok {
+				continue
+			}
+			_, err := db.Exec(ctx, `
+				INSERT INTO atividades(tipo, entidade, entidade_id, usuario, resumo, dados, data_criacao)
+				VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			`, a.Tipo, a.Entidade, a.EntidadeID, a.Usuario, a.Resumo, a.Dados, a.DataCriacao)
+			if err != nil {
+				log.Printf("[ERROR] Erro ao registrar atividade %s: %v", a.Tipo, err)
+			}
+		}
+	}
+}
+
+// Handlers de Atividades
+
+func getAtividades(c *gin.Context) {
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "50"))
+	if err != nil || limite <= 0 {
+		limite = 50
+	}
+	limite = min(limite, atividadesLimiteMaximo)
+
+	cursor, err := strconv.ParseInt(c.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil || cursor < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cursor inválido"})
+		return
+	}
+	entidadeID, err := strconv.Atoi(c.DefaultQuery("entidade_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "entidade_id inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando feed de atividades (cursor=%d, limite=%d)", cursor, limite)
+
+	// Um item a mais indica se há próxima página
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, tipo, entidade, entidade_id, usuario, resumo, dados, data_criacao
+		FROM atividades
+		WHERE ($1::bigint = 0 OR id < $1::bigint)
+			AND ($2::text = '' OR entidade = $2::text)
+			AND ($3::int = 0 OR entidade_id = $3::int)
+			AND ($4::text = '' OR usuario = $4::text)
+			AND ($5::text = '' OR tipo = $5::text)
+		ORDER BY id DESC
+		LIMIT $6
+	`, cursor, c.Query("entidade"), entidadeID, c.Query("usuario"), c.Query("tipo"), limite+1)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar atividades: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar atividades"})
+		return
+	}
+	defer rows.Close()
+
+	pagina := PaginaAtividades{Itens: []Atividade{}}
+	for rows.Next() {
+		var a Atividade
+		var usuario *string
+		if err := rows.Scan(&a.ID, &a.Tipo, &a.Entidade, &a.EntidadeID, &usuario, &a.Resumo, &a.Dados, &a.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar atividade: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if usuario != nil {
+			a.Usuario = *usuario
+		}
+		pagina.Itens = append(pagina.Itens, a)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar atividades: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar atividades"})
+		return
+	}
+
+	if len(pagina.Itens) > limite {
+		pagina.Itens = pagina.Itens[:limite]
+		pagina.ProximoCursor = pagina.Itens[limite-1].ID
+	}
+	c.JSON(http.StatusOK, pagina)
+}
@@ -0,0 +1,409 @@
+// atributos.go - Atributos dinâmicos de produto por categoria
+//
+// Cada família de produtos precisa de dados próprios (voltagem nos motores,
+// cor nos cabos, marca nas ferramentas). Em vez de uma coluna para cada um, a
+// categoria define os seus atributos em /api/atributos, com tipo (texto,
+// numero, booleano ou opcao), opções e obrigatoriedade, e o produto guarda os
+// valores em atributos (JSONB), pela chave nome. Criação, PUT, PATCH,
+// importação e alteração em lote de categoria conferem os valores contra as
+// definições da categoria do produto; atributo com valor null ou texto vazio
+// é removido. Mudanças numa definição valem para as próximas gravações e a
+// exclusão remove o atributo dos produtos da categoria.
+//
+// A listagem de produtos filtra por categoria e por atributo:
+// GET /api/produtos?categoria=Motores&atributos[voltagem]=220
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tamanho máximo dos valores de atributos do tipo texto
+const tamanhoMaximoAtributoTexto = 200
+
+// Chave do atributo: minúsculas, dígitos e _, começando por letra
+var padraoNomeAtributo = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type Atributo struct {
+	ID          int       `json:"id,omitempty"`
+	Categoria   string    `json:"categoria" binding:"required,max=100"`
+	Nome        string    `json:"nome" binding:"required,max=50"` // chave em produtos.atributos
+	Rotulo      string    `json:"rotulo" binding:"max=100"`       // texto exibido no app; padrão: o nome
+	Tipo        string    `json:"tipo" binding:"required,oneof=texto numero booleano opcao"`
+	Opcoes      []string  `json:"opcoes,omitempty"` // valores aceitos no tipo opcao
+	Obrigatorio bool      `json:"obrigatorio"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+const consultaAtributos = `
+	SELECT id, categoria, nome, rotulo, tipo, opcoes, obrigatorio, data_criacao
+	FROM atributos
+`
+
+func scanAtributo(row pgx.Row) (Atributo, error) {
+	var a Atributo
+	err := row.Scan(&a.ID, &a.Categoria, &a.Nome, &a.Rotulo, &a.Tipo, &a.Opcoes, &a.Obrigatorio, &a.DataCriacao)
+	return a, err
+}
+
+// Normaliza a definição; devolve a mensagem de erro ou "" se válida
+func normalizarAtributo(a *Atributo) string {
+	a.Categoria = strings.TrimSpace(a.Categoria)
+	a.Nome = strings.ToLower(strings.TrimSpace(a.Nome))
+	a.Rotulo = strings.TrimSpace(a.Rotulo)
+	if a.Categoria == "" {
+		return "Informe a categoria do atributo"
+	}
+	if !padraoNomeAtributo.MatchString(a.Nome) {
+		return "O nome do atributo deve começar com letra e conter só letras minúsculas, dígitos e _"
+	}
+	if a.Rotulo == "" {
+		a.Rotulo = a.Nome
+	}
+
+	opcoes := []string{}
+	for _, opcao := range a.Opcoes {
+		if opcao = strings.TrimSpace(opcao); opcao != "" && !slices.Contains(opcoes, opcao) {
+			opcoes = append(opcoes, opcao)
+		}
+	}
+	a.Opcoes = opcoes
+	if a.Tipo == "opcao" && len(a.Opcoes) == 0 {
+		return "Atributos do tipo opcao exigem as opções"
+	}
+	if a.Tipo != "opcao" && len(a.Opcoes) > 0 {
+		return "Só atributos do tipo opcao têm opções"
+	}
+	return ""
+}
+
+// Definições de atributos da categoria
+func listarAtributos(ctx context.Context, q querier, categoria string) ([]Atributo, error) {
+	rows, err := q.Query(ctx, consultaAtributos+`
+		WHERE ($1::text = '' OR categoria = $1::text)
+		ORDER BY categoria, nome
+	`, categoria)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	atributos := []Atributo{}
+	for rows.Next() {
+		a, err := scanAtributo(rows)
+		if err != nil {
+			return nil, err
+		}
+		atributos = append(atributos, a)
+	}
+	return atributos, rows.Err()
+}
+
+// Confere os valores de atributos do produto contra as definições da
+// categoria; devolve os valores normalizados (nunca nil) e as violações por
+// campo (atributos.<nome>)
+func validarAtributos(ctx context.Context, q querier, categoria string, valores map[string]any) (map[string]any, []ErroCampo, error) {
+	normalizados := map[string]any{}
+	campos := []ErroCampo{}
+	if len(valores) == 0 && strings.TrimSpace(categoria) == "" {
+		return normalizados, campos, nil
+	}
+
+	definicoes := map[string]Atributo{}
+	if categoria != "" {
+		lista, err := listarAtributos(ctx, q, categoria)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, a := range lista {
+			definicoes[a.Nome] = a
+		}
+	}
+
+	informados := map[string]bool{}
+	for nome, valor := range valores {
+		if texto, ok := valor.(string); ok {
+			valor = strings.TrimSpace(texto)
+			if valor == "" {
+				valor = nil
+			}
+		}
+		if valor == nil {
+			continue
+		}
+		informados[nome] = true
+		campo := "atributos." + nome
+		a, ok := definicoes[nome]
+		if !ok {
+			campos = append(campos, ErroCampo{Campo: campo, Codigo: "ATRIBUTO_DESCONHECIDO", Mensagem: "não é um atributo da categoria"})
+			continue
+		}
+		if codigo, msg := conferirValorAtributo(a, valor); codigo != "" {
+			campos = append(campos, ErroCampo{Campo: campo, Codigo: codigo, Mensagem: msg})
+			continue
+		}
+		normalizados[nome] = valor
+	}
+
+	for nome, a := range definicoes {
+		if a.Obrigatorio && !informados[nome] {
+			campos = append(campos, ErroCampo{Campo: "atributos." + nome, Codigo: "OBRIGATORIO", Mensagem: "é obrigatório"})
+		}
+	}
+	sort.Slice(campos, func(i, j int) bool { return campos[i].Campo < campos[j].Campo })
+	return normalizados, campos, nil
+}
+
+// Código e mensagem da violação do tipo do atributo; "" se o valor serve
+func conferirValorAtributo(a Atributo, valor any) (string, string) {
+	switch a.Tipo {
+	case "numero":
+		if _, ok := valor.(float64); !ok {
+			return "TIPO_INVALIDO", "deve ser um número"
+		}
+	case "booleano":
+		if _, ok := valor.(bool); !ok {
+			return "TIPO_INVALIDO", "deve ser verdadeiro ou falso"
+		}
+	case "opcao":
+		texto, ok := valor.(string)
+		if !ok || !slices.Contains(a.Opcoes, texto) {
+			return "VALOR_INVALIDO", "deve ser " + strings.Join(a.Opcoes, " ou ")
+		}
+	default:
+		texto, ok := valor.(string)
+		if !ok {
+			return "TIPO_INVALIDO", "deve ser um texto"
+		}
+		if len([]rune(texto)) > tamanhoMaximoAtributoTexto {
+			return "TAMANHO_MAXIMO", "deve ter no máximo " + strconv.Itoa(tamanhoMaximoAtributoTexto) + " caracteres"
+		}
+	}
+	return "", ""
+}
+
+// Confere e normaliza os atributos do produto como serão gravados; responde
+// ao cliente e devolve falso se inválidos
+func vincularAtributos(c *gin.Context, q querier, p *Produto) bool {
+	atributos, campos, err := validarAtributos(c.Request.Context(), q, p.Categoria, p.Atributos)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar atributos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar atributos"})
+		return false
+	}
+	if len(campos) > 0 {
+		log.Printf("[ERROR] Atributos inválidos no produto '%s': %d campos", p.Codigo, len(campos))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Atributos inválidos para a categoria", Codigo: CodigoDadosInvalidos, Campos: campos})
+		return false
+	}
+	p.Atributos = atributos
+	return true
+}
+
+// Handlers de Atributos
+
+func getAtributos(c *gin.Context) {
+	categoria := strings.TrimSpace(c.Query("categoria"))
+	log.Printf("[DB] Buscando atributos (categoria: %q)", categoria)
+
+	atributos, err := listarAtributos(c.Request.Context(), db, categoria)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar atributos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar atributos"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d atributos", len(atributos))
+	c.JSON(http.StatusOK, atributos)
+}
+
+func getAtributo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	a, err := scanAtributo(db.QueryRow(c.Request.Context(), consultaAtributos+"WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Atributo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Atributo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar atributo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar atributo"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+func criarAtributo(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var a Atributo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := normalizarAtributo(&a); msg != "" {
+		log.Printf("[ERROR] Atributo inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	var existente int
+	err := db.QueryRow(ctx, "SELECT id FROM atributos WHERE categoria = $1 AND nome = $2", a.Categoria, a.Nome).Scan(&existente)
+	if err == nil {
+		log.Printf("[DB] Atributo já existe: %s/%s (ID: %d)", a.Categoria, a.Nome, existente)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe um atributo com este nome na categoria"})
+		return
+	} else if err != pgx.ErrNoRows {
+		log.Printf("[ERROR] Erro ao verificar atributo existente: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar atributo existente"})
+		return
+	}
+
+	err = db.QueryRow(ctx, `
+		INSERT INTO atributos(categoria, nome, rotulo, tipo, opcoes, obrigatorio)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, data_criacao
+	`, a.Categoria, a.Nome, a.Rotulo, a.Tipo, a.Opcoes, a.Obrigatorio).Scan(&a.ID, &a.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar atributo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar atributo"})
+		return
+	}
+
+	log.Printf("[DB] Atributo criado: %s/%s (ID: %d)", a.Categoria, a.Nome, a.ID)
+	c.JSON(http.StatusCreated, a)
+}
+
+func atualizarAtributo(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var a Atributo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := normalizarAtributo(&a); msg != "" {
+		log.Printf("[ERROR] Atributo inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	// Categoria e nome identificam os valores já gravados nos produtos
+	atual, err := scanAtributo(db.QueryRow(ctx, consultaAtributos+"WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Atributo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Atributo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar atributo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar atributo"})
+		}
+		return
+	}
+	if a.Categoria != atual.Categoria || a.Nome != atual.Nome {
+		log.Printf("[ERROR] Tentativa de alterar categoria/nome do atributo %d", id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Categoria e nome do atributo não podem ser alterados; exclua e crie outro"})
+		return
+	}
+
+	err = db.QueryRow(ctx, `
+		UPDATE atributos SET rotulo = $1, tipo = $2, opcoes = $3, obrigatorio = $4
+		WHERE id = $5
+		RETURNING data_criacao
+	`, a.Rotulo, a.Tipo, a.Opcoes, a.Obrigatorio, id).Scan(&a.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar atributo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar atributo"})
+		return
+	}
+	a.ID = id
+
+	log.Printf("[DB] Atributo atualizado: %s/%s (ID: %d)", a.Categoria, a.Nome, id)
+	c.JSON(http.StatusOK, a)
+}
+
+func deletarAtributo(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir atributo"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var categoria, nome string
+	err = tx.QueryRow(ctx, "DELETE FROM atributos WHERE id = $1 RETURNING categoria, nome", id).Scan(&categoria, &nome)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Atributo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Atributo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao excluir atributo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir atributo"})
+		}
+		return
+	}
+
+	// Os valores do atributo saem dos produtos da categoria
+	tag, err := tx.Exec(ctx, `
+		UPDATE produtos SET atributos = atributos - $1::text
+		WHERE categoria = $2 AND atributos ? $1::text
+	`, nome, categoria)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao remover atributo dos produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir atributo"})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir atributo"})
+		return
+	}
+
+	log.Printf("[DB] Atributo excluído: %s/%s (ID: %d, removido de %d produtos)", categoria, nome, id, tag.RowsAffected())
+	c.JSON(http.StatusOK, gin.H{"message": "Atributo excluído com sucesso"})
+}
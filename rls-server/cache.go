@@ -0,0 +1,302 @@
+// cache.go - Cache das leituras mais frequentes
+//
+// GET /api/dashboard, GET /api/configuracoes e a busca de produto por código
+// guardam a resposta JSON por alguns segundos (CACHE_*_SEGUNDOS). O cache fica
+// na memória do processo (LRU com até CACHE_MAX_ITENS respostas) ou, com
+// CACHE_BACKEND=redis, no Redis de CACHE_REDIS_URL, compartilhado entre as
+// instâncias.
+//
+// Toda escrita bem-sucedida na API invalida os grupos afetados: escritas em
+// configurações, empresa e setup invalidam configurações e dashboard; as
+// demais invalidam produtos e dashboard, já que quase toda escrita mexe em
+// saldo. A invalidação troca a geração do grupo e as respostas antigas
+// expiram sozinhas. No cache em memória com várias instâncias, as outras
+// instâncias só veem a escrita quando o TTL vence; para isso existe o backend
+// Redis. Se o Redis não responder, as leituras vão direto ao banco.
+//
+// A resposta traz X-Cache: HIT ou MISS.
+
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Configuração do cache - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	cacheEnabled               = getEnv("CACHE_ENABLED", "true") == "true"
+	cacheBackend               = getEnv("CACHE_BACKEND", "memoria")
+	cacheRedisURL              = getEnv("CACHE_REDIS_URL", "")
+	cacheMaxItens              = getEnvAsInt("CACHE_MAX_ITENS", 1000)
+	cacheDashboardSegundos     = getEnvAsInt("CACHE_DASHBOARD_SEGUNDOS", 10)
+	cacheConfiguracoesSegundos = getEnvAsInt("CACHE_CONFIGURACOES_SEGUNDOS", 60)
+	cacheProdutosSegundos      = getEnvAsInt("CACHE_PRODUTOS_SEGUNDOS", 30)
+)
+
+// Grupos de respostas invalidados juntos
+const (
+	grupoCacheDashboard     = "dashboard"
+	grupoCacheConfiguracoes = "configuracoes"
+	grupoCacheProdutos      = "produtos"
+)
+
+const headerCache = "X-Cache"
+
+// Onde as respostas ficam guardadas
+type armazenamentoCache interface {
+	obter(chave string) ([]byte, bool, error)
+	guardar(chave string, valor []byte, ttl time.Duration) error
+	// Geração atual do grupo; muda a cada invalidação
+	geracao(grupo string) (int64, error)
+	invalidar(grupo string) error
+}
+
+// LRU na memória do processo
+
+type itemCache struct {
+	chave  string
+	valor  []byte
+	expira time.Time
+}
+
+type cacheMemoria struct {
+	mu       sync.Mutex
+	itens    map[string]*list.Element
+	ordem    *list.List // mais recente na frente
+	geracoes map[string]int64
+	maximo   int
+}
+
+func novoCacheMemoria(maximo int) *cacheMemoria {
+	return &cacheMemoria{itens: map[string]*list.Element{}, ordem: list.New(), geracoes: map[string]int64{}, maximo: max(maximo, 1)}
+}
+
+func (m *cacheMemoria) obter(chave string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.itens[chave]
+	if !ok {
+		return nil, false, nil
+	}
+	item := e.Value.(*itemCache)
+	if time.Now().After(item.expira) {
+		m.ordem.Remove(e)
+		delete(m.itens, chave)
+		return nil, false, nil
+	}
+	m.ordem.MoveToFront(e)
+	return item.valor, true, nil
+}
+
+func (m *cacheMemoria) guardar(chave string, valor []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.itens[chave]; ok {
+		item := e.Value.(*itemCache)
+		item.valor, item.expira = valor, time.Now().Add(ttl)
+		m.ordem.MoveToFront(e)
+		return nil
+	}
+	m.itens[chave] = m.ordem.PushFront(&itemCache{chave: chave, valor: valor, expira: time.Now().Add(ttl)})
+	for m.ordem.Len() > m.maximo {
+		e := m.ordem.Back()
+		m.ordem.Remove(e)
+		delete(m.itens, e.Value.(*itemCache).chave)
+	}
+	return nil
+}
+
+func (m *cacheMemoria) geracao(grupo string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.geracoes[grupo], nil
+}
+
+func (m *cacheMemoria) invalidar(grupo string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.geracoes[grupo]++
+	return nil
+}
+
+// Redis compartilhado entre as instâncias
+
+type cacheRedis struct {
+	redis *clienteRedis
+}
+
+func (r *cacheRedis) obter(chave string) ([]byte, bool, error) {
+	var valor []byte
+	err := r.redis.executar(func(ctx context.Context) error {
+		var err error
+		valor, err = r.redis.Get(ctx, "rls:cache:"+chave).Bytes()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	return valor, err == nil, err
+}
+
+func (r *cacheRedis) guardar(chave string, valor []byte, ttl time.Duration) error {
+	return r.redis.executar(func(ctx context.Context) error {
+		return r.redis.Set(ctx, "rls:cache:"+chave, valor, ttl).Err()
+	})
+}
+
+func (r *cacheRedis) geracao(grupo string) (int64, error) {
+	var valor int64
+	err := r.redis.executar(func(ctx context.Context) error {
+		var err error
+		valor, err = r.redis.Get(ctx, "rls:cache:geracao:"+grupo).Int64()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return valor, err
+}
+
+func (r *cacheRedis) invalidar(grupo string) error {
+	return r.redis.executar(func(ctx context.Context) error {
+		return r.redis.Incr(ctx, "rls:cache:geracao:"+grupo).Err()
+	})
+}
+
+// Cache das respostas (nil com CACHE_ENABLED=false)
+var cacheRespostas = configurarCache()
+
+func configurarCache() armazenamentoCache {
+	if !cacheEnabled {
+		return nil
+	}
+	switch cacheBackend {
+	case "memoria":
+		return novoCacheMemoria(cacheMaxItens)
+	case "redis":
+		r, err := novoClienteRedis(cacheRedisURL)
+		if err != nil {
+			erroConfiguracao("CACHE_REDIS_URL", cacheRedisURL, "uma URL redis://[:senha@]host[:porta][/banco]")
+			return nil
+		}
+		return &cacheRedis{redis: r}
+	}
+	erroConfiguracao("CACHE_BACKEND", cacheBackend, "memoria ou redis")
+	return nil
+}
+
+// Só o primeiro erro do cache vai ao log; os seguintes seriam um por requisição
+var erroCacheLogado sync.Once
+
+func registrarErroCache(err error) {
+	erroCacheLogado.Do(func() {
+		log.Printf("[WARN] Cache indisponível, lendo direto do banco: %v", err)
+	})
+}
+
+// Writer que guarda a cópia do corpo enviado
+type escritorCache struct {
+	gin.ResponseWriter
+	corpo []byte
+}
+
+func (w *escritorCache) Write(b []byte) (int, error) {
+	w.corpo = append(w.corpo, b...)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *escritorCache) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Middleware de uma rota de leitura: serve a resposta guardada ou guarda a
+// resposta 200 em JSON do handler por ttlSegundos
+func CacheResposta(grupo string, ttlSegundos int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cacheRespostas == nil || ttlSegundos <= 0 || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		geracao, err := cacheRespostas.geracao(grupo)
+		if err != nil {
+			registrarErroCache(err)
+			c.Next()
+			return
+		}
+		// Mesma chave para /api/v1/... e o caminho legado
+		chave := grupo + ":" + strconv.FormatInt(geracao, 10) + ":" + rotaSemVersao(c.Request.URL.Path) + "?" + c.Request.URL.RawQuery
+
+		if corpo, ok, err := cacheRespostas.obter(chave); err != nil {
+			registrarErroCache(err)
+		} else if ok {
+			c.Header(headerCache, "HIT")
+			c.Data(http.StatusOK, "application/json; charset=utf-8", corpo)
+			c.Abort()
+			return
+		}
+
+		c.Header(headerCache, "MISS")
+		w := &escritorCache{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			return
+		}
+		if err := cacheRespostas.guardar(chave, w.corpo, time.Duration(ttlSegundos)*time.Second); err != nil {
+			registrarErroCache(err)
+		}
+	}
+}
+
+// Invalida os grupos do cache afetados por uma escrita
+func invalidarCache(grupos ...string) {
+	if cacheRespostas == nil {
+		return
+	}
+	for _, grupo := range grupos {
+		if err := cacheRespostas.invalidar(grupo); err != nil {
+			log.Printf("[WARN] Erro ao invalidar o cache %s: %v", grupo, err)
+		}
+	}
+}
+
+// Rotas que gravam na tabela de configurações
+var rotasCacheConfiguracoes = []string{"/api/configuracoes", "/api/empresa", "/api/setup"}
+
+// Middleware dos grupos da API: invalida o cache depois de toda escrita
+// bem-sucedida
+func InvalidarCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() >= 400 {
+			return
+		}
+		rota := rotaSemVersao(c.FullPath())
+		for _, prefixo := range rotasCacheConfiguracoes {
+			if strings.HasPrefix(rota, prefixo) {
+				invalidarCache(grupoCacheConfiguracoes, grupoCacheDashboard)
+				return
+			}
+		}
+		invalidarCache(grupoCacheProdutos, grupoCacheDashboard)
+	}
+}
@@ -0,0 +1,85 @@
+// canais_notificacao.go - Canais de mensagens para alertas críticos
+//
+// Um CanalNotificacao entrega uma mensagem curta a um destino fixo (grupo do
+// Telegram, por exemplo) e aparece no painel de integrações. Hoje o único
+// alerta enviado pelos canais é o de produto esgotado: quando uma saída zera
+// o saldo, o supervisor recebe o aviso com o link "Ver produto"
+// (notificacao_link_produto, com {id} e {codigo} substituídos).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+type MensagemNotificacao struct {
+	Titulo string
+	Texto  string
+	// Link opcional exibido como botão
+	Link      string
+	LinkTexto string
+}
+
+// CanalNotificacao é implementado por cada driver de mensagens
+type CanalNotificacao interface {
+	Integracao
+	Enviar(ctx context.Context, m MensagemNotificacao) error
+}
+
+// Canais disponíveis; os inativos (sem configuração) são ignorados no envio
+var canaisNotificacao = []CanalNotificacao{canalTelegram}
+
+// Envia a mensagem por todos os canais ativos
+func notificarCanais(ctx context.Context, m MensagemNotificacao) {
+	for _, canal := range canaisNotificacao {
+		if !canal.Ativa() {
+			continue
+		}
+		err := canal.Enviar(ctx, m)
+		canal.Metricas().Registrar(err)
+		if err != nil {
+			log.Printf("[WARN] Erro ao notificar pelo canal %s: %v", canal.Nome(), err)
+		}
+	}
+}
+
+// Monta o link para o produto a partir de notificacao_link_produto
+func linkProduto(ctx context.Context, id int, codigo string) string {
+	modelo := lerConfiguracao(ctx, "notificacao_link_produto", "")
+	if modelo == "" {
+		return ""
+	}
+	return strings.NewReplacer("{id}", strconv.Itoa(id), "{codigo}", codigo).Replace(modelo)
+}
+
+// Registra os canais no painel e envia os alertas de produto esgotado;
+// roda até o contexto ser cancelado
+func iniciarCanaisNotificacao(ctx context.Context) {
+	for _, canal := range canaisNotificacao {
+		registrarIntegracao(canal)
+	}
+
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			a, ok := e.Dados.(AlertaEstoqueBaixo)
+			if e.Tipo != EventoEstoqueBaixo || !ok || a.Quantidade > 0 {
+				continue
+			}
+			notificarCanais(ctx, MensagemNotificacao{
+				Titulo:    "Produto esgotado",
+				Texto:     fmt.Sprintf("%s %s está sem saldo (mínimo %d).", a.Codigo, a.Nome, a.QuantidadeMinima),
+				Link:      linkProduto(ctx, a.ProdutoID, a.Codigo),
+				LinkTexto: "Ver produto",
+			})
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// chaos.go - Middleware de simulação de latência e falhas (somente desenvolvimento)
+//
+// Permite ao time mobile testar o comportamento do app em redes lentas ou
+// instáveis. Fica desligado a menos que CHAOS_ENABLED=true seja definido, e
+// só com PERFIL=desenvolvimento ou teste: em produção (o perfil padrão) o
+// servidor recusa iniciar com o chaos ligado.
+// Com ele ligado, os valores padrão vêm das variáveis de ambiente e podem ser
+// sobrescritos por requisição com os headers:
+//
+//	X-Chaos-Latencia: 1500   (milissegundos de atraso)
+//	X-Chaos-Erro:     0.2    (probabilidade de responder 500)
+//	X-Chaos-Queda:    0.1    (probabilidade de derrubar a conexão)
+//	X-Chaos:          off    (desativa o chaos nesta requisição)
+
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração do chaos - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	chaosEnabled    = getEnv("CHAOS_ENABLED", "false") == "true"
+	chaosLatenciaMs = getEnvAsInt("CHAOS_LATENCIA_MS", 0)
+	chaosProbErro   = getEnvAsFloat("CHAOS_PROB_ERRO", 0)
+	chaosProbQueda  = getEnvAsFloat("CHAOS_PROB_QUEDA", 0)
+)
+
+// Limite de latência artificial para não prender conexões indefinidamente
+const chaosLatenciaMaxMs = 30000
+
+// Função auxiliar para ler um header numérico com valor padrão
+func chaosHeaderFloat(c *gin.Context, header string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(c.GetHeader(header), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// Chaos middleware
+func Chaos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Chaos") == "off" {
+			c.Next()
+			return
+		}
+
+		latencia := int(chaosHeaderFloat(c, "X-Chaos-Latencia", float64(chaosLatenciaMs)))
+		probErro := chaosHeaderFloat(c, "X-Chaos-Erro", chaosProbErro)
+		probQueda := chaosHeaderFloat(c, "X-Chaos-Queda", chaosProbQueda)
+
+		// Latência artificial
+		if latencia > 0 {
+			if latencia > chaosLatenciaMaxMs {
+				latencia = chaosLatenciaMaxMs
+			}
+			log.Printf("[CHAOS] Atrasando %s %s em %dms", c.Request.Method, c.Request.URL.Path, latencia)
+			time.Sleep(time.Duration(latencia) * time.Millisecond)
+		}
+
+		// Queda de conexão: fecha o socket sem enviar resposta
+		if probQueda > 0 && rand.Float64() < probQueda {
+			log.Printf("[CHAOS] Derrubando conexão de %s %s", c.Request.Method, c.Request.URL.Path)
+			if hijacker, ok := c.Writer.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					conn.Close()
+					c.Abort()
+					return
+				}
+			}
+			// Sem suporte a hijack, degrada para erro 500
+			probErro = 1
+		}
+
+		// Erro 500 aleatório
+		if probErro > 0 && rand.Float64() < probErro {
+			log.Printf("[CHAOS] Simulando erro 500 em %s %s", c.Request.Method, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "Falha simulada (chaos)"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,514 @@
+// checklists.go - Checklists configuráveis em operações críticas
+//
+// As perguntas (sim/não) de cada operação são cadastradas em
+// checklist_perguntas. Um checklist aberto copia as perguntas ativas, recebe
+// as respostas item a item com o responsável e só pode ser concluído com todos
+// os itens respondidos. Saídas a partir de checklist_baixa_quantidade unidades
+// exigem um checklist de baixa concluído, que fica vinculado à movimentação
+// para auditoria.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Operações que usam checklist
+const (
+	OperacaoChecklistBaixa = "baixa"
+)
+
+// Status do checklist
+const (
+	ChecklistAberto    = "aberto"
+	ChecklistConcluido = "concluido"
+)
+
+var operacoesChecklist = map[string]bool{
+	OperacaoChecklistBaixa: true,
+}
+
+var (
+	errChecklistObrigatorio   = errors.New("saídas a partir do limite configurado exigem checklist de baixa concluído")
+	errChecklistNaoEncontrado = errors.New("checklist de baixa não encontrado")
+	errChecklistPendente      = errors.New("checklist ainda não foi concluído")
+	errChecklistUtilizado     = errors.New("checklist já vinculado a outra movimentação")
+)
+
+type PerguntaChecklist struct {
+	ID          int       `json:"id,omitempty"`
+	Operacao    string    `json:"operacao"`
+	Pergunta    string    `json:"pergunta"`
+	Ordem       int       `json:"ordem"`
+	Ativo       bool      `json:"ativo"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+type ItemChecklist struct {
+	ID           int        `json:"id"`
+	Pergunta     string     `json:"pergunta"`
+	Resposta     *bool      `json:"resposta"`
+	Responsavel  string     `json:"responsavel,omitempty"`
+	DataResposta *time.Time `json:"data_resposta,omitempty"`
+}
+
+type Checklist struct {
+	ID             int             `json:"id"`
+	Operacao       string          `json:"operacao"`
+	Responsavel    string          `json:"responsavel"`
+	Status         string          `json:"status"`
+	MovimentacaoID *int            `json:"movimentacao_id,omitempty"`
+	DataCriacao    time.Time       `json:"data_criacao"`
+	DataConclusao  *time.Time      `json:"data_conclusao,omitempty"`
+	Itens          []ItemChecklist `json:"itens"`
+}
+
+// Exige e vincula o checklist de baixa de uma saída. Deve rodar na transação
+// da movimentação, depois do INSERT (usa m.ID).
+func vincularChecklistMovimentacao(ctx context.Context, q querier, m *Movimentacao) error {
+	if m.Tipo != "saida" {
+		return nil
+	}
+
+	limite, err := strconv.Atoi(lerConfiguracao(ctx, "checklist_baixa_quantidade", "0"))
+	if err != nil {
+		limite = 0
+	}
+	if m.ChecklistID == nil {
+		if limite > 0 && m.Quantidade >= limite {
+			return errChecklistObrigatorio
+		}
+		return nil
+	}
+
+	var status string
+	var movimentacaoID *int
+	err = q.QueryRow(ctx, `
+		SELECT status, movimentacao_id FROM checklists
+		WHERE id = $1 AND operacao = $2
+		FOR UPDATE
+	`, *m.ChecklistID, OperacaoChecklistBaixa).Scan(&status, &movimentacaoID)
+	if err == pgx.ErrNoRows {
+		return errChecklistNaoEncontrado
+	}
+	if err != nil {
+		return err
+	}
+	if status != ChecklistConcluido {
+		return errChecklistPendente
+	}
+	if movimentacaoID != nil {
+		return errChecklistUtilizado
+	}
+
+	_, err = q.Exec(ctx, "UPDATE checklists SET movimentacao_id = $1 WHERE id = $2", m.ID, *m.ChecklistID)
+	return err
+}
+
+func erroChecklistValidacao(err error) bool {
+	return err == errChecklistObrigatorio || err == errChecklistNaoEncontrado ||
+		err == errChecklistPendente || err == errChecklistUtilizado
+}
+
+// Função auxiliar para carregar um checklist com seus itens
+func carregarChecklist(ctx context.Context, q querier, id int) (Checklist, error) {
+	var ch Checklist
+	err := q.QueryRow(ctx, `
+		SELECT id, operacao, responsavel, status, movimentacao_id, data_criacao, data_conclusao
+		FROM checklists
+		WHERE id = $1
+	`, id).Scan(&ch.ID, &ch.Operacao, &ch.Responsavel, &ch.Status, &ch.MovimentacaoID, &ch.DataCriacao, &ch.DataConclusao)
+	if err != nil {
+		return ch, err
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, pergunta, resposta, responsavel, data_resposta
+		FROM checklists_itens
+		WHERE checklist_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return ch, err
+	}
+	defer rows.Close()
+
+	ch.Itens = []ItemChecklist{}
+	for rows.Next() {
+		var item ItemChecklist
+		var responsavel *string
+		if err := rows.Scan(&item.ID, &item.Pergunta, &item.Resposta, &responsavel, &item.DataResposta); err != nil {
+			return ch, err
+		}
+
+		// Tratar campos nulos
+		if responsavel != nil {
+			item.Responsavel = *responsavel
+		}
+		ch.Itens = append(ch.Itens, item)
+	}
+	return ch, rows.Err()
+}
+
+// Handlers de Perguntas de Checklist
+
+func getPerguntasChecklist(c *gin.Context) {
+	operacao := c.Query("operacao")
+	log.Printf("[DB] Buscando perguntas de checklist (operação: %s)", operacao)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, operacao, pergunta, ordem, ativo, data_criacao
+		FROM checklist_perguntas
+		WHERE $1 = '' OR operacao = $1
+		ORDER BY operacao, ordem, id
+	`, operacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar perguntas de checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar perguntas de checklist"})
+		return
+	}
+	defer rows.Close()
+
+	perguntas := []PerguntaChecklist{}
+	for rows.Next() {
+		var p PerguntaChecklist
+		if err := rows.Scan(&p.ID, &p.Operacao, &p.Pergunta, &p.Ordem, &p.Ativo, &p.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar pergunta de checklist: %v", err)
+			continue
+		}
+		perguntas = append(perguntas, p)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar perguntas de checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar perguntas de checklist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, perguntas)
+}
+
+func criarPerguntaChecklist(c *gin.Context) {
+	log.Println("[API] Iniciando criação de pergunta de checklist")
+
+	p := PerguntaChecklist{Ativo: true}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if !operacoesChecklist[p.Operacao] || strings.TrimSpace(p.Pergunta) == "" {
+		log.Printf("[ERROR] Pergunta de checklist inválida. Operação: '%s'", p.Operacao)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Operação (baixa) e pergunta são obrigatórias"})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO checklist_perguntas(operacao, pergunta, ordem, ativo)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, data_criacao
+	`, p.Operacao, p.Pergunta, p.Ordem, p.Ativo).Scan(&p.ID, &p.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar pergunta de checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar pergunta de checklist"})
+		return
+	}
+
+	log.Printf("[DB] Pergunta de checklist criada (ID: %d, operação: %s)", p.ID, p.Operacao)
+	c.JSON(http.StatusCreated, p)
+}
+
+func atualizarPerguntaChecklist(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var p PerguntaChecklist
+	if err := c.ShouldBindJSON(&p); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if strings.TrimSpace(p.Pergunta) == "" {
+		log.Println("[ERROR] Pergunta de checklist vazia")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Pergunta é obrigatória"})
+		return
+	}
+
+	// Alterações não afetam checklists já abertos, que guardam a própria cópia
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE checklist_perguntas SET pergunta = $1, ordem = $2, ativo = $3
+		WHERE id = $4
+		RETURNING id, operacao, data_criacao
+	`, p.Pergunta, p.Ordem, p.Ativo, id).Scan(&p.ID, &p.Operacao, &p.DataCriacao)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pergunta de checklist não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pergunta não encontrada"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar pergunta de checklist: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar pergunta de checklist"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Pergunta de checklist atualizada com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, p)
+}
+
+// Handlers de Checklists
+
+func criarChecklist(c *gin.Context) {
+	log.Println("[API] Iniciando abertura de checklist")
+
+	var req struct {
+		Operacao    string `json:"operacao"`
+		Responsavel string `json:"responsavel"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	req.Responsavel = strings.TrimSpace(req.Responsavel)
+	if !operacoesChecklist[req.Operacao] || req.Responsavel == "" {
+		log.Printf("[ERROR] Checklist inválido. Operação: '%s', Responsável: '%s'", req.Operacao, req.Responsavel)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Operação (baixa) e responsável são obrigatórios"})
+		return
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context()) // Rollback caso ocorra algum erro
+
+	var id int
+	err = tx.QueryRow(c.Request.Context(), `
+		INSERT INTO checklists(operacao, responsavel)
+		VALUES ($1, $2)
+		RETURNING id
+	`, req.Operacao, req.Responsavel).Scan(&id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar checklist"})
+		return
+	}
+
+	// Copiar as perguntas ativas da operação
+	tag, err := tx.Exec(c.Request.Context(), `
+		INSERT INTO checklists_itens(checklist_id, pergunta)
+		SELECT $1, pergunta FROM checklist_perguntas
+		WHERE operacao = $2 AND ativo
+		ORDER BY ordem, id
+	`, id, req.Operacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar itens do checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar itens do checklist"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[ERROR] Nenhuma pergunta ativa para a operação: %s", req.Operacao)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nenhuma pergunta ativa cadastrada para a operação"})
+		return
+	}
+
+	ch, err := carregarChecklist(c.Request.Context(), tx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar checklist"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Checklist aberto (ID: %d, operação: %s, %d itens)", id, req.Operacao, len(ch.Itens))
+	c.JSON(http.StatusCreated, ch)
+}
+
+func getChecklist(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	ch, err := carregarChecklist(c.Request.Context(), db, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Checklist não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Checklist não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar checklist: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar checklist"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ch)
+}
+
+func responderItemChecklist(c *gin.Context) {
+	// Obter IDs da URL
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", c.Param("id"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		log.Printf("[ERROR] ID de item inválido: %s", c.Param("item_id"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID de item inválido"})
+		return
+	}
+
+	var req struct {
+		Resposta    *bool  `json:"resposta"`
+		Responsavel string `json:"responsavel"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	req.Responsavel = strings.TrimSpace(req.Responsavel)
+	if req.Resposta == nil || req.Responsavel == "" {
+		log.Printf("[ERROR] Resposta de checklist incompleta para o item %d", itemID)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Resposta (sim/não) e responsável são obrigatórios"})
+		return
+	}
+
+	// Itens de checklists concluídos não podem mais ser alterados
+	tag, err := db.Exec(c.Request.Context(), `
+		UPDATE checklists_itens i SET resposta = $1, responsavel = $2, data_resposta = CURRENT_TIMESTAMP
+		FROM checklists ch
+		WHERE i.id = $3 AND i.checklist_id = $4 AND ch.id = i.checklist_id AND ch.status = $5
+	`, *req.Resposta, req.Responsavel, itemID, id, ChecklistAberto)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao responder item do checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao responder item do checklist"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Item %d não encontrado em checklist aberto ID: %d", itemID, id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Item não encontrado em checklist aberto"})
+		return
+	}
+
+	ch, err := carregarChecklist(c.Request.Context(), db, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar checklist"})
+		return
+	}
+
+	log.Printf("[DB] Item %d do checklist ID: %d respondido por %s", itemID, id, req.Responsavel)
+	c.JSON(http.StatusOK, ch)
+}
+
+func concluirChecklist(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context()) // Rollback caso ocorra algum erro
+
+	var status string
+	err = tx.QueryRow(c.Request.Context(), "SELECT status FROM checklists WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Checklist não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Checklist não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar checklist: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar checklist"})
+		}
+		return
+	}
+	if status != ChecklistAberto {
+		log.Printf("[ERROR] Checklist ID: %d já está %s", id, status)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Checklist já foi concluído"})
+		return
+	}
+
+	// Bloquear a conclusão enquanto houver item sem resposta
+	var pendentes int
+	err = tx.QueryRow(c.Request.Context(), "SELECT COUNT(*) FROM checklists_itens WHERE checklist_id = $1 AND resposta IS NULL", id).Scan(&pendentes)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar itens pendentes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar itens pendentes"})
+		return
+	}
+	if pendentes > 0 {
+		log.Printf("[ERROR] Checklist ID: %d com %d itens sem resposta", id, pendentes)
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Todos os itens do checklist devem ser respondidos antes da conclusão",
+			"pendentes": pendentes,
+		})
+		return
+	}
+
+	_, err = tx.Exec(c.Request.Context(), `
+		UPDATE checklists SET status = $1, data_conclusao = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, ChecklistConcluido, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao concluir checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao concluir checklist"})
+		return
+	}
+
+	ch, err := carregarChecklist(c.Request.Context(), tx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar checklist"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Checklist ID: %d concluído", id)
+	c.JSON(http.StatusOK, ch)
+}
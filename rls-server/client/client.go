@@ -0,0 +1,176 @@
+// client.go - Cliente Go da API de estoque (SDK)
+//
+// Uso:
+//
+//	c := client.New("http://localhost:8080", client.WithToken(token))
+//	produtos, err := c.Produtos.Listar(ctx)
+//
+// Requisições idempotentes (GET, PUT, DELETE) são repetidas em falhas de rede,
+// 429 e 5xx com espera exponencial.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client acessa a API /api do servidor de estoque
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	tentativas int
+	espera     time.Duration
+
+	Produtos      *ProdutosService
+	Movimentacoes *MovimentacoesService
+}
+
+// Option configura o Client em New
+type Option func(*Client)
+
+// WithHTTPClient substitui o http.Client padrão (timeout de 30s)
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.httpClient = h }
+}
+
+// WithToken envia o token no cabeçalho Authorization: Bearer
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetries define o número máximo de tentativas e a espera inicial entre elas
+func WithRetries(tentativas int, espera time.Duration) Option {
+	return func(c *Client) {
+		if tentativas < 1 {
+			tentativas = 1
+		}
+		c.tentativas = tentativas
+		c.espera = espera
+	}
+}
+
+// New cria um cliente para o servidor em baseURL (ex.: http://localhost:8080)
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		tentativas: 3,
+		espera:     200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Produtos = &ProdutosService{c: c}
+	c.Movimentacoes = &MovimentacoesService{c: c}
+	return c
+}
+
+// Erro retornado quando o servidor responde com status de erro
+type Erro struct {
+	Status    int
+	Codigo    string // ex.: PRODUTO_NAO_ENCONTRADO, ESTOQUE_INSUFICIENTE
+	Mensagem  string
+	RequestID string
+}
+
+func (e *Erro) Error() string {
+	return fmt.Sprintf("estoque: %d %s", e.Status, e.Mensagem)
+}
+
+// Função auxiliar para decidir se uma resposta pode ser repetida
+func repetivel(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Executa a requisição com retries e decodifica a resposta em saida (se não nil)
+func (c *Client) do(ctx context.Context, metodo, caminho string, entrada, saida any) error {
+	var corpo []byte
+	if entrada != nil {
+		var err error
+		corpo, err = json.Marshal(entrada)
+		if err != nil {
+			return err
+		}
+	}
+
+	// POST e PATCH não são idempotentes e não são repetidos, para evitar
+	// duplicidade
+	tentativas := c.tentativas
+	if metodo == http.MethodPost || metodo == http.MethodPatch {
+		tentativas = 1
+	}
+
+	var ultimoErr error
+	espera := c.espera
+	for tentativa := 1; tentativa <= tentativas; tentativa++ {
+		if tentativa > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(espera):
+			}
+			espera *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, metodo, c.baseURL+caminho, bytes.NewReader(corpo))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		if entrada != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ultimoErr = err
+			continue
+		}
+
+		ultimoErr = c.lerResposta(resp, saida)
+		if e, ok := ultimoErr.(*Erro); ok && repetivel(e.Status) {
+			continue
+		}
+		return ultimoErr
+	}
+	return ultimoErr
+}
+
+// Função auxiliar para ler a resposta, convertendo status de erro em *Erro
+func (c *Client) lerResposta(resp *http.Response, saida any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error     string `json:"error"`
+			Codigo    string `json:"code"`
+			RequestID string `json:"request_id"`
+		}
+		dados, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(dados, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(dados))
+		}
+		return &Erro{Status: resp.StatusCode, Codigo: e.Codigo, Mensagem: e.Error, RequestID: e.RequestID}
+	}
+
+	if saida == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(saida)
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Servidor de teste que responde com os status de respostas, um por
+// requisição (o último se repete), e conta as requisições recebidas
+func servidorStatus(t *testing.T, respostas ...int) (*Client, *atomic.Int32) {
+	t.Helper()
+	var chamadas atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(chamadas.Add(1))
+		status := respostas[min(n, len(respostas))-1]
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status >= 400 {
+			json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status), "code": "ERRO_TESTE"})
+			return
+		}
+		json.NewEncoder(w).Encode([]Produto{{ID: 1, Codigo: "PAR-0001", Nome: "Parafuso"}})
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL, WithRetries(3, time.Millisecond)), &chamadas
+}
+
+func TestRepeteEm5xxE429(t *testing.T) {
+	c, chamadas := servidorStatus(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+
+	produtos, err := c.Produtos.Listar(context.Background())
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if len(produtos) != 1 || produtos[0].Codigo != "PAR-0001" {
+		t.Errorf("produtos = %+v, esperado PAR-0001", produtos)
+	}
+	if n := chamadas.Load(); n != 3 {
+		t.Errorf("requisições = %d, esperado 3", n)
+	}
+}
+
+func TestDesisteDepoisDasTentativas(t *testing.T) {
+	c, chamadas := servidorStatus(t, http.StatusInternalServerError)
+
+	_, err := c.Produtos.Obter(context.Background(), 1)
+	var e *Erro
+	if !errors.As(err, &e) || e.Status != http.StatusInternalServerError || e.Codigo != "ERRO_TESTE" {
+		t.Fatalf("erro = %v, esperado *Erro 500 com código ERRO_TESTE", err)
+	}
+	if n := chamadas.Load(); n != 3 {
+		t.Errorf("requisições = %d, esperado 3", n)
+	}
+}
+
+func TestNaoRepeteMetodosNaoIdempotentes(t *testing.T) {
+	for _, metodo := range []string{http.MethodPost, http.MethodPatch} {
+		t.Run(metodo, func(t *testing.T) {
+			c, chamadas := servidorStatus(t, http.StatusServiceUnavailable, http.StatusOK)
+
+			err := c.do(context.Background(), metodo, "/movimentacoes", Movimentacao{ProdutoID: 1, Tipo: "entrada", Quantidade: 5}, nil)
+			var e *Erro
+			if !errors.As(err, &e) || e.Status != http.StatusServiceUnavailable {
+				t.Fatalf("erro = %v, esperado *Erro 503", err)
+			}
+			if n := chamadas.Load(); n != 1 {
+				t.Errorf("requisições = %d, esperado 1", n)
+			}
+		})
+	}
+}
+
+func TestNaoRepeteErroDoCliente(t *testing.T) {
+	c, chamadas := servidorStatus(t, http.StatusNotFound, http.StatusOK)
+
+	_, err := c.Produtos.ObterPorCodigo(context.Background(), "XYZ")
+	var e *Erro
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Fatalf("erro = %v, esperado *Erro 404", err)
+	}
+	if n := chamadas.Load(); n != 1 {
+		t.Errorf("requisições = %d, esperado 1", n)
+	}
+}
+
+// Servidor de teste com total movimentações paginadas por limit/offset
+func servidorMovimentacoes(t *testing.T, total int) (*Client, *[]int) {
+	t.Helper()
+	var offsets []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/movimentacoes" {
+			http.NotFound(w, r)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		offsets = append(offsets, offset)
+
+		pagina := []Movimentacao{}
+		for id := offset + 1; id <= min(offset+limit, total); id++ {
+			pagina = append(pagina, Movimentacao{ID: id, ProdutoID: 1, Tipo: "saida", Quantidade: 1})
+		}
+		json.NewEncoder(w).Encode(pagina)
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL), &offsets
+}
+
+func TestListarTodasPercorreAsPaginas(t *testing.T) {
+	c, offsets := servidorMovimentacoes(t, 2*tamanhoPagina+50)
+
+	var ids []int
+	err := c.Movimentacoes.ListarTodas(context.Background(), func(m Movimentacao) bool {
+		ids = append(ids, m.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if len(ids) != 2*tamanhoPagina+50 {
+		t.Fatalf("movimentações = %d, esperado %d", len(ids), 2*tamanhoPagina+50)
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("movimentação %d com ID %d, esperado %d", i, id, i+1)
+		}
+	}
+	if got := *offsets; len(got) != 3 || got[0] != 0 || got[1] != tamanhoPagina || got[2] != 2*tamanhoPagina {
+		t.Errorf("offsets = %v, esperado [0 %d %d]", got, tamanhoPagina, 2*tamanhoPagina)
+	}
+}
+
+func TestListarTodasPaginaCheiaNoFim(t *testing.T) {
+	// Com o total múltiplo do tamanho da página, só a página vazia encerra
+	c, offsets := servidorMovimentacoes(t, tamanhoPagina)
+
+	n := 0
+	err := c.Movimentacoes.ListarTodas(context.Background(), func(Movimentacao) bool {
+		n++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if n != tamanhoPagina || len(*offsets) != 2 {
+		t.Errorf("movimentações = %d em %d páginas, esperado %d em 2", n, len(*offsets), tamanhoPagina)
+	}
+}
+
+func TestListarTodasInterrompe(t *testing.T) {
+	c, offsets := servidorMovimentacoes(t, 3*tamanhoPagina)
+
+	n := 0
+	err := c.Movimentacoes.ListarTodas(context.Background(), func(Movimentacao) bool {
+		n++
+		return n < tamanhoPagina+10
+	})
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if n != tamanhoPagina+10 || len(*offsets) != 2 {
+		t.Errorf("movimentações = %d em %d páginas, esperado %d em 2", n, len(*offsets), tamanhoPagina+10)
+	}
+}
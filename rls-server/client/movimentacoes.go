@@ -0,0 +1,85 @@
+// movimentacoes.go - Serviço de movimentações do cliente
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type Movimentacao struct {
+	ID               int       `json:"id,omitempty"`
+	ProdutoID        int       `json:"produto_id"`
+	Tipo             string    `json:"tipo"` // 'entrada' ou 'saida'
+	Quantidade       int       `json:"quantidade"`
+	Notas            string    `json:"notas,omitempty"`
+	DataMovimentacao time.Time `json:"data_movimentacao,omitempty"`
+	Lote             string    `json:"lote,omitempty"`
+	Validade         string    `json:"validade,omitempty"`
+	Series           []string  `json:"series,omitempty"`
+	Unidade          string    `json:"unidade,omitempty"`
+	CustoUnitario    *float64  `json:"custo_unitario,omitempty"`
+
+	// Preenchidos apenas nas listagens
+	ProdutoCodigo string `json:"produto_codigo,omitempty"`
+	ProdutoNome   string `json:"produto_nome,omitempty"`
+}
+
+// Tamanho de página usado por ListarTodas
+const tamanhoPagina = 100
+
+// MovimentacoesService acessa /api/movimentacoes
+type MovimentacoesService struct {
+	c *Client
+}
+
+// Listar retorna uma página de movimentações, das mais recentes para as mais antigas
+func (s *MovimentacoesService) Listar(ctx context.Context, limit, offset int) ([]Movimentacao, error) {
+	var movimentacoes []Movimentacao
+	caminho := fmt.Sprintf("/movimentacoes?limit=%d&offset=%d", limit, offset)
+	err := s.c.do(ctx, http.MethodGet, caminho, nil, &movimentacoes)
+	return movimentacoes, err
+}
+
+// ListarTodas percorre todas as páginas chamando fn para cada movimentação.
+// Retornar false em fn interrompe a paginação.
+func (s *MovimentacoesService) ListarTodas(ctx context.Context, fn func(Movimentacao) bool) error {
+	for offset := 0; ; offset += tamanhoPagina {
+		pagina, err := s.Listar(ctx, tamanhoPagina, offset)
+		if err != nil {
+			return err
+		}
+		for _, m := range pagina {
+			if !fn(m) {
+				return nil
+			}
+		}
+		if len(pagina) < tamanhoPagina {
+			return nil
+		}
+	}
+}
+
+func (s *MovimentacoesService) Obter(ctx context.Context, id int) (*Movimentacao, error) {
+	var m Movimentacao
+	if err := s.c.do(ctx, http.MethodGet, fmt.Sprintf("/movimentacoes/%d", id), nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *MovimentacoesService) PorProduto(ctx context.Context, produtoID int) ([]Movimentacao, error) {
+	var movimentacoes []Movimentacao
+	err := s.c.do(ctx, http.MethodGet, fmt.Sprintf("/movimentacoes/produto/%d", produtoID), nil, &movimentacoes)
+	return movimentacoes, err
+}
+
+func (s *MovimentacoesService) Criar(ctx context.Context, m Movimentacao) (*Movimentacao, error) {
+	var criada Movimentacao
+	if err := s.c.do(ctx, http.MethodPost, "/movimentacoes", m, &criada); err != nil {
+		return nil, err
+	}
+	return &criada, nil
+}
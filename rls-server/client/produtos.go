@@ -0,0 +1,83 @@
+// produtos.go - Serviço de produtos do cliente
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Produto struct {
+	ID               int       `json:"id,omitempty"`
+	Codigo           string    `json:"codigo"`
+	Nome             string    `json:"nome"`
+	Descricao        string    `json:"descricao,omitempty"`
+	Quantidade       int       `json:"quantidade"`
+	QuantidadeMinima int       `json:"quantidade_minima,omitempty"`
+	QuantidadeMaxima int       `json:"quantidade_maxima,omitempty"`
+	Localizacao      string    `json:"localizacao,omitempty"`
+	Fornecedor       string    `json:"fornecedor,omitempty"`
+	Notas            string    `json:"notas,omitempty"`
+	DataCriacao      time.Time `json:"data_criacao,omitempty"`
+	DataAtualizacao  time.Time `json:"data_atualizacao,omitempty"`
+	ControlaSerie    bool      `json:"controla_serie"`
+	Categoria        string    `json:"categoria,omitempty"`
+	UnidadeMedida    string    `json:"unidade_medida,omitempty"`
+	PrecoCusto       float64   `json:"preco_custo"`
+}
+
+// ProdutosService acessa /api/produtos
+type ProdutosService struct {
+	c *Client
+}
+
+func (s *ProdutosService) Listar(ctx context.Context) ([]Produto, error) {
+	var produtos []Produto
+	err := s.c.do(ctx, http.MethodGet, "/produtos", nil, &produtos)
+	return produtos, err
+}
+
+func (s *ProdutosService) Obter(ctx context.Context, id int) (*Produto, error) {
+	var p Produto
+	if err := s.c.do(ctx, http.MethodGet, fmt.Sprintf("/produtos/%d", id), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *ProdutosService) ObterPorCodigo(ctx context.Context, codigo string) (*Produto, error) {
+	var p Produto
+	if err := s.c.do(ctx, http.MethodGet, "/produtos/codigo/"+url.PathEscape(codigo), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *ProdutosService) EstoqueBaixo(ctx context.Context) ([]Produto, error) {
+	var produtos []Produto
+	err := s.c.do(ctx, http.MethodGet, "/produtos/estoque-baixo", nil, &produtos)
+	return produtos, err
+}
+
+func (s *ProdutosService) Criar(ctx context.Context, p Produto) (*Produto, error) {
+	var criado Produto
+	if err := s.c.do(ctx, http.MethodPost, "/produtos", p, &criado); err != nil {
+		return nil, err
+	}
+	return &criado, nil
+}
+
+func (s *ProdutosService) Atualizar(ctx context.Context, id int, p Produto) (*Produto, error) {
+	var atualizado Produto
+	if err := s.c.do(ctx, http.MethodPut, fmt.Sprintf("/produtos/%d", id), p, &atualizado); err != nil {
+		return nil, err
+	}
+	return &atualizado, nil
+}
+
+func (s *ProdutosService) Excluir(ctx context.Context, id int) error {
+	return s.c.do(ctx, http.MethodDelete, fmt.Sprintf("/produtos/%d", id), nil, nil)
+}
@@ -0,0 +1,115 @@
+// codigo_barras.go - Geração de símbolos Code 128 e QR Code
+//
+// A codificação fica com boombuler/barcode (Code 128, que escolhe sozinho
+// entre os conjuntos A, B e C) e skip2/go-qrcode (QR Code, correção de erros
+// nível M). Aqui só se traduzem os símbolos para módulos e os erros para
+// mensagens da API; a renderização em PNG e PDF continua com as etiquetas.
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	barcode128 "github.com/boombuler/barcode/code128"
+	"github.com/skip2/go-qrcode"
+)
+
+// Limite de caracteres do Code 128 imposto pela biblioteca
+const code128MaxCaracteres = 80
+
+var (
+	errCodigoBarrasVazio     = errors.New("conteúdo vazio")
+	errCodigoBarrasCaractere = errors.New("caractere não suportado pelo Code 128 (use ASCII)")
+	errCodigoBarrasGrande    = errors.New("conteúdo grande demais para o Code 128 (máximo de 80 caracteres)")
+	errQRCodeGrande          = errors.New("conteúdo grande demais para o QR Code")
+)
+
+// Codifica o texto em Code 128 e devolve os módulos (true = barra), sem zona de silêncio
+func code128(texto string) ([]bool, error) {
+	if texto == "" {
+		return nil, errCodigoBarrasVazio
+	}
+	for _, r := range texto {
+		if r > 127 {
+			return nil, errCodigoBarrasCaractere
+		}
+	}
+	if len(texto) > code128MaxCaracteres {
+		return nil, errCodigoBarrasGrande
+	}
+
+	simbolo, err := barcode128.Encode(texto)
+	if err != nil {
+		return nil, err
+	}
+	limites := simbolo.Bounds()
+	modulos := make([]bool, limites.Dx())
+	for i := range modulos {
+		modulos[i] = escuro(simbolo.At(limites.Min.X+i, limites.Min.Y))
+	}
+	return modulos, nil
+}
+
+// Codifica o texto em QR Code e devolve a matriz [y][x] (true = escuro), sem zona de silêncio
+func qrCode(texto string) ([][]bool, error) {
+	if texto == "" {
+		return nil, errCodigoBarrasVazio
+	}
+	simbolo, err := qrcode.New(texto, qrcode.Medium)
+	if err != nil {
+		return nil, errQRCodeGrande
+	}
+	simbolo.DisableBorder = true
+	return simbolo.Bitmap(), nil
+}
+
+func escuro(c color.Color) bool {
+	return color.GrayModel.Convert(c).(color.Gray).Y < 0x80
+}
+
+// Renderiza o Code 128 em tons de cinza, com zona de silêncio de 10 módulos
+func imagemCode128(modulos []bool, larguraModulo, altura int) *image.Gray {
+	margem := 10 * larguraModulo
+	img := image.NewGray(image.Rect(0, 0, len(modulos)*larguraModulo+2*margem, altura+2*margem))
+	preencherBranco(img)
+	for i, escuro := range modulos {
+		if !escuro {
+			continue
+		}
+		for x := margem + i*larguraModulo; x < margem+(i+1)*larguraModulo; x++ {
+			for y := margem; y < margem+altura; y++ {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	return img
+}
+
+// Renderiza o QR Code em tons de cinza, com zona de silêncio de 4 módulos
+func imagemQRCode(modulos [][]bool, escala int) *image.Gray {
+	margem := 4 * escala
+	lado := len(modulos)*escala + 2*margem
+	img := image.NewGray(image.Rect(0, 0, lado, lado))
+	preencherBranco(img)
+	for y, linha := range modulos {
+		for x, escuro := range linha {
+			if !escuro {
+				continue
+			}
+			for dy := 0; dy < escala; dy++ {
+				for dx := 0; dx < escala; dx++ {
+					img.SetGray(margem+x*escala+dx, margem+y*escala+dy, color.Gray{})
+				}
+			}
+		}
+	}
+	return img
+}
+
+func preencherBranco(img *image.Gray) {
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+}
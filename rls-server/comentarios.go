@@ -0,0 +1,351 @@
+// comentarios.go - Comentários encadeados em produtos e pedidos
+//
+// Registro da conversa da equipe sobre um produto, pedido de compra ou pedido
+// de saída (requisição). Comentários podem responder a outro do mesmo
+// registro. Menções "@nome" no texto notificam por push os dispositivos
+// registrados com esse nome. Cada comentário novo é publicado no barramento
+// como comentario.criado.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Entidades que aceitam comentários e a tabela de cada uma
+var entidadesComentario = map[string]string{
+	"produto":       "produtos",
+	"pedido_compra": "pedidos_compra",
+	"pedido_saida":  "pedidos_saida",
+}
+
+var (
+	errEntidadeComentario = errors.New("entidade inválida (use produto, pedido_compra ou pedido_saida)")
+	errRegistroComentario = errors.New("registro não encontrado")
+	errComentarioPai      = errors.New("o comentário respondido não pertence a este registro")
+)
+
+var regexpMencao = regexp.MustCompile(`@([\p{L}\p{N}._-]+)`)
+
+type Comentario struct {
+	ID              int          `json:"id,omitempty"`
+	Entidade        string       `json:"entidade"`
+	EntidadeID      int          `json:"entidade_id"`
+	ComentarioPaiID *int         `json:"comentario_pai_id,omitempty"`
+	Autor           string       `json:"autor"`
+	Texto           string       `json:"texto"`
+	Mencoes         []string     `json:"mencoes"`
+	DataCriacao     time.Time    `json:"data_criacao,omitempty"`
+	DataAtualizacao *time.Time   `json:"data_atualizacao,omitempty"`
+	Respostas       []Comentario `json:"respostas,omitempty"`
+}
+
+// Extrai as menções do texto, sem repetição
+func extrairMencoes(texto string) []string {
+	mencoes := []string{}
+	vistas := map[string]bool{}
+	for _, m := range regexpMencao.FindAllStringSubmatch(texto, -1) {
+		nome := strings.ToLower(m[1])
+		if !vistas[nome] {
+			vistas[nome] = true
+			mencoes = append(mencoes, nome)
+		}
+	}
+	return mencoes
+}
+
+// Confere a entidade, o registro comentado e o comentário respondido
+func validarAlvoComentario(ctx context.Context, cm *Comentario) error {
+	tabela, ok := entidadesComentario[cm.Entidade]
+	if !ok {
+		return errEntidadeComentario
+	}
+
+	var existe bool
+	err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM "+tabela+" WHERE id = $1)", cm.EntidadeID).Scan(&existe)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		return errRegistroComentario
+	}
+
+	if cm.ComentarioPaiID != nil {
+		err = db.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM comentarios WHERE id = $1 AND entidade = $2 AND entidade_id = $3)
+		`, *cm.ComentarioPaiID, cm.Entidade, cm.EntidadeID).Scan(&existe)
+		if err != nil {
+			return err
+		}
+		if !existe {
+			return errComentarioPai
+		}
+	}
+	return nil
+}
+
+// Notifica por push os dispositivos com o nome mencionado
+func notificarMencoes(ctx context.Context, cm Comentario) {
+	if len(cm.Mencoes) == 0 {
+		return
+	}
+	n := notificacaoPush{
+		titulo: cm.Autor + " mencionou você",
+		corpo:  cm.Texto,
+		dados: map[string]any{
+			"tipo":          EventoComentarioCriado,
+			"entidade":      cm.Entidade,
+			"entidade_id":   cm.EntidadeID,
+			"comentario_id": cm.ID,
+		},
+	}
+	if err := enviarPushMencoes(ctx, cm.Mencoes, n); err != nil {
+		log.Printf("[ERROR] Erro ao notificar menções do comentário %d: %v", cm.ID, err)
+	}
+}
+
+// Função auxiliar para ler um comentário tratando campos nulos
+func scanComentario(row pgx.Row) (Comentario, error) {
+	var cm Comentario
+	err := row.Scan(&cm.ID, &cm.Entidade, &cm.EntidadeID, &cm.ComentarioPaiID, &cm.Autor, &cm.Texto,
+		&cm.Mencoes, &cm.DataCriacao, &cm.DataAtualizacao)
+	if cm.Mencoes == nil {
+		cm.Mencoes = []string{}
+	}
+	return cm, err
+}
+
+const comentarioColunas = `id, entidade, entidade_id, comentario_pai_id, autor, texto,
+		mencoes, data_criacao, data_atualizacao`
+
+// Monta a árvore de respostas a partir da lista em ordem cronológica
+func encadearComentarios(lista []Comentario) []Comentario {
+	filhos := map[int][]Comentario{}
+	for _, cm := range lista {
+		if cm.ComentarioPaiID != nil {
+			filhos[*cm.ComentarioPaiID] = append(filhos[*cm.ComentarioPaiID], cm)
+		}
+	}
+
+	var montar func(cm Comentario) Comentario
+	montar = func(cm Comentario) Comentario {
+		for _, f := range filhos[cm.ID] {
+			cm.Respostas = append(cm.Respostas, montar(f))
+		}
+		return cm
+	}
+
+	raizes := []Comentario{}
+	for _, cm := range lista {
+		if cm.ComentarioPaiID == nil {
+			raizes = append(raizes, montar(cm))
+		}
+	}
+	return raizes
+}
+
+// Handlers de Comentários
+
+func getComentarios(c *gin.Context) {
+	entidade := c.Query("entidade")
+	entidadeID, err := strconv.Atoi(c.Query("entidade_id"))
+	if _, ok := entidadesComentario[entidade]; !ok || err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe entidade (produto, pedido_compra ou pedido_saida) e entidade_id"})
+		return
+	}
+
+	log.Printf("[DB] Buscando comentários de %s %d", entidade, entidadeID)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT `+comentarioColunas+`
+		FROM comentarios
+		WHERE entidade = $1 AND entidade_id = $2
+		ORDER BY data_criacao, id
+	`, entidade, entidadeID)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar comentários: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar comentários"})
+		return
+	}
+	defer rows.Close()
+
+	lista := []Comentario{}
+	for rows.Next() {
+		cm, err := scanComentario(rows)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar comentário: %v", err)
+			continue
+		}
+		lista = append(lista, cm)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar comentários: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar comentários"})
+		return
+	}
+
+	c.JSON(http.StatusOK, encadearComentarios(lista))
+}
+
+func criarComentario(c *gin.Context) {
+	var cm Comentario
+	if err := c.ShouldBindJSON(&cm); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	cm.Autor = strings.TrimSpace(cm.Autor)
+	cm.Texto = strings.TrimSpace(cm.Texto)
+	if cm.Autor == "" || cm.Texto == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Autor e texto são obrigatórios"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := validarAlvoComentario(ctx, &cm); err != nil {
+		if err == errEntidadeComentario || err == errRegistroComentario || err == errComentarioPai {
+			log.Printf("[ERROR] Comentário inválido: %v", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Comentário: " + err.Error()})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar registro comentado: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar registro comentado"})
+		}
+		return
+	}
+	cm.Mencoes = extrairMencoes(cm.Texto)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	cm, err = scanComentario(tx.QueryRow(ctx, `
+		INSERT INTO comentarios(entidade, entidade_id, comentario_pai_id, autor, texto, mencoes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+comentarioColunas,
+		cm.Entidade, cm.EntidadeID, cm.ComentarioPaiID, cm.Autor, cm.Texto, cm.Mencoes))
+	if err == nil {
+		err = enfileirarEntregasWebhook(ctx, tx, EventoComentarioCriado, cm)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar comentário: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar comentário"})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Comentário criado em %s %d por %s (ID: %d)", cm.Entidade, cm.EntidadeID, cm.Autor, cm.ID)
+	eventos.publicar(EventoComentarioCriado, cm)
+	go notificarMencoes(context.Background(), cm)
+
+	c.JSON(http.StatusCreated, cm)
+}
+
+func atualizarComentario(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req struct {
+		Texto string `json:"texto"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Texto) == "" {
+		log.Printf("[ERROR] Dados inválidos para comentário ID: %d", id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O texto é obrigatório"})
+		return
+	}
+	texto := strings.TrimSpace(req.Texto)
+
+	// Menções já notificadas não são notificadas de novo
+	var anteriores []string
+	err = db.QueryRow(c.Request.Context(), "SELECT mencoes FROM comentarios WHERE id = $1", id).Scan(&anteriores)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Comentário não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Comentário não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar comentário: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar comentário"})
+		}
+		return
+	}
+
+	cm, err := scanComentario(db.QueryRow(c.Request.Context(), `
+		UPDATE comentarios SET texto = $1, mencoes = $2, data_atualizacao = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING `+comentarioColunas,
+		texto, extrairMencoes(texto), id))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar comentário: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar comentário"})
+		return
+	}
+
+	log.Printf("[DB] Comentário atualizado com sucesso! ID: %d", id)
+
+	novas := Comentario{ID: cm.ID, Entidade: cm.Entidade, EntidadeID: cm.EntidadeID, Autor: cm.Autor, Texto: cm.Texto}
+	for _, m := range cm.Mencoes {
+		notificada := false
+		for _, a := range anteriores {
+			notificada = notificada || a == m
+		}
+		if !notificada {
+			novas.Mencoes = append(novas.Mencoes, m)
+		}
+	}
+	go notificarMencoes(context.Background(), novas)
+
+	c.JSON(http.StatusOK, cm)
+}
+
+func deletarComentario(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// As respostas são excluídas em cascata
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM comentarios WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir comentário: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir comentário"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Comentário não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Comentário não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Comentário excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Comentário excluído com sucesso"})
+}
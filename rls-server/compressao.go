@@ -0,0 +1,294 @@
+// compressao.go - Compressão das respostas e ETag nas leituras
+//
+// Respostas de texto (JSON, CSV, HTML, XML...) com pelo menos
+// COMPRESSAO_MINIMO_BYTES saem comprimidas em brotli ou gzip, conforme o
+// Accept-Encoding do cliente (brotli tem preferência no empate). Arquivos já
+// comprimidos (PDF, imagens), downloads com Range, streams SSE e o WebSocket
+// passam sem compressão.
+//
+// As respostas 200 em JSON dos GET ganham um ETag fraco com o hash do corpo;
+// quando o cliente repete a consulta com If-None-Match igual, recebe 304 sem
+// corpo. É o caso da carga completa de GET /api/produtos na sincronização do
+// app: sem mudanças no estoque, só os cabeçalhos trafegam no Wi-Fi do
+// armazém. A consulta ao banco continua sendo feita; a economia é de rede.
+
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração da compressão - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	compressaoEnabled       = getEnv("COMPRESSAO_ENABLED", "true") == "true"
+	compressaoMinimoBytes   = getEnvAsInt("COMPRESSAO_MINIMO_BYTES", 1024)
+	compressaoNivelBrotli   = getEnvAsInt("COMPRESSAO_NIVEL_BROTLI", 4)
+	etagRespostasHabilitado = getEnv("ETAG_ENABLED", "true") == "true"
+)
+
+// Codificações suportadas, na ordem de preferência
+const (
+	codificacaoBrotli = "br"
+	codificacaoGzip   = "gzip"
+)
+
+// Escritores reaproveitados entre requisições
+var (
+	poolGzip   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	poolBrotli = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, compressaoNivelBrotli) }}
+)
+
+// Escolhe a codificação pelo Accept-Encoding (com pesos q); vazio = sem compressão
+func escolherCodificacao(aceitas string) string {
+	pesos := map[string]float64{}
+	for _, item := range strings.Split(aceitas, ",") {
+		nome, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		pesos[strings.ToLower(strings.TrimSpace(nome))] = q
+	}
+	escolhida, maior := "", 0.0
+	for _, cod := range []string{codificacaoBrotli, codificacaoGzip} {
+		q, ok := pesos[cod]
+		if !ok {
+			q, ok = pesos["*"]
+		}
+		if ok && q > maior {
+			escolhida, maior = cod, q
+		}
+	}
+	return escolhida
+}
+
+// Tipos de conteúdo que valem a pena comprimir
+func tipoComprimivel(contentType string) bool {
+	tipo, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(tipo, "text/") {
+		return tipo != "text/event-stream"
+	}
+	switch tipo {
+	case "application/json", "application/problem+json", "application/xml", "application/javascript",
+		"application/x-ndjson", "application/geo+json", "image/svg+xml",
+		"application/msgpack", "application/x-msgpack", "application/x-protobuf":
+		return true
+	}
+	return false
+}
+
+// Writer que acumula o início da resposta até decidir se comprime
+type escritorCompressao struct {
+	gin.ResponseWriter
+	codificacao string
+	buffer      []byte
+	decidido    bool
+	compressor  io.WriteCloser
+	devolver    func()
+}
+
+func (w *escritorCompressao) Write(b []byte) (int, error) {
+	if w.decidido {
+		if w.compressor != nil {
+			return w.compressor.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buffer = append(w.buffer, b...)
+	if len(w.buffer) < compressaoMinimoBytes {
+		return len(b), nil
+	}
+	if err := w.decidir(true); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *escritorCompressao) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Stream (SSE, arquivos grandes): decide com o que já chegou e envia
+func (w *escritorCompressao) Flush() {
+	if !w.decidido {
+		w.decidir(true)
+	}
+	if f, ok := w.compressor.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Decide se comprime e envia o que estava acumulado; grande indica que o
+// corpo atingiu o tamanho mínimo
+func (w *escritorCompressao) decidir(grande bool) error {
+	w.decidido = true
+	h := w.Header()
+	status := w.Status()
+	comprimir := grande && tipoComprimivel(h.Get("Content-Type")) &&
+		h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && h.Get("Accept-Ranges") == "" &&
+		status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+	if tipoComprimivel(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	if comprimir {
+		h.Set("Content-Encoding", w.codificacao)
+		h.Del("Content-Length")
+		if w.codificacao == codificacaoBrotli {
+			bw := poolBrotli.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.compressor, w.devolver = bw, func() { poolBrotli.Put(bw) }
+		} else {
+			gw := poolGzip.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.compressor, w.devolver = gw, func() { poolGzip.Put(gw) }
+		}
+	}
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	if w.compressor != nil {
+		_, err := w.compressor.Write(buffer)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
+}
+
+// Envia o que ficou acumulado e fecha o compressor
+func (w *escritorCompressao) finalizar() {
+	if !w.decidido && len(w.buffer) > 0 {
+		w.decidir(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+		w.devolver()
+	}
+}
+
+// Middleware de compressão das respostas
+func Compressao() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		codificacao := escolherCodificacao(c.GetHeader("Accept-Encoding"))
+		if codificacao == "" || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		w := &escritorCompressao{ResponseWriter: c.Writer, codificacao: codificacao}
+		c.Writer = w
+		defer func() {
+			w.finalizar()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// Writer que guarda a resposta JSON para calcular o ETag; outros conteúdos e
+// streams passam direto
+type escritorETag struct {
+	gin.ResponseWriter
+	buffer   []byte
+	guardar  bool
+	decidido bool
+}
+
+func (w *escritorETag) Write(b []byte) (int, error) {
+	if !w.decidido {
+		w.decidido = true
+		tipo, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		w.guardar = w.Status() == http.StatusOK && tipo == "application/json" && w.Header().Get("ETag") == ""
+	}
+	if w.guardar {
+		w.buffer = append(w.buffer, b...)
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *escritorETag) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *escritorETag) Flush() {
+	w.liberar()
+	w.ResponseWriter.Flush()
+}
+
+// Desiste do ETag e envia o que foi guardado
+func (w *escritorETag) liberar() {
+	w.decidido = true
+	if w.guardar {
+		w.guardar = false
+		w.ResponseWriter.Write(w.buffer)
+		w.buffer = nil
+	}
+}
+
+// Middleware de ETag nos GET: 304 quando If-None-Match confere com o corpo
+func ETagRespostas() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		w := &escritorETag{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+
+		if !w.guardar {
+			return
+		}
+		soma := sha256.Sum256(w.buffer)
+		etag := `W/"` + hex.EncodeToString(soma[:16]) + `"`
+		h := w.Header()
+		h.Set("ETag", etag)
+		if h.Get("Cache-Control") == "" {
+			// Sempre revalidar; a resposta depende do usuário e do momento
+			h.Set("Cache-Control", "private, no-cache")
+		}
+		if etagConfere(c.GetHeader("If-None-Match"), etag) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.guardar = false
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		w.liberar()
+	}
+}
+
+// Comparação fraca do If-None-Match (RFC 9110, 13.1.2)
+func etagConfere(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	alvo := strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(item), "W/") == alvo {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+// config.go - Carregamento da configuração do servidor
+//
+// Toda configuração lida com getEnv/getEnvAsInt/getEnvAsFloat passa por aqui,
+// com a precedência: flags de linha de comando > variáveis de ambiente >
+// arquivo de configuração > valor padrão. As flags usam o nome da variável em
+// minúsculas com hífens (--db-host=servidor; sem =valor vale true) e vêm
+// antes do subcomando. O arquivo é indicado por --config ou CONFIG_FILE e
+// aceita YAML ou TOML, lidos por internal/config: seções e blocos aninhados
+// viram prefixos ([db] host = "x" ou db:\n  host: x vira DB_HOST).
+//
+// No startup, validarConfiguracao recusa chaves desconhecidas no arquivo ou
+// nas flags e valores numéricos inválidos, e registra um resumo da
+// configuração efetiva com os segredos mascarados.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rlsautomacao/estoque/internal/config"
+)
+
+// Origem de um valor de configuração
+const (
+	OrigemConfigPadrao   = "padrão"
+	OrigemConfigArquivo  = "arquivo"
+	OrigemConfigAmbiente = "ambiente"
+	OrigemConfigFlag     = "flag"
+)
+
+type valorConfig struct {
+	valor  string
+	origem string
+}
+
+var configuracao struct {
+	once        sync.Once
+	mu          sync.Mutex
+	arquivo     string
+	flags       map[string]string
+	doArquivo   map[string]string
+	posicionais []string
+	erros       []string
+	efetiva     map[string]valorConfig // chaves consultadas e o valor usado
+}
+
+// Partes do nome que identificam segredos no resumo
+var marcadoresSegredo = []string{"PASSWORD", "SENHA", "TOKEN", "SEGREDO", "SECRET"}
+
+// Senha em DSN no formato chave=valor (host=x password=y)
+var padraoSenhaDSN = regexp.MustCompile(`(?i)\b(password|senha)=('[^']*'|\S+)`)
+
+// Carrega flags e arquivo na primeira consulta (as variáveis de pacote são
+// inicializadas antes de main, então isso não pode esperar por main)
+func carregarFontesConfig() {
+	configuracao.once.Do(func() {
+		configuracao.efetiva = map[string]valorConfig{}
+		configuracao.flags, configuracao.posicionais = lerFlagsConfig(os.Args[1:])
+
+		configuracao.arquivo = configuracao.flags["CONFIG"]
+		delete(configuracao.flags, "CONFIG")
+		if configuracao.arquivo == "" {
+			configuracao.arquivo = os.Getenv("CONFIG_FILE")
+		}
+		configuracao.doArquivo = map[string]string{}
+		if configuracao.arquivo != "" {
+			valores, err := config.LerArquivo(configuracao.arquivo)
+			if err != nil {
+				configuracao.erros = append(configuracao.erros, fmt.Sprintf("arquivo %s: %v", configuracao.arquivo, err))
+			}
+			configuracao.doArquivo = valores
+		}
+	})
+}
+
+// Converte o nome de uma flag ou chave de arquivo para o nome da variável
+func chaveConfig(nome string) string {
+	return config.Chave(nome)
+}
+
+// Lê as flags iniciais (até o primeiro argumento que não é flag)
+func lerFlagsConfig(args []string) (map[string]string, []string) {
+	flags := map[string]string{}
+	for i, arg := range args {
+		if arg == "--" {
+			return flags, args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return flags, args[i:]
+		}
+
+		// O valor vem sempre com =, para não confundir com o subcomando;
+		// sem ele a flag vale como verdadeira (--aquecimento-enabled)
+		nome, valor, temValor := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !temValor {
+			valor = "true"
+		}
+		flags[chaveConfig(nome)] = valor
+	}
+	return flags, nil
+}
+
+// Resolve uma chave pela precedência e registra o valor efetivo
+func valorConfiguracao(chave, padrao string) string {
+	carregarFontesConfig()
+
+	v := valorConfig{valor: padrao, origem: OrigemConfigPadrao}
+	if valor, ok := configuracao.flags[chave]; ok {
+		v = valorConfig{valor: valor, origem: OrigemConfigFlag}
+	} else if valor := os.Getenv(chave); valor != "" {
+		v = valorConfig{valor: valor, origem: OrigemConfigAmbiente}
+	} else if valor, ok := configuracao.doArquivo[chave]; ok {
+		v = valorConfig{valor: valor, origem: OrigemConfigArquivo}
+	}
+
+	configuracao.mu.Lock()
+	configuracao.efetiva[chave] = v
+	configuracao.mu.Unlock()
+	return v.valor
+}
+
+// Registra um valor que não pôde ser convertido para o tipo esperado
+func erroConfiguracao(chave, valor, tipo string) {
+	configuracao.mu.Lock()
+	defer configuracao.mu.Unlock()
+	configuracao.erros = append(configuracao.erros, fmt.Sprintf("%s=%q não é %s", chave, valorLogConfig(chave, valor), tipo))
+}
+
+// Argumentos depois das flags de configuração (subcomando e seus argumentos)
+func argumentosPosicionais() []string {
+	carregarFontesConfig()
+	return configuracao.posicionais
+}
+
+func segredoConfig(chave string) bool {
+	for _, m := range marcadoresSegredo {
+		if strings.Contains(chave, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Valor para o log: segredos mascarados pelo nome da chave e, em qualquer
+// chave, as credenciais de URLs (usuario:senha@host) e DSNs (password=...)
+func valorLogConfig(chave, valor string) string {
+	if valor == "" {
+		return valor
+	}
+	if segredoConfig(chave) {
+		return "********"
+	}
+	if antes, depois, ok := strings.Cut(valor, "://"); ok && strings.Contains(depois, "@") {
+		if u, err := url.Parse(valor); err == nil && u.User != nil {
+			u.User = nil
+			return strings.Replace(u.String(), "://", "://********@", 1)
+		}
+		// Não é uma URL válida: descarta tudo até o último @ da autoridade
+		autoridade, caminho, temCaminho := strings.Cut(depois, "/")
+		if i := strings.LastIndex(autoridade, "@"); i >= 0 {
+			valor = antes + "://********@" + autoridade[i+1:]
+			if temCaminho {
+				valor += "/" + caminho
+			}
+		}
+	}
+	return padraoSenhaDSN.ReplaceAllString(valor, "$1=********")
+}
+
+// Valida a configuração carregada. Deve rodar em main, depois da
+// inicialização das variáveis de pacote (que registram as chaves conhecidas).
+func validarConfiguracao() error {
+	carregarFontesConfig()
+	configuracao.mu.Lock()
+	defer configuracao.mu.Unlock()
+
+	erros := append([]string{}, configuracao.erros...)
+	for _, fonte := range []struct {
+		nome    string
+		valores map[string]string
+	}{{"flag", configuracao.flags}, {"arquivo", configuracao.doArquivo}} {
+		for chave := range fonte.valores {
+			if _, ok := configuracao.efetiva[chave]; !ok {
+				erros = append(erros, fmt.Sprintf("%s desconhecida: %s", fonte.nome, chave))
+			}
+		}
+	}
+
+	if v := configuracao.efetiva["DB_USER"]; v.valor == "" {
+		erros = append(erros, "DB_USER não definido")
+	}
+	if v := configuracao.efetiva["DB_PASSWORD"]; v.valor == "" && !segredosExternos() {
+		erros = append(erros, "DB_PASSWORD não definido (ou use DB_PASSWORD_FILE/Vault)")
+	}
+	if dbPort <= 0 || dbPort > 65535 {
+		erros = append(erros, fmt.Sprintf("DB_PORT fora do intervalo: %d", dbPort))
+	}
+	if dbPoolMinConns < 0 || dbPoolMaxConns < 1 || dbPoolMinConns > dbPoolMaxConns {
+		erros = append(erros, fmt.Sprintf("pool inválido: DB_POOL_MIN_CONNS=%d, DB_POOL_MAX_CONNS=%d", dbPoolMinConns, dbPoolMaxConns))
+	}
+	switch perfilExecucao {
+	case PerfilProducao, PerfilDesenvolvimento, PerfilTeste:
+	default:
+		erros = append(erros, fmt.Sprintf("PERFIL=%q não é producao, desenvolvimento ou teste", perfilExecucao))
+	}
+	for _, p := range listaProxiesConfiaveis() {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				erros = append(erros, fmt.Sprintf("PROXIES_CONFIAVEIS: %q não é IP nem CIDR", p))
+			}
+		}
+	}
+	if chaosEnabled && perfilExecucao != PerfilDesenvolvimento && perfilExecucao != PerfilTeste {
+		erros = append(erros, "CHAOS_ENABLED exige PERFIL=desenvolvimento ou PERFIL=teste")
+	}
+
+	if len(erros) > 0 {
+		sort.Strings(erros)
+		return errors.New(strings.Join(erros, "; "))
+	}
+	return nil
+}
+
+// Registra a configuração efetiva no log, com os segredos mascarados
+func logarConfiguracao() {
+	configuracao.mu.Lock()
+	defer configuracao.mu.Unlock()
+
+	chaves := make([]string, 0, len(configuracao.efetiva))
+	for chave := range configuracao.efetiva {
+		chaves = append(chaves, chave)
+	}
+	sort.Strings(chaves)
+
+	if configuracao.arquivo != "" {
+		log.Printf("Configuração efetiva (arquivo %s):", configuracao.arquivo)
+	} else {
+		log.Printf("Configuração efetiva:")
+	}
+	for _, chave := range chaves {
+		v := configuracao.efetiva[chave]
+		log.Printf("- %s=%s (%s)", chave, valorLogConfig(chave, v.valor), v.origem)
+	}
+}
@@ -0,0 +1,243 @@
+// configuracoes_export.go - Exportação e importação de configurações entre ambientes
+//
+// GET /api/configuracoes/export gera um JSON versionado com as configurações,
+// sem os segredos (senhas, tokens). POST /api/configuracoes/import recebe esse
+// JSON e devolve o diff contra o banco atual; para aplicar, o cliente repete o
+// POST com ?confirmar=<hash do diff>, garantindo que o que foi revisado é o
+// que será gravado.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Versão do formato de exportação
+const versaoExportConfiguracoes = 1
+
+// Sufixos de chaves que guardam segredos e nunca são exportadas nem importadas
+var sufixosSecretos = []string{"_senha", "_token", "_segredo", "_secret"}
+
+type ConfiguracaoExportada struct {
+	Chave     string `json:"chave"`
+	Valor     string `json:"valor"`
+	Descricao string `json:"descricao,omitempty"`
+}
+
+type ExportConfiguracoes struct {
+	Versao        int                     `json:"versao"`
+	ExportadoEm   time.Time               `json:"exportado_em"`
+	Configuracoes []ConfiguracaoExportada `json:"configuracoes"`
+}
+
+type DiffConfiguracao struct {
+	Chave      string `json:"chave"`
+	Acao       string `json:"acao"` // criar, alterar ou igual
+	ValorAtual string `json:"valor_atual,omitempty"`
+	ValorNovo  string `json:"valor_novo"`
+}
+
+type ResultadoImportConfiguracoes struct {
+	Hash      string             `json:"hash"`
+	Aplicado  bool               `json:"aplicado"`
+	Diff      []DiffConfiguracao `json:"diff"`
+	Ignoradas []string           `json:"ignoradas,omitempty"`
+}
+
+// Indica se a chave guarda um segredo
+func configuracaoSecreta(chave string) bool {
+	for _, sufixo := range sufixosSecretos {
+		if strings.HasSuffix(chave, sufixo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Função auxiliar para ler as configurações atuais, sem os segredos
+func lerConfiguracoesExportaveis(ctx context.Context) ([]ConfiguracaoExportada, error) {
+	rows, err := db.Query(ctx, "SELECT chave, valor, descricao FROM configuracoes ORDER BY chave")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configuracoes := []ConfiguracaoExportada{}
+	for rows.Next() {
+		var conf ConfiguracaoExportada
+		var descricao *string
+		if err := rows.Scan(&conf.Chave, &conf.Valor, &descricao); err != nil {
+			return nil, err
+		}
+		if configuracaoSecreta(conf.Chave) {
+			continue
+		}
+
+		// Tratar campos nulos
+		if descricao != nil {
+			conf.Descricao = *descricao
+		}
+		configuracoes = append(configuracoes, conf)
+	}
+	return configuracoes, rows.Err()
+}
+
+// Compara o arquivo importado com as configurações atuais; o hash identifica o diff
+func diffConfiguracoes(atuais, novas []ConfiguracaoExportada) ([]DiffConfiguracao, string) {
+	porChave := map[string]string{}
+	for _, conf := range atuais {
+		porChave[conf.Chave] = conf.Valor
+	}
+
+	diff := []DiffConfiguracao{}
+	for _, conf := range novas {
+		d := DiffConfiguracao{Chave: conf.Chave, ValorNovo: conf.Valor}
+		if atual, ok := porChave[conf.Chave]; !ok {
+			d.Acao = "criar"
+		} else if atual != conf.Valor {
+			d.Acao = "alterar"
+			d.ValorAtual = atual
+		} else {
+			d.Acao = "igual"
+			d.ValorAtual = atual
+		}
+		diff = append(diff, d)
+	}
+
+	dados, _ := json.Marshal(diff)
+	soma := sha256.Sum256(dados)
+	return diff, hex.EncodeToString(soma[:8])
+}
+
+// Handlers de Exportação/Importação de Configurações
+
+func exportarConfiguracoes(c *gin.Context) {
+	log.Println("[DB] Exportando configurações")
+
+	configuracoes, err := lerConfiguracoesExportaveis(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao exportar configurações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao exportar configurações"})
+		return
+	}
+
+	nome := "configuracoes-" + time.Now().Format("20060102-1504") + ".json"
+	c.Set(chaveRegistrosExportacao, len(configuracoes))
+	c.Header("Content-Disposition", "attachment; filename="+nome)
+	c.JSON(http.StatusOK, ExportConfiguracoes{
+		Versao:        versaoExportConfiguracoes,
+		ExportadoEm:   time.Now(),
+		Configuracoes: configuracoes,
+	})
+}
+
+func importarConfiguracoes(c *gin.Context) {
+	var arquivo ExportConfiguracoes
+	if err := c.ShouldBindJSON(&arquivo); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	if arquivo.Versao != versaoExportConfiguracoes {
+		log.Printf("[ERROR] Versão de exportação não suportada: %d", arquivo.Versao)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Versão do arquivo não suportada"})
+		return
+	}
+
+	// Separar segredos e validar as chaves
+	var resultado ResultadoImportConfiguracoes
+	novas := []ConfiguracaoExportada{}
+	vistas := map[string]bool{}
+	for _, conf := range arquivo.Configuracoes {
+		conf.Chave = strings.TrimSpace(conf.Chave)
+		if conf.Chave == "" || len(conf.Chave) > 50 || conf.Valor == "" {
+			log.Printf("[ERROR] Configuração inválida na importação: %q", conf.Chave)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Configuração inválida: " + conf.Chave})
+			return
+		}
+		if vistas[conf.Chave] {
+			log.Printf("[ERROR] Configuração repetida na importação: %s", conf.Chave)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Configuração repetida: " + conf.Chave})
+			return
+		}
+		vistas[conf.Chave] = true
+
+		if configuracaoSecreta(conf.Chave) {
+			resultado.Ignoradas = append(resultado.Ignoradas, conf.Chave)
+			continue
+		}
+		novas = append(novas, conf)
+	}
+
+	ctx := c.Request.Context()
+	atuais, err := lerConfiguracoesExportaveis(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar configurações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar configurações"})
+		return
+	}
+	resultado.Diff, resultado.Hash = diffConfiguracoes(atuais, novas)
+
+	// Sem confirmação, apenas o diff
+	confirmar := c.Query("confirmar")
+	if confirmar == "" {
+		c.JSON(http.StatusOK, resultado)
+		return
+	}
+	if confirmar != resultado.Hash {
+		log.Printf("[WARN] Confirmação de importação não confere com o diff atual: %s", confirmar)
+		c.JSON(http.StatusConflict, resultado)
+		return
+	}
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar importação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	for _, conf := range novas {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO configuracoes (chave, valor, descricao)
+			VALUES ($1, $2, NULLIF($3, ''))
+			ON CONFLICT (chave) DO UPDATE SET
+				valor = EXCLUDED.valor,
+				descricao = COALESCE(EXCLUDED.descricao, configuracoes.descricao),
+				data_atualizacao = CURRENT_TIMESTAMP
+			WHERE configuracoes.valor <> EXCLUDED.valor
+				OR configuracoes.descricao IS DISTINCT FROM COALESCE(EXCLUDED.descricao, configuracoes.descricao)
+		`, conf.Chave, conf.Valor, conf.Descricao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao importar configuração %s: %v", conf.Chave, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar configuração " + conf.Chave})
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao confirmar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar importação"})
+		return
+	}
+
+	for _, conf := range novas {
+		guardarConfiguracao(conf.Chave, conf.Valor)
+	}
+
+	resultado.Aplicado = true
+	log.Printf("[DB] Importação de configurações aplicada: %d chaves (hash %s)", len(novas), resultado.Hash)
+	c.JSON(http.StatusOK, resultado)
+}
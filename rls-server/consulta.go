@@ -0,0 +1,117 @@
+// consulta.go - Consulta pública de saldo para os totens de autoatendimento
+//
+// GET /api/consulta/:codigo devolve só o necessário para o totem (saldo e
+// localização), com cache em memória e limite de requisições por IP. A rota é
+// somente leitura e não expõe custos, fornecedores ou notas.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Configuração da consulta - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	consultaCacheSegundos = getEnvAsInt("CONSULTA_CACHE_SEGUNDOS", 30)
+	consultaLimiteMinuto  = getEnvAsInt("CONSULTA_LIMITE_MINUTO", 60)
+)
+
+type ConsultaSaldo struct {
+	Codigo      string `json:"codigo"`
+	Nome        string `json:"nome"`
+	Quantidade  int    `json:"quantidade"`
+	Unidade     string `json:"unidade"`
+	Localizacao string `json:"localizacao,omitempty"`
+}
+
+type entradaConsulta struct {
+	saldo  ConsultaSaldo
+	expira time.Time
+}
+
+var (
+	consultaCacheMutex sync.Mutex
+	consultaCache      = map[string]entradaConsulta{}
+)
+
+// Contagem de requisições por IP na janela de um minuto corrente
+var (
+	consultaLimiteMutex sync.Mutex
+	consultaJanela      time.Time
+	consultaContagem    = map[string]int{}
+)
+
+// Middleware de limite de requisições por IP para a consulta pública
+func LimiteConsulta() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agora := time.Now().Truncate(time.Minute)
+
+		consultaLimiteMutex.Lock()
+		if !agora.Equal(consultaJanela) {
+			consultaJanela = agora
+			consultaContagem = map[string]int{}
+		}
+		consultaContagem[c.ClientIP()]++
+		excedido := consultaContagem[c.ClientIP()] > consultaLimiteMinuto
+		consultaLimiteMutex.Unlock()
+
+		if excedido {
+			log.Printf("[WARN] Limite de consultas excedido para %s", c.ClientIP())
+			c.Header("Retry-After", fmt.Sprint(int(time.Until(agora.Add(time.Minute)).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "Muitas consultas, aguarde um momento"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Handler para consulta de saldo
+
+func getConsultaSaldo(c *gin.Context) {
+	codigo := c.Param("codigo")
+	ttl := time.Duration(consultaCacheSegundos) * time.Second
+
+	consultaCacheMutex.Lock()
+	entrada, ok := consultaCache[codigo]
+	consultaCacheMutex.Unlock()
+
+	if !ok || time.Now().After(entrada.expira) {
+		var saldo ConsultaSaldo
+		var localizacao *string
+		err := db.QueryRow(c.Request.Context(), `
+			SELECT codigo, nome, quantidade, unidade_medida, localizacao
+			FROM produtos
+			WHERE codigo = $1
+		`, codigo).Scan(&saldo.Codigo, &saldo.Nome, &saldo.Quantidade, &saldo.Unidade, &localizacao)
+
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+			} else {
+				log.Printf("[ERROR] Erro na consulta de saldo: %v", err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro na consulta de saldo"})
+			}
+			return
+		}
+
+		// Tratar campos nulos
+		if localizacao != nil {
+			saldo.Localizacao = *localizacao
+		}
+
+		entrada = entradaConsulta{saldo: saldo, expira: time.Now().Add(ttl)}
+		consultaCacheMutex.Lock()
+		consultaCache[codigo] = entrada
+		consultaCacheMutex.Unlock()
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", consultaCacheSegundos))
+	c.JSON(http.StatusOK, entrada.saldo)
+}
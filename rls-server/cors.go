@@ -0,0 +1,132 @@
+// cors.go - Política de CORS configurável
+//
+// CORS_ORIGENS lista as origens aceitas, separadas por vírgulas. Cada origem é
+// esquema + host (+ porta), ex.: https://estoque.rlsautomacao.com.br, e pode
+// ter um curinga de subdomínio, ex.: https://*.rlsautomacao.com.br. O padrão
+// "*" aceita qualquer origem, como antes, mas sem credenciais: "*" com
+// Access-Control-Allow-Credentials é proibido pela especificação e o
+// navegador descarta a resposta. A autenticação da API é por Authorization,
+// que não depende de credenciais. Requisições de origens fora da lista recebem
+// 403.
+//
+// Com CORS_ESTRITO=true (produção) o startup recusa "*" e origens sem HTTPS,
+// exceto localhost.
+
+package main
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+)
+
+// Configuração do CORS - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	corsOrigens     = getEnv("CORS_ORIGENS", "*")
+	corsMetodos     = getEnv("CORS_METODOS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+	corsCabecalhos  = getEnv("CORS_CABECALHOS", "")
+	corsCredenciais = getEnv("CORS_CREDENCIAIS", "true") == "true"
+	corsEstrito     = getEnv("CORS_ESTRITO", "false") == "true"
+	corsMaxAgeHoras = getEnvAsInt("CORS_MAX_AGE_HORAS", 12)
+)
+
+// Cabeçalhos usados pelo cliente web e pelo SDK; CORS_CABECALHOS só acrescenta
+var (
+	cabecalhosCORS = []string{"Origin", "Content-Type", "Accept", "Authorization", "Range", "If-Range", "If-None-Match",
+		headerTreinamento, headerUsuario, headerVersaoAPI, headerRequestID, headerTraceparent}
+	cabecalhosExpostosCORS = []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", headerReprDigest,
+		headerTreinamento, headerVersaoAPI, headerDeprecacao, headerSunset, "Link", headerRequestID, headerCache}
+)
+
+var configCORS = configurarCORS()
+
+// Separa uma lista de configuração por vírgulas, sem itens vazios
+func listaConfiguracao(valor string) []string {
+	var itens []string
+	for _, item := range strings.Split(valor, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			itens = append(itens, item)
+		}
+	}
+	return itens
+}
+
+// Valida uma origem da lista: esquema + host, com no máximo um curinga de
+// subdomínio no início do host
+func origemValida(origem string) bool {
+	u, err := url.Parse(strings.Replace(origem, "*.", "curinga.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if u.Path != "" || u.RawQuery != "" || u.User != nil || strings.Count(origem, "*") > 1 {
+		return false
+	}
+	return !strings.Contains(origem, "*") || strings.HasPrefix(origem, u.Scheme+"://*.")
+}
+
+// Indica se a origem de uma requisição está entre as aceitas pelo CORS (usado
+// no handshake do WebSocket, que o navegador não submete ao CORS). Sem Origin
+// a requisição não vem de um navegador e é aceita
+func origemPermitida(origem string) bool {
+	if origem == "" || configCORS.AllowAllOrigins {
+		return true
+	}
+	for _, permitida := range configCORS.AllowOrigins {
+		if esquema, dominio, ok := strings.Cut(permitida, "://*."); ok {
+			if strings.HasPrefix(origem, esquema+"://") && strings.HasSuffix(origem, "."+dominio) {
+				return true
+			}
+		} else if origem == permitida {
+			return true
+		}
+	}
+	return false
+}
+
+// Monta a configuração do middleware a partir das variáveis de ambiente;
+// problemas são apontados por validarConfiguracao no startup
+func configurarCORS() cors.Config {
+	cfg := cors.Config{
+		AllowMethods:     listaConfiguracao(corsMetodos),
+		AllowHeaders:     append(cabecalhosCORS, listaConfiguracao(corsCabecalhos)...),
+		ExposeHeaders:    cabecalhosExpostosCORS,
+		AllowCredentials: corsCredenciais,
+		AllowWildcard:    true,
+		MaxAge:           time.Duration(max(corsMaxAgeHoras, 0)) * time.Hour,
+	}
+
+	origens := listaConfiguracao(corsOrigens)
+	for _, origem := range origens {
+		if origem == "*" {
+			if corsEstrito || len(origens) > 1 {
+				erroConfiguracao("CORS_ORIGENS", corsOrigens, "uma lista de origens explícitas (\"*\" não é aceito no modo estrito nem junto de outras origens)")
+				continue
+			}
+			cfg.AllowAllOrigins, cfg.AllowCredentials = true, false
+			continue
+		}
+		origem = strings.TrimSuffix(origem, "/")
+		if !origemValida(origem) {
+			erroConfiguracao("CORS_ORIGENS", origem, "uma origem esquema://host[:porta] (curinga só em esquema://*.dominio)")
+			continue
+		}
+		if corsEstrito && strings.HasPrefix(origem, "http://") {
+			if u, _ := url.Parse(origem); u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+				erroConfiguracao("CORS_ORIGENS", origem, "uma origem HTTPS (exigida no modo estrito)")
+				continue
+			}
+		}
+		cfg.AllowOrigins = append(cfg.AllowOrigins, origem)
+	}
+	if !cfg.AllowAllOrigins && len(cfg.AllowOrigins) == 0 {
+		erroConfiguracao("CORS_ORIGENS", corsOrigens, "uma lista com ao menos uma origem")
+		// Sem origem válida o middleware recusaria a configuração; o startup já vai falhar
+		cfg.AllowOrigins = []string{"http://localhost"}
+	}
+	if len(cfg.AllowMethods) == 0 {
+		erroConfiguracao("CORS_METODOS", corsMetodos, "uma lista de métodos HTTP")
+	}
+	return cfg
+}
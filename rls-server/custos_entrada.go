@@ -0,0 +1,109 @@
+// custos_entrada.go - Frete e impostos no custo de entrada do recebimento
+//
+// O recebimento de um pedido de compra pode informar frete e impostos da nota.
+// O total é rateado entre os itens recebidos proporcionalmente ao valor da
+// mercadoria (padrão) ou ao peso, e cada entrada é registrada com o custo
+// unitário já carregado da sua parcela. Assim o custo médio, o FIFO e os
+// relatórios de valorização passam a refletir o custo real.
+
+package main
+
+import (
+	"context"
+	"math"
+)
+
+// Critérios de rateio aceitos
+const (
+	RateioValor = "valor"
+	RateioPeso  = "peso"
+)
+
+// Despesas acessórias informadas no recebimento
+type DespesasRecebimento struct {
+	Frete    float64 `json:"frete,omitempty"`
+	Impostos float64 `json:"impostos,omitempty"`
+	Rateio   string  `json:"rateio,omitempty"` // valor ou peso
+}
+
+func (d DespesasRecebimento) total() float64 {
+	return d.Frete + d.Impostos
+}
+
+// Base de rateio de um item do pedido no recebimento
+type baseRateio struct {
+	quantidade int
+	// Valor da mercadoria informado pelo cliente; sem ele vale o custo atual
+	valor    float64
+	comCusto bool
+	peso     float64
+}
+
+// Função auxiliar para validar as despesas
+func validarDespesasRecebimento(d *DespesasRecebimento) string {
+	if d.Frete < 0 || d.Impostos < 0 {
+		return "Frete e impostos não podem ser negativos"
+	}
+	if d.Rateio == "" {
+		d.Rateio = RateioValor
+	}
+	if d.Rateio != RateioValor && d.Rateio != RateioPeso {
+		return "Rateio deve ser 'valor' ou 'peso'"
+	}
+	return ""
+}
+
+// Calcula o custo unitário de entrada de cada item (mercadoria + parcela das
+// despesas). Itens sem custo informado usam o custo atual do produto. Devolve
+// a mensagem de erro para o cliente quando o rateio não é possível.
+func custosEntradaRecebimento(ctx context.Context, q querier, itens []PedidoCompraItem, bases map[int]*baseRateio, d DespesasRecebimento) (map[int]float64, string, error) {
+	custos := map[int]float64{}
+	despesas := d.total()
+
+	var soma float64
+	for _, item := range itens {
+		b, ok := bases[item.ID]
+		if !ok {
+			continue
+		}
+		if !b.comCusto {
+			if despesas == 0 {
+				continue
+			}
+			var precoCusto float64
+			if err := q.QueryRow(ctx, "SELECT preco_custo FROM produtos WHERE id = $1", item.ProdutoID).Scan(&precoCusto); err != nil {
+				return nil, "", err
+			}
+			b.valor = precoCusto * float64(b.quantidade)
+		}
+		if d.Rateio == RateioPeso {
+			if despesas > 0 && b.peso <= 0 {
+				return nil, "Informe o peso de todos os itens para ratear por peso", nil
+			}
+			soma += b.peso
+		} else {
+			soma += b.valor
+		}
+	}
+	if despesas > 0 && soma <= 0 {
+		return nil, "Não há valor ou peso para ratear as despesas", nil
+	}
+
+	for _, item := range itens {
+		b, ok := bases[item.ID]
+		if !ok || (!b.comCusto && despesas == 0) {
+			continue
+		}
+		valor := b.valor
+		if despesas > 0 {
+			base := b.valor
+			if d.Rateio == RateioPeso {
+				base = b.peso
+			}
+			valor += despesas * base / soma
+		}
+		// Custo unitário com 4 casas, como em preco_custo
+		custos[item.ID] = math.Round(valor/float64(b.quantidade)*10000) / 10000
+	}
+	return custos, "", nil
+}
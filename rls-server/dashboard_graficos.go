@@ -0,0 +1,168 @@
+// dashboard_graficos.go - Agregados de gráficos do dashboard (consumo por categoria e entradas × saídas)
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formato de data aceito nos filtros de período (de/ate)
+const formatoData = "2006-01-02"
+
+type ConsumoCategoria struct {
+	Categoria  string  `json:"categoria"`
+	Quantidade int     `json:"quantidade"`
+	Percentual float64 `json:"percentual"`
+}
+
+type EntradasSaidasSemana struct {
+	Semana   time.Time `json:"semana"`
+	Entradas int       `json:"entradas"`
+	Saidas   int       `json:"saidas"`
+}
+
+type DashboardGraficos struct {
+	De                     time.Time              `json:"de"`
+	Ate                    time.Time              `json:"ate"`
+	ConsumoPorCategoria    []ConsumoCategoria     `json:"consumo_por_categoria"`
+	EntradasSaidasSemanais []EntradasSaidasSemana `json:"entradas_saidas_semanais"`
+}
+
+// Função auxiliar para ler o período da query string: de/ate (AAAA-MM-DD) ou
+// dias contados até hoje. O limite superior é exclusivo (dia seguinte a "ate").
+func lerPeriodo(c *gin.Context, diasPadrao int) (time.Time, time.Time, bool) {
+	agora := time.Now()
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, time.UTC)
+
+	ate := hoje
+	if ateStr := c.Query("ate"); ateStr != "" {
+		t, err := time.Parse(formatoData, ateStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		ate = t
+	}
+
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", strconv.Itoa(diasPadrao)))
+	if err != nil || dias <= 0 {
+		dias = diasPadrao
+	}
+	de := ate.AddDate(0, 0, -dias+1)
+	if deStr := c.Query("de"); deStr != "" {
+		t, err := time.Parse(formatoData, deStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		de = t
+	}
+
+	if de.After(ate) {
+		return time.Time{}, time.Time{}, false
+	}
+	return de, ate.AddDate(0, 0, 1), true
+}
+
+// Handler para gráficos do dashboard
+
+func getDashboardGraficos(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 90)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	log.Printf("[DB] Gerando gráficos do dashboard de %s a %s", de.Format(formatoData), ate.Format(formatoData))
+
+	graficos := DashboardGraficos{
+		De:                     de,
+		Ate:                    ate.AddDate(0, 0, -1),
+		ConsumoPorCategoria:    []ConsumoCategoria{},
+		EntradasSaidasSemanais: []EntradasSaidasSemana{},
+	}
+
+	// 1. Consumo (saídas) por categoria
+	rows, err := dbLeitura.Query(c.Request.Context(), `
+		SELECT COALESCE(p.categoria, 'Sem categoria') AS categoria, SUM(m.quantidade)
+		FROM movimentacoes m
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.tipo = 'saida'
+		  AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY 1
+		ORDER BY 2 DESC
+	`, de, ate)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar consumo por categoria: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar consumo por categoria"})
+		return
+	}
+
+	total := 0
+	for rows.Next() {
+		var cc ConsumoCategoria
+		if err := rows.Scan(&cc.Categoria, &cc.Quantidade); err != nil {
+			log.Printf("[WARN] Erro ao processar consumo por categoria: %v", err)
+			continue
+		}
+		total += cc.Quantidade
+		graficos.ConsumoPorCategoria = append(graficos.ConsumoPorCategoria, cc)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar consumo por categoria: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar consumo por categoria"})
+		return
+	}
+
+	for i := range graficos.ConsumoPorCategoria {
+		if total > 0 {
+			graficos.ConsumoPorCategoria[i].Percentual = float64(graficos.ConsumoPorCategoria[i].Quantidade) * 100 / float64(total)
+		}
+	}
+
+	// 2. Entradas × saídas por semana (semanas sem movimento aparecem zeradas)
+	rows, err = dbLeitura.Query(c.Request.Context(), `
+		SELECT s.semana,
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'entrada'), 0),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'saida'), 0)
+		FROM generate_series(date_trunc('week', $1::timestamp), $2::timestamp - interval '1 second', interval '1 week') AS s(semana)
+		LEFT JOIN movimentacoes m
+		       ON date_trunc('week', m.data_movimentacao) = s.semana
+		      AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY s.semana
+		ORDER BY s.semana
+	`, de, ate)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar entradas e saídas semanais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar entradas e saídas semanais"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var es EntradasSaidasSemana
+		if err := rows.Scan(&es.Semana, &es.Entradas, &es.Saidas); err != nil {
+			log.Printf("[WARN] Erro ao processar semana: %v", err)
+			continue
+		}
+		graficos.EntradasSaidasSemanais = append(graficos.EntradasSaidasSemanais, es)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar entradas e saídas semanais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar entradas e saídas semanais"})
+		return
+	}
+
+	log.Printf("[API] Gráficos gerados: %d categorias, %d semanas",
+		len(graficos.ConsumoPorCategoria), len(graficos.EntradasSaidasSemanais))
+	c.JSON(http.StatusOK, graficos)
+}
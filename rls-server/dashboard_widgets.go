@@ -0,0 +1,206 @@
+// dashboard_widgets.go - Widgets do dashboard calculados de forma independente
+//
+// Cada widget é uma consulta própria; GET /api/dashboard?widgets=a,b calcula
+// só os pedidos, em paralelo. Sem o parâmetro, o dashboard completo mantém o
+// formato de DashboardData.
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+type widgetDashboard func(ctx context.Context) (any, error)
+
+// Giro do estoque: saídas no período divididas pelo estoque atual
+type GiroEstoque struct {
+	Dias          int      `json:"dias"`
+	Saidas        int      `json:"saidas"`
+	EstoqueAtual  int      `json:"estoque_atual"`
+	Giro          float64  `json:"giro"`
+	CoberturaDias *float64 `json:"cobertura_dias,omitempty"`
+}
+
+// Widgets disponíveis, pelo nome usado em ?widgets= e na resposta
+var widgetsDashboard = map[string]widgetDashboard{
+	"total_produtos":        widgetTotalProdutos,
+	"total_itens":           widgetTotalItens,
+	"estoque_baixo":         widgetEstoqueBaixo,
+	"ultimas_movimentacoes": widgetUltimasMovimentacoes,
+	"top_produtos":          widgetTopProdutos,
+	"giro":                  widgetGiro,
+	"tarefas_pendentes":     widgetTarefasPendentes,
+}
+
+// Widgets que compõem o dashboard completo (DashboardData)
+var widgetsPadrao = []string{"total_produtos", "total_itens", "estoque_baixo", "ultimas_movimentacoes", "top_produtos", "tarefas_pendentes"}
+
+// Calcula os widgets em paralelo. Um widget com erro é logado e omitido da resposta.
+func calcularWidgets(ctx context.Context, nomes []string) map[string]any {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	resultado := map[string]any{}
+
+	for _, nome := range nomes {
+		widget, ok := widgetsDashboard[nome]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(nome string, widget widgetDashboard) {
+			defer wg.Done()
+			valor, err := widget(ctx)
+			if err != nil {
+				log.Printf("[WARN] Erro ao calcular widget %s: %v", nome, err)
+				return
+			}
+			mu.Lock()
+			resultado[nome] = valor
+			mu.Unlock()
+		}(nome, widget)
+	}
+	wg.Wait()
+
+	return resultado
+}
+
+// Função auxiliar para montar os dados do dashboard; erros parciais são apenas logados
+func montarDashboard(ctx context.Context) DashboardData {
+	widgets := calcularWidgets(ctx, widgetsPadrao)
+
+	dashboardData := DashboardData{}
+	if v, ok := widgets["total_produtos"].(int); ok {
+		dashboardData.TotalProdutos = v
+	}
+	if v, ok := widgets["total_itens"].(int); ok {
+		dashboardData.TotalItens = v
+	}
+	if v, ok := widgets["estoque_baixo"].(int); ok {
+		dashboardData.EstoqueBaixo = v
+	}
+	if v, ok := widgets["ultimas_movimentacoes"].([]MovimentacaoView); ok {
+		dashboardData.UltimasMovimentacoes = v
+	}
+	if v, ok := widgets["top_produtos"].([]ProdutoView); ok {
+		dashboardData.TopProdutos = v
+	}
+	if v, ok := widgets["tarefas_pendentes"].(PendenciasTarefas); ok {
+		dashboardData.TarefasPendentes = v
+	}
+	return dashboardData
+}
+
+// 1. Total de produtos
+func widgetTotalProdutos(ctx context.Context) (any, error) {
+	var total int
+	err := dbLeitura.QueryRow(ctx, "SELECT COUNT(*) FROM produtos").Scan(&total)
+	return total, err
+}
+
+// 2. Total de itens em estoque
+func widgetTotalItens(ctx context.Context) (any, error) {
+	var total int
+	err := dbLeitura.QueryRow(ctx, "SELECT COALESCE(SUM(quantidade), 0) FROM produtos").Scan(&total)
+	return total, err
+}
+
+// 3. Produtos com estoque baixo
+func widgetEstoqueBaixo(ctx context.Context) (any, error) {
+	var total int
+	err := dbLeitura.QueryRow(ctx, `
+		SELECT COUNT(*) FROM produtos
+		WHERE quantidade < COALESCE(quantidade_minima, 5) AND NOT kit
+	`).Scan(&total)
+	return total, err
+}
+
+// 4. Últimas movimentações
+func widgetUltimasMovimentacoes(ctx context.Context) (any, error) {
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT m.id, m.tipo, m.quantidade, m.data_movimentacao, m.notas,
+			   p.codigo as produto_codigo, p.nome as produto_nome
+		FROM movimentacoes m
+		JOIN produtos p ON m.produto_id = p.id
+		ORDER BY m.data_movimentacao DESC
+		LIMIT 10
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movimentacoes := []MovimentacaoView{}
+	for rows.Next() {
+		var m MovimentacaoView
+		var notas *string
+
+		err := rows.Scan(
+			&m.ID, &m.Tipo, &m.Quantidade, &m.DataMovimentacao, &notas,
+			&m.ProdutoCodigo, &m.ProdutoNome,
+		)
+		if err != nil {
+			log.Printf("[WARN] Erro ao processar movimentação: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if notas != nil {
+			m.Notas = *notas
+		}
+
+		movimentacoes = append(movimentacoes, m)
+	}
+	return movimentacoes, rows.Err()
+}
+
+// 5. Top produtos por quantidade
+func widgetTopProdutos(ctx context.Context) (any, error) {
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT codigo, nome, quantidade
+		FROM produtos
+		ORDER BY quantidade DESC
+		LIMIT 5
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topProdutos := []ProdutoView{}
+	for rows.Next() {
+		var p ProdutoView
+		if err := rows.Scan(&p.Codigo, &p.Nome, &p.Quantidade); err != nil {
+			log.Printf("[WARN] Erro ao processar produto: %v", err)
+			continue
+		}
+		topProdutos = append(topProdutos, p)
+	}
+	return topProdutos, rows.Err()
+}
+
+// 6. Giro do estoque nos últimos 30 dias
+func widgetGiro(ctx context.Context) (any, error) {
+	giro := GiroEstoque{Dias: 30}
+	err := dbLeitura.QueryRow(ctx, `
+		SELECT
+			(SELECT COALESCE(SUM(quantidade), 0) FROM movimentacoes
+			 WHERE tipo = 'saida' AND data_movimentacao >= CURRENT_TIMESTAMP - $1 * interval '1 day'),
+			(SELECT COALESCE(SUM(quantidade), 0) FROM produtos)
+	`, giro.Dias).Scan(&giro.Saidas, &giro.EstoqueAtual)
+	if err != nil {
+		return nil, err
+	}
+
+	if giro.EstoqueAtual > 0 {
+		giro.Giro = float64(giro.Saidas) / float64(giro.EstoqueAtual)
+	}
+	// Dias que o estoque atual dura no ritmo de saídas do período
+	if giro.Saidas > 0 {
+		cobertura := float64(giro.EstoqueAtual) * float64(giro.Dias) / float64(giro.Saidas)
+		giro.CoberturaDias = &cobertura
+	}
+	return giro, nil
+}
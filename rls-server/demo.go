@@ -0,0 +1,178 @@
+// demo.go - Dados de demonstração
+//
+// Com --seed-demo (SEED_DEMO=true) o servidor aplica as migrações, popula um
+// banco vazio com fornecedores, produtos e um mês de movimentações e
+// fotografias diárias do estoque, e sai. Serve para demonstrações e para o
+// desenvolvimento do app móvel; em um banco que já tem produtos nada é gravado.
+// Os dados são gerados com semente fixa, então duas cargas produzem o mesmo
+// resultado.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Configuração dos dados de demonstração - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var seedDemo = getEnv("SEED_DEMO", "false") == "true"
+
+// Dias de movimentações gerados
+const diasDemo = 30
+
+var errBancoComDados = errors.New("o banco já tem produtos; os dados de demonstração só são gravados em um banco vazio")
+
+type produtoDemo struct {
+	codigo, nome, categoria, unidade, localizacao, fornecedor string
+	minimo, maximo                                            int
+	custo                                                     float64
+	consumoDiario                                             int // saída média por dia
+	perigoso                                                  bool
+	classeRisco                                               string
+}
+
+var fornecedoresDemo = []struct {
+	nome  string
+	prazo int
+}{
+	{"Eletro Distribuidora Ltda", 5},
+	{"Automação Sul Comércio", 10},
+	{"Ferragens Paulista", 3},
+	{"Química Industrial Brasil", 7},
+	{"Embalagens Vale", 4},
+}
+
+var produtosDemo = []produtoDemo{
+	{"MOT-001", "Motor trifásico 1CV 220/380V", "Motores", "un", "A1-01", "Eletro Distribuidora Ltda", 4, 12, 890.00, 1, false, ""},
+	{"MOT-002", "Motor monofásico 1/2CV 220V", "Motores", "un", "A1-02", "Eletro Distribuidora Ltda", 3, 10, 540.00, 1, false, ""},
+	{"INV-001", "Inversor de frequência 2CV", "Acionamentos", "un", "A2-01", "Automação Sul Comércio", 2, 6, 1450.00, 1, false, ""},
+	{"CLP-001", "CLP compacto 16E/16S", "Automação", "un", "A2-03", "Automação Sul Comércio", 2, 5, 2100.00, 1, false, ""},
+	{"SEN-001", "Sensor indutivo M18 NPN", "Sensores", "un", "B1-01", "Automação Sul Comércio", 10, 40, 85.90, 3, false, ""},
+	{"SEN-002", "Sensor fotoelétrico difuso", "Sensores", "un", "B1-02", "Automação Sul Comércio", 8, 30, 129.00, 2, false, ""},
+	{"CON-001", "Contator 25A bobina 220V", "Comandos", "un", "B2-01", "Eletro Distribuidora Ltda", 10, 40, 96.50, 3, false, ""},
+	{"DIS-001", "Disjuntor bipolar 20A", "Proteção", "un", "B2-02", "Eletro Distribuidora Ltda", 15, 50, 42.30, 4, false, ""},
+	{"REL-001", "Relé térmico 9-13A", "Proteção", "un", "B2-03", "Eletro Distribuidora Ltda", 6, 20, 118.00, 2, false, ""},
+	{"CAB-001", "Cabo flexível 2,5mm² preto", "Cabos", "m", "C1-01", "Eletro Distribuidora Ltda", 200, 1000, 2.15, 40, false, ""},
+	{"CAB-002", "Cabo PP 3x1,5mm²", "Cabos", "m", "C1-02", "Eletro Distribuidora Ltda", 100, 500, 6.80, 20, false, ""},
+	{"TER-001", "Terminal tubular 2,5mm²", "Acessórios", "un", "C2-01", "Ferragens Paulista", 500, 3000, 0.12, 120, false, ""},
+	{"PAR-001", "Parafuso sextavado M8x30", "Fixação", "un", "D1-01", "Ferragens Paulista", 300, 1500, 0.45, 60, false, ""},
+	{"POR-001", "Porca sextavada M8", "Fixação", "un", "D1-02", "Ferragens Paulista", 300, 1500, 0.18, 60, false, ""},
+	{"ABR-001", "Abraçadeira nylon 200mm (pct 100)", "Acessórios", "caixa", "D2-01", "Ferragens Paulista", 10, 40, 18.90, 2, false, ""},
+	{"LUB-001", "Graxa de lítio 1kg", "Manutenção", "kg", "E1-01", "Química Industrial Brasil", 5, 20, 38.00, 1, false, ""},
+	{"SOL-001", "Solvente desengraxante 5L", "Manutenção", "L", "E1-02", "Química Industrial Brasil", 10, 40, 12.50, 2, true, "3 - Líquido inflamável"},
+	{"TIN-001", "Tinta esmalte cinza 3,6L", "Manutenção", "L", "E1-03", "Química Industrial Brasil", 8, 30, 24.90, 1, true, "3 - Líquido inflamável"},
+	{"EMB-001", "Caixa de papelão 40x30x30", "Embalagens", "un", "F1-01", "Embalagens Vale", 50, 300, 3.40, 15, false, ""},
+	{"EMB-002", "Fita adesiva transparente 48mm", "Embalagens", "un", "F1-02", "Embalagens Vale", 20, 100, 5.60, 4, false, ""},
+}
+
+// Popula um banco vazio com os dados de demonstração
+func popularDemo(ctx context.Context) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var existentes int
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM produtos").Scan(&existentes); err != nil {
+		return err
+	}
+	if existentes > 0 {
+		return errBancoComDados
+	}
+
+	for _, f := range fornecedoresDemo {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO fornecedores (nome, prazo_entrega_dias) VALUES ($1, $2)
+			ON CONFLICT (nome) DO NOTHING
+		`, f.nome, f.prazo)
+		if err != nil {
+			return fmt.Errorf("fornecedor %s: %w", f.nome, err)
+		}
+	}
+
+	aleatorio := rand.New(rand.NewSource(2552))
+	agora := time.Now()
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, time.Local)
+	inicio := hoje.AddDate(0, 0, -diasDemo)
+	movimentacoes := 0
+
+	for _, p := range produtosDemo {
+		var produtoID int
+		err := tx.QueryRow(ctx, `
+			INSERT INTO produtos (codigo, nome, categoria, unidade_medida, localizacao, fornecedor,
+				quantidade_minima, quantidade_maxima, preco_custo, perigoso, classe_risco, data_criacao)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12)
+			RETURNING id
+		`, p.codigo, p.nome, p.categoria, p.unidade, p.localizacao, p.fornecedor,
+			p.minimo, p.maximo, p.custo, p.perigoso, p.classeRisco, inicio).Scan(&produtoID)
+		if err != nil {
+			return fmt.Errorf("produto %s: %w", p.codigo, err)
+		}
+
+		inserir := func(data time.Time, tipo string, quantidade int, notas string) error {
+			var custo any
+			if tipo == "entrada" {
+				custo = p.custo
+			}
+			_, err := tx.Exec(ctx, `
+				INSERT INTO movimentacoes (produto_id, tipo, quantidade, notas, data_movimentacao, custo_unitario)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, produtoID, tipo, quantidade, notas, data, custo)
+			movimentacoes++
+			return err
+		}
+
+		// Estoque inicial perto do máximo, consumo diário em horário comercial
+		// e reposição até o máximo quando o saldo fica abaixo do mínimo
+		saldo := p.maximo - aleatorio.Intn(p.maximo-p.minimo+1)/2
+		if err := inserir(inicio.Add(8*time.Hour), "entrada", saldo, "Estoque inicial"); err != nil {
+			return fmt.Errorf("produto %s: %w", p.codigo, err)
+		}
+
+		for dia := 0; dia < diasDemo; dia++ {
+			data := inicio.AddDate(0, 0, dia)
+			if data.Weekday() != time.Saturday && data.Weekday() != time.Sunday {
+				saida := min(aleatorio.Intn(2*p.consumoDiario+1), saldo)
+				if saida > 0 {
+					hora := data.Add(time.Duration(8*60+aleatorio.Intn(9*60)) * time.Minute)
+					if err := inserir(hora, "saida", saida, "Consumo da produção"); err != nil {
+						return fmt.Errorf("produto %s: %w", p.codigo, err)
+					}
+					saldo -= saida
+				}
+				if saldo < p.minimo {
+					hora := data.Add(17 * time.Hour)
+					if err := inserir(hora, "entrada", p.maximo-saldo, "Reposição do fornecedor"); err != nil {
+						return fmt.Errorf("produto %s: %w", p.codigo, err)
+					}
+					saldo = p.maximo
+				}
+			}
+
+			_, err := tx.Exec(ctx, `
+				INSERT INTO estoque_snapshots (data, produto_id, quantidade) VALUES ($1, $2, $3)
+			`, data, produtoID, saldo)
+			if err != nil {
+				return fmt.Errorf("fotografia do produto %s: %w", p.codigo, err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE produtos SET quantidade = $1 WHERE id = $2", saldo, produtoID); err != nil {
+			return fmt.Errorf("produto %s: %w", p.codigo, err)
+		}
+	}
+
+	// Commit da transação
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("[DB] Dados de demonstração gravados: %d fornecedores, %d produtos, %d movimentações",
+		len(fornecedoresDemo), len(produtosDemo), movimentacoes)
+	return nil
+}
@@ -0,0 +1,248 @@
+// descarte.go - Produtos perigosos, locais de descarte e relatório de destinação
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	errDescarteMotivo = errors.New("informe o motivo da saída do produto perigoso")
+	errDescarteLocal  = errors.New("informe um local de descarte ativo")
+)
+
+type LocalDescarte struct {
+	ID               int       `json:"id,omitempty"`
+	Nome             string    `json:"nome"`
+	LicencaAmbiental string    `json:"licenca_ambiental,omitempty"`
+	Endereco         string    `json:"endereco,omitempty"`
+	Ativo            bool      `json:"ativo"`
+	DataCriacao      time.Time `json:"data_criacao,omitempty"`
+}
+
+type ItemDestinacao struct {
+	LocalDescarteID  int      `json:"local_descarte_id"`
+	LocalDescarte    string   `json:"local_descarte"`
+	LicencaAmbiental string   `json:"licenca_ambiental,omitempty"`
+	ProdutoID        int      `json:"produto_id"`
+	ProdutoCodigo    string   `json:"produto_codigo"`
+	ProdutoNome      string   `json:"produto_nome"`
+	ClasseRisco      string   `json:"classe_risco,omitempty"`
+	Unidade          string   `json:"unidade"`
+	Quantidade       int      `json:"quantidade"`
+	Movimentacoes    int      `json:"movimentacoes"`
+	Motivos          []string `json:"motivos"`
+}
+
+type RelatorioDestinacao struct {
+	De    time.Time        `json:"de"`
+	Ate   time.Time        `json:"ate"`
+	Itens []ItemDestinacao `json:"itens"`
+}
+
+// Valida motivo e local de descarte de uma saída de produto perigoso
+func validarSaidaPerigosa(ctx context.Context, q querier, m *Movimentacao) error {
+	m.Motivo = strings.TrimSpace(m.Motivo)
+	if m.Motivo == "" {
+		return errDescarteMotivo
+	}
+	if m.LocalDescarteID == nil {
+		return errDescarteLocal
+	}
+
+	var ativo bool
+	err := q.QueryRow(ctx, "SELECT ativo FROM locais_descarte WHERE id = $1", *m.LocalDescarteID).Scan(&ativo)
+	if err == pgx.ErrNoRows || (err == nil && !ativo) {
+		return errDescarteLocal
+	}
+	return err
+}
+
+// Handlers de Locais de Descarte
+
+func getLocaisDescarte(c *gin.Context) {
+	log.Println("[DB] Buscando locais de descarte")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, nome, licenca_ambiental, endereco, ativo, data_criacao
+		FROM locais_descarte
+		ORDER BY nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar locais de descarte: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar locais de descarte"})
+		return
+	}
+	defer rows.Close()
+
+	locais := []LocalDescarte{}
+	for rows.Next() {
+		var l LocalDescarte
+		var licenca, endereco *string
+		if err := rows.Scan(&l.ID, &l.Nome, &licenca, &endereco, &l.Ativo, &l.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar local de descarte: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if licenca != nil {
+			l.LicencaAmbiental = *licenca
+		}
+		if endereco != nil {
+			l.Endereco = *endereco
+		}
+		locais = append(locais, l)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar locais de descarte: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar locais de descarte"})
+		return
+	}
+
+	c.JSON(http.StatusOK, locais)
+}
+
+func criarLocalDescarte(c *gin.Context) {
+	log.Println("[API] Iniciando criação de local de descarte")
+
+	l := LocalDescarte{Ativo: true}
+	if err := c.ShouldBindJSON(&l); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if strings.TrimSpace(l.Nome) == "" {
+		log.Println("[ERROR] Local de descarte sem nome")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nome é obrigatório"})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO locais_descarte(nome, licenca_ambiental, endereco, ativo)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id, data_criacao
+	`, l.Nome, l.LicencaAmbiental, l.Endereco, l.Ativo).Scan(&l.ID, &l.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar local de descarte: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar local de descarte (verifique se o nome já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Local de descarte criado: %s (ID: %d)", l.Nome, l.ID)
+	c.JSON(http.StatusCreated, l)
+}
+
+func atualizarLocalDescarte(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var l LocalDescarte
+	if err := c.ShouldBindJSON(&l); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if strings.TrimSpace(l.Nome) == "" {
+		log.Println("[ERROR] Local de descarte sem nome")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nome é obrigatório"})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE locais_descarte SET
+			nome = $1,
+			licenca_ambiental = NULLIF($2, ''),
+			endereco = NULLIF($3, ''),
+			ativo = $4
+		WHERE id = $5
+		RETURNING id, data_criacao
+	`, l.Nome, l.LicencaAmbiental, l.Endereco, l.Ativo, id).Scan(&l.ID, &l.DataCriacao)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Local de descarte não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Local de descarte não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar local de descarte: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar local de descarte"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Local de descarte atualizado com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, l)
+}
+
+// Handler para relatório de destinação
+
+func getRelatorioDestinacao(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 30)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	log.Printf("[DB] Gerando relatório de destinação de %s a %s", de.Format(formatoData), ate.Format(formatoData))
+
+	rows, err := dbLeitura.Query(c.Request.Context(), `
+		SELECT l.id, l.nome, COALESCE(l.licenca_ambiental, ''),
+		       p.id, p.codigo, p.nome, COALESCE(p.classe_risco, ''), p.unidade_medida,
+		       SUM(m.quantidade), COUNT(*), ARRAY_AGG(DISTINCT m.motivo)
+		FROM movimentacoes m
+		JOIN locais_descarte l ON m.local_descarte_id = l.id
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.tipo = 'saida'
+		  AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY l.id, l.nome, l.licenca_ambiental, p.id, p.codigo, p.nome, p.classe_risco, p.unidade_medida
+		ORDER BY l.nome, p.nome
+	`, de, ate)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar destinações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar destinações"})
+		return
+	}
+	defer rows.Close()
+
+	relatorio := RelatorioDestinacao{De: de, Ate: ate.AddDate(0, 0, -1), Itens: []ItemDestinacao{}}
+	for rows.Next() {
+		var item ItemDestinacao
+		err := rows.Scan(&item.LocalDescarteID, &item.LocalDescarte, &item.LicencaAmbiental,
+			&item.ProdutoID, &item.ProdutoCodigo, &item.ProdutoNome, &item.ClasseRisco, &item.Unidade,
+			&item.Quantidade, &item.Movimentacoes, &item.Motivos)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar destinação: %v", err)
+			continue
+		}
+		relatorio.Itens = append(relatorio.Itens, item)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar destinações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar destinações"})
+		return
+	}
+
+	log.Printf("[API] Relatório de destinação gerado: %d linhas", len(relatorio.Itens))
+	c.JSON(http.StatusOK, relatorio)
+}
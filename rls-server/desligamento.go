@@ -0,0 +1,140 @@
+// desligamento.go - Desligamento gracioso do servidor
+//
+// Ao receber SIGINT ou SIGTERM o servidor para de aceitar conexões, encerra os
+// streams SSE e WebSocket (que ficariam abertos indefinidamente), espera as
+// requisições em andamento terminarem, deixa os workers do barramento
+// consumirem os eventos já publicados, cancela os workers e fecha o pool do
+// banco. Tudo dentro de DESLIGAMENTO_TIMEOUT_SEGUNDOS; o que passar do prazo é
+// abandonado e registrado no log.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração do desligamento - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var desligamentoTimeoutSegundos = getEnvAsInt("DESLIGAMENTO_TIMEOUT_SEGUNDOS", 30)
+
+// Fechado quando o desligamento começa; streams SSE e WebSocket encerram ao recebê-lo
+var encerrando = make(chan struct{})
+
+// Workers em segundo plano, aguardados no desligamento
+var workers sync.WaitGroup
+
+// Inicia um worker em segundo plano que roda até o contexto ser cancelado
+func iniciarWorker(ctx context.Context, worker func(context.Context)) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		worker(ctx)
+	}()
+}
+
+// Executa a espera até o prazo; false se o prazo acabou antes
+func aguardarAte(prazo time.Time, espera func()) bool {
+	feito := make(chan struct{})
+	go func() {
+		espera()
+		close(feito)
+	}()
+	select {
+	case <-feito:
+		return true
+	case <-time.After(time.Until(prazo)):
+		return false
+	}
+}
+
+// Atende as requisições até receber um sinal de término e então desliga em
+// ordem: HTTP, eventos pendentes, workers e pool do banco
+func executarServidor(r *gin.Engine, endereco string, cancelarWorkers context.CancelFunc) {
+	srv := &http.Server{Addr: endereco, Handler: r}
+
+	// Com TLS, um segundo servidor HTTP redireciona para HTTPS
+	var redirecionamento *http.Server
+	if tlsConfigurado {
+		cfg, handler, err := configurarTLS()
+		if err != nil {
+			log.Fatalf("Falha ao configurar TLS: %v", err)
+		}
+		srv.TLSConfig = cfg
+		if tlsRedirectPorta != "" {
+			redirecionamento = &http.Server{Addr: ":" + tlsRedirectPorta, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		}
+	}
+
+	erros := make(chan error, 2)
+	go func() {
+		if tlsConfigurado {
+			// Certificado e chave vêm do TLSConfig
+			erros <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		erros <- srv.ListenAndServe()
+	}()
+	if redirecionamento != nil {
+		log.Printf("Redirecionando HTTP na porta %s para HTTPS", tlsRedirectPorta)
+		go func() {
+			if err := redirecionamento.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				erros <- err
+			}
+		}()
+	}
+
+	sinais := make(chan os.Signal, 1)
+	signal.Notify(sinais, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sinais)
+
+	select {
+	case err := <-erros:
+		log.Fatalf("Falha ao iniciar servidor: %v", err)
+	case s := <-sinais:
+		log.Printf("Sinal %s recebido, desligando o servidor...", s)
+	}
+
+	prazo := time.Now().Add(time.Duration(max(desligamentoTimeoutSegundos, 1)) * time.Second)
+	ctx, cancelar := context.WithDeadline(context.Background(), prazo)
+	defer cancelar()
+
+	// Streams de longa duração não terminam sozinhos
+	close(encerrando)
+
+	if redirecionamento != nil {
+		redirecionamento.Shutdown(ctx)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("[WARN] Requisições interrompidas no desligamento: %v", err)
+	} else {
+		log.Println("✓ Requisições em andamento concluídas")
+	}
+	if err := <-erros; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[WARN] Erro do servidor HTTP: %v", err)
+	}
+
+	// Eventos já publicados ainda são gravados pelos workers
+	if !aguardarAte(prazo, eventos.aguardarEntrega) {
+		log.Printf("[WARN] Eventos não processados no desligamento: %d", eventos.pendentes())
+	}
+
+	cancelarWorkers()
+	if !aguardarAte(prazo, workers.Wait) {
+		log.Println("[WARN] Workers não encerraram dentro do prazo do desligamento")
+	}
+
+	if !aguardarAte(prazo, db.Close) {
+		log.Println("[WARN] Pool do banco não fechou dentro do prazo do desligamento")
+		return
+	}
+	log.Println("✓ Servidor desligado")
+}
@@ -0,0 +1,117 @@
+// downloads.go - Envio de arquivos com download retomável
+//
+// Os endpoints de download (anexos, logotipo e relatórios gerados) usam
+// http.ServeContent, que atende Range/If-Range e responde 206 com o trecho
+// pedido, para o cliente retomar um download interrompido. O ETag e o
+// cabeçalho Repr-Digest (RFC 9530) trazem o SHA-256 do arquivo inteiro, para o
+// cliente conferir o resultado e só retomar se o arquivo não mudou. O hash dos
+// arquivos em disco fica em memória enquanto tamanho e data de modificação
+// não mudam.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cabeçalho com o hash do conteúdo completo
+const headerReprDigest = "Repr-Digest"
+
+type hashArquivo struct {
+	tamanho    int64
+	modificado time.Time
+	sha256     []byte
+}
+
+var (
+	hashesArquivosMutex sync.Mutex
+	hashesArquivos      = map[string]hashArquivo{}
+)
+
+// SHA-256 de um arquivo em disco, reaproveitado enquanto ele não muda
+func sha256Arquivo(caminho string, arquivo *os.File, info os.FileInfo) ([]byte, error) {
+	hashesArquivosMutex.Lock()
+	h, ok := hashesArquivos[caminho]
+	hashesArquivosMutex.Unlock()
+	if ok && h.tamanho == info.Size() && h.modificado.Equal(info.ModTime()) {
+		return h.sha256, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, arquivo); err != nil {
+		return nil, err
+	}
+	if _, err := arquivo.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	h = hashArquivo{tamanho: info.Size(), modificado: info.ModTime(), sha256: hash.Sum(nil)}
+	hashesArquivosMutex.Lock()
+	hashesArquivos[caminho] = h
+	hashesArquivosMutex.Unlock()
+	return h.sha256, nil
+}
+
+// Cabeçalhos de integridade e envio com suporte a Range
+func servirDownload(c *gin.Context, conteudo io.ReadSeeker, soma []byte, nome, tipo string, modificado time.Time) {
+	c.Header("Content-Type", tipo)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", nome))
+	c.Header("ETag", `"`+hex.EncodeToString(soma)+`"`)
+	c.Header(headerReprDigest, "sha-256=:"+base64.StdEncoding.EncodeToString(soma)+":")
+	http.ServeContent(c.Writer, c.Request, nome, modificado, conteudo)
+}
+
+// Envia um arquivo em disco; com esperado (SHA-256 em hexadecimal), recusa o
+// envio se o arquivo não confere
+func servirArquivo(c *gin.Context, caminho, nome, tipo, esperado string) {
+	arquivo, err := os.Open(caminho)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("[ERROR] Arquivo não encontrado: %s", caminho)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Arquivo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao abrir arquivo %s: %v", caminho, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ler arquivo"})
+		}
+		return
+	}
+	defer arquivo.Close()
+
+	info, err := arquivo.Stat()
+	if err != nil {
+		log.Printf("[ERROR] Erro ao ler arquivo %s: %v", caminho, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ler arquivo"})
+		return
+	}
+	soma, err := sha256Arquivo(caminho, arquivo, info)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao calcular hash de %s: %v", caminho, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ler arquivo"})
+		return
+	}
+	if esperado != "" && hex.EncodeToString(soma) != esperado {
+		log.Printf("[ERROR] Arquivo %s não confere com o hash registrado", caminho)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Arquivo corrompido no servidor"})
+		return
+	}
+
+	servirDownload(c, arquivo, soma, nome, tipo, info.ModTime())
+}
+
+// Envia um arquivo gerado em memória (relatórios)
+func servirConteudo(c *gin.Context, conteudo []byte, nome, tipo string, gerado time.Time) {
+	soma := sha256.Sum256(conteudo)
+	servirDownload(c, bytes.NewReader(conteudo), soma[:], nome, tipo, gerado)
+}
@@ -0,0 +1,244 @@
+// duplicar.go - Duplicação de produtos
+//
+// POST /api/produtos/:id/duplicar cria um produto novo com todos os campos do
+// original (descrição, fornecedor, custos, atributos, posição...), a
+// estrutura de componentes e as tags, para cadastrar itens quase iguais sem
+// redigitar tudo. Só o código muda e o saldo começa em zero; números de
+// série, lotes e históricos ficam com o original. Sem código no corpo, usa o
+// próximo livre do prefixo: o de PAR-0012 é PAR-, então a cópia recebe o
+// maior número já usado com esse prefixo (em produtos ou códigos antigos)
+// mais um, com a mesma quantidade de dígitos. A cópia de uma variante precisa
+// de outra variação.
+//
+// GET /api/produtos/codigos/proximo?prefixo=PAR- devolve a mesma sugestão,
+// para o app mostrar antes de cadastrar.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Número no fim do código (PAR-0012: PAR- e 0012)
+var padraoSequenciaCodigo = regexp.MustCompile(`^(.*?)([0-9]+)$`)
+
+type RequisicaoDuplicacao struct {
+	Codigo   string `json:"codigo,omitempty" binding:"omitempty,max=50,codigo"` // vazio: o próximo livre do prefixo
+	Prefixo  string `json:"prefixo,omitempty" binding:"max=45"`                 // prefixo da sugestão; padrão: o do código original
+	Variacao string `json:"variacao,omitempty" binding:"max=100"`               // obrigatória na cópia de uma variante
+}
+
+type SugestaoCodigo struct {
+	Prefixo string `json:"prefixo"`
+	Codigo  string `json:"codigo"`
+}
+
+// Prefixo e quantidade de dígitos da numeração de um código; código sem
+// número no fim ganha um hífen e numeração de um dígito
+func prefixoCodigo(codigo string) (string, int) {
+	if m := padraoSequenciaCodigo.FindStringSubmatch(codigo); m != nil {
+		return m[1], len(m[2])
+	}
+	return codigo + "-", 1
+}
+
+// Próximo código livre do prefixo: o maior número já usado por produtos ou
+// códigos antigos mais um, com pelo menos digitos dígitos (0 = os do maior)
+func proximoCodigo(ctx context.Context, q querier, prefixo string, digitos int) (string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT substr(codigo, length($1::text) + 1) FROM produtos WHERE left(codigo, length($1::text)) = $1::text
+		UNION
+		SELECT substr(codigo, length($1::text) + 1) FROM aliases_codigos WHERE left(codigo, length($1::text)) = $1::text
+	`, prefixo)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	maior, largura := 0, 1
+	for rows.Next() {
+		var sufixo string
+		if err := rows.Scan(&sufixo); err != nil {
+			return "", err
+		}
+		if sufixo == "" || strings.Trim(sufixo, "0123456789") != "" {
+			continue
+		}
+		if n, err := strconv.Atoi(sufixo); err == nil && n >= maior {
+			maior, largura = n, len(sufixo)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if digitos > 0 {
+		largura = digitos
+	}
+	return fmt.Sprintf("%s%0*d", prefixo, largura, maior+1), nil
+}
+
+func duplicarProduto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Corpo opcional: sem código, usa a sugestão pelo prefixo
+	var req RequisicaoDuplicacao
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("[ERROR] Dados inválidos: %v", err)
+			c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+			return
+		}
+	}
+
+	log.Printf("[API] Iniciando duplicação do produto ID: %d", id)
+
+	p, err := scanProduto(db.QueryRow(ctx, "SELECT "+produtoColunas+" FROM produtos WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+	original := p.Codigo
+
+	// A cópia começa sem saldo e sem os campos calculados
+	p.ID, p.Quantidade, p.Variantes, p.Tags = 0, 0, nil, nil
+	if p.ProdutoPaiID != nil {
+		p.Variacao = req.Variacao
+	}
+
+	p.Codigo = req.Codigo
+	if strings.TrimSpace(p.Codigo) == "" {
+		prefixo, digitos := prefixoCodigo(original)
+		if req.Prefixo != "" {
+			prefixo, digitos = lerRegraCodigo(ctx).normalizar(req.Prefixo), 0
+		}
+		if p.Codigo, err = proximoCodigo(ctx, db, prefixo, digitos); err != nil {
+			log.Printf("[ERROR] Erro ao sugerir código: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao sugerir código"})
+			return
+		}
+	}
+
+	// Variante: a variação da cópia precisa ser outra
+	if err := vincularVariante(ctx, db, 0, &p); err != nil {
+		log.Printf("[ERROR] Variante inválida na duplicação do produto ID %d: %v", id, err)
+		responderErroVariante(c, err)
+		return
+	}
+
+	// Atributos conferidos contra as definições atuais da categoria
+	if !vincularAtributos(c, db, &p) {
+		return
+	}
+
+	if msg := validarNovoProduto(ctx, &p); msg != "" {
+		log.Printf("[ERROR] Cópia inválida do produto ID %d (código '%s'): %s", id, p.Codigo, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	// Verificar se já existe um produto com o mesmo código
+	var existingId int
+	err = tx.QueryRow(ctx, "SELECT id FROM produtos WHERE codigo = $1", p.Codigo).Scan(&existingId)
+	if err == nil {
+		log.Printf("[DB] Produto já existe com código: %s (ID: %d)", p.Codigo, existingId)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe um produto com este código", Codigo: CodigoCodigoDuplicado})
+		return
+	} else if err != pgx.ErrNoRows {
+		log.Printf("[ERROR] Erro ao verificar produto existente: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto existente"})
+		return
+	}
+
+	// Posição do almoxarifado do original, se continua ativa
+	if err = vincularLocalizacao(ctx, tx, &p); err != nil {
+		log.Printf("[ERROR] Localização inválida na duplicação do produto ID %d: %v", id, err)
+		responderErroLocalizacao(c, err)
+		return
+	}
+
+	if err = inserirProduto(ctx, tx, &p); err != nil {
+		log.Printf("[ERROR] Erro ao duplicar produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao duplicar produto"})
+		return
+	}
+
+	// Estrutura de componentes e tags do original
+	_, err = tx.Exec(ctx, `
+		INSERT INTO estruturas_produto(produto_id, componente_id, quantidade)
+		SELECT $1, componente_id, quantidade FROM estruturas_produto WHERE produto_id = $2
+	`, p.ID, id)
+	if err == nil {
+		err = tx.QueryRow(ctx, `
+			WITH copia AS (
+				INSERT INTO produtos_tags(produto_id, tag_id)
+				SELECT $1, tag_id FROM produtos_tags WHERE produto_id = $2
+				RETURNING tag_id
+			)
+			SELECT array_agg(t.nome ORDER BY t.nome) FROM copia JOIN tags t ON t.id = copia.tag_id
+		`, p.ID, id).Scan(&p.Tags)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao copiar estrutura e tags: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao duplicar produto"})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Produto %s duplicado como %s (ID: %d)", original, p.Codigo, p.ID)
+	c.JSON(http.StatusCreated, p)
+}
+
+// Sugestão do próximo código livre de um prefixo
+func getProximoCodigo(c *gin.Context) {
+	prefixo := lerRegraCodigo(c.Request.Context()).normalizar(c.Query("prefixo"))
+	if prefixo == "" || len(prefixo) > 45 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe o prefixo (até 45 caracteres)"})
+		return
+	}
+
+	codigo, err := proximoCodigo(c.Request.Context(), db, prefixo, 0)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao sugerir código: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao sugerir código"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SugestaoCodigo{Prefixo: prefixo, Codigo: codigo})
+}
@@ -0,0 +1,341 @@
+// duplicatas.go - Detecção e mesclagem de produtos duplicados
+//
+// A detecção compara os nomes por similaridade de trigramas (pg_trgm) e
+// também aponta produtos do mesmo fornecedor com a mesma descrição. Os pares
+// encontrados ficam em duplicatas_produtos até serem mesclados ou ignorados.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Motivos de uma sugestão de duplicata
+const (
+	MotivoNomeSimilar         = "nome_similar"
+	MotivoFornecedorDescricao = "fornecedor_descricao"
+)
+
+var (
+	errMesclagemUnidade = errors.New("os produtos usam unidades de medida diferentes")
+	errMesclagemSerie   = errors.New("os produtos divergem no controle de série")
+	errMesclagemSeries  = errors.New("os produtos têm números de série em comum")
+)
+
+type Duplicata struct {
+	ID          int       `json:"id"`
+	ProdutoA    Produto   `json:"produto_a"`
+	ProdutoB    Produto   `json:"produto_b"`
+	Score       float64   `json:"score"`
+	Motivo      string    `json:"motivo"`
+	Status      string    `json:"status"`
+	DataCriacao time.Time `json:"data_criacao"`
+}
+
+// Identifica possíveis duplicatas e grava os pares novos. Pares já ignorados
+// não voltam a aparecer.
+func detectarDuplicatas(ctx context.Context) (int64, error) {
+	limiar, err := strconv.ParseFloat(lerConfiguracao(ctx, "limiar_duplicatas", "0.6"), 64)
+	if err != nil || limiar <= 0 || limiar > 1 {
+		limiar = 0.6
+	}
+
+	tag, err := db.Exec(ctx, `
+		INSERT INTO duplicatas_produtos(produto_a, produto_b, score, motivo)
+		SELECT produto_a, produto_b, MAX(score), (ARRAY_AGG(motivo ORDER BY score DESC))[1]
+		FROM (
+			SELECT a.id AS produto_a, b.id AS produto_b,
+			       similarity(a.nome, b.nome) AS score, $2::text AS motivo
+			FROM produtos a
+			JOIN produtos b ON a.id < b.id AND a.nome % b.nome
+			WHERE similarity(a.nome, b.nome) >= $1
+			UNION ALL
+			SELECT a.id, b.id, 1.0, $3::text
+			FROM produtos a
+			JOIN produtos b ON a.id < b.id
+			 AND a.fornecedor = b.fornecedor
+			 AND lower(trim(a.descricao)) = lower(trim(b.descricao))
+			WHERE COALESCE(trim(a.descricao), '') <> ''
+		) candidatos
+		GROUP BY produto_a, produto_b
+		ON CONFLICT (produto_a, produto_b) DO NOTHING
+	`, limiar, MotivoNomeSimilar, MotivoFornecedorDescricao)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Mescla o produto "remover" no produto "manter", dentro da transação: saldo,
+// movimentações, lotes, séries, pedidos e históricos passam para o produto
+// mantido e o outro é excluído.
+func mesclarProdutos(ctx context.Context, tx pgx.Tx, manter, remover int) error {
+	var unidadeManter, unidadeRemover string
+	var serieManter, serieRemover bool
+	err := tx.QueryRow(ctx, `
+		SELECT a.unidade_medida, a.controla_serie, b.unidade_medida, b.controla_serie
+		FROM produtos a, produtos b
+		WHERE a.id = $1 AND b.id = $2
+		FOR UPDATE
+	`, manter, remover).Scan(&unidadeManter, &serieManter, &unidadeRemover, &serieRemover)
+	if err != nil {
+		return err
+	}
+	if unidadeManter != unidadeRemover {
+		return errMesclagemUnidade
+	}
+	if serieManter != serieRemover {
+		return errMesclagemSerie
+	}
+
+	var seriesComuns bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM numeros_serie a
+			JOIN numeros_serie b ON a.numero = b.numero
+			WHERE a.produto_id = $1 AND b.produto_id = $2
+		)
+	`, manter, remover).Scan(&seriesComuns)
+	if err != nil {
+		return err
+	}
+	if seriesComuns {
+		return errMesclagemSeries
+	}
+
+	comandos := []string{
+		// Saldo e custo médio ponderado
+		`UPDATE produtos a SET
+			preco_custo = CASE WHEN a.quantidade + b.quantidade > 0
+				THEN (a.quantidade * a.preco_custo + b.quantidade * b.preco_custo) / (a.quantidade + b.quantidade)
+				ELSE a.preco_custo END,
+			quantidade = a.quantidade + b.quantidade
+		FROM produtos b WHERE a.id = $1 AND b.id = $2`,
+
+		// Lotes com o mesmo número são somados; os demais mudam de produto
+		`UPDATE lotes a SET quantidade = a.quantidade + b.quantidade,
+			validade = COALESCE(a.validade, b.validade)
+		FROM lotes b
+		WHERE a.produto_id = $1 AND b.produto_id = $2 AND a.numero_lote = b.numero_lote`,
+		`UPDATE movimentacoes_lotes ml SET lote_id = a.id
+		FROM lotes a, lotes b
+		WHERE ml.lote_id = b.id AND a.produto_id = $1 AND b.produto_id = $2 AND a.numero_lote = b.numero_lote`,
+		`DELETE FROM lotes b USING lotes a
+		WHERE a.produto_id = $1 AND b.produto_id = $2 AND a.numero_lote = b.numero_lote`,
+		`UPDATE lotes SET produto_id = $1 WHERE produto_id = $2`,
+
+		// Fotografias do mesmo dia são somadas
+		`UPDATE estoque_snapshots a SET quantidade = a.quantidade + b.quantidade
+		FROM estoque_snapshots b
+		WHERE a.produto_id = $1 AND b.produto_id = $2 AND a.data = b.data`,
+		`DELETE FROM estoque_snapshots b USING estoque_snapshots a
+		WHERE a.produto_id = $1 AND b.produto_id = $2 AND a.data = b.data`,
+		`UPDATE estoque_snapshots SET produto_id = $1 WHERE produto_id = $2`,
+
+		// Conversões já cadastradas no produto mantido prevalecem
+		`DELETE FROM conversoes_unidade b USING conversoes_unidade a
+		WHERE a.produto_id = $1 AND b.produto_id = $2
+		  AND a.unidade_origem = b.unidade_origem AND a.unidade_destino = b.unidade_destino`,
+		`UPDATE conversoes_unidade SET produto_id = $1 WHERE produto_id = $2`,
+
+		`UPDATE movimentacoes SET produto_id = $1 WHERE produto_id = $2`,
+		`UPDATE numeros_serie SET produto_id = $1 WHERE produto_id = $2`,
+		`UPDATE historico_precos SET produto_id = $1 WHERE produto_id = $2`,
+		`UPDATE pedidos_compra_itens SET produto_id = $1 WHERE produto_id = $2`,
+		`UPDATE pedidos_saida_itens SET produto_id = $1 WHERE produto_id = $2`,
+		`UPDATE historico_codigos SET produto_id = $1 WHERE produto_id = $2`,
+
+		// O código do produto removido e os aliases dele levam ao produto mantido
+		`UPDATE aliases_codigos SET produto_id = $1 WHERE produto_id = $2`,
+		`INSERT INTO aliases_codigos (codigo, produto_id, origem)
+		SELECT codigo, $1, '` + OrigemAliasMesclagem + `' FROM produtos WHERE id = $2
+		ON CONFLICT (codigo) DO UPDATE SET produto_id = EXCLUDED.produto_id, origem = EXCLUDED.origem`,
+		`DELETE FROM produtos WHERE id = $2`,
+	}
+	for _, sql := range comandos {
+		if _, err := tx.Exec(ctx, sql, manter, remover); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[DB] Produto ID %d mesclado no produto ID %d", remover, manter)
+	return nil
+}
+
+// Handlers de Duplicatas
+
+func getDuplicatas(c *gin.Context) {
+	status := c.DefaultQuery("status", "pendente")
+	log.Printf("[DB] Buscando duplicatas (status: %s)", status)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT d.id, d.score, d.motivo, d.status, d.data_criacao,
+		       a.id, a.codigo, a.nome, COALESCE(a.descricao, ''), COALESCE(a.fornecedor, ''), a.quantidade,
+		       b.id, b.codigo, b.nome, COALESCE(b.descricao, ''), COALESCE(b.fornecedor, ''), b.quantidade
+		FROM duplicatas_produtos d
+		JOIN produtos a ON d.produto_a = a.id
+		JOIN produtos b ON d.produto_b = b.id
+		WHERE $1 = 'todos' OR d.status = $1
+		ORDER BY d.score DESC, d.id
+	`, status)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar duplicatas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar duplicatas"})
+		return
+	}
+	defer rows.Close()
+
+	duplicatas := []Duplicata{}
+	for rows.Next() {
+		var d Duplicata
+		err := rows.Scan(&d.ID, &d.Score, &d.Motivo, &d.Status, &d.DataCriacao,
+			&d.ProdutoA.ID, &d.ProdutoA.Codigo, &d.ProdutoA.Nome, &d.ProdutoA.Descricao, &d.ProdutoA.Fornecedor, &d.ProdutoA.Quantidade,
+			&d.ProdutoB.ID, &d.ProdutoB.Codigo, &d.ProdutoB.Nome, &d.ProdutoB.Descricao, &d.ProdutoB.Fornecedor, &d.ProdutoB.Quantidade)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar duplicata: %v", err)
+			continue
+		}
+		duplicatas = append(duplicatas, d)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar duplicatas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar duplicatas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicatas)
+}
+
+func detectarDuplicatasHandler(c *gin.Context) {
+	log.Println("[API] Iniciando detecção de produtos duplicados")
+
+	inicio := time.Now()
+	n, err := detectarDuplicatas(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao detectar duplicatas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao detectar duplicatas"})
+		return
+	}
+
+	log.Printf("[DB] Detecção concluída em %v: %d novas duplicatas", time.Since(inicio), n)
+	c.JSON(http.StatusOK, gin.H{"novas": n})
+}
+
+func mesclarDuplicata(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Produto que permanece; o outro do par é mesclado nele
+	var req struct {
+		Manter int `json:"manter"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	var produtoA, produtoB int
+	var status string
+	err = tx.QueryRow(c.Request.Context(), `
+		SELECT produto_a, produto_b, status FROM duplicatas_produtos WHERE id = $1 FOR UPDATE
+	`, id).Scan(&produtoA, &produtoB, &status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Duplicata não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Duplicata não encontrada"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar duplicata: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar duplicata"})
+		}
+		return
+	}
+
+	remover := produtoB
+	if req.Manter == produtoB {
+		remover = produtoA
+	} else if req.Manter != produtoA {
+		log.Printf("[ERROR] Produto a manter (%d) não pertence à duplicata %d", req.Manter, id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe em 'manter' um dos dois produtos da duplicata"})
+		return
+	}
+
+	err = mesclarProdutos(c.Request.Context(), tx, req.Manter, remover)
+	if err == errMesclagemUnidade || err == errMesclagemSerie || err == errMesclagemSeries {
+		log.Printf("[ERROR] Mesclagem recusada: %v", err)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Não é possível mesclar: " + err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao mesclar produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao mesclar produtos"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao confirmar mesclagem: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao confirmar mesclagem"})
+		return
+	}
+
+	// A duplicata e outras que envolviam o produto removido somem em cascata
+	p, err := scanProduto(db.QueryRow(c.Request.Context(), "SELECT "+produtoColunas+" FROM produtos WHERE id = $1", req.Manter))
+	if err != nil {
+		log.Printf("[WARN] Erro ao obter produto mesclado: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Produtos mesclados com sucesso"})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+func ignorarDuplicata(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "UPDATE duplicatas_produtos SET status = 'ignorado' WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao ignorar duplicata: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ignorar duplicata"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Duplicata não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Duplicata não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Duplicata ignorada: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicata ignorada"})
+}
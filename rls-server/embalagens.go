@@ -0,0 +1,194 @@
+// embalagens.go - Embalagens de compra por produto e fornecedor
+//
+// O fornecedor fatura em embalagens ("CX c/ 50") e o estoque é controlado na
+// unidade base do produto. Cada embalagem cadastrada liga produto e
+// fornecedor a um fator, ao código do item no fornecedor e ao EAN da caixa.
+// No recebimento de pedidos de compra a quantidade pode ser informada em
+// embalagens (pelo ID, pelo EAN bipado ou pelo código do fornecedor) e é
+// convertida pelo fator.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var errEmbalagemNaoEncontrada = errors.New("embalagem não cadastrada para este fornecedor")
+
+type EmbalagemFornecedor struct {
+	ID               int       `json:"id,omitempty"`
+	ProdutoID        int       `json:"produto_id"`
+	Fornecedor       string    `json:"fornecedor"`
+	Descricao        string    `json:"descricao,omitempty"`
+	Fator            int       `json:"fator"`
+	CodigoFornecedor string    `json:"codigo_fornecedor,omitempty"`
+	EAN              string    `json:"ean,omitempty"`
+	DataCriacao      time.Time `json:"data_criacao,omitempty"`
+}
+
+// Identificação de uma embalagem no recebimento; basta um dos campos
+type ReferenciaEmbalagem struct {
+	EmbalagemID      int    `json:"embalagem_id,omitempty"`
+	EAN              string `json:"ean,omitempty"`
+	CodigoFornecedor string `json:"codigo_fornecedor,omitempty"`
+}
+
+func (r ReferenciaEmbalagem) informada() bool {
+	return r.EmbalagemID != 0 || r.EAN != "" || r.CodigoFornecedor != ""
+}
+
+// Localiza a embalagem do fornecedor; devolve produto e fator
+func buscarEmbalagem(ctx context.Context, q querier, fornecedor string, r ReferenciaEmbalagem) (produtoID, fator int, err error) {
+	err = q.QueryRow(ctx, `
+		SELECT produto_id, fator FROM embalagens_fornecedor
+		WHERE fornecedor = $1
+			AND ($2::int = 0 OR id = $2::int)
+			AND ($3::text = '' OR ean = $3::text)
+			AND ($4::text = '' OR codigo_fornecedor = $4::text)
+		ORDER BY id
+		LIMIT 1
+	`, fornecedor, r.EmbalagemID, strings.TrimSpace(r.EAN), strings.TrimSpace(r.CodigoFornecedor)).Scan(&produtoID, &fator)
+	if err == pgx.ErrNoRows {
+		err = errEmbalagemNaoEncontrada
+	}
+	return produtoID, fator, err
+}
+
+// Handlers de Embalagens
+
+func getEmbalagensPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando embalagens do produto ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, produto_id, fornecedor, descricao, fator, codigo_fornecedor, ean, data_criacao
+		FROM embalagens_fornecedor
+		WHERE produto_id = $1
+		ORDER BY fornecedor, fator
+	`, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar embalagens: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar embalagens"})
+		return
+	}
+	defer rows.Close()
+
+	embalagens := []EmbalagemFornecedor{}
+	for rows.Next() {
+		var e EmbalagemFornecedor
+		var descricao, codigo, ean *string
+		err := rows.Scan(&e.ID, &e.ProdutoID, &e.Fornecedor, &descricao, &e.Fator, &codigo, &ean, &e.DataCriacao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar embalagem: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if descricao != nil {
+			e.Descricao = *descricao
+		}
+		if codigo != nil {
+			e.CodigoFornecedor = *codigo
+		}
+		if ean != nil {
+			e.EAN = *ean
+		}
+		embalagens = append(embalagens, e)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar embalagens: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar embalagens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, embalagens)
+}
+
+func criarEmbalagem(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var e EmbalagemFornecedor
+	if err := c.ShouldBindJSON(&e); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	e.ProdutoID = id
+	e.Fornecedor = strings.TrimSpace(e.Fornecedor)
+	e.EAN = strings.TrimSpace(e.EAN)
+	e.CodigoFornecedor = strings.TrimSpace(e.CodigoFornecedor)
+
+	if e.Fornecedor == "" || e.Fator <= 0 {
+		log.Printf("[ERROR] Embalagem inválida: fornecedor '%s', fator %d", e.Fornecedor, e.Fator)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe o fornecedor e um fator positivo"})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		INSERT INTO embalagens_fornecedor(produto_id, fornecedor, descricao, fator, codigo_fornecedor, ean)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''))
+		RETURNING id, data_criacao
+	`, e.ProdutoID, e.Fornecedor, e.Descricao, e.Fator, e.CodigoFornecedor, e.EAN).Scan(&e.ID, &e.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar embalagem: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar embalagem (verifique o produto e se o EAN já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Embalagem criada: %s %s = %d unidades do produto %d (ID: %d)",
+		e.Fornecedor, e.Descricao, e.Fator, e.ProdutoID, e.ID)
+	c.JSON(http.StatusCreated, e)
+}
+
+func deletarEmbalagem(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM embalagens_fornecedor WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir embalagem: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir embalagem"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Embalagem não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Embalagem não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Embalagem excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Embalagem excluída com sucesso"})
+}
@@ -0,0 +1,243 @@
+// empresa.go - Perfil da empresa (dados cadastrais e logotipo)
+//
+// Os dados ficam nas configurações empresa_* (as mesmas do setup) e podem ser
+// consultados e alterados a qualquer momento em /api/empresa. O logotipo
+// (PNG ou JPEG) é convertido para PNG e gravado em ANEXOS_DIR/empresa, com o
+// caminho em empresa_logo. Relatórios em PDF e etiquetas usam este perfil.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tamanho máximo do arquivo de logotipo
+const empresaLogoTamanhoMaximo = 2 << 20
+
+// Maior lado aceito para o logotipo, em pixels
+const empresaLogoDimensaoMaxima = 2000
+
+var errLogoInvalido = errors.New("logotipo deve ser uma imagem PNG ou JPEG de até 2 MB e 2000 pixels")
+
+type PerfilEmpresa struct {
+	EmpresaSetup
+	LogoURL string `json:"logo_url,omitempty"`
+}
+
+// Lê os dados da empresa das configurações
+func lerEmpresa(ctx context.Context) PerfilEmpresa {
+	p := PerfilEmpresa{EmpresaSetup: EmpresaSetup{
+		Nome:     lerConfiguracao(ctx, "empresa_nome", ""),
+		CNPJ:     lerConfiguracao(ctx, "empresa_cnpj", ""),
+		Endereco: lerConfiguracao(ctx, "empresa_endereco", ""),
+		Telefone: lerConfiguracao(ctx, "empresa_telefone", ""),
+		Email:    lerConfiguracao(ctx, "empresa_email", ""),
+	}}
+	if lerConfiguracao(ctx, "empresa_logo", "") != "" {
+		p.LogoURL = "/api/v1/empresa/logo"
+	}
+	return p
+}
+
+// Grava os dados da empresa em uma transação e atualiza o cache depois do commit
+func salvarEmpresa(ctx context.Context, e EmpresaSetup) error {
+	campos := []struct{ chave, valor, descricao string }{
+		{"empresa_nome", strings.TrimSpace(e.Nome), "Razão social ou nome da empresa"},
+		{"empresa_cnpj", strings.TrimSpace(e.CNPJ), "CNPJ da empresa"},
+		{"empresa_endereco", strings.TrimSpace(e.Endereco), "Endereço da empresa"},
+		{"empresa_telefone", strings.TrimSpace(e.Telefone), "Telefone da empresa"},
+		{"empresa_email", strings.TrimSpace(e.Email), "E-mail de contato da empresa"},
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	for _, campo := range campos {
+		if err := salvarConfiguracao(ctx, tx, campo.chave, campo.valor, campo.descricao); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, campo := range campos {
+		guardarConfiguracao(campo.chave, campo.valor)
+	}
+	return nil
+}
+
+// Carrega o logotipo para uso nos PDFs; nil quando não há logotipo
+func carregarLogoEmpresa(ctx context.Context) image.Image {
+	caminho := lerConfiguracao(ctx, "empresa_logo", "")
+	if caminho == "" {
+		return nil
+	}
+	arquivo, err := os.Open(filepath.Join(anexosDiretorio, caminho))
+	if err != nil {
+		log.Printf("[WARN] Erro ao abrir logotipo da empresa: %v", err)
+		return nil
+	}
+	defer arquivo.Close()
+
+	img, err := png.Decode(arquivo)
+	if err != nil {
+		log.Printf("[WARN] Erro ao ler logotipo da empresa: %v", err)
+		return nil
+	}
+	return img
+}
+
+// Handlers do Perfil da Empresa
+
+func getEmpresa(c *gin.Context) {
+	c.JSON(http.StatusOK, lerEmpresa(c.Request.Context()))
+}
+
+func updateEmpresa(c *gin.Context) {
+	var e EmpresaSetup
+	if err := c.ShouldBindJSON(&e); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if strings.TrimSpace(e.Nome) == "" {
+		log.Println("[ERROR] Nome da empresa não informado")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nome da empresa é obrigatório"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := salvarEmpresa(ctx, e); err != nil {
+		log.Printf("[ERROR] Erro ao salvar dados da empresa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar dados da empresa"})
+		return
+	}
+
+	log.Printf("[DB] Dados da empresa atualizados: %s", e.Nome)
+	c.JSON(http.StatusOK, lerEmpresa(ctx))
+}
+
+func getLogoEmpresa(c *gin.Context) {
+	caminho := lerConfiguracao(c.Request.Context(), "empresa_logo", "")
+	if caminho == "" {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Logotipo não cadastrado"})
+		return
+	}
+	servirArquivo(c, filepath.Join(anexosDiretorio, caminho), "logo.png", "image/png", "")
+}
+
+func uploadLogoEmpresa(c *gin.Context) {
+	fh, err := c.FormFile("arquivo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Envie o logotipo no campo 'arquivo'"})
+		return
+	}
+	if fh.Size > empresaLogoTamanhoMaximo {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: errLogoInvalido.Error()})
+		return
+	}
+
+	arquivo, err := fh.Open()
+	if err != nil {
+		log.Printf("[ERROR] Erro ao abrir logotipo enviado: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ler arquivo"})
+		return
+	}
+	defer arquivo.Close()
+
+	// Conferir as dimensões antes de decodificar a imagem inteira
+	cfg, formato, err := image.DecodeConfig(arquivo)
+	if err != nil || (formato != "png" && formato != "jpeg") ||
+		cfg.Width > empresaLogoDimensaoMaxima || cfg.Height > empresaLogoDimensaoMaxima {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: errLogoInvalido.Error()})
+		return
+	}
+	if _, err := arquivo.Seek(0, 0); err != nil {
+		log.Printf("[ERROR] Erro ao ler logotipo enviado: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao ler arquivo"})
+		return
+	}
+	img, _, err := image.Decode(arquivo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: errLogoInvalido.Error()})
+		return
+	}
+
+	// Gravar em arquivo temporário e renomear, para não corromper o logotipo atual
+	caminho := filepath.Join("empresa", "logo.png")
+	destino := filepath.Join(anexosDiretorio, caminho)
+	if err := os.MkdirAll(filepath.Dir(destino), 0o755); err != nil {
+		log.Printf("[ERROR] Erro ao criar diretório do logotipo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar logotipo"})
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(destino), "logo-*.png")
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar arquivo do logotipo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar logotipo"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	err = png.Encode(tmp, img)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), destino)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gravar logotipo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar logotipo"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := salvarConfiguracao(ctx, db, "empresa_logo", caminho, "Logotipo da empresa (caminho em ANEXOS_DIR)"); err != nil {
+		log.Printf("[ERROR] Erro ao salvar configuração do logotipo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar logotipo"})
+		return
+	}
+	guardarConfiguracao("empresa_logo", caminho)
+
+	log.Printf("[API] Logotipo da empresa atualizado (%dx%d, %s)", cfg.Width, cfg.Height, formato)
+	c.JSON(http.StatusOK, lerEmpresa(ctx))
+}
+
+func deleteLogoEmpresa(c *gin.Context) {
+	ctx := c.Request.Context()
+	caminho := lerConfiguracao(ctx, "empresa_logo", "")
+	if caminho == "" {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Logotipo não cadastrado"})
+		return
+	}
+
+	if err := salvarConfiguracao(ctx, db, "empresa_logo", "", "Logotipo da empresa (caminho em ANEXOS_DIR)"); err != nil {
+		log.Printf("[ERROR] Erro ao remover configuração do logotipo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao remover logotipo"})
+		return
+	}
+	guardarConfiguracao("empresa_logo", "")
+
+	if err := os.Remove(filepath.Join(anexosDiretorio, caminho)); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] Erro ao apagar arquivo do logotipo: %v", err)
+	}
+
+	log.Println("[API] Logotipo da empresa removido")
+	c.JSON(http.StatusOK, gin.H{"message": "Logotipo removido com sucesso"})
+}
@@ -0,0 +1,54 @@
+// estoque_negativo.go - Política de estoque negativo
+//
+// Por padrão nenhuma operação leva o saldo de um produto abaixo de zero. A
+// configuração permitir_estoque_negativo libera o saldo negativo para todos os
+// produtos; a coluna de mesmo nome no produto, quando preenchida, vale no lugar
+// da global (true libera só aquele produto, false bloqueia mesmo com a global
+// ligada). Movimentações, lotes de movimentações, sincronização offline,
+// pedidos de saída e o cadastro do produto seguem a mesma regra e, quando
+// recusam, devolvem a falta de cada produto.
+
+package main
+
+import (
+	"context"
+)
+
+// Produto sem estoque suficiente para a operação
+type FaltaEstoque struct {
+	ProdutoID     int    `json:"produto_id"`
+	ProdutoCodigo string `json:"produto_codigo"`
+	Solicitado    int    `json:"solicitado"`
+	Disponivel    int    `json:"disponivel"`
+	Falta         int    `json:"falta"` // quanto o saldo ficaria abaixo de zero
+}
+
+func novaFaltaEstoque(produtoID int, codigo string, solicitado, disponivel int) FaltaEstoque {
+	return FaltaEstoque{
+		ProdutoID:     produtoID,
+		ProdutoCodigo: codigo,
+		Solicitado:    solicitado,
+		Disponivel:    disponivel,
+		Falta:         solicitado - disponivel,
+	}
+}
+
+// Resposta de uma operação recusada por falta de estoque
+type ErroFaltaEstoque struct {
+	Error  string         `json:"error"`
+	Codigo string         `json:"code"`
+	Faltas []FaltaEstoque `json:"faltas"`
+}
+
+func novoErroFaltaEstoque(msg string, faltas []FaltaEstoque) ErroFaltaEstoque {
+	return ErroFaltaEstoque{Error: msg, Codigo: CodigoEstoqueInsuficiente, Faltas: faltas}
+}
+
+// Indica se o saldo do produto pode ficar negativo; porProduto é a coluna
+// permitir_estoque_negativo do produto
+func estoqueNegativoPermitido(ctx context.Context, porProduto *bool) bool {
+	if porProduto != nil {
+		return *porProduto
+	}
+	return lerConfiguracao(ctx, "permitir_estoque_negativo", "false") == "true"
+}
@@ -0,0 +1,251 @@
+// estoque_seguranca.go - Estoque de segurança sugerido como quantidade mínima
+//
+// O estoque de segurança cobre a variação do consumo durante o prazo de
+// entrega: ES = z × σ × √prazo, com σ o desvio padrão das saídas diárias
+// (dias sem saída contam como zero), o prazo do fornecedor (ou
+// prazo_entrega_padrao) e z o quantil da normal para o nível de serviço
+// (configuração estoque_seguranca_nivel_servico). O valor arredondado para
+// cima é a quantidade mínima sugerida; o ponto de pedido da reposição soma a
+// ela o consumo do prazo.
+//
+// GET /api/estoque-seguranca/simulacao calcula as sugestões sem gravar e
+// POST /api/estoque-seguranca/aplicar grava as quantidades mínimas aceitas.
+
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limites aceitos para o nível de serviço
+const (
+	nivelServicoMinimo = 0.5
+	nivelServicoMaximo = 0.999
+)
+
+type SugestaoEstoqueSeguranca struct {
+	ProdutoID        int     `json:"produto_id"`
+	ProdutoCodigo    string  `json:"produto_codigo"`
+	ProdutoNome      string  `json:"produto_nome"`
+	ConsumoMedio     float64 `json:"consumo_medio"`
+	DesvioPadrao     float64 `json:"desvio_padrao"`
+	PrazoEntregaDias int     `json:"prazo_entrega_dias"`
+	EstoqueSeguranca float64 `json:"estoque_seguranca"`
+	MinimoAtual      int     `json:"minimo_atual"`
+	MinimoSugerido   int     `json:"minimo_sugerido"`
+	QuantidadeMaxima int     `json:"quantidade_maxima"`
+}
+
+type SimulacaoEstoqueSeguranca struct {
+	NivelServico float64                    `json:"nivel_servico"`
+	Z            float64                    `json:"z"`
+	Dias         int                        `json:"dias"`
+	Sugestoes    []SugestaoEstoqueSeguranca `json:"sugestoes"`
+}
+
+type MinimoAceito struct {
+	ProdutoID        int `json:"produto_id"`
+	QuantidadeMinima int `json:"quantidade_minima"`
+}
+
+type RequisicaoAplicarMinimos struct {
+	Itens []MinimoAceito `json:"itens"`
+}
+
+// Quantil da normal padrão para o nível de serviço
+func zNivelServico(nivel float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*nivel-1)
+}
+
+// Média e desvio padrão amostral das saídas diárias a partir da soma e da
+// soma dos quadrados dos dias com saída; os demais dias valem zero
+func estatisticasConsumo(soma, somaQuadrados float64, dias int) (media, desvio float64) {
+	if dias <= 0 {
+		return 0, 0
+	}
+	n := float64(dias)
+	media = soma / n
+	if dias > 1 {
+		variancia := (somaQuadrados - n*media*media) / (n - 1)
+		desvio = math.Sqrt(math.Max(variancia, 0))
+	}
+	return media, desvio
+}
+
+// Lê o nível de serviço: parâmetro da requisição, configuração ou padrão
+func lerNivelServico(ctx context.Context, param string) (float64, bool) {
+	valor := param
+	if valor == "" {
+		valor = lerConfiguracao(ctx, "estoque_seguranca_nivel_servico", "0.95")
+	}
+	nivel, err := strconv.ParseFloat(valor, 64)
+	if err != nil || nivel < nivelServicoMinimo || nivel > nivelServicoMaximo {
+		return 0, false
+	}
+	return nivel, true
+}
+
+// Handlers de Estoque de Segurança
+
+func getSimulacaoEstoqueSeguranca(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	nivel, ok := lerNivelServico(ctx, c.Query("nivel_servico"))
+	if !ok {
+		log.Printf("[ERROR] Nível de serviço inválido: %s", c.Query("nivel_servico"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nível de serviço inválido, use um valor entre 0.5 e 0.999"})
+		return
+	}
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "90"))
+	if err != nil || dias <= 1 {
+		dias = 90
+	}
+	produtoID, _ := strconv.Atoi(c.Query("produto_id"))
+	prazoPadrao, err := strconv.Atoi(lerConfiguracao(ctx, "prazo_entrega_padrao", "7"))
+	if err != nil || prazoPadrao < 0 {
+		prazoPadrao = 7
+	}
+
+	simulacao := SimulacaoEstoqueSeguranca{
+		NivelServico: nivel,
+		Z:            math.Round(zNivelServico(nivel)*1000) / 1000,
+		Dias:         dias,
+		Sugestoes:    []SugestaoEstoqueSeguranca{},
+	}
+
+	log.Printf("[DB] Simulando estoque de segurança (nível %.3f, %d dias)", nivel, dias)
+
+	rows, err := db.Query(ctx, `
+		WITH diario AS (
+			SELECT produto_id, SUM(quantidade)::float8 AS quantidade
+			FROM movimentacoes
+			WHERE tipo = 'saida' AND data_movimentacao >= CURRENT_DATE - ($1::int - 1)
+			GROUP BY produto_id, date_trunc('day', data_movimentacao)
+		)
+		SELECT p.id, p.codigo, p.nome, COALESCE(p.quantidade_minima, 0), p.quantidade_maxima,
+		       SUM(d.quantidade), SUM(d.quantidade * d.quantidade),
+		       COALESCE(f.prazo_entrega_dias, $2)
+		FROM produtos p
+		JOIN diario d ON d.produto_id = p.id
+		LEFT JOIN fornecedores f ON f.nome = p.fornecedor
+		WHERE $3 = 0 OR p.id = $3
+		GROUP BY p.id, f.prazo_entrega_dias
+		ORDER BY p.nome
+	`, dias, prazoPadrao, produtoID)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar consumo para estoque de segurança: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular estoque de segurança"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s SugestaoEstoqueSeguranca
+		var soma, somaQuadrados float64
+		err := rows.Scan(&s.ProdutoID, &s.ProdutoCodigo, &s.ProdutoNome, &s.MinimoAtual, &s.QuantidadeMaxima,
+			&soma, &somaQuadrados, &s.PrazoEntregaDias)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar consumo do produto: %v", err)
+			continue
+		}
+
+		media, desvio := estatisticasConsumo(soma, somaQuadrados, dias)
+		es := simulacao.Z * desvio * math.Sqrt(float64(s.PrazoEntregaDias))
+		s.ConsumoMedio = math.Round(media*100) / 100
+		s.DesvioPadrao = math.Round(desvio*100) / 100
+		s.EstoqueSeguranca = math.Round(es*100) / 100
+		s.MinimoSugerido = int(math.Ceil(es))
+		simulacao.Sugestoes = append(simulacao.Sugestoes, s)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar consumo para estoque de segurança: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular estoque de segurança"})
+		return
+	}
+
+	log.Printf("[API] Estoque de segurança simulado para %d produtos", len(simulacao.Sugestoes))
+	c.JSON(http.StatusOK, simulacao)
+}
+
+// Grava as quantidades mínimas aceitas; tudo ou nada
+func aplicarEstoqueSeguranca(c *gin.Context) {
+	var req RequisicaoAplicarMinimos
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if len(req.Itens) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nenhuma sugestão informada"})
+		return
+	}
+	for _, item := range req.Itens {
+		if item.QuantidadeMinima < 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Quantidades não podem ser negativas"})
+			return
+		}
+	}
+
+	log.Printf("[API] Aplicando estoque de segurança em %d produtos", len(req.Itens))
+	ctx := c.Request.Context()
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	produtos := make([]Produto, 0, len(req.Itens))
+	for _, item := range req.Itens {
+		p, err := scanProduto(tx.QueryRow(ctx, `
+			UPDATE produtos SET quantidade_minima = $1, data_atualizacao = CURRENT_TIMESTAMP
+			WHERE id = $2
+			RETURNING `+produtoColunas,
+			item.QuantidadeMinima, item.ProdutoID))
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado: " + strconv.Itoa(item.ProdutoID)})
+			return
+		} else if err != nil {
+			log.Printf("[ERROR] Erro ao atualizar mínimo do produto %d: %v", item.ProdutoID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao aplicar estoque de segurança"})
+			return
+		}
+		if p.QuantidadeMaxima > 0 && p.QuantidadeMaxima < p.QuantidadeMinima {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: p.Codigo + ": quantidade mínima acima da máxima"})
+			return
+		}
+		if err = enfileirarEntregasProduto(ctx, tx, p); err != nil {
+			log.Printf("[ERROR] Erro ao enfileirar entregas de webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao aplicar estoque de segurança"})
+			return
+		}
+		produtos = append(produtos, p)
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Quantidade mínima atualizada em %d produtos", len(produtos))
+	for _, p := range produtos {
+		eventos.publicar(EventoProdutoAtualizado, p)
+		publicarSeEstoqueBaixo(p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"atualizados": len(produtos)})
+}
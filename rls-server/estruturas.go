@@ -0,0 +1,211 @@
+// estruturas.go - Estrutura (lista de materiais) dos produtos fabricados
+//
+// GET /api/produtos/:id/estrutura lista os componentes do produto com a
+// quantidade por unidade; PUT substitui a lista inteira (lista vazia = produto
+// comprado, sem estrutura). Componentes podem ter a própria estrutura, desde
+// que nenhum caminho volte ao produto. Nos kits a estrutura define os
+// componentes baixados na saída (ver kits.go).
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ComponenteEstrutura struct {
+	ComponenteID     int     `json:"componente_id"`
+	ComponenteCodigo string  `json:"componente_codigo,omitempty"`
+	ComponenteNome   string  `json:"componente_nome,omitempty"`
+	Unidade          string  `json:"unidade,omitempty"`
+	Quantidade       float64 `json:"quantidade"` // por unidade do produto
+}
+
+// Carrega todas as estruturas: produto -> componentes
+func carregarEstruturas(ctx context.Context, q querier) (map[int][]ComponenteEstrutura, error) {
+	rows, err := q.Query(ctx, `
+		SELECT e.produto_id, e.componente_id, p.codigo, p.nome, p.unidade_medida, e.quantidade::float8
+		FROM estruturas_produto e
+		JOIN produtos p ON p.id = e.componente_id
+		ORDER BY e.produto_id, p.codigo
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	estruturas := map[int][]ComponenteEstrutura{}
+	for rows.Next() {
+		var produtoID int
+		var comp ComponenteEstrutura
+		if err := rows.Scan(&produtoID, &comp.ComponenteID, &comp.ComponenteCodigo, &comp.ComponenteNome,
+			&comp.Unidade, &comp.Quantidade); err != nil {
+			return nil, err
+		}
+		estruturas[produtoID] = append(estruturas[produtoID], comp)
+	}
+	return estruturas, rows.Err()
+}
+
+// Função auxiliar que diz se o produto alvo aparece na estrutura (em qualquer
+// nível) a partir do produto de origem
+func estruturaAlcanca(estruturas map[int][]ComponenteEstrutura, origem, alvo int) bool {
+	visitados := map[int]bool{}
+	pilha := []int{origem}
+	for len(pilha) > 0 {
+		atual := pilha[len(pilha)-1]
+		pilha = pilha[:len(pilha)-1]
+		if atual == alvo {
+			return true
+		}
+		if visitados[atual] {
+			continue
+		}
+		visitados[atual] = true
+		for _, comp := range estruturas[atual] {
+			pilha = append(pilha, comp.ComponenteID)
+		}
+	}
+	return false
+}
+
+// Handlers de Estruturas
+
+func getEstruturaProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando estrutura do produto ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT e.componente_id, p.codigo, p.nome, p.unidade_medida, e.quantidade::float8
+		FROM estruturas_produto e
+		JOIN produtos p ON p.id = e.componente_id
+		WHERE e.produto_id = $1
+		ORDER BY p.codigo
+	`, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar estrutura: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar estrutura"})
+		return
+	}
+	defer rows.Close()
+
+	componentes := []ComponenteEstrutura{}
+	for rows.Next() {
+		var comp ComponenteEstrutura
+		if err := rows.Scan(&comp.ComponenteID, &comp.ComponenteCodigo, &comp.ComponenteNome, &comp.Unidade, &comp.Quantidade); err != nil {
+			log.Printf("[ERROR] Erro ao processar componente: %v", err)
+			continue
+		}
+		componentes = append(componentes, comp)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar estrutura: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar estrutura"})
+		return
+	}
+
+	c.JSON(http.StatusOK, componentes)
+}
+
+// Substitui a estrutura do produto
+func salvarEstruturaProduto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var componentes []ComponenteEstrutura
+	if err := c.ShouldBindJSON(&componentes); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	repetidos := map[int]bool{}
+	for _, comp := range componentes {
+		if comp.ComponenteID <= 0 || comp.Quantidade <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cada componente precisa de produto e quantidade positiva"})
+			return
+		}
+		if comp.ComponenteID == id || repetidos[comp.ComponenteID] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Componente repetido ou igual ao próprio produto"})
+			return
+		}
+		repetidos[comp.ComponenteID] = true
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var existe bool
+	if err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM produtos WHERE id = $1)", id).Scan(&existe); err != nil || !existe {
+		log.Printf("[DB] Produto não encontrado com ID: %d (%v)", id, err)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		return
+	}
+
+	// Um componente cuja estrutura leva de volta ao produto criaria um ciclo
+	estruturas, err := carregarEstruturas(ctx, tx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar estruturas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar estrutura"})
+		return
+	}
+	for _, comp := range componentes {
+		if estruturaAlcanca(estruturas, comp.ComponenteID, id) {
+			log.Printf("[ERROR] Estrutura circular: componente %d contém o produto %d", comp.ComponenteID, id)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Estrutura circular: um componente já contém este produto"})
+			return
+		}
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM estruturas_produto WHERE produto_id = $1", id); err != nil {
+		log.Printf("[ERROR] Erro ao limpar estrutura: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar estrutura"})
+		return
+	}
+	for _, comp := range componentes {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO estruturas_produto(produto_id, componente_id, quantidade)
+			VALUES ($1, $2, $3)
+		`, id, comp.ComponenteID, comp.Quantidade)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao inserir componente %d: %v", comp.ComponenteID, err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao salvar estrutura (verifique os componentes)"})
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao confirmar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao confirmar transação"})
+		return
+	}
+
+	log.Printf("[DB] Estrutura do produto %d salva com %d componentes", id, len(componentes))
+	getEstruturaProduto(c)
+}
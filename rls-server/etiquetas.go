@@ -0,0 +1,268 @@
+// etiquetas.go - Etiquetas de produto com código de barras ou QR Code
+//
+// GET /api/produtos/:id/etiqueta gera a etiqueta de um produto: em PDF
+// (100 x 50 mm, com nome, código e símbolo) ou em PNG (somente o símbolo,
+// para uso em modelos de impressora). POST /api/etiquetas/lote gera uma folha
+// A4 com 3 x 8 etiquetas por página para uma lista de produtos. O símbolo
+// sempre codifica o código do produto.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tipos de símbolo e formatos aceitos
+const (
+	EtiquetaCode128 = "code128"
+	EtiquetaQRCode  = "qrcode"
+
+	FormatoEtiquetaPNG = "png"
+	FormatoEtiquetaPDF = "pdf"
+)
+
+// Quantidade máxima de produtos por folha em lote - valor padrão, pode ser sobrescrito por variável de ambiente
+var etiquetasLoteMaximo = getEnvAsInt("ETIQUETAS_LOTE_MAXIMO", 500)
+
+type etiquetaProduto struct {
+	ID     int
+	Codigo string
+	Nome   string
+}
+
+type LoteEtiquetas struct {
+	IDs  []int  `json:"ids"`
+	Tipo string `json:"tipo,omitempty"`
+}
+
+// Desenha uma etiqueta no retângulo (x, y, largura, altura) em pontos
+// empresa (nome do perfil da empresa) sai em letra pequena; vazio omite
+func desenharEtiqueta(d *documentoPDF, x, y, largura, altura float64, p etiquetaProduto, tipo, empresa string) error {
+	margem := 3 * pontosPorMM
+	x, y, largura, altura = x+margem, y+margem, largura-2*margem, altura-2*margem
+
+	if tipo == EtiquetaQRCode {
+		modulos, err := qrCode(p.Codigo)
+		if err != nil {
+			return err
+		}
+		lado := altura
+		modulo := lado / float64(len(modulos))
+		for my, linha := range modulos {
+			for mx, escuro := range linha {
+				if escuro {
+					d.retangulo(x+float64(mx)*modulo, y+float64(my)*modulo, modulo, modulo)
+				}
+			}
+		}
+
+		textoX := x + lado + margem
+		textoLargura := largura - lado - margem
+		d.textoNegrito(textoX, y+12, 11, d.ajustarTexto(p.Codigo, 11, textoLargura))
+		d.texto(textoX, y+26, 8, d.ajustarTexto(p.Nome, 8, textoLargura))
+		if empresa != "" {
+			d.texto(textoX, y+altura-2, 6, d.ajustarTexto(empresa, 6, textoLargura))
+		}
+		return nil
+	}
+
+	modulos, err := code128(p.Codigo)
+	if err != nil {
+		return err
+	}
+	// Nome do produto à esquerda e da empresa à direita, na mesma linha
+	larguraNome := largura
+	if empresa != "" {
+		empresa = d.ajustarTexto(empresa, 6, largura*0.35)
+		larguraNome = largura - d.larguraTexto(empresa, 6) - 2*pontosPorMM
+		d.texto(x+largura-d.larguraTexto(empresa, 6), y+9, 6, empresa)
+	}
+	d.textoNegrito(x, y+9, 9, d.ajustarTexto(p.Nome, 9, larguraNome))
+
+	// Barras centralizadas, entre o nome e o código
+	modulo := min(largura/float64(len(modulos)+20), 1.5)
+	inicio := x + (largura-modulo*float64(len(modulos)))/2
+	barrasY := y + 13
+	barrasAltura := altura - 26
+	for i, escuro := range modulos {
+		if escuro {
+			d.retangulo(inicio+float64(i)*modulo, barrasY, modulo, barrasAltura)
+		}
+	}
+
+	d.texto(x+(largura-d.larguraTexto(p.Codigo, 9))/2, y+altura-2, 9, p.Codigo)
+	return nil
+}
+
+// Função auxiliar para carregar os produtos na ordem dos IDs informados
+func carregarProdutosEtiqueta(ctx context.Context, ids []int) ([]etiquetaProduto, error) {
+	rows, err := db.Query(ctx, "SELECT id, codigo, nome FROM produtos WHERE id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	porID := map[int]etiquetaProduto{}
+	for rows.Next() {
+		var p etiquetaProduto
+		if err := rows.Scan(&p.ID, &p.Codigo, &p.Nome); err != nil {
+			return nil, err
+		}
+		porID[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	produtos := []etiquetaProduto{}
+	for _, id := range ids {
+		if p, ok := porID[id]; ok {
+			produtos = append(produtos, p)
+		}
+	}
+	return produtos, nil
+}
+
+// Handlers de Etiquetas
+
+func getEtiquetaProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	formato := c.DefaultQuery("formato", FormatoEtiquetaPNG)
+	tipo := c.DefaultQuery("tipo", EtiquetaCode128)
+	if (formato != FormatoEtiquetaPNG && formato != FormatoEtiquetaPDF) || (tipo != EtiquetaCode128 && tipo != EtiquetaQRCode) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Use formato=png|pdf e tipo=code128|qrcode"})
+		return
+	}
+
+	var p etiquetaProduto
+	err = db.QueryRow(c.Request.Context(), "SELECT id, codigo, nome FROM produtos WHERE id = $1", id).Scan(&p.ID, &p.Codigo, &p.Nome)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+
+	log.Printf("[API] Gerando etiqueta %s/%s do produto %s", tipo, formato, p.Codigo)
+
+	if formato == FormatoEtiquetaPDF {
+		d := novoPDF(100*pontosPorMM, 50*pontosPorMM)
+		if err := desenharEtiqueta(d, 0, 0, d.largura, d.altura, p, tipo, lerConfiguracao(c.Request.Context(), "empresa_nome", "")); err != nil {
+			log.Printf("[ERROR] Erro ao gerar etiqueta do produto %s: %v", p.Codigo, err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Etiqueta: " + err.Error()})
+			return
+		}
+		conteudo, err := d.bytes()
+		if err != nil {
+			log.Printf("[ERROR] Erro ao gerar PDF da etiqueta do produto %s: %v", p.Codigo, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar etiqueta"})
+			return
+		}
+		c.Header("Content-Disposition", "inline; filename=etiqueta-"+strconv.Itoa(id)+".pdf")
+		c.Data(http.StatusOK, "application/pdf", conteudo)
+		return
+	}
+
+	var buf bytes.Buffer
+	if tipo == EtiquetaQRCode {
+		var modulos [][]bool
+		if modulos, err = qrCode(p.Codigo); err == nil {
+			err = png.Encode(&buf, imagemQRCode(modulos, 8))
+		}
+	} else {
+		var modulos []bool
+		if modulos, err = code128(p.Codigo); err == nil {
+			err = png.Encode(&buf, imagemCode128(modulos, 2, 80))
+		}
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gerar etiqueta do produto %s: %v", p.Codigo, err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Etiqueta: " + err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+func gerarEtiquetasLote(c *gin.Context) {
+	var req LoteEtiquetas
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if req.Tipo == "" {
+		req.Tipo = EtiquetaCode128
+	}
+	if req.Tipo != EtiquetaCode128 && req.Tipo != EtiquetaQRCode {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Tipo deve ser code128 ou qrcode"})
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > etiquetasLoteMaximo {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Informe entre 1 e %d produtos", etiquetasLoteMaximo),
+		})
+		return
+	}
+
+	produtos, err := carregarProdutosEtiqueta(c.Request.Context(), req.IDs)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar produtos para etiquetas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
+		return
+	}
+	if len(produtos) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Nenhum produto encontrado"})
+		return
+	}
+
+	log.Printf("[API] Gerando folha com %d etiquetas (%s)", len(produtos), req.Tipo)
+
+	// Folha A4 com 3 colunas e 8 linhas de 70 x 37,1 mm
+	const colunas, linhas = 3, 8
+	d := novoPDF(210*pontosPorMM, 297*pontosPorMM)
+	largura, altura := d.largura/colunas, d.altura/linhas
+	empresa := lerConfiguracao(c.Request.Context(), "empresa_nome", "")
+	for i, p := range produtos {
+		posicao := i % (colunas * linhas)
+		if posicao == 0 {
+			d.novaPagina()
+		}
+		x := float64(posicao%colunas) * largura
+		y := float64(posicao/colunas) * altura
+		if err := desenharEtiqueta(d, x, y, largura, altura, p, req.Tipo, empresa); err != nil {
+			log.Printf("[ERROR] Erro ao gerar etiqueta do produto %s: %v", p.Codigo, err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Etiqueta do produto %s: %v", p.Codigo, err)})
+			return
+		}
+	}
+
+	conteudo, err := d.bytes()
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gerar PDF da folha de etiquetas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar etiquetas"})
+		return
+	}
+	c.Header("Content-Disposition", "inline; filename=etiquetas.pdf")
+	c.Data(http.StatusOK, "application/pdf", conteudo)
+}
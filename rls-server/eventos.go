@@ -0,0 +1,243 @@
+// eventos.go - Barramento interno de eventos e stream SSE
+//
+// Os handlers publicam eventos (produto.atualizado, movimentacao.criada,
+// estoque.baixo, comentario.criado) depois de confirmar a escrita; GET /api/stream repassa os
+// eventos aos clientes conectados via Server-Sent Events, substituindo o
+// polling do tablet do almoxarifado. Clientes lentos perdem eventos em vez de
+// travar quem publica.
+
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de evento publicados
+const (
+	EventoProdutoAtualizado  = "produto.atualizado"
+	EventoMovimentacaoCriada = "movimentacao.criada"
+	EventoEstoqueBaixo       = "estoque.baixo"
+	EventoComentarioCriado   = "comentario.criado"
+)
+
+// Configuração do stream - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	streamBufferEventos = getEnvAsInt("STREAM_BUFFER_EVENTOS", 64)
+	streamPingSegundos  = getEnvAsInt("STREAM_PING_SEGUNDOS", 25)
+	streamHistorico     = getEnvAsInt("STREAM_HISTORICO_EVENTOS", 200)
+)
+
+type Evento struct {
+	// Sequência crescente; clientes que reconectam pedem os eventos após a
+	// última recebida. Entregas de webhook não têm sequência (ver X-Estoque-Entrega)
+	Seq   int64     `json:"seq,omitempty"`
+	Tipo  string    `json:"tipo"`
+	Dados any       `json:"dados"`
+	Data  time.Time `json:"data"`
+}
+
+// Dados do evento estoque.baixo
+type AlertaEstoqueBaixo struct {
+	ProdutoID        int    `json:"produto_id"`
+	Codigo           string `json:"codigo"`
+	Nome             string `json:"nome"`
+	Quantidade       int    `json:"quantidade"`
+	QuantidadeMinima int    `json:"quantidade_minima"`
+}
+
+type barramentoEventos struct {
+	mu        sync.Mutex
+	inscritos map[chan Evento]struct{}
+	seq       int64
+	historico []Evento
+}
+
+var eventos = &barramentoEventos{inscritos: map[chan Evento]struct{}{}}
+
+func (b *barramentoEventos) inscrever() chan Evento {
+	ch := make(chan Evento, max(streamBufferEventos, 1))
+	b.mu.Lock()
+	b.inscritos[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *barramentoEventos) cancelar(ch chan Evento) {
+	b.mu.Lock()
+	delete(b.inscritos, ch)
+	b.mu.Unlock()
+}
+
+// Entrega o evento a todos os inscritos sem bloquear
+func (b *barramentoEventos) publicar(tipo string, dados any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e := Evento{Seq: b.seq, Tipo: tipo, Dados: dados, Data: time.Now()}
+
+	// Manter os últimos eventos para a reconexão
+	b.historico = append(b.historico, e)
+	if excesso := len(b.historico) - max(streamHistorico, 0); excesso > 0 {
+		b.historico = append(b.historico[:0], b.historico[excesso:]...)
+	}
+
+	for ch := range b.inscritos {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("[WARN] Cliente do stream lento, evento %s descartado", tipo)
+		}
+	}
+
+	// As entregas do evento já foram gravadas com a escrita; só acordar o envio
+	if eventosWebhook[tipo] {
+		sinalizarWebhooks()
+	}
+}
+
+// Sequência do último evento publicado
+func (b *barramentoEventos) sequencia() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Eventos publicados e ainda não consumidos pelos inscritos
+func (b *barramentoEventos) pendentes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for ch := range b.inscritos {
+		n += len(ch)
+	}
+	return n
+}
+
+// Espera os inscritos consumirem os eventos pendentes (usado no desligamento)
+func (b *barramentoEventos) aguardarEntrega() {
+	for b.pendentes() > 0 {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Eventos do histórico com sequência maior que seq
+func (b *barramentoEventos) desde(seq int64) []Evento {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pendentes := []Evento{}
+	for _, e := range b.historico {
+		if e.Seq > seq {
+			pendentes = append(pendentes, e)
+		}
+	}
+	return pendentes
+}
+
+// Alerta de estoque baixo se o saldo ficou abaixo do mínimo (padrão 5, como no dashboard)
+func alertaEstoqueBaixo(p Produto) (AlertaEstoqueBaixo, bool) {
+	// Kits não têm estoque próprio; o alerta vem dos componentes
+	if p.Kit {
+		return AlertaEstoqueBaixo{}, false
+	}
+	minimo := p.QuantidadeMinima
+	if minimo == 0 {
+		minimo = 5
+	}
+	return AlertaEstoqueBaixo{
+		ProdutoID:        p.ID,
+		Codigo:           p.Codigo,
+		Nome:             p.Nome,
+		Quantidade:       p.Quantidade,
+		QuantidadeMinima: minimo,
+	}, p.Quantidade < minimo
+}
+
+// Publica estoque.baixo se o saldo ficou abaixo do mínimo
+func publicarSeEstoqueBaixo(p Produto) {
+	if alerta, ok := alertaEstoqueBaixo(p); ok {
+		eventos.publicar(EventoEstoqueBaixo, alerta)
+	}
+}
+
+// Publica as movimentações confirmadas e os alertas de estoque baixo das saídas
+func publicarMovimentacoes(ctx context.Context, movimentacoes []Movimentacao) {
+	// A escrita já foi confirmada: os alertas não dependem do cliente continuar conectado
+	ctx = context.WithoutCancel(ctx)
+	for _, m := range movimentacoes {
+		// Saída de kit: o que foi registrado são as saídas dos componentes
+		if len(m.Componentes) > 0 {
+			publicarMovimentacoes(ctx, m.Componentes)
+			continue
+		}
+		eventos.publicar(EventoMovimentacaoCriada, m)
+		if m.Tipo != "saida" {
+			continue
+		}
+
+		p, err := scanProduto(db.QueryRow(ctx, "SELECT "+produtoColunas+" FROM produtos WHERE id = $1", m.ProdutoID))
+		if err != nil {
+			log.Printf("[WARN] Erro ao verificar estoque baixo do produto %d: %v", m.ProdutoID, err)
+			continue
+		}
+		publicarSeEstoqueBaixo(p)
+	}
+}
+
+// Handler para o stream de eventos
+
+func getStream(c *gin.Context) {
+	// Filtro opcional: ?tipos=movimentacao.criada,estoque.baixo
+	var tipos map[string]bool
+	if t := c.Query("tipos"); t != "" {
+		tipos = map[string]bool{}
+		for _, tipo := range strings.Split(t, ",") {
+			tipos[strings.TrimSpace(tipo)] = true
+		}
+	}
+
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+
+	log.Printf("[API] Cliente conectado ao stream: %s", c.ClientIP())
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ping := time.NewTicker(time.Duration(max(streamPingSegundos, 1)) * time.Second)
+	defer ping.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-encerrando:
+			return false
+		case <-ping.C:
+			// Comentário SSE mantém a conexão viva através de proxies
+			io.WriteString(w, ": ping\n\n")
+			return true
+		case e := <-ch:
+			if tipos == nil || tipos[e.Tipo] {
+				c.SSEvent(e.Tipo, e)
+			}
+			return true
+		}
+	})
+
+	log.Printf("[API] Cliente desconectado do stream: %s", c.ClientIP())
+}
@@ -0,0 +1,193 @@
+// exportacoes.go - Auditoria e limite diário das exportações de dados
+//
+// As rotas que exportam dados (relatórios em PDF e exportação das
+// configurações) passam pelo middleware AuditarExportacao, que grava em
+// exportacoes quem exportou (o IP e, informado pelo cliente, o cabeçalho
+// X-Usuario), os filtros da requisição e o volume (registros e bytes). Acima
+// do limite exportacoes_limite_diario a requisição é recusada com 429 e a
+// tentativa fica registrada como bloqueada. Sem autenticação, o X-Usuario é
+// escolhido pelo próprio cliente, então o limite é contado por IP.
+//
+// As listagens em massa (produtos, que o app usa na sincronização, e
+// movimentações) passam por AuditarListagem: páginas maiores que
+// exportacoes_limite_pagina valem como exportação.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cabeçalho com o nome de quem faz a requisição
+const headerUsuario = "X-Usuario"
+
+// Chave do contexto onde o handler informa quantos registros exportou
+const chaveRegistrosExportacao = "exportacao_registros"
+
+type Exportacao struct {
+	ID          int64             `json:"id"`
+	Tipo        string            `json:"tipo"`
+	Usuario     string            `json:"usuario"`
+	IP          string            `json:"ip"`
+	Filtros     map[string]string `json:"filtros"`
+	Registros   int               `json:"registros"`
+	Bytes       int64             `json:"bytes"`
+	Status      int               `json:"status"`
+	Bloqueada   bool              `json:"bloqueada"`
+	DataCriacao time.Time         `json:"data_criacao"`
+}
+
+// Nome informado por quem exporta: o cabeçalho X-Usuario ou, sem ele, o IP.
+// Só identifica o registro; o limite é contado pelo IP
+func usuarioExportacao(c *gin.Context) string {
+	if usuario := strings.TrimSpace(c.GetHeader(headerUsuario)); usuario != "" {
+		if len(usuario) > 100 {
+			usuario = usuario[:100]
+		}
+		return usuario
+	}
+	return c.ClientIP()
+}
+
+func registrarExportacao(ctx context.Context, e Exportacao) {
+	filtros, err := json.Marshal(e.Filtros)
+	if err != nil {
+		filtros = []byte("{}")
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO exportacoes (tipo, usuario, ip, filtros, registros, bytes, status, bloqueada)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, e.Tipo, e.Usuario, e.IP, filtros, e.Registros, e.Bytes, e.Status, e.Bloqueada)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao registrar exportação %s de %s: %v", e.Tipo, e.Usuario, err)
+	}
+}
+
+// Middleware das rotas de exportação: aplica o limite diário e registra a
+// exportação depois da resposta
+func AuditarExportacao(tipo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.Background()
+		e := Exportacao{
+			Tipo:    tipo,
+			Usuario: usuarioExportacao(c),
+			IP:      c.ClientIP(),
+			Filtros: map[string]string{},
+		}
+		for chave, valores := range c.Request.URL.Query() {
+			e.Filtros[chave] = strings.Join(valores, ",")
+		}
+
+		limite, err := strconv.Atoi(lerConfiguracao(ctx, "exportacoes_limite_diario", "20"))
+		if err != nil {
+			limite = 20
+		}
+		if limite > 0 {
+			// Só exportações completas contam para o limite; retomadas de
+			// download (206) e revalidações (304) não
+			var feitas int
+			err := db.QueryRow(ctx, `
+				SELECT COUNT(*) FROM exportacoes
+				WHERE ip = $1 AND NOT bloqueada AND status = 200 AND data_criacao >= CURRENT_DATE
+			`, e.IP).Scan(&feitas)
+			if err != nil {
+				log.Printf("[WARN] Erro ao contar exportações de %s: %v", e.IP, err)
+			} else if feitas >= limite {
+				e.Status = http.StatusTooManyRequests
+				e.Bloqueada = true
+				registrarExportacao(ctx, e)
+
+				log.Printf("[WARN] Exportação %s bloqueada para %s (%s): limite diário de %d atingido", tipo, e.IP, e.Usuario, limite)
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+					Error: fmt.Sprintf("Limite diário de %d exportações atingido", limite),
+				})
+				return
+			}
+		}
+
+		c.Next()
+
+		e.Registros = c.GetInt(chaveRegistrosExportacao)
+		e.Bytes = int64(max(c.Writer.Size(), 0))
+		e.Status = c.Writer.Status()
+		registrarExportacao(ctx, e)
+	}
+}
+
+// Middleware das listagens paginadas: uma página acima de
+// exportacoes_limite_pagina registros é uma exportação da base e passa pelo
+// limite diário e pela auditoria; as páginas normais seguem direto
+func AuditarListagem(tipo string) gin.HandlerFunc {
+	auditar := AuditarExportacao(tipo)
+	return func(c *gin.Context) {
+		pagina, err := strconv.Atoi(lerConfiguracao(c.Request.Context(), "exportacoes_limite_pagina", "500"))
+		if err != nil {
+			pagina = 500
+		}
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if pagina > 0 && err == nil && limit > pagina {
+			auditar(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Handler para consulta do registro de exportações
+
+func getExportacoes(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 30)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+	bloqueadas := c.Query("bloqueadas") == "true"
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, tipo, usuario, ip, filtros, registros, bytes, status, bloqueada, data_criacao
+		FROM exportacoes
+		WHERE data_criacao >= $1 AND data_criacao < $2
+			AND ($3::text = '' OR usuario = $3::text)
+			AND ($4::text = '' OR tipo = $4::text)
+			AND (NOT $5::boolean OR bloqueada)
+		ORDER BY id DESC
+		LIMIT 1000
+	`, de, ate, c.Query("usuario"), c.Query("tipo"), bloqueadas)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar exportações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar exportações"})
+		return
+	}
+	defer rows.Close()
+
+	exportacoes := []Exportacao{}
+	for rows.Next() {
+		var e Exportacao
+		err := rows.Scan(&e.ID, &e.Tipo, &e.Usuario, &e.IP, &e.Filtros, &e.Registros, &e.Bytes,
+			&e.Status, &e.Bloqueada, &e.DataCriacao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar exportação: %v", err)
+			continue
+		}
+		exportacoes = append(exportacoes, e)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar exportações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar exportações"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exportacoes)
+}
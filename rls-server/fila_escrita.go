@@ -0,0 +1,138 @@
+// fila_escrita.go - Fila de escrita com limite e backpressure
+//
+// Nos picos de movimentação (troca de turno) as escritas disputam as conexões
+// do pool. Este middleware deixa no máximo FILA_ESCRITA_CONCORRENCIA escritas
+// em execução e FILA_ESCRITA_CAPACIDADE no total (em execução + aguardando);
+// com a fila cheia a requisição recebe 429 com Retry-After em vez de esperar
+// indefinidamente. Leituras (GET, HEAD, OPTIONS) não passam pela fila.
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração da fila - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	filaEscritaCapacidade   = getEnvAsInt("FILA_ESCRITA_CAPACIDADE", 100)
+	filaEscritaConcorrencia = getEnvAsInt("FILA_ESCRITA_CONCORRENCIA", 6)
+	filaEscritaRetryAfter   = getEnvAsInt("FILA_ESCRITA_RETRY_AFTER", 2)
+)
+
+type filaEscrita struct {
+	fila  chan struct{}
+	slots chan struct{}
+
+	mu                 sync.Mutex
+	processadas        int64
+	rejeitadas         int64
+	canceladas         int64
+	profundidadeMaxima int
+	esperaTotal        time.Duration
+}
+
+type MetricasFilaEscrita struct {
+	Capacidade         int     `json:"capacidade"`
+	Concorrencia       int     `json:"concorrencia"`
+	Profundidade       int     `json:"profundidade"`
+	EmExecucao         int     `json:"em_execucao"`
+	ProfundidadeMaxima int     `json:"profundidade_maxima"`
+	Processadas        int64   `json:"processadas"`
+	Rejeitadas         int64   `json:"rejeitadas"`
+	Canceladas         int64   `json:"canceladas"`
+	EsperaMediaMs      float64 `json:"espera_media_ms"`
+}
+
+var filaEscritaPadrao = novaFilaEscrita(filaEscritaCapacidade, filaEscritaConcorrencia)
+
+func novaFilaEscrita(capacidade, concorrencia int) *filaEscrita {
+	concorrencia = max(concorrencia, 1)
+	capacidade = max(capacidade, concorrencia)
+	return &filaEscrita{
+		fila:  make(chan struct{}, capacidade),
+		slots: make(chan struct{}, concorrencia),
+	}
+}
+
+// Função auxiliar para montar as métricas atuais da fila
+func (f *filaEscrita) metricas() MetricasFilaEscrita {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := MetricasFilaEscrita{
+		Capacidade:         cap(f.fila),
+		Concorrencia:       cap(f.slots),
+		Profundidade:       len(f.fila),
+		EmExecucao:         len(f.slots),
+		ProfundidadeMaxima: f.profundidadeMaxima,
+		Processadas:        f.processadas,
+		Rejeitadas:         f.rejeitadas,
+		Canceladas:         f.canceladas,
+	}
+	if f.processadas > 0 {
+		m.EsperaMediaMs = float64(f.esperaTotal.Milliseconds()) / float64(f.processadas)
+	}
+	return m
+}
+
+// FilaEscrita middleware
+func FilaEscrita() gin.HandlerFunc {
+	f := filaEscritaPadrao
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		// Entrar na fila sem bloquear; cheia, devolve backpressure ao cliente
+		select {
+		case f.fila <- struct{}{}:
+		default:
+			f.mu.Lock()
+			f.rejeitadas++
+			f.mu.Unlock()
+			log.Printf("[WARN] Fila de escrita cheia (%d), rejeitando %s %s", cap(f.fila), c.Request.Method, c.Request.URL.Path)
+			c.Header("Retry-After", strconv.Itoa(filaEscritaRetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "Servidor ocupado, tente novamente em instantes"})
+			return
+		}
+		defer func() { <-f.fila }()
+
+		f.mu.Lock()
+		f.profundidadeMaxima = max(f.profundidadeMaxima, len(f.fila))
+		f.mu.Unlock()
+
+		// Aguardar a vez de executar; desiste se o cliente desconectar
+		inicio := time.Now()
+		select {
+		case f.slots <- struct{}{}:
+		case <-c.Request.Context().Done():
+			f.mu.Lock()
+			f.canceladas++
+			f.mu.Unlock()
+			c.Abort()
+			return
+		}
+		defer func() { <-f.slots }()
+
+		f.mu.Lock()
+		f.processadas++
+		f.esperaTotal += time.Since(inicio)
+		f.mu.Unlock()
+
+		c.Next()
+	}
+}
+
+// Handler para métricas da fila de escrita
+
+func getFilaEscrita(c *gin.Context) {
+	c.JSON(http.StatusOK, filaEscritaPadrao.metricas())
+}
@@ -0,0 +1,341 @@
+// filiais.go - Disponibilidade de produtos nas outras filiais
+//
+// As filiais da rede são cadastradas em /api/filiais com a URL da instância
+// de cada uma. GET /api/produtos/:codigo/disponibilidade-rede consulta em
+// paralelo a consulta pública de saldo (/api/consulta/:codigo) de cada filial
+// ativa e devolve quantidade, localização e contato. As respostas ficam em
+// cache por DISPONIBILIDADE_CACHE_SEGUNDOS; uma filial fora do ar aparece
+// como indisponível sem atrasar as demais além do timeout.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Configuração da consulta federada - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	disponibilidadeCacheSegundos   = getEnvAsInt("DISPONIBILIDADE_CACHE_SEGUNDOS", 60)
+	disponibilidadeTimeoutSegundos = getEnvAsInt("DISPONIBILIDADE_TIMEOUT_SEGUNDOS", 5)
+)
+
+// Situação de uma filial na consulta de disponibilidade
+const (
+	DisponibilidadeOK           = "ok"
+	DisponibilidadeSemProduto   = "sem_produto"
+	DisponibilidadeIndisponivel = "indisponivel"
+)
+
+type Filial struct {
+	ID          int       `json:"id,omitempty"`
+	Nome        string    `json:"nome"`
+	URL         string    `json:"url"`
+	Contato     string    `json:"contato,omitempty"`
+	Ativo       bool      `json:"ativo"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+type DisponibilidadeFilial struct {
+	FilialID     int       `json:"filial_id"`
+	Filial       string    `json:"filial"`
+	Contato      string    `json:"contato,omitempty"`
+	Situacao     string    `json:"situacao"`
+	Quantidade   int       `json:"quantidade"`
+	Unidade      string    `json:"unidade,omitempty"`
+	Localizacao  string    `json:"localizacao,omitempty"`
+	Erro         string    `json:"erro,omitempty"`
+	ConsultadoEm time.Time `json:"consultado_em"`
+}
+
+type entradaDisponibilidade struct {
+	disponibilidade DisponibilidadeFilial
+	expira          time.Time
+}
+
+var (
+	disponibilidadeCacheMutex sync.Mutex
+	disponibilidadeCache      = map[string]entradaDisponibilidade{}
+)
+
+var filialClient = &http.Client{Timeout: time.Duration(max(disponibilidadeTimeoutSegundos, 1)) * time.Second}
+
+// Consulta o saldo do código em uma filial, usando o cache quando válido.
+// Falhas de conexão não entram no cache, para a próxima consulta tentar de novo.
+func consultarFilial(ctx context.Context, f Filial, codigo string) DisponibilidadeFilial {
+	chave := strconv.Itoa(f.ID) + "|" + codigo
+
+	disponibilidadeCacheMutex.Lock()
+	entrada, ok := disponibilidadeCache[chave]
+	disponibilidadeCacheMutex.Unlock()
+	if ok && time.Now().Before(entrada.expira) {
+		return entrada.disponibilidade
+	}
+
+	d := DisponibilidadeFilial{FilialID: f.ID, Filial: f.Nome, Contato: f.Contato, ConsultadoEm: time.Now()}
+
+	// Caminho sem versão: as filiais podem rodar versões anteriores à /api/v1
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(f.URL, "/")+"/api/consulta/"+url.PathEscape(codigo), nil)
+	if err != nil {
+		d.Situacao, d.Erro = DisponibilidadeIndisponivel, err.Error()
+		return d
+	}
+
+	if id := idRequisicao(ctx); id != "" {
+		req.Header.Set(headerRequestID, id)
+	}
+	propagarTrace(ctx, req.Header)
+
+	resp, err := filialClient.Do(req)
+	if err != nil {
+		d.Situacao, d.Erro = DisponibilidadeIndisponivel, "filial não respondeu"
+		log.Printf("[WARN] Filial %s não respondeu à consulta de %s: %v", f.Nome, codigo, err)
+		return d
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var saldo ConsultaSaldo
+		if err := json.NewDecoder(resp.Body).Decode(&saldo); err != nil {
+			d.Situacao, d.Erro = DisponibilidadeIndisponivel, "resposta inválida da filial"
+			return d
+		}
+		d.Situacao = DisponibilidadeOK
+		d.Quantidade = saldo.Quantidade
+		d.Unidade = saldo.Unidade
+		d.Localizacao = saldo.Localizacao
+	case http.StatusNotFound:
+		d.Situacao = DisponibilidadeSemProduto
+	default:
+		d.Situacao, d.Erro = DisponibilidadeIndisponivel, fmt.Sprintf("filial respondeu HTTP %d", resp.StatusCode)
+		return d
+	}
+
+	disponibilidadeCacheMutex.Lock()
+	disponibilidadeCache[chave] = entradaDisponibilidade{
+		disponibilidade: d,
+		expira:          time.Now().Add(time.Duration(disponibilidadeCacheSegundos) * time.Second),
+	}
+	disponibilidadeCacheMutex.Unlock()
+	return d
+}
+
+// Função auxiliar para carregar as filiais ativas, por nome
+func carregarFiliaisAtivas(ctx context.Context) ([]Filial, error) {
+	rows, err := db.Query(ctx, "SELECT id, nome, url, contato FROM filiais WHERE ativo ORDER BY nome")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filiais []Filial
+	for rows.Next() {
+		var f Filial
+		var contato *string
+		if err := rows.Scan(&f.ID, &f.Nome, &f.URL, &contato); err != nil {
+			return nil, err
+		}
+
+		// Tratar campos nulos
+		if contato != nil {
+			f.Contato = *contato
+		}
+		f.Ativo = true
+		filiais = append(filiais, f)
+	}
+	return filiais, rows.Err()
+}
+
+// Handler para a disponibilidade na rede
+
+// O segmento da URL é o código do produto; o parâmetro se chama id para
+// compartilhar a árvore de rotas de /produtos/:id
+func getDisponibilidadeRede(c *gin.Context) {
+	codigo := c.Param("id")
+	log.Printf("[API] Consultando disponibilidade na rede do produto: %s", codigo)
+
+	ctx := c.Request.Context()
+	filiais, err := carregarFiliaisAtivas(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar filiais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar filiais"})
+		return
+	}
+
+	resultado := make([]DisponibilidadeFilial, len(filiais))
+	var wg sync.WaitGroup
+	for i, f := range filiais {
+		wg.Add(1)
+		go func(i int, f Filial) {
+			defer wg.Done()
+			resultado[i] = consultarFilial(ctx, f, codigo)
+		}(i, f)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{"codigo": codigo, "filiais": resultado})
+}
+
+// Função auxiliar para validar os dados de uma filial
+func validarFilial(f Filial) string {
+	if strings.TrimSpace(f.Nome) == "" {
+		return "Nome é obrigatório"
+	}
+	u, err := url.Parse(f.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL inválida, use http:// ou https://"
+	}
+	return ""
+}
+
+// Handlers de Filiais
+
+func getFiliais(c *gin.Context) {
+	log.Println("[DB] Buscando filiais")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, nome, url, contato, ativo, data_criacao
+		FROM filiais
+		ORDER BY nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar filiais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar filiais"})
+		return
+	}
+	defer rows.Close()
+
+	filiais := []Filial{}
+	for rows.Next() {
+		var f Filial
+		var contato *string
+		if err := rows.Scan(&f.ID, &f.Nome, &f.URL, &contato, &f.Ativo, &f.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar filial: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if contato != nil {
+			f.Contato = *contato
+		}
+		filiais = append(filiais, f)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar filiais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar filiais"})
+		return
+	}
+
+	c.JSON(http.StatusOK, filiais)
+}
+
+func criarFilial(c *gin.Context) {
+	f := Filial{Ativo: true}
+	if err := c.ShouldBindJSON(&f); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarFilial(f); msg != "" {
+		log.Printf("[ERROR] Filial inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO filiais(nome, url, contato, ativo)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id, data_criacao
+	`, f.Nome, f.URL, f.Contato, f.Ativo).Scan(&f.ID, &f.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar filial: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar filial (verifique se o nome já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Filial criada: %s (ID: %d)", f.Nome, f.ID)
+	c.JSON(http.StatusCreated, f)
+}
+
+func atualizarFilial(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var f Filial
+	if err := c.ShouldBindJSON(&f); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarFilial(f); msg != "" {
+		log.Printf("[ERROR] Filial inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE filiais SET nome = $1, url = $2, contato = NULLIF($3, ''), ativo = $4
+		WHERE id = $5
+		RETURNING id, data_criacao
+	`, f.Nome, f.URL, f.Contato, f.Ativo, id).Scan(&f.ID, &f.DataCriacao)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Filial não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Filial não encontrada"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar filial: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar filial"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Filial atualizada com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, f)
+}
+
+func deletarFilial(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM filiais WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir filial: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir filial"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Filial não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Filial não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Filial excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Filial excluída com sucesso"})
+}
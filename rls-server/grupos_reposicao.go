@@ -0,0 +1,476 @@
+// grupos_reposicao.go - Grupos de produtos com política de reposição própria (teste A/B)
+//
+// Cada grupo define a política usada nas sugestões de reposição dos seus
+// produtos: min_max (repõe até a quantidade máxima, o comportamento padrão) ou
+// ponto_pedido (ao atingir o ponto de pedido compra cobertura_dias de consumo).
+// Um produto participa de no máximo um grupo por vez; os períodos de
+// participação ficam registrados, e os produtos fora de grupos formam o grupo
+// de controle.
+//
+// GET /api/relatorios/grupos-reposicao compara os grupos no período (de/ate ou
+// dias) a partir das fotografias diárias do estoque: rupturas (produto-dias
+// com saldo zerado), giro (saídas / estoque médio) e capital imobilizado
+// médio (saldo × preço de custo atual), no total e por semana.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Políticas de reposição
+const (
+	PoliticaMinMax      = "min_max"
+	PoliticaPontoPedido = "ponto_pedido"
+)
+
+// Nome do grupo de controle (produtos fora de grupos) no relatório
+const nomeGrupoControle = "Sem grupo"
+
+type GrupoReposicao struct {
+	ID            int       `json:"id,omitempty"`
+	Nome          string    `json:"nome"`
+	Descricao     string    `json:"descricao,omitempty"`
+	Politica      string    `json:"politica"`
+	CoberturaDias int       `json:"cobertura_dias"`
+	Produtos      int       `json:"produtos"`
+	DataCriacao   time.Time `json:"data_criacao,omitempty"`
+}
+
+// Produtos incluídos no grupo; saem do grupo em que estavam
+type ProdutosGrupoReposicao struct {
+	ProdutoIDs []int `json:"produto_ids"`
+}
+
+type SemanaGrupoReposicao struct {
+	Semana             time.Time `json:"semana"`
+	Rupturas           int       `json:"rupturas"`
+	Saidas             int       `json:"saidas"`
+	EstoqueMedio       float64   `json:"estoque_medio"`
+	CapitalImobilizado float64   `json:"capital_imobilizado"`
+}
+
+type ComparacaoGrupoReposicao struct {
+	GrupoID            int                    `json:"grupo_id"` // 0 = grupo de controle
+	Grupo              string                 `json:"grupo"`
+	Politica           string                 `json:"politica"`
+	Produtos           int                    `json:"produtos"` // produtos distintos no período
+	ProdutoDias        int                    `json:"produto_dias"`
+	Rupturas           int                    `json:"rupturas"`
+	TaxaRuptura        float64                `json:"taxa_ruptura"`
+	Saidas             int                    `json:"saidas"`
+	EstoqueMedio       float64                `json:"estoque_medio"`
+	Giro               float64                `json:"giro"`
+	CapitalImobilizado float64                `json:"capital_imobilizado"` // média diária
+	Semanas            []SemanaGrupoReposicao `json:"semanas"`
+}
+
+type RelatorioGruposReposicao struct {
+	De     time.Time                  `json:"de"`
+	Ate    time.Time                  `json:"ate"`
+	Grupos []ComparacaoGrupoReposicao `json:"grupos"`
+}
+
+// Função auxiliar para validar o grupo e completar a cobertura padrão
+func validarGrupoReposicao(g *GrupoReposicao) string {
+	if g.Nome == "" {
+		return "Nome do grupo é obrigatório"
+	}
+	if g.Politica != PoliticaMinMax && g.Politica != PoliticaPontoPedido {
+		return "Política inválida, use " + PoliticaMinMax + " ou " + PoliticaPontoPedido
+	}
+	if g.CoberturaDias == 0 {
+		g.CoberturaDias = 30
+	}
+	if g.CoberturaDias < 0 {
+		return "Cobertura em dias deve ser positiva"
+	}
+	return ""
+}
+
+// Handlers de Grupos de reposição
+
+func getGruposReposicao(c *gin.Context) {
+	log.Println("[DB] Buscando grupos de reposição")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT g.id, g.nome, COALESCE(g.descricao, ''), g.politica, g.cobertura_dias, g.data_criacao,
+		       (SELECT COUNT(*) FROM grupos_reposicao_produtos gp WHERE gp.grupo_id = g.id AND gp.fim IS NULL)
+		FROM grupos_reposicao g
+		ORDER BY g.nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar grupos de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar grupos de reposição"})
+		return
+	}
+	defer rows.Close()
+
+	grupos := []GrupoReposicao{}
+	for rows.Next() {
+		var g GrupoReposicao
+		if err := rows.Scan(&g.ID, &g.Nome, &g.Descricao, &g.Politica, &g.CoberturaDias, &g.DataCriacao, &g.Produtos); err != nil {
+			log.Printf("[ERROR] Erro ao processar grupo de reposição: %v", err)
+			continue
+		}
+		grupos = append(grupos, g)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar grupos de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar grupos de reposição"})
+		return
+	}
+
+	c.JSON(http.StatusOK, grupos)
+}
+
+func criarGrupoReposicao(c *gin.Context) {
+	var g GrupoReposicao
+	if err := c.ShouldBindJSON(&g); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarGrupoReposicao(&g); msg != "" {
+		log.Printf("[ERROR] Grupo de reposição inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO grupos_reposicao(nome, descricao, politica, cobertura_dias)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING id, data_criacao
+	`, g.Nome, g.Descricao, g.Politica, g.CoberturaDias).Scan(&g.ID, &g.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar grupo de reposição: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar grupo de reposição (verifique se o nome já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Grupo de reposição criado: %s (ID: %d, política %s)", g.Nome, g.ID, g.Politica)
+	c.JSON(http.StatusCreated, g)
+}
+
+func atualizarGrupoReposicao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var g GrupoReposicao
+	if err := c.ShouldBindJSON(&g); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarGrupoReposicao(&g); msg != "" {
+		log.Printf("[ERROR] Grupo de reposição inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE grupos_reposicao SET nome = $1, descricao = NULLIF($2, ''), politica = $3, cobertura_dias = $4
+		WHERE id = $5
+		RETURNING id, data_criacao,
+			(SELECT COUNT(*) FROM grupos_reposicao_produtos gp WHERE gp.grupo_id = $5 AND gp.fim IS NULL)
+	`, g.Nome, g.Descricao, g.Politica, g.CoberturaDias, id).Scan(&g.ID, &g.DataCriacao, &g.Produtos)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Grupo de reposição não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Grupo de reposição não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar grupo de reposição: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar grupo de reposição"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Grupo de reposição atualizado: %s (ID: %d, política %s)", g.Nome, id, g.Politica)
+	c.JSON(http.StatusOK, g)
+}
+
+func deletarGrupoReposicao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Os períodos de participação saem junto (ON DELETE CASCADE)
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM grupos_reposicao WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir grupo de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir grupo de reposição"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Grupo de reposição não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Grupo de reposição não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Grupo de reposição excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Grupo de reposição excluído com sucesso"})
+}
+
+// Encerra a participação atual dos produtos em outros grupos. Participações
+// iniciadas hoje são apagadas em vez de encerradas, para não deixar períodos
+// vazios; as demais terminam hoje.
+func encerrarParticipacoesGrupo(ctx context.Context, q querier, produtoIDs []int, exceto int) error {
+	_, err := q.Exec(ctx, `
+		DELETE FROM grupos_reposicao_produtos
+		WHERE produto_id = ANY($1) AND fim IS NULL AND inicio = CURRENT_DATE AND grupo_id <> $2
+	`, produtoIDs, exceto)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `
+		UPDATE grupos_reposicao_produtos SET fim = CURRENT_DATE
+		WHERE produto_id = ANY($1) AND fim IS NULL AND grupo_id <> $2
+	`, produtoIDs, exceto)
+	return err
+}
+
+// Inclui produtos no grupo, tirando-os do grupo em que estavam
+func incluirProdutosGrupoReposicao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req ProdutosGrupoReposicao
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.ProdutoIDs) == 0 {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe produto_ids"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var existe bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM grupos_reposicao WHERE id = $1)", id).Scan(&existe)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar grupo de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar grupo de reposição"})
+		return
+	}
+	if !existe {
+		log.Printf("[DB] Grupo de reposição não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Grupo de reposição não encontrado"})
+		return
+	}
+
+	if err = encerrarParticipacoesGrupo(ctx, tx, req.ProdutoIDs, id); err != nil {
+		log.Printf("[ERROR] Erro ao encerrar participações anteriores: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao alterar produtos do grupo"})
+		return
+	}
+
+	// Produtos inexistentes ou já no grupo são ignorados
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO grupos_reposicao_produtos(grupo_id, produto_id)
+		SELECT $1, p.id FROM produtos p
+		WHERE p.id = ANY($2)
+		  AND NOT EXISTS (SELECT 1 FROM grupos_reposicao_produtos gp WHERE gp.produto_id = p.id AND gp.fim IS NULL)
+	`, id, req.ProdutoIDs)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao incluir produtos no grupo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao alterar produtos do grupo"})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao confirmar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao confirmar transação"})
+		return
+	}
+
+	log.Printf("[DB] %d produtos incluídos no grupo de reposição %d", tag.RowsAffected(), id)
+	c.JSON(http.StatusOK, gin.H{"message": "Produtos incluídos no grupo", "incluidos": tag.RowsAffected()})
+}
+
+// Tira o produto do grupo; ele volta para o grupo de controle
+func removerProdutoGrupoReposicao(c *gin.Context) {
+	id, err1 := strconv.Atoi(c.Param("id"))
+	produtoID, err2 := strconv.Atoi(c.Param("produto_id"))
+	if err1 != nil || err2 != nil {
+		log.Printf("[ERROR] ID inválido: %s/%s", c.Param("id"), c.Param("produto_id"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var participa bool
+	err := db.QueryRow(c.Request.Context(), `
+		SELECT EXISTS(SELECT 1 FROM grupos_reposicao_produtos WHERE grupo_id = $1 AND produto_id = $2 AND fim IS NULL)
+	`, id, produtoID).Scan(&participa)
+	if err == nil && participa {
+		// 0 não é ID de grupo: encerra a participação atual, seja qual for
+		err = encerrarParticipacoesGrupo(c.Request.Context(), db, []int{produtoID}, 0)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao retirar produto do grupo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao retirar produto do grupo"})
+		return
+	}
+	if !participa {
+		log.Printf("[DB] Produto %d não participa do grupo de reposição %d", produtoID, id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não participa do grupo"})
+		return
+	}
+
+	log.Printf("[DB] Produto %d retirado do grupo de reposição %d", produtoID, id)
+	c.JSON(http.StatusOK, gin.H{"message": "Produto retirado do grupo"})
+}
+
+// Handler do relatório comparativo
+
+func getRelatorioGruposReposicao(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 90)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	log.Printf("[DB] Comparando grupos de reposição de %s a %s", de.Format(formatoData), ate.Format(formatoData))
+
+	// Cada fotografia e cada saída contam para o grupo vigente no dia
+	rows, err := dbLeitura.Query(c.Request.Context(), `
+		WITH diario AS (
+			SELECT COALESCE(gp.grupo_id, 0) AS grupo_id, s.data, s.produto_id, s.quantidade,
+			       s.quantidade * p.preco_custo AS valor
+			FROM estoque_snapshots s
+			JOIN produtos p ON p.id = s.produto_id
+			LEFT JOIN grupos_reposicao_produtos gp ON gp.produto_id = s.produto_id
+				AND s.data >= gp.inicio AND (gp.fim IS NULL OR s.data < gp.fim)
+			WHERE s.data >= $1 AND s.data < $2
+		), estoque AS (
+			SELECT grupo_id, date_trunc('week', data)::date AS semana,
+			       COUNT(DISTINCT data) AS dias, COUNT(*) AS produto_dias,
+			       COUNT(*) FILTER (WHERE quantidade <= 0) AS rupturas,
+			       COALESCE(SUM(quantidade), 0) AS quantidade, COALESCE(SUM(valor), 0)::float8 AS valor
+			FROM diario
+			GROUP BY 1, 2
+		), saidas AS (
+			SELECT COALESCE(gp.grupo_id, 0) AS grupo_id, date_trunc('week', m.data_movimentacao)::date AS semana,
+			       SUM(m.quantidade) AS quantidade
+			FROM movimentacoes m
+			LEFT JOIN grupos_reposicao_produtos gp ON gp.produto_id = m.produto_id
+				AND m.data_movimentacao::date >= gp.inicio AND (gp.fim IS NULL OR m.data_movimentacao::date < gp.fim)
+			WHERE m.tipo = 'saida' AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+			GROUP BY 1, 2
+		), produtos_grupo AS (
+			SELECT grupo_id, COUNT(DISTINCT produto_id) AS produtos FROM diario GROUP BY 1
+		)
+		SELECT COALESCE(e.grupo_id, s.grupo_id), COALESCE(g.nome, $3), COALESCE(g.politica, $4),
+		       COALESCE(pg.produtos, 0), COALESCE(e.semana, s.semana),
+		       COALESCE(e.dias, 0), COALESCE(e.produto_dias, 0), COALESCE(e.rupturas, 0),
+		       COALESCE(e.quantidade, 0), COALESCE(e.valor, 0), COALESCE(s.quantidade, 0)
+		FROM estoque e
+		FULL JOIN saidas s ON s.grupo_id = e.grupo_id AND s.semana = e.semana
+		LEFT JOIN grupos_reposicao g ON g.id = COALESCE(e.grupo_id, s.grupo_id)
+		LEFT JOIN produtos_grupo pg ON pg.grupo_id = COALESCE(e.grupo_id, s.grupo_id)
+		ORDER BY 1, 5
+	`, de, ate, nomeGrupoControle, PoliticaMinMax)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao comparar grupos de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao comparar grupos de reposição"})
+		return
+	}
+	defer rows.Close()
+
+	relatorio := RelatorioGruposReposicao{De: de, Ate: ate.AddDate(0, 0, -1), Grupos: []ComparacaoGrupoReposicao{}}
+	var atual *ComparacaoGrupoReposicao
+	var dias int
+	var somaQuantidade, somaValor float64
+
+	// Fecha as médias do grupo corrente (por dia com fotografia)
+	fechar := func() {
+		if atual == nil {
+			return
+		}
+		if atual.ProdutoDias > 0 {
+			atual.TaxaRuptura = float64(atual.Rupturas) / float64(atual.ProdutoDias)
+		}
+		if dias > 0 {
+			atual.EstoqueMedio = somaQuantidade / float64(dias)
+			atual.CapitalImobilizado = somaValor / float64(dias)
+		}
+		if atual.EstoqueMedio > 0 {
+			atual.Giro = float64(atual.Saidas) / atual.EstoqueMedio
+		}
+		relatorio.Grupos = append(relatorio.Grupos, *atual)
+	}
+
+	for rows.Next() {
+		var grupoID, produtos, diasSemana, produtoDias, rupturas, quantidade, saidas int
+		var nome, politica string
+		var semana time.Time
+		var valor float64
+		err := rows.Scan(&grupoID, &nome, &politica, &produtos, &semana,
+			&diasSemana, &produtoDias, &rupturas, &quantidade, &valor, &saidas)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar semana do grupo: %v", err)
+			continue
+		}
+
+		if atual == nil || atual.GrupoID != grupoID {
+			fechar()
+			atual = &ComparacaoGrupoReposicao{GrupoID: grupoID, Grupo: nome, Politica: politica, Produtos: produtos}
+			dias, somaQuantidade, somaValor = 0, 0, 0
+		}
+
+		s := SemanaGrupoReposicao{Semana: semana, Rupturas: rupturas, Saidas: saidas}
+		if diasSemana > 0 {
+			s.EstoqueMedio = float64(quantidade) / float64(diasSemana)
+			s.CapitalImobilizado = valor / float64(diasSemana)
+		}
+		atual.Semanas = append(atual.Semanas, s)
+		atual.ProdutoDias += produtoDias
+		atual.Rupturas += rupturas
+		atual.Saidas += saidas
+		dias += diasSemana
+		somaQuantidade += float64(quantidade)
+		somaValor += valor
+	}
+	fechar()
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar comparação de grupos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar comparação de grupos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, relatorio)
+}
@@ -0,0 +1,182 @@
+// heartbeat.go - Heartbeat periódico para monitoramento externo
+//
+// A cada heartbeat_intervalo_minutos o servidor envia as métricas principais
+// (banco, pool, migrações, produtos abaixo do mínimo, movimentações das
+// últimas 24 horas e filas pendentes) por POST JSON para heartbeat_url
+// (healthchecks.io, Uptime Kuma ou equivalente) e/ou por e-mail para
+// heartbeat_email. Quando alguma verificação falha, o POST vai para
+// heartbeat_url_falha, se configurada. O serviço de monitoramento avisa quando
+// os heartbeats param de chegar, ou seja, quando a máquina cai.
+//
+// O envio aparece no painel de integrações como "heartbeat" e o teste do
+// painel envia um heartbeat na hora.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+type MetricasHeartbeat struct {
+	Status            string               `json:"status"` // ok ou falha
+	Servidor          string               `json:"servidor"`
+	Data              time.Time            `json:"data"`
+	UptimeSegundos    int64                `json:"uptime_segundos"`
+	Banco             VerificacaoBanco     `json:"banco"`
+	Pool              VerificacaoPool      `json:"pool"`
+	Migracoes         VerificacaoMigracoes `json:"migracoes"`
+	Produtos          int                  `json:"produtos"`
+	AbaixoMinimo      int                  `json:"abaixo_minimo"`
+	Movimentacoes24h  int                  `json:"movimentacoes_24h"`
+	WebhooksPendentes int                  `json:"webhooks_pendentes"`
+	ImpressaoPendente int                  `json:"impressao_pendente"`
+}
+
+var errHeartbeatNaoConfigurado = errors.New("heartbeat não configurado (heartbeat_url ou heartbeat_email)")
+
+// Integração do painel para o heartbeat
+type integracaoHeartbeat struct {
+	metricas MetricasIntegracao
+}
+
+var heartbeat = &integracaoHeartbeat{}
+
+func (i *integracaoHeartbeat) Nome() string                  { return "heartbeat" }
+func (i *integracaoHeartbeat) Tipo() string                  { return "monitoramento" }
+func (i *integracaoHeartbeat) FilaPendente() int             { return 0 }
+func (i *integracaoHeartbeat) Metricas() *MetricasIntegracao { return &i.metricas }
+
+func (i *integracaoHeartbeat) Ativa() bool {
+	ctx := context.Background()
+	return lerConfiguracao(ctx, "heartbeat_url", "") != "" || lerConfiguracao(ctx, "heartbeat_email", "") != ""
+}
+
+// Envia um heartbeat na hora
+func (i *integracaoHeartbeat) Testar(ctx context.Context) error {
+	if !i.Ativa() {
+		return errHeartbeatNaoConfigurado
+	}
+	return enviarHeartbeat(ctx)
+}
+
+// Coleta as métricas do heartbeat; as consultas que falham ficam zeradas e o
+// status vira falha
+func coletarMetricasHeartbeat(ctx context.Context) MetricasHeartbeat {
+	m := MetricasHeartbeat{
+		Status:         "ok",
+		Data:           time.Now(),
+		UptimeSegundos: int64(time.Since(inicioProcesso).Seconds()),
+	}
+	m.Servidor, _ = os.Hostname()
+
+	m.Banco = verificarBanco(ctx)
+	m.Pool = verificarPool()
+	if !m.Banco.OK {
+		m.Status = "falha"
+		return m
+	}
+	m.Migracoes = verificarMigracoes(ctx)
+
+	err := db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM produtos),
+			(SELECT COUNT(*) FROM produtos WHERE quantidade < COALESCE(quantidade_minima, 5) AND NOT kit),
+			(SELECT COUNT(*) FROM movimentacoes WHERE data_movimentacao >= CURRENT_TIMESTAMP - interval '24 hours'),
+			(SELECT COUNT(*) FROM webhook_entregas WHERE status = $1),
+			(SELECT COUNT(*) FROM trabalhos_impressao WHERE status = $2)
+	`, EntregaPendente, TrabalhoPendente).Scan(&m.Produtos, &m.AbaixoMinimo, &m.Movimentacoes24h,
+		&m.WebhooksPendentes, &m.ImpressaoPendente)
+	if err != nil {
+		log.Printf("[WARN] Erro ao coletar métricas do heartbeat: %v", err)
+		m.Status = "falha"
+	}
+	if !m.Migracoes.OK || !m.Pool.OK {
+		m.Status = "falha"
+	}
+	return m
+}
+
+var clienteHeartbeat = &http.Client{Timeout: 10 * time.Second}
+
+// Coleta as métricas e envia para a URL e o e-mail configurados
+func enviarHeartbeat(ctx context.Context) error {
+	m := coletarMetricasHeartbeat(ctx)
+	var erros []error
+
+	url := lerConfiguracao(ctx, "heartbeat_url", "")
+	if falha := lerConfiguracao(ctx, "heartbeat_url_falha", ""); m.Status != "ok" && falha != "" {
+		url = falha
+	}
+	if url != "" {
+		corpo, _ := json.Marshal(m)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(corpo))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			var resp *http.Response
+			if resp, err = clienteHeartbeat.Do(req); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode >= 300 {
+					err = fmt.Errorf("monitoramento respondeu %s", resp.Status)
+				}
+			}
+		}
+		heartbeat.metricas.Registrar(err)
+		if err != nil {
+			erros = append(erros, fmt.Errorf("heartbeat_url: %w", err))
+		}
+	}
+
+	if email := lerConfiguracao(ctx, "heartbeat_email", ""); email != "" {
+		assunto := fmt.Sprintf("Heartbeat %s: %s", m.Servidor, m.Status)
+		corpo := fmt.Sprintf("Servidor: %s\nData: %s\nStatus: %s\nEm execução há: %s\n\n"+
+			"Banco: ok=%t, latência %d ms %s\nPool: %d de %d conexões em uso\nMigrações pendentes: %d\n\n"+
+			"Produtos: %d (%d abaixo do mínimo)\nMovimentações nas últimas 24 horas: %d\n"+
+			"Webhooks pendentes: %d\nTrabalhos de impressão pendentes: %d\n",
+			m.Servidor, m.Data.Format("02/01/2006 15:04"), m.Status,
+			(time.Duration(m.UptimeSegundos) * time.Second).String(),
+			m.Banco.OK, m.Banco.LatenciaMs, m.Banco.Erro, m.Pool.EmUso, m.Pool.Maximo, m.Migracoes.Pendentes,
+			m.Produtos, m.AbaixoMinimo, m.Movimentacoes24h, m.WebhooksPendentes, m.ImpressaoPendente)
+		err := enviarEmail(ctx, []string{email}, assunto, corpo)
+		heartbeat.metricas.Registrar(err)
+		if err != nil {
+			erros = append(erros, fmt.Errorf("heartbeat_email: %w", err))
+		}
+	}
+
+	return errors.Join(erros...)
+}
+
+// Envia um heartbeat no início e a cada intervalo configurado; roda até o
+// contexto ser cancelado
+func iniciarHeartbeat(ctx context.Context) {
+	registrarIntegracao(heartbeat)
+
+	for {
+		if heartbeat.Ativa() {
+			envio, cancelar := context.WithTimeout(ctx, 30*time.Second)
+			if err := enviarHeartbeat(envio); err != nil {
+				log.Printf("[WARN] Erro ao enviar heartbeat: %v", err)
+			}
+			cancelar()
+		}
+
+		minutos, err := strconv.Atoi(lerConfiguracao(ctx, "heartbeat_intervalo_minutos", "5"))
+		if err != nil || minutos < 1 {
+			minutos = 5
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(minutos) * time.Minute):
+		}
+	}
+}
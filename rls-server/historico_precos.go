@@ -0,0 +1,94 @@
+// historico_precos.go - Linha do tempo dos preços de custo de cada produto
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Origens de uma alteração de preço
+const (
+	OrigemPrecoProduto = "produto"
+	OrigemPrecoEntrada = "entrada"
+)
+
+type HistoricoPreco struct {
+	ID             int       `json:"id"`
+	ProdutoID      int       `json:"produto_id"`
+	PrecoAnterior  float64   `json:"preco_anterior"`
+	PrecoNovo      float64   `json:"preco_novo"`
+	Origem         string    `json:"origem"`
+	MovimentacaoID *int      `json:"movimentacao_id,omitempty"`
+	DataRegistro   time.Time `json:"data_registro"`
+}
+
+// Registra uma alteração de preço de custo. Em entradas, preco_novo é o custo
+// unitário pago na entrada, não o custo médio resultante.
+func registrarHistoricoPreco(ctx context.Context, q querier, produtoID int, anterior, novo float64, origem string, movimentacaoID *int) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO historico_precos(produto_id, preco_anterior, preco_novo, origem, movimentacao_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, produtoID, anterior, novo, origem, movimentacaoID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DB] Preço do produto ID %d alterado de %.4f para %.4f (%s)", produtoID, anterior, novo, origem)
+	return nil
+}
+
+// Handler para histórico de preços
+
+func getHistoricoPrecos(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando histórico de preços do produto ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, produto_id, preco_anterior, preco_novo, origem, movimentacao_id, data_registro
+		FROM historico_precos
+		WHERE produto_id = $1
+		ORDER BY data_registro ASC, id
+	`, id)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar histórico de preços: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar histórico de preços"})
+		return
+	}
+	defer rows.Close()
+
+	historico := []HistoricoPreco{}
+	for rows.Next() {
+		var h HistoricoPreco
+		err := rows.Scan(&h.ID, &h.ProdutoID, &h.PrecoAnterior, &h.PrecoNovo, &h.Origem, &h.MovimentacaoID, &h.DataRegistro)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar histórico de preço: %v", err)
+			continue
+		}
+		historico = append(historico, h)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar histórico de preços: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar histórico de preços"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d registros de preço para o produto ID: %d", len(historico), id)
+	c.JSON(http.StatusOK, historico)
+}
@@ -0,0 +1,727 @@
+// impressao.go - Fila de impressão de etiquetas com impressoras por setor
+//
+// As impressoras de rede ficam em /api/impressoras com endereço, porta (envio
+// direto, normalmente 9100), tipo (zpl para Zebra, pdf para impressoras que
+// aceitam PDF) e setor. POST /api/impressao/trabalhos enfileira etiquetas de
+// produtos para a impressora informada ou, sem ela, para uma impressora ativa
+// do setor informado ou do setor do usuário (X-Usuario, cadastrado em
+// /api/setores-usuarios).
+//
+// Um worker envia os trabalhos pendentes, gerando o conteúdo com os dados
+// atuais dos produtos. Falhas são repetidas com backoff até
+// IMPRESSAO_MAX_TENTATIVAS; depois o trabalho fica como falhou e pode ser
+// reenviado em POST /api/impressao/trabalhos/:id/reimprimir, opcionalmente
+// para outra impressora.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tipos de impressora
+const (
+	ImpressoraZPL = "zpl"
+	ImpressoraPDF = "pdf"
+)
+
+// Status de um trabalho de impressão
+const (
+	TrabalhoPendente = "pendente"
+	TrabalhoImpresso = "impresso"
+	TrabalhoFalhou   = "falhou"
+)
+
+// Configuração da fila - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	impressaoMaxTentativas   = getEnvAsInt("IMPRESSAO_MAX_TENTATIVAS", 5)
+	impressaoBackoffSegundos = getEnvAsInt("IMPRESSAO_BACKOFF_SEGUNDOS", 15)
+	impressaoTimeoutSegundos = getEnvAsInt("IMPRESSAO_TIMEOUT_SEGUNDOS", 10)
+)
+
+// Sinaliza o worker para enviar trabalhos novos sem esperar o próximo ciclo
+var impressaoSinal = make(chan struct{}, 1)
+
+type Impressora struct {
+	ID          int       `json:"id,omitempty"`
+	Nome        string    `json:"nome"`
+	Endereco    string    `json:"endereco"`
+	Porta       int       `json:"porta"`
+	Tipo        string    `json:"tipo"`
+	Setor       string    `json:"setor"`
+	Ativa       bool      `json:"ativa"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+type SetorUsuario struct {
+	Usuario string `json:"usuario"`
+	Setor   string `json:"setor"`
+}
+
+type TrabalhoImpressao struct {
+	ID               int        `json:"id"`
+	ImpressoraID     int        `json:"impressora_id"`
+	Impressora       string     `json:"impressora"`
+	Setor            string     `json:"setor"`
+	Usuario          string     `json:"usuario"`
+	ProdutoIDs       []int      `json:"produto_ids"`
+	Simbolo          string     `json:"simbolo"`
+	Copias           int        `json:"copias"`
+	Status           string     `json:"status"`
+	Tentativas       int        `json:"tentativas"`
+	UltimoErro       string     `json:"ultimo_erro,omitempty"`
+	ProximaTentativa *time.Time `json:"proxima_tentativa,omitempty"`
+	DataCriacao      time.Time  `json:"data_criacao"`
+	DataImpressao    *time.Time `json:"data_impressao,omitempty"`
+}
+
+// Corpo de POST /api/impressao/trabalhos; impressora_id e setor são opcionais
+type NovoTrabalhoImpressao struct {
+	ProdutoIDs   []int  `json:"produto_ids"`
+	Simbolo      string `json:"simbolo,omitempty"`
+	Copias       int    `json:"copias,omitempty"`
+	ImpressoraID int    `json:"impressora_id,omitempty"`
+	Setor        string `json:"setor,omitempty"`
+}
+
+// Remove os caracteres de controle do ZPL de um texto de campo
+func textoZPL(s string) string {
+	return strings.NewReplacer("^", " ", "~", " ").Replace(s)
+}
+
+// Etiquetas de 100 x 50 mm em ZPL (203 dpi), com nome, símbolo e código
+func gerarZPL(produtos []etiquetaProduto, simbolo string, copias int, empresa string) []byte {
+	var b strings.Builder
+	for _, p := range produtos {
+		b.WriteString("^XA^CI28^PW800^LL400\n")
+		if simbolo == EtiquetaQRCode {
+			fmt.Fprintf(&b, "^FO30,30^BQN,2,8^FDMA,%s^FS\n", textoZPL(p.Codigo))
+			fmt.Fprintf(&b, "^FO370,50^A0N,44,44^FB400,1,0,L^FD%s^FS\n", textoZPL(p.Codigo))
+			fmt.Fprintf(&b, "^FO370,120^A0N,30,30^FB400,4,0,L^FD%s^FS\n", textoZPL(p.Nome))
+		} else {
+			fmt.Fprintf(&b, "^FO30,25^A0N,34,34^FB740,1,0,L^FD%s^FS\n", textoZPL(p.Nome))
+			fmt.Fprintf(&b, "^FO60,80^BY2^BCN,210,N,N,N^FD%s^FS\n", textoZPL(p.Codigo))
+			fmt.Fprintf(&b, "^FO30,305^A0N,40,40^FB740,1,0,C^FD%s^FS\n", textoZPL(p.Codigo))
+		}
+		if empresa != "" {
+			fmt.Fprintf(&b, "^FO30,360^A0N,22,22^FB740,1,0,R^FD%s^FS\n", textoZPL(empresa))
+		}
+		fmt.Fprintf(&b, "^PQ%d\n^XZ\n", copias)
+	}
+	return []byte(b.String())
+}
+
+// Gera o conteúdo do trabalho na linguagem da impressora
+func conteudoTrabalhoImpressao(ctx context.Context, tipo string, produtoIDs []int, simbolo string, copias int) ([]byte, error) {
+	produtos, err := carregarProdutosEtiqueta(ctx, produtoIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(produtos) == 0 {
+		return nil, fmt.Errorf("nenhum dos produtos existe mais")
+	}
+	empresa := lerConfiguracao(ctx, "empresa_nome", "")
+
+	if tipo == ImpressoraZPL {
+		return gerarZPL(produtos, simbolo, copias, empresa), nil
+	}
+
+	d := novoPDF(100*pontosPorMM, 50*pontosPorMM)
+	for _, p := range produtos {
+		for range copias {
+			d.novaPagina()
+			if err := desenharEtiqueta(d, 0, 0, d.largura, d.altura, p, simbolo, empresa); err != nil {
+				return nil, fmt.Errorf("etiqueta do produto %s: %w", p.Codigo, err)
+			}
+		}
+	}
+	return d.bytes()
+}
+
+// Envia os dados direto para a porta da impressora
+func enviarImpressora(ctx context.Context, endereco string, porta int, dados []byte) error {
+	timeout := time.Duration(max(impressaoTimeoutSegundos, 1)) * time.Second
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(endereco, strconv.Itoa(porta)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err = conn.Write(dados)
+	return err
+}
+
+// Espera antes da tentativa n (1, 2, 3...): base, 2×base, 4×base..., até 1 hora
+func backoffImpressao(tentativa int) time.Duration {
+	espera := time.Duration(max(impressaoBackoffSegundos, 1)) * time.Second
+	for i := 1; i < tentativa && espera < time.Hour; i++ {
+		espera *= 2
+	}
+	return min(espera, time.Hour)
+}
+
+// Envia os trabalhos pendentes vencidos; devolve quantos foram processados.
+// Trabalhos de impressoras desativadas aguardam a reativação.
+func processarTrabalhosImpressao(ctx context.Context) (int, error) {
+	rows, err := db.Query(ctx, `
+		SELECT t.id, t.produto_ids, t.simbolo, t.copias, t.tentativas, i.id, i.endereco, i.porta, i.tipo
+		FROM trabalhos_impressao t
+		JOIN impressoras i ON t.impressora_id = i.id
+		WHERE t.status = $1 AND t.proxima_tentativa <= CURRENT_TIMESTAMP AND i.ativa
+		ORDER BY t.id
+		LIMIT 20
+	`, TrabalhoPendente)
+	if err != nil {
+		return 0, err
+	}
+
+	type pendente struct {
+		trabalho   TrabalhoImpressao
+		impressora Impressora
+	}
+	var pendentes []pendente
+	for rows.Next() {
+		var p pendente
+		err := rows.Scan(&p.trabalho.ID, &p.trabalho.ProdutoIDs, &p.trabalho.Simbolo, &p.trabalho.Copias,
+			&p.trabalho.Tentativas, &p.impressora.ID, &p.impressora.Endereco, &p.impressora.Porta, &p.impressora.Tipo)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pendentes = append(pendentes, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range pendentes {
+		t := p.trabalho
+		dados, err := conteudoTrabalhoImpressao(ctx, p.impressora.Tipo, t.ProdutoIDs, t.Simbolo, t.Copias)
+		if err == nil {
+			err = enviarImpressora(ctx, p.impressora.Endereco, p.impressora.Porta, dados)
+		}
+		tentativas := t.Tentativas + 1
+
+		if err == nil {
+			log.Printf("[API] Trabalho de impressão %d enviado para a impressora %d", t.ID, p.impressora.ID)
+			_, err = db.Exec(ctx, `
+				UPDATE trabalhos_impressao SET status = $1, tentativas = $2, ultimo_erro = NULL,
+					data_impressao = CURRENT_TIMESTAMP
+				WHERE id = $3
+			`, TrabalhoImpresso, tentativas, t.ID)
+		} else {
+			log.Printf("[WARN] Trabalho de impressão %d falhou (tentativa %d): %v", t.ID, tentativas, err)
+			novoStatus := TrabalhoPendente
+			if tentativas >= impressaoMaxTentativas {
+				novoStatus = TrabalhoFalhou
+			}
+			_, err = db.Exec(ctx, `
+				UPDATE trabalhos_impressao SET status = $1, tentativas = $2, ultimo_erro = $3,
+					proxima_tentativa = CURRENT_TIMESTAMP + $4::float8 * interval '1 second'
+				WHERE id = $5
+			`, novoStatus, tentativas, err.Error(), backoffImpressao(tentativas).Seconds(), t.ID)
+		}
+		if err != nil {
+			log.Printf("[ERROR] Erro ao atualizar trabalho de impressão %d: %v", t.ID, err)
+		}
+	}
+	return len(pendentes), nil
+}
+
+func sinalizarImpressao() {
+	select {
+	case impressaoSinal <- struct{}{}:
+	default:
+	}
+}
+
+// Envia os trabalhos pendentes a cada ciclo ou quando há trabalhos novos;
+// roda até o contexto ser cancelado
+func iniciarImpressao(ctx context.Context) {
+	ciclo := time.NewTicker(5 * time.Second)
+	defer ciclo.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ciclo.C:
+		case <-impressaoSinal:
+		}
+
+		for {
+			n, err := processarTrabalhosImpressao(ctx)
+			if err != nil {
+				log.Printf("[ERROR] Erro ao processar fila de impressão: %v", err)
+			}
+			if err != nil || n < 20 {
+				break
+			}
+		}
+	}
+}
+
+// Função auxiliar para validar uma impressora e completar a porta padrão
+func validarImpressora(i *Impressora) string {
+	if i.Nome == "" || i.Endereco == "" || i.Setor == "" {
+		return "Nome, endereço e setor são obrigatórios"
+	}
+	if i.Tipo != ImpressoraZPL && i.Tipo != ImpressoraPDF {
+		return "Tipo deve ser zpl ou pdf"
+	}
+	if i.Porta == 0 {
+		i.Porta = 9100
+	}
+	if i.Porta < 1 || i.Porta > 65535 {
+		return "Porta inválida"
+	}
+	return ""
+}
+
+// Escolhe a impressora do trabalho: a informada, ou a primeira ativa do setor
+// informado ou do setor do usuário. Devolve a mensagem de erro ou "".
+func escolherImpressora(ctx context.Context, impressoraID int, setor, usuario string) (int, string, error) {
+	if impressoraID > 0 {
+		var ativa bool
+		err := db.QueryRow(ctx, "SELECT ativa FROM impressoras WHERE id = $1", impressoraID).Scan(&ativa)
+		if err == pgx.ErrNoRows {
+			return 0, "Impressora não encontrada", nil
+		}
+		if err == nil && !ativa {
+			return 0, "Impressora desativada", nil
+		}
+		return impressoraID, "", err
+	}
+
+	if setor == "" {
+		err := db.QueryRow(ctx, "SELECT setor FROM setores_usuarios WHERE usuario = $1", usuario).Scan(&setor)
+		if err == pgx.ErrNoRows {
+			return 0, "Usuário sem setor cadastrado; informe setor ou impressora_id", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	var id int
+	err := db.QueryRow(ctx, "SELECT id FROM impressoras WHERE setor = $1 AND ativa ORDER BY id LIMIT 1", setor).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, "Nenhuma impressora ativa no setor " + setor, nil
+	}
+	return id, "", err
+}
+
+// Handlers de Impressoras
+
+func getImpressoras(c *gin.Context) {
+	log.Println("[DB] Buscando impressoras")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, nome, endereco, porta, tipo, setor, ativa, data_criacao
+		FROM impressoras
+		ORDER BY setor, nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar impressoras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar impressoras"})
+		return
+	}
+	defer rows.Close()
+
+	impressoras := []Impressora{}
+	for rows.Next() {
+		var i Impressora
+		if err := rows.Scan(&i.ID, &i.Nome, &i.Endereco, &i.Porta, &i.Tipo, &i.Setor, &i.Ativa, &i.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar impressora: %v", err)
+			continue
+		}
+		impressoras = append(impressoras, i)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar impressoras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar impressoras"})
+		return
+	}
+
+	c.JSON(http.StatusOK, impressoras)
+}
+
+func criarImpressora(c *gin.Context) {
+	i := Impressora{Ativa: true}
+	if err := c.ShouldBindJSON(&i); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarImpressora(&i); msg != "" {
+		log.Printf("[ERROR] Impressora inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO impressoras(nome, endereco, porta, tipo, setor, ativa)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, data_criacao
+	`, i.Nome, i.Endereco, i.Porta, i.Tipo, i.Setor, i.Ativa).Scan(&i.ID, &i.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar impressora: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar impressora (verifique se o nome já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Impressora criada: %s (ID: %d, setor %s)", i.Nome, i.ID, i.Setor)
+	c.JSON(http.StatusCreated, i)
+}
+
+func atualizarImpressora(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var i Impressora
+	if err := c.ShouldBindJSON(&i); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarImpressora(&i); msg != "" {
+		log.Printf("[ERROR] Impressora inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE impressoras SET nome = $1, endereco = $2, porta = $3, tipo = $4, setor = $5, ativa = $6
+		WHERE id = $7
+		RETURNING id, data_criacao
+	`, i.Nome, i.Endereco, i.Porta, i.Tipo, i.Setor, i.Ativa, id).Scan(&i.ID, &i.DataCriacao)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Impressora não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Impressora não encontrada"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar impressora: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar impressora"})
+		}
+		return
+	}
+
+	// Reativada, os trabalhos que aguardavam podem seguir
+	if i.Ativa {
+		sinalizarImpressao()
+	}
+
+	log.Printf("[DB] Impressora atualizada: %s (ID: %d)", i.Nome, id)
+	c.JSON(http.StatusOK, i)
+}
+
+func deletarImpressora(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Os trabalhos da impressora saem junto (ON DELETE CASCADE)
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM impressoras WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir impressora: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir impressora"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Impressora não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Impressora não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Impressora excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Impressora excluída com sucesso"})
+}
+
+// Handlers de Setores dos usuários
+
+func getSetoresUsuarios(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(), "SELECT usuario, setor FROM setores_usuarios ORDER BY usuario")
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar setores dos usuários: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar setores dos usuários"})
+		return
+	}
+	defer rows.Close()
+
+	setores := []SetorUsuario{}
+	for rows.Next() {
+		var s SetorUsuario
+		if err := rows.Scan(&s.Usuario, &s.Setor); err != nil {
+			log.Printf("[ERROR] Erro ao processar setor do usuário: %v", err)
+			continue
+		}
+		setores = append(setores, s)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar setores dos usuários: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar setores dos usuários"})
+		return
+	}
+
+	c.JSON(http.StatusOK, setores)
+}
+
+// Define o setor do usuário (setor vazio remove o cadastro)
+func salvarSetorUsuario(c *gin.Context) {
+	s := SetorUsuario{Usuario: c.Param("usuario")}
+	var req struct {
+		Setor string `json:"setor"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	s.Setor = strings.TrimSpace(req.Setor)
+
+	var err error
+	if s.Setor == "" {
+		_, err = db.Exec(c.Request.Context(), "DELETE FROM setores_usuarios WHERE usuario = $1", s.Usuario)
+	} else {
+		_, err = db.Exec(c.Request.Context(), `
+			INSERT INTO setores_usuarios(usuario, setor) VALUES ($1, $2)
+			ON CONFLICT (usuario) DO UPDATE SET setor = EXCLUDED.setor
+		`, s.Usuario, s.Setor)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao salvar setor do usuário: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar setor do usuário"})
+		return
+	}
+
+	log.Printf("[DB] Setor do usuário %s: %q", s.Usuario, s.Setor)
+	c.JSON(http.StatusOK, s)
+}
+
+// Handlers da Fila de impressão
+
+// Busca um trabalho com a impressora
+func buscarTrabalhoImpressao(ctx context.Context, id int) (TrabalhoImpressao, error) {
+	var t TrabalhoImpressao
+	var ultimoErro *string
+	err := db.QueryRow(ctx, `
+		SELECT t.id, t.impressora_id, i.nome, i.setor, t.usuario, t.produto_ids, t.simbolo, t.copias, t.status,
+		       t.tentativas, t.ultimo_erro, t.proxima_tentativa, t.data_criacao, t.data_impressao
+		FROM trabalhos_impressao t
+		JOIN impressoras i ON t.impressora_id = i.id
+		WHERE t.id = $1
+	`, id).Scan(&t.ID, &t.ImpressoraID, &t.Impressora, &t.Setor, &t.Usuario, &t.ProdutoIDs, &t.Simbolo, &t.Copias,
+		&t.Status, &t.Tentativas, &ultimoErro, &t.ProximaTentativa, &t.DataCriacao, &t.DataImpressao)
+	if ultimoErro != nil {
+		t.UltimoErro = *ultimoErro
+	}
+	if t.Status != TrabalhoPendente {
+		t.ProximaTentativa = nil
+	}
+	return t, err
+}
+
+func getTrabalhosImpressao(c *gin.Context) {
+	status := c.Query("status")
+	impressoraID, _ := strconv.Atoi(c.Query("impressora_id"))
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "100"))
+	if err != nil || limite <= 0 || limite > 500 {
+		limite = 100
+	}
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT t.id, t.impressora_id, i.nome, i.setor, t.usuario, t.produto_ids, t.simbolo, t.copias, t.status,
+		       t.tentativas, t.ultimo_erro, t.proxima_tentativa, t.data_criacao, t.data_impressao
+		FROM trabalhos_impressao t
+		JOIN impressoras i ON t.impressora_id = i.id
+		WHERE ($1 = '' OR t.status = $1) AND ($2 = 0 OR t.impressora_id = $2)
+		ORDER BY t.id DESC
+		LIMIT $3
+	`, status, impressoraID, limite)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar trabalhos de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar trabalhos de impressão"})
+		return
+	}
+	defer rows.Close()
+
+	trabalhos := []TrabalhoImpressao{}
+	for rows.Next() {
+		var t TrabalhoImpressao
+		var ultimoErro *string
+		err := rows.Scan(&t.ID, &t.ImpressoraID, &t.Impressora, &t.Setor, &t.Usuario, &t.ProdutoIDs, &t.Simbolo,
+			&t.Copias, &t.Status, &t.Tentativas, &ultimoErro, &t.ProximaTentativa, &t.DataCriacao, &t.DataImpressao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar trabalho de impressão: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if ultimoErro != nil {
+			t.UltimoErro = *ultimoErro
+		}
+		if t.Status != TrabalhoPendente {
+			t.ProximaTentativa = nil
+		}
+		trabalhos = append(trabalhos, t)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar trabalhos de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar trabalhos de impressão"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trabalhos)
+}
+
+func criarTrabalhoImpressao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	req := NovoTrabalhoImpressao{Simbolo: EtiquetaCode128, Copias: 1}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if req.Simbolo != EtiquetaCode128 && req.Simbolo != EtiquetaQRCode {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Símbolo deve ser code128 ou qrcode"})
+		return
+	}
+	if req.Copias < 1 || req.Copias > 100 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cópias deve estar entre 1 e 100"})
+		return
+	}
+	if len(req.ProdutoIDs) == 0 || len(req.ProdutoIDs) > etiquetasLoteMaximo {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Informe entre 1 e %d produtos", etiquetasLoteMaximo),
+		})
+		return
+	}
+
+	usuario := usuarioExportacao(c)
+	impressoraID, msg, err := escolherImpressora(ctx, req.ImpressoraID, strings.TrimSpace(req.Setor), usuario)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao escolher impressora: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao escolher impressora"})
+		return
+	}
+	if msg != "" {
+		log.Printf("[WARN] Trabalho de impressão sem impressora (usuário %s): %s", usuario, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	var id int
+	err = db.QueryRow(ctx, `
+		INSERT INTO trabalhos_impressao(impressora_id, usuario, produto_ids, simbolo, copias)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, impressoraID, usuario, req.ProdutoIDs, req.Simbolo, req.Copias).Scan(&id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar trabalho de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar trabalho de impressão"})
+		return
+	}
+	sinalizarImpressao()
+
+	t, err := buscarTrabalhoImpressao(ctx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar trabalho de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar trabalho de impressão"})
+		return
+	}
+
+	log.Printf("[DB] Trabalho de impressão %d enfileirado para %s (%d produtos)", id, t.Impressora, len(req.ProdutoIDs))
+	c.JSON(http.StatusCreated, t)
+}
+
+// Recoloca na fila um trabalho já processado, opcionalmente em outra impressora
+func reimprimirTrabalho(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req struct {
+		ImpressoraID int `json:"impressora_id,omitempty"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("[ERROR] Dados inválidos: %v", err)
+			c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+			return
+		}
+	}
+	if req.ImpressoraID > 0 {
+		if _, msg, err := escolherImpressora(ctx, req.ImpressoraID, "", ""); err != nil || msg != "" {
+			if msg == "" {
+				msg = "Erro ao verificar impressora"
+			}
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+			return
+		}
+	}
+
+	tag, err := db.Exec(ctx, `
+		UPDATE trabalhos_impressao SET status = $1, tentativas = 0, ultimo_erro = NULL,
+			proxima_tentativa = CURRENT_TIMESTAMP, data_impressao = NULL,
+			impressora_id = COALESCE(NULLIF($2, 0), impressora_id)
+		WHERE id = $3 AND status <> $1
+	`, TrabalhoPendente, req.ImpressoraID, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao reenviar trabalho de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao reenviar trabalho de impressão"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Trabalho de impressão %d não encontrado ou ainda pendente", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Trabalho não encontrado ou ainda na fila"})
+		return
+	}
+	sinalizarImpressao()
+
+	t, err := buscarTrabalhoImpressao(ctx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar trabalho de impressão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar trabalho de impressão"})
+		return
+	}
+
+	log.Printf("[API] Trabalho de impressão %d reenviado para %s", id, t.Impressora)
+	c.JSON(http.StatusOK, t)
+}
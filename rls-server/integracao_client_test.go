@@ -0,0 +1,126 @@
+//go:build integration
+
+// integracao_client_test.go - Pacote client contra o servidor real
+//
+// O router e o PostgreSQL são os da suíte de integração (integracao_test.go),
+// servidos por um httptest.Server; roda com go test -tags integration ./...
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rlsautomacao/estoque/client"
+)
+
+func novoClienteIntegracao(t *testing.T) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(roteadorIntegracao)
+	t.Cleanup(srv.Close)
+	// Sem retries: uma falha do servidor tem de aparecer no teste
+	return client.New(srv.URL, client.WithRetries(1, 0))
+}
+
+// Status e código do *client.Erro (0 e "" para outros erros)
+func erroCliente(err error) (int, string) {
+	var e *client.Erro
+	if !errors.As(err, &e) {
+		return 0, ""
+	}
+	return e.Status, e.Codigo
+}
+
+func TestIntegracaoClienteProdutos(t *testing.T) {
+	ctx := context.Background()
+	c := novoClienteIntegracao(t)
+
+	codigo := fmt.Sprintf("CLI-%d", time.Now().UnixNano())
+	criado, err := c.Produtos.Criar(ctx, client.Produto{Codigo: codigo, Nome: "Produto do cliente"})
+	if err != nil {
+		t.Fatalf("criar: %v", err)
+	}
+
+	p, err := c.Produtos.ObterPorCodigo(ctx, codigo)
+	if err != nil {
+		t.Fatalf("obter por código: %v", err)
+	}
+	if p.ID != criado.ID || p.Nome != "Produto do cliente" {
+		t.Errorf("obter por código = %d %q, esperado %d", p.ID, p.Nome, criado.ID)
+	}
+
+	p.Nome = "Produto do cliente alterado"
+	if _, err := c.Produtos.Atualizar(ctx, p.ID, *p); err != nil {
+		t.Fatalf("atualizar: %v", err)
+	}
+	if p, err = c.Produtos.Obter(ctx, criado.ID); err != nil || p.Nome != "Produto do cliente alterado" {
+		t.Errorf("depois de atualizar: %v, nome %q", err, p.Nome)
+	}
+
+	_, err = c.Produtos.Criar(ctx, client.Produto{Codigo: codigo, Nome: "Duplicado"})
+	if status, cod := erroCliente(err); status != http.StatusConflict || cod != CodigoCodigoDuplicado {
+		t.Errorf("código duplicado: status %d, código %q (%v)", status, cod, err)
+	}
+
+	if err := c.Produtos.Excluir(ctx, criado.ID); err != nil {
+		t.Fatalf("excluir: %v", err)
+	}
+	_, err = c.Produtos.Obter(ctx, criado.ID)
+	if status, _ := erroCliente(err); status != http.StatusNotFound {
+		t.Errorf("depois de excluir: status %d (%v), esperado 404", status, err)
+	}
+}
+
+func TestIntegracaoClienteMovimentacoes(t *testing.T) {
+	ctx := context.Background()
+	c := novoClienteIntegracao(t)
+
+	p, err := c.Produtos.Criar(ctx, client.Produto{Codigo: fmt.Sprintf("CLI-%d", time.Now().UnixNano()), Nome: "Produto movimentado pelo cliente"})
+	if err != nil {
+		t.Fatalf("criar produto: %v", err)
+	}
+
+	entrada, err := c.Movimentacoes.Criar(ctx, client.Movimentacao{ProdutoID: p.ID, Tipo: "entrada", Quantidade: 5})
+	if err != nil {
+		t.Fatalf("entrada: %v", err)
+	}
+	saida, err := c.Movimentacoes.Criar(ctx, client.Movimentacao{ProdutoID: p.ID, Tipo: "saida", Quantidade: 2})
+	if err != nil {
+		t.Fatalf("saída: %v", err)
+	}
+
+	_, err = c.Movimentacoes.Criar(ctx, client.Movimentacao{ProdutoID: p.ID, Tipo: "saida", Quantidade: 10})
+	if status, cod := erroCliente(err); status != http.StatusBadRequest || cod != CodigoEstoqueInsuficiente {
+		t.Errorf("saída acima do saldo: status %d, código %q (%v)", status, cod, err)
+	}
+
+	if atual, err := c.Produtos.Obter(ctx, p.ID); err != nil || atual.Quantidade != 3 {
+		t.Errorf("saldo: %v, quantidade %d, esperado 3", err, atual.Quantidade)
+	}
+
+	movs, err := c.Movimentacoes.PorProduto(ctx, p.ID)
+	if err != nil || len(movs) != 2 {
+		t.Fatalf("movimentações do produto: %v, %d registros, esperados 2", err, len(movs))
+	}
+
+	if m, err := c.Movimentacoes.Obter(ctx, entrada.ID); err != nil || m.ProdutoID != p.ID || m.Quantidade != 5 {
+		t.Errorf("obter entrada: %v, %+v", err, m)
+	}
+
+	// A paginação de ListarTodas encontra as duas, das mais recentes para as mais antigas
+	var encontradas []int
+	err = c.Movimentacoes.ListarTodas(ctx, func(m client.Movimentacao) bool {
+		if m.ProdutoID == p.ID {
+			encontradas = append(encontradas, m.ID)
+		}
+		return len(encontradas) < 2
+	})
+	if err != nil || len(encontradas) != 2 || encontradas[0] != saida.ID || encontradas[1] != entrada.ID {
+		t.Errorf("listar todas: %v, encontradas %v, esperadas [%d %d]", err, encontradas, saida.ID, entrada.ID)
+	}
+}
@@ -0,0 +1,277 @@
+//go:build integration
+
+// integracao_test.go - Suíte de integração contra um PostgreSQL descartável
+//
+//	go test -tags integration ./...
+//
+// Sobe um PostgreSQL em container (dockertest, precisa do Docker), aplica as
+// migrações embutidas e exercita a API montada por configurarRouter: todas as
+// rotas de leitura sem parâmetros, o CRUD de produtos, os caminhos da
+// transação de movimentação e saídas concorrentes sobre o mesmo saldo. Sem a
+// tag, `go test ./...` não compila este arquivo e não precisa do Docker.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Router da suíte, montado uma vez sobre o banco do container
+var roteadorIntegracao *gin.Engine
+
+func TestMain(m *testing.M) {
+	os.Exit(executarIntegracao(m))
+}
+
+func executarIntegracao(m *testing.M) int {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		log.Printf("[ERROR] Docker indisponível: %v", err)
+		return 1
+	}
+	pool.MaxWait = 2 * time.Minute
+
+	container, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Env:        []string{"POSTGRES_USER=rls", "POSTGRES_PASSWORD=rls", "POSTGRES_DB=rls_estoque_teste"},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		log.Printf("[ERROR] Não foi possível iniciar o PostgreSQL: %v", err)
+		return 1
+	}
+	defer pool.Purge(container)
+	// Garante a remoção mesmo se o processo for interrompido
+	container.Expire(600)
+
+	host, porta, err := net.SplitHostPort(container.GetHostPort("5432/tcp"))
+	if err != nil {
+		log.Printf("[ERROR] Porta do PostgreSQL: %v", err)
+		return 1
+	}
+	dbHost, dbUser, dbPassword, dbName = host, "rls", "rls", "rls_estoque_teste"
+	dbPort, _ = strconv.Atoi(porta)
+
+	if err := pool.Retry(func() error {
+		var err error
+		db, err = conectarBanco(dbName)
+		return err
+	}); err != nil {
+		log.Printf("[ERROR] PostgreSQL não respondeu: %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := aplicarMigracoes(ctx, db); err != nil {
+		log.Printf("[ERROR] Falha ao aplicar migrações: %v", err)
+		return 1
+	}
+	configurarLeituras(ctx)
+
+	// Todas as requisições da suíte vêm do mesmo IP do httptest
+	rateLimitEnabled = false
+	roteadorIntegracao = configurarRouter()
+
+	return m.Run()
+}
+
+// Faz uma requisição ao router e decodifica a resposta JSON em destino (se não nil)
+func requisicao(t *testing.T, metodo, caminho string, corpo any, destino any) int {
+	t.Helper()
+	var leitor *bytes.Reader
+	if corpo != nil {
+		dados, err := json.Marshal(corpo)
+		if err != nil {
+			t.Fatalf("corpo de %s %s: %v", metodo, caminho, err)
+		}
+		leitor = bytes.NewReader(dados)
+	} else {
+		leitor = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(metodo, caminho, leitor)
+	if corpo != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	roteadorIntegracao.ServeHTTP(w, req)
+	if destino != nil && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), destino); err != nil {
+			t.Fatalf("resposta de %s %s (%d): %v: %s", metodo, caminho, w.Code, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// Cria um produto com código único e devolve o ID
+func criarProdutoTeste(t *testing.T, nome string) int {
+	t.Helper()
+	codigo := fmt.Sprintf("INT-%d", time.Now().UnixNano())
+	var p Produto
+	if status := requisicao(t, http.MethodPost, "/api/v1/produtos", map[string]any{"codigo": codigo, "nome": nome}, &p); status != http.StatusCreated {
+		t.Fatalf("criar produto: status %d", status)
+	}
+	return p.ID
+}
+
+func quantidadeProduto(t *testing.T, id int) int {
+	t.Helper()
+	var p Produto
+	if status := requisicao(t, http.MethodGet, "/api/v1/produtos/"+strconv.Itoa(id), nil, &p); status != http.StatusOK {
+		t.Fatalf("buscar produto %d: status %d", id, status)
+	}
+	return p.Quantidade
+}
+
+func movimentar(t *testing.T, id int, tipo string, quantidade int) (int, ErrorResponse) {
+	t.Helper()
+	var resp ErrorResponse
+	status := requisicao(t, http.MethodPost, "/api/v1/movimentacoes",
+		map[string]any{"produto_id": id, "tipo": tipo, "quantidade": quantidade}, &resp)
+	return status, resp
+}
+
+// Nenhuma rota de leitura sem parâmetros pode falhar com 5xx num banco recém-migrado
+func TestIntegracaoRotasLeitura(t *testing.T) {
+	for _, rota := range roteadorIntegracao.Routes() {
+		if rota.Method != http.MethodGet || !strings.HasPrefix(rota.Path, prefixoVersaoAPI(1)) ||
+			strings.ContainsAny(rota.Path, ":*") || strings.HasSuffix(rota.Path, "/stream") {
+			continue
+		}
+		t.Run(rota.Path, func(t *testing.T) {
+			if status := requisicao(t, http.MethodGet, rota.Path, nil, nil); status >= 500 {
+				t.Errorf("GET %s: status %d", rota.Path, status)
+			}
+		})
+	}
+}
+
+func TestIntegracaoProdutoCRUD(t *testing.T) {
+	id := criarProdutoTeste(t, "Parafuso de teste")
+
+	var p Produto
+	if status := requisicao(t, http.MethodGet, "/api/v1/produtos/"+strconv.Itoa(id), nil, &p); status != http.StatusOK {
+		t.Fatalf("buscar: status %d", status)
+	}
+	if p.Nome != "Parafuso de teste" {
+		t.Errorf("nome = %q", p.Nome)
+	}
+
+	p.Nome = "Parafuso alterado"
+	if status := requisicao(t, http.MethodPut, "/api/v1/produtos/"+strconv.Itoa(id), p, nil); status != http.StatusOK {
+		t.Fatalf("atualizar: status %d", status)
+	}
+	if status := requisicao(t, http.MethodGet, "/api/v1/produtos/"+strconv.Itoa(id), nil, &p); status != http.StatusOK || p.Nome != "Parafuso alterado" {
+		t.Errorf("depois do PUT: status %d, nome %q", status, p.Nome)
+	}
+
+	// Código duplicado
+	if status := requisicao(t, http.MethodPost, "/api/v1/produtos", map[string]any{"codigo": p.Codigo, "nome": "Outro"}, nil); status != http.StatusConflict {
+		t.Errorf("código duplicado: status %d, esperado 409", status)
+	}
+
+	if status := requisicao(t, http.MethodDelete, "/api/v1/produtos/"+strconv.Itoa(id), nil, nil); status >= 300 {
+		t.Fatalf("excluir: status %d", status)
+	}
+	if status := requisicao(t, http.MethodGet, "/api/v1/produtos/"+strconv.Itoa(id), nil, nil); status != http.StatusNotFound {
+		t.Errorf("depois de excluir: status %d, esperado 404", status)
+	}
+}
+
+func TestIntegracaoMovimentacoes(t *testing.T) {
+	id := criarProdutoTeste(t, "Produto movimentado")
+
+	if status, _ := movimentar(t, id, "entrada", 10); status != http.StatusCreated {
+		t.Fatalf("entrada: status %d", status)
+	}
+	if status, _ := movimentar(t, id, "saida", 4); status != http.StatusCreated {
+		t.Fatalf("saída: status %d", status)
+	}
+	if q := quantidadeProduto(t, id); q != 6 {
+		t.Fatalf("saldo = %d, esperado 6", q)
+	}
+
+	// Saída acima do saldo é recusada e a transação não altera nada
+	status, erro := movimentar(t, id, "saida", 7)
+	if status != http.StatusBadRequest || erro.Codigo != CodigoEstoqueInsuficiente {
+		t.Errorf("saída acima do saldo: status %d, código %q", status, erro.Codigo)
+	}
+	if q := quantidadeProduto(t, id); q != 6 {
+		t.Errorf("saldo depois da recusa = %d, esperado 6", q)
+	}
+
+	// Produto inexistente e dados inválidos
+	if status, _ := movimentar(t, 999999999, "entrada", 1); status < 400 || status >= 500 {
+		t.Errorf("produto inexistente: status %d", status)
+	}
+	if status, _ := movimentar(t, id, "ajuste", 1); status != http.StatusBadRequest {
+		t.Errorf("tipo inválido: status %d, esperado 400", status)
+	}
+
+	var movs []Movimentacao
+	if status := requisicao(t, http.MethodGet, "/api/v1/movimentacoes/produto/"+strconv.Itoa(id), nil, &movs); status != http.StatusOK {
+		t.Fatalf("movimentações do produto: status %d", status)
+	}
+	if len(movs) != 2 {
+		t.Errorf("%d movimentações registradas, esperadas 2", len(movs))
+	}
+}
+
+// Saídas simultâneas sobre o mesmo saldo: só as que cabem no estoque passam
+// e o saldo nunca fica negativo
+func TestIntegracaoSaidasConcorrentes(t *testing.T) {
+	const estoque, tentativas = 10, 25
+	id := criarProdutoTeste(t, "Produto disputado")
+	if status, _ := movimentar(t, id, "entrada", estoque); status != http.StatusCreated {
+		t.Fatalf("entrada: status %d", status)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	aceitas, recusadas := 0, 0
+	for range tentativas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, _ := movimentar(t, id, "saida", 1)
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case http.StatusCreated:
+				aceitas++
+			case http.StatusBadRequest:
+				recusadas++
+			default:
+				t.Errorf("saída concorrente: status %d", status)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if aceitas != estoque || recusadas != tentativas-estoque {
+		t.Errorf("%d saídas aceitas e %d recusadas, esperadas %d e %d", aceitas, recusadas, estoque, tentativas-estoque)
+	}
+	if q := quantidadeProduto(t, id); q != 0 {
+		t.Errorf("saldo final = %d, esperado 0", q)
+	}
+}
@@ -0,0 +1,176 @@
+// integracoes.go - Registro e painel de status das integrações externas
+//
+// Cada integração (webhooks, e-mail, ERP...) se registra com registrarIntegracao
+// e acumula suas métricas em um MetricasIntegracao. O painel em
+// GET /api/admin/integracoes mostra o estado de todas sem precisar olhar logs.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Integracao é implementada por cada canal de integração externa
+type Integracao interface {
+	Nome() string
+	Tipo() string
+	Ativa() bool
+	// Quantidade de entregas aguardando envio
+	FilaPendente() int
+	Metricas() *MetricasIntegracao
+	// Testa a conectividade com o destino sem gerar efeitos colaterais
+	Testar(ctx context.Context) error
+}
+
+// Contadores de entregas compartilhados pelas integrações
+type MetricasIntegracao struct {
+	mu            sync.Mutex
+	entregas      int64
+	falhas        int64
+	ultimaEntrega time.Time
+	ultimaFalha   time.Time
+	ultimoErro    string
+}
+
+// Registra o resultado de uma tentativa de entrega
+func (m *MetricasIntegracao) Registrar(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entregas++
+	if err != nil {
+		m.falhas++
+		m.ultimaFalha = time.Now()
+		m.ultimoErro = err.Error()
+		return
+	}
+	m.ultimaEntrega = time.Now()
+}
+
+type StatusIntegracao struct {
+	Nome          string     `json:"nome"`
+	Tipo          string     `json:"tipo"`
+	Ativa         bool       `json:"ativa"`
+	Entregas      int64      `json:"entregas"`
+	Falhas        int64      `json:"falhas"`
+	TaxaFalha     float64    `json:"taxa_falha"`
+	UltimaEntrega *time.Time `json:"ultima_entrega,omitempty"`
+	UltimaFalha   *time.Time `json:"ultima_falha,omitempty"`
+	UltimoErro    string     `json:"ultimo_erro,omitempty"`
+	FilaPendente  int        `json:"fila_pendente"`
+}
+
+var (
+	integracoesMutex sync.RWMutex
+	integracoes      = map[string]Integracao{}
+)
+
+// Adiciona uma integração ao painel
+func registrarIntegracao(i Integracao) {
+	integracoesMutex.Lock()
+	defer integracoesMutex.Unlock()
+	integracoes[i.Nome()] = i
+	log.Printf("[INTEGRACAO] Integração registrada: %s (%s)", i.Nome(), i.Tipo())
+}
+
+// Retira uma integração do painel
+func removerIntegracao(nome string) {
+	integracoesMutex.Lock()
+	defer integracoesMutex.Unlock()
+	delete(integracoes, nome)
+	log.Printf("[INTEGRACAO] Integração removida: %s", nome)
+}
+
+// Função auxiliar para montar o status de uma integração
+func statusIntegracao(i Integracao) StatusIntegracao {
+	m := i.Metricas()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := StatusIntegracao{
+		Nome:         i.Nome(),
+		Tipo:         i.Tipo(),
+		Ativa:        i.Ativa(),
+		Entregas:     m.entregas,
+		Falhas:       m.falhas,
+		UltimoErro:   m.ultimoErro,
+		FilaPendente: i.FilaPendente(),
+	}
+	if m.entregas > 0 {
+		s.TaxaFalha = float64(m.falhas) / float64(m.entregas)
+	}
+	if !m.ultimaEntrega.IsZero() {
+		t := m.ultimaEntrega
+		s.UltimaEntrega = &t
+	}
+	if !m.ultimaFalha.IsZero() {
+		t := m.ultimaFalha
+		s.UltimaFalha = &t
+	}
+	return s
+}
+
+// Handlers de Integrações
+
+func getIntegracoes(c *gin.Context) {
+	log.Println("[API] Montando painel de integrações")
+
+	integracoesMutex.RLock()
+	status := make([]StatusIntegracao, 0, len(integracoes))
+	for _, i := range integracoes {
+		status = append(status, statusIntegracao(i))
+	}
+	integracoesMutex.RUnlock()
+
+	sort.Slice(status, func(a, b int) bool { return status[a].Nome < status[b].Nome })
+
+	c.JSON(http.StatusOK, status)
+}
+
+func testarIntegracao(c *gin.Context) {
+	nome := c.Param("nome")
+
+	integracoesMutex.RLock()
+	i, ok := integracoes[nome]
+	integracoesMutex.RUnlock()
+
+	if !ok {
+		log.Printf("[API] Integração não encontrada: %s", nome)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Integração não encontrada"})
+		return
+	}
+
+	log.Printf("[INTEGRACAO] Testando conectividade: %s", nome)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	inicio := time.Now()
+	err := i.Testar(ctx)
+	duracao := time.Since(inicio)
+
+	if err != nil {
+		log.Printf("[WARN] Teste da integração %s falhou: %v", nome, err)
+		c.JSON(http.StatusOK, gin.H{
+			"nome":       nome,
+			"ok":         false,
+			"erro":       err.Error(),
+			"duracao_ms": duracao.Milliseconds(),
+		})
+		return
+	}
+
+	log.Printf("[INTEGRACAO] Teste da integração %s concluído em %v", nome, duracao)
+	c.JSON(http.StatusOK, gin.H{
+		"nome":       nome,
+		"ok":         true,
+		"duracao_ms": duracao.Milliseconds(),
+	})
+}
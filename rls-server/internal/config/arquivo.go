@@ -0,0 +1,125 @@
+// Package config lê o arquivo de configuração do servidor (YAML ou TOML) para
+// o mapa plano de chaves usado pelo restante da configuração, no formato das
+// variáveis de ambiente.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Chave converte o nome de uma flag ou chave de arquivo para o nome da
+// variável de ambiente (db.host e db-host viram DB_HOST)
+func Chave(nome string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(strings.TrimSpace(nome)))
+}
+
+// LerArquivo lê o arquivo de configuração. A extensão .toml indica TOML e
+// .yaml/.yml indicam YAML; nas demais tenta YAML e depois TOML. Seções e
+// blocos aninhados viram prefixos ([db] host = "x" ou db: {host: x} viram
+// DB_HOST) e listas viram valores separados por vírgula.
+func LerArquivo(caminho string) (map[string]string, error) {
+	dados, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+
+	var arvore map[string]any
+	switch strings.ToLower(filepath.Ext(caminho)) {
+	case ".toml":
+		err = lerTOML(dados, &arvore)
+	case ".yaml", ".yml":
+		err = lerYAML(dados, &arvore)
+	default:
+		if err = lerYAML(dados, &arvore); err != nil {
+			if errTOML := lerTOML(dados, &arvore); errTOML == nil {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	valores := map[string]string{}
+	if err := achatar("", arvore, valores); err != nil {
+		return nil, err
+	}
+	return valores, nil
+}
+
+func lerYAML(dados []byte, arvore *map[string]any) error {
+	if err := yaml.Unmarshal(dados, arvore); err != nil {
+		return fmt.Errorf("YAML inválido: %w", err)
+	}
+	return nil
+}
+
+func lerTOML(dados []byte, arvore *map[string]any) error {
+	if err := toml.Unmarshal(dados, arvore); err != nil {
+		return fmt.Errorf("TOML inválido: %w", err)
+	}
+	return nil
+}
+
+// Copia a árvore para o mapa plano, prefixando as chaves com o caminho das seções
+func achatar(prefixo string, arvore map[string]any, valores map[string]string) error {
+	chaves := make([]string, 0, len(arvore))
+	for k := range arvore {
+		chaves = append(chaves, k)
+	}
+	sort.Strings(chaves)
+
+	for _, k := range chaves {
+		chave := Chave(k)
+		if prefixo != "" {
+			chave = prefixo + "_" + chave
+		}
+		if secao, ok := arvore[k].(map[string]any); ok {
+			if err := achatar(chave, secao, valores); err != nil {
+				return err
+			}
+			continue
+		}
+		valor, err := texto(arvore[k])
+		if err != nil {
+			return fmt.Errorf("%s: %w", chave, err)
+		}
+		valores[chave] = valor
+	}
+	return nil
+}
+
+// Representação textual de um valor escalar ou lista de escalares
+func texto(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		partes := make([]string, len(v))
+		for i, item := range v {
+			if _, ok := item.(map[string]any); ok {
+				return "", fmt.Errorf("listas de seções não são suportadas")
+			}
+			s, err := texto(item)
+			if err != nil {
+				return "", err
+			}
+			partes[i] = s
+		}
+		return strings.Join(partes, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
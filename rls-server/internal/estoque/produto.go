@@ -0,0 +1,122 @@
+// Package estoque reúne os tipos do domínio compartilhados entre o
+// repositório, os serviços e os handlers HTTP.
+package estoque
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Unidade usada quando o produto não informa a sua
+const UnidadePadrao = "un"
+
+type Produto struct {
+	ID                      int              `json:"id,omitempty"`
+	Codigo                  string           `json:"codigo" binding:"required,max=50,codigo"`
+	Nome                    string           `json:"nome" binding:"required_without=ProdutoPaiID,max=200"` // variantes herdam do pai
+	Descricao               string           `json:"descricao,omitempty"`
+	Quantidade              int              `json:"quantidade"`
+	QuantidadeMinima        int              `json:"quantidade_minima,omitempty" binding:"min=0"`
+	QuantidadeMaxima        int              `json:"quantidade_maxima,omitempty" binding:"min=0"` // 0 = sem máximo definido
+	Localizacao             string           `json:"localizacao,omitempty" binding:"max=100"`
+	Fornecedor              string           `json:"fornecedor,omitempty" binding:"max=200"`
+	Notas                   string           `json:"notas,omitempty"`
+	DataCriacao             time.Time        `json:"data_criacao,omitempty"`
+	DataAtualizacao         time.Time        `json:"data_atualizacao,omitempty"`
+	ControlaSerie           bool             `json:"controla_serie"`
+	Categoria               string           `json:"categoria,omitempty" binding:"max=100"`
+	UnidadeMedida           string           `json:"unidade_medida" binding:"max=10"`
+	PrecoCusto              float64          `json:"preco_custo" binding:"min=0"`
+	Perigoso                bool             `json:"perigoso"`
+	ClasseRisco             string           `json:"classe_risco,omitempty" binding:"max=50"`
+	FispqURL                string           `json:"fispq_url,omitempty"`
+	Reciclavel              bool             `json:"reciclavel"`
+	NumeroONU               string           `json:"numero_onu,omitempty" binding:"omitempty,len=4,numeric"` // 4 dígitos; a classe ONU é ClasseRisco
+	PermitirEstoqueNegativo *bool            `json:"permitir_estoque_negativo"`                              // nil segue a configuração global
+	LocalizacaoID           *int             `json:"localizacao_id,omitempty"`                               // posição cadastrada; Localizacao traz o código dela
+	Kit                     bool             `json:"kit"`                                                    // composto pela estrutura, sem estoque próprio
+	ProdutoPaiID            *int             `json:"produto_pai_id,omitempty"`                               // variante: nome e descrição vêm do pai
+	Variacao                string           `json:"variacao,omitempty" binding:"max=100"`                   // o que distingue a variante (ex.: 20mm)
+	Variantes               *ResumoVariantes `json:"variantes,omitempty"`                                    // só nos produtos pai; calculado
+	Atributos               map[string]any   `json:"atributos,omitempty"`                                    // campos definidos para a categoria
+	Tags                    []string         `json:"tags,omitempty"`                                         // calculado; alterado em /produtos/:id/tags
+}
+
+// Filtro da listagem de produtos: categoria exata, atributos pelo valor em
+// texto (220, true, Azul) e tags, todas presentes no produto
+type Filtro struct {
+	Categoria string
+	Atributos map[string]string
+	Tags      []string
+}
+
+// Variantes de um produto pai: quantas são e o saldo somado delas
+type ResumoVariantes struct {
+	Quantidade int `json:"quantidade"`
+	Estoque    int `json:"estoque"`
+}
+
+// Colunas de produtos na ordem esperada por ScanProduto
+const ColunasProduto = `id, codigo, nome, descricao, quantidade, quantidade_minima,
+		quantidade_maxima, localizacao, fornecedor, notas, data_criacao, data_atualizacao, controla_serie,
+		categoria, unidade_medida, preco_custo, perigoso, classe_risco, fispq_url,
+		reciclavel, numero_onu, permitir_estoque_negativo, localizacao_id, kit,
+		produto_pai_id, variacao, atributos`
+
+// Lê um produto (linha com ColunasProduto) tratando campos nulos
+func ScanProduto(row pgx.Row) (Produto, error) {
+	var p Produto
+	var descricao, localizacao, fornecedor, notas, categoria, classeRisco, fispqURL, numeroONU, variacao *string
+	var quantidadeMinima *int
+	var dataAtualizacao *time.Time
+
+	err := row.Scan(
+		&p.ID, &p.Codigo, &p.Nome, &descricao, &p.Quantidade,
+		&quantidadeMinima, &p.QuantidadeMaxima, &localizacao, &fornecedor, &notas,
+		&p.DataCriacao, &dataAtualizacao, &p.ControlaSerie,
+		&categoria, &p.UnidadeMedida, &p.PrecoCusto, &p.Perigoso, &classeRisco, &fispqURL,
+		&p.Reciclavel, &numeroONU, &p.PermitirEstoqueNegativo, &p.LocalizacaoID, &p.Kit,
+		&p.ProdutoPaiID, &variacao, &p.Atributos,
+	)
+	if err != nil {
+		return p, err
+	}
+
+	// Tratar campos nulos
+	if descricao != nil {
+		p.Descricao = *descricao
+	}
+	if quantidadeMinima != nil {
+		p.QuantidadeMinima = *quantidadeMinima
+	}
+	if localizacao != nil {
+		p.Localizacao = *localizacao
+	}
+	if fornecedor != nil {
+		p.Fornecedor = *fornecedor
+	}
+	if notas != nil {
+		p.Notas = *notas
+	}
+	if dataAtualizacao != nil {
+		p.DataAtualizacao = *dataAtualizacao
+	}
+	if categoria != nil {
+		p.Categoria = *categoria
+	}
+	if classeRisco != nil {
+		p.ClasseRisco = *classeRisco
+	}
+	if fispqURL != nil {
+		p.FispqURL = *fispqURL
+	}
+	if numeroONU != nil {
+		p.NumeroONU = *numeroONU
+	}
+	if variacao != nil {
+		p.Variacao = *variacao
+	}
+
+	return p, nil
+}
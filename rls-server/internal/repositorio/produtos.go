@@ -0,0 +1,238 @@
+// Package repositorio isola o acesso ao PostgreSQL: cada repositório é uma
+// interface usada pelos serviços e uma implementação sobre o pgx, que aceita
+// tanto o pool quanto uma transação.
+package repositorio
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/rlsautomacao/estoque/internal/estoque"
+)
+
+// Querier é atendido tanto pelo pool quanto por uma transação
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ErrNaoEncontrado indica que o registro procurado não existe
+var ErrNaoEncontrado = errors.New("registro não encontrado")
+
+// ErrEstoqueInsuficiente indica que a saída deixaria o saldo negativo sem que
+// o estoque negativo seja permitido
+var ErrEstoqueInsuficiente = errors.New("quantidade insuficiente em estoque")
+
+type Produtos interface {
+	Listar(ctx context.Context, filtro estoque.Filtro, limite, deslocamento int) ([]estoque.Produto, error)
+	BuscarPorID(ctx context.Context, id int) (estoque.Produto, error)
+	BuscarPorCodigo(ctx context.Context, codigo string) (estoque.Produto, error)
+	// Produto atual de um código antigo (aliases_codigos)
+	BuscarPorAlias(ctx context.Context, codigos []string) (estoque.Produto, error)
+	// Variantes do produto pai, por variação
+	ListarVariantes(ctx context.Context, paiID int) ([]estoque.Produto, error)
+	// Quantidade e saldo somado das variantes de cada pai, só dos que têm variantes
+	ResumirVariantes(ctx context.Context, paiIDs []int) (map[int]estoque.ResumoVariantes, error)
+	// Tags de cada produto, em ordem alfabética, só dos que têm tags
+	ListarTags(ctx context.Context, ids []int) (map[int][]string, error)
+	// Produtos abaixo do mínimo; sem mínimo definido vale minimoPadrao
+	ListarEstoqueBaixo(ctx context.Context, minimoPadrao int) ([]estoque.Produto, error)
+	// Soma delta ao saldo e devolve o saldo resultante, numa única instrução;
+	// com permitirNegativo o saldo pode ficar abaixo de zero
+	AjustarQuantidade(ctx context.Context, id, delta int, permitirNegativo bool) (int, error)
+	Excluir(ctx context.Context, id int) error
+}
+
+type produtosPgx struct {
+	q Querier
+}
+
+func NovoProdutos(q Querier) Produtos {
+	return &produtosPgx{q: q}
+}
+
+func (r *produtosPgx) buscar(ctx context.Context, sql string, args ...any) (estoque.Produto, error) {
+	p, err := estoque.ScanProduto(r.q.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, ErrNaoEncontrado
+	}
+	return p, err
+}
+
+func (r *produtosPgx) listar(ctx context.Context, sql string, args ...any) ([]estoque.Produto, error) {
+	rows, err := r.q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	produtos := []estoque.Produto{}
+	for rows.Next() {
+		p, err := estoque.ScanProduto(rows)
+		if err != nil {
+			return nil, err
+		}
+		produtos = append(produtos, p)
+	}
+
+	// Verificar erros durante a iteração
+	return produtos, rows.Err()
+}
+
+func (r *produtosPgx) Listar(ctx context.Context, filtro estoque.Filtro, limite, deslocamento int) ([]estoque.Produto, error) {
+	atributos := filtro.Atributos
+	if atributos == nil {
+		atributos = map[string]string{}
+	}
+	tags := filtro.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return r.listar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE ($3::text = '' OR categoria = $3::text)
+			AND NOT EXISTS (
+				SELECT 1 FROM jsonb_each_text($4::jsonb) f
+				WHERE produtos.atributos->>f.key IS DISTINCT FROM f.value
+			)
+			AND (
+				SELECT COUNT(*) FROM produtos_tags pt
+				JOIN tags t ON t.id = pt.tag_id
+				WHERE pt.produto_id = produtos.id AND t.nome = ANY($5::text[])
+			) = cardinality($5::text[])
+		ORDER BY nome
+		LIMIT $1 OFFSET $2
+	`, limite, deslocamento, filtro.Categoria, atributos, tags)
+}
+
+func (r *produtosPgx) BuscarPorID(ctx context.Context, id int) (estoque.Produto, error) {
+	return r.buscar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE id = $1
+	`, id)
+}
+
+func (r *produtosPgx) BuscarPorCodigo(ctx context.Context, codigo string) (estoque.Produto, error) {
+	return r.buscar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE codigo = $1
+	`, codigo)
+}
+
+func (r *produtosPgx) BuscarPorAlias(ctx context.Context, codigos []string) (estoque.Produto, error) {
+	return r.buscar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE id = (SELECT produto_id FROM aliases_codigos WHERE codigo = ANY($1) LIMIT 1)
+	`, codigos)
+}
+
+func (r *produtosPgx) ListarVariantes(ctx context.Context, paiID int) ([]estoque.Produto, error) {
+	return r.listar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE produto_pai_id = $1
+		ORDER BY variacao, codigo
+	`, paiID)
+}
+
+func (r *produtosPgx) ResumirVariantes(ctx context.Context, paiIDs []int) (map[int]estoque.ResumoVariantes, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT produto_pai_id, COUNT(*), COALESCE(SUM(quantidade), 0)
+		FROM produtos
+		WHERE produto_pai_id = ANY($1)
+		GROUP BY produto_pai_id
+	`, paiIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resumos := map[int]estoque.ResumoVariantes{}
+	for rows.Next() {
+		var paiID int
+		var resumo estoque.ResumoVariantes
+		if err := rows.Scan(&paiID, &resumo.Quantidade, &resumo.Estoque); err != nil {
+			return nil, err
+		}
+		resumos[paiID] = resumo
+	}
+	return resumos, rows.Err()
+}
+
+func (r *produtosPgx) ListarTags(ctx context.Context, ids []int) (map[int][]string, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT pt.produto_id, t.nome
+		FROM produtos_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.produto_id = ANY($1)
+		ORDER BY pt.produto_id, t.nome
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var id int
+		var nome string
+		if err := rows.Scan(&id, &nome); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], nome)
+	}
+	return tags, rows.Err()
+}
+
+func (r *produtosPgx) ListarEstoqueBaixo(ctx context.Context, minimoPadrao int) ([]estoque.Produto, error) {
+	return r.listar(ctx, `
+		SELECT `+estoque.ColunasProduto+`
+		FROM produtos
+		WHERE quantidade < COALESCE(quantidade_minima, $1) AND NOT kit
+		ORDER BY quantidade ASC
+	`, minimoPadrao)
+}
+
+// O saldo é alterado pelo próprio UPDATE, sem ler e regravar o valor: a linha
+// fica travada até o fim da transação e saídas concorrentes não se sobrepõem.
+// Sem permitirNegativo, uma saída maior que o saldo não altera nada e devolve
+// ErrEstoqueInsuficiente.
+func (r *produtosPgx) AjustarQuantidade(ctx context.Context, id, delta int, permitirNegativo bool) (int, error) {
+	var saldo int
+	err := r.q.QueryRow(ctx, `
+		UPDATE produtos SET quantidade = quantidade + $2::int
+		WHERE id = $1 AND ($3 OR $2::int >= 0 OR quantidade + $2::int >= 0)
+		RETURNING quantidade
+	`, id, delta, permitirNegativo).Scan(&saldo)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return saldo, err
+	}
+
+	var existe bool
+	if err := r.q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM produtos WHERE id = $1)", id).Scan(&existe); err != nil {
+		return 0, err
+	}
+	if !existe {
+		return 0, ErrNaoEncontrado
+	}
+	return 0, ErrEstoqueInsuficiente
+}
+
+func (r *produtosPgx) Excluir(ctx context.Context, id int) error {
+	tag, err := r.q.Exec(ctx, "DELETE FROM produtos WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repositorio
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Roteador separa as leituras pesadas (relatórios, exportações, dashboard) das
+// escritas: Query e QueryRow vão para a réplica de leitura enquanto ela
+// responde e está em dia com o primário; Exec e todo o resto vão sempre para o
+// primário. Sem réplica, tudo vai para o primário.
+type Roteador struct {
+	primario Querier
+	replica  Querier
+	emDia    atomic.Bool
+}
+
+// NovoRoteador cria o roteador; replica nil desliga o roteamento. A réplica
+// só passa a ser usada depois da primeira verificação bem-sucedida.
+func NovoRoteador(primario, replica Querier) *Roteador {
+	return &Roteador{primario: primario, replica: replica}
+}
+
+// Leitura devolve onde as leituras pesadas devem ser feitas agora
+func (r *Roteador) Leitura() Querier {
+	if r.replica != nil && r.emDia.Load() {
+		return r.replica
+	}
+	return r.primario
+}
+
+// UsandoReplica indica se as leituras estão indo para a réplica
+func (r *Roteador) UsandoReplica() bool {
+	return r.Leitura() != r.primario
+}
+
+func (r *Roteador) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return r.primario.Exec(ctx, sql, args...)
+}
+
+// Query usa a réplica; se ela falhar, repete no primário e deixa de usá-la
+// até a próxima verificação
+func (r *Roteador) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q := r.Leitura()
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil && q != r.primario && ctx.Err() == nil {
+		r.emDia.Store(false)
+		return r.primario.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+func (r *Roteador) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return r.Leitura().QueryRow(ctx, sql, args...)
+}
+
+// VerificarReplica mede o atraso da réplica em relação ao primário e decide se
+// ela continua recebendo as leituras: com erro ou atraso acima de
+// atrasoMaximo, as leituras voltam ao primário. Devolve o atraso medido.
+func (r *Roteador) VerificarReplica(ctx context.Context, atrasoMaximo time.Duration) (time.Duration, error) {
+	if r.replica == nil {
+		return 0, nil
+	}
+	// Sem WAL pendente de aplicação a réplica está em dia, mesmo que a última
+	// transação replicada seja antiga (primário ocioso)
+	var segundos float64
+	err := r.replica.QueryRow(ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END
+	`).Scan(&segundos)
+	if err != nil {
+		r.emDia.Store(false)
+		return 0, err
+	}
+	atraso := time.Duration(segundos * float64(time.Second))
+	r.emDia.Store(atraso <= atrasoMaximo)
+	return atraso, nil
+}
@@ -0,0 +1,185 @@
+// Package servico concentra as regras de negócio, sem conhecer HTTP nem SQL:
+// os serviços recebem os repositórios no construtor e são usados pelos
+// handlers do pacote principal.
+package servico
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rlsautomacao/estoque/internal/estoque"
+	"github.com/rlsautomacao/estoque/internal/repositorio"
+)
+
+// Mínimo considerado para produtos sem quantidade_minima
+const EstoqueMinimoPadrao = 5
+
+// Paginação padrão da listagem de produtos
+const limitePadraoProdutos = 100
+
+var ErrProdutoNaoEncontrado = errors.New("produto não encontrado")
+
+type Produtos struct {
+	repo repositorio.Produtos
+}
+
+func NovoProdutos(repo repositorio.Produtos) *Produtos {
+	return &Produtos{repo: repo}
+}
+
+func traduzirErro(err error) error {
+	if errors.Is(err, repositorio.ErrNaoEncontrado) {
+		return ErrProdutoNaoEncontrado
+	}
+	return err
+}
+
+// Lista uma página de produtos do filtro; limite e deslocamento inválidos
+// usam o padrão
+func (s *Produtos) Listar(ctx context.Context, filtro estoque.Filtro, limite, deslocamento int) ([]estoque.Produto, error) {
+	if limite <= 0 {
+		limite = limitePadraoProdutos
+	}
+	deslocamento = max(deslocamento, 0)
+	produtos, err := s.repo.Listar(ctx, filtro, limite, deslocamento)
+	if err != nil {
+		return nil, err
+	}
+	return produtos, s.completar(ctx, produtos)
+}
+
+func (s *Produtos) Buscar(ctx context.Context, id int) (estoque.Produto, error) {
+	p, err := s.repo.BuscarPorID(ctx, id)
+	if err != nil {
+		return p, traduzirErro(err)
+	}
+	produtos := []estoque.Produto{p}
+	err = s.completar(ctx, produtos)
+	return produtos[0], err
+}
+
+// Variantes do produto; para uma variante, as irmãs (as variantes do pai)
+func (s *Produtos) Variantes(ctx context.Context, id int) ([]estoque.Produto, error) {
+	p, err := s.repo.BuscarPorID(ctx, id)
+	if err != nil {
+		return nil, traduzirErro(err)
+	}
+	if p.ProdutoPaiID != nil {
+		id = *p.ProdutoPaiID
+	}
+	return s.repo.ListarVariantes(ctx, id)
+}
+
+// Preenche os campos calculados: as tags e, nos produtos pai, o resumo das
+// variantes (estoque somado)
+func (s *Produtos) completar(ctx context.Context, produtos []estoque.Produto) error {
+	ids := make([]int, len(produtos))
+	for i, p := range produtos {
+		ids[i] = p.ID
+	}
+	resumos, err := s.repo.ResumirVariantes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	tags, err := s.repo.ListarTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range produtos {
+		if resumo, ok := resumos[produtos[i].ID]; ok {
+			produtos[i].Variantes = &resumo
+		}
+		produtos[i].Tags = tags[produtos[i].ID]
+	}
+	return nil
+}
+
+// Busca pelo código atual e, se nenhum produto o usa, pelos códigos antigos;
+// obsoleto indica que o produto foi encontrado por um alias
+func (s *Produtos) BuscarPorCodigo(ctx context.Context, codigo string) (p estoque.Produto, obsoleto bool, err error) {
+	p, err = s.repo.BuscarPorCodigo(ctx, codigo)
+	if errors.Is(err, repositorio.ErrNaoEncontrado) {
+		p, err = s.repo.BuscarPorAlias(ctx, []string{codigo})
+		obsoleto = err == nil
+	}
+	if err != nil {
+		return p, obsoleto, traduzirErro(err)
+	}
+	produtos := []estoque.Produto{p}
+	err = s.completar(ctx, produtos)
+	return produtos[0], obsoleto, err
+}
+
+// Produtos abaixo do mínimo, com o mínimo padrão preenchido quando ausente
+func (s *Produtos) EstoqueBaixo(ctx context.Context) ([]estoque.Produto, error) {
+	produtos, err := s.repo.ListarEstoqueBaixo(ctx, EstoqueMinimoPadrao)
+	if err != nil {
+		return nil, err
+	}
+	for i := range produtos {
+		if produtos[i].QuantidadeMinima == 0 {
+			produtos[i].QuantidadeMinima = EstoqueMinimoPadrao
+		}
+	}
+	return produtos, nil
+}
+
+func (s *Produtos) Excluir(ctx context.Context, id int) error {
+	return traduzirErro(s.repo.Excluir(ctx, id))
+}
+
+// Regras de cadastro comuns à criação e à edição; completa a unidade padrão e
+// devolve a mensagem de erro ou "" se válido
+func ValidarProduto(p *estoque.Produto) string {
+	if strings.TrimSpace(p.Codigo) == "" || p.Nome == "" {
+		return "Código e nome são obrigatórios"
+	}
+
+	if p.UnidadeMedida == "" {
+		p.UnidadeMedida = estoque.UnidadePadrao
+	}
+
+	if p.QuantidadeMaxima < 0 || (p.QuantidadeMaxima > 0 && p.QuantidadeMaxima < p.QuantidadeMinima) {
+		return "Quantidade máxima deve ser maior ou igual à quantidade mínima"
+	}
+
+	if p.Perigoso && strings.TrimSpace(p.ClasseRisco) == "" {
+		return "Produtos perigosos exigem a classe de risco"
+	}
+
+	if msg := ValidarNumeroONU(p.NumeroONU); msg != "" {
+		return msg
+	}
+
+	if msg := ValidarKit(p); msg != "" {
+		return msg
+	}
+	return ""
+}
+
+// Kits não têm estoque próprio: saldo zero e sem controle de série (os
+// componentes é que são baixados e podem ter série)
+func ValidarKit(p *estoque.Produto) string {
+	if !p.Kit {
+		return ""
+	}
+	if p.Quantidade != 0 {
+		return "Kits não têm estoque próprio: o saldo do kit deve ser zero"
+	}
+	if p.ControlaSerie {
+		return "Kits não podem ter controle de série; o controle fica nos componentes"
+	}
+	return ""
+}
+
+// O número ONU, quando informado, tem 4 dígitos (ex.: 1203)
+func ValidarNumeroONU(numero string) string {
+	if numero == "" {
+		return ""
+	}
+	if len(numero) != 4 || strings.Trim(numero, "0123456789") != "" {
+		return "Número ONU deve ter 4 dígitos"
+	}
+	return ""
+}
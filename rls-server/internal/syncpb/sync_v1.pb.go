@@ -0,0 +1,1231 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: sync_v1.proto
+
+package syncpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type HandshakeSync struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivo   string                 `protobuf:"bytes,1,opt,name=dispositivo,proto3" json:"dispositivo,omitempty"`
+	Relogio       string                 `protobuf:"bytes,2,opt,name=relogio,proto3" json:"relogio,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandshakeSync) Reset() {
+	*x = HandshakeSync{}
+	mi := &file_sync_v1_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandshakeSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandshakeSync) ProtoMessage() {}
+
+func (x *HandshakeSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandshakeSync.ProtoReflect.Descriptor instead.
+func (*HandshakeSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *HandshakeSync) GetDispositivo() string {
+	if x != nil {
+		return x.Dispositivo
+	}
+	return ""
+}
+
+func (x *HandshakeSync) GetRelogio() string {
+	if x != nil {
+		return x.Relogio
+	}
+	return ""
+}
+
+type RespostaHandshakeSync struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivo        string                 `protobuf:"bytes,1,opt,name=dispositivo,proto3" json:"dispositivo,omitempty"`
+	Servidor           string                 `protobuf:"bytes,2,opt,name=servidor,proto3" json:"servidor,omitempty"`
+	OffsetMs           int64                  `protobuf:"varint,3,opt,name=offset_ms,json=offsetMs,proto3" json:"offset_ms,omitempty"`
+	ToleranciaSegundos int32                  `protobuf:"varint,4,opt,name=tolerancia_segundos,json=toleranciaSegundos,proto3" json:"tolerancia_segundos,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RespostaHandshakeSync) Reset() {
+	*x = RespostaHandshakeSync{}
+	mi := &file_sync_v1_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RespostaHandshakeSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RespostaHandshakeSync) ProtoMessage() {}
+
+func (x *RespostaHandshakeSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RespostaHandshakeSync.ProtoReflect.Descriptor instead.
+func (*RespostaHandshakeSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RespostaHandshakeSync) GetDispositivo() string {
+	if x != nil {
+		return x.Dispositivo
+	}
+	return ""
+}
+
+func (x *RespostaHandshakeSync) GetServidor() string {
+	if x != nil {
+		return x.Servidor
+	}
+	return ""
+}
+
+func (x *RespostaHandshakeSync) GetOffsetMs() int64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+func (x *RespostaHandshakeSync) GetToleranciaSegundos() int32 {
+	if x != nil {
+		return x.ToleranciaSegundos
+	}
+	return 0
+}
+
+type MovimentacaoLote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LoteId        int32                  `protobuf:"varint,1,opt,name=lote_id,json=loteId,proto3" json:"lote_id,omitempty"`
+	NumeroLote    string                 `protobuf:"bytes,2,opt,name=numero_lote,json=numeroLote,proto3" json:"numero_lote,omitempty"`
+	Validade      *string                `protobuf:"bytes,3,opt,name=validade,proto3,oneof" json:"validade,omitempty"`
+	Quantidade    int32                  `protobuf:"varint,4,opt,name=quantidade,proto3" json:"quantidade,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MovimentacaoLote) Reset() {
+	*x = MovimentacaoLote{}
+	mi := &file_sync_v1_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MovimentacaoLote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MovimentacaoLote) ProtoMessage() {}
+
+func (x *MovimentacaoLote) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MovimentacaoLote.ProtoReflect.Descriptor instead.
+func (*MovimentacaoLote) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MovimentacaoLote) GetLoteId() int32 {
+	if x != nil {
+		return x.LoteId
+	}
+	return 0
+}
+
+func (x *MovimentacaoLote) GetNumeroLote() string {
+	if x != nil {
+		return x.NumeroLote
+	}
+	return ""
+}
+
+func (x *MovimentacaoLote) GetValidade() string {
+	if x != nil && x.Validade != nil {
+		return *x.Validade
+	}
+	return ""
+}
+
+func (x *MovimentacaoLote) GetQuantidade() int32 {
+	if x != nil {
+		return x.Quantidade
+	}
+	return 0
+}
+
+type Movimentacao struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProdutoId        int32                  `protobuf:"varint,2,opt,name=produto_id,json=produtoId,proto3" json:"produto_id,omitempty"`
+	Tipo             string                 `protobuf:"bytes,3,opt,name=tipo,proto3" json:"tipo,omitempty"`
+	Quantidade       int32                  `protobuf:"varint,4,opt,name=quantidade,proto3" json:"quantidade,omitempty"`
+	Notas            string                 `protobuf:"bytes,5,opt,name=notas,proto3" json:"notas,omitempty"`
+	DataMovimentacao string                 `protobuf:"bytes,6,opt,name=data_movimentacao,json=dataMovimentacao,proto3" json:"data_movimentacao,omitempty"`
+	Lote             string                 `protobuf:"bytes,7,opt,name=lote,proto3" json:"lote,omitempty"`
+	Validade         string                 `protobuf:"bytes,8,opt,name=validade,proto3" json:"validade,omitempty"`
+	Lotes            []*MovimentacaoLote    `protobuf:"bytes,9,rep,name=lotes,proto3" json:"lotes,omitempty"`
+	Series           []string               `protobuf:"bytes,10,rep,name=series,proto3" json:"series,omitempty"`
+	Unidade          string                 `protobuf:"bytes,11,opt,name=unidade,proto3" json:"unidade,omitempty"`
+	CustoUnitario    *float64               `protobuf:"fixed64,12,opt,name=custo_unitario,json=custoUnitario,proto3,oneof" json:"custo_unitario,omitempty"`
+	Motivo           string                 `protobuf:"bytes,13,opt,name=motivo,proto3" json:"motivo,omitempty"`
+	LocalDescarteId  *int32                 `protobuf:"varint,14,opt,name=local_descarte_id,json=localDescarteId,proto3,oneof" json:"local_descarte_id,omitempty"`
+	ChecklistId      *int32                 `protobuf:"varint,15,opt,name=checklist_id,json=checklistId,proto3,oneof" json:"checklist_id,omitempty"`
+	Componentes      []*Movimentacao        `protobuf:"bytes,16,rep,name=componentes,proto3" json:"componentes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Movimentacao) Reset() {
+	*x = Movimentacao{}
+	mi := &file_sync_v1_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Movimentacao) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Movimentacao) ProtoMessage() {}
+
+func (x *Movimentacao) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Movimentacao.ProtoReflect.Descriptor instead.
+func (*Movimentacao) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Movimentacao) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetProdutoId() int32 {
+	if x != nil {
+		return x.ProdutoId
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetTipo() string {
+	if x != nil {
+		return x.Tipo
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetQuantidade() int32 {
+	if x != nil {
+		return x.Quantidade
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetNotas() string {
+	if x != nil {
+		return x.Notas
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetDataMovimentacao() string {
+	if x != nil {
+		return x.DataMovimentacao
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetLote() string {
+	if x != nil {
+		return x.Lote
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetValidade() string {
+	if x != nil {
+		return x.Validade
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetLotes() []*MovimentacaoLote {
+	if x != nil {
+		return x.Lotes
+	}
+	return nil
+}
+
+func (x *Movimentacao) GetSeries() []string {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *Movimentacao) GetUnidade() string {
+	if x != nil {
+		return x.Unidade
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetCustoUnitario() float64 {
+	if x != nil && x.CustoUnitario != nil {
+		return *x.CustoUnitario
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetMotivo() string {
+	if x != nil {
+		return x.Motivo
+	}
+	return ""
+}
+
+func (x *Movimentacao) GetLocalDescarteId() int32 {
+	if x != nil && x.LocalDescarteId != nil {
+		return *x.LocalDescarteId
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetChecklistId() int32 {
+	if x != nil && x.ChecklistId != nil {
+		return *x.ChecklistId
+	}
+	return 0
+}
+
+func (x *Movimentacao) GetComponentes() []*Movimentacao {
+	if x != nil {
+		return x.Componentes
+	}
+	return nil
+}
+
+type LancamentoSync struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProdutoId        int32                  `protobuf:"varint,2,opt,name=produto_id,json=produtoId,proto3" json:"produto_id,omitempty"`
+	Tipo             string                 `protobuf:"bytes,3,opt,name=tipo,proto3" json:"tipo,omitempty"`
+	Quantidade       int32                  `protobuf:"varint,4,opt,name=quantidade,proto3" json:"quantidade,omitempty"`
+	Notas            string                 `protobuf:"bytes,5,opt,name=notas,proto3" json:"notas,omitempty"`
+	DataMovimentacao string                 `protobuf:"bytes,6,opt,name=data_movimentacao,json=dataMovimentacao,proto3" json:"data_movimentacao,omitempty"`
+	Lote             string                 `protobuf:"bytes,7,opt,name=lote,proto3" json:"lote,omitempty"`
+	Validade         string                 `protobuf:"bytes,8,opt,name=validade,proto3" json:"validade,omitempty"`
+	Lotes            []*MovimentacaoLote    `protobuf:"bytes,9,rep,name=lotes,proto3" json:"lotes,omitempty"`
+	Series           []string               `protobuf:"bytes,10,rep,name=series,proto3" json:"series,omitempty"`
+	Unidade          string                 `protobuf:"bytes,11,opt,name=unidade,proto3" json:"unidade,omitempty"`
+	CustoUnitario    *float64               `protobuf:"fixed64,12,opt,name=custo_unitario,json=custoUnitario,proto3,oneof" json:"custo_unitario,omitempty"`
+	Motivo           string                 `protobuf:"bytes,13,opt,name=motivo,proto3" json:"motivo,omitempty"`
+	LocalDescarteId  *int32                 `protobuf:"varint,14,opt,name=local_descarte_id,json=localDescarteId,proto3,oneof" json:"local_descarte_id,omitempty"`
+	ChecklistId      *int32                 `protobuf:"varint,15,opt,name=checklist_id,json=checklistId,proto3,oneof" json:"checklist_id,omitempty"`
+	Componentes      []*Movimentacao        `protobuf:"bytes,16,rep,name=componentes,proto3" json:"componentes,omitempty"`
+	DataDispositivo  string                 `protobuf:"bytes,17,opt,name=data_dispositivo,json=dataDispositivo,proto3" json:"data_dispositivo,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LancamentoSync) Reset() {
+	*x = LancamentoSync{}
+	mi := &file_sync_v1_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LancamentoSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LancamentoSync) ProtoMessage() {}
+
+func (x *LancamentoSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LancamentoSync.ProtoReflect.Descriptor instead.
+func (*LancamentoSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LancamentoSync) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetProdutoId() int32 {
+	if x != nil {
+		return x.ProdutoId
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetTipo() string {
+	if x != nil {
+		return x.Tipo
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetQuantidade() int32 {
+	if x != nil {
+		return x.Quantidade
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetNotas() string {
+	if x != nil {
+		return x.Notas
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetDataMovimentacao() string {
+	if x != nil {
+		return x.DataMovimentacao
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetLote() string {
+	if x != nil {
+		return x.Lote
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetValidade() string {
+	if x != nil {
+		return x.Validade
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetLotes() []*MovimentacaoLote {
+	if x != nil {
+		return x.Lotes
+	}
+	return nil
+}
+
+func (x *LancamentoSync) GetSeries() []string {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *LancamentoSync) GetUnidade() string {
+	if x != nil {
+		return x.Unidade
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetCustoUnitario() float64 {
+	if x != nil && x.CustoUnitario != nil {
+		return *x.CustoUnitario
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetMotivo() string {
+	if x != nil {
+		return x.Motivo
+	}
+	return ""
+}
+
+func (x *LancamentoSync) GetLocalDescarteId() int32 {
+	if x != nil && x.LocalDescarteId != nil {
+		return *x.LocalDescarteId
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetChecklistId() int32 {
+	if x != nil && x.ChecklistId != nil {
+		return *x.ChecklistId
+	}
+	return 0
+}
+
+func (x *LancamentoSync) GetComponentes() []*Movimentacao {
+	if x != nil {
+		return x.Componentes
+	}
+	return nil
+}
+
+func (x *LancamentoSync) GetDataDispositivo() string {
+	if x != nil {
+		return x.DataDispositivo
+	}
+	return ""
+}
+
+type RequisicaoSync struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivo   string                 `protobuf:"bytes,1,opt,name=dispositivo,proto3" json:"dispositivo,omitempty"`
+	Lancamentos   []*LancamentoSync      `protobuf:"bytes,2,rep,name=lancamentos,proto3" json:"lancamentos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequisicaoSync) Reset() {
+	*x = RequisicaoSync{}
+	mi := &file_sync_v1_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequisicaoSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequisicaoSync) ProtoMessage() {}
+
+func (x *RequisicaoSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequisicaoSync.ProtoReflect.Descriptor instead.
+func (*RequisicaoSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RequisicaoSync) GetDispositivo() string {
+	if x != nil {
+		return x.Dispositivo
+	}
+	return ""
+}
+
+func (x *RequisicaoSync) GetLancamentos() []*LancamentoSync {
+	if x != nil {
+		return x.Lancamentos
+	}
+	return nil
+}
+
+type ErroCampo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Campo         string                 `protobuf:"bytes,1,opt,name=campo,proto3" json:"campo,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Mensagem      string                 `protobuf:"bytes,3,opt,name=mensagem,proto3" json:"mensagem,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErroCampo) Reset() {
+	*x = ErroCampo{}
+	mi := &file_sync_v1_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErroCampo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErroCampo) ProtoMessage() {}
+
+func (x *ErroCampo) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErroCampo.ProtoReflect.Descriptor instead.
+func (*ErroCampo) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ErroCampo) GetCampo() string {
+	if x != nil {
+		return x.Campo
+	}
+	return ""
+}
+
+func (x *ErroCampo) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ErroCampo) GetMensagem() string {
+	if x != nil {
+		return x.Mensagem
+	}
+	return ""
+}
+
+type FaltaEstoque struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProdutoId     int32                  `protobuf:"varint,1,opt,name=produto_id,json=produtoId,proto3" json:"produto_id,omitempty"`
+	ProdutoCodigo string                 `protobuf:"bytes,2,opt,name=produto_codigo,json=produtoCodigo,proto3" json:"produto_codigo,omitempty"`
+	Solicitado    int32                  `protobuf:"varint,3,opt,name=solicitado,proto3" json:"solicitado,omitempty"`
+	Disponivel    int32                  `protobuf:"varint,4,opt,name=disponivel,proto3" json:"disponivel,omitempty"`
+	Falta         int32                  `protobuf:"varint,5,opt,name=falta,proto3" json:"falta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FaltaEstoque) Reset() {
+	*x = FaltaEstoque{}
+	mi := &file_sync_v1_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FaltaEstoque) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FaltaEstoque) ProtoMessage() {}
+
+func (x *FaltaEstoque) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FaltaEstoque.ProtoReflect.Descriptor instead.
+func (*FaltaEstoque) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FaltaEstoque) GetProdutoId() int32 {
+	if x != nil {
+		return x.ProdutoId
+	}
+	return 0
+}
+
+func (x *FaltaEstoque) GetProdutoCodigo() string {
+	if x != nil {
+		return x.ProdutoCodigo
+	}
+	return ""
+}
+
+func (x *FaltaEstoque) GetSolicitado() int32 {
+	if x != nil {
+		return x.Solicitado
+	}
+	return 0
+}
+
+func (x *FaltaEstoque) GetDisponivel() int32 {
+	if x != nil {
+		return x.Disponivel
+	}
+	return 0
+}
+
+func (x *FaltaEstoque) GetFalta() int32 {
+	if x != nil {
+		return x.Falta
+	}
+	return 0
+}
+
+type ResultadoItemLote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Indice        int32                  `protobuf:"varint,1,opt,name=indice,proto3" json:"indice,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Erro          string                 `protobuf:"bytes,3,opt,name=erro,proto3" json:"erro,omitempty"`
+	Code          string                 `protobuf:"bytes,4,opt,name=code,proto3" json:"code,omitempty"`
+	Campos        []*ErroCampo           `protobuf:"bytes,5,rep,name=campos,proto3" json:"campos,omitempty"`
+	Falta         *FaltaEstoque          `protobuf:"bytes,6,opt,name=falta,proto3" json:"falta,omitempty"`
+	Movimentacao  *Movimentacao          `protobuf:"bytes,7,opt,name=movimentacao,proto3" json:"movimentacao,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultadoItemLote) Reset() {
+	*x = ResultadoItemLote{}
+	mi := &file_sync_v1_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultadoItemLote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultadoItemLote) ProtoMessage() {}
+
+func (x *ResultadoItemLote) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultadoItemLote.ProtoReflect.Descriptor instead.
+func (*ResultadoItemLote) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ResultadoItemLote) GetIndice() int32 {
+	if x != nil {
+		return x.Indice
+	}
+	return 0
+}
+
+func (x *ResultadoItemLote) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ResultadoItemLote) GetErro() string {
+	if x != nil {
+		return x.Erro
+	}
+	return ""
+}
+
+func (x *ResultadoItemLote) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ResultadoItemLote) GetCampos() []*ErroCampo {
+	if x != nil {
+		return x.Campos
+	}
+	return nil
+}
+
+func (x *ResultadoItemLote) GetFalta() *FaltaEstoque {
+	if x != nil {
+		return x.Falta
+	}
+	return nil
+}
+
+func (x *ResultadoItemLote) GetMovimentacao() *Movimentacao {
+	if x != nil {
+		return x.Movimentacao
+	}
+	return nil
+}
+
+type ResultadoSync struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivo   string                 `protobuf:"bytes,1,opt,name=dispositivo,proto3" json:"dispositivo,omitempty"`
+	OffsetMs      int64                  `protobuf:"varint,2,opt,name=offset_ms,json=offsetMs,proto3" json:"offset_ms,omitempty"`
+	Registrados   int32                  `protobuf:"varint,3,opt,name=registrados,proto3" json:"registrados,omitempty"`
+	Rejeitados    int32                  `protobuf:"varint,4,opt,name=rejeitados,proto3" json:"rejeitados,omitempty"`
+	Itens         []*ResultadoItemLote   `protobuf:"bytes,5,rep,name=itens,proto3" json:"itens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultadoSync) Reset() {
+	*x = ResultadoSync{}
+	mi := &file_sync_v1_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultadoSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultadoSync) ProtoMessage() {}
+
+func (x *ResultadoSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultadoSync.ProtoReflect.Descriptor instead.
+func (*ResultadoSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ResultadoSync) GetDispositivo() string {
+	if x != nil {
+		return x.Dispositivo
+	}
+	return ""
+}
+
+func (x *ResultadoSync) GetOffsetMs() int64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+func (x *ResultadoSync) GetRegistrados() int32 {
+	if x != nil {
+		return x.Registrados
+	}
+	return 0
+}
+
+func (x *ResultadoSync) GetRejeitados() int32 {
+	if x != nil {
+		return x.Rejeitados
+	}
+	return 0
+}
+
+func (x *ResultadoSync) GetItens() []*ResultadoItemLote {
+	if x != nil {
+		return x.Itens
+	}
+	return nil
+}
+
+type DispositivoSync struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivo     string                 `protobuf:"bytes,1,opt,name=dispositivo,proto3" json:"dispositivo,omitempty"`
+	OffsetMs        int64                  `protobuf:"varint,2,opt,name=offset_ms,json=offsetMs,proto3" json:"offset_ms,omitempty"`
+	SkewMaximoMs    int64                  `protobuf:"varint,3,opt,name=skew_maximo_ms,json=skewMaximoMs,proto3" json:"skew_maximo_ms,omitempty"`
+	UltimoHandshake string                 `protobuf:"bytes,4,opt,name=ultimo_handshake,json=ultimoHandshake,proto3" json:"ultimo_handshake,omitempty"`
+	UltimoSync      *string                `protobuf:"bytes,5,opt,name=ultimo_sync,json=ultimoSync,proto3,oneof" json:"ultimo_sync,omitempty"`
+	Lancamentos     int32                  `protobuf:"varint,6,opt,name=lancamentos,proto3" json:"lancamentos,omitempty"`
+	Rejeitados      int32                  `protobuf:"varint,7,opt,name=rejeitados,proto3" json:"rejeitados,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DispositivoSync) Reset() {
+	*x = DispositivoSync{}
+	mi := &file_sync_v1_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DispositivoSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DispositivoSync) ProtoMessage() {}
+
+func (x *DispositivoSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DispositivoSync.ProtoReflect.Descriptor instead.
+func (*DispositivoSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DispositivoSync) GetDispositivo() string {
+	if x != nil {
+		return x.Dispositivo
+	}
+	return ""
+}
+
+func (x *DispositivoSync) GetOffsetMs() int64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+func (x *DispositivoSync) GetSkewMaximoMs() int64 {
+	if x != nil {
+		return x.SkewMaximoMs
+	}
+	return 0
+}
+
+func (x *DispositivoSync) GetUltimoHandshake() string {
+	if x != nil {
+		return x.UltimoHandshake
+	}
+	return ""
+}
+
+func (x *DispositivoSync) GetUltimoSync() string {
+	if x != nil && x.UltimoSync != nil {
+		return *x.UltimoSync
+	}
+	return ""
+}
+
+func (x *DispositivoSync) GetLancamentos() int32 {
+	if x != nil {
+		return x.Lancamentos
+	}
+	return 0
+}
+
+func (x *DispositivoSync) GetRejeitados() int32 {
+	if x != nil {
+		return x.Rejeitados
+	}
+	return 0
+}
+
+type ListaDispositivosSync struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dispositivos  []*DispositivoSync     `protobuf:"bytes,1,rep,name=dispositivos,proto3" json:"dispositivos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListaDispositivosSync) Reset() {
+	*x = ListaDispositivosSync{}
+	mi := &file_sync_v1_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListaDispositivosSync) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListaDispositivosSync) ProtoMessage() {}
+
+func (x *ListaDispositivosSync) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_v1_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListaDispositivosSync.ProtoReflect.Descriptor instead.
+func (*ListaDispositivosSync) Descriptor() ([]byte, []int) {
+	return file_sync_v1_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListaDispositivosSync) GetDispositivos() []*DispositivoSync {
+	if x != nil {
+		return x.Dispositivos
+	}
+	return nil
+}
+
+var File_sync_v1_proto protoreflect.FileDescriptor
+
+const file_sync_v1_proto_rawDesc = "" +
+	"\n" +
+	"\rsync_v1.proto\x12\vrls.sync.v1\"K\n" +
+	"\rHandshakeSync\x12 \n" +
+	"\vdispositivo\x18\x01 \x01(\tR\vdispositivo\x12\x18\n" +
+	"\arelogio\x18\x02 \x01(\tR\arelogio\"\xa3\x01\n" +
+	"\x15RespostaHandshakeSync\x12 \n" +
+	"\vdispositivo\x18\x01 \x01(\tR\vdispositivo\x12\x1a\n" +
+	"\bservidor\x18\x02 \x01(\tR\bservidor\x12\x1b\n" +
+	"\toffset_ms\x18\x03 \x01(\x03R\boffsetMs\x12/\n" +
+	"\x13tolerancia_segundos\x18\x04 \x01(\x05R\x12toleranciaSegundos\"\x9a\x01\n" +
+	"\x10MovimentacaoLote\x12\x17\n" +
+	"\alote_id\x18\x01 \x01(\x05R\x06loteId\x12\x1f\n" +
+	"\vnumero_lote\x18\x02 \x01(\tR\n" +
+	"numeroLote\x12\x1f\n" +
+	"\bvalidade\x18\x03 \x01(\tH\x00R\bvalidade\x88\x01\x01\x12\x1e\n" +
+	"\n" +
+	"quantidade\x18\x04 \x01(\x05R\n" +
+	"quantidadeB\v\n" +
+	"\t_validade\"\xdf\x04\n" +
+	"\fMovimentacao\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1d\n" +
+	"\n" +
+	"produto_id\x18\x02 \x01(\x05R\tprodutoId\x12\x12\n" +
+	"\x04tipo\x18\x03 \x01(\tR\x04tipo\x12\x1e\n" +
+	"\n" +
+	"quantidade\x18\x04 \x01(\x05R\n" +
+	"quantidade\x12\x14\n" +
+	"\x05notas\x18\x05 \x01(\tR\x05notas\x12+\n" +
+	"\x11data_movimentacao\x18\x06 \x01(\tR\x10dataMovimentacao\x12\x12\n" +
+	"\x04lote\x18\a \x01(\tR\x04lote\x12\x1a\n" +
+	"\bvalidade\x18\b \x01(\tR\bvalidade\x123\n" +
+	"\x05lotes\x18\t \x03(\v2\x1d.rls.sync.v1.MovimentacaoLoteR\x05lotes\x12\x16\n" +
+	"\x06series\x18\n" +
+	" \x03(\tR\x06series\x12\x18\n" +
+	"\aunidade\x18\v \x01(\tR\aunidade\x12*\n" +
+	"\x0ecusto_unitario\x18\f \x01(\x01H\x00R\rcustoUnitario\x88\x01\x01\x12\x16\n" +
+	"\x06motivo\x18\r \x01(\tR\x06motivo\x12/\n" +
+	"\x11local_descarte_id\x18\x0e \x01(\x05H\x01R\x0flocalDescarteId\x88\x01\x01\x12&\n" +
+	"\fchecklist_id\x18\x0f \x01(\x05H\x02R\vchecklistId\x88\x01\x01\x12;\n" +
+	"\vcomponentes\x18\x10 \x03(\v2\x19.rls.sync.v1.MovimentacaoR\vcomponentesB\x11\n" +
+	"\x0f_custo_unitarioB\x14\n" +
+	"\x12_local_descarte_idB\x0f\n" +
+	"\r_checklist_id\"\x8c\x05\n" +
+	"\x0eLancamentoSync\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1d\n" +
+	"\n" +
+	"produto_id\x18\x02 \x01(\x05R\tprodutoId\x12\x12\n" +
+	"\x04tipo\x18\x03 \x01(\tR\x04tipo\x12\x1e\n" +
+	"\n" +
+	"quantidade\x18\x04 \x01(\x05R\n" +
+	"quantidade\x12\x14\n" +
+	"\x05notas\x18\x05 \x01(\tR\x05notas\x12+\n" +
+	"\x11data_movimentacao\x18\x06 \x01(\tR\x10dataMovimentacao\x12\x12\n" +
+	"\x04lote\x18\a \x01(\tR\x04lote\x12\x1a\n" +
+	"\bvalidade\x18\b \x01(\tR\bvalidade\x123\n" +
+	"\x05lotes\x18\t \x03(\v2\x1d.rls.sync.v1.MovimentacaoLoteR\x05lotes\x12\x16\n" +
+	"\x06series\x18\n" +
+	" \x03(\tR\x06series\x12\x18\n" +
+	"\aunidade\x18\v \x01(\tR\aunidade\x12*\n" +
+	"\x0ecusto_unitario\x18\f \x01(\x01H\x00R\rcustoUnitario\x88\x01\x01\x12\x16\n" +
+	"\x06motivo\x18\r \x01(\tR\x06motivo\x12/\n" +
+	"\x11local_descarte_id\x18\x0e \x01(\x05H\x01R\x0flocalDescarteId\x88\x01\x01\x12&\n" +
+	"\fchecklist_id\x18\x0f \x01(\x05H\x02R\vchecklistId\x88\x01\x01\x12;\n" +
+	"\vcomponentes\x18\x10 \x03(\v2\x19.rls.sync.v1.MovimentacaoR\vcomponentes\x12)\n" +
+	"\x10data_dispositivo\x18\x11 \x01(\tR\x0fdataDispositivoB\x11\n" +
+	"\x0f_custo_unitarioB\x14\n" +
+	"\x12_local_descarte_idB\x0f\n" +
+	"\r_checklist_id\"q\n" +
+	"\x0eRequisicaoSync\x12 \n" +
+	"\vdispositivo\x18\x01 \x01(\tR\vdispositivo\x12=\n" +
+	"\vlancamentos\x18\x02 \x03(\v2\x1b.rls.sync.v1.LancamentoSyncR\vlancamentos\"Q\n" +
+	"\tErroCampo\x12\x14\n" +
+	"\x05campo\x18\x01 \x01(\tR\x05campo\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x1a\n" +
+	"\bmensagem\x18\x03 \x01(\tR\bmensagem\"\xaa\x01\n" +
+	"\fFaltaEstoque\x12\x1d\n" +
+	"\n" +
+	"produto_id\x18\x01 \x01(\x05R\tprodutoId\x12%\n" +
+	"\x0eproduto_codigo\x18\x02 \x01(\tR\rprodutoCodigo\x12\x1e\n" +
+	"\n" +
+	"solicitado\x18\x03 \x01(\x05R\n" +
+	"solicitado\x12\x1e\n" +
+	"\n" +
+	"disponivel\x18\x04 \x01(\x05R\n" +
+	"disponivel\x12\x14\n" +
+	"\x05falta\x18\x05 \x01(\x05R\x05falta\"\x8b\x02\n" +
+	"\x11ResultadoItemLote\x12\x16\n" +
+	"\x06indice\x18\x01 \x01(\x05R\x06indice\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04erro\x18\x03 \x01(\tR\x04erro\x12\x12\n" +
+	"\x04code\x18\x04 \x01(\tR\x04code\x12.\n" +
+	"\x06campos\x18\x05 \x03(\v2\x16.rls.sync.v1.ErroCampoR\x06campos\x12/\n" +
+	"\x05falta\x18\x06 \x01(\v2\x19.rls.sync.v1.FaltaEstoqueR\x05falta\x12=\n" +
+	"\fmovimentacao\x18\a \x01(\v2\x19.rls.sync.v1.MovimentacaoR\fmovimentacao\"\xc6\x01\n" +
+	"\rResultadoSync\x12 \n" +
+	"\vdispositivo\x18\x01 \x01(\tR\vdispositivo\x12\x1b\n" +
+	"\toffset_ms\x18\x02 \x01(\x03R\boffsetMs\x12 \n" +
+	"\vregistrados\x18\x03 \x01(\x05R\vregistrados\x12\x1e\n" +
+	"\n" +
+	"rejeitados\x18\x04 \x01(\x05R\n" +
+	"rejeitados\x124\n" +
+	"\x05itens\x18\x05 \x03(\v2\x1e.rls.sync.v1.ResultadoItemLoteR\x05itens\"\x99\x02\n" +
+	"\x0fDispositivoSync\x12 \n" +
+	"\vdispositivo\x18\x01 \x01(\tR\vdispositivo\x12\x1b\n" +
+	"\toffset_ms\x18\x02 \x01(\x03R\boffsetMs\x12$\n" +
+	"\x0eskew_maximo_ms\x18\x03 \x01(\x03R\fskewMaximoMs\x12)\n" +
+	"\x10ultimo_handshake\x18\x04 \x01(\tR\x0fultimoHandshake\x12$\n" +
+	"\vultimo_sync\x18\x05 \x01(\tH\x00R\n" +
+	"ultimoSync\x88\x01\x01\x12 \n" +
+	"\vlancamentos\x18\x06 \x01(\x05R\vlancamentos\x12\x1e\n" +
+	"\n" +
+	"rejeitados\x18\a \x01(\x05R\n" +
+	"rejeitadosB\x0e\n" +
+	"\f_ultimo_sync\"Y\n" +
+	"\x15ListaDispositivosSync\x12@\n" +
+	"\fdispositivos\x18\x01 \x03(\v2\x1c.rls.sync.v1.DispositivoSyncR\fdispositivosB1Z/github.com/rlsautomacao/estoque/internal/syncpbb\x06proto3"
+
+var (
+	file_sync_v1_proto_rawDescOnce sync.Once
+	file_sync_v1_proto_rawDescData []byte
+)
+
+func file_sync_v1_proto_rawDescGZIP() []byte {
+	file_sync_v1_proto_rawDescOnce.Do(func() {
+		file_sync_v1_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sync_v1_proto_rawDesc), len(file_sync_v1_proto_rawDesc)))
+	})
+	return file_sync_v1_proto_rawDescData
+}
+
+var file_sync_v1_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_sync_v1_proto_goTypes = []any{
+	(*HandshakeSync)(nil),         // 0: rls.sync.v1.HandshakeSync
+	(*RespostaHandshakeSync)(nil), // 1: rls.sync.v1.RespostaHandshakeSync
+	(*MovimentacaoLote)(nil),      // 2: rls.sync.v1.MovimentacaoLote
+	(*Movimentacao)(nil),          // 3: rls.sync.v1.Movimentacao
+	(*LancamentoSync)(nil),        // 4: rls.sync.v1.LancamentoSync
+	(*RequisicaoSync)(nil),        // 5: rls.sync.v1.RequisicaoSync
+	(*ErroCampo)(nil),             // 6: rls.sync.v1.ErroCampo
+	(*FaltaEstoque)(nil),          // 7: rls.sync.v1.FaltaEstoque
+	(*ResultadoItemLote)(nil),     // 8: rls.sync.v1.ResultadoItemLote
+	(*ResultadoSync)(nil),         // 9: rls.sync.v1.ResultadoSync
+	(*DispositivoSync)(nil),       // 10: rls.sync.v1.DispositivoSync
+	(*ListaDispositivosSync)(nil), // 11: rls.sync.v1.ListaDispositivosSync
+}
+var file_sync_v1_proto_depIdxs = []int32{
+	2,  // 0: rls.sync.v1.Movimentacao.lotes:type_name -> rls.sync.v1.MovimentacaoLote
+	3,  // 1: rls.sync.v1.Movimentacao.componentes:type_name -> rls.sync.v1.Movimentacao
+	2,  // 2: rls.sync.v1.LancamentoSync.lotes:type_name -> rls.sync.v1.MovimentacaoLote
+	3,  // 3: rls.sync.v1.LancamentoSync.componentes:type_name -> rls.sync.v1.Movimentacao
+	4,  // 4: rls.sync.v1.RequisicaoSync.lancamentos:type_name -> rls.sync.v1.LancamentoSync
+	6,  // 5: rls.sync.v1.ResultadoItemLote.campos:type_name -> rls.sync.v1.ErroCampo
+	7,  // 6: rls.sync.v1.ResultadoItemLote.falta:type_name -> rls.sync.v1.FaltaEstoque
+	3,  // 7: rls.sync.v1.ResultadoItemLote.movimentacao:type_name -> rls.sync.v1.Movimentacao
+	8,  // 8: rls.sync.v1.ResultadoSync.itens:type_name -> rls.sync.v1.ResultadoItemLote
+	10, // 9: rls.sync.v1.ListaDispositivosSync.dispositivos:type_name -> rls.sync.v1.DispositivoSync
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_sync_v1_proto_init() }
+func file_sync_v1_proto_init() {
+	if File_sync_v1_proto != nil {
+		return
+	}
+	file_sync_v1_proto_msgTypes[2].OneofWrappers = []any{}
+	file_sync_v1_proto_msgTypes[3].OneofWrappers = []any{}
+	file_sync_v1_proto_msgTypes[4].OneofWrappers = []any{}
+	file_sync_v1_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sync_v1_proto_rawDesc), len(file_sync_v1_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_sync_v1_proto_goTypes,
+		DependencyIndexes: file_sync_v1_proto_depIdxs,
+		MessageInfos:      file_sync_v1_proto_msgTypes,
+	}.Build()
+	File_sync_v1_proto = out.File
+	file_sync_v1_proto_goTypes = nil
+	file_sync_v1_proto_depIdxs = nil
+}
@@ -0,0 +1,444 @@
+// kits.go - Kits e montagem de produtos a partir da estrutura
+//
+// Um kit (produtos.kit) é composto pelos componentes da sua estrutura e não
+// tem estoque próprio: a saída de um kit, por movimentação ou pedido de
+// saída, registra a saída de cada componente (kits dentro de kits são
+// abertos até os componentes com estoque). Produtos montados, como os painéis,
+// têm estoque próprio: POST /api/produtos/:id/montagem baixa os componentes e
+// dá entrada do produto com o custo dos componentes consumidos.
+//
+// GET /api/produtos/:id/disponibilidade calcula quantas unidades o estoque
+// dos componentes permite montar e qual componente limita; nos kits esse é o
+// saldo disponível para saída.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type RequisicaoMontagem struct {
+	Quantidade int      `json:"quantidade" binding:"required,min=1"`
+	Notas      string   `json:"notas,omitempty" binding:"max=500"`
+	Lote       string   `json:"lote,omitempty" binding:"max=50"`
+	Validade   string   `json:"validade,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	Series     []string `json:"series,omitempty" binding:"dive,max=100"` // produtos com controle de série
+
+	// Séries, descarte e checklist das baixas de componentes que os exigem
+	Componentes []BaixaComponente `json:"componentes,omitempty" binding:"dive"`
+}
+
+type BaixaComponente struct {
+	ProdutoID int `json:"produto_id" binding:"required,min=1"`
+	DadosBaixa
+}
+
+type ResultadoMontagem struct {
+	ProdutoID     int            `json:"produto_id"`
+	Quantidade    int            `json:"quantidade"`
+	CustoUnitario float64        `json:"custo_unitario"`
+	Entrada       Movimentacao   `json:"entrada"`
+	Consumos      []Movimentacao `json:"consumos"`
+}
+
+type DisponibilidadeComponente struct {
+	ComponenteID     int     `json:"componente_id"`
+	ComponenteCodigo string  `json:"componente_codigo"`
+	Quantidade       float64 `json:"quantidade"` // por unidade do produto
+	Disponivel       int     `json:"disponivel"` // saldo, ou o montável se o componente for kit
+	Montavel         int     `json:"montavel"`   // unidades do produto que o componente permite
+}
+
+type DisponibilidadeProduto struct {
+	ProdutoID   int                         `json:"produto_id"`
+	Codigo      string                      `json:"codigo"`
+	Kit         bool                        `json:"kit"`
+	Estoque     int                         `json:"estoque"`             // saldo próprio; sempre 0 nos kits
+	Montavel    int                         `json:"montavel"`            // unidades que o estoque dos componentes permite montar
+	Disponivel  int                         `json:"disponivel"`          // para saída: o montável nos kits, o saldo nos demais
+	Limitante   string                      `json:"limitante,omitempty"` // código do componente que limita a montagem
+	Componentes []DisponibilidadeComponente `json:"componentes"`
+}
+
+// Saída de um componente com estoque na abertura de um kit
+type baixaKit struct {
+	ProdutoID  int
+	Codigo     string
+	Quantidade int
+}
+
+// Abre o kit até os componentes com estoque próprio, somando a quantidade de
+// cada um (arredondada para cima). Um produto que não é kit volta sozinho;
+// kit sem componentes volta vazio.
+func explodirKit(ctx context.Context, q querier, produtoID, quantidade int) ([]baixaKit, error) {
+	rows, err := q.Query(ctx, `
+		WITH RECURSIVE explosao(produto_id, quantidade, kit) AS (
+			SELECT id, $2::numeric, kit FROM produtos WHERE id = $1
+			UNION ALL
+			SELECT e.componente_id, x.quantidade * e.quantidade, p.kit
+			FROM explosao x
+			JOIN estruturas_produto e ON e.produto_id = x.produto_id
+			JOIN produtos p ON p.id = e.componente_id
+			WHERE x.kit
+		)
+		SELECT x.produto_id, p.codigo, CEIL(SUM(x.quantidade))::int
+		FROM explosao x
+		JOIN produtos p ON p.id = x.produto_id
+		WHERE NOT x.kit
+		GROUP BY x.produto_id, p.codigo
+		ORDER BY x.produto_id
+	`, produtoID, quantidade)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baixas := []baixaKit{}
+	for rows.Next() {
+		var b baixaKit
+		if err := rows.Scan(&b.ProdutoID, &b.Codigo, &b.Quantidade); err != nil {
+			return nil, err
+		}
+		baixas = append(baixas, b)
+	}
+	return baixas, rows.Err()
+}
+
+// Registra a saída de um kit como saídas dos componentes, em ordem de ID para
+// as travas não se cruzarem com outras transações; a movimentação do kit
+// volta com as dos componentes em Componentes
+func registrarSaidaKit(ctx context.Context, tx pgx.Tx, m *Movimentacao, codigo string) error {
+	if m.Tipo != "saida" {
+		log.Printf("[ERROR] Entrada em kit recusada. Produto ID: %d", m.ProdutoID)
+		return &erroMovimentacao{status: http.StatusBadRequest, msg: "Kits não têm estoque próprio: registre a entrada dos componentes"}
+	}
+	if m.Lote != "" || len(m.Series) > 0 {
+		return &erroMovimentacao{status: http.StatusBadRequest, msg: "Lote e números de série são informados na saída de cada componente do kit"}
+	}
+
+	baixas, err := explodirKit(ctx, tx, m.ProdutoID, m.Quantidade)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao abrir kit %d: %v", m.ProdutoID, err)
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao carregar componentes do kit"}
+	}
+	if len(baixas) == 0 {
+		log.Printf("[ERROR] Kit sem componentes. Produto ID: %d", m.ProdutoID)
+		return &erroMovimentacao{status: http.StatusBadRequest, msg: "Kit sem componentes: cadastre a estrutura do kit"}
+	}
+
+	notas := fmt.Sprintf("Kit %s (%d un.)", codigo, m.Quantidade)
+	if m.Notas != "" {
+		notas += ": " + m.Notas
+	}
+	m.Componentes = make([]Movimentacao, 0, len(baixas))
+	for _, b := range baixas {
+		cm := Movimentacao{
+			ProdutoID:       b.ProdutoID,
+			Tipo:            "saida",
+			Quantidade:      b.Quantidade,
+			Notas:           notas,
+			Motivo:          m.Motivo,
+			LocalDescarteID: m.LocalDescarteID,
+		}
+		if err := registrarMovimentacao(ctx, tx, &cm); err != nil {
+			return err
+		}
+		m.Componentes = append(m.Componentes, cm)
+	}
+	m.DataMovimentacao = m.Componentes[0].DataMovimentacao
+
+	log.Printf("[DB] Saída do kit %s (%d un.) registrada em %d componentes", codigo, m.Quantidade, len(m.Componentes))
+	return nil
+}
+
+// IDs das movimentações gravadas: as dos componentes na saída de kit
+func (m *Movimentacao) idsRegistrados() []int {
+	if len(m.Componentes) == 0 {
+		return []int{m.ID}
+	}
+	ids := make([]int, len(m.Componentes))
+	for i, cm := range m.Componentes {
+		ids[i] = cm.ID
+	}
+	return ids
+}
+
+// Handlers de Kits e montagem
+
+// Monta o produto: baixa os componentes diretos da estrutura e dá entrada do
+// produto com o custo dos componentes consumidos
+func montarProduto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req RequisicaoMontagem
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var codigo string
+	var kit bool
+	err = tx.QueryRow(ctx, "SELECT codigo, kit FROM produtos WHERE id = $1", id).Scan(&codigo, &kit)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+		}
+		return
+	}
+	if kit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Kits não são montados: a saída do kit já baixa os componentes"})
+		return
+	}
+
+	// Componentes diretos, em ordem de ID; submontagens saem do próprio estoque
+	rows, err := tx.Query(ctx, `
+		SELECT e.componente_id, e.quantidade::float8, p.preco_custo
+		FROM estruturas_produto e
+		JOIN produtos p ON p.id = e.componente_id
+		WHERE e.produto_id = $1
+		ORDER BY e.componente_id
+	`, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar estrutura: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar estrutura"})
+		return
+	}
+	dados := map[int]DadosBaixa{}
+	for _, d := range req.Componentes {
+		dados[d.ProdutoID] = d.DadosBaixa
+	}
+	notas := fmt.Sprintf("Montagem de %d %s", req.Quantidade, codigo)
+	var consumos []Movimentacao
+	custoTotal := 0.0
+	for rows.Next() {
+		var componenteID int
+		var porUnidade, precoCusto float64
+		if err := rows.Scan(&componenteID, &porUnidade, &precoCusto); err != nil {
+			rows.Close()
+			log.Printf("[ERROR] Erro ao processar componente: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar estrutura"})
+			return
+		}
+		quantidade := int(math.Ceil(porUnidade*float64(req.Quantidade) - 1e-9))
+		custoTotal += precoCusto * float64(quantidade)
+		consumo := Movimentacao{ProdutoID: componenteID, Tipo: "saida", Quantidade: quantidade, Notas: notas}
+		dados[componenteID].aplicar(&consumo)
+		delete(dados, componenteID)
+		consumos = append(consumos, consumo)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar estrutura: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar estrutura"})
+		return
+	}
+	if len(consumos) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Produto sem estrutura: cadastre os componentes antes da montagem"})
+		return
+	}
+	for componenteID := range dados {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Produto %d não é componente de %s", componenteID, codigo)})
+		return
+	}
+
+	for i := range consumos {
+		if err = registrarMovimentacao(ctx, tx, &consumos[i]); err != nil {
+			e, ok := err.(*erroMovimentacao)
+			if !ok {
+				log.Printf("[ERROR] Erro ao registrar consumo do componente %d: %v", consumos[i].ProdutoID, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+				return
+			}
+			c.JSON(e.status, e.resposta())
+			return
+		}
+	}
+
+	custoUnitario := math.Round(custoTotal/float64(req.Quantidade)*10000) / 10000
+	if req.Notas != "" {
+		notas += ": " + req.Notas
+	}
+	entrada := Movimentacao{
+		ProdutoID:     id,
+		Tipo:          "entrada",
+		Quantidade:    req.Quantidade,
+		Notas:         notas,
+		Lote:          req.Lote,
+		Validade:      req.Validade,
+		Series:        req.Series,
+		CustoUnitario: &custoUnitario,
+	}
+	if err = registrarMovimentacao(ctx, tx, &entrada); err != nil {
+		e, ok := err.(*erroMovimentacao)
+		if !ok {
+			log.Printf("[ERROR] Erro ao registrar entrada da montagem de %s: %v", codigo, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+			return
+		}
+		c.JSON(e.status, e.resposta())
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Montagem de %d %s registrada (%d componentes, custo unitário %.4f)", req.Quantidade, codigo, len(consumos), custoUnitario)
+	publicarMovimentacoes(ctx, append(consumos, entrada))
+	c.JSON(http.StatusCreated, ResultadoMontagem{
+		ProdutoID:     id,
+		Quantidade:    req.Quantidade,
+		CustoUnitario: custoUnitario,
+		Entrada:       entrada,
+		Consumos:      consumos,
+	})
+}
+
+// Quanto do produto o estoque dos componentes permite montar
+func getDisponibilidadeProduto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	estruturas, err := carregarEstruturas(ctx, db)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar estruturas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular disponibilidade"})
+		return
+	}
+
+	// Saldo e tipo do produto e de tudo abaixo dele na estrutura
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		for _, comp := range estruturas[ids[i]] {
+			ids = append(ids, comp.ComponenteID)
+		}
+	}
+	rows, err := db.Query(ctx, "SELECT id, codigo, quantidade, kit FROM produtos WHERE id = ANY($1)", ids)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar saldos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular disponibilidade"})
+		return
+	}
+	type saldoProduto struct {
+		codigo     string
+		quantidade int
+		kit        bool
+	}
+	saldos := map[int]saldoProduto{}
+	for rows.Next() {
+		var pid int
+		var s saldoProduto
+		if err := rows.Scan(&pid, &s.codigo, &s.quantidade, &s.kit); err != nil {
+			rows.Close()
+			log.Printf("[ERROR] Erro ao processar saldo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular disponibilidade"})
+			return
+		}
+		saldos[pid] = s
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar saldos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular disponibilidade"})
+		return
+	}
+	produto, ok := saldos[id]
+	if !ok {
+		log.Printf("[DB] Produto não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		return
+	}
+
+	// Disponível para saída: kits pelo montável, os demais pelo saldo. A
+	// estrutura não tem ciclos (ver estruturas.go)
+	montaveis := map[int]int{}
+	var disponivel, montavel func(int) int
+	disponivel = func(pid int) int {
+		if saldos[pid].kit {
+			return montavel(pid)
+		}
+		return max(saldos[pid].quantidade, 0)
+	}
+	montavel = func(pid int) int {
+		if n, ok := montaveis[pid]; ok {
+			return n
+		}
+		n := 0
+		for i, comp := range estruturas[pid] {
+			permite := int(math.Floor(float64(disponivel(comp.ComponenteID))/comp.Quantidade + 1e-9))
+			if i == 0 || permite < n {
+				n = permite
+			}
+		}
+		montaveis[pid] = n
+		return n
+	}
+
+	d := DisponibilidadeProduto{
+		ProdutoID:   id,
+		Codigo:      produto.codigo,
+		Kit:         produto.kit,
+		Estoque:     produto.quantidade,
+		Montavel:    montavel(id),
+		Componentes: []DisponibilidadeComponente{},
+	}
+	d.Disponivel = d.Estoque
+	if d.Kit {
+		d.Disponivel = d.Montavel
+	}
+	for _, comp := range estruturas[id] {
+		dc := DisponibilidadeComponente{
+			ComponenteID:     comp.ComponenteID,
+			ComponenteCodigo: comp.ComponenteCodigo,
+			Quantidade:       comp.Quantidade,
+			Disponivel:       disponivel(comp.ComponenteID),
+		}
+		dc.Montavel = int(math.Floor(float64(dc.Disponivel)/comp.Quantidade + 1e-9))
+		if dc.Montavel == d.Montavel && d.Limitante == "" {
+			d.Limitante = comp.ComponenteCodigo
+		}
+		d.Componentes = append(d.Componentes, dc)
+	}
+
+	c.JSON(http.StatusOK, d)
+}
@@ -0,0 +1,217 @@
+// limite_taxa.go - Limite de requisições por cliente (token bucket)
+//
+// Cada cliente tem um balde de fichas por classe de requisição: leituras
+// (GET, HEAD) e escritas (demais métodos), com limites separados porque as
+// escritas são as que pesam no banco e as que um equipamento mal configurado
+// na rede do prédio costuma repetir sem parar. O cliente é o IP: o servidor
+// ainda não autentica tokens, e um Bearer qualquer escolhido pelo cliente
+// daria um balde novo a cada requisição. Sem fichas, a requisição recebe 429
+// com Retry-After.
+//
+// Os baldes ficam na memória do processo; com RATE_LIMIT_REDIS_URL eles ficam
+// no Redis e valem para todas as instâncias. Se o Redis não responder, o
+// limite volta a ser local até ele voltar. IPs em RATE_LIMIT_LIVRES (lista
+// separada por vírgulas, ex.: o servidor de deploy) não são limitados. Atrás
+// de um proxy reverso, o IP só vem do X-Forwarded-For se o proxy estiver em
+// PROXIES_CONFIAVEIS.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Configuração do limite - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	rateLimitEnabled          = getEnv("RATE_LIMIT_ENABLED", "true") == "true"
+	rateLimitLeituraPorMinuto = getEnvAsInt("RATE_LIMIT_LEITURA_POR_MINUTO", 600)
+	rateLimitLeituraRajada    = getEnvAsInt("RATE_LIMIT_LEITURA_RAJADA", 100)
+	rateLimitEscritaPorMinuto = getEnvAsInt("RATE_LIMIT_ESCRITA_POR_MINUTO", 120)
+	rateLimitEscritaRajada    = getEnvAsInt("RATE_LIMIT_ESCRITA_RAJADA", 30)
+	rateLimitRedisURL         = getEnv("RATE_LIMIT_REDIS_URL", "")
+	rateLimitLivres           = getEnv("RATE_LIMIT_LIVRES", "")
+)
+
+// Limite de uma classe de requisição: capacidade do balde e reposição por segundo
+type limiteTaxa struct {
+	classe     string
+	capacidade float64
+	porSegundo float64
+}
+
+// Baldes na memória do processo; retirar devolve se a ficha foi concedida ou
+// quanto esperar pela próxima
+
+type balde struct {
+	fichas     float64
+	atualizado time.Time
+}
+
+type baldesMemoria struct {
+	mu      sync.Mutex
+	baldes  map[string]*balde
+	limpoEm time.Time
+}
+
+func (m *baldesMemoria) retirar(chave string, l limiteTaxa) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agora := time.Now()
+	// Baldes parados há mais tempo do que levam para encher estão cheios: descartá-los não muda nada
+	if agora.Sub(m.limpoEm) > time.Minute {
+		for k, b := range m.baldes {
+			if agora.Sub(b.atualizado) > 10*time.Minute {
+				delete(m.baldes, k)
+			}
+		}
+		m.limpoEm = agora
+	}
+
+	b, ok := m.baldes[chave]
+	if !ok {
+		b = &balde{fichas: l.capacidade, atualizado: agora}
+		m.baldes[chave] = b
+	}
+	b.fichas = min(l.capacidade, b.fichas+agora.Sub(b.atualizado).Seconds()*l.porSegundo)
+	b.atualizado = agora
+	if b.fichas >= 1 {
+		b.fichas--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.fichas) / l.porSegundo * float64(time.Second)), nil
+}
+
+// Baldes no Redis, atualizados por um script atômico com o relógio do Redis
+
+var scriptBaldeRedis = redis.NewScript(`
+local capacidade = tonumber(ARGV[1])
+local por_ms = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local agora = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local b = redis.call('HMGET', KEYS[1], 'f', 'a')
+local fichas = tonumber(b[1]) or capacidade
+local antes = tonumber(b[2]) or agora
+fichas = math.min(capacidade, fichas + (agora - antes) * por_ms)
+local ok, espera = 0, 0
+if fichas >= 1 then
+	fichas = fichas - 1
+	ok = 1
+else
+	espera = math.ceil((1 - fichas) / por_ms)
+end
+redis.call('HSET', KEYS[1], 'f', tostring(fichas), 'a', agora)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacidade / por_ms) + 1000)
+return {ok, espera}`)
+
+// Baldes no Redis compartilhado
+type baldesRedis struct {
+	redis *clienteRedis
+}
+
+func (r *baldesRedis) retirar(chave string, l limiteTaxa) (bool, time.Duration, error) {
+	var resp []int64
+	err := r.redis.executar(func(ctx context.Context) error {
+		var err error
+		resp, err = scriptBaldeRedis.Run(ctx, r.redis, []string{"rls:limite:" + chave}, l.capacidade, l.porSegundo/1000).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	if len(resp) != 2 {
+		return false, 0, fmt.Errorf("resposta inesperada do script: %v", resp)
+	}
+	return resp[0] == 1, time.Duration(resp[1]) * time.Millisecond, nil
+}
+
+// Baldes compartilhados no Redis (nil sem RATE_LIMIT_REDIS_URL)
+var baldesRedisLimite = configurarRedisLimite()
+
+func configurarRedisLimite() *baldesRedis {
+	if rateLimitRedisURL == "" {
+		return nil
+	}
+	r, err := novoClienteRedis(rateLimitRedisURL)
+	if err != nil {
+		erroConfiguracao("RATE_LIMIT_REDIS_URL", rateLimitRedisURL, "uma URL redis://[:senha@]host[:porta][/banco]")
+		return nil
+	}
+	return &baldesRedis{redis: r}
+}
+
+// Identifica o cliente pelo IP; a chave por token só pode entrar quando o
+// token for autenticado antes deste middleware
+func chaveClienteLimite(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware que aplica o limite de requisições por cliente
+func LimiteTaxa() gin.HandlerFunc {
+	leitura := limiteTaxa{"leitura", float64(max(rateLimitLeituraRajada, 1)), float64(rateLimitLeituraPorMinuto) / 60}
+	escrita := limiteTaxa{"escrita", float64(max(rateLimitEscritaRajada, 1)), float64(rateLimitEscritaPorMinuto) / 60}
+
+	livres := map[string]bool{}
+	for _, ip := range strings.Split(rateLimitLivres, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			livres[ip] = true
+		}
+	}
+
+	local := &baldesMemoria{baldes: map[string]*balde{}}
+	var redisFora sync.Once
+
+	return func(c *gin.Context) {
+		l := escrita
+		switch c.Request.Method {
+		case http.MethodOptions:
+			c.Next()
+			return
+		case http.MethodGet, http.MethodHead:
+			l = leitura
+		}
+		if l.porSegundo <= 0 || livres[c.ClientIP()] {
+			c.Next()
+			return
+		}
+
+		chave := l.classe + ":" + chaveClienteLimite(c)
+		var permitida bool
+		var espera time.Duration
+		usarLocal := baldesRedisLimite == nil
+		if !usarLocal {
+			var err error
+			if permitida, espera, err = baldesRedisLimite.retirar(chave, l); err != nil {
+				// Só o primeiro erro vai ao log; os seguintes seriam um por requisição
+				redisFora.Do(func() {
+					log.Printf("[WARN] Redis do limite de requisições indisponível, usando limite local: %v", err)
+				})
+				usarLocal = true
+			}
+		}
+		if usarLocal {
+			permitida, espera, _ = local.retirar(chave, l)
+		}
+
+		if !permitida {
+			segundos := int(math.Ceil(espera.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(max(segundos, 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+				Error: fmt.Sprintf("Muitas requisições; tente novamente em %d s", max(segundos, 1)),
+			})
+			return
+		}
+		c.Next()
+	}
+}
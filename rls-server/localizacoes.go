@@ -0,0 +1,500 @@
+// localizacoes.go - Posições do almoxarifado (corredor, prateleira, nível)
+//
+// Cada posição tem código corredor-prateleira-nível (ex.: A-03-2), gerado a
+// partir das partes, e capacidade opcional em unidades. O produto aponta para
+// a posição por localizacao_id e o campo texto localizacao passa a trazer o
+// código dela, então relatórios, etiquetas e a separação por localização
+// continuam funcionando. Um texto igual ao código de uma posição ativa também
+// vincula o produto; outros textos ficam livres, sem posição, para os
+// almoxarifados que ainda não cadastraram o layout.
+//
+// Cada posição pertence a um depósito (padrão "principal"), e o produto fica
+// no depósito da posição; o WebSocket filtra eventos e contadores por ele.
+//
+// GET /api/localizacoes/:id/produtos lista os produtos da posição e
+// GET /api/localizacoes/mapa devolve as posições ativas agrupadas por
+// corredor e prateleira, com a ocupação de cada uma, para o mapa do app.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type Localizacao struct {
+	ID                 int       `json:"id,omitempty"`
+	Codigo             string    `json:"codigo"` // gerado: corredor-prateleira-nível
+	Deposito           string    `json:"deposito" binding:"max=50"`
+	Corredor           string    `json:"corredor" binding:"required,max=20,excludes=-"`
+	Prateleira         string    `json:"prateleira" binding:"required,max=20,excludes=-"`
+	Nivel              int       `json:"nivel" binding:"min=0"`
+	Capacidade         int       `json:"capacidade,omitempty" binding:"min=0"` // 0 = sem limite
+	Descricao          string    `json:"descricao,omitempty"`
+	Ativo              bool      `json:"ativo"`
+	Produtos           int       `json:"produtos"`
+	Ocupacao           int       `json:"ocupacao"`                      // unidades em estoque na posição
+	OcupacaoPercentual *float64  `json:"ocupacao_percentual,omitempty"` // só com capacidade
+	DataCriacao        time.Time `json:"data_criacao,omitempty"`
+}
+
+type PrateleiraMapa struct {
+	Prateleira string        `json:"prateleira"`
+	Niveis     []Localizacao `json:"niveis"`
+}
+
+type CorredorMapa struct {
+	Corredor    string           `json:"corredor"`
+	Prateleiras []PrateleiraMapa `json:"prateleiras"`
+}
+
+type MapaAlmoxarifado struct {
+	Corredores     []CorredorMapa `json:"corredores"`
+	SemLocalizacao int            `json:"sem_localizacao"` // produtos sem posição cadastrada
+}
+
+// Posição informada no produto não existe ou está inativa
+var errLocalizacaoNaoEncontrada = errors.New("localização não encontrada ou inativa")
+
+// Posições com a contagem de produtos e a ocupação, na ordem de scanLocalizacao
+const consultaLocalizacoes = `
+	SELECT l.id, l.codigo, l.deposito, l.corredor, l.prateleira, l.nivel, COALESCE(l.capacidade, 0),
+	       COALESCE(l.descricao, ''), l.ativo, l.data_criacao,
+	       COUNT(p.id), COALESCE(SUM(GREATEST(p.quantidade, 0)), 0)
+	FROM localizacoes l
+	LEFT JOIN produtos p ON p.localizacao_id = l.id
+`
+
+func scanLocalizacao(row pgx.Row) (Localizacao, error) {
+	var l Localizacao
+	err := row.Scan(&l.ID, &l.Codigo, &l.Deposito, &l.Corredor, &l.Prateleira, &l.Nivel, &l.Capacidade,
+		&l.Descricao, &l.Ativo, &l.DataCriacao, &l.Produtos, &l.Ocupacao)
+	if err == nil && l.Capacidade > 0 {
+		percentual := float64(l.Ocupacao) * 100 / float64(l.Capacidade)
+		l.OcupacaoPercentual = &percentual
+	}
+	return l, err
+}
+
+// Depósito das posições cadastradas sem um
+const DepositoPadrao = "principal"
+
+// Normaliza depósito, corredor e prateleira e gera o código da posição
+func normalizarLocalizacao(l *Localizacao) {
+	l.Deposito = strings.TrimSpace(l.Deposito)
+	if l.Deposito == "" {
+		l.Deposito = DepositoPadrao
+	}
+	l.Corredor = strings.ToUpper(strings.TrimSpace(l.Corredor))
+	l.Prateleira = strings.ToUpper(strings.TrimSpace(l.Prateleira))
+	l.Codigo = l.Corredor + "-" + l.Prateleira + "-" + strconv.Itoa(l.Nivel)
+}
+
+// Resolve a posição do produto: com id, a posição precisa existir e estar
+// ativa e o texto vira o código dela; sem id, um texto igual ao código de uma
+// posição ativa vincula o produto e qualquer outro texto fica livre
+func resolverLocalizacao(ctx context.Context, q querier, id *int, texto string) (*int, string, error) {
+	if id != nil {
+		var codigo string
+		err := q.QueryRow(ctx, "SELECT codigo FROM localizacoes WHERE id = $1 AND ativo", *id).Scan(&codigo)
+		if err == pgx.ErrNoRows {
+			return nil, "", errLocalizacaoNaoEncontrada
+		}
+		return id, codigo, err
+	}
+
+	texto = strings.TrimSpace(texto)
+	if texto == "" {
+		return nil, "", nil
+	}
+	var encontrada int
+	var codigo string
+	err := q.QueryRow(ctx, "SELECT id, codigo FROM localizacoes WHERE codigo = upper($1::text) AND ativo", texto).Scan(&encontrada, &codigo)
+	if err == pgx.ErrNoRows {
+		return nil, texto, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &encontrada, codigo, nil
+}
+
+// Resolve a posição de um produto completo (criação, PUT, importação)
+func vincularLocalizacao(ctx context.Context, q querier, p *Produto) error {
+	id, texto, err := resolverLocalizacao(ctx, q, p.LocalizacaoID, p.Localizacao)
+	if err != nil {
+		return err
+	}
+	p.LocalizacaoID, p.Localizacao = id, texto
+	return nil
+}
+
+// Responde ao erro de vincularLocalizacao/resolverLocalizacao
+func responderErroLocalizacao(c *gin.Context, err error) {
+	if errors.Is(err, errLocalizacaoNaoEncontrada) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Localização não encontrada ou inativa", Codigo: CodigoLocalizacaoNaoEncontrada})
+		return
+	}
+	log.Printf("[ERROR] Erro ao verificar localização: %v", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar localização"})
+}
+
+// Handlers de Localizações
+
+func getLocalizacoes(c *gin.Context) {
+	log.Println("[DB] Buscando localizações")
+
+	corredor := strings.ToUpper(strings.TrimSpace(c.Query("corredor")))
+	deposito := strings.TrimSpace(c.Query("deposito"))
+	rows, err := db.Query(c.Request.Context(), consultaLocalizacoes+`
+		WHERE ($1::text = '' OR l.corredor = $1::text) AND ($2::text = '' OR l.deposito = $2::text)
+		GROUP BY l.id
+		ORDER BY l.deposito, l.corredor, l.prateleira, l.nivel
+	`, corredor, deposito)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar localizações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar localizações"})
+		return
+	}
+	defer rows.Close()
+
+	localizacoes := []Localizacao{}
+	for rows.Next() {
+		l, err := scanLocalizacao(rows)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar localização: %v", err)
+			continue
+		}
+		localizacoes = append(localizacoes, l)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar localizações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar localizações"})
+		return
+	}
+
+	c.JSON(http.StatusOK, localizacoes)
+}
+
+func getLocalizacao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	l, err := scanLocalizacao(db.QueryRow(c.Request.Context(), consultaLocalizacoes+`
+		WHERE l.id = $1
+		GROUP BY l.id
+	`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Localização não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Localização não encontrada", Codigo: CodigoLocalizacaoNaoEncontrada})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar localização: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar localização"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, l)
+}
+
+func criarLocalizacao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	l := Localizacao{Ativo: true}
+	if err := c.ShouldBindJSON(&l); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	normalizarLocalizacao(&l)
+
+	var existente int
+	err := db.QueryRow(ctx, "SELECT id FROM localizacoes WHERE codigo = $1", l.Codigo).Scan(&existente)
+	if err == nil {
+		log.Printf("[DB] Localização já existe: %s (ID: %d)", l.Codigo, existente)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe uma localização nesta posição", Codigo: CodigoCodigoDuplicado})
+		return
+	} else if err != pgx.ErrNoRows {
+		log.Printf("[ERROR] Erro ao verificar localização existente: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar localização existente"})
+		return
+	}
+
+	err = db.QueryRow(ctx, `
+		INSERT INTO localizacoes(codigo, corredor, prateleira, nivel, capacidade, descricao, ativo, deposito)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, ''), $7, $8)
+		RETURNING id, data_criacao
+	`, l.Codigo, l.Corredor, l.Prateleira, l.Nivel, l.Capacidade, l.Descricao, l.Ativo, l.Deposito).Scan(&l.ID, &l.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar localização"})
+		return
+	}
+
+	// Produtos com o texto igual ao código da nova posição passam a apontar para ela
+	tag, err := db.Exec(ctx, `
+		UPDATE produtos SET localizacao_id = $1, localizacao = $2
+		WHERE localizacao_id IS NULL AND upper(trim(localizacao)) = $2
+	`, l.ID, l.Codigo)
+	if err != nil {
+		log.Printf("[WARN] Erro ao vincular produtos à localização %s: %v", l.Codigo, err)
+	}
+	l.Produtos = int(tag.RowsAffected())
+
+	log.Printf("[DB] Localização criada: %s (ID: %d, %d produtos vinculados)", l.Codigo, l.ID, l.Produtos)
+	c.JSON(http.StatusCreated, l)
+}
+
+func atualizarLocalizacao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	l := Localizacao{Ativo: true}
+	if err := c.ShouldBindJSON(&l); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	normalizarLocalizacao(&l)
+
+	var existente int
+	err = db.QueryRow(ctx, "SELECT id FROM localizacoes WHERE codigo = $1 AND id != $2", l.Codigo, id).Scan(&existente)
+	if err == nil {
+		log.Printf("[DB] Posição %s já usada pela localização ID: %d", l.Codigo, existente)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe outra localização nesta posição", Codigo: CodigoCodigoDuplicado})
+		return
+	} else if err != pgx.ErrNoRows {
+		log.Printf("[ERROR] Erro ao verificar localização existente: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar localização existente"})
+		return
+	}
+
+	// Posição e texto dos produtos vinculados na mesma transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	err = tx.QueryRow(ctx, `
+		UPDATE localizacoes SET codigo = $1, corredor = $2, prateleira = $3, nivel = $4,
+			capacidade = NULLIF($5, 0), descricao = NULLIF($6, ''), ativo = $7, deposito = $8
+		WHERE id = $9
+		RETURNING id, data_criacao
+	`, l.Codigo, l.Corredor, l.Prateleira, l.Nivel, l.Capacidade, l.Descricao, l.Ativo, l.Deposito, id).Scan(&l.ID, &l.DataCriacao)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Localização não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Localização não encontrada", Codigo: CodigoLocalizacaoNaoEncontrada})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar localização: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar localização"})
+		}
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE produtos SET localizacao = $1, data_atualizacao = CURRENT_TIMESTAMP
+		WHERE localizacao_id = $2 AND localizacao IS DISTINCT FROM $1
+	`, l.Codigo, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar localização dos produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar localização"})
+		return
+	}
+
+	l, err = scanLocalizacao(tx.QueryRow(ctx, consultaLocalizacoes+`
+		WHERE l.id = $1
+		GROUP BY l.id
+	`, id))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar localização atualizada: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar localização"})
+		return
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Localização atualizada: %s (ID: %d)", l.Codigo, id)
+	c.JSON(http.StatusOK, l)
+}
+
+func deletarLocalizacao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Posição com produtos não é excluída: os produtos precisam ser
+	// transferidos antes (ou a posição desativada)
+	var produtos int
+	if err = db.QueryRow(ctx, "SELECT COUNT(*) FROM produtos WHERE localizacao_id = $1", id).Scan(&produtos); err != nil {
+		log.Printf("[ERROR] Erro ao verificar produtos da localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir localização"})
+		return
+	}
+	if produtos > 0 {
+		log.Printf("[DB] Localização %d tem %d produtos, exclusão recusada", id, produtos)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Localização com " + strconv.Itoa(produtos) + " produtos; transfira-os ou desative a localização"})
+		return
+	}
+
+	tag, err := db.Exec(ctx, "DELETE FROM localizacoes WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir localização"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Localização não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Localização não encontrada", Codigo: CodigoLocalizacaoNaoEncontrada})
+		return
+	}
+
+	log.Printf("[DB] Localização excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Localização excluída com sucesso"})
+}
+
+// Produtos guardados na posição
+func getProdutosLocalizacao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var existe bool
+	if err = db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM localizacoes WHERE id = $1)", id).Scan(&existe); err != nil {
+		log.Printf("[ERROR] Erro ao verificar localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar localização"})
+		return
+	}
+	if !existe {
+		log.Printf("[DB] Localização não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Localização não encontrada", Codigo: CodigoLocalizacaoNaoEncontrada})
+		return
+	}
+
+	rows, err := db.Query(ctx, "SELECT "+produtoColunas+" FROM produtos WHERE localizacao_id = $1 ORDER BY codigo", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar produtos da localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos da localização"})
+		return
+	}
+	defer rows.Close()
+
+	produtos := []Produto{}
+	for rows.Next() {
+		p, err := scanProduto(rows)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar produto: %v", err)
+			continue
+		}
+		produtos = append(produtos, p)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar produtos da localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar produtos da localização"})
+		return
+	}
+
+	c.JSON(http.StatusOK, produtos)
+}
+
+// Posições ativas agrupadas por corredor e prateleira, para o mapa do app
+func getMapaAlmoxarifado(c *gin.Context) {
+	ctx := c.Request.Context()
+	log.Println("[DB] Montando mapa do almoxarifado")
+
+	rows, err := db.Query(ctx, consultaLocalizacoes+`
+		WHERE l.ativo
+		GROUP BY l.id
+		ORDER BY l.corredor, l.prateleira, l.nivel
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar localizações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao montar mapa do almoxarifado"})
+		return
+	}
+	defer rows.Close()
+
+	mapa := MapaAlmoxarifado{Corredores: []CorredorMapa{}}
+	for rows.Next() {
+		l, err := scanLocalizacao(rows)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar localização: %v", err)
+			continue
+		}
+
+		// As posições chegam ordenadas: corredor e prateleira novos abrem um grupo
+		if n := len(mapa.Corredores); n == 0 || mapa.Corredores[n-1].Corredor != l.Corredor {
+			mapa.Corredores = append(mapa.Corredores, CorredorMapa{Corredor: l.Corredor})
+		}
+		corredor := &mapa.Corredores[len(mapa.Corredores)-1]
+		if n := len(corredor.Prateleiras); n == 0 || corredor.Prateleiras[n-1].Prateleira != l.Prateleira {
+			corredor.Prateleiras = append(corredor.Prateleiras, PrateleiraMapa{Prateleira: l.Prateleira})
+		}
+		prateleira := &corredor.Prateleiras[len(corredor.Prateleiras)-1]
+		prateleira.Niveis = append(prateleira.Niveis, l)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar localizações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao montar mapa do almoxarifado"})
+		return
+	}
+
+	if err = db.QueryRow(ctx, "SELECT COUNT(*) FROM produtos WHERE localizacao_id IS NULL").Scan(&mapa.SemLocalizacao); err != nil {
+		log.Printf("[ERROR] Erro ao contar produtos sem localização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao montar mapa do almoxarifado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapa)
+}
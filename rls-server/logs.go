@@ -0,0 +1,207 @@
+// logs.go - Logs estruturados e ID das requisições
+//
+// Os logs saem em JSON (LOG_FORMATO=json, padrão) ou texto chave=valor
+// (LOG_FORMATO=texto) pelo log/slog, a partir de LOG_NIVEL. As chamadas
+// log.Printf existentes passam pelo mesmo handler: o prefixo "[ERROR]",
+// "[WARN]", "[DEBUG]" vira o nível e os demais ("[API]", "[DB]"...) o campo
+// origem.
+//
+// O middleware RequestID aceita o X-Request-ID do cliente (ou gera um), devolve
+// no cabeçalho da resposta e o guarda no contexto da requisição. Com ele:
+// - a linha de acesso de cada requisição e cada consulta ao banco feita com
+//   o contexto da requisição saem com request_id;
+// - as respostas de erro em JSON ganham o campo request_id;
+// - as consultas às filiais repassam o cabeçalho.
+// Assim uma falha relatada pelo app (que mostra o request_id) é localizada nos
+// logs do servidor.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Configuração dos logs - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	logFormato = getEnv("LOG_FORMATO", "json")
+	logNivel   = getEnv("LOG_NIVEL", "info")
+)
+
+const headerRequestID = "X-Request-ID"
+
+type chaveRequestID struct{}
+
+// IDs aceitos do cliente; outros são substituídos por um gerado
+var padraoRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Prefixos de nível usados nas mensagens do log padrão
+var niveisPrefixo = map[string]slog.Level{
+	"DEBUG": slog.LevelDebug,
+	"INFO":  slog.LevelInfo,
+	"WARN":  slog.LevelWarn,
+	"ERROR": slog.LevelError,
+}
+
+// Configura o slog como destino de todos os logs do processo
+func configurarLogs() {
+	nivel := slog.LevelInfo
+	if err := nivel.UnmarshalText([]byte(logNivel)); err != nil {
+		erroConfiguracao("LOG_NIVEL", logNivel, "debug, info, warn ou error")
+	}
+
+	opcoes := &slog.HandlerOptions{Level: nivel}
+	var handler slog.Handler
+	switch logFormato {
+	case "texto":
+		handler = slog.NewTextHandler(os.Stderr, opcoes)
+	default:
+		if logFormato != "json" {
+			erroConfiguracao("LOG_FORMATO", logFormato, "json ou texto")
+		}
+		handler = slog.NewJSONHandler(os.Stderr, opcoes)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	// O log padrão escreve no adaptador, que repassa ao handler (depois do
+	// SetDefault, que também redirecionaria o log padrão)
+	log.SetFlags(0)
+	log.SetOutput(escritorLog{handler})
+}
+
+// Adaptador do log padrão: extrai nível e origem do prefixo "[X]" da mensagem
+type escritorLog struct {
+	handler slog.Handler
+}
+
+func (e escritorLog) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	nivel := slog.LevelInfo
+	origem := ""
+	if strings.HasPrefix(msg, "[") {
+		if fim := strings.Index(msg, "] "); fim > 0 && fim <= 10 {
+			tag := msg[1:fim]
+			if n, ok := niveisPrefixo[tag]; ok {
+				nivel = n
+			} else {
+				origem = strings.ToLower(tag)
+			}
+			msg = msg[fim+2:]
+		}
+	}
+
+	ctx := context.Background()
+	if !e.handler.Enabled(ctx, nivel) {
+		return len(p), nil
+	}
+	r := slog.NewRecord(time.Now(), nivel, msg, 0)
+	if origem != "" {
+		r.AddAttrs(slog.String("origem", origem))
+	}
+	return len(p), e.handler.Handle(ctx, r)
+}
+
+// ID da requisição guardado no contexto ("" fora de uma requisição)
+func idRequisicao(ctx context.Context) string {
+	id, _ := ctx.Value(chaveRequestID{}).(string)
+	return id
+}
+
+func novoRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strings.ReplaceAll(time.Now().Format("20060102150405.000000000"), ".", "")
+	}
+	return hex.EncodeToString(b)
+}
+
+// Middleware que identifica a requisição
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(headerRequestID)
+		if !padraoRequestID.MatchString(id) {
+			id = novoRequestID()
+		}
+
+		c.Header(headerRequestID, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), chaveRequestID{}, id))
+		c.Next()
+	}
+}
+
+// Logger middleware: uma linha de acesso por requisição
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inicio := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		nivel := slog.LevelInfo
+		switch {
+		case status >= 500:
+			nivel = slog.LevelError
+		case status >= 400:
+			nivel = slog.LevelWarn
+		}
+		slog.Log(c.Request.Context(), nivel, "requisição",
+			"origem", "api",
+			"request_id", idRequisicao(c.Request.Context()),
+			"trace_id", idTrace(c.Request.Context()),
+			"metodo", c.Request.Method,
+			"caminho", c.Request.URL.Path,
+			"rota", c.FullPath(),
+			"status", status,
+			"duracao_ms", time.Since(inicio).Milliseconds(),
+			"ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// Tracer do pgx: registra as consultas feitas com o contexto de uma requisição
+// (nível debug; erros em error), com o request_id; o span da consulta fica
+// com o tracer do otelpgx (ver tracing.go)
+type tracerConsultas struct{}
+
+type chaveInicioConsulta struct{}
+
+type inicioConsulta struct {
+	sql    string
+	inicio time.Time
+}
+
+func (tracerConsultas) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, chaveInicioConsulta{}, inicioConsulta{sql: data.SQL, inicio: time.Now()})
+}
+
+func (tracerConsultas) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	inicio, _ := ctx.Value(chaveInicioConsulta{}).(inicioConsulta)
+	id := idRequisicao(ctx)
+	if id == "" {
+		return
+	}
+
+	nivel := slog.LevelDebug
+	atributos := []any{
+		"origem", "db",
+		"request_id", id,
+		"sql", strings.Join(strings.Fields(inicio.sql), " "),
+		"duracao_ms", time.Since(inicio.inicio).Milliseconds(),
+		"linhas", data.CommandTag.RowsAffected(),
+	}
+	if data.Err != nil {
+		nivel = slog.LevelError
+		atributos = append(atributos, "erro", data.Err.Error())
+	}
+	slog.Log(ctx, nivel, "consulta", atributos...)
+}
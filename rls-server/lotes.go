@@ -0,0 +1,252 @@
+// lotes.go - Controle de lotes com validade e consumo FEFO
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type Lote struct {
+	ID            int        `json:"id,omitempty"`
+	ProdutoID     int        `json:"produto_id"`
+	ProdutoCodigo string     `json:"produto_codigo,omitempty"`
+	ProdutoNome   string     `json:"produto_nome,omitempty"`
+	NumeroLote    string     `json:"numero_lote"`
+	Validade      *time.Time `json:"validade,omitempty"`
+	Quantidade    int        `json:"quantidade"`
+	DataCriacao   time.Time  `json:"data_criacao,omitempty"`
+}
+
+// Quantidade de um lote afetada por uma movimentação
+type MovimentacaoLote struct {
+	LoteID     int        `json:"lote_id"`
+	NumeroLote string     `json:"numero_lote"`
+	Validade   *time.Time `json:"validade,omitempty"`
+	Quantidade int        `json:"quantidade"`
+}
+
+var (
+	errLoteNaoEncontrado = errors.New("lote não encontrado para o produto")
+	errLoteInsuficiente  = errors.New("quantidade insuficiente no lote")
+	errValidadeInvalida  = errors.New("validade inválida, use o formato AAAA-MM-DD")
+)
+
+// Registra o efeito de uma movimentação nos lotes do produto, dentro da transação.
+// Entradas com lote criam ou incrementam o lote informado. Saídas com lote
+// consomem o lote informado; sem lote, consomem os lotes pela validade mais
+// próxima (FEFO) até onde houver saldo em lotes.
+func registrarLotesMovimentacao(ctx context.Context, tx pgx.Tx, m *Movimentacao) error {
+	if m.Tipo == "entrada" {
+		if m.Lote == "" {
+			return nil
+		}
+
+		var validade *time.Time
+		if m.Validade != "" {
+			v, err := time.Parse(formatoData, m.Validade)
+			if err != nil {
+				return errValidadeInvalida
+			}
+			validade = &v
+		}
+
+		ml := MovimentacaoLote{NumeroLote: m.Lote, Quantidade: m.Quantidade}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO lotes(produto_id, numero_lote, validade, quantidade)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (produto_id, numero_lote) DO UPDATE SET
+				quantidade = lotes.quantidade + EXCLUDED.quantidade,
+				validade = COALESCE(EXCLUDED.validade, lotes.validade)
+			RETURNING id, validade
+		`, m.ProdutoID, m.Lote, validade, m.Quantidade).Scan(&ml.LoteID, &ml.Validade)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DB] Entrada de %d itens no lote %s (ID: %d)", m.Quantidade, m.Lote, ml.LoteID)
+		m.Lotes = []MovimentacaoLote{ml}
+		return inserirMovimentacaoLotes(ctx, tx, m.ID, m.Lotes)
+	}
+
+	// Saída de um lote específico
+	if m.Lote != "" {
+		var ml MovimentacaoLote
+		var disponivel int
+		err := tx.QueryRow(ctx, `
+			SELECT id, numero_lote, validade, quantidade
+			FROM lotes
+			WHERE produto_id = $1 AND numero_lote = $2
+			FOR UPDATE
+		`, m.ProdutoID, m.Lote).Scan(&ml.LoteID, &ml.NumeroLote, &ml.Validade, &disponivel)
+		if err == pgx.ErrNoRows {
+			return errLoteNaoEncontrado
+		} else if err != nil {
+			return err
+		}
+		if disponivel < m.Quantidade {
+			return errLoteInsuficiente
+		}
+
+		ml.Quantidade = m.Quantidade
+		m.Lotes = []MovimentacaoLote{ml}
+	} else {
+		// FEFO: primeiro a vencer, primeiro a sair
+		rows, err := tx.Query(ctx, `
+			SELECT id, numero_lote, validade, quantidade
+			FROM lotes
+			WHERE produto_id = $1 AND quantidade > 0
+			ORDER BY validade ASC NULLS LAST, id
+			FOR UPDATE
+		`, m.ProdutoID)
+		if err != nil {
+			return err
+		}
+
+		restante := m.Quantidade
+		consumo := []MovimentacaoLote{}
+		for rows.Next() && restante > 0 {
+			var ml MovimentacaoLote
+			var disponivel int
+			if err := rows.Scan(&ml.LoteID, &ml.NumeroLote, &ml.Validade, &disponivel); err != nil {
+				rows.Close()
+				return err
+			}
+			ml.Quantidade = min(disponivel, restante)
+			restante -= ml.Quantidade
+			consumo = append(consumo, ml)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(consumo) == 0 {
+			return nil
+		}
+		m.Lotes = consumo
+	}
+
+	for _, ml := range m.Lotes {
+		log.Printf("[DB] Saída de %d itens do lote %s (ID: %d)", ml.Quantidade, ml.NumeroLote, ml.LoteID)
+		_, err := tx.Exec(ctx, "UPDATE lotes SET quantidade = quantidade - $1 WHERE id = $2", ml.Quantidade, ml.LoteID)
+		if err != nil {
+			return err
+		}
+	}
+	return inserirMovimentacaoLotes(ctx, tx, m.ID, m.Lotes)
+}
+
+// Função auxiliar para registrar a relação movimentação × lote
+func inserirMovimentacaoLotes(ctx context.Context, tx pgx.Tx, movimentacaoID int, lotes []MovimentacaoLote) error {
+	for _, ml := range lotes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO movimentacoes_lotes(movimentacao_id, lote_id, quantidade)
+			VALUES ($1, $2, $3)
+		`, movimentacaoID, ml.LoteID, ml.Quantidade)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Função auxiliar para processar linhas de lotes com dados do produto
+func scanLotes(rows pgx.Rows) ([]Lote, error) {
+	lotes := []Lote{}
+	for rows.Next() {
+		var l Lote
+		err := rows.Scan(&l.ID, &l.ProdutoID, &l.ProdutoCodigo, &l.ProdutoNome,
+			&l.NumeroLote, &l.Validade, &l.Quantidade, &l.DataCriacao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar lote: %v", err)
+			continue
+		}
+		lotes = append(lotes, l)
+	}
+	return lotes, rows.Err()
+}
+
+// Handlers de Lotes
+
+func getLotesVencendo(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 0 {
+		dias = 30
+	}
+
+	log.Printf("[DB] Buscando lotes que vencem nos próximos %d dias", dias)
+
+	// Inclui lotes já vencidos que ainda têm saldo
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT l.id, l.produto_id, p.codigo, p.nome, l.numero_lote, l.validade, l.quantidade, l.data_criacao
+		FROM lotes l
+		JOIN produtos p ON l.produto_id = p.id
+		WHERE l.quantidade > 0
+		  AND l.validade IS NOT NULL
+		  AND l.validade <= CURRENT_DATE + $1::int
+		ORDER BY l.validade ASC, p.nome
+	`, dias)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar lotes vencendo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar lotes vencendo"})
+		return
+	}
+	defer rows.Close()
+
+	lotes, err := scanLotes(rows)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao processar lotes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar lotes"})
+		return
+	}
+
+	log.Printf("[DB] Encontrados %d lotes vencendo", len(lotes))
+	c.JSON(http.StatusOK, lotes)
+}
+
+func getLotesPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando lotes do produto ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT l.id, l.produto_id, p.codigo, p.nome, l.numero_lote, l.validade, l.quantidade, l.data_criacao
+		FROM lotes l
+		JOIN produtos p ON l.produto_id = p.id
+		WHERE l.produto_id = $1
+		ORDER BY l.validade ASC NULLS LAST, l.id
+	`, id)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar lotes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar lotes"})
+		return
+	}
+	defer rows.Close()
+
+	lotes, err := scanLotes(rows)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao processar lotes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar lotes"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d lotes para o produto ID: %d", len(lotes), id)
+	c.JSON(http.StatusOK, lotes)
+}
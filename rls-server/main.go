@@ -4,45 +4,99 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rlsautomacao/estoque/internal/estoque"
+	"github.com/rlsautomacao/estoque/internal/repositorio"
+	"github.com/rlsautomacao/estoque/internal/servico"
 )
 
-// Configuração do banco de dados - valores padrão, podem ser sobrescritos por variáveis de ambiente
+// Configuração do banco de dados - valores padrão, podem ser sobrescritos por
+// variáveis de ambiente, arquivo de configuração ou flags (ver config.go)
 var (
 	dbHost     = getEnv("DB_HOST", "localhost")
 	dbPort     = getEnvAsInt("DB_PORT", 5432)
-	dbUser     = getEnv("DB_USER", "danilo")
-	dbPassword = getEnv("DB_PASSWORD", "Danilo@34333528")
+	dbUser     = getEnv("DB_USER", "postgres")
+	dbPassword = getEnv("DB_PASSWORD", "")
 	dbName     = getEnv("DB_NAME", "rls_estoque")
+
+	// Pool de conexões
+	dbPoolMaxConns        = getEnvAsInt("DB_POOL_MAX_CONNS", 10)
+	dbPoolMinConns        = getEnvAsInt("DB_POOL_MIN_CONNS", 2)
+	dbPoolMaxIdleMinutos  = getEnvAsInt("DB_POOL_MAX_IDLE_MINUTOS", 5)
+	dbPoolHealthCheckSegs = getEnvAsInt("DB_POOL_HEALTHCHECK_SEGUNDOS", 60)
+
+	// Porta do servidor web
+	serverPort = getEnv("PORT", "8080")
+
+	// Proxies reversos (IPs ou CIDRs separados por vírgula) cujo
+	// X-Forwarded-For é aceito; vazio: o IP do cliente é o da conexão
+	proxiesConfiaveis = getEnv("PROXIES_CONFIAVEIS", "")
+
+	// Perfil de execução: producao, desenvolvimento ou teste. Recursos só de
+	// desenvolvimento (como o chaos) exigem que o perfil seja informado
+	perfilExecucao = getEnv("PERFIL", PerfilProducao)
+)
+
+// Perfis de execução
+const (
+	PerfilProducao        = "producao"
+	PerfilDesenvolvimento = "desenvolvimento"
+	PerfilTeste           = "teste"
 )
 
-// Função auxiliar para obter variável de ambiente com valor padrão
+// Função auxiliar para obter uma configuração com valor padrão
 func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
+	return valorConfiguracao(key, defaultValue)
+}
+
+// Função auxiliar para obter uma configuração como inteiro; valor inválido é
+// apontado por validarConfiguracao no startup
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := valorConfiguracao(key, strconv.Itoa(defaultValue))
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		erroConfiguracao(key, valueStr, "um número inteiro")
 		return defaultValue
 	}
 	return value
 }
 
-// Função auxiliar para obter variável de ambiente como inteiro
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
+// Função auxiliar para obter uma configuração como float; valor inválido é
+// apontado por validarConfiguracao no startup
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := valorConfiguracao(key, strconv.FormatFloat(defaultValue, 'g', -1, 64))
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		erroConfiguracao(key, valueStr, "um número")
+		return defaultValue
 	}
-	return defaultValue
+	return value
+}
+
+// Função auxiliar para separar a lista de PROXIES_CONFIAVEIS
+func listaProxiesConfiaveis() []string {
+	var proxies []string
+	for _, p := range strings.Split(proxiesConfiaveis, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
 }
 
 // Função para obter endereços IP locais
@@ -81,34 +135,72 @@ func getLocalIPs() []string {
 }
 
 // Estruturas de dados
-type Produto struct {
-	ID               int       `json:"id,omitempty"`
-	Codigo           string    `json:"codigo"`
-	Nome             string    `json:"nome"`
-	Descricao        string    `json:"descricao,omitempty"`
-	Quantidade       int       `json:"quantidade"`
-	QuantidadeMinima int       `json:"quantidade_minima,omitempty"`
-	Localizacao      string    `json:"localizacao,omitempty"`
-	Fornecedor       string    `json:"fornecedor,omitempty"`
-	Notas            string    `json:"notas,omitempty"`
-	DataCriacao      time.Time `json:"data_criacao,omitempty"`
-	DataAtualizacao  time.Time `json:"data_atualizacao,omitempty"`
-}
+type Produto = estoque.Produto
 
 type Movimentacao struct {
 	ID               int       `json:"id,omitempty"`
-	ProdutoID        int       `json:"produto_id"`
-	Tipo             string    `json:"tipo"` // 'entrada' ou 'saida'
-	Quantidade       int       `json:"quantidade"`
-	Notas            string    `json:"notas,omitempty"`
+	ProdutoID        int       `json:"produto_id" binding:"required,min=1"`
+	Tipo             string    `json:"tipo" binding:"required,oneof=entrada saida"` // 'entrada' ou 'saida'
+	Quantidade       int       `json:"quantidade" binding:"required,min=1"`
+	Notas            string    `json:"notas,omitempty" binding:"max=1000"`
 	DataMovimentacao time.Time `json:"data_movimentacao,omitempty"`
+
+	// Controle de lotes: na entrada, lote e validade (AAAA-MM-DD) opcionais;
+	// na saída, lote opcional (sem ele o consumo segue FEFO)
+	Lote     string             `json:"lote,omitempty" binding:"max=50"`
+	Validade string             `json:"validade,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	Lotes    []MovimentacaoLote `json:"lotes,omitempty"`
+
+	// Números de série das unidades, obrigatórios para produtos com controle de série
+	Series []string `json:"series,omitempty" binding:"dive,max=100"`
+
+	// Unidade em que a quantidade foi informada; convertida para a unidade base do produto
+	Unidade string `json:"unidade,omitempty" binding:"max=10"`
+
+	// Custo unitário na unidade base; em entradas atualiza o custo médio do produto
+	CustoUnitario *float64 `json:"custo_unitario,omitempty" binding:"omitempty,min=0"`
+
+	// Obrigatórios nas saídas de produtos perigosos
+	Motivo          string `json:"motivo,omitempty" binding:"max=500"`
+	LocalDescarteID *int   `json:"local_descarte_id,omitempty"`
+
+	// Checklist de baixa concluído, obrigatório a partir de checklist_baixa_quantidade
+	ChecklistID *int `json:"checklist_id,omitempty"`
+
+	// Saída de kit: as saídas dos componentes registradas no lugar dela
+	Componentes []Movimentacao `json:"componentes,omitempty"`
+}
+
+// Dados que a baixa de um produto pode exigir conforme o cadastro dele:
+// séries (controla_serie), motivo e local de descarte (perigoso) e checklist
+// (a partir de checklist_baixa_quantidade). Separação, montagem e ajuste
+// recebem esses dados por linha e passam para a movimentação
+type DadosBaixa struct {
+	Series          []string `json:"series,omitempty" binding:"dive,max=100"`
+	Motivo          string   `json:"motivo,omitempty" binding:"max=500"`
+	LocalDescarteID *int     `json:"local_descarte_id,omitempty"`
+	ChecklistID     *int     `json:"checklist_id,omitempty"`
+}
+
+func (d DadosBaixa) aplicar(m *Movimentacao) {
+	m.Series = d.Series
+	m.Motivo = d.Motivo
+	m.LocalDescarteID = d.LocalDescarteID
+	m.ChecklistID = d.ChecklistID
+}
+
+// Corpo do PUT de produto: o cadastro e, quando a quantidade muda, os dados
+// que a movimentação de ajuste pode exigir
+type AtualizacaoProduto struct {
+	Produto
+	Ajuste DadosBaixa `json:"ajuste"`
 }
 
 type Configuracao struct {
 	ID              int       `json:"id,omitempty"`
-	Chave           string    `json:"chave"`
-	Valor           string    `json:"valor"`
-	Descricao       string    `json:"descricao,omitempty"`
+	Chave           string    `json:"chave" binding:"max=50"`
+	Valor           string    `json:"valor" binding:"required"`
+	Descricao       string    `json:"descricao,omitempty" binding:"max=500"`
 	DataAtualizacao time.Time `json:"data_atualizacao,omitempty"`
 }
 
@@ -118,6 +210,7 @@ type DashboardData struct {
 	EstoqueBaixo         int                `json:"estoque_baixo"`
 	UltimasMovimentacoes []MovimentacaoView `json:"ultimas_movimentacoes"`
 	TopProdutos          []ProdutoView      `json:"top_produtos"`
+	TarefasPendentes     PendenciasTarefas  `json:"tarefas_pendentes"`
 }
 
 type MovimentacaoView struct {
@@ -136,350 +229,634 @@ type ProdutoView struct {
 	Quantidade int    `json:"quantidade"`
 }
 
+// Erro devolvido pelos handlers; o middleware Problemas o converte em
+// problem+json (ver problemas.go)
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string      `json:"error"`
+	Codigo    string      `json:"code,omitempty"`       // sem código, vale o genérico do status
+	Campos    []ErroCampo `json:"campos,omitempty"`     // erros por campo da requisição
+	RequestID string      `json:"request_id,omitempty"` // acrescentado pelo middleware RequestID
 }
 
-var db *pgxpool.Pool
-
-// Logger middleware
-func Logger() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Tempo inicial
-		startTime := time.Now()
-
-		// Processar request
-		c.Next()
+// Colunas de produtos na ordem esperada por scanProduto
+const produtoColunas = estoque.ColunasProduto
 
-		// Tempo após processar
-		endTime := time.Now()
-		latency := endTime.Sub(startTime)
+// Função auxiliar para ler um produto (linha com produtoColunas) tratando campos nulos
+var scanProduto = estoque.ScanProduto
 
-		// Acessar os detalhes da requisição
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-		statusCode := c.Writer.Status()
-		path := c.Request.URL.Path
+var db *pgxpool.Pool
 
-		log.Printf("[API] %s | %3d | %v | %s | %s",
-			method, statusCode, latency, clientIP, path)
-	}
-}
+// querier é atendido tanto pelo pool quanto por uma transação, permitindo
+// reutilizar consultas auxiliares dentro e fora de transações
+type querier = repositorio.Querier
 
-func main() {
-	// Configurar logging
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.Printf("Iniciando servidor RLS Estoque API...")
+// Função para criar o pool de conexões com o banco de dados informado
+func conectarBanco(nome string) (*pgxpool.Pool, error) {
+	// A senha não entra na URL: é aplicada a cada conexão (ver segredos.go)
+	connStr := fmt.Sprintf("postgres://%s@%s:%d/%s", dbUser, dbHost, dbPort, nome)
+	log.Printf("Conectando ao PostgreSQL: %s:%d/%s", dbHost, dbPort, nome)
 
-	// Inicializar conexão com o banco de dados
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
-	log.Printf("Conectando ao PostgreSQL: %s:%d/%s", dbHost, dbPort, dbName)
-
-	var err error
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
-		log.Fatalf("Erro ao criar configuração de pool: %v", err)
+		return nil, fmt.Errorf("erro ao criar configuração de pool: %w", err)
 	}
+	config.BeforeConnect = aplicarCredenciais
+
+	return abrirPool(config)
+}
 
+// Aplica as opções de pool e tracer comuns ao primário e à réplica, cria o
+// pool e testa a conexão
+func abrirPool(config *pgxpool.Config) (*pgxpool.Pool, error) {
 	// Configurar o pool de conexões
-	config.MaxConns = 10
-	config.MinConns = 2
-	config.MaxConnIdleTime = 5 * time.Minute
-	config.HealthCheckPeriod = 1 * time.Minute
+	config.MaxConns = int32(dbPoolMaxConns)
+	config.MinConns = int32(dbPoolMinConns)
+	config.MaxConnIdleTime = time.Duration(dbPoolMaxIdleMinutos) * time.Minute
+	config.HealthCheckPeriod = time.Duration(dbPoolHealthCheckSegs) * time.Second
+
+	// Consultas das requisições no log com o request_id e no trace da requisição
+	config.ConnConfig.Tracer = multitracer.New(tracerConsultas{}, tracerConsultasOTel())
 
 	// Criar o pool
-	db, err = pgxpool.NewWithConfig(context.Background(), config)
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
-		log.Fatalf("Não foi possível conectar ao banco de dados: %v", err)
+		return nil, fmt.Errorf("não foi possível conectar ao banco de dados: %w", err)
 	}
-	defer db.Close()
 
 	// Testar conexão
-	err = db.Ping(context.Background())
-	if err != nil {
-		log.Fatalf("Não foi possível pingar o banco de dados: %v", err)
+	if err = pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("não foi possível pingar o banco de dados: %w", err)
 	}
-	log.Println("✓ Conectado ao banco de dados PostgreSQL!")
 
-	// Configurar o Gin
+	return pool, nil
+}
+
+// Função para montar o router com middlewares e rotas da API
+func configurarRouter() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
+	configurarValidacao()
 	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(Logger())
 
-	// Configurar CORS - aceitar requisições de qualquer origem
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Agrupar rotas API
-	api := r.Group("/api")
-	{
-		// Rotas de produtos
-		api.GET("/produtos", getProdutos)
-		api.GET("/produtos/:id", getProduto)
-		api.POST("/produtos", criarProduto)
-		api.PUT("/produtos/:id", atualizarProduto)
-		api.DELETE("/produtos/:id", deletarProduto)
-		api.GET("/produtos/codigo/:codigo", getProdutoPorCodigo)
-		api.GET("/produtos/estoque-baixo", getProdutosEstoqueBaixo)
-
-		// Rotas de movimentações
-		api.GET("/movimentacoes", getMovimentacoes)
-		api.GET("/movimentacoes/:id", getMovimentacao)
-		api.POST("/movimentacoes", criarMovimentacao)
-		api.GET("/movimentacoes/produto/:produto_id", getMovimentacoesPorProduto)
-
-		// Rotas de configurações
-		api.GET("/configuracoes", getConfiguracoes)
-		api.GET("/configuracoes/:chave", getConfiguracao)
-		api.PUT("/configuracoes/:chave", atualizarConfiguracao)
-
-		// Rotas de dashboard
-		api.GET("/dashboard", getDashboardData)
+	// O gin confia em qualquer X-Forwarded-For por padrão; sem proxies
+	// configurados, o cliente não escolhe o IP usado no limite de taxa, nas
+	// exportações e na consulta pública
+	if err := r.SetTrustedProxies(listaProxiesConfiaveis()); err != nil {
+		log.Printf("[ERROR] PROXIES_CONFIAVEIS inválido, ignorando X-Forwarded-For: %v", err)
+		r.SetTrustedProxies(nil)
 	}
 
-	// Iniciar servidor
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	r.Use(RequestID())
+	r.Use(Rastreamento()...)
+	r.Use(gin.Recovery())
 
-	// Obter IPs locais para mostrar nos logs
-	ips := getLocalIPs()
+	// Probes de saúde e prontidão, antes do log de acesso
+	r.GET("/healthz", getHealthz)
+	r.GET("/readyz", getReadyz)
 
-	// Logar endereços de acesso
-	log.Printf("Servidor rodando nas seguintes URLs:")
-	log.Printf("- Local: http://localhost:%s", port)
+	r.Use(Logger())
 
-	// Mostrar todos os IPs disponíveis na rede
-	for _, ip := range ips {
-		log.Printf("- Rede: http://%s:%s", ip, port)
+	// Compressão e ETag; antes do replay, que grava o corpo sem compressão
+	if compressaoEnabled {
+		r.Use(Compressao())
+	}
+	if etagRespostasHabilitado {
+		r.Use(ETagRespostas())
 	}
 
-	log.Printf("- Aceita conexões de qualquer dispositivo na mesma rede")
-
-	// Iniciar servidor para escutar em todas as interfaces
-	log.Fatal(r.Run(":" + port))
-}
-
-// Handlers de Produtos
+	// Erros em problem+json; depois da compressão, para converter o JSON do
+	// handler antes de ele ser comprimido
+	r.Use(Problemas())
 
-func getProdutos(c *gin.Context) {
-	log.Println("[DB] Buscando lista de produtos")
+	// Prazo de cada requisição no contexto usado nas consultas ao banco;
+	// depois de Problemas, para que o 504 também saia como problema
+	r.Use(TimeoutRequisicao())
 
-	// Parâmetros opcionais de consulta para paginação
-	limitStr := c.DefaultQuery("limit", "100")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 100
+	// Simulação de falhas para testes do app - validarConfiguracao só aceita
+	// o chaos nos perfis de desenvolvimento e teste
+	if chaosEnabled {
+		log.Printf("[WARN] ****************************************************************")
+		log.Printf("[WARN] MODO CHAOS ATIVO (perfil %s): falhas simuladas em TODAS as requisições", perfilExecucao)
+		log.Printf("[WARN] latência=%dms, erro=%.2f, queda=%.2f - nunca use em produção",
+			chaosLatenciaMs, chaosProbErro, chaosProbQueda)
+		log.Printf("[WARN] ****************************************************************")
+		r.Use(Chaos())
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	// Modo treinamento: a instância de treino marca as respostas; a principal
+	// encaminha para ela as requisições com X-Training-Mode
+	if treinamentoEnabled {
+		log.Printf("[WARN] Instância de TREINAMENTO: dados isolados, reset diário às %s", treinamentoResetHorario)
+		r.Use(MarcarTreinamento())
+	} else {
+		r.Use(EncaminharTreinamento())
 	}
 
-	log.Printf("[DB] Realizando consulta com limit=%d, offset=%d", limit, offset)
+	// Gravação de requisições para replay posterior
+	if recordFile != "" {
+		log.Printf("[WARN] Gravando requisições em: %s", recordFile)
+		r.Use(Recorder())
+	}
 
-	// Consulta SQL
-	rows, err := db.Query(context.Background(), `
-		SELECT id, codigo, nome, descricao, quantidade, quantidade_minima, 
-		       localizacao, fornecedor, notas, data_criacao, data_atualizacao
-		FROM produtos
-		ORDER BY nome
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
+	// Configurar CORS - origens, métodos e cabeçalhos vêm da configuração (ver cors.go)
+	if configCORS.AllowAllOrigins {
+		log.Println("[WARN] CORS aceita qualquer origem, sem credenciais; defina CORS_ORIGENS em produção")
+	}
+	r.Use(cors.New(configCORS))
 
-	if err != nil {
-		log.Printf("[ERROR] Erro ao consultar produtos: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
-		return
+	// Limite de requisições por cliente, depois do CORS para o 429 chegar ao navegador
+	if rateLimitEnabled {
+		r.Use(LimiteTaxa())
 	}
-	defer rows.Close()
 
-	// Processar resultados
-	produtos := []Produto{}
-	for rows.Next() {
-		var p Produto
-		var descricao, localizacao, fornecedor, notas *string
-		var quantidadeMinima *int
-		var dataAtualizacao *time.Time
+	// Canal WebSocket do dashboard (fora do grupo /api)
+	r.GET("/ws", getWebSocket)
 
-		err := rows.Scan(
-			&p.ID, &p.Codigo, &p.Nome, &descricao, &p.Quantidade,
-			&quantidadeMinima, &localizacao, &fornecedor, &notas,
-			&p.DataCriacao, &dataAtualizacao,
-		)
+	// Camadas já separadas: repositório -> serviço -> handlers
+	hp := novoHandlersProdutos(servico.NovoProdutos(repositorio.NovoProdutos(db)))
 
-		if err != nil {
-			log.Printf("[ERROR] Erro ao processar produto: %v", err)
-			continue
-		}
+	// Rotas versionadas e caminhos antigos sem versão (obsoletos, mesmo
+	// comportamento da versão atual); escritas passam pela fila com backpressure
+	v1 := r.Group(prefixoVersaoAPI(1), VersaoAPI(1), FilaEscrita(), InvalidarCache())
+	registrarRotasAPI(v1, hp)
 
-		// Tratar campos nulos
-		if descricao != nil {
-			p.Descricao = *descricao
-		}
-		if quantidadeMinima != nil {
-			p.QuantidadeMinima = *quantidadeMinima
-		}
-		if localizacao != nil {
-			p.Localizacao = *localizacao
-		}
-		if fornecedor != nil {
-			p.Fornecedor = *fornecedor
-		}
-		if notas != nil {
-			p.Notas = *notas
-		}
-		if dataAtualizacao != nil {
-			p.DataAtualizacao = *dataAtualizacao
-		}
+	legado := r.Group("/api", VersaoAPI(versaoAtualAPI), CaminhoLegado(), FilaEscrita(), InvalidarCache())
+	registrarRotasAPI(legado, hp)
 
-		produtos = append(produtos, p)
-	}
+	// Rotas inexistentes respondem no formato de erro da API
+	r.NoRoute(rotaNaoEncontrada)
 
-	// Verificar erros durante a iteração
-	if err = rows.Err(); err != nil {
-		log.Printf("[ERROR] Erro ao processar produtos: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar produtos"})
-		return
-	}
+	// Especificação gerada a partir das rotas registradas acima
+	documentarAPI(r.Routes())
 
-	log.Printf("[DB] Retornando %d produtos", len(produtos))
-	// Retornar lista de produtos
-	c.JSON(http.StatusOK, produtos)
+	return r
 }
 
-func getProduto(c *gin.Context) {
-	// Obter ID da URL
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		log.Printf("[ERROR] ID inválido: %s", idStr)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
-		return
+// Registra as rotas da API no grupo de uma versão
+func registrarRotasAPI(api *gin.RouterGroup, hp *handlersProdutos) {
+	// Rotas de produtos
+	api.GET("/produtos", AuditarListagem("produtos"), hp.listar)
+	api.GET("/produtos/:id", hp.buscar)
+	api.PATCH("/produtos/lote", atualizarProdutosLote)
+	api.POST("/produtos", criarProduto)
+	api.PUT("/produtos/:id", atualizarProduto)
+	api.PATCH("/produtos/:id", patchProduto)
+	api.DELETE("/produtos/:id", hp.excluir)
+	api.GET("/produtos/codigo/:codigo", CacheResposta(grupoCacheProdutos, cacheProdutosSegundos), hp.buscarPorCodigo)
+	api.GET("/produtos/codigos/fora-do-padrao", getCodigosForaPadrao)
+	api.POST("/produtos/codigos/renomear", renomearCodigos)
+	api.GET("/produtos/codigos/proximo", getProximoCodigo)
+	api.GET("/produtos/estoque-baixo", hp.estoqueBaixo)
+	api.GET("/produtos/:id/variantes", hp.variantes)
+	api.POST("/produtos/:id/duplicar", duplicarProduto)
+	api.GET("/produtos/:id/lotes", getLotesPorProduto)
+	api.GET("/produtos/:id/series", getSeriesPorProduto)
+	api.GET("/produtos/:id/precos", getHistoricoPrecos)
+	api.GET("/produtos/:id/previsao", getPrevisaoConsumo)
+	api.GET("/produtos/:id/historico-estoque", getHistoricoEstoque)
+	api.GET("/produtos/:id/conversoes", getConversoesPorProduto)
+	// :id aqui é o código do produto (documentado como :codigo); o nome do
+	// wildcard precisa ser o mesmo das rotas irmãs /produtos/:id/...
+	api.GET("/produtos/:id/disponibilidade-rede", getDisponibilidadeRede)
+	api.GET("/produtos/:id/etiqueta", getEtiquetaProduto)
+	api.GET("/produtos/:id/codigos-barras", getCodigosBarrasPorProduto)
+	api.POST("/produtos/:id/codigos-barras", criarCodigoBarras)
+	api.GET("/produtos/:id/aliases", getAliasesPorProduto)
+	api.POST("/produtos/:id/aliases", criarAliasCodigo)
+	api.GET("/produtos/:id/embalagens", getEmbalagensPorProduto)
+	api.POST("/produtos/:id/embalagens", criarEmbalagem)
+	api.POST("/produtos/:id/conversoes", criarConversao)
+	api.GET("/produtos/:id/estrutura", getEstruturaProduto)
+	api.PUT("/produtos/:id/estrutura", salvarEstruturaProduto)
+	api.GET("/produtos/:id/disponibilidade", getDisponibilidadeProduto)
+	api.POST("/produtos/:id/montagem", montarProduto)
+
+	// Rotas de movimentações
+	api.GET("/movimentacoes", AuditarListagem("movimentacoes"), getMovimentacoes)
+	api.GET("/movimentacoes/:id", getMovimentacao)
+	api.POST("/movimentacoes", criarMovimentacao)
+	api.POST("/movimentacoes/lote", criarMovimentacoesLote)
+	api.GET("/movimentacoes/produto/:produto_id", getMovimentacoesPorProduto)
+	api.GET("/movimentacoes/:id/anexos", getAnexosMovimentacao)
+	api.POST("/movimentacoes/:id/anexos", criarAnexoMovimentacao)
+
+	// Rotas de sincronização dos coletores
+	api.POST("/sync/handshake", handshakeSync)
+	api.POST("/sync/lancamentos", sincronizarLancamentos)
+	api.GET("/sync/dispositivos", getDispositivosSync)
+
+	// Rotas de anexos
+	api.GET("/anexos/:id/download", baixarAnexo)
+	api.DELETE("/anexos/:id", deletarAnexo)
+
+	// Rotas de lotes
+	api.GET("/lotes/vencendo", getLotesVencendo)
+
+	// Rotas de números de série
+	api.GET("/series/:numero", getSerie)
+
+	// Rotas de unidades de medida
+	api.GET("/unidades", getUnidades)
+	api.GET("/conversoes", getConversoes)
+	api.POST("/conversoes", criarConversao)
+	api.DELETE("/conversoes/:id", deletarConversao)
+
+	// Rotas de embalagens, códigos de barras e etiquetas
+	api.DELETE("/embalagens/:id", deletarEmbalagem)
+	api.DELETE("/codigos-barras/:id", deletarCodigoBarras)
+	api.DELETE("/aliases-codigos/:id", deletarAliasCodigo)
+	api.GET("/scan/*codigo", getScan)
+	api.POST("/etiquetas/lote", gerarEtiquetasLote)
+
+	// Rotas de impressoras e da fila de impressão
+	api.GET("/impressoras", getImpressoras)
+	api.POST("/impressoras", criarImpressora)
+	api.PUT("/impressoras/:id", atualizarImpressora)
+	api.DELETE("/impressoras/:id", deletarImpressora)
+	api.GET("/setores-usuarios", getSetoresUsuarios)
+	api.PUT("/setores-usuarios/:usuario", salvarSetorUsuario)
+	api.GET("/impressao/trabalhos", getTrabalhosImpressao)
+	api.POST("/impressao/trabalhos", criarTrabalhoImpressao)
+	api.POST("/impressao/trabalhos/:id/reimprimir", reimprimirTrabalho)
+
+	// Rotas de atividades
+	api.GET("/atividades", getAtividades)
+
+	// Rotas do modo treinamento (somente na instância de treino)
+	if treinamentoEnabled {
+		api.GET("/treinamento", getTreinamento)
+		api.POST("/treinamento/reset", resetarTreinamentoHandler)
+		api.POST("/treinamento/modelo", salvarModeloTreinamentoHandler)
+	}
+
+	// Rotas do perfil da empresa
+	api.GET("/empresa", getEmpresa)
+	api.PUT("/empresa", updateEmpresa)
+	api.GET("/empresa/logo", getLogoEmpresa)
+	api.POST("/empresa/logo", uploadLogoEmpresa)
+	api.DELETE("/empresa/logo", deleteLogoEmpresa)
+
+	// Rotas de tarefas
+	api.GET("/tarefas", getTarefas)
+	api.POST("/tarefas", createTarefa)
+	api.PUT("/tarefas/:id", updateTarefa)
+	api.DELETE("/tarefas/:id", deleteTarefa)
+
+	// Rotas de notificações
+	api.GET("/notificacoes", getNotificacoes)
+	api.POST("/notificacoes/lidas", marcarTodasNotificacoesLidas)
+	api.POST("/notificacoes/:id/lida", marcarNotificacaoLida)
+
+	// Rotas de comentários
+	api.GET("/comentarios", getComentarios)
+	api.POST("/comentarios", criarComentario)
+	api.PUT("/comentarios/:id", atualizarComentario)
+	api.DELETE("/comentarios/:id", deletarComentario)
+
+	// Rotas de configurações
+	api.GET("/configuracoes", CacheResposta(grupoCacheConfiguracoes, cacheConfiguracoesSegundos), getConfiguracoes)
+	api.GET("/configuracoes/export", AuditarExportacao("configuracoes"), exportarConfiguracoes)
+	api.POST("/configuracoes/import", importarConfiguracoes)
+	api.GET("/configuracoes/:chave", getConfiguracao)
+	api.PUT("/configuracoes/:chave", atualizarConfiguracao)
+
+	// Rotas de pedidos de compra
+	api.GET("/pedidos-compra", getPedidosCompra)
+	api.GET("/pedidos-compra/:id", getPedidoCompra)
+	api.POST("/pedidos-compra", criarPedidoCompra)
+	api.PUT("/pedidos-compra/:id", atualizarPedidoCompra)
+	api.DELETE("/pedidos-compra/:id", deletarPedidoCompra)
+	api.POST("/pedidos-compra/:id/enviar", enviarPedidoCompra)
+	api.POST("/pedidos-compra/:id/receber", receberPedidoCompra)
+
+	// Rotas de pedidos de saída
+	api.GET("/pedidos-saida", getPedidosSaida)
+	api.GET("/pedidos-saida/:id", getPedidoSaida)
+	api.POST("/pedidos-saida", criarPedidoSaida)
+	api.POST("/pedidos-saida/:id/cancelar", cancelarPedidoSaida)
+	api.GET("/pedidos-saida/:id/separacao", getSeparacaoPedidoSaida)
+	api.POST("/pedidos-saida/:id/confirmar-separacao", confirmarSeparacaoPedidoSaida)
+
+	// Rotas de administração
+	api.GET("/admin/integracoes", getIntegracoes)
+	api.POST("/admin/integracoes/:nome/testar", testarIntegracao)
+	api.GET("/admin/fila-escrita", getFilaEscrita)
+	api.POST("/admin/snapshots-estoque", criarSnapshotEstoque)
+	api.GET("/admin/duplicatas", getDuplicatas)
+	api.POST("/admin/duplicatas/detectar", detectarDuplicatasHandler)
+	api.POST("/admin/duplicatas/:id/mesclar", mesclarDuplicata)
+	api.POST("/admin/duplicatas/:id/ignorar", ignorarDuplicata)
+	api.GET("/admin/exportacoes", getExportacoes)
+	api.POST("/admin/anexos/verificar", verificarIntegridadeAnexos)
+
+	// Rotas de dashboard
+	api.GET("/dashboard", CacheResposta(grupoCacheDashboard, cacheDashboardSegundos), getDashboardData)
+	api.GET("/dashboard/graficos", getDashboardGraficos)
+
+	// Rotas de relatórios
+	api.GET("/relatorios/valorizacao", getRelatorioValorizacao)
+	api.GET("/relatorios/abc", getRelatorioABC)
+	api.GET("/relatorios/movimentacoes", getRelatorioMovimentacoes)
+	api.GET("/relatorios/destinacao", getRelatorioDestinacao)
+	api.GET("/relatorios/ambiental", getRelatorioAmbiental)
+	api.GET("/relatorios/grupos-reposicao", getRelatorioGruposReposicao)
+	api.GET("/relatorios/rede/estoque", getRelatorioEstoqueRede)
+	api.GET("/relatorios/rede/consumo", getRelatorioConsumoRede)
+	api.GET("/relatorios/estoque.pdf", AuditarExportacao("estoque_pdf"), getRelatorioEstoquePDF)
+	api.GET("/relatorios/movimentacoes.pdf", AuditarExportacao("movimentacoes_pdf"), getRelatorioMovimentacoesPDF)
+
+	// Rotas de locais de descarte
+	api.GET("/locais-descarte", getLocaisDescarte)
+	api.POST("/locais-descarte", criarLocalDescarte)
+	api.PUT("/locais-descarte/:id", atualizarLocalDescarte)
+
+	// Rotas de reposição
+	api.GET("/reposicao/sugestoes", getSugestoesReposicao)
+	api.GET("/estoque-seguranca/simulacao", getSimulacaoEstoqueSeguranca)
+	api.POST("/estoque-seguranca/aplicar", aplicarEstoqueSeguranca)
+	api.GET("/grupos-reposicao", getGruposReposicao)
+	api.POST("/grupos-reposicao", criarGrupoReposicao)
+	api.PUT("/grupos-reposicao/:id", atualizarGrupoReposicao)
+	api.DELETE("/grupos-reposicao/:id", deletarGrupoReposicao)
+	api.POST("/grupos-reposicao/:id/produtos", incluirProdutosGrupoReposicao)
+	api.DELETE("/grupos-reposicao/:id/produtos/:produto_id", removerProdutoGrupoReposicao)
+
+	// Rotas de localizações (posições do almoxarifado)
+	api.GET("/localizacoes", getLocalizacoes)
+	api.GET("/localizacoes/mapa", getMapaAlmoxarifado)
+	api.GET("/localizacoes/:id", getLocalizacao)
+	api.POST("/localizacoes", criarLocalizacao)
+	api.PUT("/localizacoes/:id", atualizarLocalizacao)
+	api.DELETE("/localizacoes/:id", deletarLocalizacao)
+	api.GET("/localizacoes/:id/produtos", getProdutosLocalizacao)
+
+	// Planejamento de necessidades (MRP) e fornecedores
+	api.POST("/mrp/calcular", calcularMRPHandler)
+	api.GET("/fornecedores", getFornecedores)
+	api.PUT("/fornecedores/:nome", salvarFornecedor)
+
+	// Atributos dinâmicos de produto por categoria
+	api.GET("/atributos", getAtributos)
+	api.GET("/atributos/:id", getAtributo)
+	api.POST("/atributos", criarAtributo)
+	api.PUT("/atributos/:id", atualizarAtributo)
+	api.DELETE("/atributos/:id", deletarAtributo)
+
+	// Tags de produtos
+	api.GET("/tags", getTags)
+	api.GET("/tags/:id", getTag)
+	api.POST("/tags", criarTag)
+	api.PUT("/tags/:id", atualizarTag)
+	api.DELETE("/tags/:id", deletarTag)
+	api.GET("/produtos/:id/tags", getTagsProduto)
+	api.PUT("/produtos/:id/tags", atribuirTagsProduto)
+	api.POST("/produtos/:id/tags", atribuirTagsProduto)
+	api.DELETE("/produtos/:id/tags/:tag", removerTagProduto)
+
+	// Consulta pública de saldo (totens de autoatendimento), somente leitura
+	api.GET("/consulta/:codigo", LimiteConsulta(), getConsultaSaldo)
+
+	// Rotas de webhooks de saída
+	api.GET("/webhooks", getWebhooks)
+	api.POST("/webhooks", criarWebhook)
+	api.PUT("/webhooks/:id", atualizarWebhook)
+	api.DELETE("/webhooks/:id", deletarWebhook)
+	api.GET("/webhooks/:id/entregas", getEntregasWebhook)
+
+	// Rotas de filiais da rede
+	api.GET("/filiais", getFiliais)
+	api.POST("/filiais", criarFilial)
+	api.PUT("/filiais/:id", atualizarFilial)
+	api.DELETE("/filiais/:id", deletarFilial)
+
+	// Rotas do assistente de primeira execução
+	api.GET("/setup", getSetup)
+	setup := api.Group("/setup", SetupDisponivel())
+	setup.PUT("/empresa", salvarEmpresaSetup)
+	setup.POST("/produtos", importarProdutosSetup)
+	setup.POST("/concluir", concluirSetup)
+
+	// Rotas de dispositivos (notificações push)
+	api.GET("/dispositivos", getDispositivos)
+	api.POST("/dispositivos", registrarDispositivo)
+	api.PUT("/dispositivos/:id", atualizarDispositivo)
+	api.DELETE("/dispositivos/:id", deletarDispositivo)
+
+	// Rotas de alertas por e-mail
+	api.GET("/alertas/assinantes", getAssinantesAlerta)
+	api.POST("/alertas/assinantes", salvarAssinanteAlerta)
+	api.PUT("/alertas/assinantes/:id", salvarAssinanteAlerta)
+	api.DELETE("/alertas/assinantes/:id", deletarAssinanteAlerta)
+	api.POST("/alertas/resumo", enviarResumoAlertas)
+
+	// Rotas de checklists de operações críticas
+	api.GET("/checklists/perguntas", getPerguntasChecklist)
+	api.POST("/checklists/perguntas", criarPerguntaChecklist)
+	api.PUT("/checklists/perguntas/:id", atualizarPerguntaChecklist)
+	api.POST("/checklists", criarChecklist)
+	api.GET("/checklists/:id", getChecklist)
+	api.PUT("/checklists/:id/itens/:item_id", responderItemChecklist)
+	api.POST("/checklists/:id/concluir", concluirChecklist)
+
+	// Rota para eventos em tempo real (SSE)
+	api.GET("/stream", getStream)
+
+	// Documentação OpenAPI e Swagger UI
+	api.GET("/docs", getSwaggerUI)
+	api.GET("/docs/openapi.json", getEspecificacaoAPI)
+}
+
+func main() {
+	// Configurar logging (JSON estruturado, ver logs.go)
+	configurarLogs()
+
+	// Configuração: flags > ambiente > arquivo > padrão
+	if err := validarConfiguracao(); err != nil {
+		log.Fatalf("Configuração inválida: %v", err)
 	}
 
-	log.Printf("[DB] Buscando produto com ID: %d", id)
+	// Subcomandos
+	if args := argumentosPosicionais(); len(args) > 0 {
+		switch args[0] {
+		case "replay":
+			os.Exit(executarReplay(args[1:]))
+		case "smoke":
+			os.Exit(executarSmoke(args[1:]))
+		}
+	}
 
-	// Consultar produto por ID
-	var p Produto
-	var descricao, localizacao, fornecedor, notas *string
-	var quantidadeMinima *int
-	var dataAtualizacao *time.Time
-
-	err = db.QueryRow(context.Background(), `
-		SELECT id, codigo, nome, descricao, quantidade, quantidade_minima, 
-		       localizacao, fornecedor, notas, data_criacao, data_atualizacao
-		FROM produtos
-		WHERE id = $1
-	`, id).Scan(
-		&p.ID, &p.Codigo, &p.Nome, &descricao, &p.Quantidade,
-		&quantidadeMinima, &localizacao, &fornecedor, &notas,
-		&p.DataCriacao, &dataAtualizacao,
-	)
+	log.Printf("Iniciando servidor RLS Estoque API...")
+	logarConfiguracao()
 
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[DB] Produto não encontrado com ID: %d", id)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
-		} else {
-			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+	// Instalação nova: criar o banco antes de conectar
+	if migracoesEnabled || migrateOnly || seedDemo {
+		if err := criarBancoSeNecessario(dbName); err != nil {
+			log.Fatalf("Falha ao criar banco de dados: %v", err)
 		}
-		return
 	}
 
-	// Tratar campos nulos
-	if descricao != nil {
-		p.Descricao = *descricao
+	// Inicializar conexão com o banco de dados
+	var err error
+	db, err = conectarBanco(dbName)
+	if err != nil {
+		log.Fatalf("Falha ao iniciar banco de dados: %v", err)
 	}
-	if quantidadeMinima != nil {
-		p.QuantidadeMinima = *quantidadeMinima
+	defer db.Close()
+	log.Println("✓ Conectado ao banco de dados PostgreSQL!")
+
+	// Criar ou atualizar o esquema do banco
+	if migracoesEnabled || migrateOnly || seedDemo {
+		n, err := aplicarMigracoes(context.Background(), db)
+		if err != nil {
+			log.Fatalf("Falha ao aplicar migrações: %v", err)
+		}
+		log.Printf("✓ Esquema do banco atualizado (%d migrações aplicadas)", n)
 	}
-	if localizacao != nil {
-		p.Localizacao = *localizacao
+	if migrateOnly {
+		return
 	}
-	if fornecedor != nil {
-		p.Fornecedor = *fornecedor
+
+	// Carga de dados de demonstração (banco vazio)
+	if seedDemo {
+		if err := popularDemo(context.Background()); err != nil {
+			log.Fatalf("Falha ao gravar dados de demonstração: %v", err)
+		}
+		return
 	}
-	if notas != nil {
-		p.Notas = *notas
+
+	// Relatórios e dashboard na réplica de leitura, se configurada
+	configurarLeituras(context.Background())
+
+	// Workers em segundo plano, cancelados no desligamento (ver desligamento.go)
+	ctxWorkers, cancelarWorkers := context.WithCancel(context.Background())
+	defer cancelarWorkers()
+
+	// Troca entre réplica e primário conforme o atraso da réplica
+	iniciarWorker(ctxWorkers, iniciarVerificacaoReplica)
+
+	// Recarga das credenciais quando vêm de arquivo ou do Vault
+	iniciarWorker(ctxWorkers, func(ctx context.Context) { vigiarSegredos(ctx, db) })
+
+	// Pré-carregar caches e preparar statements antes de aceitar requisições
+	if aquecimentoEnabled {
+		aquecer(context.Background())
 	}
-	if dataAtualizacao != nil {
-		p.DataAtualizacao = *dataAtualizacao
+
+	// Antivírus dos anexos no painel de integrações
+	if antivirusAnexos.Ativa() {
+		registrarIntegracao(antivirusAnexos)
 	}
 
-	log.Printf("[DB] Produto encontrado: %s (ID: %d)", p.Nome, p.ID)
-	// Retornar produto
-	c.JSON(http.StatusOK, p)
-}
+	// Fotografia diária do estoque
+	iniciarWorker(ctxWorkers, agendarSnapshotsEstoque)
 
-func getProdutoPorCodigo(c *gin.Context) {
-	// Obter código da URL
-	codigo := c.Param("codigo")
-	log.Printf("[DB] Buscando produto com código: %s", codigo)
+	// Repasse dos eventos às conexões WebSocket
+	iniciarWorker(ctxWorkers, hubWs.executar)
 
-	// Consultar produto por código
-	var p Produto
-	var descricao, localizacao, fornecedor, notas *string
-	var quantidadeMinima *int
-	var dataAtualizacao *time.Time
-
-	err := db.QueryRow(context.Background(), `
-		SELECT id, codigo, nome, descricao, quantidade, quantidade_minima, 
-		       localizacao, fornecedor, notas, data_criacao, data_atualizacao
-		FROM produtos
-		WHERE codigo = $1
-	`, codigo).Scan(
-		&p.ID, &p.Codigo, &p.Nome, &descricao, &p.Quantidade,
-		&quantidadeMinima, &localizacao, &fornecedor, &notas,
-		&p.DataCriacao, &dataAtualizacao,
-	)
+	// A instância de treino não dispara efeitos externos, só o reset diário
+	if treinamentoEnabled {
+		iniciarWorker(ctxWorkers, iniciarTreinamento)
+	} else {
+		// Entregas dos webhooks de saída
+		iniciarWorker(ctxWorkers, iniciarWebhooks)
 
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[DB] Produto não encontrado com código: %s", codigo)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
-		} else {
-			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
-		}
-		return
-	}
+		// Alertas de estoque baixo por e-mail
+		iniciarWorker(ctxWorkers, iniciarAlertasEmail)
 
-	// Tratar campos nulos
-	if descricao != nil {
-		p.Descricao = *descricao
+		// Notificações push para o app móvel
+		iniciarWorker(ctxWorkers, iniciarPush)
+
+		// Alertas de produto esgotado pelos canais de mensagens (Telegram)
+		iniciarWorker(ctxWorkers, iniciarCanaisNotificacao)
+
+		// Envio da fila de impressão às impressoras
+		iniciarWorker(ctxWorkers, iniciarImpressao)
+
+		// Heartbeat para o monitoramento externo
+		iniciarWorker(ctxWorkers, iniciarHeartbeat)
 	}
-	if quantidadeMinima != nil {
-		p.QuantidadeMinima = *quantidadeMinima
+
+	// Envio dos traces ao coletor OpenTelemetry
+	iniciarWorker(ctxWorkers, iniciarExportacaoTraces)
+
+	// Feed de atividade
+	iniciarWorker(ctxWorkers, iniciarAtividades)
+
+	// Notificações internas do app
+	iniciarWorker(ctxWorkers, iniciarNotificacoes)
+
+	// Regras que geram tarefas a partir de eventos
+	iniciarWorker(ctxWorkers, iniciarRegrasTarefas)
+
+	// Configurar o Gin
+	r := configurarRouter()
+
+	// Iniciar servidor
+	port := serverPort
+
+	// Obter IPs locais para mostrar nos logs
+	ips := getLocalIPs()
+
+	// Logar endereços de acesso
+	log.Printf("Servidor rodando nas seguintes URLs:")
+	log.Printf("- Local: %s://localhost:%s", esquemaServidor(), port)
+
+	// Mostrar todos os IPs disponíveis na rede
+	for _, ip := range ips {
+		log.Printf("- Rede: %s://%s:%s", esquemaServidor(), ip, port)
 	}
-	if localizacao != nil {
-		p.Localizacao = *localizacao
+
+	log.Printf("- Aceita conexões de qualquer dispositivo na mesma rede")
+
+	// Iniciar servidor para escutar em todas as interfaces, até SIGINT/SIGTERM
+	executarServidor(r, ":"+port, cancelarWorkers)
+}
+
+// Handlers de Produtos
+
+// Função auxiliar para validar um produto novo e completar a unidade padrão;
+// devolve a mensagem de erro ou "" se válido
+func validarNovoProduto(ctx context.Context, p *Produto) string {
+	// Campos obrigatórios, quantidades e classe de risco
+	if msg := servico.ValidarProduto(p); msg != "" {
+		return msg
 	}
-	if fornecedor != nil {
-		p.Fornecedor = *fornecedor
+
+	// Código no formato canônico definido nas configurações
+	if msg := normalizarCodigoProduto(ctx, &p.Codigo); msg != "" {
+		return msg
 	}
-	if notas != nil {
-		p.Notas = *notas
+
+	// Produtos serializados entram no estoque apenas por movimentação com números de série
+	if p.ControlaSerie && p.Quantidade != 0 {
+		return msgQuantidadeSerie
 	}
-	if dataAtualizacao != nil {
-		p.DataAtualizacao = *dataAtualizacao
+
+	// Saldo inicial negativo só onde o estoque negativo é permitido
+	if p.Quantidade < 0 && !estoqueNegativoPermitido(ctx, p.PermitirEstoqueNegativo) {
+		return fmt.Sprintf("Estoque negativo não permitido: a quantidade inicial ficaria %d abaixo de zero", -p.Quantidade)
 	}
+	return ""
+}
 
-	log.Printf("[DB] Produto encontrado: %s (ID: %d)", p.Nome, p.ID)
-	// Retornar produto
-	c.JSON(http.StatusOK, p)
+// Função auxiliar para inserir um produto, preenchendo ID e data de criação
+func inserirProduto(ctx context.Context, q querier, p *Produto) error {
+	return q.QueryRow(ctx, `
+		INSERT INTO produtos(
+			codigo, nome, descricao, quantidade, quantidade_minima,
+			localizacao, fornecedor, notas, controla_serie, categoria,
+			unidade_medida, preco_custo, quantidade_maxima, perigoso, classe_risco,
+			fispq_url, reciclavel, numero_onu, permitir_estoque_negativo, localizacao_id, kit,
+			produto_pai_id, variacao, atributos
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12, $13, $14,
+			NULLIF($15, ''), NULLIF($16, ''), $17, NULLIF($18, ''), $19, $20, $21, $22, NULLIF($23, ''),
+			COALESCE($24, '{}'::jsonb))
+		RETURNING id, data_criacao
+	`, p.Codigo, p.Nome, p.Descricao, p.Quantidade, p.QuantidadeMinima,
+		p.Localizacao, p.Fornecedor, p.Notas, p.ControlaSerie, p.Categoria,
+		p.UnidadeMedida, p.PrecoCusto, p.QuantidadeMaxima, p.Perigoso, p.ClasseRisco,
+		p.FispqURL, p.Reciclavel, p.NumeroONU, p.PermitirEstoqueNegativo, p.LocalizacaoID, p.Kit,
+		p.ProdutoPaiID, p.Variacao, p.Atributos).Scan(&p.ID, &p.DataCriacao)
 }
 
 func criarProduto(c *gin.Context) {
@@ -489,24 +866,45 @@ func criarProduto(c *gin.Context) {
 	var p Produto
 	if err := c.ShouldBindJSON(&p); err != nil {
 		log.Printf("[ERROR] Dados inválidos: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos"})
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	// Variante: nome e descrição vêm do produto pai
+	if err := vincularVariante(c.Request.Context(), db, 0, &p); err != nil {
+		log.Printf("[ERROR] Variante inválida (código '%s'): %v", p.Codigo, err)
+		responderErroVariante(c, err)
+		return
+	}
+
+	// Atributos definidos para a categoria
+	if !vincularAtributos(c, db, &p) {
 		return
 	}
 
-	// Validar campos obrigatórios
-	if p.Codigo == "" || p.Nome == "" {
-		log.Printf("[ERROR] Campos obrigatórios ausentes. Código: '%s', Nome: '%s'", p.Codigo, p.Nome)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Código e nome são obrigatórios"})
+	if msg := validarNovoProduto(c.Request.Context(), &p); msg != "" {
+		log.Printf("[ERROR] Produto inválido (código '%s'): %s", p.Codigo, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
 		return
 	}
 
+	// Produto e movimentação inicial na mesma transação: o histórico sempre
+	// acompanha o saldo
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context()) // Rollback caso ocorra algum erro
+
 	log.Printf("[DB] Verificando se já existe produto com código: %s", p.Codigo)
 	// Verificar se já existe um produto com o mesmo código
 	var existingId int
-	err := db.QueryRow(context.Background(), "SELECT id FROM produtos WHERE codigo = $1", p.Codigo).Scan(&existingId)
+	err = tx.QueryRow(c.Request.Context(), "SELECT id FROM produtos WHERE codigo = $1", p.Codigo).Scan(&existingId)
 	if err == nil {
 		log.Printf("[DB] Produto já existe com código: %s (ID: %d)", p.Codigo, existingId)
-		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe um produto com este código"})
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe um produto com este código", Codigo: CodigoCodigoDuplicado})
 		return
 	} else if err != pgx.ErrNoRows {
 		log.Printf("[ERROR] Erro ao verificar produto existente: %v", err)
@@ -514,16 +912,16 @@ func criarProduto(c *gin.Context) {
 		return
 	}
 
+	// Posição do almoxarifado informada por ID ou pelo código
+	if err = vincularLocalizacao(c.Request.Context(), tx, &p); err != nil {
+		log.Printf("[ERROR] Localização inválida para produto '%s': %v", p.Codigo, err)
+		responderErroLocalizacao(c, err)
+		return
+	}
+
 	log.Printf("[DB] Inserindo novo produto: %s (Código: %s)", p.Nome, p.Codigo)
 	// Inserir novo produto
-	err = db.QueryRow(context.Background(), `
-		INSERT INTO produtos(
-			codigo, nome, descricao, quantidade, quantidade_minima,
-			localizacao, fornecedor, notas
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, data_criacao
-	`, p.Codigo, p.Nome, p.Descricao, p.Quantidade, p.QuantidadeMinima,
-		p.Localizacao, p.Fornecedor, p.Notas).Scan(&p.ID, &p.DataCriacao)
+	err = inserirProduto(c.Request.Context(), tx, &p)
 
 	if err != nil {
 		log.Printf("[ERROR] Erro ao criar produto: %v", err)
@@ -531,27 +929,34 @@ func criarProduto(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[DB] Produto criado com sucesso! ID: %d, Código: %s, Nome: %s", p.ID, p.Codigo, p.Nome)
+	// Registrar a quantidade inicial no histórico; o produto já foi criado com
+	// ela, então a movimentação não altera o saldo. Saldo inicial negativo
+	// (estoque negativo permitido) vira uma saída
+	if p.Quantidade != 0 {
+		tipo, quantidade := "entrada", p.Quantidade
+		if quantidade < 0 {
+			tipo, quantidade = "saida", -quantidade
+		}
+		log.Printf("[DB] Registrando movimentação inicial de %s para produto ID: %d, Quantidade: %d", tipo, p.ID, quantidade)
+		_, err = tx.Exec(c.Request.Context(), `
+			INSERT INTO movimentacoes(produto_id, tipo, quantidade, notas)
+			VALUES ($1, $2, $3, 'Estoque inicial')
+		`, p.ID, tipo, quantidade)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao registrar movimentação inicial: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação inicial do produto"})
+			return
+		}
+	}
 
-	// NOTA: Bloco comentado para evitar duplicação da quantidade inicial
-	// O produto já é criado com a quantidade inicial correta
-	//
-	/*
-	   if p.Quantidade > 0 {
-	       log.Printf("[DB] Registrando movimentação inicial de entrada para produto ID: %d, Quantidade: %d", p.ID, p.Quantidade)
-	       _, err = db.Exec(context.Background(), `
-	           INSERT INTO movimentacoes(produto_id, tipo, quantidade, notas)
-	           VALUES ($1, 'entrada', $2, 'Estoque inicial')
-	       `, p.ID, p.Quantidade)
-
-	       if err != nil {
-	           log.Printf("[WARN] Erro ao registrar movimentação inicial: %v", err)
-	           // Não é um erro crítico, continuamos mesmo se falhar
-	       } else {
-	           log.Printf("[DB] Movimentação inicial registrada com sucesso")
-	       }
-	   }
-	*/
+	// Commit da transação
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Produto criado com sucesso! ID: %d, Código: %s, Nome: %s", p.ID, p.Codigo, p.Nome)
 
 	// Retornar produto criado
 	c.JSON(http.StatusCreated, p)
@@ -569,13 +974,52 @@ func atualizarProduto(c *gin.Context) {
 
 	log.Printf("[API] Iniciando atualização de produto ID: %d", id)
 
-	// Verificar se o produto existe
+	// Decodificar produto do request
+	var req AtualizacaoProduto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	p := req.Produto
+
+	// Variante: nome e descrição vêm do produto pai
+	if err := vincularVariante(c.Request.Context(), db, id, &p); err != nil {
+		log.Printf("[ERROR] Variante inválida (ID %d): %v", id, err)
+		responderErroVariante(c, err)
+		return
+	}
+
+	// Atributos definidos para a categoria
+	if !vincularAtributos(c, db, &p) {
+		return
+	}
+
+	// Campos obrigatórios, quantidades e classe de risco
+	if msg := servico.ValidarProduto(&p); msg != "" {
+		log.Printf("[ERROR] Produto inválido (ID %d): %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	// Produto, ajuste de quantidade e históricos na mesma transação: uma falha
+	// em qualquer etapa desfaz tudo e é devolvida ao cliente
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context()) // Rollback caso ocorra algum erro
+
+	// Verificar se o produto existe, travando-o até o fim da transação para o
+	// ajuste de quantidade partir do saldo atual
 	var existingProduto Produto
-	err = db.QueryRow(context.Background(), "SELECT id, quantidade FROM produtos WHERE id = $1", id).Scan(&existingProduto.ID, &existingProduto.Quantidade)
+	err = tx.QueryRow(c.Request.Context(), "SELECT id, codigo, quantidade, controla_serie, preco_custo, perigoso, kit FROM produtos WHERE id = $1 FOR UPDATE", id).Scan(&existingProduto.ID, &existingProduto.Codigo, &existingProduto.Quantidade, &existingProduto.ControlaSerie, &existingProduto.PrecoCusto, &existingProduto.Perigoso, &existingProduto.Kit)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			log.Printf("[DB] Produto não encontrado com ID: %d", id)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
 		} else {
 			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
@@ -583,27 +1027,18 @@ func atualizarProduto(c *gin.Context) {
 		return
 	}
 
-	// Decodificar produto do request
-	var p Produto
-	if err := c.ShouldBindJSON(&p); err != nil {
-		log.Printf("[ERROR] Dados inválidos: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos"})
-		return
-	}
-
-	// Validar campos obrigatórios
-	if p.Codigo == "" || p.Nome == "" {
-		log.Printf("[ERROR] Campos obrigatórios ausentes. Código: '%s', Nome: '%s'", p.Codigo, p.Nome)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Código e nome são obrigatórios"})
+	if msg := validarCodigoEditado(c.Request.Context(), existingProduto.Codigo, &p.Codigo); msg != "" {
+		log.Printf("[ERROR] Código inválido para produto ID %d: %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
 		return
 	}
 
 	// Verificar se o código já está sendo usado por outro produto
 	var existingId int
-	err = db.QueryRow(context.Background(), "SELECT id FROM produtos WHERE codigo = $1 AND id != $2", p.Codigo, id).Scan(&existingId)
+	err = tx.QueryRow(c.Request.Context(), "SELECT id FROM produtos WHERE codigo = $1 AND id != $2", p.Codigo, id).Scan(&existingId)
 	if err == nil {
 		log.Printf("[DB] Código '%s' já está sendo usado por outro produto (ID: %d)", p.Codigo, existingId)
-		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe outro produto com este código"})
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe outro produto com este código", Codigo: CodigoCodigoDuplicado})
 		return
 	} else if err != pgx.ErrNoRows {
 		log.Printf("[ERROR] Erro ao verificar produto existente: %v", err)
@@ -611,37 +1046,26 @@ func atualizarProduto(c *gin.Context) {
 		return
 	}
 
-	// Se a quantidade foi alterada, registrar movimentação
-	if p.Quantidade != existingProduto.Quantidade {
-		var tipo string
-		var quantidade int
-
-		if p.Quantidade > existingProduto.Quantidade {
-			tipo = "entrada"
-			quantidade = p.Quantidade - existingProduto.Quantidade
-			log.Printf("[DB] Registrando entrada de %d itens para produto ID: %d", quantidade, id)
-		} else {
-			tipo = "saida"
-			quantidade = existingProduto.Quantidade - p.Quantidade
-			log.Printf("[DB] Registrando saída de %d itens para produto ID: %d", quantidade, id)
-		}
-
-		_, err = db.Exec(context.Background(), `
-			INSERT INTO movimentacoes(produto_id, tipo, quantidade, notas)
-			VALUES ($1, $2, $3, 'Ajuste manual')
-		`, id, tipo, quantidade)
+	// Posição do almoxarifado informada por ID ou pelo código
+	if err = vincularLocalizacao(c.Request.Context(), tx, &p); err != nil {
+		log.Printf("[ERROR] Localização inválida para produto ID %d: %v", id, err)
+		responderErroLocalizacao(c, err)
+		return
+	}
 
-		if err != nil {
-			log.Printf("[WARN] Erro ao registrar movimentação: %v", err)
-			// Não é um erro crítico, continuamos mesmo se falhar
-		} else {
-			log.Printf("[DB] Movimentação registrada com sucesso")
-		}
+	// Kits não têm estoque próprio para ajustar
+	if (existingProduto.Kit || p.Kit) && p.Quantidade != existingProduto.Quantidade {
+		log.Printf("[ERROR] Ajuste manual de quantidade em kit. ID: %d", id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Kits não têm estoque próprio: ajuste a quantidade dos componentes"})
+		return
 	}
 
 	log.Printf("[DB] Atualizando produto ID: %d, Nome: %s", id, p.Nome)
-	// Atualizar produto
-	_, err = db.Exec(context.Background(), `
+	// Atualizar o cadastro antes do ajuste, mantendo o saldo atual: a
+	// movimentação de ajuste valida séries, descarte e estoque negativo com as
+	// regras do produto como fica depois do PUT e é ela que altera o saldo
+	p.ID = id
+	err = tx.QueryRow(c.Request.Context(), `
 		UPDATE produtos SET 
 			codigo = $1, 
 			nome = $2, 
@@ -651,10 +1075,30 @@ func atualizarProduto(c *gin.Context) {
 			localizacao = $6, 
 			fornecedor = $7, 
 			notas = $8,
+			controla_serie = $9,
+			categoria = NULLIF($10, ''),
+			unidade_medida = $11,
+			preco_custo = $12,
+			quantidade_maxima = $13,
+			perigoso = $14,
+			classe_risco = NULLIF($15, ''),
+			fispq_url = NULLIF($16, ''),
+			reciclavel = $17,
+			numero_onu = NULLIF($18, ''),
+			permitir_estoque_negativo = $19,
+			localizacao_id = $20,
+			kit = $21,
+			produto_pai_id = $22,
+			variacao = NULLIF($23, ''),
+			atributos = COALESCE($24, '{}'::jsonb),
 			data_atualizacao = CURRENT_TIMESTAMP
-		WHERE id = $9
-	`, p.Codigo, p.Nome, p.Descricao, p.Quantidade, p.QuantidadeMinima,
-		p.Localizacao, p.Fornecedor, p.Notas, id)
+		WHERE id = $25
+		RETURNING data_criacao, data_atualizacao
+	`, p.Codigo, p.Nome, p.Descricao, existingProduto.Quantidade, p.QuantidadeMinima,
+		p.Localizacao, p.Fornecedor, p.Notas, p.ControlaSerie, p.Categoria,
+		p.UnidadeMedida, p.PrecoCusto, p.QuantidadeMaxima, p.Perigoso, p.ClasseRisco,
+		p.FispqURL, p.Reciclavel, p.NumeroONU, p.PermitirEstoqueNegativo, p.LocalizacaoID, p.Kit,
+		p.ProdutoPaiID, p.Variacao, p.Atributos, id).Scan(&p.DataCriacao, &p.DataAtualizacao)
 
 	if err != nil {
 		log.Printf("[ERROR] Erro ao atualizar produto: %v", err)
@@ -662,138 +1106,83 @@ func atualizarProduto(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[DB] Produto atualizado com sucesso! ID: %d", id)
-
-	// Obter produto atualizado
-	p.ID = id
-	err = db.QueryRow(context.Background(), `
-		SELECT data_criacao, data_atualizacao
-		FROM produtos
-		WHERE id = $1
-	`, id).Scan(&p.DataCriacao, &p.DataAtualizacao)
-
-	if err != nil {
-		log.Printf("[WARN] Erro ao obter datas do produto: %v", err)
-		// Não é um erro crítico, continuamos mesmo se falhar
-	}
-
-	// Retornar produto atualizado
-	c.JSON(http.StatusOK, p)
-}
-
-func deletarProduto(c *gin.Context) {
-	// Obter ID da URL
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		log.Printf("[ERROR] ID inválido: %s", idStr)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
-		return
-	}
-
-	log.Printf("[API] Iniciando exclusão de produto ID: %d", id)
-
-	// Verificar se o produto existe
-	var existingId int
-	err = db.QueryRow(context.Background(), "SELECT id FROM produtos WHERE id = $1", id).Scan(&existingId)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[DB] Produto não encontrado com ID: %d", id)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
+	// Se a quantidade foi alterada, registrar a movimentação de ajuste, que
+	// aplica lotes (entrada sem lote, saída FEFO), séries, descarte, checklist
+	// e o saldo com os dados de "ajuste"
+	if p.Quantidade != existingProduto.Quantidade {
+		m := Movimentacao{ProdutoID: id, Notas: "Ajuste manual"}
+		req.Ajuste.aplicar(&m)
+		if p.Quantidade > existingProduto.Quantidade {
+			m.Tipo = "entrada"
+			m.Quantidade = p.Quantidade - existingProduto.Quantidade
+			log.Printf("[DB] Registrando entrada de %d itens para produto ID: %d", m.Quantidade, id)
 		} else {
-			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+			m.Tipo = "saida"
+			m.Quantidade = existingProduto.Quantidade - p.Quantidade
+			log.Printf("[DB] Registrando saída de %d itens para produto ID: %d", m.Quantidade, id)
 		}
-		return
-	}
 
-	log.Printf("[DB] Excluindo produto ID: %d", id)
-	// Excluir produto
-	_, err = db.Exec(context.Background(), "DELETE FROM produtos WHERE id = $1", id)
-	if err != nil {
-		log.Printf("[ERROR] Erro ao excluir produto: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir produto"})
-		return
+		if err = registrarMovimentacao(c.Request.Context(), tx, &m); err != nil {
+			e, ok := err.(*erroMovimentacao)
+			if !ok {
+				log.Printf("[ERROR] Erro ao registrar ajuste do produto %d: %v", id, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+				return
+			}
+			c.JSON(e.status, e.resposta())
+			return
+		}
 	}
 
-	log.Printf("[DB] Produto excluído com sucesso! ID: %d", id)
-	// Retornar sucesso
-	c.JSON(http.StatusOK, gin.H{"message": "Produto excluído com sucesso"})
-}
-
-func getProdutosEstoqueBaixo(c *gin.Context) {
-	log.Println("[DB] Buscando produtos com estoque baixo")
-
-	// Consultar produtos com estoque baixo
-	rows, err := db.Query(context.Background(), `
-		SELECT id, codigo, nome, descricao, quantidade, quantidade_minima, 
-		       localizacao, fornecedor, notas, data_criacao, data_atualizacao
-		FROM produtos
-		WHERE quantidade < COALESCE(quantidade_minima, 5)
-		ORDER BY quantidade ASC
-	`)
-
-	if err != nil {
-		log.Printf("[ERROR] Erro ao buscar produtos com estoque baixo: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos com estoque baixo"})
-		return
+	// Nome e descrição do produto pai valem para as variantes
+	if p.ProdutoPaiID == nil {
+		if err = propagarParaVariantes(c.Request.Context(), tx, id, p.Nome, p.Descricao); err != nil {
+			log.Printf("[ERROR] Erro ao atualizar variantes: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+			return
+		}
 	}
-	defer rows.Close()
-
-	// Processar resultados
-	produtos := []Produto{}
-	for rows.Next() {
-		var p Produto
-		var descricao, localizacao, fornecedor, notas *string
-		var quantidadeMinima *int
-		var dataAtualizacao *time.Time
-
-		err := rows.Scan(
-			&p.ID, &p.Codigo, &p.Nome, &descricao, &p.Quantidade,
-			&quantidadeMinima, &localizacao, &fornecedor, &notas,
-			&p.DataCriacao, &dataAtualizacao,
-		)
 
+	// Registrar alteração do preço de custo no histórico
+	if p.PrecoCusto != existingProduto.PrecoCusto {
+		err = registrarHistoricoPreco(c.Request.Context(), tx, id, existingProduto.PrecoCusto, p.PrecoCusto, OrigemPrecoProduto, nil)
 		if err != nil {
-			log.Printf("[ERROR] Erro ao processar produto: %v", err)
-			continue
+			log.Printf("[ERROR] Erro ao registrar histórico de preço: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar histórico de preço"})
+			return
 		}
+	}
 
-		// Tratar campos nulos
-		if descricao != nil {
-			p.Descricao = *descricao
-		}
-		if quantidadeMinima != nil {
-			p.QuantidadeMinima = *quantidadeMinima
-		} else {
-			p.QuantidadeMinima = 5 // Valor padrão
-		}
-		if localizacao != nil {
-			p.Localizacao = *localizacao
-		}
-		if fornecedor != nil {
-			p.Fornecedor = *fornecedor
-		}
-		if notas != nil {
-			p.Notas = *notas
-		}
-		if dataAtualizacao != nil {
-			p.DataAtualizacao = *dataAtualizacao
+	// Registrar a troca de código
+	if p.Codigo != existingProduto.Codigo {
+		err = registrarCodigoAnterior(c.Request.Context(), tx, id, existingProduto.Codigo, p.Codigo)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao registrar histórico de código: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar histórico de código"})
+			return
 		}
+	}
 
-		produtos = append(produtos, p)
+	if err = enfileirarEntregasProduto(c.Request.Context(), tx, p); err != nil {
+		log.Printf("[ERROR] Erro ao enfileirar entregas de webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+		return
 	}
 
-	// Verificar erros durante a iteração
-	if err = rows.Err(); err != nil {
-		log.Printf("[ERROR] Erro ao processar produtos: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar produtos"})
+	// Commit da transação
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
 		return
 	}
 
-	log.Printf("[DB] Encontrados %d produtos com estoque baixo", len(produtos))
-	// Retornar lista de produtos
-	c.JSON(http.StatusOK, produtos)
+	log.Printf("[DB] Produto atualizado com sucesso! ID: %d", id)
+
+	eventos.publicar(EventoProdutoAtualizado, p)
+	publicarSeEstoqueBaixo(p)
+
+	// Retornar produto atualizado
+	c.JSON(http.StatusOK, p)
 }
 
 // Handlers de Movimentações
@@ -818,7 +1207,7 @@ func getMovimentacoes(c *gin.Context) {
 	log.Printf("[DB] Realizando consulta com limit=%d, offset=%d", limit, offset)
 
 	// Consultar movimentações
-	rows, err := db.Query(context.Background(), `
+	rows, err := db.Query(c.Request.Context(), `
 		SELECT m.id, m.produto_id, m.tipo, m.quantidade, m.notas, m.data_movimentacao,
 			   p.codigo as produto_codigo, p.nome as produto_nome
 		FROM movimentacoes m
@@ -875,6 +1264,7 @@ func getMovimentacoes(c *gin.Context) {
 	}
 
 	log.Printf("[DB] Retornando %d movimentações", len(movimentacoes))
+	c.Set(chaveRegistrosExportacao, len(movimentacoes))
 	// Retornar lista de movimentações
 	c.JSON(http.StatusOK, movimentacoes)
 }
@@ -899,7 +1289,7 @@ func getMovimentacao(c *gin.Context) {
 	}
 	var notas *string
 
-	err = db.QueryRow(context.Background(), `
+	err = db.QueryRow(c.Request.Context(), `
 		SELECT m.id, m.produto_id, m.tipo, m.quantidade, m.notas, m.data_movimentacao,
 			   p.codigo as produto_codigo, p.nome as produto_nome
 		FROM movimentacoes m
@@ -931,102 +1321,233 @@ func getMovimentacao(c *gin.Context) {
 	c.JSON(http.StatusOK, m)
 }
 
-func criarMovimentacao(c *gin.Context) {
-	log.Println("[API] Iniciando criação de movimentação")
+// Erro de validação ou de banco ao registrar uma movimentação, com o status
+// HTTP e a mensagem devolvidos ao cliente
+type erroMovimentacao struct {
+	status int
+	msg    string
+	codigo string        // código do erro para o app (ver problemas.go)
+	campos []ErroCampo   // violações de validação
+	falta  *FaltaEstoque // saída recusada por falta de estoque
+}
 
-	// Decodificar movimentação do request
-	var m Movimentacao
-	if err := c.ShouldBindJSON(&m); err != nil {
-		log.Printf("[ERROR] Dados inválidos: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos"})
-		return
+func (e *erroMovimentacao) Error() string { return e.msg }
+
+// Corpo da resposta de erro; a recusa por falta de estoque traz a falta
+func (e *erroMovimentacao) resposta() any {
+	if e.falta != nil {
+		return novoErroFaltaEstoque(e.msg, []FaltaEstoque{*e.falta})
 	}
+	return ErrorResponse{Error: e.msg, Codigo: e.codigo, Campos: e.campos}
+}
 
-	// Validar campos obrigatórios
-	if m.ProdutoID <= 0 || m.Quantidade <= 0 || (m.Tipo != "entrada" && m.Tipo != "saida") {
-		log.Printf("[ERROR] Campos obrigatórios inválidos. ProdutoID: %d, Quantidade: %d, Tipo: %s",
+// Valida e registra a movimentação na transação: converte a unidade, aplica
+// lotes, séries, checklist e custo médio e atualiza o saldo do produto. O
+// produto é travado até o fim da transação, então movimentações do mesmo
+// produto na mesma transação enxergam o saldo já atualizado. Os erros são
+// sempre *erroMovimentacao.
+func registrarMovimentacao(ctx context.Context, tx pgx.Tx, m *Movimentacao) error {
+	// Validar os campos (tags binding); lotes e sync chegam aqui sem validação
+	if campos := validarCampos(m); len(campos) > 0 {
+		log.Printf("[ERROR] Movimentação inválida. ProdutoID: %d, Quantidade: %d, Tipo: %s",
 			m.ProdutoID, m.Quantidade, m.Tipo)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Produto, quantidade e tipo (entrada/saida) são obrigatórios"})
-		return
+		return &erroMovimentacao{status: http.StatusBadRequest, msg: "Dados inválidos", codigo: CodigoDadosInvalidos, campos: campos}
 	}
 
 	log.Printf("[DB] Verificando produto ID: %d", m.ProdutoID)
-	// Verificar se o produto existe
-	var existingId int
+	// Verificar se o produto existe. O saldo lido aqui só antecipa a recusa de
+	// saídas sem estoque; quem decide é o UPDATE atômico no fim
 	var quantidade int
-	err := db.QueryRow(context.Background(), "SELECT id, quantidade FROM produtos WHERE id = $1", m.ProdutoID).Scan(&existingId, &quantidade)
+	var controlaSerie, perigoso, kit bool
+	var codigo, unidadeBase string
+	var negativoProduto *bool
+	err := tx.QueryRow(ctx, "SELECT codigo, quantidade, controla_serie, unidade_medida, perigoso, permitir_estoque_negativo, kit FROM produtos WHERE id = $1",
+		m.ProdutoID).Scan(&codigo, &quantidade, &controlaSerie, &unidadeBase, &perigoso, &negativoProduto, &kit)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			log.Printf("[DB] Produto não encontrado com ID: %d", m.ProdutoID)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
-		} else {
-			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+			return &erroMovimentacao{status: http.StatusNotFound, msg: "Produto não encontrado", codigo: CodigoProdutoNaoEncontrado}
+		}
+		log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao verificar produto"}
+	}
+
+	// Converter a quantidade para a unidade base do produto
+	if m.Unidade != "" && m.Unidade != unidadeBase {
+		quantidadeInformada := m.Quantidade
+		m.Quantidade, err = converterParaBase(ctx, tx, m.ProdutoID, unidadeBase, m.Unidade, quantidadeInformada)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao converter %d %s para %s: %v", quantidadeInformada, m.Unidade, unidadeBase, err)
+			if err == errConversaoInexistente || err == errConversaoFracionada {
+				return &erroMovimentacao{status: http.StatusBadRequest, msg: "Unidade: " + err.Error()}
+			}
+			return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao converter unidade"}
+		}
+		m.Notas = strings.TrimSpace(fmt.Sprintf("%s (informado: %d %s)", m.Notas, quantidadeInformada, m.Unidade))
+	}
+	m.Unidade = unidadeBase
+
+	// Kits não têm estoque próprio: a saída baixa os componentes
+	if kit {
+		return registrarSaidaKit(ctx, tx, m, codigo)
+	}
+
+	// Saídas de produtos perigosos exigem motivo e local de descarte ativo
+	if m.Tipo == "saida" && perigoso {
+		if err = validarSaidaPerigosa(ctx, tx, m); err != nil {
+			log.Printf("[ERROR] Saída de produto perigoso inválida: %v", err)
+			if err == errDescarteMotivo || err == errDescarteLocal {
+				return &erroMovimentacao{status: http.StatusBadRequest, msg: "Descarte: " + err.Error()}
+			}
+			return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao verificar local de descarte"}
 		}
-		return
 	}
 
 	// Verificar se há quantidade suficiente para saída
-	if m.Tipo == "saida" && quantidade < m.Quantidade {
+	permitirNegativo := estoqueNegativoPermitido(ctx, negativoProduto)
+	if m.Tipo == "saida" && quantidade < m.Quantidade && !permitirNegativo {
 		log.Printf("[ERROR] Quantidade insuficiente para saída. Solicitado: %d, Disponível: %d",
 			m.Quantidade, quantidade)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Quantidade insuficiente em estoque"})
-		return
+		falta := novaFaltaEstoque(m.ProdutoID, codigo, m.Quantidade, quantidade)
+		return &erroMovimentacao{status: http.StatusBadRequest, msg: "Quantidade insuficiente em estoque", codigo: CodigoEstoqueInsuficiente, falta: &falta}
 	}
 
-	log.Printf("[DB] Iniciando transação para registrar movimentação")
-	// Iniciar transação
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
-		return
-	}
-	defer tx.Rollback(context.Background()) // Rollback caso ocorra algum erro
-
 	log.Printf("[DB] Inserindo movimentação: Produto ID: %d, Tipo: %s, Quantidade: %d",
 		m.ProdutoID, m.Tipo, m.Quantidade)
 	// Inserir movimentação
-	err = tx.QueryRow(context.Background(), `
-		INSERT INTO movimentacoes(produto_id, tipo, quantidade, notas)
-		VALUES ($1, $2, $3, $4)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimentacoes(produto_id, tipo, quantidade, notas, custo_unitario, motivo, local_descarte_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
 		RETURNING id, data_movimentacao
-	`, m.ProdutoID, m.Tipo, m.Quantidade, m.Notas).Scan(&m.ID, &m.DataMovimentacao)
+	`, m.ProdutoID, m.Tipo, m.Quantidade, m.Notas, m.CustoUnitario, m.Motivo, m.LocalDescarteID).Scan(&m.ID, &m.DataMovimentacao)
 
 	if err != nil {
 		log.Printf("[ERROR] Erro ao registrar movimentação: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
-		return
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao registrar movimentação"}
 	}
 
-	// Atualizar quantidade do produto
-	var novaQuantidade int
-	if m.Tipo == "entrada" {
-		novaQuantidade = quantidade + m.Quantidade
-		log.Printf("[DB] Atualizando quantidade do produto ID: %d, Quantidade anterior: %d, Nova quantidade: %d",
-			m.ProdutoID, quantidade, novaQuantidade)
-	} else {
-		novaQuantidade = quantidade - m.Quantidade
-		log.Printf("[DB] Atualizando quantidade do produto ID: %d, Quantidade anterior: %d, Nova quantidade: %d",
-			m.ProdutoID, quantidade, novaQuantidade)
+	// Registrar efeito nos lotes do produto
+	if err = registrarLotesMovimentacao(ctx, tx, m); err != nil {
+		log.Printf("[ERROR] Erro ao registrar lotes da movimentação: %v", err)
+		if err == errLoteNaoEncontrado || err == errLoteInsuficiente || err == errValidadeInvalida {
+			return &erroMovimentacao{status: http.StatusBadRequest, msg: "Lote: " + err.Error()}
+		}
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao registrar lotes da movimentação"}
+	}
+
+	// Registrar números de série das unidades movimentadas
+	if err = registrarSeriesMovimentacao(ctx, tx, m, controlaSerie); err != nil {
+		log.Printf("[ERROR] Erro ao registrar números de série da movimentação: %v", err)
+		if erroSerieValidacao(err) {
+			return &erroMovimentacao{status: http.StatusBadRequest, msg: "Número de série: " + err.Error()}
+		}
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao registrar números de série"}
+	}
+
+	// Vincular o checklist de baixa (obrigatório em saídas grandes)
+	if err = vincularChecklistMovimentacao(ctx, tx, m); err != nil {
+		log.Printf("[ERROR] Erro ao vincular checklist da movimentação: %v", err)
+		if erroChecklistValidacao(err) {
+			return &erroMovimentacao{status: http.StatusBadRequest, msg: "Checklist: " + err.Error()}
+		}
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao vincular checklist"}
 	}
 
-	_, err = tx.Exec(context.Background(), "UPDATE produtos SET quantidade = $1 WHERE id = $2", novaQuantidade, m.ProdutoID)
+	// Recalcular o custo médio com o custo da entrada (antes de alterar a quantidade)
+	if m.Tipo == "entrada" && m.CustoUnitario != nil {
+		if err = atualizarCustoMedio(ctx, tx, m); err != nil {
+			log.Printf("[ERROR] Erro ao atualizar custo médio do produto: %v", err)
+			return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao atualizar custo do produto"}
+		}
+	}
+
+	// Atualizar quantidade do produto no próprio banco: saídas concorrentes
+	// não conseguem levar o saldo abaixo de zero
+	delta := m.Quantidade
+	if m.Tipo == "saida" {
+		delta = -m.Quantidade
+	}
+	novaQuantidade, err := repositorio.NovoProdutos(tx).AjustarQuantidade(ctx, m.ProdutoID, delta, permitirNegativo)
 	if err != nil {
+		if errors.Is(err, repositorio.ErrEstoqueInsuficiente) {
+			log.Printf("[ERROR] Quantidade insuficiente para saída no momento da baixa. Produto ID: %d, Solicitado: %d",
+				m.ProdutoID, m.Quantidade)
+			// O saldo mudou desde a leitura; a falta é calculada sobre o atual
+			if err = tx.QueryRow(ctx, "SELECT quantidade FROM produtos WHERE id = $1", m.ProdutoID).Scan(&quantidade); err != nil {
+				log.Printf("[ERROR] Erro ao ler saldo do produto: %v", err)
+				return &erroMovimentacao{status: http.StatusBadRequest, msg: "Quantidade insuficiente em estoque", codigo: CodigoEstoqueInsuficiente}
+			}
+			falta := novaFaltaEstoque(m.ProdutoID, codigo, m.Quantidade, quantidade)
+			return &erroMovimentacao{status: http.StatusBadRequest, msg: "Quantidade insuficiente em estoque", codigo: CodigoEstoqueInsuficiente, falta: &falta}
+		}
 		log.Printf("[ERROR] Erro ao atualizar quantidade do produto: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar quantidade do produto"})
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao atualizar quantidade do produto"}
+	}
+	log.Printf("[DB] Quantidade do produto ID: %d atualizada: %d -> %d", m.ProdutoID, novaQuantidade-delta, novaQuantidade)
+
+	// Entregas dos webhooks na mesma transação da movimentação
+	if err = enfileirarEntregasWebhook(ctx, tx, EventoMovimentacaoCriada, *m); err != nil {
+		log.Printf("[ERROR] Erro ao enfileirar entregas de webhook: %v", err)
+		return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao registrar movimentação"}
+	}
+	if m.Tipo == "saida" {
+		p, err := scanProduto(tx.QueryRow(ctx, "SELECT "+produtoColunas+" FROM produtos WHERE id = $1", m.ProdutoID))
+		if err == nil {
+			if alerta, ok := alertaEstoqueBaixo(p); ok {
+				err = enfileirarEntregasWebhook(ctx, tx, EventoEstoqueBaixo, alerta)
+			}
+		}
+		if err != nil {
+			log.Printf("[ERROR] Erro ao enfileirar alerta de estoque baixo: %v", err)
+			return &erroMovimentacao{status: http.StatusInternalServerError, msg: "Erro ao registrar movimentação"}
+		}
+	}
+	return nil
+}
+
+func criarMovimentacao(c *gin.Context) {
+	log.Println("[API] Iniciando criação de movimentação")
+
+	// Decodificar movimentação do request
+	var m Movimentacao
+	if err := c.ShouldBindJSON(&m); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	log.Printf("[DB] Iniciando transação para registrar movimentação")
+	// Iniciar transação
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context()) // Rollback caso ocorra algum erro
+
+	if err = registrarMovimentacao(c.Request.Context(), tx, &m); err != nil {
+		e, ok := err.(*erroMovimentacao)
+		if !ok {
+			log.Printf("[ERROR] Erro ao registrar movimentação: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+			return
+		}
+		c.JSON(e.status, e.resposta())
 		return
 	}
 
 	log.Printf("[DB] Confirmando transação")
 	// Commit da transação
-	if err = tx.Commit(context.Background()); err != nil {
+	if err = tx.Commit(c.Request.Context()); err != nil {
 		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
 		return
 	}
 
 	log.Printf("[DB] Movimentação registrada com sucesso! ID: %d", m.ID)
+	publicarMovimentacoes(c.Request.Context(), []Movimentacao{m})
+
 	// Retornar movimentação criada
 	c.JSON(http.StatusCreated, m)
 }
@@ -1045,11 +1566,11 @@ func getMovimentacoesPorProduto(c *gin.Context) {
 
 	// Verificar se o produto existe
 	var existingId int
-	err = db.QueryRow(context.Background(), "SELECT id FROM produtos WHERE id = $1", produtoID).Scan(&existingId)
+	err = db.QueryRow(c.Request.Context(), "SELECT id FROM produtos WHERE id = $1", produtoID).Scan(&existingId)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			log.Printf("[DB] Produto não encontrado com ID: %d", produtoID)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado"})
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
 		} else {
 			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
@@ -1058,7 +1579,7 @@ func getMovimentacoesPorProduto(c *gin.Context) {
 	}
 
 	// Consultar movimentações do produto
-	rows, err := db.Query(context.Background(), `
+	rows, err := db.Query(c.Request.Context(), `
 		SELECT id, produto_id, tipo, quantidade, notas, data_movimentacao
 		FROM movimentacoes
 		WHERE produto_id = $1
@@ -1113,7 +1634,7 @@ func getConfiguracoes(c *gin.Context) {
 	log.Println("[DB] Buscando lista de configurações")
 
 	// Consultar todas as configurações
-	rows, err := db.Query(context.Background(), `
+	rows, err := db.Query(c.Request.Context(), `
 		SELECT id, chave, valor, descricao, data_atualizacao
 		FROM configuracoes
 		ORDER BY chave
@@ -1170,7 +1691,7 @@ func getConfiguracao(c *gin.Context) {
 	var conf Configuracao
 	var descricao *string
 
-	err := db.QueryRow(context.Background(), `
+	err := db.QueryRow(c.Request.Context(), `
 		SELECT id, chave, valor, descricao, data_atualizacao
 		FROM configuracoes
 		WHERE chave = $1
@@ -1206,7 +1727,7 @@ func atualizarConfiguracao(c *gin.Context) {
 
 	// Verificar se a configuração existe
 	var existingId int
-	err := db.QueryRow(context.Background(), "SELECT id FROM configuracoes WHERE chave = $1", chave).Scan(&existingId)
+	err := db.QueryRow(c.Request.Context(), "SELECT id FROM configuracoes WHERE chave = $1", chave).Scan(&existingId)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			log.Printf("[DB] Configuração não encontrada com chave: %s", chave)
@@ -1222,21 +1743,14 @@ func atualizarConfiguracao(c *gin.Context) {
 	var conf Configuracao
 	if err := c.ShouldBindJSON(&conf); err != nil {
 		log.Printf("[ERROR] Dados inválidos: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos"})
-		return
-	}
-
-	// Validar campos obrigatórios
-	if conf.Valor == "" {
-		log.Printf("[ERROR] Valor não pode ser vazio para chave: %s", chave)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Valor é obrigatório"})
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
 		return
 	}
 
 	log.Printf("[DB] Atualizando configuração %s = %s", chave, conf.Valor)
 	// Atualizar configuração
 	var dataAtualizacao time.Time
-	err = db.QueryRow(context.Background(), `
+	err = db.QueryRow(c.Request.Context(), `
 		UPDATE configuracoes SET 
 			valor = $1, 
 			descricao = $2,
@@ -1254,137 +1768,54 @@ func atualizarConfiguracao(c *gin.Context) {
 	// Definir chave e data de atualização
 	conf.Chave = chave
 	conf.DataAtualizacao = dataAtualizacao
+	guardarConfiguracao(chave, conf.Valor)
 
 	log.Printf("[DB] Configuração atualizada com sucesso! %s = %s", chave, conf.Valor)
 	// Retornar configuração atualizada
 	c.JSON(http.StatusOK, conf)
 }
 
-// Handler para Dashboard
-
-func getDashboardData(c *gin.Context) {
-	log.Println("[DB] Gerando dados para o dashboard")
-
-	dashboardData := DashboardData{}
-
-	// 1. Total de produtos
-	err := db.QueryRow(context.Background(), "SELECT COUNT(*) FROM produtos").Scan(&dashboardData.TotalProdutos)
-	if err != nil {
-		log.Printf("[WARN] Erro ao contar produtos: %v", err)
-		// Continuar mesmo com erro
-	} else {
-		log.Printf("[DB] Total de produtos: %d", dashboardData.TotalProdutos)
+// Função auxiliar para ler o valor de uma configuração, com valor padrão
+// quando a chave não existe ou não pode ser lida
+func lerConfiguracao(ctx context.Context, chave, padrao string) string {
+	if valor, ok := configuracaoEmCache(chave); ok {
+		return valor
 	}
 
-	// 2. Total de itens em estoque
-	err = db.QueryRow(context.Background(), "SELECT COALESCE(SUM(quantidade), 0) FROM produtos").Scan(&dashboardData.TotalItens)
+	var valor string
+	err := db.QueryRow(ctx, "SELECT valor FROM configuracoes WHERE chave = $1", chave).Scan(&valor)
 	if err != nil {
-		log.Printf("[WARN] Erro ao somar itens em estoque: %v", err)
-		// Continuar mesmo com erro
-	} else {
-		log.Printf("[DB] Total de itens em estoque: %d", dashboardData.TotalItens)
-	}
-
-	// 3. Produtos com estoque baixo
-	err = db.QueryRow(context.Background(), `
-		SELECT COUNT(*) FROM produtos
-		WHERE quantidade < COALESCE(quantidade_minima, 5)
-	`).Scan(&dashboardData.EstoqueBaixo)
-	if err != nil {
-		log.Printf("[WARN] Erro ao contar produtos com estoque baixo: %v", err)
-		// Continuar mesmo com erro
-	} else {
-		log.Printf("[DB] Produtos com estoque baixo: %d", dashboardData.EstoqueBaixo)
+		if err != pgx.ErrNoRows {
+			log.Printf("[WARN] Erro ao ler configuração %s: %v", chave, err)
+		}
+		return padrao
 	}
+	return valor
+}
 
-	// 4. Últimas movimentações
-	rows, err := db.Query(context.Background(), `
-		SELECT m.id, m.tipo, m.quantidade, m.data_movimentacao, m.notas,
-			   p.codigo as produto_codigo, p.nome as produto_nome
-		FROM movimentacoes m
-		JOIN produtos p ON m.produto_id = p.id
-		ORDER BY m.data_movimentacao DESC
-		LIMIT 10
-	`)
-
-	if err != nil {
-		log.Printf("[WARN] Erro ao buscar últimas movimentações: %v", err)
-		// Continuar mesmo com erro
-	} else {
-		defer rows.Close()
-
-		// Processar resultados
-		movimentacoes := []MovimentacaoView{}
-		for rows.Next() {
-			var m MovimentacaoView
-			var notas *string
-
-			err := rows.Scan(
-				&m.ID, &m.Tipo, &m.Quantidade, &m.DataMovimentacao, &notas,
-				&m.ProdutoCodigo, &m.ProdutoNome,
-			)
-
-			if err != nil {
-				log.Printf("[WARN] Erro ao processar movimentação: %v", err)
-				continue
-			}
+// Handler para Dashboard
 
-			// Tratar campos nulos
-			if notas != nil {
-				m.Notas = *notas
+func getDashboardData(c *gin.Context) {
+	// Widgets específicos: cada um é calculado e retornado de forma independente
+	if param := c.Query("widgets"); param != "" {
+		nomes := strings.Split(param, ",")
+		for i, nome := range nomes {
+			nomes[i] = strings.TrimSpace(nome)
+			if _, ok := widgetsDashboard[nomes[i]]; !ok {
+				log.Printf("[ERROR] Widget de dashboard desconhecido: %s", nomes[i])
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Widget desconhecido: " + nomes[i]})
+				return
 			}
-
-			movimentacoes = append(movimentacoes, m)
-		}
-
-		// Verificar erros durante a iteração
-		if err = rows.Err(); err != nil {
-			log.Printf("[WARN] Erro ao processar movimentações: %v", err)
-			// Continuar mesmo com erro
 		}
 
-		dashboardData.UltimasMovimentacoes = movimentacoes
-		log.Printf("[DB] Últimas movimentações: %d registros", len(movimentacoes))
+		log.Printf("[DB] Gerando widgets do dashboard: %v", nomes)
+		c.JSON(http.StatusOK, calcularWidgets(c.Request.Context(), nomes))
+		return
 	}
 
-	// 5. Top produtos por quantidade
-	rows, err = db.Query(context.Background(), `
-		SELECT codigo, nome, quantidade
-		FROM produtos
-		ORDER BY quantidade DESC
-		LIMIT 5
-	`)
-
-	if err != nil {
-		log.Printf("[WARN] Erro ao buscar top produtos: %v", err)
-		// Continuar mesmo com erro
-	} else {
-		defer rows.Close()
-
-		// Processar resultados
-		topProdutos := []ProdutoView{}
-		for rows.Next() {
-			var p ProdutoView
-
-			err := rows.Scan(&p.Codigo, &p.Nome, &p.Quantidade)
-
-			if err != nil {
-				log.Printf("[WARN] Erro ao processar produto: %v", err)
-				continue
-			}
-
-			topProdutos = append(topProdutos, p)
-		}
-
-		// Verificar erros durante a iteração
-		if err = rows.Err(); err != nil {
-			log.Printf("[WARN] Erro ao processar produtos: %v", err)
-			// Continuar mesmo com erro
-		}
+	log.Println("[DB] Gerando dados para o dashboard")
 
-		dashboardData.TopProdutos = topProdutos
-		log.Printf("[DB] Top produtos: %d registros", len(topProdutos))
-	}
+	dashboardData := montarDashboard(c.Request.Context())
 
 	log.Println("[API] Dashboard gerado com sucesso")
 	// Retornar dados do dashboard
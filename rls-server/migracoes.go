@@ -0,0 +1,216 @@
+// migracoes.go - Migrações do esquema do banco de dados
+//
+// Os arquivos SQL de migracoes/ são embutidos no binário e aplicados em ordem
+// no startup, cada um em sua própria transação, com o registro em
+// schema_migracoes. O nome do arquivo define a versão (0001_esquema_inicial.sql).
+// Um advisory lock impede que duas instâncias migrem ao mesmo tempo.
+//
+// Bancos instalados manualmente pelo db_setup.sql antes das migrações (tabela
+// produtos existente e schema_migracoes ausente) são marcados como já estando
+// na versão 1. Se o banco DB_NAME não existir, ele é criado.
+//
+// Com --migrate-only (MIGRATE_ONLY=true) o servidor aplica as migrações e sai.
+
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migracoes/*.sql
+var arquivosMigracoes embed.FS
+
+// Configuração das migrações - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	migracoesEnabled = getEnv("MIGRACOES_ENABLED", "true") == "true"
+	migrateOnly      = getEnv("MIGRATE_ONLY", "false") == "true"
+)
+
+// Tabela de controle das migrações aplicadas
+const tabelaMigracoes = "schema_migracoes"
+
+// Chave do advisory lock das migrações (arbitrária, única na aplicação)
+const lockMigracoes = 7352001
+
+// Versão equivalente ao esquema criado pelo db_setup.sql
+const versaoEsquemaInicial = 1
+
+type migracao struct {
+	versao int
+	nome   string
+	sql    string
+}
+
+// Lê as migrações embutidas, ordenadas pela versão
+func listarMigracoes() ([]migracao, error) {
+	arquivos, err := fs.Glob(arquivosMigracoes, "migracoes/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migracoes []migracao
+	versoes := map[int]string{}
+	for _, arquivo := range arquivos {
+		nome := strings.TrimSuffix(path.Base(arquivo), ".sql")
+		prefixo, _, _ := strings.Cut(nome, "_")
+		versao, err := strconv.Atoi(prefixo)
+		if err != nil || versao <= 0 {
+			return nil, fmt.Errorf("migração %s: nome deve começar pela versão (0001_descricao.sql)", arquivo)
+		}
+		if outro, ok := versoes[versao]; ok {
+			return nil, fmt.Errorf("migrações %s e %s com a mesma versão", outro, nome)
+		}
+		versoes[versao] = nome
+
+		conteudo, err := arquivosMigracoes.ReadFile(arquivo)
+		if err != nil {
+			return nil, err
+		}
+		migracoes = append(migracoes, migracao{versao: versao, nome: nome, sql: string(conteudo)})
+	}
+
+	sort.Slice(migracoes, func(i, j int) bool { return migracoes[i].versao < migracoes[j].versao })
+	return migracoes, nil
+}
+
+// Cria o banco de dados quando ele ainda não existe (instalação nova)
+func criarBancoSeNecessario(nome string) error {
+	pool, err := conectarBanco(nome)
+	if err == nil {
+		pool.Close()
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "3D000" { // invalid_catalog_name
+		return err
+	}
+
+	log.Printf("[DB] Banco %s não existe, criando", nome)
+	admin, err := conectarBanco("postgres")
+	if err != nil {
+		return fmt.Errorf("erro ao conectar para criar o banco: %w", err)
+	}
+	defer admin.Close()
+
+	if _, err := admin.Exec(context.Background(), "CREATE DATABASE "+pgx.Identifier{nome}.Sanitize()); err != nil {
+		// Outra instância pode ter criado o banco no meio tempo
+		if errors.As(err, &pgErr) && pgErr.Code == "42P04" { // duplicate_database
+			return nil
+		}
+		return fmt.Errorf("erro ao criar o banco %s: %w", nome, err)
+	}
+	return nil
+}
+
+// Aplica as migrações pendentes e retorna quantas foram aplicadas
+func aplicarMigracoes(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	migracoes, err := listarMigracoes()
+	if err != nil {
+		return 0, err
+	}
+
+	// O advisory lock é da sessão, então todas as operações usam a mesma conexão
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockMigracoes); err != nil {
+		return 0, fmt.Errorf("erro ao obter lock das migrações: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockMigracoes)
+
+	// Banco criado pelo db_setup.sql antes das migrações
+	var semControle, comEsquema bool
+	err = conn.QueryRow(ctx, "SELECT to_regclass($1) IS NULL, to_regclass('produtos') IS NOT NULL",
+		tabelaMigracoes).Scan(&semControle, &comEsquema)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migracoes (
+			versao INTEGER PRIMARY KEY,
+			nome VARCHAR(200) NOT NULL,
+			aplicada_em TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao criar %s: %w", tabelaMigracoes, err)
+	}
+
+	if semControle && comEsquema && len(migracoes) > 0 && migracoes[0].versao == versaoEsquemaInicial {
+		_, err = conn.Exec(ctx, "INSERT INTO schema_migracoes (versao, nome) VALUES ($1, $2)",
+			migracoes[0].versao, migracoes[0].nome)
+		if err != nil {
+			return 0, err
+		}
+		log.Printf("[DB] Banco existente marcado na migração %s", migracoes[0].nome)
+	}
+
+	aplicadas := map[int]bool{}
+	rows, err := conn.Query(ctx, "SELECT versao FROM schema_migracoes")
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var versao int
+		if err := rows.Scan(&versao); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		aplicadas[versao] = true
+	}
+	rows.Close()
+
+	// Verificar erros durante a iteração
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, m := range migracoes {
+		if aplicadas[m.versao] {
+			continue
+		}
+		if err := aplicarMigracao(ctx, conn.Conn(), m); err != nil {
+			return n, fmt.Errorf("migração %s: %w", m.nome, err)
+		}
+		log.Printf("[DB] Migração aplicada: %s", m.nome)
+		n++
+	}
+	return n, nil
+}
+
+func aplicarMigracao(ctx context.Context, conn *pgx.Conn, m migracao) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	// Sem argumentos o pgx usa o protocolo simples, que aceita vários comandos
+	if _, err := tx.Exec(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migracoes (versao, nome) VALUES ($1, $2)", m.versao, m.nome); err != nil {
+		return err
+	}
+
+	// Commit da transação
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,547 @@
+-- 0001_esquema_inicial.sql - Esquema inicial do RLS Estoque
+--
+-- Tabelas, índices, configurações iniciais e triggers. Aplicada pelo servidor
+-- no startup (ver migracoes.go) ou incluída pelo db_setup.sql na instalação
+-- manual com psql.
+
+-- Extensão de similaridade por trigramas (detecção de produtos duplicados)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Criar tabela de unidades de medida
+CREATE TABLE unidades_medida (
+    sigla VARCHAR(10) PRIMARY KEY,
+    descricao VARCHAR(50) NOT NULL
+);
+
+INSERT INTO unidades_medida (sigla, descricao)
+VALUES
+('un', 'Unidade'),
+('kg', 'Quilograma'),
+('g', 'Grama'),
+('m', 'Metro'),
+('cm', 'Centímetro'),
+('L', 'Litro'),
+('mL', 'Mililitro'),
+('caixa', 'Caixa');
+
+-- Criar tabela de locais de descarte (destinação de resíduos e produtos perigosos)
+CREATE TABLE locais_descarte (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(200) UNIQUE NOT NULL,
+    licenca_ambiental VARCHAR(100),
+    endereco TEXT,
+    ativo BOOLEAN NOT NULL DEFAULT true,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Criar tabela de produtos
+CREATE TABLE produtos (
+    id SERIAL PRIMARY KEY,
+    codigo VARCHAR(50) UNIQUE NOT NULL,
+    nome VARCHAR(200) NOT NULL,
+    descricao TEXT,
+    quantidade INTEGER NOT NULL DEFAULT 0,
+    quantidade_minima INTEGER,
+    quantidade_maxima INTEGER NOT NULL DEFAULT 0 CHECK (quantidade_maxima >= 0),
+    localizacao VARCHAR(100),
+    fornecedor VARCHAR(200),
+    notas TEXT,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP,
+    controla_serie BOOLEAN NOT NULL DEFAULT false,
+    categoria VARCHAR(100),
+    unidade_medida VARCHAR(10) NOT NULL DEFAULT 'un' REFERENCES unidades_medida(sigla),
+    preco_custo NUMERIC(14, 4) NOT NULL DEFAULT 0 CHECK (preco_custo >= 0),
+    perigoso BOOLEAN NOT NULL DEFAULT false,
+    classe_risco VARCHAR(50),
+    fispq_url TEXT
+);
+
+-- Criar tabela de movimentações
+CREATE TABLE movimentacoes (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER REFERENCES produtos(id) ON DELETE CASCADE,
+    tipo VARCHAR(10) NOT NULL CHECK (tipo IN ('entrada', 'saida')),
+    quantidade INTEGER NOT NULL,
+    notas TEXT,
+    data_movimentacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    custo_unitario NUMERIC(14, 4) CHECK (custo_unitario >= 0),
+    motivo TEXT,
+    local_descarte_id INTEGER REFERENCES locais_descarte(id)
+);
+
+-- Criar tabela de lotes
+CREATE TABLE lotes (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    numero_lote VARCHAR(50) NOT NULL,
+    validade DATE,
+    quantidade INTEGER NOT NULL DEFAULT 0 CHECK (quantidade >= 0),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (produto_id, numero_lote)
+);
+
+CREATE INDEX idx_lotes_validade ON lotes(validade) WHERE quantidade > 0;
+
+-- Criar tabela de lotes afetados por cada movimentação
+CREATE TABLE movimentacoes_lotes (
+    movimentacao_id INTEGER NOT NULL REFERENCES movimentacoes(id) ON DELETE CASCADE,
+    lote_id INTEGER NOT NULL REFERENCES lotes(id) ON DELETE CASCADE,
+    quantidade INTEGER NOT NULL CHECK (quantidade > 0),
+    PRIMARY KEY (movimentacao_id, lote_id)
+);
+
+-- Criar tabela de unidades serializadas
+CREATE TABLE numeros_serie (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    numero VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'em_estoque' CHECK (status IN ('em_estoque', 'baixado')),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (produto_id, numero)
+);
+
+CREATE INDEX idx_numeros_serie_numero ON numeros_serie(numero);
+
+-- Criar tabela de unidades afetadas por cada movimentação
+CREATE TABLE movimentacoes_series (
+    movimentacao_id INTEGER NOT NULL REFERENCES movimentacoes(id) ON DELETE CASCADE,
+    serie_id INTEGER NOT NULL REFERENCES numeros_serie(id) ON DELETE CASCADE,
+    PRIMARY KEY (movimentacao_id, serie_id)
+);
+
+-- Criar tabela de histórico de preços de custo
+-- origem: 'produto' (alteração no cadastro) ou 'entrada' (custo diferente em uma entrada)
+CREATE TABLE historico_precos (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    preco_anterior NUMERIC(14, 4) NOT NULL,
+    preco_novo NUMERIC(14, 4) NOT NULL,
+    origem VARCHAR(10) NOT NULL CHECK (origem IN ('produto', 'entrada')),
+    movimentacao_id INTEGER REFERENCES movimentacoes(id) ON DELETE SET NULL,
+    data_registro TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_historico_precos_produto ON historico_precos(produto_id, data_registro);
+
+-- Criar tabela de fotografias diárias do estoque (uma linha por produto e dia)
+CREATE TABLE estoque_snapshots (
+    id SERIAL PRIMARY KEY,
+    data DATE NOT NULL,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    quantidade INTEGER NOT NULL,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (produto_id, data)
+);
+
+-- Criar tabela de conversões de unidade (1 unidade_origem = fator unidade_destino)
+-- Sem produto_id a conversão vale para todos os produtos
+CREATE TABLE conversoes_unidade (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER REFERENCES produtos(id) ON DELETE CASCADE,
+    unidade_origem VARCHAR(10) NOT NULL REFERENCES unidades_medida(sigla),
+    unidade_destino VARCHAR(10) NOT NULL REFERENCES unidades_medida(sigla),
+    fator NUMERIC(14, 6) NOT NULL CHECK (fator > 0)
+);
+
+CREATE UNIQUE INDEX idx_conversoes_unidade_produto
+    ON conversoes_unidade(produto_id, unidade_origem, unidade_destino) WHERE produto_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_conversoes_unidade_global
+    ON conversoes_unidade(unidade_origem, unidade_destino) WHERE produto_id IS NULL;
+
+INSERT INTO conversoes_unidade (unidade_origem, unidade_destino, fator)
+VALUES
+('kg', 'g', 1000),
+('m', 'cm', 100),
+('L', 'mL', 1000);
+
+-- Criar tabela de configurações
+CREATE TABLE configuracoes (
+    id SERIAL PRIMARY KEY,
+    chave VARCHAR(50) UNIQUE NOT NULL,
+    valor TEXT NOT NULL,
+    descricao TEXT,
+    data_atualizacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Criar tabela de fornecedores (prazo de entrega usado nas sugestões de reposição)
+-- O nome corresponde ao campo fornecedor dos produtos e pedidos de compra
+CREATE TABLE fornecedores (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(200) UNIQUE NOT NULL,
+    prazo_entrega_dias INTEGER NOT NULL DEFAULT 7 CHECK (prazo_entrega_dias >= 0),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP
+);
+
+-- Criar tabela de pedidos de compra
+CREATE TABLE pedidos_compra (
+    id SERIAL PRIMARY KEY,
+    fornecedor VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'rascunho'
+        CHECK (status IN ('rascunho', 'enviado', 'recebido_parcial', 'recebido')),
+    notas TEXT,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP
+);
+
+-- Criar tabela de itens dos pedidos de compra
+CREATE TABLE pedidos_compra_itens (
+    id SERIAL PRIMARY KEY,
+    pedido_id INTEGER NOT NULL REFERENCES pedidos_compra(id) ON DELETE CASCADE,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id),
+    quantidade INTEGER NOT NULL CHECK (quantidade > 0),
+    quantidade_recebida INTEGER NOT NULL DEFAULT 0 CHECK (quantidade_recebida >= 0)
+);
+
+CREATE INDEX idx_pedidos_compra_itens_pedido ON pedidos_compra_itens(pedido_id);
+
+-- Criar tabela de pedidos de saída
+CREATE TABLE pedidos_saida (
+    id SERIAL PRIMARY KEY,
+    cliente VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'aberto'
+        CHECK (status IN ('aberto', 'separado', 'cancelado')),
+    notas TEXT,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP
+);
+
+-- Criar tabela de itens dos pedidos de saída
+CREATE TABLE pedidos_saida_itens (
+    id SERIAL PRIMARY KEY,
+    pedido_id INTEGER NOT NULL REFERENCES pedidos_saida(id) ON DELETE CASCADE,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id),
+    quantidade INTEGER NOT NULL CHECK (quantidade > 0)
+);
+
+CREATE INDEX idx_pedidos_saida_itens_pedido ON pedidos_saida_itens(pedido_id);
+
+-- Criar tabela de possíveis produtos duplicados (produto_a < produto_b)
+CREATE TABLE duplicatas_produtos (
+    id SERIAL PRIMARY KEY,
+    produto_a INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    produto_b INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    score NUMERIC(5, 4) NOT NULL,
+    motivo VARCHAR(30) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pendente'
+        CHECK (status IN ('pendente', 'ignorado')),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (produto_a, produto_b),
+    CHECK (produto_a < produto_b)
+);
+
+-- Criar tabela de perguntas (sim/não) dos checklists por operação
+CREATE TABLE checklist_perguntas (
+    id SERIAL PRIMARY KEY,
+    operacao VARCHAR(20) NOT NULL CHECK (operacao IN ('baixa')),
+    pergunta TEXT NOT NULL,
+    ordem INTEGER NOT NULL DEFAULT 0,
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Criar tabela de checklists preenchidos (registro para auditoria)
+CREATE TABLE checklists (
+    id SERIAL PRIMARY KEY,
+    operacao VARCHAR(20) NOT NULL CHECK (operacao IN ('baixa')),
+    responsavel VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'aberto'
+        CHECK (status IN ('aberto', 'concluido')),
+    movimentacao_id INTEGER UNIQUE REFERENCES movimentacoes(id) ON DELETE SET NULL,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_conclusao TIMESTAMP
+);
+
+-- Criar tabela de itens dos checklists (cópia da pergunta no momento da abertura)
+CREATE TABLE checklists_itens (
+    id SERIAL PRIMARY KEY,
+    checklist_id INTEGER NOT NULL REFERENCES checklists(id) ON DELETE CASCADE,
+    pergunta TEXT NOT NULL,
+    resposta BOOLEAN,
+    responsavel VARCHAR(100),
+    data_resposta TIMESTAMP
+);
+
+CREATE INDEX idx_checklists_itens_checklist ON checklists_itens(checklist_id);
+
+-- Criar tabela de webhooks de saída (eventos assinados e segredo da assinatura HMAC)
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    eventos TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP
+);
+
+-- Criar tabela de entregas dos webhooks (fila de envio e log)
+CREATE TABLE webhook_entregas (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    evento VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pendente'
+        CHECK (status IN ('pendente', 'entregue', 'falhou')),
+    tentativas INTEGER NOT NULL DEFAULT 0,
+    ultimo_status_http INTEGER,
+    ultimo_erro TEXT,
+    proxima_tentativa TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_entrega TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_entregas_webhook ON webhook_entregas(webhook_id, id);
+CREATE INDEX idx_webhook_entregas_pendentes ON webhook_entregas(proxima_tentativa) WHERE status = 'pendente';
+
+-- Criar tabela de assinantes dos alertas de estoque baixo por e-mail
+CREATE TABLE assinantes_alertas (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(200) UNIQUE NOT NULL,
+    nome VARCHAR(100),
+    resumo_diario BOOLEAN NOT NULL DEFAULT TRUE,
+    alerta_imediato BOOLEAN NOT NULL DEFAULT TRUE,
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Criar tabela de dispositivos do app móvel (notificações push)
+CREATE TABLE dispositivos (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(300) UNIQUE NOT NULL,
+    plataforma VARCHAR(10) NOT NULL CHECK (plataforma IN ('expo', 'fcm')),
+    nome VARCHAR(100),
+    eventos TEXT[] NOT NULL DEFAULT '{}',
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ultimo_envio TIMESTAMP
+);
+
+-- Criar tabela de filiais da rede (consulta de disponibilidade entre instâncias)
+CREATE TABLE filiais (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(100) UNIQUE NOT NULL,
+    url VARCHAR(300) NOT NULL,
+    contato VARCHAR(200),
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Criar tabela de embalagens de compra por produto e fornecedor ("CX c/ 50")
+CREATE TABLE embalagens_fornecedor (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    fornecedor VARCHAR(200) NOT NULL,
+    descricao VARCHAR(50),
+    fator INTEGER NOT NULL CHECK (fator > 0),
+    codigo_fornecedor VARCHAR(100),
+    ean VARCHAR(20) UNIQUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_embalagens_fornecedor_produto ON embalagens_fornecedor(produto_id);
+
+-- Criar tabela de códigos de barras adicionais por produto (EAN, Code 128, QR)
+CREATE TABLE codigos_barras (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    codigo VARCHAR(200) NOT NULL UNIQUE,
+    tipo VARCHAR(20),
+    descricao VARCHAR(100),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_codigos_barras_produto ON codigos_barras(produto_id);
+
+-- Criar tabela de comentários encadeados em produtos e pedidos
+CREATE TABLE comentarios (
+    id SERIAL PRIMARY KEY,
+    entidade VARCHAR(20) NOT NULL CHECK (entidade IN ('produto', 'pedido_compra', 'pedido_saida')),
+    entidade_id INTEGER NOT NULL,
+    comentario_pai_id INTEGER REFERENCES comentarios(id) ON DELETE CASCADE,
+    autor VARCHAR(100) NOT NULL,
+    texto TEXT NOT NULL,
+    mencoes TEXT[] NOT NULL DEFAULT '{}',
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_atualizacao TIMESTAMP
+);
+
+CREATE INDEX idx_comentarios_entidade ON comentarios(entidade, entidade_id);
+
+-- Criar tabela de anexos (arquivos ficam em ANEXOS_DIR)
+CREATE TABLE anexos (
+    id SERIAL PRIMARY KEY,
+    entidade VARCHAR(20) NOT NULL CHECK (entidade IN ('movimentacao')),
+    entidade_id INTEGER NOT NULL,
+    nome_arquivo VARCHAR(255) NOT NULL,
+    tipo_conteudo VARCHAR(100) NOT NULL,
+    tamanho BIGINT NOT NULL,
+    caminho VARCHAR(300) NOT NULL UNIQUE,
+    descricao VARCHAR(200),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_anexos_entidade ON anexos(entidade, entidade_id);
+
+-- Criar tabela do feed de atividade (gravada a partir do barramento de eventos)
+CREATE TABLE atividades (
+    id BIGSERIAL PRIMARY KEY,
+    tipo VARCHAR(50) NOT NULL,
+    entidade VARCHAR(20) NOT NULL,
+    entidade_id INTEGER NOT NULL,
+    usuario VARCHAR(100),
+    resumo TEXT NOT NULL,
+    dados JSONB,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_atividades_entidade ON atividades(entidade, entidade_id);
+CREATE INDEX idx_atividades_usuario ON atividades(usuario);
+
+-- Criar tabelas de notificações internas (destinatario nulo = todos) e leituras
+CREATE TABLE notificacoes (
+    id SERIAL PRIMARY KEY,
+    destinatario VARCHAR(100),
+    tipo VARCHAR(20) NOT NULL,
+    titulo VARCHAR(200) NOT NULL,
+    texto TEXT NOT NULL,
+    entidade VARCHAR(20),
+    entidade_id INTEGER,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notificacoes_destinatario ON notificacoes(destinatario);
+
+CREATE TABLE notificacoes_leituras (
+    notificacao_id INTEGER NOT NULL REFERENCES notificacoes(id) ON DELETE CASCADE,
+    destinatario VARCHAR(100) NOT NULL,
+    data_leitura TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (notificacao_id, destinatario)
+);
+
+-- Criar tabela de tarefas de estoque (regra nula = criada manualmente)
+CREATE TABLE tarefas (
+    id SERIAL PRIMARY KEY,
+    titulo VARCHAR(200) NOT NULL,
+    descricao TEXT,
+    responsavel VARCHAR(100),
+    prazo TIMESTAMP,
+    status VARCHAR(20) NOT NULL DEFAULT 'pendente' CHECK (status IN ('pendente', 'em_andamento', 'concluida', 'cancelada')),
+    regra VARCHAR(50),
+    entidade VARCHAR(20),
+    entidade_id INTEGER,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_conclusao TIMESTAMP
+);
+
+CREATE INDEX idx_tarefas_status ON tarefas(status);
+
+CREATE INDEX idx_produtos_nome_trgm ON produtos USING gin (nome gin_trgm_ops);
+
+-- Inserir configurações iniciais
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('versao_app', '1.0.0', 'Versão atual do aplicativo');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('alerta_estoque_baixo', 'true', 'Ativar alertas de estoque baixo');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('nivel_estoque_baixo', '5', 'Nível considerado estoque baixo');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('metodo_valorizacao', 'custo_medio', 'Método de valorização do estoque: custo_medio ou fifo');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('prazo_entrega_padrao', '7', 'Prazo de entrega em dias para fornecedores sem prazo cadastrado');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('limiar_duplicatas', '0.6', 'Similaridade mínima de nome (0 a 1) para sugerir produtos duplicados');
+
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES ('checklist_baixa_quantidade', '0', 'Quantidade a partir da qual saídas exigem checklist de baixa concluído (0 desativa)');
+
+-- Servidor SMTP dos alertas por e-mail (smtp_host vazio desativa o envio)
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES
+('smtp_host', '', 'Servidor SMTP dos alertas por e-mail (vazio desativa)'),
+('smtp_porta', '587', 'Porta do servidor SMTP (STARTTLS quando disponível)'),
+('smtp_usuario', '', 'Usuário de autenticação SMTP'),
+('smtp_senha', '', 'Senha de autenticação SMTP'),
+('smtp_remetente', '', 'Endereço de remetente dos alertas (padrão: usuário SMTP)');
+
+-- Notificações push do app móvel
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES
+('push_fcm_token', '', 'Chave de servidor do FCM para dispositivos Android sem Expo'),
+('push_movimentacao_grande', '100', 'Quantidade a partir da qual uma movimentação gera notificação push');
+
+-- Assistente de primeira execução e dados da empresa
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES
+('setup_concluido', 'false', 'Assistente de primeira execução concluído'),
+('empresa_nome', '', 'Razão social ou nome da empresa'),
+('empresa_cnpj', '', 'CNPJ da empresa'),
+('empresa_endereco', '', 'Endereço da empresa'),
+('empresa_telefone', '', 'Telefone da empresa'),
+('empresa_email', '', 'E-mail de contato da empresa'),
+('empresa_logo', '', 'Logotipo da empresa (caminho em ANEXOS_DIR)');
+
+-- Canais de mensagens para alertas de produto esgotado
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES
+('telegram_bot_token', '', 'Token do bot do Telegram (vazio desativa o canal)'),
+('telegram_chat_id', '', 'Chat ou grupo do Telegram que recebe os alertas'),
+('notificacao_link_produto', '', 'Link "Ver produto" nos alertas; {id} e {codigo} são substituídos');
+
+-- Regras de geração automática de tarefas
+INSERT INTO configuracoes (chave, valor, descricao)
+VALUES
+('tarefas_regra_estoque_baixo', 'true', 'Alerta de estoque baixo abre tarefa de conferência do produto'),
+('tarefas_responsavel_padrao', '', 'Responsável das tarefas geradas por regras (vazio deixa sem responsável)'),
+('tarefas_prazo_regra_dias', '1', 'Prazo em dias das tarefas geradas por regras');
+
+-- Criar função para atualizar timestamp de atualização
+CREATE OR REPLACE FUNCTION update_timestamp()
+RETURNS TRIGGER AS $$
+BEGIN
+   NEW.data_atualizacao = now(); 
+   RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Criar trigger para atualizar timestamp em produtos
+CREATE TRIGGER update_produtos_timestamp
+BEFORE UPDATE ON produtos
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
+
+-- Criar trigger para atualizar timestamp em configurações
+CREATE TRIGGER update_configuracoes_timestamp
+BEFORE UPDATE ON configuracoes
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
+
+-- Criar trigger para atualizar timestamp em webhooks
+CREATE TRIGGER update_webhooks_timestamp
+BEFORE UPDATE ON webhooks
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
+
+-- Criar trigger para atualizar timestamp em pedidos de compra
+CREATE TRIGGER update_pedidos_compra_timestamp
+BEFORE UPDATE ON pedidos_compra
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
+
+-- Criar trigger para atualizar timestamp em pedidos de saída
+CREATE TRIGGER update_pedidos_saida_timestamp
+BEFORE UPDATE ON pedidos_saida
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
+
+-- Criar trigger para atualizar timestamp em fornecedores
+CREATE TRIGGER update_fornecedores_timestamp
+BEFORE UPDATE ON fornecedores
+FOR EACH ROW
+EXECUTE PROCEDURE update_timestamp();
@@ -0,0 +1,22 @@
+-- 0002_exportacoes.sql - Registro e limite diário das exportações de dados
+
+-- Criar tabela de exportações (auditoria; bloqueada = recusada pelo limite)
+CREATE TABLE exportacoes (
+    id BIGSERIAL PRIMARY KEY,
+    tipo VARCHAR(50) NOT NULL,
+    usuario VARCHAR(100) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    filtros JSONB NOT NULL DEFAULT '{}',
+    registros INTEGER NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    status INTEGER NOT NULL,
+    bloqueada BOOLEAN NOT NULL DEFAULT FALSE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_exportacoes_usuario_data ON exportacoes(usuario, data_criacao);
+
+-- Limite de exportações por usuário e dia (0 = sem limite)
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('exportacoes_limite_diario', '20', 'Exportações permitidas por usuário por dia (0 = sem limite)')
+ON CONFLICT (chave) DO NOTHING;
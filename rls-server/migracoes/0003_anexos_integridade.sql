@@ -0,0 +1,8 @@
+-- 0003_anexos_integridade.sql - Hash e verificação antivírus dos anexos
+
+-- sha256 do arquivo gravado (nulo nos anexos anteriores, preenchido por
+-- POST /api/admin/anexos/verificar) e resultado da verificação do ClamAV
+ALTER TABLE anexos
+    ADD COLUMN sha256 CHAR(64),
+    ADD COLUMN verificacao VARCHAR(20) NOT NULL DEFAULT 'nao_verificado'
+        CHECK (verificacao IN ('nao_verificado', 'limpo'));
@@ -0,0 +1,20 @@
+-- 0004_politica_codigos.sql - Formato dos códigos de produto e histórico de renomeações
+
+-- Criar tabela de histórico dos códigos de produto (renomeações)
+CREATE TABLE historico_codigos (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    codigo_anterior VARCHAR(50) NOT NULL,
+    codigo_novo VARCHAR(50) NOT NULL,
+    data_alteracao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_historico_codigos_produto ON historico_codigos(produto_id);
+
+-- Regra de formato dos códigos de produto
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('codigo_formato_regex', '', 'Expressão regular que o código inteiro deve atender (vazio = qualquer formato)'),
+('codigo_maiusculas', 'true', 'Converter códigos de produto para maiúsculas'),
+('codigo_tamanho_minimo', '1', 'Tamanho mínimo do código de produto'),
+('codigo_tamanho_maximo', '50', 'Tamanho máximo do código de produto')
+ON CONFLICT (chave) DO NOTHING;
@@ -0,0 +1,20 @@
+-- 0005_aliases_codigos.sql - Códigos antigos que continuam levando ao produto atual
+
+-- Criar tabela de aliases de código (renomeações, mesclagens e cadastro manual)
+CREATE TABLE aliases_codigos (
+    id SERIAL PRIMARY KEY,
+    codigo VARCHAR(50) NOT NULL UNIQUE,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    origem VARCHAR(20) NOT NULL CHECK (origem IN ('renomeacao', 'mesclagem', 'manual')),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_aliases_codigos_produto ON aliases_codigos(produto_id);
+
+-- Renomeações já registradas; vale a mais recente de cada código que não
+-- voltou a ser usado por um produto
+INSERT INTO aliases_codigos (codigo, produto_id, origem, data_criacao)
+SELECT DISTINCT ON (h.codigo_anterior) h.codigo_anterior, h.produto_id, 'renomeacao', h.data_alteracao
+FROM historico_codigos h
+WHERE NOT EXISTS (SELECT 1 FROM produtos p WHERE p.codigo = h.codigo_anterior)
+ORDER BY h.codigo_anterior, h.data_alteracao DESC;
@@ -0,0 +1,5 @@
+-- 0006_classificacao_ambiental.sql - Classificação ambiental dos produtos
+
+-- Reciclável e número ONU; perigoso e classe_risco (classe ONU) já existem
+ALTER TABLE produtos ADD COLUMN reciclavel BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE produtos ADD COLUMN numero_onu VARCHAR(4) CHECK (numero_onu ~ '^[0-9]{4}$');
@@ -0,0 +1,5 @@
+-- 0007_estoque_seguranca.sql - Nível de serviço do cálculo de estoque de segurança
+
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('estoque_seguranca_nivel_servico', '0.95', 'Nível de serviço (0,50 a 0,999) usado no cálculo do estoque de segurança')
+ON CONFLICT (chave) DO NOTHING;
@@ -0,0 +1,25 @@
+-- 0008_grupos_reposicao.sql - Grupos de produtos com política de reposição própria (teste A/B)
+
+-- Política: min_max repõe até a quantidade máxima; ponto_pedido compra a
+-- cobertura de dias de consumo ao atingir o ponto de pedido
+CREATE TABLE grupos_reposicao (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(100) NOT NULL UNIQUE,
+    descricao TEXT,
+    politica VARCHAR(20) NOT NULL CHECK (politica IN ('min_max', 'ponto_pedido')),
+    cobertura_dias INTEGER NOT NULL DEFAULT 30 CHECK (cobertura_dias > 0),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Períodos de participação de cada produto (fim nulo = participação atual),
+-- para o relatório comparar cada dia com o grupo vigente naquele dia
+CREATE TABLE grupos_reposicao_produtos (
+    id SERIAL PRIMARY KEY,
+    grupo_id INTEGER NOT NULL REFERENCES grupos_reposicao(id) ON DELETE CASCADE,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    inicio DATE NOT NULL DEFAULT CURRENT_DATE,
+    fim DATE
+);
+
+CREATE UNIQUE INDEX idx_grupos_reposicao_produtos_atual ON grupos_reposicao_produtos(produto_id) WHERE fim IS NULL;
+CREATE INDEX idx_grupos_reposicao_produtos_grupo ON grupos_reposicao_produtos(grupo_id);
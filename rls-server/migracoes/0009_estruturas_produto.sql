@@ -0,0 +1,14 @@
+-- 0009_estruturas_produto.sql - Estrutura (lista de materiais) dos produtos fabricados
+
+-- Quantidade do componente por unidade do produto; componentes podem ter a
+-- própria estrutura (submontagens)
+CREATE TABLE estruturas_produto (
+    id SERIAL PRIMARY KEY,
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    componente_id INTEGER NOT NULL REFERENCES produtos(id),
+    quantidade NUMERIC(14, 4) NOT NULL CHECK (quantidade > 0),
+    UNIQUE (produto_id, componente_id),
+    CHECK (produto_id <> componente_id)
+);
+
+CREATE INDEX idx_estruturas_produto_componente ON estruturas_produto(componente_id);
@@ -0,0 +1,42 @@
+-- 0010_impressao.sql - Impressoras de etiqueta por setor e fila de impressão
+
+-- Impressoras de rede (envio direto para a porta, normalmente 9100); o tipo
+-- define a linguagem gerada: zpl (Zebra) ou pdf
+CREATE TABLE impressoras (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(100) NOT NULL UNIQUE,
+    endereco VARCHAR(255) NOT NULL,
+    porta INTEGER NOT NULL DEFAULT 9100 CHECK (porta BETWEEN 1 AND 65535),
+    tipo VARCHAR(10) NOT NULL CHECK (tipo IN ('zpl', 'pdf')),
+    setor VARCHAR(100) NOT NULL,
+    ativa BOOLEAN NOT NULL DEFAULT TRUE,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impressoras_setor ON impressoras(setor) WHERE ativa;
+
+-- Setor de cada usuário (X-Usuario), usado para escolher a impressora
+CREATE TABLE setores_usuarios (
+    usuario VARCHAR(100) PRIMARY KEY,
+    setor VARCHAR(100) NOT NULL
+);
+
+-- Trabalhos de impressão; o conteúdo é gerado no envio com os dados atuais
+CREATE TABLE trabalhos_impressao (
+    id SERIAL PRIMARY KEY,
+    impressora_id INTEGER NOT NULL REFERENCES impressoras(id) ON DELETE CASCADE,
+    usuario VARCHAR(100) NOT NULL,
+    produto_ids INTEGER[] NOT NULL,
+    simbolo VARCHAR(10) NOT NULL DEFAULT 'code128' CHECK (simbolo IN ('code128', 'qrcode')),
+    copias INTEGER NOT NULL DEFAULT 1 CHECK (copias BETWEEN 1 AND 100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pendente'
+        CHECK (status IN ('pendente', 'impresso', 'falhou')),
+    tentativas INTEGER NOT NULL DEFAULT 0,
+    ultimo_erro TEXT,
+    proxima_tentativa TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    data_impressao TIMESTAMP
+);
+
+CREATE INDEX idx_trabalhos_impressao_impressora ON trabalhos_impressao(impressora_id, id);
+CREATE INDEX idx_trabalhos_impressao_pendentes ON trabalhos_impressao(proxima_tentativa) WHERE status = 'pendente';
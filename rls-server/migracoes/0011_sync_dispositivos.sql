@@ -0,0 +1,14 @@
+-- 0011_sync_dispositivos.sql - Relógio dos coletores que sincronizam lançamentos
+
+-- Diferença entre o relógio do dispositivo e o do servidor, medida no
+-- handshake; os lançamentos do dispositivo são ajustados por ela. O maior
+-- desvio já visto fica para diagnóstico.
+CREATE TABLE sync_dispositivos (
+    dispositivo VARCHAR(100) PRIMARY KEY,
+    offset_ms BIGINT NOT NULL DEFAULT 0,
+    skew_maximo_ms BIGINT NOT NULL DEFAULT 0,
+    ultimo_handshake TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ultimo_sync TIMESTAMP,
+    lancamentos INTEGER NOT NULL DEFAULT 0,
+    rejeitados INTEGER NOT NULL DEFAULT 0
+);
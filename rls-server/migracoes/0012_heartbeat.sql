@@ -0,0 +1,8 @@
+-- 0012_heartbeat.sql - Heartbeat para monitoramento externo
+
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('heartbeat_url', '', 'URL chamada a cada heartbeat (healthchecks.io, Uptime Kuma...); vazio desativa'),
+('heartbeat_url_falha', '', 'URL chamada no lugar da heartbeat_url quando uma verificação falha (ex.: .../fail); vazio usa a heartbeat_url'),
+('heartbeat_email', '', 'Endereço que recebe o heartbeat por e-mail (usa o SMTP dos alertas); vazio desativa'),
+('heartbeat_intervalo_minutos', '5', 'Minutos entre dois heartbeats')
+ON CONFLICT (chave) DO NOTHING;
@@ -0,0 +1,8 @@
+-- 0013_estoque_negativo.sql - Política de estoque negativo
+
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('permitir_estoque_negativo', 'false', 'Permitir que saídas e ajustes deixem o saldo dos produtos abaixo de zero (true/false); o produto pode sobrescrever')
+ON CONFLICT (chave) DO NOTHING;
+
+-- NULL segue a configuração global; true/false vale só para o produto
+ALTER TABLE produtos ADD COLUMN permitir_estoque_negativo BOOLEAN;
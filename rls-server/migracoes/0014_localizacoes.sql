@@ -0,0 +1,36 @@
+-- 0014_localizacoes.sql - Posições do almoxarifado (corredor, prateleira, nível)
+
+-- Código da posição: corredor-prateleira-nível (ex.: A-03-2), o mesmo texto
+-- gravado em produtos.localizacao. Capacidade nula = sem limite.
+CREATE TABLE localizacoes (
+    id SERIAL PRIMARY KEY,
+    codigo VARCHAR(100) NOT NULL UNIQUE,
+    corredor VARCHAR(20) NOT NULL,
+    prateleira VARCHAR(20) NOT NULL,
+    nivel INTEGER NOT NULL CHECK (nivel >= 0),
+    capacidade INTEGER CHECK (capacidade > 0),
+    descricao TEXT,
+    ativo BOOLEAN NOT NULL DEFAULT true,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (corredor, prateleira, nivel)
+);
+
+ALTER TABLE produtos ADD COLUMN localizacao_id INTEGER REFERENCES localizacoes(id) ON DELETE SET NULL;
+CREATE INDEX idx_produtos_localizacao_id ON produtos(localizacao_id);
+
+-- Textos livres já no formato corredor-prateleira-nível viram posições e os
+-- produtos são vinculados a elas; os demais textos ficam como estão
+INSERT INTO localizacoes (codigo, corredor, prateleira, nivel)
+SELECT DISTINCT ON (upper(split_part(t.loc, '-', 1)), upper(split_part(t.loc, '-', 2)), split_part(t.loc, '-', 3)::int)
+       upper(split_part(t.loc, '-', 1)) || '-' || upper(split_part(t.loc, '-', 2)) || '-' || split_part(t.loc, '-', 3)::int,
+       upper(split_part(t.loc, '-', 1)), upper(split_part(t.loc, '-', 2)), split_part(t.loc, '-', 3)::int
+FROM (SELECT trim(localizacao) AS loc FROM produtos) t
+WHERE t.loc ~ '^[^- ]{1,20}-[^- ]{1,20}-[0-9]{1,6}$'
+ON CONFLICT DO NOTHING;
+
+UPDATE produtos p SET localizacao_id = l.id, localizacao = l.codigo
+FROM localizacoes l
+WHERE trim(p.localizacao) ~ '^[^- ]{1,20}-[^- ]{1,20}-[0-9]{1,6}$'
+  AND l.corredor = upper(split_part(trim(p.localizacao), '-', 1))
+  AND l.prateleira = upper(split_part(trim(p.localizacao), '-', 2))
+  AND l.nivel = split_part(trim(p.localizacao), '-', 3)::int;
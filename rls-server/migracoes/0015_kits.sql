@@ -0,0 +1,6 @@
+-- 0015_kits.sql - Kits: produtos compostos sem estoque próprio
+
+-- Os componentes do kit vêm de estruturas_produto; a saída de um kit baixa
+-- os componentes e o saldo do kit fica sempre zero
+ALTER TABLE produtos ADD COLUMN kit BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE produtos ADD CONSTRAINT produtos_kit_sem_estoque CHECK (NOT kit OR (quantidade = 0 AND NOT controla_serie));
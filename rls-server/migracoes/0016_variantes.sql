@@ -0,0 +1,11 @@
+-- 0016_variantes.sql - Variantes de produto (tamanho, cor)
+
+-- A variante aponta para o produto pai, de quem herda nome e descrição, e
+-- tem código, saldo e códigos de barras próprios; variacao a distingue das
+-- irmãs (ex.: "20mm", "Azul / M"). Excluir o pai solta as variantes.
+ALTER TABLE produtos ADD COLUMN produto_pai_id INTEGER REFERENCES produtos(id) ON DELETE SET NULL;
+ALTER TABLE produtos ADD COLUMN variacao VARCHAR(100);
+ALTER TABLE produtos ADD CONSTRAINT produtos_pai_diferente CHECK (produto_pai_id <> id);
+
+CREATE INDEX idx_produtos_produto_pai ON produtos(produto_pai_id);
+CREATE UNIQUE INDEX idx_produtos_variacao ON produtos(produto_pai_id, variacao) WHERE produto_pai_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- 0017_atributos.sql - Atributos dinâmicos de produto por categoria
+
+-- Cada categoria define os seus campos (voltagem, marca, cor...) com tipo;
+-- o produto guarda os valores em produtos.atributos, pela chave nome.
+-- Opções só valem para o tipo opcao.
+CREATE TABLE atributos (
+    id SERIAL PRIMARY KEY,
+    categoria VARCHAR(100) NOT NULL,
+    nome VARCHAR(50) NOT NULL,
+    rotulo VARCHAR(100) NOT NULL,
+    tipo VARCHAR(10) NOT NULL CHECK (tipo IN ('texto', 'numero', 'booleano', 'opcao')),
+    opcoes TEXT[] NOT NULL DEFAULT '{}',
+    obrigatorio BOOLEAN NOT NULL DEFAULT false,
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (categoria, nome)
+);
+
+ALTER TABLE produtos ADD COLUMN atributos JSONB NOT NULL DEFAULT '{}'::jsonb;
+CREATE INDEX idx_produtos_atributos ON produtos USING GIN (atributos jsonb_path_ops);
@@ -0,0 +1,18 @@
+-- 0018_tags.sql - Tags de produtos
+
+-- Agrupamentos livres (obra-cliente-x, inventario-pendente); o nome é
+-- guardado em minúsculas e a cor, opcional, é #RRGGBB para o app
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    nome VARCHAR(50) NOT NULL UNIQUE,
+    cor VARCHAR(7),
+    data_criacao TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE produtos_tags (
+    produto_id INTEGER NOT NULL REFERENCES produtos(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (produto_id, tag_id)
+);
+
+CREATE INDEX idx_produtos_tags_tag ON produtos_tags(tag_id);
@@ -0,0 +1,12 @@
+-- 0019_exportacoes_ip.sql - Limite de exportações por IP e listagens em massa
+
+-- O limite diário passa a ser contado pelo IP (o X-Usuario vem do cliente)
+CREATE INDEX idx_exportacoes_ip_data ON exportacoes(ip, data_criacao);
+
+UPDATE configuracoes SET descricao = 'Exportações permitidas por IP por dia (0 = sem limite)'
+WHERE chave = 'exportacoes_limite_diario';
+
+-- Páginas de listagem acima deste tamanho contam como exportação (0 = nunca)
+INSERT INTO configuracoes (chave, valor, descricao) VALUES
+('exportacoes_limite_pagina', '500', 'Registros por página de listagem acima dos quais a consulta conta como exportação (0 = nunca)')
+ON CONFLICT (chave) DO NOTHING;
@@ -0,0 +1,6 @@
+-- 0020_localizacoes_deposito.sql - Depósito de cada posição do almoxarifado
+
+-- Posições agrupadas por depósito (galpão, almoxarifado externo); o código da
+-- posição continua único na instância. Os produtos herdam o depósito da posição.
+ALTER TABLE localizacoes ADD COLUMN deposito VARCHAR(50) NOT NULL DEFAULT 'principal';
+CREATE INDEX idx_localizacoes_deposito ON localizacoes(deposito);
@@ -0,0 +1,123 @@
+// movimentacoes_lote.go - Registro de várias movimentações em uma requisição
+//
+// POST /api/movimentacoes/lote recebe uma lista de movimentações (um palete
+// bipado, por exemplo) e registra todas em uma única transação: ou todas
+// entram, ou nenhuma. Cada item roda em um savepoint, então um item inválido
+// não impede a validação dos seguintes e a resposta traz o resultado de cada
+// um.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tamanho máximo do lote - valor padrão, pode ser sobrescrito por variável de ambiente
+var movimentacoesLoteMaximo = getEnvAsInt("MOVIMENTACOES_LOTE_MAXIMO", 200)
+
+type ResultadoItemLote struct {
+	Indice       int           `json:"indice"`
+	Status       string        `json:"status"` // ok ou erro
+	Erro         string        `json:"erro,omitempty"`
+	Codigo       string        `json:"code,omitempty"` // código do erro (ver problemas.go)
+	Campos       []ErroCampo   `json:"campos,omitempty"`
+	Falta        *FaltaEstoque `json:"falta,omitempty"` // recusa por falta de estoque
+	Movimentacao *Movimentacao `json:"movimentacao,omitempty"`
+}
+
+type ResultadoLoteMovimentacoes struct {
+	// Falso quando algum item falhou e nada foi gravado
+	Aplicado bool                `json:"aplicado"`
+	Error    string              `json:"error,omitempty"`
+	Itens    []ResultadoItemLote `json:"itens"`
+}
+
+func criarMovimentacoesLote(c *gin.Context) {
+	// Cada item é validado ao ser registrado, com o erro no resultado do item
+	var movimentacoes []Movimentacao
+	if err := decodificarLista(c, &movimentacoes); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if len(movimentacoes) == 0 || len(movimentacoes) > movimentacoesLoteMaximo {
+		log.Printf("[ERROR] Lote de movimentações com %d itens", len(movimentacoes))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("O lote deve ter entre 1 e %d movimentações", movimentacoesLoteMaximo),
+		})
+		return
+	}
+
+	log.Printf("[API] Iniciando lote de %d movimentações", len(movimentacoes))
+	ctx := c.Request.Context()
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	resultado := ResultadoLoteMovimentacoes{Itens: make([]ResultadoItemLote, len(movimentacoes))}
+	falhas := 0
+	status := http.StatusBadRequest
+	for i := range movimentacoes {
+		item := &resultado.Itens[i]
+		item.Indice = i
+
+		// Savepoint: um item com erro é desfeito sem abortar a transação
+		sp, err := tx.Begin(ctx)
+		if err == nil {
+			if err = registrarMovimentacao(ctx, sp, &movimentacoes[i]); err != nil {
+				sp.Rollback(ctx)
+			} else {
+				err = sp.Commit(ctx)
+			}
+		}
+
+		if err != nil {
+			falhas++
+			item.Status = "erro"
+			if e, ok := err.(*erroMovimentacao); ok {
+				item.Erro, item.Codigo, item.Campos = e.msg, e.codigo, e.campos
+				item.Falta = e.falta
+				if e.status == http.StatusInternalServerError {
+					status = e.status
+				}
+			} else {
+				log.Printf("[ERROR] Erro no savepoint do item %d: %v", i, err)
+				item.Erro = "Erro ao registrar movimentação"
+				status = http.StatusInternalServerError
+			}
+			continue
+		}
+		item.Status = "ok"
+		item.Movimentacao = &movimentacoes[i]
+	}
+
+	if falhas > 0 {
+		log.Printf("[ERROR] Lote de movimentações rejeitado: %d de %d itens com erro", falhas, len(movimentacoes))
+		resultado.Error = fmt.Sprintf("%d de %d movimentações com erro; nada foi registrado", falhas, len(movimentacoes))
+		c.JSON(status, resultado)
+		return
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Lote de %d movimentações registrado com sucesso", len(movimentacoes))
+	publicarMovimentacoes(ctx, movimentacoes)
+
+	resultado.Aplicado = true
+	c.JSON(http.StatusCreated, resultado)
+}
@@ -0,0 +1,406 @@
+// mrp.go - Planejamento de necessidades a partir do plano de produção (MRP simplificado)
+//
+// POST /api/mrp/calcular recebe o plano (quantidade de cada produto final e a
+// data em que deve estar pronto) e explode as estruturas nível a nível. Em
+// cada produto as necessidades brutas são atendidas, por data, pelo estoque
+// atual e pelos recebimentos previstos (saldo pendente dos pedidos de compra,
+// esperado na data do pedido mais o prazo do fornecedor); o que falta é a
+// necessidade líquida. Produtos com estrutura repassam a necessidade líquida
+// aos componentes na mesma data (sem prazo de fabricação); os demais são
+// comprados, com data de pedido = data da necessidade menos o prazo do
+// fornecedor.
+//
+// Com gerar_pedidos, as necessidades líquidas dos produtos comprados viram
+// pedidos de compra em rascunho, um por fornecedor. Como rascunhos contam
+// como recebimentos previstos, recalcular o mesmo plano não duplica compras.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ItemPlanoProducao struct {
+	ProdutoID  int    `json:"produto_id"`
+	Quantidade int    `json:"quantidade"`
+	Data       string `json:"data"` // AAAA-MM-DD
+}
+
+type PlanoProducao struct {
+	Itens        []ItemPlanoProducao `json:"itens"`
+	GerarPedidos bool                `json:"gerar_pedidos,omitempty"`
+}
+
+type NecessidadeMRP struct {
+	ProdutoID       int       `json:"produto_id"`
+	ProdutoCodigo   string    `json:"produto_codigo"`
+	ProdutoNome     string    `json:"produto_nome"`
+	Unidade         string    `json:"unidade"`
+	Fornecedor      string    `json:"fornecedor,omitempty"`
+	Nivel           int       `json:"nivel"`     // 0 = produto do plano
+	Fabricado       bool      `json:"fabricado"` // tem estrutura
+	DataNecessidade time.Time `json:"data_necessidade"`
+	DataPedido      time.Time `json:"data_pedido"`
+	Bruta           int       `json:"bruta"`
+	DoEstoque       int       `json:"do_estoque"`
+	DeRecebimentos  int       `json:"de_recebimentos"`
+	Liquida         int       `json:"liquida"`
+	Atrasada        bool      `json:"atrasada"` // data do pedido já passou
+}
+
+type ResultadoMRP struct {
+	Necessidades []NecessidadeMRP `json:"necessidades"`
+	Pedidos      []PedidoCompra   `json:"pedidos,omitempty"`
+	Avisos       []string         `json:"avisos,omitempty"`
+}
+
+// Dados de um produto usados no cálculo
+type produtoMRP struct {
+	codigo, nome, unidade, fornecedor string
+	estoque, prazo                    int
+}
+
+type recebimentoPrevisto struct {
+	data       time.Time
+	quantidade int
+}
+
+type demandaMRP struct {
+	data       time.Time
+	quantidade float64
+}
+
+// Função auxiliar para o nível mais baixo em que cada produto aparece a partir
+// dos produtos do plano; devolve erro se a estrutura for circular
+func niveisMRP(estruturas map[int][]ComponenteEstrutura, raizes []int) (map[int]int, error) {
+	niveis := map[int]int{}
+	var visitar func(id, nivel int, caminho map[int]bool) error
+	visitar = func(id, nivel int, caminho map[int]bool) error {
+		if caminho[id] {
+			return fmt.Errorf("estrutura circular no produto %d", id)
+		}
+		if atual, ok := niveis[id]; ok && atual >= nivel {
+			return nil
+		}
+		niveis[id] = nivel
+		caminho[id] = true
+		defer delete(caminho, id)
+		for _, comp := range estruturas[id] {
+			if err := visitar(comp.ComponenteID, nivel+1, caminho); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, id := range raizes {
+		if err := visitar(id, 0, map[int]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return niveis, nil
+}
+
+// Calcula as necessidades nível a nível: um produto só é processado depois de
+// todos os que o usam, quando a sua demanda bruta está completa
+func calcularMRP(plano []ItemPlanoProducao, estruturas map[int][]ComponenteEstrutura,
+	produtos map[int]produtoMRP, recebimentos map[int][]recebimentoPrevisto, hoje time.Time) ([]NecessidadeMRP, error) {
+
+	raizes := make([]int, 0, len(plano))
+	demandas := map[int][]demandaMRP{}
+	for _, item := range plano {
+		data, _ := time.Parse(formatoData, item.Data)
+		raizes = append(raizes, item.ProdutoID)
+		demandas[item.ProdutoID] = append(demandas[item.ProdutoID], demandaMRP{data, float64(item.Quantidade)})
+	}
+
+	niveis, err := niveisMRP(estruturas, raizes)
+	if err != nil {
+		return nil, err
+	}
+	ordem := make([]int, 0, len(niveis))
+	for id := range niveis {
+		ordem = append(ordem, id)
+	}
+	sort.Slice(ordem, func(a, b int) bool {
+		if niveis[ordem[a]] != niveis[ordem[b]] {
+			return niveis[ordem[a]] < niveis[ordem[b]]
+		}
+		return produtos[ordem[a]].codigo < produtos[ordem[b]].codigo
+	})
+
+	necessidades := []NecessidadeMRP{}
+	for _, id := range ordem {
+		p := produtos[id]
+
+		// Demanda bruta por data, arredondada para cima (componentes fracionários)
+		porData := map[time.Time]float64{}
+		for _, d := range demandas[id] {
+			porData[d.data] += d.quantidade
+		}
+		datas := make([]time.Time, 0, len(porData))
+		for data := range porData {
+			datas = append(datas, data)
+		}
+		sort.Slice(datas, func(a, b int) bool { return datas[a].Before(datas[b]) })
+
+		estoque := max(p.estoque, 0)
+		pendentes := recebimentos[id]
+		recebido := 0
+		for _, data := range datas {
+			for len(pendentes) > 0 && !pendentes[0].data.After(data) {
+				recebido += pendentes[0].quantidade
+				pendentes = pendentes[1:]
+			}
+
+			n := NecessidadeMRP{
+				ProdutoID: id, ProdutoCodigo: p.codigo, ProdutoNome: p.nome, Unidade: p.unidade,
+				Fornecedor: p.fornecedor, Nivel: niveis[id], Fabricado: len(estruturas[id]) > 0,
+				DataNecessidade: data, DataPedido: data,
+				Bruta: int(math.Ceil(porData[data] - 1e-9)),
+			}
+			n.DoEstoque = min(estoque, n.Bruta)
+			estoque -= n.DoEstoque
+			n.DeRecebimentos = min(recebido, n.Bruta-n.DoEstoque)
+			recebido -= n.DeRecebimentos
+			n.Liquida = n.Bruta - n.DoEstoque - n.DeRecebimentos
+
+			if n.Fabricado {
+				for _, comp := range estruturas[id] {
+					demandas[comp.ComponenteID] = append(demandas[comp.ComponenteID],
+						demandaMRP{data, float64(n.Liquida) * comp.Quantidade})
+				}
+			} else {
+				n.DataPedido = data.AddDate(0, 0, -p.prazo)
+			}
+			n.Atrasada = n.Liquida > 0 && n.DataPedido.Before(hoje)
+			necessidades = append(necessidades, n)
+		}
+	}
+	return necessidades, nil
+}
+
+// Carrega produtos (com prazo do fornecedor) e recebimentos previstos dos IDs
+func carregarDadosMRP(ctx context.Context, ids []int) (map[int]produtoMRP, map[int][]recebimentoPrevisto, error) {
+	prazoPadrao, err := strconv.Atoi(lerConfiguracao(ctx, "prazo_entrega_padrao", "7"))
+	if err != nil || prazoPadrao < 0 {
+		prazoPadrao = 7
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT p.id, p.codigo, p.nome, p.unidade_medida, COALESCE(p.fornecedor, ''), p.quantidade,
+		       COALESCE(f.prazo_entrega_dias, $2)
+		FROM produtos p
+		LEFT JOIN fornecedores f ON f.nome = p.fornecedor
+		WHERE p.id = ANY($1)
+	`, ids, prazoPadrao)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	produtos := map[int]produtoMRP{}
+	for rows.Next() {
+		var id int
+		var p produtoMRP
+		if err := rows.Scan(&id, &p.codigo, &p.nome, &p.unidade, &p.fornecedor, &p.estoque, &p.prazo); err != nil {
+			return nil, nil, err
+		}
+		produtos[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Saldo pendente dos pedidos em aberto, na data esperada de chegada
+	rows, err = db.Query(ctx, `
+		SELECT i.produto_id, (pc.data_criacao::date + COALESCE(f.prazo_entrega_dias, $2))::timestamp,
+		       SUM(i.quantidade - i.quantidade_recebida)
+		FROM pedidos_compra_itens i
+		JOIN pedidos_compra pc ON pc.id = i.pedido_id
+		LEFT JOIN fornecedores f ON f.nome = pc.fornecedor
+		WHERE i.produto_id = ANY($1) AND pc.status IN ('rascunho', 'enviado', 'recebido_parcial')
+		  AND i.quantidade > i.quantidade_recebida
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, ids, prazoPadrao)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	recebimentos := map[int][]recebimentoPrevisto{}
+	for rows.Next() {
+		var id int
+		var r recebimentoPrevisto
+		if err := rows.Scan(&id, &r.data, &r.quantidade); err != nil {
+			return nil, nil, err
+		}
+		recebimentos[id] = append(recebimentos[id], r)
+	}
+	return produtos, recebimentos, rows.Err()
+}
+
+// Cria um pedido de compra em rascunho por fornecedor com as necessidades
+// líquidas dos produtos comprados
+func gerarPedidosMRP(ctx context.Context, necessidades []NecessidadeMRP) ([]PedidoCompra, []string, error) {
+	var avisos []string
+	porFornecedor := map[string]map[int]int{}
+	for _, n := range necessidades {
+		if n.Fabricado || n.Liquida <= 0 {
+			continue
+		}
+		if n.Fornecedor == "" {
+			avisos = append(avisos, fmt.Sprintf("Produto %s sem fornecedor: necessidade de %d não entrou nos pedidos", n.ProdutoCodigo, n.Liquida))
+			continue
+		}
+		if porFornecedor[n.Fornecedor] == nil {
+			porFornecedor[n.Fornecedor] = map[int]int{}
+		}
+		porFornecedor[n.Fornecedor][n.ProdutoID] += n.Liquida
+	}
+
+	fornecedores := make([]string, 0, len(porFornecedor))
+	for f := range porFornecedor {
+		fornecedores = append(fornecedores, f)
+	}
+	sort.Strings(fornecedores)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	pedidos := []PedidoCompra{}
+	for _, fornecedor := range fornecedores {
+		p := PedidoCompra{
+			Fornecedor: fornecedor,
+			Status:     PedidoCompraRascunho,
+			Notas:      "Gerado pelo planejamento de necessidades (MRP) em " + time.Now().Format("02/01/2006"),
+		}
+		for produtoID, quantidade := range porFornecedor[fornecedor] {
+			p.Itens = append(p.Itens, PedidoCompraItem{ProdutoID: produtoID, Quantidade: quantidade})
+		}
+		sort.Slice(p.Itens, func(a, b int) bool { return p.Itens[a].ProdutoID < p.Itens[b].ProdutoID })
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO pedidos_compra(fornecedor, status, notas)
+			VALUES ($1, $2, $3)
+			RETURNING id, data_criacao
+		`, p.Fornecedor, p.Status, p.Notas).Scan(&p.ID, &p.DataCriacao)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = inserirItensPedidoCompra(ctx, tx, p.ID, p.Itens); err != nil {
+			return nil, nil, err
+		}
+		if p.Itens, err = carregarItensPedidoCompra(ctx, tx, p.ID); err != nil {
+			return nil, nil, err
+		}
+		pedidos = append(pedidos, p)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return pedidos, avisos, nil
+}
+
+// Handler do MRP
+
+func calcularMRPHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var plano PlanoProducao
+	if err := c.ShouldBindJSON(&plano); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if len(plano.Itens) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O plano deve ter pelo menos um item"})
+		return
+	}
+	for _, item := range plano.Itens {
+		if item.ProdutoID <= 0 || item.Quantidade <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cada item do plano precisa de produto e quantidade positiva"})
+			return
+		}
+		if _, err := time.Parse(formatoData, item.Data); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Data do plano inválida, use o formato AAAA-MM-DD"})
+			return
+		}
+	}
+
+	log.Printf("[API] Calculando MRP para %d itens do plano de produção", len(plano.Itens))
+
+	estruturas, err := carregarEstruturas(ctx, db)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar estruturas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar estruturas"})
+		return
+	}
+
+	// Todos os produtos alcançados pelo plano
+	ids := []int{}
+	vistos := map[int]bool{}
+	for _, item := range plano.Itens {
+		pilha := []int{item.ProdutoID}
+		for len(pilha) > 0 {
+			id := pilha[len(pilha)-1]
+			pilha = pilha[:len(pilha)-1]
+			if vistos[id] {
+				continue
+			}
+			vistos[id] = true
+			ids = append(ids, id)
+			for _, comp := range estruturas[id] {
+				pilha = append(pilha, comp.ComponenteID)
+			}
+		}
+	}
+
+	produtos, recebimentos, err := carregarDadosMRP(ctx, ids)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar dados do MRP: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar dados do MRP"})
+		return
+	}
+	for _, item := range plano.Itens {
+		if _, ok := produtos[item.ProdutoID]; !ok {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("Produto %d do plano não encontrado", item.ProdutoID)})
+			return
+		}
+	}
+
+	agora := time.Now()
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, time.UTC)
+	necessidades, err := calcularMRP(plano.Itens, estruturas, produtos, recebimentos, hoje)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao calcular MRP: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao calcular MRP: " + err.Error()})
+		return
+	}
+
+	resultado := ResultadoMRP{Necessidades: necessidades}
+	if plano.GerarPedidos {
+		resultado.Pedidos, resultado.Avisos, err = gerarPedidosMRP(ctx, necessidades)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao gerar pedidos do MRP: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar pedidos de compra"})
+			return
+		}
+		log.Printf("[DB] MRP gerou %d pedidos de compra em rascunho", len(resultado.Pedidos))
+	}
+
+	c.JSON(http.StatusOK, resultado)
+}
@@ -0,0 +1,233 @@
+// notificacoes.go - Notificações internas do app com marcação de leitura
+//
+// Um inscrito do barramento cria as notificações: alertas de estoque baixo
+// para todos (destinatario nulo, no máximo um por produto a cada
+// notificacoesAlertaIntervalo) e menções em comentários para cada nome
+// mencionado. Sem usuários autenticados, o destinatário é o mesmo nome usado
+// nas menções e no registro do dispositivo. A leitura é registrada por
+// destinatário em notificacoes_leituras, o que vale também para os alertas
+// compartilhados. O push continua com os workers de cada canal.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de notificação
+const (
+	NotificacaoAlerta = "alerta"
+	NotificacaoMencao = "mencao"
+	NotificacaoTarefa = "tarefa" // gravada por tarefas.go ao atribuir uma tarefa
+)
+
+// Intervalo mínimo entre alertas do mesmo produto
+const notificacoesAlertaIntervalo = 12 * time.Hour
+
+type Notificacao struct {
+	ID           int       `json:"id"`
+	Destinatario string    `json:"destinatario,omitempty"`
+	Tipo         string    `json:"tipo"`
+	Titulo       string    `json:"titulo"`
+	Texto        string    `json:"texto"`
+	Entidade     string    `json:"entidade,omitempty"`
+	EntidadeID   int       `json:"entidade_id,omitempty"`
+	Lida         bool      `json:"lida"`
+	DataCriacao  time.Time `json:"data_criacao"`
+}
+
+type ListaNotificacoes struct {
+	Itens    []Notificacao `json:"itens"`
+	NaoLidas int           `json:"nao_lidas"`
+}
+
+// Grava as notificações geradas por um evento do barramento
+func registrarNotificacoesDoEvento(ctx context.Context, e Evento) error {
+	switch dados := e.Dados.(type) {
+	case AlertaEstoqueBaixo:
+		_, err := db.Exec(ctx, `
+			INSERT INTO notificacoes(tipo, titulo, texto, entidade, entidade_id)
+			SELECT $1, $2, $3, 'produto', $4
+			WHERE NOT EXISTS (
+				SELECT 1 FROM notificacoes
+				WHERE tipo = $1 AND entidade = 'produto' AND entidade_id = $4
+					AND data_criacao > CURRENT_TIMESTAMP - $5::interval
+			)
+		`, NotificacaoAlerta, "Estoque baixo",
+			dados.Codigo+" "+dados.Nome+": "+strconv.Itoa(dados.Quantidade)+" unidades (mínimo "+strconv.Itoa(dados.QuantidadeMinima)+")",
+			dados.ProdutoID, notificacoesAlertaIntervalo.String())
+		return err
+
+	case Comentario:
+		for _, nome := range dados.Mencoes {
+			_, err := db.Exec(ctx, `
+				INSERT INTO notificacoes(destinatario, tipo, titulo, texto, entidade, entidade_id)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, nome, NotificacaoMencao, dados.Autor+" mencionou você", dados.Texto, dados.Entidade, dados.EntidadeID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Assina o barramento e grava as notificações; roda até o contexto ser cancelado
+func iniciarNotificacoes(ctx context.Context) {
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			if err := registrarNotificacoesDoEvento(ctx, e); err != nil {
+				log.Printf("[ERROR] Erro ao registrar notificação (%s): %v", e.Tipo, err)
+			}
+		}
+	}
+}
+
+// Destinatário informado em ?destinatario=, normalizado como nas menções
+func destinatarioNotificacoes(c *gin.Context) string {
+	return strings.ToLower(strings.TrimSpace(c.Query("destinatario")))
+}
+
+// Handlers de Notificações
+
+func getNotificacoes(c *gin.Context) {
+	destinatario := destinatarioNotificacoes(c)
+	if destinatario == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe o destinatário"})
+		return
+	}
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "50"))
+	if err != nil || limite <= 0 {
+		limite = 50
+	}
+	somenteNaoLidas := c.Query("nao_lidas") == "true"
+
+	log.Printf("[DB] Buscando notificações de %s", destinatario)
+	ctx := c.Request.Context()
+
+	rows, err := db.Query(ctx, `
+		SELECT n.id, n.destinatario, n.tipo, n.titulo, n.texto, n.entidade, n.entidade_id,
+			l.notificacao_id IS NOT NULL AS lida, n.data_criacao
+		FROM notificacoes n
+		LEFT JOIN notificacoes_leituras l ON l.notificacao_id = n.id AND l.destinatario = $1
+		WHERE (n.destinatario IS NULL OR n.destinatario = $1)
+			AND (NOT $2 OR l.notificacao_id IS NULL)
+		ORDER BY n.id DESC
+		LIMIT $3
+	`, destinatario, somenteNaoLidas, limite)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar notificações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar notificações"})
+		return
+	}
+	defer rows.Close()
+
+	lista := ListaNotificacoes{Itens: []Notificacao{}}
+	for rows.Next() {
+		var n Notificacao
+		var dest, entidade *string
+		var entidadeID *int
+		if err := rows.Scan(&n.ID, &dest, &n.Tipo, &n.Titulo, &n.Texto, &entidade, &entidadeID, &n.Lida, &n.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar notificação: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if dest != nil {
+			n.Destinatario = *dest
+		}
+		if entidade != nil {
+			n.Entidade = *entidade
+		}
+		if entidadeID != nil {
+			n.EntidadeID = *entidadeID
+		}
+		lista.Itens = append(lista.Itens, n)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar notificações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar notificações"})
+		return
+	}
+
+	err = db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notificacoes n
+		WHERE (n.destinatario IS NULL OR n.destinatario = $1)
+			AND NOT EXISTS (SELECT 1 FROM notificacoes_leituras l WHERE l.notificacao_id = n.id AND l.destinatario = $1)
+	`, destinatario).Scan(&lista.NaoLidas)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao contar notificações não lidas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao contar notificações"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lista)
+}
+
+func marcarNotificacaoLida(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+	destinatario := destinatarioNotificacoes(c)
+	if destinatario == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe o destinatário"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), `
+		INSERT INTO notificacoes_leituras(notificacao_id, destinatario)
+		SELECT id, $2 FROM notificacoes
+		WHERE id = $1 AND (destinatario IS NULL OR destinatario = $2)
+		ON CONFLICT DO NOTHING
+	`, id, destinatario)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao marcar notificação como lida: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao marcar notificação como lida"})
+		return
+	}
+
+	log.Printf("[DB] Notificação %d marcada como lida por %s (%d)", id, destinatario, tag.RowsAffected())
+	c.JSON(http.StatusOK, gin.H{"message": "Notificação marcada como lida"})
+}
+
+func marcarTodasNotificacoesLidas(c *gin.Context) {
+	destinatario := destinatarioNotificacoes(c)
+	if destinatario == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe o destinatário"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), `
+		INSERT INTO notificacoes_leituras(notificacao_id, destinatario)
+		SELECT id, $1 FROM notificacoes
+		WHERE destinatario IS NULL OR destinatario = $1
+		ON CONFLICT DO NOTHING
+	`, destinatario)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao marcar notificações como lidas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao marcar notificações como lidas"})
+		return
+	}
+
+	log.Printf("[DB] %d notificações marcadas como lidas por %s", tag.RowsAffected(), destinatario)
+	c.JSON(http.StatusOK, gin.H{"marcadas": tag.RowsAffected()})
+}
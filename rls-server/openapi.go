@@ -0,0 +1,592 @@
+// openapi.go - Especificação OpenAPI 3 e Swagger UI
+//
+// A especificação é montada no startup a partir das rotas registradas no
+// router e da tabela docsOperacoes, mantida à mão ao lado das rotas: resumo,
+// grupo, parâmetros de consulta e os tipos Go de requisição e resposta. Os
+// esquemas saem desses tipos por reflexão (tags json; omitempty = opcional),
+// então mudanças nos structs aparecem na especificação sem edição extra.
+// Toda operação documenta o formato de erro Problema (problem+json).
+//
+// GET /api/v1/docs/openapi.json devolve a especificação e GET /api/v1/docs
+// abre o Swagger UI (os arquivos da interface vêm do CDN do swagger-ui-dist).
+// A tabela usa os caminhos sem versão; a especificação publica só os
+// caminhos versionados (ver versoes.go).
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Versão da API publicada na especificação
+const versaoAPI = "1.0.0"
+
+// Documentação de uma operação; Requisicao e Resposta são valores de exemplo
+// dos tipos Go (nil = sem corpo)
+type docOperacao struct {
+	Resumo     string
+	Grupo      string
+	Consulta   []string // parâmetros de consulta opcionais
+	Requisicao any
+	Resposta   any
+	Status     int               // status de sucesso; padrão 200
+	Conteudo   string            // tipo da resposta quando não é JSON
+	Upload     bool              // requisição multipart com o campo "arquivo"
+	Parametros map[string]string // parâmetros de rota documentados com outro nome (o gin exige o mesmo nome nas rotas irmãs)
+}
+
+// Corpo das respostas simples com mensagem
+type respostaMensagem struct {
+	Message string `json:"message"`
+}
+
+var docsOperacoes = map[string]docOperacao{
+	// Produtos
+	"GET /api/produtos":                          {Resumo: "Lista produtos", Grupo: "Produtos", Consulta: []string{"limit", "offset", "categoria", "atributos[nome]", "tags"}, Resposta: []Produto{}},
+	"GET /api/produtos/:id":                      {Resumo: "Busca produto por ID", Grupo: "Produtos", Resposta: Produto{}},
+	"POST /api/produtos":                         {Resumo: "Cria produto", Grupo: "Produtos", Requisicao: Produto{}, Resposta: Produto{}, Status: http.StatusCreated},
+	"PUT /api/produtos/:id":                      {Resumo: "Substitui o cadastro do produto", Grupo: "Produtos", Requisicao: AtualizacaoProduto{}, Resposta: Produto{}},
+	"PATCH /api/produtos/:id":                    {Resumo: "Altera campos do produto", Grupo: "Produtos", Requisicao: PatchProduto{}, Resposta: Produto{}},
+	"PATCH /api/produtos/lote":                   {Resumo: "Altera produtos em massa", Grupo: "Produtos", Requisicao: AtualizacaoLoteProdutos{}, Resposta: []ResultadoProdutoLote{}},
+	"DELETE /api/produtos/:id":                   {Resumo: "Exclui produto", Grupo: "Produtos", Resposta: respostaMensagem{}},
+	"GET /api/produtos/codigo/:codigo":           {Resumo: "Busca produto pelo código (resolve códigos antigos)", Grupo: "Produtos", Resposta: ProdutoResolvido{}},
+	"GET /api/produtos/estoque-baixo":            {Resumo: "Produtos abaixo do mínimo", Grupo: "Produtos", Resposta: []Produto{}},
+	"GET /api/produtos/codigos/fora-do-padrao":   {Resumo: "Códigos fora do formato definido", Grupo: "Códigos", Resposta: []CodigoForaPadrao{}},
+	"POST /api/produtos/codigos/renomear":        {Resumo: "Renomeia códigos em massa", Grupo: "Códigos", Requisicao: RequisicaoRenomearCodigos{}, Resposta: ResultadoRenomearCodigos{}},
+	"GET /api/produtos/codigos/proximo":          {Resumo: "Próximo código livre do prefixo", Grupo: "Produtos", Consulta: []string{"prefixo"}, Resposta: SugestaoCodigo{}},
+	"GET /api/produtos/:id/aliases":              {Resumo: "Códigos antigos do produto", Grupo: "Códigos", Resposta: []AliasCodigo{}},
+	"POST /api/produtos/:id/aliases":             {Resumo: "Cadastra código antigo do produto", Grupo: "Códigos", Requisicao: AliasCodigo{}, Resposta: AliasCodigo{}, Status: http.StatusCreated},
+	"DELETE /api/aliases-codigos/:id":            {Resumo: "Exclui código antigo", Grupo: "Códigos", Resposta: respostaMensagem{}},
+	"GET /api/produtos/:id/codigos-barras":       {Resumo: "Códigos de barras do produto", Grupo: "Códigos", Resposta: []CodigoBarras{}},
+	"POST /api/produtos/:id/codigos-barras":      {Resumo: "Cadastra código de barras", Grupo: "Códigos", Requisicao: CodigoBarras{}, Resposta: CodigoBarras{}, Status: http.StatusCreated},
+	"DELETE /api/codigos-barras/:id":             {Resumo: "Exclui código de barras", Grupo: "Códigos", Resposta: respostaMensagem{}},
+	"GET /api/produtos/:id/precos":               {Resumo: "Histórico do preço de custo", Grupo: "Produtos", Resposta: []HistoricoPreco{}},
+	"GET /api/produtos/:id/previsao":             {Resumo: "Previsão de consumo e ruptura", Grupo: "Produtos", Consulta: []string{"dias", "horizonte", "metodo", "alpha", "janela"}, Resposta: PrevisaoConsumo{}},
+	"GET /api/produtos/:id/historico-estoque":    {Resumo: "Saldo diário do produto", Grupo: "Produtos", Consulta: []string{"de", "ate", "dias"}, Resposta: []PontoEstoque{}},
+	"GET /api/produtos/:id/disponibilidade-rede": {Resumo: "Saldo do produto nas filiais", Grupo: "Filiais", Parametros: map[string]string{"id": "codigo"}},
+	"GET /api/produtos/:id/etiqueta":             {Resumo: "Etiqueta do produto", Grupo: "Etiquetas", Consulta: []string{"formato", "tipo"}, Conteudo: "image/png"},
+	"GET /api/produtos/:id/embalagens":           {Resumo: "Embalagens de fornecedor do produto", Grupo: "Produtos", Resposta: []EmbalagemFornecedor{}},
+	"POST /api/produtos/:id/embalagens":          {Resumo: "Cadastra embalagem de fornecedor", Grupo: "Produtos", Requisicao: EmbalagemFornecedor{}, Resposta: EmbalagemFornecedor{}, Status: http.StatusCreated},
+	"DELETE /api/embalagens/:id":                 {Resumo: "Exclui embalagem de fornecedor", Grupo: "Produtos", Resposta: respostaMensagem{}},
+	"GET /api/produtos/:id/estrutura":            {Resumo: "Estrutura (lista de materiais) do produto", Grupo: "Produtos", Resposta: []ComponenteEstrutura{}},
+	"PUT /api/produtos/:id/estrutura":            {Resumo: "Substitui a estrutura do produto", Grupo: "Produtos", Requisicao: []ComponenteEstrutura{}, Resposta: []ComponenteEstrutura{}},
+	"GET /api/produtos/:id/disponibilidade":      {Resumo: "Disponibilidade pelo estoque dos componentes (kits e montagem)", Grupo: "Produtos", Resposta: DisponibilidadeProduto{}},
+	"GET /api/produtos/:id/variantes":            {Resumo: "Variantes do produto (ou irmãs, para uma variante)", Grupo: "Produtos", Resposta: []Produto{}},
+	"POST /api/produtos/:id/duplicar":            {Resumo: "Duplica o produto com outro código (sugerido pelo prefixo se omitido)", Grupo: "Produtos", Requisicao: RequisicaoDuplicacao{}, Resposta: Produto{}, Status: http.StatusCreated},
+	"POST /api/produtos/:id/montagem":            {Resumo: "Monta o produto a partir dos componentes", Grupo: "Produtos", Requisicao: RequisicaoMontagem{}, Resposta: ResultadoMontagem{}, Status: http.StatusCreated},
+	"GET /api/produtos/:id/lotes":                {Resumo: "Lotes do produto", Grupo: "Lotes e séries", Resposta: []Lote{}},
+	"GET /api/produtos/:id/series":               {Resumo: "Números de série do produto", Grupo: "Lotes e séries", Resposta: []UnidadeSerie{}},
+	"GET /api/lotes/vencendo":                    {Resumo: "Lotes próximos do vencimento", Grupo: "Lotes e séries", Consulta: []string{"dias"}, Resposta: []Lote{}},
+	"GET /api/series/:numero":                    {Resumo: "Rastreia um número de série", Grupo: "Lotes e séries", Resposta: []UnidadeSerie{}},
+
+	// Unidades
+	"GET /api/unidades":                            {Resumo: "Unidades de medida", Grupo: "Unidades", Resposta: []UnidadeMedida{}},
+	"GET /api/conversoes":                          {Resumo: "Conversões de unidade", Grupo: "Unidades", Resposta: []ConversaoUnidade{}},
+	"POST /api/conversoes":                         {Resumo: "Cadastra conversão de unidade", Grupo: "Unidades", Requisicao: ConversaoUnidade{}, Resposta: ConversaoUnidade{}, Status: http.StatusCreated},
+	"GET /api/produtos/:id/conversoes":             {Resumo: "Conversões de unidade do produto", Grupo: "Unidades", Resposta: []ConversaoUnidade{}},
+	"POST /api/produtos/:id/conversoes":            {Resumo: "Cadastra conversão do produto", Grupo: "Unidades", Requisicao: ConversaoUnidade{}, Resposta: ConversaoUnidade{}, Status: http.StatusCreated},
+	"DELETE /api/conversoes/:id":                   {Resumo: "Exclui conversão de unidade", Grupo: "Unidades", Resposta: respostaMensagem{}},
+	"GET /api/scan/*codigo":                        {Resumo: "Resolve a leitura do scanner", Grupo: "Scanner", Resposta: ResultadoScan{}},
+	"GET /api/consulta/:codigo":                    {Resumo: "Consulta rápida de saldo", Grupo: "Scanner", Resposta: ConsultaSaldo{}},
+	"POST /api/etiquetas/lote":                     {Resumo: "Etiquetas de vários produtos em PDF", Grupo: "Etiquetas", Requisicao: LoteEtiquetas{}, Conteudo: "application/pdf"},
+	"POST /api/sync/handshake":                     {Resumo: "Mede o relógio do coletor", Grupo: "Movimentações", Requisicao: HandshakeSync{}, Resposta: RespostaHandshakeSync{}},
+	"POST /api/sync/lancamentos":                   {Resumo: "Sincroniza lançamentos offline com a data ajustada", Grupo: "Movimentações", Requisicao: RequisicaoSync{}, Resposta: ResultadoSync{}},
+	"GET /api/sync/dispositivos":                   {Resumo: "Desvio de relógio dos coletores", Grupo: "Movimentações", Resposta: []DispositivoSync{}},
+	"GET /api/impressoras":                         {Resumo: "Lista impressoras", Grupo: "Etiquetas", Resposta: []Impressora{}},
+	"POST /api/impressoras":                        {Resumo: "Cadastra impressora", Grupo: "Etiquetas", Requisicao: Impressora{}, Resposta: Impressora{}, Status: http.StatusCreated},
+	"PUT /api/impressoras/:id":                     {Resumo: "Atualiza impressora", Grupo: "Etiquetas", Requisicao: Impressora{}, Resposta: Impressora{}},
+	"DELETE /api/impressoras/:id":                  {Resumo: "Exclui impressora e seus trabalhos", Grupo: "Etiquetas"},
+	"GET /api/setores-usuarios":                    {Resumo: "Setores dos usuários", Grupo: "Etiquetas", Resposta: []SetorUsuario{}},
+	"PUT /api/setores-usuarios/:usuario":           {Resumo: "Define o setor do usuário", Grupo: "Etiquetas", Requisicao: SetorUsuario{}, Resposta: SetorUsuario{}},
+	"GET /api/impressao/trabalhos":                 {Resumo: "Fila de impressão", Grupo: "Etiquetas", Consulta: []string{"status", "impressora_id", "limite"}, Resposta: []TrabalhoImpressao{}},
+	"POST /api/impressao/trabalhos":                {Resumo: "Enfileira etiquetas na impressora do setor", Grupo: "Etiquetas", Requisicao: NovoTrabalhoImpressao{}, Resposta: TrabalhoImpressao{}, Status: http.StatusCreated},
+	"POST /api/impressao/trabalhos/:id/reimprimir": {Resumo: "Reenvia um trabalho de impressão", Grupo: "Etiquetas", Resposta: TrabalhoImpressao{}},
+	"GET /api/estoque-seguranca/simulacao":         {Resumo: "Simula o estoque de segurança", Grupo: "Reposição", Consulta: []string{"dias", "nivel_servico", "produto_id"}, Resposta: SimulacaoEstoqueSeguranca{}},
+	"POST /api/estoque-seguranca/aplicar":          {Resumo: "Aplica as quantidades mínimas aceitas", Grupo: "Reposição", Requisicao: RequisicaoAplicarMinimos{}},
+
+	// Movimentações
+	"GET /api/movimentacoes":                     {Resumo: "Lista movimentações", Grupo: "Movimentações", Consulta: []string{"limit", "offset"}, Resposta: []MovimentacaoView{}},
+	"GET /api/movimentacoes/:id":                 {Resumo: "Busca movimentação", Grupo: "Movimentações", Resposta: MovimentacaoView{}},
+	"POST /api/movimentacoes":                    {Resumo: "Registra movimentação", Grupo: "Movimentações", Requisicao: Movimentacao{}, Resposta: Movimentacao{}, Status: http.StatusCreated},
+	"POST /api/movimentacoes/lote":               {Resumo: "Registra várias movimentações", Grupo: "Movimentações", Requisicao: []Movimentacao{}, Resposta: ResultadoLoteMovimentacoes{}, Status: http.StatusCreated},
+	"GET /api/movimentacoes/produto/:produto_id": {Resumo: "Movimentações do produto", Grupo: "Movimentações", Resposta: []Movimentacao{}},
+	"GET /api/movimentacoes/:id/anexos":          {Resumo: "Anexos da movimentação", Grupo: "Anexos", Resposta: []Anexo{}},
+	"POST /api/movimentacoes/:id/anexos":         {Resumo: "Envia anexo da movimentação", Grupo: "Anexos", Upload: true, Resposta: Anexo{}, Status: http.StatusCreated},
+	"GET /api/anexos/:id/download":               {Resumo: "Baixa anexo (link assinado)", Grupo: "Anexos", Consulta: []string{"expira", "assinatura"}, Conteudo: "application/octet-stream"},
+	"DELETE /api/anexos/:id":                     {Resumo: "Exclui anexo", Grupo: "Anexos", Resposta: respostaMensagem{}},
+
+	// Pedidos
+	"GET /api/pedidos-compra":                         {Resumo: "Lista pedidos de compra", Grupo: "Pedidos de compra", Resposta: []PedidoCompra{}},
+	"GET /api/pedidos-compra/:id":                     {Resumo: "Busca pedido de compra", Grupo: "Pedidos de compra", Resposta: PedidoCompra{}},
+	"POST /api/pedidos-compra":                        {Resumo: "Cria pedido de compra", Grupo: "Pedidos de compra", Requisicao: PedidoCompra{}, Resposta: PedidoCompra{}, Status: http.StatusCreated},
+	"PUT /api/pedidos-compra/:id":                     {Resumo: "Altera pedido de compra em rascunho", Grupo: "Pedidos de compra", Requisicao: PedidoCompra{}, Resposta: PedidoCompra{}},
+	"DELETE /api/pedidos-compra/:id":                  {Resumo: "Exclui pedido de compra", Grupo: "Pedidos de compra", Resposta: respostaMensagem{}},
+	"POST /api/pedidos-compra/:id/enviar":             {Resumo: "Envia pedido ao fornecedor", Grupo: "Pedidos de compra"},
+	"POST /api/pedidos-compra/:id/receber":            {Resumo: "Recebe itens do pedido", Grupo: "Pedidos de compra", Requisicao: RecebimentoPedido{}},
+	"GET /api/pedidos-saida":                          {Resumo: "Lista pedidos de saída", Grupo: "Pedidos de saída", Resposta: []PedidoSaida{}},
+	"GET /api/pedidos-saida/:id":                      {Resumo: "Busca pedido de saída", Grupo: "Pedidos de saída", Resposta: PedidoSaida{}},
+	"POST /api/pedidos-saida":                         {Resumo: "Cria pedido de saída", Grupo: "Pedidos de saída", Requisicao: PedidoSaida{}, Resposta: PedidoSaida{}, Status: http.StatusCreated},
+	"GET /api/pedidos-saida/:id/separacao":            {Resumo: "Lista de separação (picking)", Grupo: "Pedidos de saída"},
+	"POST /api/pedidos-saida/:id/confirmar-separacao": {Resumo: "Confirma a separação e baixa o estoque", Grupo: "Pedidos de saída", Requisicao: ConfirmacaoSeparacao{}},
+	"POST /api/pedidos-saida/:id/cancelar":            {Resumo: "Cancela pedido de saída", Grupo: "Pedidos de saída", Resposta: respostaMensagem{}},
+
+	// Reposição e fornecedores
+	"GET /api/reposicao/sugestoes":                          {Resumo: "Sugestões de reposição", Grupo: "Reposição", Consulta: []string{"dias", "cobertura", "agrupar"}, Resposta: []SugestaoReposicao{}},
+	"GET /api/grupos-reposicao":                             {Resumo: "Grupos de reposição (teste A/B)", Grupo: "Reposição", Resposta: []GrupoReposicao{}},
+	"POST /api/grupos-reposicao":                            {Resumo: "Cria grupo de reposição", Grupo: "Reposição", Requisicao: GrupoReposicao{}, Resposta: GrupoReposicao{}, Status: http.StatusCreated},
+	"PUT /api/grupos-reposicao/:id":                         {Resumo: "Altera grupo de reposição", Grupo: "Reposição", Requisicao: GrupoReposicao{}, Resposta: GrupoReposicao{}},
+	"DELETE /api/grupos-reposicao/:id":                      {Resumo: "Exclui grupo de reposição", Grupo: "Reposição", Resposta: respostaMensagem{}},
+	"POST /api/grupos-reposicao/:id/produtos":               {Resumo: "Inclui produtos no grupo", Grupo: "Reposição", Requisicao: ProdutosGrupoReposicao{}},
+	"DELETE /api/grupos-reposicao/:id/produtos/:produto_id": {Resumo: "Retira produto do grupo", Grupo: "Reposição", Resposta: respostaMensagem{}},
+
+	// Localizações
+	"GET /api/localizacoes":              {Resumo: "Posições do almoxarifado", Grupo: "Localizações", Consulta: []string{"corredor", "deposito"}, Resposta: []Localizacao{}},
+	"GET /api/localizacoes/mapa":         {Resumo: "Mapa do almoxarifado por corredor e prateleira", Grupo: "Localizações", Resposta: MapaAlmoxarifado{}},
+	"GET /api/localizacoes/:id":          {Resumo: "Busca posição por ID", Grupo: "Localizações", Resposta: Localizacao{}},
+	"POST /api/localizacoes":             {Resumo: "Cria posição", Grupo: "Localizações", Requisicao: Localizacao{}, Resposta: Localizacao{}, Status: http.StatusCreated},
+	"PUT /api/localizacoes/:id":          {Resumo: "Altera posição", Grupo: "Localizações", Requisicao: Localizacao{}, Resposta: Localizacao{}},
+	"DELETE /api/localizacoes/:id":       {Resumo: "Exclui posição sem produtos", Grupo: "Localizações", Resposta: respostaMensagem{}},
+	"GET /api/localizacoes/:id/produtos": {Resumo: "Produtos guardados na posição", Grupo: "Localizações", Resposta: []Produto{}},
+
+	// Atributos
+	"GET /api/atributos":        {Resumo: "Atributos definidos por categoria", Grupo: "Produtos", Consulta: []string{"categoria"}, Resposta: []Atributo{}},
+	"GET /api/atributos/:id":    {Resumo: "Busca atributo por ID", Grupo: "Produtos", Resposta: Atributo{}},
+	"POST /api/atributos":       {Resumo: "Define atributo da categoria", Grupo: "Produtos", Requisicao: Atributo{}, Resposta: Atributo{}, Status: http.StatusCreated},
+	"PUT /api/atributos/:id":    {Resumo: "Altera rótulo, tipo, opções e obrigatoriedade do atributo", Grupo: "Produtos", Requisicao: Atributo{}, Resposta: Atributo{}},
+	"DELETE /api/atributos/:id": {Resumo: "Exclui atributo e remove os valores dos produtos", Grupo: "Produtos", Resposta: respostaMensagem{}},
+
+	// Tags
+	"GET /api/tags":                      {Resumo: "Lista tags com a contagem de produtos", Grupo: "Produtos", Resposta: []Tag{}},
+	"GET /api/tags/:id":                  {Resumo: "Busca tag por ID", Grupo: "Produtos", Resposta: Tag{}},
+	"POST /api/tags":                     {Resumo: "Cria tag", Grupo: "Produtos", Requisicao: Tag{}, Resposta: Tag{}, Status: http.StatusCreated},
+	"PUT /api/tags/:id":                  {Resumo: "Renomeia tag ou altera a cor", Grupo: "Produtos", Requisicao: Tag{}, Resposta: Tag{}},
+	"DELETE /api/tags/:id":               {Resumo: "Exclui tag e as atribuições", Grupo: "Produtos", Resposta: respostaMensagem{}},
+	"GET /api/produtos/:id/tags":         {Resumo: "Tags do produto", Grupo: "Produtos", Resposta: []Tag{}},
+	"PUT /api/produtos/:id/tags":         {Resumo: "Troca as tags do produto", Grupo: "Produtos", Requisicao: TagsProduto{}, Resposta: []Tag{}},
+	"POST /api/produtos/:id/tags":        {Resumo: "Acrescenta tags ao produto", Grupo: "Produtos", Requisicao: TagsProduto{}, Resposta: []Tag{}},
+	"DELETE /api/produtos/:id/tags/:tag": {Resumo: "Retira a tag do produto", Grupo: "Produtos", Resposta: respostaMensagem{}},
+	"POST /api/mrp/calcular":             {Resumo: "Necessidades de material do plano de produção", Grupo: "Reposição", Requisicao: PlanoProducao{}, Resposta: ResultadoMRP{}},
+	"GET /api/fornecedores":              {Resumo: "Lista fornecedores", Grupo: "Reposição", Resposta: []Fornecedor{}},
+	"PUT /api/fornecedores/:nome":        {Resumo: "Cria ou altera o prazo do fornecedor", Grupo: "Reposição", Requisicao: Fornecedor{}, Resposta: Fornecedor{}},
+
+	// Relatórios
+	"GET /api/relatorios/valorizacao":       {Resumo: "Valorização do estoque", Grupo: "Relatórios", Resposta: RelatorioValorizacao{}},
+	"GET /api/relatorios/abc":               {Resumo: "Curva ABC", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias"}, Resposta: RelatorioABC{}},
+	"GET /api/relatorios/movimentacoes":     {Resumo: "Movimentações por período", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias"}, Resposta: RelatorioMovimentacoes{}},
+	"GET /api/relatorios/destinacao":        {Resumo: "Destinação de descartes", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias"}, Resposta: RelatorioDestinacao{}},
+	"GET /api/relatorios/ambiental":         {Resumo: "Consumo mensal por classe ambiental", Grupo: "Relatórios", Consulta: []string{"mes"}, Resposta: RelatorioAmbiental{}},
+	"GET /api/relatorios/grupos-reposicao":  {Resumo: "Comparação dos grupos de reposição", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias"}, Resposta: RelatorioGruposReposicao{}},
+	"GET /api/relatorios/rede/estoque":      {Resumo: "Posição de estoque somada entre as filiais", Grupo: "Relatórios", Consulta: []string{"filial"}, Resposta: RelatorioEstoqueRede{}},
+	"GET /api/relatorios/rede/consumo":      {Resumo: "Consumo por produto nas filiais", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias", "filial"}, Resposta: RelatorioConsumoRede{}},
+	"GET /api/relatorios/estoque.pdf":       {Resumo: "Posição de estoque em PDF", Grupo: "Relatórios", Conteudo: "application/pdf"},
+	"GET /api/relatorios/movimentacoes.pdf": {Resumo: "Movimentações em PDF", Grupo: "Relatórios", Consulta: []string{"de", "ate", "dias"}, Conteudo: "application/pdf"},
+	"GET /api/dashboard":                    {Resumo: "Dados do dashboard", Grupo: "Dashboard", Consulta: []string{"widgets"}, Resposta: DashboardData{}},
+	"GET /api/dashboard/graficos":           {Resumo: "Séries dos gráficos do dashboard", Grupo: "Dashboard", Consulta: []string{"de", "ate", "dias"}, Resposta: DashboardGraficos{}},
+
+	// Descarte
+	"GET /api/locais-descarte":     {Resumo: "Locais de descarte", Grupo: "Descarte", Resposta: []LocalDescarte{}},
+	"POST /api/locais-descarte":    {Resumo: "Cadastra local de descarte", Grupo: "Descarte", Requisicao: LocalDescarte{}, Resposta: LocalDescarte{}, Status: http.StatusCreated},
+	"PUT /api/locais-descarte/:id": {Resumo: "Altera local de descarte", Grupo: "Descarte", Requisicao: LocalDescarte{}, Resposta: LocalDescarte{}},
+
+	// Configurações e empresa
+	"GET /api/configuracoes":         {Resumo: "Lista configurações", Grupo: "Configurações", Resposta: []Configuracao{}},
+	"GET /api/configuracoes/:chave":  {Resumo: "Busca configuração", Grupo: "Configurações", Resposta: Configuracao{}},
+	"PUT /api/configuracoes/:chave":  {Resumo: "Altera configuração", Grupo: "Configurações", Requisicao: Configuracao{}, Resposta: Configuracao{}},
+	"GET /api/configuracoes/export":  {Resumo: "Exporta configurações", Grupo: "Configurações", Resposta: ExportConfiguracoes{}},
+	"POST /api/configuracoes/import": {Resumo: "Importa configurações", Grupo: "Configurações", Consulta: []string{"simular"}, Requisicao: ExportConfiguracoes{}, Resposta: ResultadoImportConfiguracoes{}},
+	"GET /api/empresa":               {Resumo: "Perfil da empresa", Grupo: "Empresa", Resposta: PerfilEmpresa{}},
+	"PUT /api/empresa":               {Resumo: "Altera o perfil da empresa", Grupo: "Empresa", Requisicao: PerfilEmpresa{}, Resposta: PerfilEmpresa{}},
+	"GET /api/empresa/logo":          {Resumo: "Logotipo da empresa", Grupo: "Empresa", Conteudo: "image/png"},
+	"POST /api/empresa/logo":         {Resumo: "Envia o logotipo", Grupo: "Empresa", Upload: true},
+	"DELETE /api/empresa/logo":       {Resumo: "Remove o logotipo", Grupo: "Empresa", Resposta: respostaMensagem{}},
+	"GET /api/setup":                 {Resumo: "Estado do assistente de primeira execução", Grupo: "Setup", Resposta: EstadoSetup{}},
+	"PUT /api/setup/empresa":         {Resumo: "Define os dados da empresa", Grupo: "Setup", Requisicao: EmpresaSetup{}, Resposta: EmpresaSetup{}},
+	"POST /api/setup/produtos":       {Resumo: "Importação inicial de produtos", Grupo: "Setup", Requisicao: []ProdutoSetup{}, Status: http.StatusCreated},
+	"POST /api/setup/concluir":       {Resumo: "Conclui o setup", Grupo: "Setup", Resposta: EstadoSetup{}},
+
+	// Colaboração
+	"GET /api/comentarios":  {Resumo: "Comentários de uma entidade", Grupo: "Comentários", Consulta: []string{"entidade", "entidade_id"}, Resposta: []Comentario{}},
+	"POST /api/comentarios": {Resumo: "Cria comentário", Grupo: "Comentários", Requisicao: Comentario{}, Resposta: Comentario{}, Status: http.StatusCreated},
+	"PUT /api/comentarios/:id": {Resumo: "Edita comentário", Grupo: "Comentários", Requisicao: struct {
+		Texto string `json:"texto"`
+	}{}, Resposta: Comentario{}},
+	"DELETE /api/comentarios/:id":            {Resumo: "Exclui comentário", Grupo: "Comentários", Resposta: respostaMensagem{}},
+	"GET /api/tarefas":                       {Resumo: "Lista tarefas", Grupo: "Tarefas", Consulta: []string{"status", "responsavel", "atrasadas"}, Resposta: []Tarefa{}},
+	"POST /api/tarefas":                      {Resumo: "Cria tarefa", Grupo: "Tarefas", Requisicao: Tarefa{}, Resposta: Tarefa{}, Status: http.StatusCreated},
+	"PUT /api/tarefas/:id":                   {Resumo: "Altera tarefa", Grupo: "Tarefas", Requisicao: AtualizacaoTarefa{}, Resposta: Tarefa{}},
+	"DELETE /api/tarefas/:id":                {Resumo: "Exclui tarefa", Grupo: "Tarefas", Resposta: respostaMensagem{}},
+	"GET /api/checklists/perguntas":          {Resumo: "Perguntas de checklist", Grupo: "Checklists", Resposta: []PerguntaChecklist{}},
+	"POST /api/checklists/perguntas":         {Resumo: "Cria pergunta de checklist", Grupo: "Checklists", Requisicao: PerguntaChecklist{}, Resposta: PerguntaChecklist{}, Status: http.StatusCreated},
+	"PUT /api/checklists/perguntas/:id":      {Resumo: "Altera pergunta de checklist", Grupo: "Checklists", Requisicao: PerguntaChecklist{}, Resposta: PerguntaChecklist{}},
+	"POST /api/checklists":                   {Resumo: "Abre checklist de uma operação", Grupo: "Checklists", Resposta: Checklist{}, Status: http.StatusCreated},
+	"GET /api/checklists/:id":                {Resumo: "Busca checklist", Grupo: "Checklists", Resposta: Checklist{}},
+	"PUT /api/checklists/:id/itens/:item_id": {Resumo: "Responde item do checklist", Grupo: "Checklists", Resposta: Checklist{}},
+	"POST /api/checklists/:id/concluir":      {Resumo: "Conclui checklist", Grupo: "Checklists", Resposta: Checklist{}},
+	"GET /api/atividades":                    {Resumo: "Feed de atividade", Grupo: "Notificações", Consulta: []string{"limite", "cursor", "entidade", "entidade_id", "usuario", "tipo"}, Resposta: PaginaAtividades{}},
+	"GET /api/notificacoes":                  {Resumo: "Notificações do destinatário", Grupo: "Notificações", Consulta: []string{"destinatario", "limite", "nao_lidas"}, Resposta: ListaNotificacoes{}},
+	"POST /api/notificacoes/:id/lida":        {Resumo: "Marca notificação como lida", Grupo: "Notificações", Resposta: respostaMensagem{}},
+	"POST /api/notificacoes/lidas":           {Resumo: "Marca todas como lidas", Grupo: "Notificações", Consulta: []string{"destinatario"}},
+	"GET /api/alertas/assinantes":            {Resumo: "Assinantes dos alertas por e-mail", Grupo: "Notificações", Resposta: []AssinanteAlerta{}},
+	"POST /api/alertas/assinantes":           {Resumo: "Cadastra assinante", Grupo: "Notificações", Requisicao: AssinanteAlerta{}, Resposta: AssinanteAlerta{}, Status: http.StatusCreated},
+	"PUT /api/alertas/assinantes/:id":        {Resumo: "Altera assinante", Grupo: "Notificações", Requisicao: AssinanteAlerta{}, Resposta: AssinanteAlerta{}},
+	"DELETE /api/alertas/assinantes/:id":     {Resumo: "Exclui assinante", Grupo: "Notificações", Resposta: respostaMensagem{}},
+	"POST /api/alertas/resumo":               {Resumo: "Envia o resumo de estoque baixo agora", Grupo: "Notificações"},
+	"GET /api/dispositivos":                  {Resumo: "Dispositivos do push", Grupo: "Notificações", Resposta: []Dispositivo{}},
+	"POST /api/dispositivos":                 {Resumo: "Registra dispositivo", Grupo: "Notificações", Requisicao: Dispositivo{}, Resposta: Dispositivo{}, Status: http.StatusCreated},
+	"PUT /api/dispositivos/:id":              {Resumo: "Altera dispositivo", Grupo: "Notificações", Requisicao: Dispositivo{}, Resposta: Dispositivo{}},
+	"DELETE /api/dispositivos/:id":           {Resumo: "Remove dispositivo", Grupo: "Notificações", Resposta: respostaMensagem{}},
+
+	// Integrações
+	"GET /api/webhooks":              {Resumo: "Lista webhooks", Grupo: "Integrações", Resposta: []Webhook{}},
+	"POST /api/webhooks":             {Resumo: "Cadastra webhook", Grupo: "Integrações", Requisicao: Webhook{}, Resposta: Webhook{}, Status: http.StatusCreated},
+	"PUT /api/webhooks/:id":          {Resumo: "Altera webhook", Grupo: "Integrações", Requisicao: Webhook{}, Resposta: Webhook{}},
+	"DELETE /api/webhooks/:id":       {Resumo: "Exclui webhook", Grupo: "Integrações", Resposta: respostaMensagem{}},
+	"GET /api/webhooks/:id/entregas": {Resumo: "Entregas do webhook", Grupo: "Integrações", Resposta: []EntregaWebhook{}},
+	"GET /api/filiais":               {Resumo: "Lista filiais", Grupo: "Filiais", Resposta: []Filial{}},
+	"POST /api/filiais":              {Resumo: "Cadastra filial", Grupo: "Filiais", Requisicao: Filial{}, Resposta: Filial{}, Status: http.StatusCreated},
+	"PUT /api/filiais/:id":           {Resumo: "Altera filial", Grupo: "Filiais", Requisicao: Filial{}, Resposta: Filial{}},
+	"DELETE /api/filiais/:id":        {Resumo: "Exclui filial", Grupo: "Filiais", Resposta: respostaMensagem{}},
+
+	// Administração
+	"GET /api/admin/integracoes":               {Resumo: "Estado das integrações externas", Grupo: "Administração", Resposta: []StatusIntegracao{}},
+	"POST /api/admin/integracoes/:nome/testar": {Resumo: "Testa uma integração", Grupo: "Administração"},
+	"GET /api/admin/fila-escrita":              {Resumo: "Métricas da fila de escrita", Grupo: "Administração", Resposta: MetricasFilaEscrita{}},
+	"GET /api/admin/duplicatas":                {Resumo: "Possíveis produtos duplicados", Grupo: "Administração", Consulta: []string{"status"}, Resposta: []Duplicata{}},
+	"POST /api/admin/duplicatas/detectar":      {Resumo: "Procura produtos duplicados", Grupo: "Administração"},
+	"POST /api/admin/duplicatas/:id/mesclar": {Resumo: "Mescla o par de duplicatas", Grupo: "Administração", Requisicao: struct {
+		Manter int `json:"manter"`
+	}{}, Resposta: Produto{}},
+	"POST /api/admin/duplicatas/:id/ignorar": {Resumo: "Ignora o par de duplicatas", Grupo: "Administração", Resposta: respostaMensagem{}},
+	"POST /api/admin/snapshots-estoque":      {Resumo: "Grava a fotografia do estoque agora", Grupo: "Administração", Status: http.StatusCreated},
+	"POST /api/admin/anexos/verificar":       {Resumo: "Verifica a integridade dos anexos", Grupo: "Administração", Resposta: ResultadoVerificacaoAnexos{}},
+	"GET /api/admin/exportacoes":             {Resumo: "Registro de exportações", Grupo: "Administração", Resposta: []Exportacao{}},
+
+	// Treinamento (somente na instância de treino)
+	"GET /api/treinamento":         {Resumo: "Estado da instância de treinamento", Grupo: "Treinamento"},
+	"POST /api/treinamento/reset":  {Resumo: "Restaura o banco de treino", Grupo: "Treinamento"},
+	"POST /api/treinamento/modelo": {Resumo: "Salva o estado atual como modelo do treino", Grupo: "Treinamento"},
+
+	// Tempo real
+	"GET /api/stream": {Resumo: "Eventos em tempo real (Server-Sent Events)", Grupo: "Tempo real", Conteudo: "text/event-stream"},
+	"GET /ws":         {Resumo: "Canal WebSocket do dashboard", Grupo: "Tempo real"},
+
+	// Documentação
+	"GET /api/docs":              {Resumo: "Swagger UI", Grupo: "Documentação", Conteudo: "text/html"},
+	"GET /api/docs/openapi.json": {Resumo: "Esta especificação", Grupo: "Documentação"},
+}
+
+// Parâmetros de caminho numéricos
+var parametrosInteiros = map[string]bool{"id": true, "produto_id": true, "item_id": true}
+
+var padraoParametroRota = regexp.MustCompile(`[:*]([a-z_]+)`)
+
+// Especificação gerada uma vez, depois de registradas as rotas
+var especificacaoAPI struct {
+	once  sync.Once
+	rotas gin.RoutesInfo
+	json  []byte
+}
+
+// Gera os esquemas dos tipos Go em components.schemas
+type geradorEsquemas struct {
+	componentes map[string]any
+}
+
+var (
+	tipoTime                = reflect.TypeOf(time.Time{})
+	tipoInstanteDispositivo = reflect.TypeOf(instanteDispositivo{})
+)
+
+func (g *geradorEsquemas) esquema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == tipoTime:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == tipoInstanteDispositivo:
+		return map[string]any{"type": "string", "description": "RFC 3339, AAAA-MM-DD HH:MM:SS, DD/MM/AAAA HH:MM:SS ou época Unix"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.componentes[t.Name()]; !ok {
+			g.componentes[t.Name()] = map[string]any{} // evita recursão infinita
+			g.componentes[t.Name()] = g.objeto(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		return g.objeto(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.esquema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.esquema(t.Elem())}
+	}
+	// interface{} e demais: qualquer valor
+	return map[string]any{}
+}
+
+func (g *geradorEsquemas) objeto(t reflect.Type) map[string]any {
+	propriedades := map[string]any{}
+	var obrigatorios []string
+	g.campos(t, propriedades, &obrigatorios)
+
+	obj := map[string]any{"type": "object", "properties": propriedades}
+	if len(obrigatorios) > 0 {
+		sort.Strings(obrigatorios)
+		obj["required"] = obrigatorios
+	}
+	return obj
+}
+
+// Campos do struct pelas regras do encoding/json, incluindo os embutidos
+func (g *geradorEsquemas) campos(t reflect.Type, propriedades map[string]any, obrigatorios *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		nome, opcoes, _ := strings.Cut(tag, ",")
+
+		tipo := f.Type
+		for tipo.Kind() == reflect.Pointer {
+			tipo = tipo.Elem()
+		}
+		if f.Anonymous && nome == "" && tipo.Kind() == reflect.Struct {
+			g.campos(tipo, propriedades, obrigatorios)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if nome == "" {
+			nome = f.Name
+		}
+
+		propriedades[nome] = restricoesBinding(g.esquema(f.Type), f.Tag.Get("binding"))
+		if !strings.Contains(opcoes, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*obrigatorios = append(*obrigatorios, nome)
+		}
+	}
+}
+
+// Acrescenta ao esquema de um campo simples as regras da tag binding (ver
+// validacao.go): tamanhos, limites e valores aceitos
+func restricoesBinding(esquema map[string]any, regras string) map[string]any {
+	if regras == "" || esquema["$ref"] != nil {
+		return esquema
+	}
+	texto := esquema["type"] == "string"
+	for _, regra := range strings.Split(regras, ",") {
+		nome, valor, _ := strings.Cut(regra, "=")
+		if nome == "dive" {
+			break
+		}
+		if nome == "oneof" {
+			esquema["enum"] = strings.Fields(valor)
+			continue
+		}
+		n, err := strconv.ParseFloat(valor, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case nome == "max" && texto:
+			esquema["maxLength"] = n
+		case nome == "max":
+			esquema["maximum"] = n
+		case nome == "min" && texto:
+			esquema["minLength"] = n
+		case nome == "min":
+			esquema["minimum"] = n
+		case nome == "len" && texto:
+			esquema["minLength"], esquema["maxLength"] = n, n
+		}
+	}
+	return esquema
+}
+
+// Monta a especificação a partir das rotas e da tabela de documentação
+func gerarEspecificacaoAPI(rotas gin.RoutesInfo) map[string]any {
+	g := &geradorEsquemas{componentes: map[string]any{}}
+	erro := map[string]any{
+		"description": "Erro",
+		"content":     map[string]any{"application/problem+json": map[string]any{"schema": g.esquema(reflect.TypeOf(Problema{}))}},
+	}
+
+	caminhos := map[string]map[string]any{}
+	for _, rota := range rotas {
+		if rota.Method == http.MethodHead || rota.Method == http.MethodOptions {
+			continue
+		}
+		// Os caminhos sem versão são aliases obsoletos da versão atual
+		if strings.HasPrefix(rota.Path, "/api/") && rotaSemVersao(rota.Path) == rota.Path {
+			continue
+		}
+		doc, ok := docsOperacoes[rota.Method+" "+rotaSemVersao(rota.Path)]
+		if !ok {
+			log.Printf("[WARN] Rota sem documentação na especificação OpenAPI: %s %s", rota.Method, rota.Path)
+			doc = docOperacao{Resumo: rota.Method + " " + rota.Path, Grupo: "Outros"}
+		}
+
+		operacao := map[string]any{
+			"summary":     doc.Resumo,
+			"tags":        []string{doc.Grupo},
+			"operationId": nomeHandler(rota.Handler),
+		}
+
+		var parametros []map[string]any
+		nomeParametro := func(nome string) string {
+			if documentado, ok := doc.Parametros[nome]; ok {
+				return documentado
+			}
+			return nome
+		}
+		for _, m := range padraoParametroRota.FindAllStringSubmatch(rota.Path, -1) {
+			nome := nomeParametro(m[1])
+			tipo := "string"
+			if parametrosInteiros[nome] {
+				tipo = "integer"
+			}
+			parametros = append(parametros, map[string]any{
+				"name": nome, "in": "path", "required": true, "schema": map[string]any{"type": tipo},
+			})
+		}
+		for _, nome := range doc.Consulta {
+			parametros = append(parametros, map[string]any{
+				"name": nome, "in": "query", "required": false, "schema": map[string]any{"type": "string"},
+			})
+		}
+		if len(parametros) > 0 {
+			operacao["parameters"] = parametros
+		}
+
+		if doc.Upload {
+			operacao["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"arquivo": map[string]any{"type": "string", "format": "binary"}},
+					"required":   []string{"arquivo"},
+				}}},
+			}
+		} else if doc.Requisicao != nil {
+			operacao["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": g.esquema(reflect.TypeOf(doc.Requisicao))}},
+			}
+		}
+
+		sucesso := map[string]any{"description": "Sucesso"}
+		switch {
+		case doc.Conteudo != "":
+			sucesso["content"] = map[string]any{doc.Conteudo: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+		case doc.Resposta != nil:
+			sucesso["content"] = map[string]any{"application/json": map[string]any{"schema": g.esquema(reflect.TypeOf(doc.Resposta))}}
+		default:
+			sucesso["content"] = map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}}
+		}
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operacao["responses"] = map[string]any{
+			strconv.Itoa(status): sucesso,
+			"default":            erro,
+		}
+
+		caminho := padraoParametroRota.ReplaceAllStringFunc(rota.Path, func(parametro string) string {
+			return "{" + nomeParametro(parametro[1:]) + "}"
+		})
+		if caminhos[caminho] == nil {
+			caminhos[caminho] = map[string]any{}
+		}
+		caminhos[caminho][strings.ToLower(rota.Method)] = operacao
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "RLS Estoque API",
+			"version":     versaoAPI,
+			"description": "API do controle de estoque RLS. Erros usam o formato application/problem+json (RFC 7807) com o código do erro em code; os caminhos sem versão (/api/...) são obsoletos.",
+		},
+		"paths":      caminhos,
+		"components": map[string]any{"schemas": g.componentes},
+	}
+}
+
+// Nome do handler registrado, sem o pacote (métodos sem o sufixo -fm)
+func nomeHandler(completo string) string {
+	nome := completo[strings.LastIndex(completo, ".")+1:]
+	return strings.TrimSuffix(nome, "-fm")
+}
+
+// Guarda as rotas para gerar a especificação na primeira consulta
+func documentarAPI(rotas gin.RoutesInfo) {
+	especificacaoAPI.rotas = rotas
+}
+
+// Handlers da Documentação
+
+func getEspecificacaoAPI(c *gin.Context) {
+	especificacaoAPI.once.Do(func() {
+		dados, err := json.Marshal(gerarEspecificacaoAPI(especificacaoAPI.rotas))
+		if err != nil {
+			log.Printf("[ERROR] Erro ao gerar especificação OpenAPI: %v", err)
+			return
+		}
+		especificacaoAPI.json = dados
+	})
+	if especificacaoAPI.json == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar especificação"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", especificacaoAPI.json)
+}
+
+const paginaSwaggerUI = `<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>RLS Estoque API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+SwaggerUIBundle({ url: "/api/v1/docs/openapi.json", dom_id: "#swagger-ui" });
+</script>
+</body>
+</html>`
+
+func getSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(paginaSwaggerUI))
+}
@@ -0,0 +1,132 @@
+// pdf.go - Documentos PDF das etiquetas e relatórios
+//
+// Camada fina sobre jung-kurt/gofpdf: páginas de tamanho fixo, retângulos
+// preenchidos, imagens e texto nas fontes padrão Helvetica e Helvetica-Bold
+// (cp1252, cobre os acentos do português). As coordenadas são em pontos a
+// partir do canto superior esquerdo da página.
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Conversão de milímetros para pontos
+const pontosPorMM = 72 / 25.4
+
+type documentoPDF struct {
+	largura, altura float64
+	pdf             *gofpdf.Fpdf
+	traduzir        func(string) string
+	imagens         []imagemPDF
+}
+
+// Imagem registrada no documento, com as dimensões originais em pixels
+type imagemPDF struct {
+	nome            string
+	largura, altura float64
+}
+
+func novoPDF(largura, altura float64) *documentoPDF {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "pt",
+		Size:    gofpdf.SizeType{Wd: largura, Ht: altura},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFillColor(0, 0, 0)
+	return &documentoPDF{
+		largura:  largura,
+		altura:   altura,
+		pdf:      pdf,
+		traduzir: pdf.UnicodeTranslatorFromDescriptor(""),
+	}
+}
+
+func (d *documentoPDF) novaPagina() {
+	d.pdf.AddPage()
+}
+
+func (d *documentoPDF) garantirPagina() {
+	if d.pdf.PageCount() == 0 {
+		d.novaPagina()
+	}
+}
+
+// Retângulo preenchido em preto
+func (d *documentoPDF) retangulo(x, y, largura, altura float64) {
+	d.garantirPagina()
+	d.pdf.Rect(x, y, largura, altura, "F")
+}
+
+// Registra a imagem no documento; o índice devolvido é usado em desenharImagem
+// e -1 indica que a imagem não pôde ser registrada
+func (d *documentoPDF) adicionarImagem(img image.Image) int {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return -1
+	}
+	nome := "img" + strconv.Itoa(len(d.imagens))
+	info := d.pdf.RegisterImageOptionsReader(nome, gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	if info == nil || d.pdf.Err() {
+		return -1
+	}
+	b := img.Bounds()
+	d.imagens = append(d.imagens, imagemPDF{nome: nome, largura: float64(b.Dx()), altura: float64(b.Dy())})
+	return len(d.imagens) - 1
+}
+
+// Desenha uma imagem registrada no retângulo informado
+func (d *documentoPDF) desenharImagem(indice int, x, y, largura, altura float64) {
+	d.garantirPagina()
+	d.pdf.ImageOptions(d.imagens[indice].nome, x, y, largura, altura, false, gofpdf.ImageOptions{}, 0, "")
+}
+
+// Texto com a linha de base em y
+func (d *documentoPDF) texto(x, y, tamanho float64, s string) {
+	d.escreverTexto("", x, y, tamanho, s)
+}
+
+func (d *documentoPDF) textoNegrito(x, y, tamanho float64, s string) {
+	d.escreverTexto("B", x, y, tamanho, s)
+}
+
+func (d *documentoPDF) escreverTexto(estilo string, x, y, tamanho float64, s string) {
+	d.garantirPagina()
+	d.pdf.SetFont("Helvetica", estilo, tamanho)
+	d.pdf.Text(x, y, d.traduzir(s))
+}
+
+// Largura do texto em Helvetica, pelas métricas da fonte
+func (d *documentoPDF) larguraTexto(s string, tamanho float64) float64 {
+	d.pdf.SetFont("Helvetica", "", tamanho)
+	return d.pdf.GetStringWidth(d.traduzir(s))
+}
+
+// Corta o texto para caber na largura, terminando com reticências
+func (d *documentoPDF) ajustarTexto(s string, tamanho, largura float64) string {
+	if d.larguraTexto(s, tamanho) <= largura {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && d.larguraTexto(string(r)+"...", tamanho) > largura {
+		r = r[:len(r)-1]
+	}
+	return strings.TrimSpace(string(r)) + "..."
+}
+
+// Serializa o documento
+func (d *documentoPDF) bytes() ([]byte, error) {
+	d.garantirPagina()
+	var out bytes.Buffer
+	if err := d.pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,765 @@
+// pedidos_compra.go - Handlers de pedidos de compra (reposição junto aos fornecedores)
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados possíveis de um pedido de compra
+const (
+	PedidoCompraRascunho        = "rascunho"
+	PedidoCompraEnviado         = "enviado"
+	PedidoCompraRecebidoParcial = "recebido_parcial"
+	PedidoCompraRecebido        = "recebido"
+)
+
+type PedidoCompra struct {
+	ID              int                `json:"id,omitempty"`
+	Fornecedor      string             `json:"fornecedor"`
+	Status          string             `json:"status"`
+	Notas           string             `json:"notas,omitempty"`
+	Itens           []PedidoCompraItem `json:"itens"`
+	DataCriacao     time.Time          `json:"data_criacao,omitempty"`
+	DataAtualizacao time.Time          `json:"data_atualizacao,omitempty"`
+}
+
+type PedidoCompraItem struct {
+	ID                 int    `json:"id,omitempty"`
+	ProdutoID          int    `json:"produto_id"`
+	ProdutoCodigo      string `json:"produto_codigo,omitempty"`
+	ProdutoNome        string `json:"produto_nome,omitempty"`
+	Quantidade         int    `json:"quantidade"`
+	QuantidadeRecebida int    `json:"quantidade_recebida"`
+}
+
+// Corpo da requisição de recebimento: quantidade recebida por item do pedido.
+// Sem itens, todo o saldo pendente do pedido é recebido. Com uma embalagem do
+// fornecedor (ID, EAN da caixa ou código do fornecedor) a quantidade é em
+// embalagens e o item pode ser omitido. Custo unitário (da unidade informada)
+// e peso da linha alimentam o rateio de frete e impostos. Produtos com controle
+// de série recebem os números de série da linha (um por unidade base).
+type RecebimentoPedido struct {
+	Itens []struct {
+		ItemID        int      `json:"item_id"`
+		Quantidade    int      `json:"quantidade"`
+		CustoUnitario *float64 `json:"custo_unitario,omitempty"`
+		Peso          float64  `json:"peso,omitempty"`
+		ReferenciaEmbalagem
+		DadosBaixa
+	} `json:"itens" binding:"dive"`
+	Notas string `json:"notas,omitempty"`
+	DespesasRecebimento
+}
+
+// Função auxiliar para carregar os itens de um pedido
+func carregarItensPedidoCompra(ctx context.Context, q querier, pedidoID int) ([]PedidoCompraItem, error) {
+	rows, err := q.Query(ctx, `
+		SELECT i.id, i.produto_id, p.codigo, p.nome, i.quantidade, i.quantidade_recebida
+		FROM pedidos_compra_itens i
+		JOIN produtos p ON i.produto_id = p.id
+		WHERE i.pedido_id = $1
+		ORDER BY i.id
+	`, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	itens := []PedidoCompraItem{}
+	for rows.Next() {
+		var item PedidoCompraItem
+		if err := rows.Scan(&item.ID, &item.ProdutoID, &item.ProdutoCodigo, &item.ProdutoNome,
+			&item.Quantidade, &item.QuantidadeRecebida); err != nil {
+			return nil, err
+		}
+		itens = append(itens, item)
+	}
+	return itens, rows.Err()
+}
+
+// Função auxiliar para validar os itens enviados pelo cliente. Kits não têm
+// estoque próprio e não podem ser comprados: o pedido traz os componentes
+func validarItensPedidoCompra(ctx context.Context, q querier, itens []PedidoCompraItem) (string, error) {
+	if len(itens) == 0 {
+		return "O pedido deve ter pelo menos um item", nil
+	}
+	produtoIDs := make([]int, len(itens))
+	for i, item := range itens {
+		if item.ProdutoID <= 0 || item.Quantidade <= 0 {
+			return "Cada item precisa de produto e quantidade positiva", nil
+		}
+		produtoIDs[i] = item.ProdutoID
+	}
+
+	var kit string
+	err := q.QueryRow(ctx, "SELECT codigo FROM produtos WHERE id = ANY($1) AND kit ORDER BY codigo LIMIT 1", produtoIDs).Scan(&kit)
+	if err == nil {
+		return fmt.Sprintf("Kits não têm estoque próprio: peça os componentes do kit %s", kit), nil
+	} else if err != pgx.ErrNoRows {
+		return "", err
+	}
+	return "", nil
+}
+
+// Função auxiliar para inserir os itens de um pedido dentro de uma transação
+func inserirItensPedidoCompra(ctx context.Context, tx pgx.Tx, pedidoID int, itens []PedidoCompraItem) error {
+	for _, item := range itens {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO pedidos_compra_itens(pedido_id, produto_id, quantidade)
+			VALUES ($1, $2, $3)
+		`, pedidoID, item.ProdutoID, item.Quantidade)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handlers de Pedidos de Compra
+
+func getPedidosCompra(c *gin.Context) {
+	log.Println("[DB] Buscando lista de pedidos de compra")
+
+	status := c.Query("status")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, fornecedor, status, notas, data_criacao, data_atualizacao
+		FROM pedidos_compra
+		WHERE $1 = '' OR status = $1
+		ORDER BY data_criacao DESC
+	`, status)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar pedidos de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar pedidos de compra"})
+		return
+	}
+	defer rows.Close()
+
+	pedidos := []PedidoCompra{}
+	for rows.Next() {
+		var p PedidoCompra
+		var notas *string
+		var dataAtualizacao *time.Time
+
+		err := rows.Scan(&p.ID, &p.Fornecedor, &p.Status, &notas, &p.DataCriacao, &dataAtualizacao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar pedido de compra: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if notas != nil {
+			p.Notas = *notas
+		}
+		if dataAtualizacao != nil {
+			p.DataAtualizacao = *dataAtualizacao
+		}
+		p.Itens = []PedidoCompraItem{}
+
+		pedidos = append(pedidos, p)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar pedidos de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar pedidos de compra"})
+		return
+	}
+
+	// Carregar itens de cada pedido
+	for i := range pedidos {
+		itens, err := carregarItensPedidoCompra(c.Request.Context(), db, pedidos[i].ID)
+		if err != nil {
+			log.Printf("[WARN] Erro ao carregar itens do pedido %d: %v", pedidos[i].ID, err)
+			continue
+		}
+		pedidos[i].Itens = itens
+	}
+
+	log.Printf("[DB] Retornando %d pedidos de compra", len(pedidos))
+	c.JSON(http.StatusOK, pedidos)
+}
+
+func getPedidoCompra(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando pedido de compra com ID: %d", id)
+
+	var p PedidoCompra
+	var notas *string
+	var dataAtualizacao *time.Time
+
+	err = db.QueryRow(c.Request.Context(), `
+		SELECT id, fornecedor, status, notas, data_criacao, data_atualizacao
+		FROM pedidos_compra
+		WHERE id = $1
+	`, id).Scan(&p.ID, &p.Fornecedor, &p.Status, &notas, &p.DataCriacao, &dataAtualizacao)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de compra não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de compra não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar pedido de compra: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar pedido de compra"})
+		}
+		return
+	}
+
+	// Tratar campos nulos
+	if notas != nil {
+		p.Notas = *notas
+	}
+	if dataAtualizacao != nil {
+		p.DataAtualizacao = *dataAtualizacao
+	}
+
+	p.Itens, err = carregarItensPedidoCompra(c.Request.Context(), db, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar itens do pedido de compra"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de compra encontrado: ID: %d, Status: %s, Itens: %d", p.ID, p.Status, len(p.Itens))
+	c.JSON(http.StatusOK, p)
+}
+
+func criarPedidoCompra(c *gin.Context) {
+	log.Println("[API] Iniciando criação de pedido de compra")
+
+	// Decodificar pedido do request
+	var p PedidoCompra
+	if err := c.ShouldBindJSON(&p); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	// Validar campos obrigatórios
+	if p.Fornecedor == "" {
+		log.Printf("[ERROR] Fornecedor ausente no pedido de compra")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Fornecedor é obrigatório"})
+		return
+	}
+	msg, err := validarItensPedidoCompra(c.Request.Context(), db, p.Itens)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar itens do pedido de compra"})
+		return
+	}
+	if msg != "" {
+		log.Printf("[ERROR] Itens inválidos no pedido de compra: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	log.Printf("[DB] Inserindo pedido de compra para fornecedor: %s", p.Fornecedor)
+	p.Status = PedidoCompraRascunho
+	err = tx.QueryRow(c.Request.Context(), `
+		INSERT INTO pedidos_compra(fornecedor, status, notas)
+		VALUES ($1, $2, $3)
+		RETURNING id, data_criacao
+	`, p.Fornecedor, p.Status, p.Notas).Scan(&p.ID, &p.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar pedido de compra"})
+		return
+	}
+
+	if err = inserirItensPedidoCompra(c.Request.Context(), tx, p.ID, p.Itens); err != nil {
+		log.Printf("[ERROR] Erro ao inserir itens do pedido de compra: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao inserir itens do pedido (verifique os produtos)"})
+		return
+	}
+
+	p.Itens, err = carregarItensPedidoCompra(c.Request.Context(), tx, p.ID)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar pedido de compra"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de compra criado com sucesso! ID: %d, Itens: %d", p.ID, len(p.Itens))
+	c.JSON(http.StatusCreated, p)
+}
+
+func atualizarPedidoCompra(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Iniciando atualização de pedido de compra ID: %d", id)
+
+	// Decodificar pedido do request
+	var p PedidoCompra
+	if err := c.ShouldBindJSON(&p); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	if p.Fornecedor == "" {
+		log.Printf("[ERROR] Fornecedor ausente no pedido de compra")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Fornecedor é obrigatório"})
+		return
+	}
+	msg, err := validarItensPedidoCompra(c.Request.Context(), db, p.Itens)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar itens do pedido de compra"})
+		return
+	}
+	if msg != "" {
+		log.Printf("[ERROR] Itens inválidos no pedido de compra: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	// Verificar se o pedido existe e ainda pode ser editado
+	var status string
+	err = tx.QueryRow(c.Request.Context(), "SELECT status FROM pedidos_compra WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de compra não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de compra não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar pedido de compra: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar pedido de compra"})
+		}
+		return
+	}
+
+	if status != PedidoCompraRascunho {
+		log.Printf("[ERROR] Pedido de compra ID: %d não está em rascunho (status: %s)", id, status)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Somente pedidos em rascunho podem ser alterados"})
+		return
+	}
+
+	log.Printf("[DB] Atualizando pedido de compra ID: %d", id)
+	err = tx.QueryRow(c.Request.Context(), `
+		UPDATE pedidos_compra SET
+			fornecedor = $1,
+			notas = $2
+		WHERE id = $3
+		RETURNING data_criacao, data_atualizacao
+	`, p.Fornecedor, p.Notas, id).Scan(&p.DataCriacao, &p.DataAtualizacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar pedido de compra"})
+		return
+	}
+
+	// Substituir os itens do rascunho
+	if _, err = tx.Exec(c.Request.Context(), "DELETE FROM pedidos_compra_itens WHERE pedido_id = $1", id); err != nil {
+		log.Printf("[ERROR] Erro ao remover itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar pedido de compra"})
+		return
+	}
+	if err = inserirItensPedidoCompra(c.Request.Context(), tx, id, p.Itens); err != nil {
+		log.Printf("[ERROR] Erro ao inserir itens do pedido de compra: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao inserir itens do pedido (verifique os produtos)"})
+		return
+	}
+
+	p.Itens, err = carregarItensPedidoCompra(c.Request.Context(), tx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar pedido de compra"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	p.ID = id
+	p.Status = status
+
+	log.Printf("[DB] Pedido de compra atualizado com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, p)
+}
+
+func deletarPedidoCompra(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Iniciando exclusão de pedido de compra ID: %d", id)
+
+	// Verificar se o pedido existe
+	var status string
+	err = db.QueryRow(c.Request.Context(), "SELECT status FROM pedidos_compra WHERE id = $1", id).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de compra não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de compra não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar pedido de compra: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar pedido de compra"})
+		}
+		return
+	}
+
+	if status != PedidoCompraRascunho {
+		log.Printf("[ERROR] Pedido de compra ID: %d não está em rascunho (status: %s)", id, status)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Somente pedidos em rascunho podem ser excluídos"})
+		return
+	}
+
+	_, err = db.Exec(c.Request.Context(), "DELETE FROM pedidos_compra WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir pedido de compra"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de compra excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Pedido de compra excluído com sucesso"})
+}
+
+func enviarPedidoCompra(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Enviando pedido de compra ID: %d", id)
+
+	// Apenas rascunhos podem ser enviados ao fornecedor
+	tag, err := db.Exec(c.Request.Context(), `
+		UPDATE pedidos_compra SET status = $1
+		WHERE id = $2 AND status = $3
+	`, PedidoCompraEnviado, id, PedidoCompraRascunho)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao enviar pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao enviar pedido de compra"})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Pedido de compra ID: %d não encontrado ou fora de rascunho", id)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Pedido de compra não encontrado ou não está em rascunho"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de compra enviado com sucesso! ID: %d", id)
+	getPedidoCompra(c)
+}
+
+func receberPedidoCompra(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Iniciando recebimento do pedido de compra ID: %d", id)
+
+	// Corpo opcional: sem itens, recebe todo o saldo pendente
+	var req RecebimentoPedido
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("[ERROR] Dados inválidos: %v", err)
+			c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+			return
+		}
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	// Bloquear o pedido durante o recebimento
+	var status, fornecedor string
+	err = tx.QueryRow(c.Request.Context(), "SELECT status, fornecedor FROM pedidos_compra WHERE id = $1 FOR UPDATE", id).Scan(&status, &fornecedor)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de compra não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de compra não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar pedido de compra: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar pedido de compra"})
+		}
+		return
+	}
+
+	if status != PedidoCompraEnviado && status != PedidoCompraRecebidoParcial {
+		log.Printf("[ERROR] Pedido de compra ID: %d não pode ser recebido (status: %s)", id, status)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Somente pedidos enviados ou recebidos parcialmente podem ser recebidos"})
+		return
+	}
+
+	itens, err := carregarItensPedidoCompra(c.Request.Context(), tx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar itens do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar itens do pedido de compra"})
+		return
+	}
+
+	if msg := validarDespesasRecebimento(&req.DespesasRecebimento); msg != "" {
+		log.Printf("[ERROR] Despesas inválidas no recebimento do pedido %d: %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	// Montar as quantidades a receber por item
+	receber := map[int]int{}
+	bases := map[int]*baseRateio{}
+	// Séries informadas por item; a entrada valida a quantidade e a unicidade
+	dados := map[int]DadosBaixa{}
+	if len(req.Itens) == 0 {
+		for _, item := range itens {
+			if pendente := item.Quantidade - item.QuantidadeRecebida; pendente > 0 {
+				receber[item.ID] = pendente
+				bases[item.ID] = &baseRateio{quantidade: pendente}
+			}
+		}
+	} else {
+		pendentes := map[int]int{}
+		for _, item := range itens {
+			pendentes[item.ID] = item.Quantidade - item.QuantidadeRecebida
+		}
+		for _, r := range req.Itens {
+			informada := r.Quantidade
+
+			// Converter embalagens do fornecedor para a unidade base
+			if r.ReferenciaEmbalagem.informada() {
+				produtoID, fator, err := buscarEmbalagem(c.Request.Context(), tx, fornecedor, r.ReferenciaEmbalagem)
+				if err != nil {
+					log.Printf("[ERROR] Erro ao buscar embalagem no recebimento do pedido %d: %v", id, err)
+					if err == errEmbalagemNaoEncontrada {
+						c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Embalagem: " + err.Error()})
+					} else {
+						c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar embalagem"})
+					}
+					return
+				}
+
+				// Sem item informado, vale o item pendente do produto da embalagem
+				for _, item := range itens {
+					if r.ItemID == 0 && item.ProdutoID == produtoID && pendentes[item.ID] > receber[item.ID] {
+						r.ItemID = item.ID
+					}
+					if item.ID == r.ItemID && item.ProdutoID != produtoID {
+						log.Printf("[ERROR] Embalagem do produto %d informada para o item %d", produtoID, r.ItemID)
+						c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("A embalagem não é do produto do item %d", r.ItemID)})
+						return
+					}
+				}
+				if r.ItemID == 0 {
+					log.Printf("[ERROR] Produto %d da embalagem sem saldo pendente no pedido %d", produtoID, id)
+					c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O produto da embalagem não tem saldo pendente neste pedido"})
+					return
+				}
+				r.Quantidade *= fator
+			}
+
+			pendente, ok := pendentes[r.ItemID]
+			if !ok {
+				log.Printf("[ERROR] Item %d não pertence ao pedido de compra ID: %d", r.ItemID, id)
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Item %d não pertence ao pedido", r.ItemID)})
+				return
+			}
+			if r.Quantidade <= 0 || receber[r.ItemID]+r.Quantidade > pendente {
+				log.Printf("[ERROR] Quantidade inválida para item %d. Solicitado: %d, Pendente: %d", r.ItemID, r.Quantidade, pendente)
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Quantidade inválida para o item %d (pendente: %d)", r.ItemID, pendente)})
+				return
+			}
+			if r.CustoUnitario != nil && *r.CustoUnitario < 0 {
+				log.Printf("[ERROR] Custo unitário negativo para item %d: %f", r.ItemID, *r.CustoUnitario)
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Custo unitário não pode ser negativo"})
+				return
+			}
+			receber[r.ItemID] += r.Quantidade
+			d := dados[r.ItemID]
+			d.Series = append(d.Series, r.Series...)
+			dados[r.ItemID] = d
+
+			b := bases[r.ItemID]
+			if b == nil {
+				b = &baseRateio{comCusto: true}
+				bases[r.ItemID] = b
+			}
+			b.quantidade += r.Quantidade
+			b.peso += r.Peso
+			if r.CustoUnitario != nil {
+				b.valor += *r.CustoUnitario * float64(informada)
+			} else {
+				b.comCusto = false
+			}
+		}
+	}
+
+	// Custo de entrada de cada item com a parcela de frete e impostos
+	custos, msg, err := custosEntradaRecebimento(c.Request.Context(), tx, itens, bases, req.DespesasRecebimento)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao calcular custos de entrada do pedido %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao calcular custos de entrada"})
+		return
+	}
+	if msg != "" {
+		log.Printf("[ERROR] Rateio inválido no recebimento do pedido %d: %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+	if req.total() > 0 {
+		log.Printf("[API] Rateando %.2f de frete e impostos por %s no pedido %d", req.total(), req.Rateio, id)
+	}
+
+	if len(receber) == 0 {
+		log.Printf("[ERROR] Nada a receber no pedido de compra ID: %d", id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Não há itens pendentes para receber"})
+		return
+	}
+
+	notas := fmt.Sprintf("Recebimento do pedido de compra #%d", id)
+	if req.Notas != "" {
+		notas = notas + " - " + req.Notas
+	}
+
+	// Gerar uma movimentação de entrada por item recebido
+	movimentacoes := []Movimentacao{}
+	for _, item := range itens {
+		quantidade, ok := receber[item.ID]
+		if !ok {
+			continue
+		}
+
+		log.Printf("[DB] Recebendo %d itens do produto ID: %d (pedido %d)", quantidade, item.ProdutoID, id)
+
+		m := Movimentacao{ProdutoID: item.ProdutoID, Tipo: "entrada", Quantidade: quantidade, Notas: notas}
+		if custo, ok := custos[item.ID]; ok {
+			m.CustoUnitario = &custo
+		}
+		dados[item.ID].aplicar(&m)
+		// Lotes, séries, custo médio e saldo seguem as regras de qualquer entrada
+		if err = registrarMovimentacao(c.Request.Context(), tx, &m); err != nil {
+			e, ok := err.(*erroMovimentacao)
+			if !ok {
+				log.Printf("[ERROR] Erro ao registrar entrada do item %d no recebimento do pedido %d: %v", item.ID, id, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+				return
+			}
+			log.Printf("[ERROR] Entrada do item %d recusada no recebimento do pedido %d: %s", item.ID, id, e.msg)
+			c.JSON(e.status, e.resposta())
+			return
+		}
+
+		_, err = tx.Exec(c.Request.Context(), `
+			UPDATE pedidos_compra_itens SET quantidade_recebida = quantidade_recebida + $1
+			WHERE id = $2
+		`, quantidade, item.ID)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao atualizar item do pedido de compra: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar item do pedido de compra"})
+			return
+		}
+
+		movimentacoes = append(movimentacoes, m)
+	}
+
+	// Definir novo status conforme o saldo pendente
+	var pendentes int
+	err = tx.QueryRow(c.Request.Context(), `
+		SELECT COUNT(*) FROM pedidos_compra_itens
+		WHERE pedido_id = $1 AND quantidade_recebida < quantidade
+	`, id).Scan(&pendentes)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar itens pendentes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar itens pendentes"})
+		return
+	}
+
+	novoStatus := PedidoCompraRecebido
+	if pendentes > 0 {
+		novoStatus = PedidoCompraRecebidoParcial
+	}
+
+	_, err = tx.Exec(c.Request.Context(), "UPDATE pedidos_compra SET status = $1 WHERE id = $2", novoStatus, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar status do pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar status do pedido de compra"})
+		return
+	}
+
+	log.Printf("[DB] Confirmando transação")
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de compra ID: %d recebido (%d movimentações, status: %s)", id, len(movimentacoes), novoStatus)
+	publicarMovimentacoes(c.Request.Context(), movimentacoes)
+	c.JSON(http.StatusOK, gin.H{
+		"pedido_id":     id,
+		"status":        novoStatus,
+		"movimentacoes": movimentacoes,
+	})
+}
@@ -0,0 +1,603 @@
+// pedidos_saida.go - Handlers de pedidos de saída com lista de separação (picking)
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados possíveis de um pedido de saída
+const (
+	PedidoSaidaAberto    = "aberto"
+	PedidoSaidaSeparado  = "separado"
+	PedidoSaidaCancelado = "cancelado"
+)
+
+type PedidoSaida struct {
+	ID              int               `json:"id,omitempty"`
+	Cliente         string            `json:"cliente"`
+	Status          string            `json:"status"`
+	Notas           string            `json:"notas,omitempty"`
+	Itens           []PedidoSaidaItem `json:"itens"`
+	DataCriacao     time.Time         `json:"data_criacao,omitempty"`
+	DataAtualizacao time.Time         `json:"data_atualizacao,omitempty"`
+}
+
+type PedidoSaidaItem struct {
+	ID            int    `json:"id,omitempty"`
+	ProdutoID     int    `json:"produto_id"`
+	ProdutoCodigo string `json:"produto_codigo,omitempty"`
+	ProdutoNome   string `json:"produto_nome,omitempty"`
+	Quantidade    int    `json:"quantidade"`
+}
+
+// Linha da lista de separação, já ordenada pela localização no depósito
+type ItemSeparacao struct {
+	ItemID               int    `json:"item_id"`
+	ProdutoID            int    `json:"produto_id"`
+	ProdutoCodigo        string `json:"produto_codigo"`
+	ProdutoNome          string `json:"produto_nome"`
+	Localizacao          string `json:"localizacao,omitempty"`
+	Quantidade           int    `json:"quantidade"`
+	QuantidadeDisponivel int    `json:"quantidade_disponivel"`
+}
+
+// Corpo opcional da confirmação da separação: séries, descarte e checklist
+// de cada baixa. Em itens de kit, produto_id indica o componente
+type ConfirmacaoSeparacao struct {
+	Itens []BaixaSeparacao `json:"itens,omitempty" binding:"dive"`
+}
+
+type BaixaSeparacao struct {
+	ItemID    int `json:"item_id" binding:"required,min=1"`
+	ProdutoID int `json:"produto_id,omitempty" binding:"omitempty,min=1"`
+	DadosBaixa
+}
+
+// Função auxiliar para carregar os itens de um pedido de saída
+func carregarItensPedidoSaida(ctx context.Context, q querier, pedidoID int) ([]PedidoSaidaItem, error) {
+	rows, err := q.Query(ctx, `
+		SELECT i.id, i.produto_id, p.codigo, p.nome, i.quantidade
+		FROM pedidos_saida_itens i
+		JOIN produtos p ON i.produto_id = p.id
+		WHERE i.pedido_id = $1
+		ORDER BY i.id
+	`, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	itens := []PedidoSaidaItem{}
+	for rows.Next() {
+		var item PedidoSaidaItem
+		if err := rows.Scan(&item.ID, &item.ProdutoID, &item.ProdutoCodigo, &item.ProdutoNome, &item.Quantidade); err != nil {
+			return nil, err
+		}
+		itens = append(itens, item)
+	}
+	return itens, rows.Err()
+}
+
+// Handlers de Pedidos de Saída
+
+func getPedidosSaida(c *gin.Context) {
+	log.Println("[DB] Buscando lista de pedidos de saída")
+
+	status := c.Query("status")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, cliente, status, notas, data_criacao, data_atualizacao
+		FROM pedidos_saida
+		WHERE $1 = '' OR status = $1
+		ORDER BY data_criacao DESC
+	`, status)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar pedidos de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar pedidos de saída"})
+		return
+	}
+	defer rows.Close()
+
+	pedidos := []PedidoSaida{}
+	for rows.Next() {
+		var p PedidoSaida
+		var notas *string
+		var dataAtualizacao *time.Time
+
+		err := rows.Scan(&p.ID, &p.Cliente, &p.Status, &notas, &p.DataCriacao, &dataAtualizacao)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar pedido de saída: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if notas != nil {
+			p.Notas = *notas
+		}
+		if dataAtualizacao != nil {
+			p.DataAtualizacao = *dataAtualizacao
+		}
+		p.Itens = []PedidoSaidaItem{}
+
+		pedidos = append(pedidos, p)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar pedidos de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar pedidos de saída"})
+		return
+	}
+
+	// Carregar itens de cada pedido
+	for i := range pedidos {
+		itens, err := carregarItensPedidoSaida(c.Request.Context(), db, pedidos[i].ID)
+		if err != nil {
+			log.Printf("[WARN] Erro ao carregar itens do pedido %d: %v", pedidos[i].ID, err)
+			continue
+		}
+		pedidos[i].Itens = itens
+	}
+
+	log.Printf("[DB] Retornando %d pedidos de saída", len(pedidos))
+	c.JSON(http.StatusOK, pedidos)
+}
+
+func getPedidoSaida(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando pedido de saída com ID: %d", id)
+
+	var p PedidoSaida
+	var notas *string
+	var dataAtualizacao *time.Time
+
+	err = db.QueryRow(c.Request.Context(), `
+		SELECT id, cliente, status, notas, data_criacao, data_atualizacao
+		FROM pedidos_saida
+		WHERE id = $1
+	`, id).Scan(&p.ID, &p.Cliente, &p.Status, &notas, &p.DataCriacao, &dataAtualizacao)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de saída não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de saída não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar pedido de saída: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar pedido de saída"})
+		}
+		return
+	}
+
+	// Tratar campos nulos
+	if notas != nil {
+		p.Notas = *notas
+	}
+	if dataAtualizacao != nil {
+		p.DataAtualizacao = *dataAtualizacao
+	}
+
+	p.Itens, err = carregarItensPedidoSaida(c.Request.Context(), db, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar itens do pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar itens do pedido de saída"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de saída encontrado: ID: %d, Status: %s, Itens: %d", p.ID, p.Status, len(p.Itens))
+	c.JSON(http.StatusOK, p)
+}
+
+func criarPedidoSaida(c *gin.Context) {
+	log.Println("[API] Iniciando criação de pedido de saída")
+
+	// Decodificar pedido do request
+	var p PedidoSaida
+	if err := c.ShouldBindJSON(&p); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	// Validar campos obrigatórios
+	if p.Cliente == "" {
+		log.Printf("[ERROR] Cliente ausente no pedido de saída")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cliente é obrigatório"})
+		return
+	}
+	if len(p.Itens) == 0 {
+		log.Printf("[ERROR] Pedido de saída sem itens")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O pedido deve ter pelo menos um item"})
+		return
+	}
+	for _, item := range p.Itens {
+		if item.ProdutoID <= 0 || item.Quantidade <= 0 {
+			log.Printf("[ERROR] Item inválido no pedido de saída. ProdutoID: %d, Quantidade: %d", item.ProdutoID, item.Quantidade)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cada item precisa de produto e quantidade positiva"})
+			return
+		}
+	}
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	log.Printf("[DB] Inserindo pedido de saída para cliente: %s", p.Cliente)
+	p.Status = PedidoSaidaAberto
+	err = tx.QueryRow(c.Request.Context(), `
+		INSERT INTO pedidos_saida(cliente, status, notas)
+		VALUES ($1, $2, $3)
+		RETURNING id, data_criacao
+	`, p.Cliente, p.Status, p.Notas).Scan(&p.ID, &p.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar pedido de saída"})
+		return
+	}
+
+	for _, item := range p.Itens {
+		_, err = tx.Exec(c.Request.Context(), `
+			INSERT INTO pedidos_saida_itens(pedido_id, produto_id, quantidade)
+			VALUES ($1, $2, $3)
+		`, p.ID, item.ProdutoID, item.Quantidade)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao inserir item do pedido de saída: %v", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao inserir itens do pedido (verifique os produtos)"})
+			return
+		}
+	}
+
+	p.Itens, err = carregarItensPedidoSaida(c.Request.Context(), tx, p.ID)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar itens do pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar pedido de saída"})
+		return
+	}
+
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de saída criado com sucesso! ID: %d, Itens: %d", p.ID, len(p.Itens))
+	c.JSON(http.StatusCreated, p)
+}
+
+func cancelarPedidoSaida(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Cancelando pedido de saída ID: %d", id)
+
+	// Apenas pedidos abertos podem ser cancelados
+	tag, err := db.Exec(c.Request.Context(), `
+		UPDATE pedidos_saida SET status = $1
+		WHERE id = $2 AND status = $3
+	`, PedidoSaidaCancelado, id, PedidoSaidaAberto)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao cancelar pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao cancelar pedido de saída"})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Pedido de saída ID: %d não encontrado ou não está aberto", id)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Pedido de saída não encontrado ou não está aberto"})
+		return
+	}
+
+	log.Printf("[DB] Pedido de saída cancelado com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Pedido de saída cancelado com sucesso"})
+}
+
+func getSeparacaoPedidoSaida(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Gerando lista de separação do pedido de saída ID: %d", id)
+
+	// Verificar se o pedido existe
+	var status string
+	err = db.QueryRow(c.Request.Context(), "SELECT status FROM pedidos_saida WHERE id = $1", id).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de saída não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de saída não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar pedido de saída: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar pedido de saída"})
+		}
+		return
+	}
+
+	// Itens ordenados pelo percurso no depósito (localização), sem localização por último
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT i.id, i.produto_id, p.codigo, p.nome, p.localizacao, i.quantidade, p.quantidade
+		FROM pedidos_saida_itens i
+		JOIN produtos p ON i.produto_id = p.id
+		WHERE i.pedido_id = $1
+		ORDER BY p.localizacao ASC NULLS LAST, p.codigo
+	`, id)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar itens para separação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar itens para separação"})
+		return
+	}
+	defer rows.Close()
+
+	itens := []ItemSeparacao{}
+	for rows.Next() {
+		var item ItemSeparacao
+		var localizacao *string
+
+		err := rows.Scan(&item.ItemID, &item.ProdutoID, &item.ProdutoCodigo, &item.ProdutoNome,
+			&localizacao, &item.Quantidade, &item.QuantidadeDisponivel)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar item de separação: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if localizacao != nil {
+			item.Localizacao = *localizacao
+		}
+
+		itens = append(itens, item)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar itens de separação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar itens de separação"})
+		return
+	}
+
+	log.Printf("[DB] Lista de separação do pedido %d com %d itens", id, len(itens))
+	c.JSON(http.StatusOK, gin.H{
+		"pedido_id": id,
+		"status":    status,
+		"itens":     itens,
+	})
+}
+
+func confirmarSeparacaoPedidoSaida(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req ConfirmacaoSeparacao
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("[ERROR] Dados inválidos: %v", err)
+			c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+			return
+		}
+	}
+
+	log.Printf("[API] Confirmando separação do pedido de saída ID: %d", id)
+
+	tx, err := db.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(c.Request.Context())
+
+	// Bloquear o pedido durante a confirmação
+	var status string
+	err = tx.QueryRow(c.Request.Context(), "SELECT status FROM pedidos_saida WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Pedido de saída não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pedido de saída não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar pedido de saída: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar pedido de saída"})
+		}
+		return
+	}
+
+	if status != PedidoSaidaAberto {
+		log.Printf("[ERROR] Pedido de saída ID: %d não está aberto (status: %s)", id, status)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Somente pedidos abertos podem ter a separação confirmada"})
+		return
+	}
+
+	itens, err := carregarItensPedidoSaida(c.Request.Context(), tx, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar itens do pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao carregar itens do pedido de saída"})
+		return
+	}
+
+	// Kits saem pelos componentes: cada item vira as baixas dos produtos com
+	// estoque próprio
+	type baixaPedido struct {
+		baixaKit
+		itemID int
+		kit    string // código do kit de origem, se houver
+	}
+	baixas := []baixaPedido{}
+	produtoItem := map[int]int{}
+	for _, item := range itens {
+		produtoItem[item.ID] = item.ProdutoID
+		componentes, err := explodirKit(c.Request.Context(), tx, item.ProdutoID, item.Quantidade)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao abrir kit %d: %v", item.ProdutoID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar itens do pedido de saída"})
+			return
+		}
+		if len(componentes) == 0 {
+			log.Printf("[ERROR] Kit sem componentes no pedido de saída ID: %d (produto %d)", id, item.ProdutoID)
+			c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Kit %s sem componentes: cadastre a estrutura do kit", item.ProdutoCodigo)})
+			return
+		}
+		kit := ""
+		if len(componentes) > 1 || componentes[0].ProdutoID != item.ProdutoID {
+			kit = item.ProdutoCodigo
+		}
+		for _, b := range componentes {
+			baixas = append(baixas, baixaPedido{baixaKit: b, itemID: item.ID, kit: kit})
+		}
+	}
+
+	// Dados informados por baixa (item e produto; sem produto, o do item)
+	type chaveBaixa struct{ item, produto int }
+	dados := map[chaveBaixa]DadosBaixa{}
+	for _, d := range req.Itens {
+		produto := d.ProdutoID
+		if produto == 0 {
+			produto = produtoItem[d.ItemID]
+		}
+		dados[chaveBaixa{d.ItemID, produto}] = d.DadosBaixa
+	}
+	for k := range dados {
+		encontrado := false
+		for _, b := range baixas {
+			if b.itemID == k.item && b.ProdutoID == k.produto {
+				encontrado = true
+				break
+			}
+		}
+		if !encontrado {
+			log.Printf("[ERROR] Dados de baixa sem item correspondente no pedido %d: item %d, produto %d", id, k.item, k.produto)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Item %d (produto %d) não faz parte da separação do pedido", k.item, k.produto)})
+			return
+		}
+	}
+
+	// Somar a quantidade a baixar por produto
+	type necessidade struct {
+		produtoID  int
+		codigo     string
+		disponivel int
+		solicitado int
+	}
+	solicitados := map[int]int{}
+	necessidades := []necessidade{}
+	for _, b := range baixas {
+		if _, ok := solicitados[b.ProdutoID]; !ok {
+			necessidades = append(necessidades, necessidade{produtoID: b.ProdutoID, codigo: b.Codigo})
+		}
+		solicitados[b.ProdutoID] += b.Quantidade
+	}
+	for i := range necessidades {
+		necessidades[i].solicitado = solicitados[necessidades[i].produtoID]
+	}
+	sort.Slice(necessidades, func(i, j int) bool { return necessidades[i].produtoID < necessidades[j].produtoID })
+
+	// Travar os produtos (em ordem de ID, evitando deadlock) e ler o saldo atual
+	faltas := []FaltaEstoque{}
+	for i, n := range necessidades {
+		var negativoProduto *bool
+		err = tx.QueryRow(c.Request.Context(), "SELECT quantidade, permitir_estoque_negativo FROM produtos WHERE id = $1 FOR UPDATE", n.produtoID).Scan(&necessidades[i].disponivel, &negativoProduto)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao bloquear produto %d: %v", n.produtoID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar estoque dos itens"})
+			return
+		}
+		if necessidades[i].disponivel < n.solicitado && !estoqueNegativoPermitido(c.Request.Context(), negativoProduto) {
+			faltas = append(faltas, novaFaltaEstoque(n.produtoID, n.codigo, n.solicitado, necessidades[i].disponivel))
+		}
+	}
+
+	// Rejeitar o pedido inteiro se qualquer item não tiver estoque
+	if len(faltas) > 0 {
+		log.Printf("[ERROR] Estoque insuficiente para %d itens do pedido de saída ID: %d", len(faltas), id)
+		c.JSON(http.StatusConflict, novoErroFaltaEstoque("Quantidade insuficiente em estoque para o pedido", faltas))
+		return
+	}
+
+	notas := fmt.Sprintf("Separação do pedido de saída #%d", id)
+
+	// Gerar uma movimentação de saída por item (por componente, nos kits)
+	movimentacoes := []Movimentacao{}
+	for _, b := range baixas {
+		m := Movimentacao{ProdutoID: b.ProdutoID, Tipo: "saida", Quantidade: b.Quantidade, Notas: notas}
+		if b.kit != "" {
+			m.Notas = fmt.Sprintf("%s (kit %s)", notas, b.kit)
+		}
+		dados[chaveBaixa{b.itemID, b.ProdutoID}].aplicar(&m)
+		// Séries, produtos perigosos, checklist, lotes (FEFO) e saldo seguem as
+		// regras de qualquer saída
+		if err = registrarMovimentacao(c.Request.Context(), tx, &m); err != nil {
+			e, ok := err.(*erroMovimentacao)
+			if !ok {
+				log.Printf("[ERROR] Erro ao registrar baixa do produto %d: %v", b.ProdutoID, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar movimentação"})
+				return
+			}
+			log.Printf("[ERROR] Baixa do produto %d recusada na separação do pedido %d: %s", b.ProdutoID, id, e.msg)
+			c.JSON(e.status, e.resposta())
+			return
+		}
+
+		movimentacoes = append(movimentacoes, m)
+	}
+
+	_, err = tx.Exec(c.Request.Context(), "UPDATE pedidos_saida SET status = $1 WHERE id = $2", PedidoSaidaSeparado, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar status do pedido de saída: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar status do pedido de saída"})
+		return
+	}
+
+	log.Printf("[DB] Confirmando transação")
+	if err = tx.Commit(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Separação do pedido de saída ID: %d confirmada (%d movimentações)", id, len(movimentacoes))
+	publicarMovimentacoes(c.Request.Context(), movimentacoes)
+	c.JSON(http.StatusOK, gin.H{
+		"pedido_id":     id,
+		"status":        PedidoSaidaSeparado,
+		"movimentacoes": movimentacoes,
+	})
+}
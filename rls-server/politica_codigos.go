@@ -0,0 +1,352 @@
+// politica_codigos.go - Formato canônico dos códigos de produto
+//
+// Na criação e edição de produtos o código é normalizado (espaços nas pontas
+// removidos e, com codigo_maiusculas, convertido para maiúsculas) e validado
+// pelo tamanho (codigo_tamanho_minimo/maximo) e pela expressão regular
+// codigo_formato_regex, que precisa casar com o código inteiro. As regras
+// ficam nas configurações e valem sem reiniciar o servidor. Na edição, um
+// código antigo que não foi alterado continua aceito.
+//
+// GET /api/produtos/codigos/fora-do-padrao lista os códigos que não atendem à
+// regra, com uma sugestão, e POST /api/produtos/codigos/renomear renomeia em
+// massa: o produto mantém o ID (movimentações, lotes e pedidos não mudam) e o
+// código anterior fica registrado em historico_codigos.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type regraCodigo struct {
+	maiusculas bool
+	minimo     int
+	maximo     int
+	expressao  string
+	formato    *regexp.Regexp
+}
+
+// Última expressão compilada, para não recompilar a cada produto
+var regexCodigoCache struct {
+	mu        sync.Mutex
+	expressao string
+	formato   *regexp.Regexp
+}
+
+func compilarRegexCodigo(expressao string) *regexp.Regexp {
+	regexCodigoCache.mu.Lock()
+	defer regexCodigoCache.mu.Unlock()
+
+	if expressao != regexCodigoCache.expressao || regexCodigoCache.formato == nil {
+		formato, err := regexp.Compile("^(?:" + expressao + ")$")
+		if err != nil {
+			log.Printf("[WARN] codigo_formato_regex inválida, ignorada: %v", err)
+			return nil
+		}
+		regexCodigoCache.expressao, regexCodigoCache.formato = expressao, formato
+	}
+	return regexCodigoCache.formato
+}
+
+// Lê a regra de formato das configurações
+func lerRegraCodigo(ctx context.Context) regraCodigo {
+	r := regraCodigo{
+		maiusculas: lerConfiguracao(ctx, "codigo_maiusculas", "true") == "true",
+		minimo:     1,
+		maximo:     50,
+		expressao:  lerConfiguracao(ctx, "codigo_formato_regex", ""),
+	}
+	if v, err := strconv.Atoi(lerConfiguracao(ctx, "codigo_tamanho_minimo", "1")); err == nil && v >= 1 {
+		r.minimo = v
+	}
+	// O máximo não passa do tamanho da coluna
+	if v, err := strconv.Atoi(lerConfiguracao(ctx, "codigo_tamanho_maximo", "50")); err == nil && v >= r.minimo {
+		r.maximo = min(v, 50)
+	}
+	if r.expressao != "" {
+		r.formato = compilarRegexCodigo(r.expressao)
+	}
+	return r
+}
+
+func (r regraCodigo) normalizar(codigo string) string {
+	codigo = strings.TrimSpace(codigo)
+	if r.maiusculas {
+		codigo = strings.ToUpper(codigo)
+	}
+	return codigo
+}
+
+// Devolve a mensagem de erro ou "" se o código (já normalizado) atende à regra
+func (r regraCodigo) validar(codigo string) string {
+	if n := utf8.RuneCountInString(codigo); n < r.minimo || n > r.maximo {
+		return fmt.Sprintf("Código deve ter entre %d e %d caracteres", r.minimo, r.maximo)
+	}
+	if r.formato != nil && !r.formato.MatchString(codigo) {
+		return "Código fora do formato definido (" + r.expressao + ")"
+	}
+	return ""
+}
+
+// Sugestão de código no padrão: normalizado e com espaços internos trocados
+// por hífen; "" se nem assim atende à regra
+func (r regraCodigo) sugerir(codigo string) string {
+	sugestao := strings.Join(strings.Fields(r.normalizar(codigo)), "-")
+	if r.validar(sugestao) != "" {
+		return ""
+	}
+	return sugestao
+}
+
+// Normaliza o código no lugar e devolve a mensagem de erro ou "" se válido
+func normalizarCodigoProduto(ctx context.Context, codigo *string) string {
+	r := lerRegraCodigo(ctx)
+	*codigo = r.normalizar(*codigo)
+	return r.validar(*codigo)
+}
+
+// Código enviado na edição: mantido se não mudou (códigos antigos fora do
+// padrão continuam editáveis), senão normalizado e validado
+func validarCodigoEditado(ctx context.Context, atual string, codigo *string) string {
+	if strings.TrimSpace(*codigo) == atual {
+		*codigo = atual
+		return ""
+	}
+	return normalizarCodigoProduto(ctx, codigo)
+}
+
+// Registra a troca de código de um produto; o código anterior passa a ser
+// alias do produto e o novo deixa de ser alias de outro
+func registrarCodigoAnterior(ctx context.Context, q querier, produtoID int, anterior, novo string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO historico_codigos (produto_id, codigo_anterior, codigo_novo)
+		VALUES ($1, $2, $3)
+	`, produtoID, anterior, novo)
+	if err != nil {
+		return err
+	}
+	if _, err = q.Exec(ctx, "DELETE FROM aliases_codigos WHERE codigo = $1", novo); err != nil {
+		return err
+	}
+	return registrarAliasCodigo(ctx, q, anterior, produtoID, OrigemAliasRenomeacao)
+}
+
+type CodigoForaPadrao struct {
+	ProdutoID int    `json:"produto_id"`
+	Codigo    string `json:"codigo"`
+	Nome      string `json:"nome"`
+	Motivo    string `json:"motivo"`
+	Sugestao  string `json:"sugestao,omitempty"`
+}
+
+type RenomeacaoCodigo struct {
+	CodigoAtual string `json:"codigo_atual"`
+	CodigoNovo  string `json:"codigo_novo"`
+}
+
+type RequisicaoRenomearCodigos struct {
+	Itens []RenomeacaoCodigo `json:"itens"`
+	// Sem itens, aplica as sugestões do relatório de códigos fora do padrão
+	Sugestoes bool `json:"sugestoes"`
+	// Só valida e devolve o que seria renomeado
+	Simular bool `json:"simular"`
+}
+
+type ResultadoRenomearCodigos struct {
+	Renomeados int                `json:"renomeados"`
+	Itens      []RenomeacaoCodigo `json:"itens"`
+	Simulacao  bool               `json:"simulacao"`
+}
+
+// Função auxiliar para listar os códigos que não atendem à regra
+func listarCodigosForaPadrao(ctx context.Context) ([]CodigoForaPadrao, error) {
+	regra := lerRegraCodigo(ctx)
+
+	rows, err := db.Query(ctx, "SELECT id, codigo, nome FROM produtos ORDER BY codigo")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fora := []CodigoForaPadrao{}
+	for rows.Next() {
+		var f CodigoForaPadrao
+		if err := rows.Scan(&f.ProdutoID, &f.Codigo, &f.Nome); err != nil {
+			return nil, err
+		}
+		if normalizado := regra.normalizar(f.Codigo); normalizado != f.Codigo {
+			f.Motivo = "Código não normalizado (espaços ou minúsculas)"
+		} else if msg := regra.validar(f.Codigo); msg != "" {
+			f.Motivo = msg
+		} else {
+			continue
+		}
+		f.Sugestao = regra.sugerir(f.Codigo)
+		fora = append(fora, f)
+	}
+	return fora, rows.Err()
+}
+
+// Handlers da Política de Códigos
+
+func getCodigosForaPadrao(c *gin.Context) {
+	log.Println("[DB] Buscando códigos de produto fora do padrão")
+
+	fora, err := listarCodigosForaPadrao(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar códigos fora do padrão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar códigos fora do padrão"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fora)
+}
+
+func renomearCodigos(c *gin.Context) {
+	var req RequisicaoRenomearCodigos
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	ctx := c.Request.Context()
+
+	if len(req.Itens) == 0 && req.Sugestoes {
+		fora, err := listarCodigosForaPadrao(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao buscar códigos fora do padrão: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar códigos fora do padrão"})
+			return
+		}
+		for _, f := range fora {
+			if f.Sugestao != "" {
+				req.Itens = append(req.Itens, RenomeacaoCodigo{CodigoAtual: f.Codigo, CodigoNovo: f.Sugestao})
+			}
+		}
+	}
+	if len(req.Itens) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nenhuma renomeação informada"})
+		return
+	}
+
+	// Validar os códigos novos e repetições dentro do lote
+	regra := lerRegraCodigo(ctx)
+	atuais := map[string]bool{}
+	novos := map[string]bool{}
+	for i := range req.Itens {
+		item := &req.Itens[i]
+		item.CodigoNovo = regra.normalizar(item.CodigoNovo)
+		if msg := regra.validar(item.CodigoNovo); msg != "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: item.CodigoAtual + ": " + msg})
+			return
+		}
+		if item.CodigoNovo == item.CodigoAtual {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: item.CodigoAtual + ": código novo igual ao atual"})
+			return
+		}
+		if atuais[item.CodigoAtual] || novos[item.CodigoNovo] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Código repetido no lote: " + item.CodigoAtual + " → " + item.CodigoNovo})
+			return
+		}
+		atuais[item.CodigoAtual] = true
+		novos[item.CodigoNovo] = true
+	}
+
+	log.Printf("[API] Renomeando %d códigos de produto (simulação: %t)", len(req.Itens), req.Simular)
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	ids := make([]int, 0, len(req.Itens))
+	for _, item := range req.Itens {
+		var id int
+		err := tx.QueryRow(ctx, "SELECT id FROM produtos WHERE codigo = $1 FOR UPDATE", item.CodigoAtual).Scan(&id)
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado: " + item.CodigoAtual})
+			return
+		} else if err != nil {
+			log.Printf("[ERROR] Erro ao buscar produto %s: %v", item.CodigoAtual, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+			return
+		}
+
+		// Trocas entre códigos do próprio lote não são aceitas: o código novo
+		// precisa estar livre
+		var existe bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM produtos WHERE codigo = $1)", item.CodigoNovo).Scan(&existe); err != nil {
+			log.Printf("[ERROR] Erro ao verificar código %s: %v", item.CodigoNovo, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto existente"})
+			return
+		}
+		if existe {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe um produto com o código " + item.CodigoNovo, Codigo: CodigoCodigoDuplicado})
+			return
+		}
+
+		if !req.Simular {
+			_, err = tx.Exec(ctx, "UPDATE produtos SET codigo = $1, data_atualizacao = CURRENT_TIMESTAMP WHERE id = $2", item.CodigoNovo, id)
+			if err == nil {
+				err = registrarCodigoAnterior(ctx, tx, id, item.CodigoAtual, item.CodigoNovo)
+			}
+			if err != nil {
+				log.Printf("[ERROR] Erro ao renomear %s para %s: %v", item.CodigoAtual, item.CodigoNovo, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao renomear códigos"})
+				return
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	resultado := ResultadoRenomearCodigos{Itens: req.Itens, Simulacao: req.Simular}
+	if req.Simular {
+		c.JSON(http.StatusOK, resultado)
+		return
+	}
+
+	// Os clientes conectados e os webhooks recebem os produtos com o código novo
+	renomeados := make([]Produto, 0, len(ids))
+	for _, id := range ids {
+		p, err := scanProduto(tx.QueryRow(ctx, "SELECT "+produtoColunas+" FROM produtos WHERE id = $1", id))
+		if err == nil {
+			err = enfileirarEntregasWebhook(ctx, tx, EventoProdutoAtualizado, p)
+		}
+		if err != nil {
+			log.Printf("[ERROR] Erro ao enfileirar entregas do produto renomeado %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao renomear códigos"})
+			return
+		}
+		renomeados = append(renomeados, p)
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+	resultado.Renomeados = len(ids)
+	log.Printf("[DB] %d códigos de produto renomeados", resultado.Renomeados)
+
+	for _, p := range renomeados {
+		eventos.publicar(EventoProdutoAtualizado, p)
+	}
+
+	c.JSON(http.StatusOK, resultado)
+}
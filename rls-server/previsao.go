@@ -0,0 +1,168 @@
+// previsao.go - Previsão de consumo e data estimada de ruptura por produto
+
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Modelos de previsão aceitos
+const (
+	PrevisaoExponencial = "exponencial"
+	PrevisaoMediaMovel  = "media_movel"
+)
+
+type PrevisaoConsumo struct {
+	ProdutoID          int        `json:"produto_id"`
+	ProdutoCodigo      string     `json:"produto_codigo"`
+	ProdutoNome        string     `json:"produto_nome"`
+	Quantidade         int        `json:"quantidade"`
+	Metodo             string     `json:"metodo"`
+	DiasHistorico      int        `json:"dias_historico"`
+	ConsumoDiario      float64    `json:"consumo_diario"`
+	Horizonte          int        `json:"horizonte"`
+	DemandaProjetada   float64    `json:"demanda_projetada"`
+	DiasAteRuptura     *float64   `json:"dias_ate_ruptura,omitempty"`
+	DataRuptura        *time.Time `json:"data_ruptura,omitempty"`
+	RupturaNoHorizonte bool       `json:"ruptura_no_horizonte"`
+}
+
+// Suavização exponencial simples: o nível final é a previsão de consumo diário
+func suavizacaoExponencial(serie []float64, alpha float64) float64 {
+	if len(serie) == 0 {
+		return 0
+	}
+	nivel := serie[0]
+	for _, v := range serie[1:] {
+		nivel = alpha*v + (1-alpha)*nivel
+	}
+	return nivel
+}
+
+// Média dos últimos "janela" dias da série
+func mediaMovel(serie []float64, janela int) float64 {
+	if len(serie) == 0 {
+		return 0
+	}
+	janela = min(janela, len(serie))
+	soma := 0.0
+	for _, v := range serie[len(serie)-janela:] {
+		soma += v
+	}
+	return soma / float64(janela)
+}
+
+// Handler para previsão de consumo
+
+func getPrevisaoConsumo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "90"))
+	if err != nil || dias <= 0 {
+		dias = 90
+	}
+	horizonte, err := strconv.Atoi(c.DefaultQuery("horizonte", "30"))
+	if err != nil || horizonte <= 0 {
+		horizonte = 30
+	}
+	metodo := c.DefaultQuery("metodo", PrevisaoExponencial)
+	if metodo != PrevisaoExponencial && metodo != PrevisaoMediaMovel {
+		log.Printf("[ERROR] Método de previsão inválido: %s", metodo)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Método de previsão inválido, use exponencial ou media_movel"})
+		return
+	}
+	alpha, err := strconv.ParseFloat(c.DefaultQuery("alpha", "0.3"), 64)
+	if err != nil || alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	janela, err := strconv.Atoi(c.DefaultQuery("janela", "7"))
+	if err != nil || janela <= 0 {
+		janela = 7
+	}
+
+	log.Printf("[DB] Calculando previsão de consumo do produto ID: %d (%s, %d dias)", id, metodo, dias)
+
+	ctx := c.Request.Context()
+	previsao := PrevisaoConsumo{ProdutoID: id, Metodo: metodo, DiasHistorico: dias, Horizonte: horizonte}
+	err = db.QueryRow(ctx, "SELECT codigo, nome, quantidade FROM produtos WHERE id = $1", id).
+		Scan(&previsao.ProdutoCodigo, &previsao.ProdutoNome, &previsao.Quantidade)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+
+	// Saídas diárias, com os dias sem movimento zerados
+	rows, err := db.Query(ctx, `
+		SELECT COALESCE(SUM(m.quantidade), 0)
+		FROM generate_series(CURRENT_DATE - ($2::int - 1), CURRENT_DATE, interval '1 day') AS d(dia)
+		LEFT JOIN movimentacoes m
+		       ON m.produto_id = $1 AND m.tipo = 'saida'
+		      AND m.data_movimentacao >= d.dia AND m.data_movimentacao < d.dia + interval '1 day'
+		GROUP BY d.dia
+		ORDER BY d.dia
+	`, id, dias)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar histórico de saídas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar histórico de saídas"})
+		return
+	}
+	defer rows.Close()
+
+	serie := make([]float64, 0, dias)
+	for rows.Next() {
+		var quantidade int
+		if err := rows.Scan(&quantidade); err != nil {
+			log.Printf("[ERROR] Erro ao processar histórico de saídas: %v", err)
+			continue
+		}
+		serie = append(serie, float64(quantidade))
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar histórico de saídas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar histórico de saídas"})
+		return
+	}
+
+	if metodo == PrevisaoMediaMovel {
+		previsao.ConsumoDiario = mediaMovel(serie, janela)
+	} else {
+		previsao.ConsumoDiario = suavizacaoExponencial(serie, alpha)
+	}
+	previsao.ConsumoDiario = math.Round(previsao.ConsumoDiario*100) / 100
+	previsao.DemandaProjetada = previsao.ConsumoDiario * float64(horizonte)
+
+	// Sem consumo previsto não há data de ruptura
+	if previsao.ConsumoDiario > 0 {
+		diasAteRuptura := math.Max(0, float64(previsao.Quantidade)/previsao.ConsumoDiario)
+		diasAteRuptura = math.Round(diasAteRuptura*10) / 10
+		dataRuptura := time.Now().AddDate(0, 0, int(math.Floor(diasAteRuptura)))
+		previsao.DiasAteRuptura = &diasAteRuptura
+		previsao.DataRuptura = &dataRuptura
+		previsao.RupturaNoHorizonte = diasAteRuptura <= float64(horizonte)
+	}
+
+	log.Printf("[API] Previsão do produto ID %d: %.2f/dia", id, previsao.ConsumoDiario)
+	c.JSON(http.StatusOK, previsao)
+}
@@ -0,0 +1,185 @@
+// problemas.go - Respostas de erro no formato application/problem+json (RFC 7807)
+//
+// Os handlers continuam respondendo com ErrorResponse (ou outro corpo com
+// "error", como o de falta de estoque); o middleware Problemas converte toda
+// resposta de erro em JSON num problema com type, title, status, detail e
+// instance, o código estável em "code" (ex.: PRODUTO_NAO_ENCONTRADO) para o
+// app traduzir a mensagem, os erros por campo em "campos" e o request_id.
+// Sem código definido pelo handler vale o código genérico do status. O
+// "error" com a mensagem em português e os demais membros do corpo original
+// ("faltas", por exemplo) são mantidos, então clientes antigos continuam
+// funcionando.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Códigos de erro com significado próprio para o app
+const (
+	CodigoDadosInvalidos           = "DADOS_INVALIDOS"
+	CodigoProdutoNaoEncontrado     = "PRODUTO_NAO_ENCONTRADO"
+	CodigoCodigoDuplicado          = "CODIGO_DUPLICADO"
+	CodigoEstoqueInsuficiente      = "ESTOQUE_INSUFICIENTE"
+	CodigoRotaNaoEncontrada        = "ROTA_NAO_ENCONTRADA"
+	CodigoLocalizacaoNaoEncontrada = "LOCALIZACAO_NAO_ENCONTRADA"
+)
+
+// Título de cada código (o title do problema)
+var titulosProblema = map[string]string{
+	CodigoDadosInvalidos:           "Dados inválidos",
+	CodigoProdutoNaoEncontrado:     "Produto não encontrado",
+	CodigoCodigoDuplicado:          "Código já cadastrado",
+	CodigoEstoqueInsuficiente:      "Estoque insuficiente",
+	CodigoRotaNaoEncontrada:        "Rota não encontrada",
+	CodigoLocalizacaoNaoEncontrada: "Localização não encontrada",
+	"REQUISICAO_INVALIDA":          "Requisição inválida",
+	"NAO_AUTENTICADO":              "Não autenticado",
+	"ACESSO_NEGADO":                "Acesso negado",
+	"NAO_ENCONTRADO":               "Não encontrado",
+	"CONFLITO":                     "Conflito",
+	"PRECONDICAO_FALHOU":           "Pré-condição falhou",
+	"CONTEUDO_MUITO_GRANDE":        "Conteúdo muito grande",
+	"TIPO_NAO_SUPORTADO":           "Tipo de conteúdo não suportado",
+	"ENTIDADE_INVALIDA":            "Entidade inválida",
+	"LIMITE_EXCEDIDO":              "Limite de requisições excedido",
+	"ERRO_INTERNO":                 "Erro interno",
+	"SERVICO_INDISPONIVEL":         "Serviço indisponível",
+	"TEMPO_ESGOTADO":               "Tempo esgotado",
+}
+
+// Código genérico de cada status, para erros sem código próprio
+var codigosPorStatus = map[int]string{
+	http.StatusBadRequest:            "REQUISICAO_INVALIDA",
+	http.StatusUnauthorized:          "NAO_AUTENTICADO",
+	http.StatusForbidden:             "ACESSO_NEGADO",
+	http.StatusNotFound:              "NAO_ENCONTRADO",
+	http.StatusConflict:              "CONFLITO",
+	http.StatusPreconditionFailed:    "PRECONDICAO_FALHOU",
+	http.StatusRequestEntityTooLarge: "CONTEUDO_MUITO_GRANDE",
+	http.StatusUnsupportedMediaType:  "TIPO_NAO_SUPORTADO",
+	http.StatusUnprocessableEntity:   "ENTIDADE_INVALIDA",
+	http.StatusTooManyRequests:       "LIMITE_EXCEDIDO",
+	http.StatusInternalServerError:   "ERRO_INTERNO",
+	http.StatusServiceUnavailable:    "SERVICO_INDISPONIVEL",
+	http.StatusGatewayTimeout:        "TEMPO_ESGOTADO",
+}
+
+func codigoPorStatus(status int) string {
+	if codigo, ok := codigosPorStatus[status]; ok {
+		return codigo
+	}
+	if status >= 500 {
+		return "ERRO_INTERNO"
+	}
+	return "ERRO_" + strconv.Itoa(status)
+}
+
+// Erro de validação de um campo da requisição
+type ErroCampo struct {
+	Campo    string `json:"campo"`
+	Codigo   string `json:"code"`
+	Mensagem string `json:"mensagem"`
+}
+
+// Resposta de erro no formato problem+json; os membros do corpo original do
+// handler que não estão aqui seguem junto
+type Problema struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance,omitempty"`
+	Codigo    string      `json:"code"`
+	Campos    []ErroCampo `json:"campos,omitempty"`
+	Error     string      `json:"error"` // o mesmo que detail, para os clientes antigos
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Resposta de corpo JSON inválido, com as violações por campo quando o
+// erro de decodificação ou de validação as identifica
+func erroDadosInvalidos(err error) ErrorResponse {
+	return ErrorResponse{Error: "Dados inválidos", Codigo: CodigoDadosInvalidos, Campos: errosDeCampo(err)}
+}
+
+// Converte o corpo de erro de um handler ({"error": ...}) em problema; falso
+// se o corpo não tiver esse formato
+func converterProblema(corpo []byte, status int, instancia, requestID string) ([]byte, bool) {
+	var membros map[string]json.RawMessage
+	if err := json.Unmarshal(corpo, &membros); err != nil {
+		return nil, false
+	}
+	var p Problema
+	if err := json.Unmarshal(corpo, &p); err != nil || p.Error == "" {
+		return nil, false
+	}
+
+	if p.Codigo == "" {
+		p.Codigo = codigoPorStatus(status)
+	}
+	p.Type = "urn:rls-estoque:erro:" + strings.ToLower(strings.ReplaceAll(p.Codigo, "_", "-"))
+	p.Title = titulosProblema[p.Codigo]
+	if p.Title == "" {
+		p.Title = http.StatusText(status)
+	}
+	p.Status = status
+	p.Detail = p.Error
+	p.Instance = instancia
+	p.RequestID = requestID
+
+	campos, err := json.Marshal(p)
+	if err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(campos, &membros); err != nil {
+		return nil, false
+	}
+	convertido, err := json.Marshal(membros)
+	return convertido, err == nil
+}
+
+// Converte as respostas de erro em JSON, que os handlers escrevem de uma vez
+// com c.JSON, em problem+json com o request_id
+type escritorProblema struct {
+	gin.ResponseWriter
+	id        string
+	instancia string
+}
+
+func (w *escritorProblema) Write(b []byte) (int, error) {
+	if w.Status() < 400 || w.Written() || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") ||
+		!bytes.HasPrefix(b, []byte(`{"error":`)) {
+		return w.ResponseWriter.Write(b)
+	}
+
+	corpo, ok := converterProblema(b, w.Status(), w.instancia, w.id)
+	if !ok {
+		return w.ResponseWriter.Write(b)
+	}
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	if _, err := w.ResponseWriter.Write(corpo); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Middleware que converte as respostas de erro; precisa ficar depois de
+// Compressao, que recebe o corpo já convertido
+func Problemas() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &escritorProblema{ResponseWriter: c.Writer, id: idRequisicao(c.Request.Context()), instancia: c.Request.URL.Path}
+		c.Next()
+	}
+}
+
+// Handler das rotas inexistentes, no mesmo formato de erro da API
+func rotaNaoEncontrada(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{Error: "Rota não encontrada", Codigo: CodigoRotaNaoEncontrada})
+}
@@ -0,0 +1,183 @@
+// produtos.go - Handlers de consulta e exclusão de produtos
+//
+// Primeira parte da separação em camadas: estes handlers só tratam HTTP
+// (parâmetros, status e mensagens) e delegam ao servico.Produtos, que aplica
+// as regras e usa o repositorio.Produtos para o SQL. As dependências chegam
+// pelo construtor, montado em configurarRouter.
+
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rlsautomacao/estoque/internal/estoque"
+	"github.com/rlsautomacao/estoque/internal/servico"
+)
+
+type handlersProdutos struct {
+	produtos *servico.Produtos
+}
+
+func novoHandlersProdutos(produtos *servico.Produtos) *handlersProdutos {
+	return &handlersProdutos{produtos: produtos}
+}
+
+// Handlers de Produtos
+
+func (h *handlersProdutos) listar(c *gin.Context) {
+	log.Println("[DB] Buscando lista de produtos")
+
+	// Parâmetros opcionais de consulta para paginação
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	// Filtros opcionais: categoria, atributos (atributos[voltagem]=220) e
+	// tags (tags=a,b: produtos com todas)
+	filtro := estoque.Filtro{
+		Categoria: strings.TrimSpace(c.Query("categoria")),
+		Atributos: c.QueryMap("atributos"),
+		Tags:      tagsConsulta(c.Query("tags")),
+	}
+
+	produtos, err := h.produtos.Listar(c.Request.Context(), filtro, limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao consultar produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d produtos", len(produtos))
+	c.Set(chaveRegistrosExportacao, len(produtos))
+	c.JSON(http.StatusOK, produtos)
+}
+
+func (h *handlersProdutos) buscar(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando produto com ID: %d", id)
+
+	p, err := h.produtos.Buscar(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, servico.ErrProdutoNaoEncontrado) {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Produto encontrado: %s (ID: %d)", p.Nome, p.ID)
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *handlersProdutos) buscarPorCodigo(c *gin.Context) {
+	// Obter código da URL
+	codigo := c.Param("codigo")
+	log.Printf("[DB] Buscando produto com código: %s", codigo)
+
+	p, obsoleto, err := h.produtos.BuscarPorCodigo(c.Request.Context(), codigo)
+	if err != nil {
+		if errors.Is(err, servico.ErrProdutoNaoEncontrado) {
+			log.Printf("[DB] Produto não encontrado com código: %s", codigo)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+
+	// Código antigo (renomeado ou mesclado) leva ao produto atual com aviso
+	if obsoleto {
+		log.Printf("[DB] Código obsoleto %s resolvido para o produto %s (ID: %d)", codigo, p.Codigo, p.ID)
+		c.JSON(http.StatusOK, ProdutoResolvido{Produto: p, CodigoObsoleto: novoAvisoCodigoObsoleto(codigo, p.Codigo)})
+		return
+	}
+
+	log.Printf("[DB] Produto encontrado: %s (ID: %d)", p.Nome, p.ID)
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *handlersProdutos) variantes(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando variantes do produto ID: %d", id)
+
+	produtos, err := h.produtos.Variantes(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, servico.ErrProdutoNaoEncontrado) {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar variantes: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar variantes"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Retornando %d variantes do produto ID: %d", len(produtos), id)
+	c.JSON(http.StatusOK, produtos)
+}
+
+func (h *handlersProdutos) estoqueBaixo(c *gin.Context) {
+	log.Println("[DB] Buscando produtos com estoque baixo")
+
+	produtos, err := h.produtos.EstoqueBaixo(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar produtos com estoque baixo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos com estoque baixo"})
+		return
+	}
+
+	log.Printf("[DB] Encontrados %d produtos com estoque baixo", len(produtos))
+	c.JSON(http.StatusOK, produtos)
+}
+
+func (h *handlersProdutos) excluir(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[API] Iniciando exclusão de produto ID: %d", id)
+
+	if err := h.produtos.Excluir(c.Request.Context(), id); err != nil {
+		if errors.Is(err, servico.ErrProdutoNaoEncontrado) {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao excluir produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir produto"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Produto excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Produto excluído com sucesso"})
+}
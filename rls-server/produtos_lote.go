@@ -0,0 +1,249 @@
+// produtos_lote.go - Alteração em massa de cadastro de produtos
+//
+// PATCH /api/produtos/lote aplica a mesma alteração parcial (fornecedor,
+// localização, categoria, quantidades mínima/máxima, política de estoque
+// negativo) a uma lista de IDs ou a todos os produtos de um filtro, como na
+// realocação de prateleiras. Saldo não é alterado aqui: quantidade só muda
+// por movimentação. Cada produto é atualizado em seu próprio savepoint e a
+// resposta traz o resultado de cada um.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Campos alteráveis em massa; nil mantém o valor atual
+type AlteracaoProdutos struct {
+	Fornecedor              *string `json:"fornecedor"`
+	Localizacao             *string `json:"localizacao"`
+	Categoria               *string `json:"categoria"`
+	QuantidadeMinima        *int    `json:"quantidade_minima"`
+	QuantidadeMaxima        *int    `json:"quantidade_maxima"`
+	PermitirEstoqueNegativo *bool   `json:"permitir_estoque_negativo"`
+	LocalizacaoID           *int    `json:"localizacao_id"`
+}
+
+// Filtro de produtos: fornecedor e categoria exatos, localização por prefixo
+type FiltroProdutos struct {
+	Fornecedor  string `json:"fornecedor,omitempty"`
+	Categoria   string `json:"categoria,omitempty"`
+	Localizacao string `json:"localizacao,omitempty"`
+}
+
+type AtualizacaoLoteProdutos struct {
+	IDs       []int             `json:"ids,omitempty"`
+	Filtro    *FiltroProdutos   `json:"filtro,omitempty"`
+	Alteracao AlteracaoProdutos `json:"alteracao"`
+}
+
+type ResultadoProdutoLote struct {
+	ID      int      `json:"id"`
+	Status  string   `json:"status"` // ok ou erro
+	Erro    string   `json:"erro,omitempty"`
+	Produto *Produto `json:"produto,omitempty"`
+}
+
+func (a AlteracaoProdutos) vazia() bool {
+	return a.Fornecedor == nil && a.Localizacao == nil && a.Categoria == nil &&
+		a.QuantidadeMinima == nil && a.QuantidadeMaxima == nil && a.PermitirEstoqueNegativo == nil &&
+		a.LocalizacaoID == nil
+}
+
+// A alteração troca a posição do almoxarifado (e o texto da localização)
+func (a AlteracaoProdutos) alteraLocalizacao() bool {
+	return a.Localizacao != nil || a.LocalizacaoID != nil
+}
+
+// Função auxiliar para resolver os IDs do filtro
+func idsPorFiltro(ctx context.Context, f FiltroProdutos) ([]int, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id FROM produtos
+		WHERE ($1::text = '' OR fornecedor = $1::text)
+			AND ($2::text = '' OR categoria = $2::text)
+			AND ($3::text = '' OR localizacao LIKE $3::text || '%')
+		ORDER BY id
+	`, f.Fornecedor, f.Categoria, f.Localizacao)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Atualiza um produto dentro do savepoint; devolve a mensagem de erro para o cliente
+func alterarProdutoLote(ctx context.Context, tx pgx.Tx, id int, a AlteracaoProdutos) (Produto, string) {
+	p, err := scanProduto(tx.QueryRow(ctx, `
+		UPDATE produtos SET
+			fornecedor = COALESCE($1, fornecedor),
+			localizacao = COALESCE($2, localizacao),
+			categoria = CASE WHEN $3::text IS NULL THEN categoria ELSE NULLIF($3, '') END,
+			quantidade_minima = COALESCE($4, quantidade_minima),
+			quantidade_maxima = COALESCE($5, quantidade_maxima),
+			permitir_estoque_negativo = COALESCE($6, permitir_estoque_negativo),
+			localizacao_id = CASE WHEN $7 THEN $8 ELSE localizacao_id END
+		WHERE id = $9
+		RETURNING `+produtoColunas,
+		a.Fornecedor, a.Localizacao, a.Categoria, a.QuantidadeMinima, a.QuantidadeMaxima, a.PermitirEstoqueNegativo,
+		a.alteraLocalizacao(), a.LocalizacaoID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return p, "Produto não encontrado"
+		}
+		log.Printf("[ERROR] Erro ao atualizar produto %d no lote: %v", id, err)
+		return p, "Erro ao atualizar produto"
+	}
+
+	if p.QuantidadeMaxima > 0 && p.QuantidadeMaxima < p.QuantidadeMinima {
+		return p, "Quantidade máxima deve ser maior ou igual à quantidade mínima"
+	}
+
+	// Os atributos do produto precisam valer na nova categoria
+	if a.Categoria != nil {
+		_, campos, err := validarAtributos(ctx, tx, p.Categoria, p.Atributos)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao verificar atributos do produto %d no lote: %v", id, err)
+			return p, "Erro ao atualizar produto"
+		}
+		if len(campos) > 0 {
+			violacoes := make([]string, len(campos))
+			for i, campo := range campos {
+				violacoes[i] = campo.Campo + " " + campo.Mensagem
+			}
+			return p, "Atributos inválidos para a categoria: " + strings.Join(violacoes, "; ")
+		}
+	}
+	return p, ""
+}
+
+func atualizarProdutosLote(c *gin.Context) {
+	var req AtualizacaoLoteProdutos
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	// Validar alteração e seleção
+	if req.Alteracao.vazia() {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe ao menos um campo em alteracao"})
+		return
+	}
+	a := req.Alteracao
+	if (a.QuantidadeMinima != nil && *a.QuantidadeMinima < 0) || (a.QuantidadeMaxima != nil && *a.QuantidadeMaxima < 0) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Quantidades não podem ser negativas"})
+		return
+	}
+	filtroVazio := req.Filtro == nil ||
+		(req.Filtro.Fornecedor == "" && req.Filtro.Categoria == "" && strings.TrimSpace(req.Filtro.Localizacao) == "")
+	if (len(req.IDs) == 0) == filtroVazio {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe a lista de ids ou um filtro (não ambos)"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ids := req.IDs
+	if !filtroVazio {
+		var err error
+		if ids, err = idsPorFiltro(ctx, *req.Filtro); err != nil {
+			log.Printf("[ERROR] Erro ao buscar produtos do filtro: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
+			return
+		}
+	}
+
+	// Posição resolvida uma vez para todos os produtos (realocação de prateleira)
+	if req.Alteracao.alteraLocalizacao() {
+		texto := ""
+		if a.Localizacao != nil {
+			texto = *a.Localizacao
+		}
+		localizacaoID, texto, err := resolverLocalizacao(ctx, db, a.LocalizacaoID, texto)
+		if err != nil {
+			log.Printf("[ERROR] Localização inválida na alteração em massa: %v", err)
+			responderErroLocalizacao(c, err)
+			return
+		}
+		req.Alteracao.LocalizacaoID, req.Alteracao.Localizacao = localizacaoID, &texto
+	}
+
+	log.Printf("[API] Iniciando alteração em massa de %d produtos", len(ids))
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	resultados := make([]ResultadoProdutoLote, len(ids))
+	var alterados []Produto
+	for i, id := range ids {
+		resultados[i] = ResultadoProdutoLote{ID: id, Status: "erro"}
+
+		// Savepoint: um produto com erro não desfaz os demais
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao criar savepoint: %v", err)
+			resultados[i].Erro = "Erro ao atualizar produto"
+			continue
+		}
+		p, msg := alterarProdutoLote(ctx, sp, id, req.Alteracao)
+		if msg == "" {
+			if err = enfileirarEntregasProduto(ctx, sp, p); err != nil {
+				log.Printf("[ERROR] Erro ao enfileirar entregas de webhook: %v", err)
+				msg = "Erro ao atualizar produto"
+			}
+		}
+		if msg != "" {
+			sp.Rollback(ctx)
+			resultados[i].Erro = msg
+			continue
+		}
+		if err = sp.Commit(ctx); err != nil {
+			log.Printf("[ERROR] Erro ao liberar savepoint: %v", err)
+			resultados[i].Erro = "Erro ao atualizar produto"
+			continue
+		}
+
+		resultados[i].Status = "ok"
+		resultados[i].Produto = &p
+		alterados = append(alterados, p)
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	for _, p := range alterados {
+		eventos.publicar(EventoProdutoAtualizado, p)
+		publicarSeEstoqueBaixo(p)
+	}
+
+	log.Printf("[DB] Alteração em massa concluída: %d de %d produtos", len(alterados), len(ids))
+	c.JSON(http.StatusOK, gin.H{
+		"alterados": len(alterados),
+		"falhas":    len(ids) - len(alterados),
+		"itens":     resultados,
+	})
+}
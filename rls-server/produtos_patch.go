@@ -0,0 +1,313 @@
+// produtos_patch.go - Atualização parcial de produtos (PATCH)
+//
+// O PUT /api/produtos/:id substitui o cadastro inteiro: um cliente que omite
+// notas, por exemplo, apaga o campo. O PATCH altera apenas os campos enviados
+// e mantém os demais. A quantidade não é alterada aqui; ajustes de saldo
+// continuam pelo PUT ou por movimentação. O produto pai de uma variante
+// também só muda pelo PUT; no PATCH a variante altera apenas a variação.
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rlsautomacao/estoque/internal/servico"
+)
+
+// Campos alteráveis pelo PATCH; nil mantém o valor atual
+type PatchProduto struct {
+	Codigo                  *string        `json:"codigo" binding:"omitempty,max=50,codigo"`
+	Nome                    *string        `json:"nome" binding:"omitempty,max=200"`
+	Descricao               *string        `json:"descricao"`
+	Quantidade              *int           `json:"quantidade"`
+	QuantidadeMinima        *int           `json:"quantidade_minima" binding:"omitempty,min=0"`
+	QuantidadeMaxima        *int           `json:"quantidade_maxima" binding:"omitempty,min=0"`
+	Localizacao             *string        `json:"localizacao" binding:"omitempty,max=100"`
+	Fornecedor              *string        `json:"fornecedor" binding:"omitempty,max=200"`
+	Notas                   *string        `json:"notas"`
+	ControlaSerie           *bool          `json:"controla_serie"`
+	Categoria               *string        `json:"categoria" binding:"omitempty,max=100"`
+	UnidadeMedida           *string        `json:"unidade_medida" binding:"omitempty,max=10"`
+	PrecoCusto              *float64       `json:"preco_custo" binding:"omitempty,min=0"`
+	Perigoso                *bool          `json:"perigoso"`
+	ClasseRisco             *string        `json:"classe_risco" binding:"omitempty,max=50"`
+	FispqURL                *string        `json:"fispq_url"`
+	Reciclavel              *bool          `json:"reciclavel"`
+	NumeroONU               *string        `json:"numero_onu"`
+	PermitirEstoqueNegativo *bool          `json:"permitir_estoque_negativo"`
+	LocalizacaoID           *int           `json:"localizacao_id"`
+	Kit                     *bool          `json:"kit"`
+	Variacao                *string        `json:"variacao" binding:"omitempty,max=100"`
+	Atributos               map[string]any `json:"atributos"` // só as chaves enviadas; null remove
+}
+
+// Função auxiliar para validar os campos enviados no PATCH
+func validarPatchProduto(p *PatchProduto) string {
+	if p.Quantidade != nil {
+		return "A quantidade não pode ser alterada por PATCH; use uma movimentação"
+	}
+	if (p.Codigo != nil && strings.TrimSpace(*p.Codigo) == "") || (p.Nome != nil && strings.TrimSpace(*p.Nome) == "") {
+		return "Código e nome não podem ficar vazios"
+	}
+	if p.NumeroONU != nil {
+		if msg := servico.ValidarNumeroONU(*p.NumeroONU); msg != "" {
+			return msg
+		}
+	}
+	if p.UnidadeMedida != nil && *p.UnidadeMedida == "" {
+		padrao := unidadePadrao
+		p.UnidadeMedida = &padrao
+	}
+	return ""
+}
+
+func patchProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req PatchProduto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarPatchProduto(&req); msg != "" {
+		log.Printf("[ERROR] PATCH inválido para produto ID %d: %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	log.Printf("[API] Iniciando atualização parcial de produto ID: %d", id)
+	ctx := c.Request.Context()
+
+	// Verificar o formato e se o código já está sendo usado por outro produto
+	if req.Codigo != nil {
+		var codigoAtual string
+		err = db.QueryRow(ctx, "SELECT codigo FROM produtos WHERE id = $1", id).Scan(&codigoAtual)
+		if err != nil && err != pgx.ErrNoRows {
+			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+			return
+		}
+		if msg := validarCodigoEditado(ctx, codigoAtual, req.Codigo); msg != "" {
+			log.Printf("[ERROR] Código inválido para produto ID %d: %s", id, msg)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+			return
+		}
+
+		var existingId int
+		err = db.QueryRow(ctx, "SELECT id FROM produtos WHERE codigo = $1 AND id != $2", *req.Codigo, id).Scan(&existingId)
+		if err == nil {
+			log.Printf("[DB] Código '%s' já está sendo usado por outro produto (ID: %d)", *req.Codigo, existingId)
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe outro produto com este código", Codigo: CodigoCodigoDuplicado})
+			return
+		} else if err != pgx.ErrNoRows {
+			log.Printf("[ERROR] Erro ao verificar produto existente: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto existente"})
+			return
+		}
+	}
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var precoAnterior float64
+	var codigoAnterior string
+	var atual Produto
+	err = tx.QueryRow(ctx, "SELECT preco_custo, codigo, quantidade, controla_serie, kit, produto_pai_id, COALESCE(categoria, ''), atributos FROM produtos WHERE id = $1 FOR UPDATE", id).Scan(&precoAnterior, &codigoAnterior, &atual.Quantidade, &atual.ControlaSerie, &atual.Kit, &atual.ProdutoPaiID, &atual.Categoria, &atual.Atributos)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Produto não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+		}
+		return
+	}
+
+	// Kit com os valores que ficam depois do PATCH
+	if req.Kit != nil {
+		atual.Kit = *req.Kit
+	}
+	if req.ControlaSerie != nil {
+		atual.ControlaSerie = *req.ControlaSerie
+	}
+	if msg := servico.ValidarKit(&atual); msg != "" {
+		log.Printf("[ERROR] Kit inválido (ID %d): %s", id, msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	// Variante: nome e descrição vêm do produto pai e a variação continua única
+	if atual.ProdutoPaiID != nil {
+		if req.Nome != nil || req.Descricao != nil {
+			log.Printf("[ERROR] PATCH de nome/descrição em variante. ID: %d", id)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nome e descrição da variante vêm do produto pai"})
+			return
+		}
+		if req.Variacao != nil {
+			*req.Variacao = strings.TrimSpace(*req.Variacao)
+			if err = validarVariacao(ctx, tx, *atual.ProdutoPaiID, id, *req.Variacao); err != nil {
+				log.Printf("[ERROR] Variação inválida para produto ID %d: %v", id, err)
+				responderErroVariante(c, err)
+				return
+			}
+		}
+	} else if req.Variacao != nil {
+		log.Printf("[ERROR] PATCH de variação em produto que não é variante. ID: %d", id)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Só variantes têm variação; informe o produto pai pelo PUT"})
+		return
+	}
+
+	// Atributos: as chaves enviadas sobre as atuais, conferidos contra a
+	// categoria que fica depois do PATCH
+	alterarAtributos := req.Atributos != nil || req.Categoria != nil
+	if alterarAtributos {
+		if req.Categoria != nil {
+			atual.Categoria = *req.Categoria
+		}
+		if atual.Atributos == nil {
+			atual.Atributos = map[string]any{}
+		}
+		for nome, valor := range req.Atributos {
+			atual.Atributos[nome] = valor
+		}
+		atual.Codigo = codigoAnterior
+		if !vincularAtributos(c, tx, &atual) {
+			return
+		}
+	}
+
+	// Posição do almoxarifado: localizacao_id ou localizacao trocam as duas
+	// colunas juntas; localizacao "" tira o produto da posição
+	alterarLocalizacao := req.LocalizacaoID != nil || req.Localizacao != nil
+	var localizacaoID *int
+	if alterarLocalizacao {
+		texto := ""
+		if req.Localizacao != nil {
+			texto = *req.Localizacao
+		}
+		if localizacaoID, texto, err = resolverLocalizacao(ctx, tx, req.LocalizacaoID, texto); err != nil {
+			log.Printf("[ERROR] Localização inválida para produto ID %d: %v", id, err)
+			responderErroLocalizacao(c, err)
+			return
+		}
+		req.Localizacao = &texto
+	}
+
+	p, err := scanProduto(tx.QueryRow(ctx, `
+		UPDATE produtos SET
+			codigo = COALESCE($1, codigo),
+			nome = COALESCE($2, nome),
+			descricao = COALESCE($3, descricao),
+			quantidade_minima = COALESCE($4, quantidade_minima),
+			quantidade_maxima = COALESCE($5, quantidade_maxima),
+			localizacao = COALESCE($6, localizacao),
+			fornecedor = COALESCE($7, fornecedor),
+			notas = COALESCE($8, notas),
+			controla_serie = COALESCE($9, controla_serie),
+			categoria = CASE WHEN $10::text IS NULL THEN categoria ELSE NULLIF($10, '') END,
+			unidade_medida = COALESCE($11, unidade_medida),
+			preco_custo = COALESCE($12, preco_custo),
+			perigoso = COALESCE($13, perigoso),
+			classe_risco = CASE WHEN $14::text IS NULL THEN classe_risco ELSE NULLIF($14, '') END,
+			fispq_url = CASE WHEN $15::text IS NULL THEN fispq_url ELSE NULLIF($15, '') END,
+			reciclavel = COALESCE($16, reciclavel),
+			numero_onu = CASE WHEN $17::text IS NULL THEN numero_onu ELSE NULLIF($17, '') END,
+			permitir_estoque_negativo = COALESCE($18, permitir_estoque_negativo),
+			localizacao_id = CASE WHEN $19 THEN $20 ELSE localizacao_id END,
+			kit = COALESCE($21, kit),
+			variacao = COALESCE($22, variacao),
+			atributos = CASE WHEN $23 THEN $24 ELSE atributos END,
+			data_atualizacao = CURRENT_TIMESTAMP
+		WHERE id = $25
+		RETURNING `+produtoColunas,
+		req.Codigo, req.Nome, req.Descricao, req.QuantidadeMinima, req.QuantidadeMaxima,
+		req.Localizacao, req.Fornecedor, req.Notas, req.ControlaSerie, req.Categoria,
+		req.UnidadeMedida, req.PrecoCusto, req.Perigoso, req.ClasseRisco, req.FispqURL,
+		req.Reciclavel, req.NumeroONU, req.PermitirEstoqueNegativo, alterarLocalizacao, localizacaoID, req.Kit, req.Variacao,
+		alterarAtributos, atual.Atributos, id))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+		return
+	}
+
+	// Validar o resultado combinado com os valores mantidos
+	if p.QuantidadeMaxima > 0 && p.QuantidadeMaxima < p.QuantidadeMinima {
+		log.Printf("[ERROR] Quantidade máxima inválida: %d (mínima: %d)", p.QuantidadeMaxima, p.QuantidadeMinima)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Quantidade máxima deve ser maior ou igual à quantidade mínima"})
+		return
+	}
+	if p.Perigoso && strings.TrimSpace(p.ClasseRisco) == "" {
+		log.Printf("[ERROR] Produto perigoso sem classe de risco: %s", p.Codigo)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Produtos perigosos exigem a classe de risco"})
+		return
+	}
+
+	// Nome e descrição do produto pai valem para as variantes
+	if p.ProdutoPaiID == nil && (req.Nome != nil || req.Descricao != nil) {
+		if err = propagarParaVariantes(ctx, tx, id, p.Nome, p.Descricao); err != nil {
+			log.Printf("[ERROR] Erro ao atualizar variantes: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+			return
+		}
+	}
+
+	// Registrar alteração do preço de custo no histórico
+	if p.PrecoCusto != precoAnterior {
+		if err = registrarHistoricoPreco(ctx, tx, id, precoAnterior, p.PrecoCusto, OrigemPrecoProduto, nil); err != nil {
+			log.Printf("[ERROR] Erro ao registrar histórico de preço: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+			return
+		}
+	}
+
+	// Registrar a troca de código
+	if p.Codigo != codigoAnterior {
+		if err = registrarCodigoAnterior(ctx, tx, id, codigoAnterior, p.Codigo); err != nil {
+			log.Printf("[ERROR] Erro ao registrar histórico de código: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+			return
+		}
+	}
+
+	if err = enfileirarEntregasProduto(ctx, tx, p); err != nil {
+		log.Printf("[ERROR] Erro ao enfileirar entregas de webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar produto"})
+		return
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Produto atualizado parcialmente com sucesso! ID: %d", id)
+
+	eventos.publicar(EventoProdutoAtualizado, p)
+	publicarSeEstoqueBaixo(p)
+
+	c.JSON(http.StatusOK, p)
+}
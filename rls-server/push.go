@@ -0,0 +1,484 @@
+// push.go - Notificações push para o app móvel
+//
+// O app registra o token do aparelho em POST /api/dispositivos (tokens
+// "ExponentPushToken[...]" vão pelo serviço de push do Expo, os demais pelo
+// FCM, com a chave em push_fcm_token). Cada dispositivo escolhe os tipos de
+// notificação que recebe: estoque.baixo e movimentacao.grande (movimentações a
+// partir de push_movimentacao_grande unidades). Tokens recusados pelo serviço
+// como não registrados são desativados.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	expoPushURL = "https://exp.host/--/api/v2/push/send"
+	fcmPushURL  = "https://fcm.googleapis.com/fcm/send"
+
+	PlataformaExpo = "expo"
+	PlataformaFCM  = "fcm"
+
+	// Notificação de movimentação acima do limite configurado
+	PushMovimentacaoGrande = "movimentacao.grande"
+)
+
+// Tipos de notificação que um dispositivo pode escolher
+var tiposPush = map[string]bool{
+	EventoEstoqueBaixo:     true,
+	PushMovimentacaoGrande: true,
+}
+
+type Dispositivo struct {
+	ID          int        `json:"id,omitempty"`
+	Token       string     `json:"token"`
+	Plataforma  string     `json:"plataforma,omitempty"`
+	Nome        string     `json:"nome,omitempty"`
+	Eventos     []string   `json:"eventos"`
+	Ativo       bool       `json:"ativo"`
+	DataCriacao time.Time  `json:"data_criacao,omitempty"`
+	UltimoEnvio *time.Time `json:"ultimo_envio,omitempty"`
+}
+
+type notificacaoPush struct {
+	titulo string
+	corpo  string
+	dados  map[string]any
+}
+
+var pushClient = &http.Client{Timeout: 10 * time.Second}
+
+// Integração do painel para as notificações push
+type integracaoPush struct {
+	metricas MetricasIntegracao
+}
+
+var notificacoesPush = &integracaoPush{}
+
+func (i *integracaoPush) Nome() string                  { return "push" }
+func (i *integracaoPush) Tipo() string                  { return "push" }
+func (i *integracaoPush) FilaPendente() int             { return 0 }
+func (i *integracaoPush) Metricas() *MetricasIntegracao { return &i.metricas }
+
+func (i *integracaoPush) Ativa() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var existe bool
+	err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dispositivos WHERE ativo)").Scan(&existe)
+	if err != nil {
+		log.Printf("[WARN] Erro ao verificar dispositivos ativos: %v", err)
+	}
+	return existe
+}
+
+// Envia uma notificação de teste aos dispositivos ativos
+func (i *integracaoPush) Testar(ctx context.Context) error {
+	return enviarPush(ctx, "", notificacaoPush{titulo: "RLS Estoque", corpo: "Notificação de teste"})
+}
+
+// Identifica o serviço de push pelo formato do token
+func plataformaPush(token string) string {
+	if strings.HasPrefix(token, "ExponentPushToken[") || strings.HasPrefix(token, "ExpoPushToken[") {
+		return PlataformaExpo
+	}
+	return PlataformaFCM
+}
+
+// Função auxiliar para fazer o POST JSON ao serviço de push e decodificar a resposta
+func postarPush(ctx context.Context, url, autorizacao string, corpo, resposta any) error {
+	dados, err := json.Marshal(corpo)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(dados))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if autorizacao != "" {
+		req.Header.Set("Authorization", autorizacao)
+	}
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("serviço de push respondeu %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(resposta)
+}
+
+// Envia pelo Expo; devolve os tokens não registrados
+func enviarPushExpo(ctx context.Context, tokens []string, n notificacaoPush) ([]string, error) {
+	mensagens := make([]map[string]any, len(tokens))
+	for i, token := range tokens {
+		mensagens[i] = map[string]any{"to": token, "title": n.titulo, "body": n.corpo, "data": n.dados, "sound": "default"}
+	}
+
+	var resposta struct {
+		Data []struct {
+			Status  string `json:"status"`
+			Details struct {
+				Error string `json:"error"`
+			} `json:"details"`
+		} `json:"data"`
+	}
+	if err := postarPush(ctx, expoPushURL, "", mensagens, &resposta); err != nil {
+		return nil, err
+	}
+
+	var invalidos []string
+	for i, r := range resposta.Data {
+		if i < len(tokens) && r.Details.Error == "DeviceNotRegistered" {
+			invalidos = append(invalidos, tokens[i])
+		}
+	}
+	return invalidos, nil
+}
+
+// Envia pelo FCM (API legada com chave de servidor); devolve os tokens não registrados
+func enviarPushFCM(ctx context.Context, tokens []string, n notificacaoPush) ([]string, error) {
+	chave := lerConfiguracao(ctx, "push_fcm_token", "")
+	if chave == "" {
+		return nil, fmt.Errorf("chave do FCM não configurada (push_fcm_token)")
+	}
+
+	corpo := map[string]any{
+		"registration_ids": tokens,
+		"notification":     map[string]string{"title": n.titulo, "body": n.corpo},
+		"data":             n.dados,
+	}
+	var resposta struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := postarPush(ctx, fcmPushURL, "key="+chave, corpo, &resposta); err != nil {
+		return nil, err
+	}
+
+	var invalidos []string
+	for i, r := range resposta.Results {
+		if i < len(tokens) && (r.Error == "NotRegistered" || r.Error == "InvalidRegistration") {
+			invalidos = append(invalidos, tokens[i])
+		}
+	}
+	return invalidos, nil
+}
+
+// Envia a notificação aos dispositivos ativos que escolheram o tipo (todos, se tipo vazio)
+func enviarPush(ctx context.Context, tipo string, n notificacaoPush) error {
+	return enviarPushDispositivos(ctx, n, `
+		SELECT token, plataforma FROM dispositivos
+		WHERE ativo AND ($1::text = '' OR $1::text = ANY(eventos))
+	`, tipo)
+}
+
+// Envia a notificação aos dispositivos ativos registrados com um dos nomes
+func enviarPushMencoes(ctx context.Context, nomes []string, n notificacaoPush) error {
+	return enviarPushDispositivos(ctx, n, `
+		SELECT token, plataforma FROM dispositivos
+		WHERE ativo AND lower(nome) = ANY($1)
+	`, nomes)
+}
+
+// Envia a notificação aos dispositivos (token, plataforma) selecionados pela consulta
+func enviarPushDispositivos(ctx context.Context, n notificacaoPush, consulta string, args ...any) error {
+	rows, err := db.Query(ctx, consulta, args...)
+	if err != nil {
+		return err
+	}
+	porPlataforma := map[string][]string{}
+	for rows.Next() {
+		var token, plataforma string
+		if err := rows.Scan(&token, &plataforma); err != nil {
+			rows.Close()
+			return err
+		}
+		porPlataforma[plataforma] = append(porPlataforma[plataforma], token)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var erros []string
+	for plataforma, tokens := range porPlataforma {
+		enviar := enviarPushFCM
+		if plataforma == PlataformaExpo {
+			enviar = enviarPushExpo
+		}
+
+		// Expo aceita até 100 mensagens por requisição
+		for len(tokens) > 0 {
+			lote := tokens[:min(len(tokens), 100)]
+			tokens = tokens[len(lote):]
+
+			invalidos, err := enviar(ctx, lote, n)
+			notificacoesPush.metricas.Registrar(err)
+			if err != nil {
+				log.Printf("[WARN] Erro ao enviar push (%s): %v", plataforma, err)
+				erros = append(erros, plataforma+": "+err.Error())
+				continue
+			}
+
+			_, err = db.Exec(ctx, "UPDATE dispositivos SET ultimo_envio = CURRENT_TIMESTAMP WHERE token = ANY($1)", lote)
+			if err != nil {
+				log.Printf("[WARN] Erro ao registrar envio de push: %v", err)
+			}
+			if len(invalidos) > 0 {
+				log.Printf("[INTEGRACAO] Desativando %d dispositivos não registrados (%s)", len(invalidos), plataforma)
+				if _, err := db.Exec(ctx, "UPDATE dispositivos SET ativo = FALSE WHERE token = ANY($1)", invalidos); err != nil {
+					log.Printf("[WARN] Erro ao desativar dispositivos: %v", err)
+				}
+			}
+		}
+	}
+
+	if len(erros) > 0 {
+		return fmt.Errorf("%s", strings.Join(erros, "; "))
+	}
+	return nil
+}
+
+// Converte um evento do barramento em notificação; ok falso quando não notifica
+func notificacaoDoEvento(ctx context.Context, e Evento) (string, notificacaoPush, bool) {
+	switch dados := e.Dados.(type) {
+	case AlertaEstoqueBaixo:
+		return EventoEstoqueBaixo, notificacaoPush{
+			titulo: "Estoque baixo",
+			corpo:  fmt.Sprintf("%s %s: %d unidades (mínimo %d)", dados.Codigo, dados.Nome, dados.Quantidade, dados.QuantidadeMinima),
+			dados:  map[string]any{"tipo": EventoEstoqueBaixo, "produto_id": dados.ProdutoID},
+		}, true
+
+	case Movimentacao:
+		limite, err := strconv.Atoi(lerConfiguracao(ctx, "push_movimentacao_grande", "100"))
+		if err != nil || limite <= 0 || dados.Quantidade < limite {
+			return "", notificacaoPush{}, false
+		}
+
+		var codigo, nome string
+		err = db.QueryRow(ctx, "SELECT codigo, nome FROM produtos WHERE id = $1", dados.ProdutoID).Scan(&codigo, &nome)
+		if err != nil {
+			log.Printf("[WARN] Erro ao buscar produto %d para push: %v", dados.ProdutoID, err)
+		}
+		return PushMovimentacaoGrande, notificacaoPush{
+			titulo: "Movimentação grande",
+			corpo:  fmt.Sprintf("%s %s: %s de %d unidades", codigo, nome, dados.Tipo, dados.Quantidade),
+			dados:  map[string]any{"tipo": PushMovimentacaoGrande, "produto_id": dados.ProdutoID, "movimentacao_id": dados.ID},
+		}, true
+	}
+	return "", notificacaoPush{}, false
+}
+
+// Assina o barramento e envia as notificações; roda até o contexto ser cancelado
+func iniciarPush(ctx context.Context) {
+	registrarIntegracao(notificacoesPush)
+
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			tipo, n, ok := notificacaoDoEvento(ctx, e)
+			if !ok {
+				continue
+			}
+			if err := enviarPush(ctx, tipo, n); err != nil {
+				log.Printf("[ERROR] Erro ao enviar notificações push (%s): %v", tipo, err)
+			}
+		}
+	}
+}
+
+// Função auxiliar para validar os tipos de notificação escolhidos
+func validarEventosPush(eventos []string) string {
+	for _, e := range eventos {
+		if !tiposPush[e] {
+			return "Tipo de notificação desconhecido: " + e
+		}
+	}
+	return ""
+}
+
+// Handlers de Dispositivos
+
+func getDispositivos(c *gin.Context) {
+	log.Println("[DB] Buscando dispositivos de push")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, token, plataforma, nome, eventos, ativo, data_criacao, ultimo_envio
+		FROM dispositivos
+		ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar dispositivos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar dispositivos"})
+		return
+	}
+	defer rows.Close()
+
+	dispositivos := []Dispositivo{}
+	for rows.Next() {
+		var d Dispositivo
+		var nome *string
+		err := rows.Scan(&d.ID, &d.Token, &d.Plataforma, &nome, &d.Eventos, &d.Ativo, &d.DataCriacao, &d.UltimoEnvio)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar dispositivo: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if nome != nil {
+			d.Nome = *nome
+		}
+		dispositivos = append(dispositivos, d)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar dispositivos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar dispositivos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispositivos)
+}
+
+// Registra o token do aparelho; um token já conhecido é atualizado e reativado
+func registrarDispositivo(c *gin.Context) {
+	d := Dispositivo{Eventos: []string{EventoEstoqueBaixo, PushMovimentacaoGrande}}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	d.Token = strings.TrimSpace(d.Token)
+	if d.Token == "" {
+		log.Println("[ERROR] Token do dispositivo não informado")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Token é obrigatório"})
+		return
+	}
+	if msg := validarEventosPush(d.Eventos); msg != "" {
+		log.Printf("[ERROR] Dispositivo inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+	d.Plataforma = plataformaPush(d.Token)
+	d.Ativo = true
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO dispositivos(token, plataforma, nome, eventos)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (token) DO UPDATE SET
+			nome = COALESCE(EXCLUDED.nome, dispositivos.nome),
+			eventos = EXCLUDED.eventos,
+			ativo = TRUE
+		RETURNING id, data_criacao, ultimo_envio
+	`, d.Token, d.Plataforma, d.Nome, d.Eventos).Scan(&d.ID, &d.DataCriacao, &d.UltimoEnvio)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao registrar dispositivo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar dispositivo"})
+		return
+	}
+
+	log.Printf("[DB] Dispositivo registrado (ID: %d, plataforma: %s)", d.ID, d.Plataforma)
+	c.JSON(http.StatusOK, d)
+}
+
+func atualizarDispositivo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var d Dispositivo
+	if err := c.ShouldBindJSON(&d); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarEventosPush(d.Eventos); msg != "" {
+		log.Printf("[ERROR] Dispositivo inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	var nome *string
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE dispositivos SET nome = NULLIF($1, ''), eventos = $2, ativo = $3
+		WHERE id = $4
+		RETURNING id, token, plataforma, nome, eventos, ativo, data_criacao, ultimo_envio
+	`, d.Nome, d.Eventos, d.Ativo, id).Scan(
+		&d.ID, &d.Token, &d.Plataforma, &nome, &d.Eventos, &d.Ativo, &d.DataCriacao, &d.UltimoEnvio,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Dispositivo não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Dispositivo não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar dispositivo: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar dispositivo"})
+		}
+		return
+	}
+
+	// Tratar campos nulos
+	d.Nome = ""
+	if nome != nil {
+		d.Nome = *nome
+	}
+
+	log.Printf("[DB] Dispositivo atualizado com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, d)
+}
+
+func deletarDispositivo(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM dispositivos WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir dispositivo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir dispositivo"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Dispositivo não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Dispositivo não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Dispositivo excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Dispositivo excluído com sucesso"})
+}
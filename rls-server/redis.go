@@ -0,0 +1,61 @@
+// redis.go - Conexão com o Redis (go-redis)
+//
+// Usado pelo limite de requisições e pelo cache quando há mais de uma
+// instância. Depois de uma falha de conexão o Redis não é tentado por alguns
+// segundos, para as requisições não esperarem o timeout uma a uma.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var errRedisIndisponivel = errors.New("Redis indisponível")
+
+type clienteRedis struct {
+	*redis.Client
+
+	mu              sync.Mutex
+	indisponivelAte time.Time
+}
+
+// Cria o cliente a partir de redis://[:senha@]host[:porta][/banco] (ou
+// rediss:// com TLS); a conexão só é aberta no primeiro comando
+func novoClienteRedis(bruta string) (*clienteRedis, error) {
+	opcoes, err := redis.ParseURL(bruta)
+	if err != nil {
+		return nil, errors.New("URL do Redis inválida")
+	}
+	opcoes.DialTimeout = time.Second
+	opcoes.ReadTimeout = 500 * time.Millisecond
+	opcoes.WriteTimeout = 500 * time.Millisecond
+	// Sem novas tentativas: uma falha já suspende o Redis
+	opcoes.MaxRetries = -1
+	return &clienteRedis{Client: redis.NewClient(opcoes)}, nil
+}
+
+// Executa os comandos de operacao, a menos que o Redis esteja suspenso.
+// Erros de conexão suspendem o uso do Redis por 10 segundos; erros devolvidos
+// pelo próprio Redis e chaves inexistentes (redis.Nil) não.
+func (r *clienteRedis) executar(operacao func(ctx context.Context) error) error {
+	r.mu.Lock()
+	suspenso := time.Now().Before(r.indisponivelAte)
+	r.mu.Unlock()
+	if suspenso {
+		return errRedisIndisponivel
+	}
+
+	err := operacao(context.Background())
+	var erroRedis redis.Error
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.As(err, &erroRedis) {
+		r.mu.Lock()
+		r.indisponivelAte = time.Now().Add(10 * time.Second)
+		r.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+// relatorio_ambiental.go - Relatório ambiental mensal de consumo por classe de material
+//
+// Cada produto tem uma classe ambiental derivada do cadastro: perigoso (com a
+// classe ONU em classe_risco e o número ONU), reciclável ou comum; perigoso
+// prevalece sobre reciclável. GET /api/relatorios/ambiental?mes=AAAA-MM soma
+// as movimentações do mês por classe e unidade (entradas, consumo = todas as
+// saídas, e a parte descartada) e lista o destino das saídas de descarte por
+// local.
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Classes ambientais de material
+const (
+	ClasseAmbientalPerigoso   = "perigoso"
+	ClasseAmbientalReciclavel = "reciclavel"
+	ClasseAmbientalComum      = "comum"
+)
+
+const formatoMes = "2006-01"
+
+// Expressão SQL da classe ambiental de um produto (alias p)
+const sqlClasseAmbiental = `CASE WHEN p.perigoso THEN '` + ClasseAmbientalPerigoso + `'
+	WHEN p.reciclavel THEN '` + ClasseAmbientalReciclavel + `'
+	ELSE '` + ClasseAmbientalComum + `' END`
+
+type TotalClasseAmbiental struct {
+	Classe     string `json:"classe"`
+	ClasseONU  string `json:"classe_onu,omitempty"`
+	Unidade    string `json:"unidade"`
+	Produtos   int    `json:"produtos"`
+	Entradas   int    `json:"entradas"`
+	Consumo    int    `json:"consumo"`
+	Descartado int    `json:"descartado"`
+}
+
+type DestinoDescarte struct {
+	LocalDescarteID  int    `json:"local_descarte_id"`
+	LocalDescarte    string `json:"local_descarte"`
+	LicencaAmbiental string `json:"licenca_ambiental,omitempty"`
+	Classe           string `json:"classe"`
+	ClasseONU        string `json:"classe_onu,omitempty"`
+	Unidade          string `json:"unidade"`
+	Quantidade       int    `json:"quantidade"`
+	Movimentacoes    int    `json:"movimentacoes"`
+}
+
+type RelatorioAmbiental struct {
+	Mes      string                 `json:"mes"`
+	Classes  []TotalClasseAmbiental `json:"classes"`
+	Destinos []DestinoDescarte      `json:"destinos"`
+}
+
+// Handlers do Relatório Ambiental
+
+func getRelatorioAmbiental(c *gin.Context) {
+	agora := time.Now()
+	de := time.Date(agora.Year(), agora.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if mesStr := c.Query("mes"); mesStr != "" {
+		t, err := time.Parse(formatoMes, mesStr)
+		if err != nil {
+			log.Printf("[ERROR] Mês inválido: %s", mesStr)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Mês inválido, use o formato AAAA-MM"})
+			return
+		}
+		de = t
+	}
+	ate := de.AddDate(0, 1, 0)
+	ctx := c.Request.Context()
+
+	log.Printf("[DB] Gerando relatório ambiental de %s", de.Format(formatoMes))
+
+	relatorio := RelatorioAmbiental{
+		Mes:      de.Format(formatoMes),
+		Classes:  []TotalClasseAmbiental{},
+		Destinos: []DestinoDescarte{},
+	}
+
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT `+sqlClasseAmbiental+`,
+		       CASE WHEN p.perigoso THEN COALESCE(p.classe_risco, '') ELSE '' END,
+		       p.unidade_medida,
+		       COUNT(DISTINCT p.id),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'entrada'), 0),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'saida'), 0),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'saida' AND m.local_descarte_id IS NOT NULL), 0)
+		FROM movimentacoes m
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`, de, ate)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao calcular consumo por classe: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório ambiental"})
+		return
+	}
+	for rows.Next() {
+		var t TotalClasseAmbiental
+		if err := rows.Scan(&t.Classe, &t.ClasseONU, &t.Unidade, &t.Produtos, &t.Entradas, &t.Consumo, &t.Descartado); err != nil {
+			log.Printf("[ERROR] Erro ao processar classe ambiental: %v", err)
+			continue
+		}
+		relatorio.Classes = append(relatorio.Classes, t)
+	}
+	rows.Close()
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar consumo por classe: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório ambiental"})
+		return
+	}
+
+	rows, err = dbLeitura.Query(ctx, `
+		SELECT l.id, l.nome, COALESCE(l.licenca_ambiental, ''),
+		       `+sqlClasseAmbiental+`,
+		       CASE WHEN p.perigoso THEN COALESCE(p.classe_risco, '') ELSE '' END,
+		       p.unidade_medida, SUM(m.quantidade), COUNT(*)
+		FROM movimentacoes m
+		JOIN locais_descarte l ON m.local_descarte_id = l.id
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.tipo = 'saida'
+		  AND m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		GROUP BY l.id, l.nome, l.licenca_ambiental, 4, 5, p.unidade_medida
+		ORDER BY l.nome, 4, 5
+	`, de, ate)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar destinos de descarte: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório ambiental"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DestinoDescarte
+		err := rows.Scan(&d.LocalDescarteID, &d.LocalDescarte, &d.LicencaAmbiental,
+			&d.Classe, &d.ClasseONU, &d.Unidade, &d.Quantidade, &d.Movimentacoes)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar destino de descarte: %v", err)
+			continue
+		}
+		relatorio.Destinos = append(relatorio.Destinos, d)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar destinos de descarte: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório ambiental"})
+		return
+	}
+
+	log.Printf("[API] Relatório ambiental de %s gerado: %d classes, %d destinos",
+		relatorio.Mes, len(relatorio.Classes), len(relatorio.Destinos))
+	c.JSON(http.StatusOK, relatorio)
+}
@@ -0,0 +1,160 @@
+// relatorio_movimentacoes.go - Entradas × saídas agregadas por dia, semana ou mês
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Agrupamentos aceitos, mapeados para o campo do date_trunc
+var agrupamentosMovimentacao = map[string]string{
+	"dia":    "day",
+	"semana": "week",
+	"mes":    "month",
+}
+
+type PeriodoMovimentacao struct {
+	Periodo  time.Time `json:"periodo"`
+	Entradas int       `json:"entradas"`
+	Saidas   int       `json:"saidas"`
+	Saldo    int       `json:"saldo"`
+}
+
+type ProdutoPeriodoMovimentacao struct {
+	ProdutoID     int       `json:"produto_id"`
+	ProdutoCodigo string    `json:"produto_codigo"`
+	ProdutoNome   string    `json:"produto_nome"`
+	Periodo       time.Time `json:"periodo"`
+	Entradas      int       `json:"entradas"`
+	Saidas        int       `json:"saidas"`
+}
+
+type RelatorioMovimentacoes struct {
+	Agrupamento string                       `json:"agrupamento"`
+	De          time.Time                    `json:"de"`
+	Ate         time.Time                    `json:"ate"`
+	Periodos    []PeriodoMovimentacao        `json:"periodos"`
+	Produtos    []ProdutoPeriodoMovimentacao `json:"produtos"`
+}
+
+// Handler para relatório de movimentações agrupadas
+
+func getRelatorioMovimentacoes(c *gin.Context) {
+	agrupamento := c.DefaultQuery("agrupamento", "dia")
+	campo, ok := agrupamentosMovimentacao[agrupamento]
+	if !ok {
+		log.Printf("[ERROR] Agrupamento inválido: %s", agrupamento)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Agrupamento inválido, use dia, semana ou mes"})
+		return
+	}
+
+	de, ate, ok := lerPeriodo(c, 30)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	// Filtro opcional por produto
+	var produtoID *int
+	if idStr := c.Query("produto_id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("[ERROR] ID de produto inválido: %s", idStr)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID de produto inválido"})
+			return
+		}
+		produtoID = &id
+	}
+
+	log.Printf("[DB] Gerando relatório de movimentações por %s de %s a %s", agrupamento, de.Format(formatoData), ate.Format(formatoData))
+
+	relatorio := RelatorioMovimentacoes{
+		Agrupamento: agrupamento,
+		De:          de,
+		Ate:         ate.AddDate(0, 0, -1),
+		Periodos:    []PeriodoMovimentacao{},
+		Produtos:    []ProdutoPeriodoMovimentacao{},
+	}
+
+	// 1. Totais por período (períodos sem movimento aparecem zerados)
+	rows, err := dbLeitura.Query(c.Request.Context(), `
+		SELECT s.periodo,
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'entrada'), 0),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'saida'), 0)
+		FROM generate_series(date_trunc($1, $2::timestamp), $3::timestamp - interval '1 second', ('1 ' || $1)::interval) AS s(periodo)
+		LEFT JOIN movimentacoes m
+		       ON date_trunc($1, m.data_movimentacao) = s.periodo
+		      AND m.data_movimentacao >= $2 AND m.data_movimentacao < $3
+		      AND ($4::int IS NULL OR m.produto_id = $4)
+		GROUP BY s.periodo
+		ORDER BY s.periodo
+	`, campo, de, ate, produtoID)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar movimentações por período: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar movimentações por período"})
+		return
+	}
+
+	for rows.Next() {
+		var p PeriodoMovimentacao
+		if err := rows.Scan(&p.Periodo, &p.Entradas, &p.Saidas); err != nil {
+			log.Printf("[WARN] Erro ao processar período: %v", err)
+			continue
+		}
+		p.Saldo = p.Entradas - p.Saidas
+		relatorio.Periodos = append(relatorio.Periodos, p)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar movimentações por período: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar movimentações por período"})
+		return
+	}
+
+	// 2. Totais por produto e período (somente combinações com movimento)
+	rows, err = dbLeitura.Query(c.Request.Context(), `
+		SELECT p.id, p.codigo, p.nome, date_trunc($1, m.data_movimentacao) AS periodo,
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'entrada'), 0),
+		       COALESCE(SUM(m.quantidade) FILTER (WHERE m.tipo = 'saida'), 0)
+		FROM movimentacoes m
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.data_movimentacao >= $2 AND m.data_movimentacao < $3
+		  AND ($4::int IS NULL OR m.produto_id = $4)
+		GROUP BY p.id, p.codigo, p.nome, periodo
+		ORDER BY periodo, p.nome
+	`, campo, de, ate, produtoID)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar movimentações por produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar movimentações por produto"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p ProdutoPeriodoMovimentacao
+		if err := rows.Scan(&p.ProdutoID, &p.ProdutoCodigo, &p.ProdutoNome, &p.Periodo, &p.Entradas, &p.Saidas); err != nil {
+			log.Printf("[WARN] Erro ao processar movimentação por produto: %v", err)
+			continue
+		}
+		relatorio.Produtos = append(relatorio.Produtos, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar movimentações por produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar movimentações por produto"})
+		return
+	}
+
+	log.Printf("[API] Relatório de movimentações gerado: %d períodos, %d linhas por produto",
+		len(relatorio.Periodos), len(relatorio.Produtos))
+	c.JSON(http.StatusOK, relatorio)
+}
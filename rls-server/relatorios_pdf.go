@@ -0,0 +1,363 @@
+// relatorios_pdf.go - Relatórios impressos de estoque e de movimentações
+//
+// Os PDFs usam o gerador de pdf.go em A4 retrato: cabeçalho com o logotipo e
+// os dados da empresa (perfil de empresa.go), tabela com quebra de página
+// repetindo os títulos das colunas, linha de totais e, ao final, o quadro de
+// assinaturas do responsável pelo estoque e da gerência.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Margem das páginas do relatório, em pontos
+const margemRelatorioPDF = 15 * pontosPorMM
+
+type colunaRelatorioPDF struct {
+	titulo  string
+	largura float64 // em milímetros
+	direita bool    // alinhar à direita (valores numéricos)
+}
+
+// Relatório tabular sobre um documentoPDF, com posição vertical corrente
+type relatorioPDF struct {
+	d       *documentoPDF
+	titulo  string
+	periodo string
+	colunas []colunaRelatorioPDF
+	y       float64
+	pagina  int
+	empresa PerfilEmpresa
+	logo    int // índice da imagem no documento; -1 sem logotipo
+	gerado  time.Time
+}
+
+func novoRelatorioPDF(ctx context.Context, titulo, periodo string, colunas []colunaRelatorioPDF) *relatorioPDF {
+	r := &relatorioPDF{
+		d:       novoPDF(210*pontosPorMM, 297*pontosPorMM),
+		titulo:  titulo,
+		periodo: periodo,
+		colunas: colunas,
+		gerado:  time.Now(),
+		empresa: lerEmpresa(ctx),
+		logo:    -1,
+	}
+	if img := carregarLogoEmpresa(ctx); img != nil {
+		r.logo = r.d.adicionarImagem(img)
+	}
+	r.novaPagina()
+	return r
+}
+
+// Abre uma página com o cabeçalho da empresa e os títulos das colunas
+func (r *relatorioPDF) novaPagina() {
+	r.d.novaPagina()
+	r.pagina++
+	largura := r.d.largura - 2*margemRelatorioPDF
+
+	// Logotipo à esquerda, com até 15 mm de altura, e os dados ao lado
+	x := margemRelatorioPDF
+	if r.logo >= 0 {
+		img := r.d.imagens[r.logo]
+		altura := 15 * pontosPorMM
+		larguraLogo := min(altura*img.largura/img.altura, 50*pontosPorMM)
+		r.d.desenharImagem(r.logo, x, margemRelatorioPDF, larguraLogo, altura)
+		x += larguraLogo + 4*pontosPorMM
+	}
+	larguraDados := r.d.largura - margemRelatorioPDF - x
+
+	r.y = margemRelatorioPDF + 12
+	nome := r.empresa.Nome
+	if nome == "" {
+		nome = "Controle de Estoque"
+	}
+	r.d.textoNegrito(x, r.y, 12, r.d.ajustarTexto(nome, 12, larguraDados))
+
+	var contato []string
+	if r.empresa.CNPJ != "" {
+		contato = append(contato, "CNPJ "+r.empresa.CNPJ)
+	}
+	for _, s := range []string{r.empresa.Endereco, r.empresa.Telefone, r.empresa.Email} {
+		if s != "" {
+			contato = append(contato, s)
+		}
+	}
+	if len(contato) > 0 {
+		r.y += 11
+		r.d.texto(x, r.y, 8, r.d.ajustarTexto(strings.Join(contato, " - "), 8, larguraDados))
+	}
+	if r.logo >= 0 {
+		r.y = max(r.y, margemRelatorioPDF+15*pontosPorMM)
+	}
+
+	r.y += 20
+	r.d.textoNegrito(margemRelatorioPDF, r.y, 14, r.titulo)
+	pagina := "Página " + strconv.Itoa(r.pagina)
+	r.d.texto(r.d.largura-margemRelatorioPDF-r.d.larguraTexto(pagina, 8), r.y, 8, pagina)
+
+	r.y += 12
+	linha := "Emitido em " + r.gerado.Format("02/01/2006 15:04")
+	if r.periodo != "" {
+		linha = r.periodo + " - " + linha
+	}
+	r.d.texto(margemRelatorioPDF, r.y, 8, linha)
+
+	r.y += 6
+	r.d.retangulo(margemRelatorioPDF, r.y, largura, 1)
+	r.y += 16
+	r.linha(true, tituloColunas(r.colunas)...)
+	r.y -= 4
+	r.d.retangulo(margemRelatorioPDF, r.y, largura, 0.5)
+	r.y += 12
+}
+
+func tituloColunas(colunas []colunaRelatorioPDF) []string {
+	titulos := make([]string, len(colunas))
+	for i, c := range colunas {
+		titulos[i] = c.titulo
+	}
+	return titulos
+}
+
+// Garante espaço para a próxima altura, abrindo outra página se preciso
+func (r *relatorioPDF) reservar(altura float64) {
+	if r.y+altura > r.d.altura-margemRelatorioPDF {
+		r.novaPagina()
+	}
+}
+
+// Escreve uma linha da tabela e avança a posição
+func (r *relatorioPDF) linha(negrito bool, valores ...string) {
+	const tamanho = 8
+	r.reservar(12)
+	x := margemRelatorioPDF
+	for i, c := range r.colunas {
+		largura := c.largura * pontosPorMM
+		if i < len(valores) {
+			s := r.d.ajustarTexto(valores[i], tamanho, largura-4)
+			px := x
+			if c.direita {
+				px = x + largura - 4 - r.d.larguraTexto(s, tamanho)
+			}
+			if negrito {
+				r.d.textoNegrito(px, r.y, tamanho, s)
+			} else {
+				r.d.texto(px, r.y, tamanho, s)
+			}
+		}
+		x += largura
+	}
+	r.y += 12
+}
+
+// Linha de totais separada da tabela por um traço
+func (r *relatorioPDF) totais(valores ...string) {
+	r.reservar(24)
+	r.y -= 4
+	r.d.retangulo(margemRelatorioPDF, r.y, r.d.largura-2*margemRelatorioPDF, 0.5)
+	r.y += 12
+	r.linha(true, valores...)
+}
+
+// Quadro de assinaturas no fim do relatório
+func (r *relatorioPDF) assinaturas(nomes ...string) {
+	r.reservar(80)
+	r.y += 50
+	largura := (r.d.largura - 2*margemRelatorioPDF) / float64(len(nomes))
+	for i, nome := range nomes {
+		x := margemRelatorioPDF + float64(i)*largura
+		r.d.retangulo(x+10, r.y, largura-20, 0.5)
+		r.d.texto(x+(largura-r.d.larguraTexto(nome, 8))/2, r.y+11, 8, nome)
+	}
+	r.y += 20
+}
+
+func (r *relatorioPDF) bytes() ([]byte, error) {
+	return r.d.bytes()
+}
+
+// Formata um número no padrão brasileiro (milhar com ponto, decimal com vírgula)
+func formatarNumeroPDF(v float64, casas int) string {
+	s := strconv.FormatFloat(v, 'f', casas, 64)
+	sinal := ""
+	if strings.HasPrefix(s, "-") {
+		sinal, s = "-", s[1:]
+	}
+	inteiro, decimal, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, r := range inteiro {
+		if i > 0 && (len(inteiro)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if decimal != "" {
+		b.WriteString("," + decimal)
+	}
+	return sinal + b.String()
+}
+
+// Handlers de Relatórios em PDF
+
+func getRelatorioEstoquePDF(c *gin.Context) {
+	ctx := c.Request.Context()
+	log.Printf("[DB] Gerando relatório de estoque em PDF")
+
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT codigo, nome, quantidade, quantidade_minima, preco_custo
+		FROM produtos
+		ORDER BY nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar produtos para o relatório: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
+		return
+	}
+	defer rows.Close()
+
+	r := novoRelatorioPDF(ctx, "Posição de Estoque", "", []colunaRelatorioPDF{
+		{titulo: "Código", largura: 28},
+		{titulo: "Produto", largura: 72},
+		{titulo: "Quantidade", largura: 22, direita: true},
+		{titulo: "Mínimo", largura: 18, direita: true},
+		{titulo: "Custo unit.", largura: 20, direita: true},
+		{titulo: "Valor total", largura: 20, direita: true},
+	})
+
+	var produtos, abaixoMinimo, quantidadeTotal int
+	var valorTotal float64
+	for rows.Next() {
+		var codigo, nome string
+		var quantidade, minima int
+		var custo float64
+		if err := rows.Scan(&codigo, &nome, &quantidade, &minima, &custo); err != nil {
+			log.Printf("[ERROR] Erro ao processar produto do relatório: %v", err)
+			continue
+		}
+
+		valor := float64(quantidade) * custo
+		produtos++
+		quantidadeTotal += quantidade
+		valorTotal += valor
+		if quantidade < minima {
+			abaixoMinimo++
+		}
+		r.linha(quantidade < minima, codigo, nome, formatarNumeroPDF(float64(quantidade), 0),
+			formatarNumeroPDF(float64(minima), 0), formatarNumeroPDF(custo, 2), formatarNumeroPDF(valor, 2))
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar produtos do relatório: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar produtos"})
+		return
+	}
+
+	r.totais("Totais", fmt.Sprintf("%d produtos (%d abaixo do mínimo, em negrito)", produtos, abaixoMinimo),
+		formatarNumeroPDF(float64(quantidadeTotal), 0), "", "", formatarNumeroPDF(valorTotal, 2))
+	r.assinaturas("Responsável pelo estoque", "Gerência")
+
+	log.Printf("[API] Relatório de estoque em PDF gerado: %d produtos, %d páginas", produtos, r.pagina)
+	c.Set(chaveRegistrosExportacao, produtos)
+	conteudo, err := r.bytes()
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gerar PDF do relatório de estoque: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório"})
+		return
+	}
+	servirConteudo(c, conteudo, "estoque-"+r.gerado.Format(formatoData)+".pdf", "application/pdf", r.gerado)
+}
+
+func getRelatorioMovimentacoesPDF(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 30)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	log.Printf("[DB] Gerando relatório de movimentações em PDF de %s a %s", de.Format(formatoData), ate.Format(formatoData))
+
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT m.data_movimentacao, p.codigo, p.nome, m.tipo, m.quantidade, m.notas
+		FROM movimentacoes m
+		JOIN produtos p ON m.produto_id = p.id
+		WHERE m.data_movimentacao >= $1 AND m.data_movimentacao < $2
+		ORDER BY m.data_movimentacao, m.id
+	`, de, ate)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar movimentações para o relatório: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar movimentações"})
+		return
+	}
+	defer rows.Close()
+
+	periodo := "Período de " + de.Format("02/01/2006") + " a " + ate.AddDate(0, 0, -1).Format("02/01/2006")
+	r := novoRelatorioPDF(ctx, "Movimentações de Estoque", periodo, []colunaRelatorioPDF{
+		{titulo: "Data", largura: 26},
+		{titulo: "Código", largura: 26},
+		{titulo: "Produto", largura: 52},
+		{titulo: "Tipo", largura: 16},
+		{titulo: "Quantidade", largura: 20, direita: true},
+		{titulo: "Notas", largura: 40},
+	})
+
+	var total, entradas, saidas int
+	for rows.Next() {
+		var data time.Time
+		var codigo, nome, tipo string
+		var quantidade int
+		var notas *string
+		if err := rows.Scan(&data, &codigo, &nome, &tipo, &quantidade, &notas); err != nil {
+			log.Printf("[ERROR] Erro ao processar movimentação do relatório: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		nota := ""
+		if notas != nil {
+			nota = *notas
+		}
+
+		total++
+		if tipo == "entrada" {
+			entradas += quantidade
+		} else {
+			saidas += quantidade
+		}
+		r.linha(false, data.Format("02/01/2006 15:04"), codigo, nome, tipo, formatarNumeroPDF(float64(quantidade), 0), nota)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar movimentações do relatório: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar movimentações"})
+		return
+	}
+
+	r.totais("Totais", fmt.Sprintf("%d movimentações", total), "", "Entradas", formatarNumeroPDF(float64(entradas), 0))
+	r.linha(true, "", "", "", "Saídas", formatarNumeroPDF(float64(saidas), 0))
+	r.linha(true, "", "", "", "Saldo", formatarNumeroPDF(float64(entradas-saidas), 0))
+	r.assinaturas("Responsável pelo estoque", "Gerência")
+
+	log.Printf("[API] Relatório de movimentações em PDF gerado: %d movimentações, %d páginas", total, r.pagina)
+	c.Set(chaveRegistrosExportacao, total)
+	conteudo, err := r.bytes()
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gerar PDF do relatório de movimentações: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar relatório"})
+		return
+	}
+	servirConteudo(c, conteudo, "movimentacoes-"+de.Format(formatoData)+".pdf", "application/pdf", r.gerado)
+}
@@ -0,0 +1,261 @@
+// relatorios_rede.go - Relatórios consolidados das filiais na instância central
+//
+// A instância central consulta os relatórios das filiais cadastradas em
+// /api/filiais (ver filiais.go) e soma os resultados por código de produto,
+// já que os IDs de cada filial são independentes:
+//
+//   - GET /api/relatorios/rede/estoque: posição de estoque e valor por produto,
+//     a partir de /api/relatorios/valorizacao de cada filial
+//   - GET /api/relatorios/rede/consumo: saídas por produto no período (de, ate,
+//     dias), a partir de /api/relatorios/movimentacoes de cada filial
+//
+// Os dois aceitam ?filial=1,3 para limitar as filiais consultadas. Cada
+// filial aparece em "filiais" com a situação da consulta; uma filial fora do
+// ar fica de fora da soma sem derrubar o relatório. Transferências entre
+// filiais não entram: o sistema não registra transferências (a saída de uma
+// filial e a entrada na outra são movimentações comuns, sem vínculo entre si),
+// então não há transferência pendente a listar.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Situação da consulta de um relatório em uma filial
+type SituacaoFilialRelatorio struct {
+	FilialID int    `json:"filial_id"`
+	Filial   string `json:"filial"`
+	Situacao string `json:"situacao"`
+	Erro     string `json:"erro,omitempty"`
+}
+
+type QuantidadeFilial struct {
+	FilialID   int     `json:"filial_id"`
+	Filial     string  `json:"filial"`
+	Quantidade int     `json:"quantidade"`
+	ValorTotal float64 `json:"valor_total,omitempty"`
+}
+
+type ProdutoRede struct {
+	Codigo     string             `json:"codigo"`
+	Nome       string             `json:"nome"`
+	Quantidade int                `json:"quantidade"`
+	ValorTotal float64            `json:"valor_total,omitempty"`
+	Filiais    []QuantidadeFilial `json:"filiais"`
+}
+
+type RelatorioEstoqueRede struct {
+	Filiais    []SituacaoFilialRelatorio `json:"filiais"`
+	Produtos   []ProdutoRede             `json:"produtos"`
+	ValorTotal float64                   `json:"valor_total"`
+}
+
+type RelatorioConsumoRede struct {
+	De       string                    `json:"de"`
+	Ate      string                    `json:"ate"`
+	Filiais  []SituacaoFilialRelatorio `json:"filiais"`
+	Produtos []ProdutoRede             `json:"produtos"`
+}
+
+// Função auxiliar para ler o filtro ?filial=1,3 e aplicá-lo às filiais ativas
+func filtrarFiliais(c *gin.Context, filiais []Filial) ([]Filial, bool) {
+	filtro := c.Query("filial")
+	if filtro == "" {
+		return filiais, true
+	}
+	ids := map[int]bool{}
+	for _, s := range strings.Split(filtro, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, false
+		}
+		ids[id] = true
+	}
+	filtradas := []Filial{}
+	for _, f := range filiais {
+		if ids[f.ID] {
+			filtradas = append(filtradas, f)
+		}
+	}
+	return filtradas, true
+}
+
+// Busca um relatório JSON na filial; caminho sem versão, como em consultarFilial
+func buscarRelatorioFilial(ctx context.Context, f Filial, caminho string, destino any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(f.URL, "/")+caminho, nil)
+	if err != nil {
+		return err
+	}
+	if id := idRequisicao(ctx); id != "" {
+		req.Header.Set(headerRequestID, id)
+	}
+	propagarTrace(ctx, req.Header)
+
+	resp, err := filialClient.Do(req)
+	if err != nil {
+		log.Printf("[WARN] Filial %s não respondeu ao relatório %s: %v", f.Nome, caminho, err)
+		return fmt.Errorf("filial não respondeu")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("filial respondeu HTTP %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(destino); err != nil {
+		return fmt.Errorf("resposta inválida da filial")
+	}
+	return nil
+}
+
+// Consulta o mesmo relatório em todas as filiais em paralelo; consultar
+// recebe a filial e devolve o erro da consulta
+func consultarFiliais(filiais []Filial, consultar func(i int, f Filial) error) []SituacaoFilialRelatorio {
+	situacoes := make([]SituacaoFilialRelatorio, len(filiais))
+	var wg sync.WaitGroup
+	for i, f := range filiais {
+		wg.Add(1)
+		go func(i int, f Filial) {
+			defer wg.Done()
+			s := SituacaoFilialRelatorio{FilialID: f.ID, Filial: f.Nome, Situacao: DisponibilidadeOK}
+			if err := consultar(i, f); err != nil {
+				s.Situacao, s.Erro = DisponibilidadeIndisponivel, err.Error()
+			}
+			situacoes[i] = s
+		}(i, f)
+	}
+	wg.Wait()
+	return situacoes
+}
+
+// Acumula as quantidades das filiais por código de produto
+type somaProdutosRede struct {
+	produtos map[string]*ProdutoRede
+}
+
+func (s *somaProdutosRede) somar(f Filial, codigo, nome string, quantidade int, valor float64) {
+	if s.produtos == nil {
+		s.produtos = map[string]*ProdutoRede{}
+	}
+	p := s.produtos[codigo]
+	if p == nil {
+		p = &ProdutoRede{Codigo: codigo, Nome: nome, Filiais: []QuantidadeFilial{}}
+		s.produtos[codigo] = p
+	}
+	p.Quantidade += quantidade
+	p.ValorTotal += valor
+	for i := range p.Filiais {
+		if p.Filiais[i].FilialID == f.ID {
+			p.Filiais[i].Quantidade += quantidade
+			p.Filiais[i].ValorTotal += valor
+			return
+		}
+	}
+	p.Filiais = append(p.Filiais, QuantidadeFilial{FilialID: f.ID, Filial: f.Nome, Quantidade: quantidade, ValorTotal: valor})
+}
+
+// Produtos somados, por código
+func (s *somaProdutosRede) lista() []ProdutoRede {
+	lista := make([]ProdutoRede, 0, len(s.produtos))
+	for _, p := range s.produtos {
+		sort.Slice(p.Filiais, func(i, j int) bool { return p.Filiais[i].Filial < p.Filiais[j].Filial })
+		lista = append(lista, *p)
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Codigo < lista[j].Codigo })
+	return lista
+}
+
+// Função auxiliar para carregar as filiais do relatório, respondendo o erro
+func filiaisRelatorio(c *gin.Context) ([]Filial, bool) {
+	filiais, err := carregarFiliaisAtivas(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar filiais: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar filiais"})
+		return nil, false
+	}
+	filiais, ok := filtrarFiliais(c, filiais)
+	if !ok {
+		log.Printf("[ERROR] Filtro de filial inválido: %s", c.Query("filial"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Filtro de filial inválido, use IDs separados por vírgula"})
+		return nil, false
+	}
+	return filiais, true
+}
+
+// Handlers dos relatórios consolidados
+
+func getRelatorioEstoqueRede(c *gin.Context) {
+	filiais, ok := filiaisRelatorio(c)
+	if !ok {
+		return
+	}
+	log.Printf("[API] Consolidando posição de estoque de %d filiais", len(filiais))
+
+	relatorios := make([]RelatorioValorizacao, len(filiais))
+	situacoes := consultarFiliais(filiais, func(i int, f Filial) error {
+		return buscarRelatorioFilial(c.Request.Context(), f, "/api/relatorios/valorizacao", &relatorios[i])
+	})
+
+	relatorio := RelatorioEstoqueRede{Filiais: situacoes}
+	var soma somaProdutosRede
+	for i, f := range filiais {
+		if situacoes[i].Situacao != DisponibilidadeOK {
+			continue
+		}
+		for _, item := range relatorios[i].Itens {
+			soma.somar(f, item.Codigo, item.Nome, item.Quantidade, item.ValorTotal)
+		}
+		relatorio.ValorTotal += relatorios[i].ValorTotal
+	}
+	relatorio.Produtos = soma.lista()
+
+	c.JSON(http.StatusOK, relatorio)
+}
+
+func getRelatorioConsumoRede(c *gin.Context) {
+	de, ate, ok := lerPeriodo(c, 30)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+	filiais, ok := filiaisRelatorio(c)
+	if !ok {
+		return
+	}
+
+	relatorio := RelatorioConsumoRede{De: de.Format(formatoData), Ate: ate.AddDate(0, 0, -1).Format(formatoData)}
+	log.Printf("[API] Consolidando consumo de %d filiais de %s a %s", len(filiais), relatorio.De, relatorio.Ate)
+
+	consulta := url.Values{"agrupamento": {"mes"}, "de": {relatorio.De}, "ate": {relatorio.Ate}}
+	relatorios := make([]RelatorioMovimentacoes, len(filiais))
+	relatorio.Filiais = consultarFiliais(filiais, func(i int, f Filial) error {
+		return buscarRelatorioFilial(c.Request.Context(), f, "/api/relatorios/movimentacoes?"+consulta.Encode(), &relatorios[i])
+	})
+
+	var soma somaProdutosRede
+	for i, f := range filiais {
+		if relatorio.Filiais[i].Situacao != DisponibilidadeOK {
+			continue
+		}
+		for _, p := range relatorios[i].Produtos {
+			if p.Saidas > 0 {
+				soma.somar(f, p.ProdutoCodigo, p.ProdutoNome, p.Saidas, 0)
+			}
+		}
+	}
+	relatorio.Produtos = soma.lista()
+
+	c.JSON(http.StatusOK, relatorio)
+}
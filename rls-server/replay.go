@@ -0,0 +1,310 @@
+// replay.go - Gravação de requisições e replay para depuração
+//
+// Com RECORD_FILE definido, cada requisição da API é gravada (uma por linha,
+// em JSON) com headers e corpos sanitizados, cada corpo limitado a 64 KB; os
+// streams (SSE e WebSocket) e os uploads multipart não são gravados. O subcomando
+//
+//	rls-server replay [-db rls_estoque_teste] [-parar] arquivo.json
+//
+// reexecuta a sequência gravada contra um banco de teste e compara os status
+// obtidos com os gravados. Os IDs gravados referem-se ao banco de origem, então
+// o banco de teste deve partir de uma cópia compatível dele.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rlsautomacao/estoque/internal/repositorio"
+)
+
+// Arquivo de gravação - vazio desativa a gravação
+var recordFile = getEnv("RECORD_FILE", "")
+
+// Headers preservados na gravação; os demais (Authorization, Cookie...) são descartados
+var headersGravados = []string{"Content-Type", "Accept"}
+
+// Campos JSON cujo valor é mascarado na gravação
+var camposSensiveis = []string{"senha", "password", "token", "secret", "segredo"}
+
+// Tamanho máximo gravado de cada corpo (requisição ou resposta); o excedente é descartado
+const limiteCorpoGravado = 64 << 10
+
+type RequisicaoGravada struct {
+	Momento  time.Time         `json:"momento"`
+	Metodo   string            `json:"metodo"`
+	Caminho  string            `json:"caminho"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Corpo    string            `json:"corpo,omitempty"`
+	Status   int               `json:"status"`
+	Resposta string            `json:"resposta,omitempty"`
+	// Corpos maiores que limiteCorpoGravado são gravados cortados
+	CorpoTruncado    bool `json:"corpo_truncado,omitempty"`
+	RespostaTruncada bool `json:"resposta_truncada,omitempty"`
+}
+
+// Writer que copia o corpo da resposta enquanto ele é enviado ao cliente,
+// até limiteCorpoGravado
+type bodyRecorder struct {
+	gin.ResponseWriter
+	corpo    bytes.Buffer
+	truncado bool
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	if resta := limiteCorpoGravado - w.corpo.Len(); len(b) > resta {
+		w.corpo.Write(b[:resta])
+		w.truncado = true
+	} else {
+		w.corpo.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Rotas fora da gravação: os streams (SSE e WebSocket) ficam abertos
+// indefinidamente e os uploads são arquivos binários
+func gravacaoIgnorada(c *gin.Context) bool {
+	rota := c.FullPath()
+	if rota == "/ws" || strings.HasSuffix(rota, "/stream") || c.GetHeader("Upgrade") != "" {
+		return true
+	}
+	return strings.HasPrefix(c.ContentType(), "multipart/")
+}
+
+var gravacaoMutex sync.Mutex
+
+// Função auxiliar para mascarar campos sensíveis de um corpo JSON
+func sanitizarCorpo(corpo []byte) string {
+	var dados any
+	if err := json.Unmarshal(corpo, &dados); err != nil {
+		return string(corpo)
+	}
+
+	var mascarar func(v any)
+	mascarar = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for chave, valor := range t {
+				sensivel := false
+				for _, campo := range camposSensiveis {
+					if strings.Contains(strings.ToLower(chave), campo) {
+						sensivel = true
+						break
+					}
+				}
+				if sensivel {
+					t[chave] = "***"
+				} else {
+					mascarar(valor)
+				}
+			}
+		case []any:
+			for _, item := range t {
+				mascarar(item)
+			}
+		}
+	}
+	mascarar(dados)
+
+	sanitizado, err := json.Marshal(dados)
+	if err != nil {
+		return ""
+	}
+	return string(sanitizado)
+}
+
+// Recorder middleware
+func Recorder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if gravacaoIgnorada(c) {
+			c.Next()
+			return
+		}
+
+		// Ler o início do corpo e devolvê-lo inteiro para os handlers
+		var corpo []byte
+		corpoTruncado := false
+		if c.Request.Body != nil {
+			corpo, _ = io.ReadAll(io.LimitReader(c.Request.Body, limiteCorpoGravado+1))
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(corpo), c.Request.Body), c.Request.Body}
+			if len(corpo) > limiteCorpoGravado {
+				corpo = corpo[:limiteCorpoGravado]
+				corpoTruncado = true
+			}
+		}
+
+		writer := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		gravada := RequisicaoGravada{
+			Momento: time.Now(),
+			Metodo:  c.Request.Method,
+			Caminho: c.Request.URL.RequestURI(),
+			Headers: map[string]string{},
+			Status:  c.Writer.Status(),
+
+			CorpoTruncado:    corpoTruncado,
+			RespostaTruncada: writer.truncado,
+		}
+		for _, h := range headersGravados {
+			if v := c.Request.Header.Get(h); v != "" {
+				gravada.Headers[h] = v
+			}
+		}
+		if len(corpo) > 0 {
+			gravada.Corpo = sanitizarCorpo(corpo)
+		}
+		if writer.corpo.Len() > 0 {
+			gravada.Resposta = sanitizarCorpo(writer.corpo.Bytes())
+		}
+
+		linha, err := json.Marshal(gravada)
+		if err != nil {
+			log.Printf("[WARN] Erro ao serializar requisição gravada: %v", err)
+			return
+		}
+
+		gravacaoMutex.Lock()
+		defer gravacaoMutex.Unlock()
+
+		f, err := os.OpenFile(recordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Printf("[WARN] Erro ao abrir arquivo de gravação: %v", err)
+			return
+		}
+		defer f.Close()
+
+		if _, err := f.Write(append(linha, '\n')); err != nil {
+			log.Printf("[WARN] Erro ao gravar requisição: %v", err)
+		}
+	}
+}
+
+// Função para ler o arquivo gravado: aceita um array JSON ou uma requisição por linha
+func lerGravacao(caminho string) ([]RequisicaoGravada, error) {
+	conteudo, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+
+	requisicoes := []RequisicaoGravada{}
+	if trimmed := bytes.TrimSpace(conteudo); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &requisicoes); err != nil {
+			return nil, err
+		}
+		return requisicoes, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(conteudo))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	linha := 0
+	for scanner.Scan() {
+		linha++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var r RequisicaoGravada
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("linha %d: %w", linha, err)
+		}
+		requisicoes = append(requisicoes, r)
+	}
+	return requisicoes, scanner.Err()
+}
+
+// Subcomando replay: retorna o código de saída do processo
+func executarReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	banco := fs.String("db", dbName+"_teste", "banco de dados de teste usado no replay")
+	parar := fs.Bool("parar", false, "interromper na primeira divergência de status")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "uso: rls-server replay [-db banco] [-parar] arquivo.json")
+		return 2
+	}
+
+	if *banco == dbName {
+		log.Printf("[ERROR] Replay recusado: o banco '%s' é o banco principal, use um banco de teste", *banco)
+		return 2
+	}
+
+	requisicoes, err := lerGravacao(fs.Arg(0))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao ler gravação: %v", err)
+		return 1
+	}
+	log.Printf("[REPLAY] %d requisições carregadas de %s", len(requisicoes), fs.Arg(0))
+
+	db, err = conectarBanco(*banco)
+	if err != nil {
+		log.Printf("[ERROR] Falha ao iniciar banco de dados: %v", err)
+		return 1
+	}
+	defer db.Close()
+	// O replay consulta só o banco de teste, sem réplica
+	dbLeitura = repositorio.NovoRoteador(db, nil)
+
+	// Não regravar o próprio replay
+	recordFile = ""
+	r := configurarRouter()
+
+	divergencias := 0
+	for i, gravada := range requisicoes {
+		// Sem o corpo completo a requisição não pode ser reproduzida
+		if gravada.CorpoTruncado {
+			log.Printf("[REPLAY] %d/%d %s %s ignorada: corpo truncado na gravação",
+				i+1, len(requisicoes), gravada.Metodo, gravada.Caminho)
+			continue
+		}
+
+		req := httptest.NewRequest(gravada.Metodo, gravada.Caminho, strings.NewReader(gravada.Corpo))
+		for h, v := range gravada.Headers {
+			req.Header.Set(h, v)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		resultado := "OK"
+		if w.Code != gravada.Status {
+			resultado = "DIVERGENTE"
+			divergencias++
+		}
+		log.Printf("[REPLAY] %d/%d %s %s -> %d (gravado %d) %s",
+			i+1, len(requisicoes), gravada.Metodo, gravada.Caminho, w.Code, gravada.Status, resultado)
+
+		if w.Code != gravada.Status {
+			log.Printf("[REPLAY]   resposta gravada: %s", gravada.Resposta)
+			log.Printf("[REPLAY]   resposta obtida:  %s", w.Body.String())
+			if *parar {
+				break
+			}
+		}
+	}
+
+	log.Printf("[REPLAY] Concluído: %d requisições, %d divergências", len(requisicoes), divergencias)
+	if divergencias > 0 {
+		return 1
+	}
+	return 0
+}
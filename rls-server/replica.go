@@ -0,0 +1,102 @@
+// replica.go - Réplica de leitura para relatórios
+//
+// Com DB_REPLICA_DSN (URL postgres:// de uma réplica somente leitura) os
+// relatórios, exportações em PDF e o dashboard passam a consultar a réplica,
+// para o fechamento do mês não disputar o primário com as movimentações do
+// dia. A réplica é verificada a cada DB_REPLICA_VERIFICACAO_SEGUNDOS: se não
+// responder ou estiver mais de DB_REPLICA_ATRASO_MAX_SEGUNDOS atrás do
+// primário, as leituras voltam ao primário até ela se recuperar. Sem senha na
+// URL, valem as credenciais do primário (ver segredos.go).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rlsautomacao/estoque/internal/repositorio"
+)
+
+// Configuração da réplica - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	dbReplicaDSN             = getEnv("DB_REPLICA_DSN", "")
+	dbReplicaAtrasoMaxSegs   = getEnvAsInt("DB_REPLICA_ATRASO_MAX_SEGUNDOS", 30)
+	dbReplicaVerificacaoSegs = getEnvAsInt("DB_REPLICA_VERIFICACAO_SEGUNDOS", 15)
+)
+
+// Consultas pesadas de leitura: réplica quando disponível, senão o primário
+var dbLeitura *repositorio.Roteador
+
+var dbReplica *pgxpool.Pool
+
+// Conecta a réplica, se configurada, e monta o roteador das leituras. Falha
+// na réplica não impede o startup: as leituras ficam no primário.
+func configurarLeituras(ctx context.Context) {
+	if dbReplicaDSN == "" {
+		dbLeitura = repositorio.NovoRoteador(db, nil)
+		return
+	}
+
+	var err error
+	dbReplica, err = conectarReplica(dbReplicaDSN)
+	if err != nil {
+		log.Printf("[WARN] Réplica de leitura indisponível, relatórios no primário: %v", err)
+		dbLeitura = repositorio.NovoRoteador(db, nil)
+		return
+	}
+	dbLeitura = repositorio.NovoRoteador(db, dbReplica)
+	if atraso, err := dbLeitura.VerificarReplica(ctx, time.Duration(dbReplicaAtrasoMaxSegs)*time.Second); err != nil {
+		log.Printf("[WARN] Erro ao verificar a réplica de leitura: %v", err)
+	} else {
+		log.Printf("✓ Réplica de leitura conectada (atraso %v)", atraso.Round(time.Millisecond))
+	}
+}
+
+func conectarReplica(dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("DB_REPLICA_DSN inválida: %w", err)
+	}
+	log.Printf("Conectando à réplica de leitura: %s:%d/%s", config.ConnConfig.Host, config.ConnConfig.Port, config.ConnConfig.Database)
+	if config.ConnConfig.Password == "" {
+		config.BeforeConnect = aplicarCredenciais
+	}
+	return abrirPool(config)
+}
+
+// Verifica a réplica periodicamente e registra as trocas entre réplica e
+// primário; roda até o contexto ser cancelado
+func iniciarVerificacaoReplica(ctx context.Context) {
+	if dbReplica == nil {
+		return
+	}
+	defer dbReplica.Close()
+
+	atrasoMax := time.Duration(dbReplicaAtrasoMaxSegs) * time.Second
+	usando := dbLeitura.UsandoReplica()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(max(dbReplicaVerificacaoSegs, 1)) * time.Second):
+		}
+
+		verificacao, cancelar := context.WithTimeout(ctx, 5*time.Second)
+		atraso, err := dbLeitura.VerificarReplica(verificacao, atrasoMax)
+		cancelar()
+
+		switch agora := dbLeitura.UsandoReplica(); {
+		case usando && !agora && err != nil:
+			log.Printf("[WARN] Réplica de leitura com erro, relatórios no primário: %v", err)
+		case usando && !agora:
+			log.Printf("[WARN] Réplica de leitura %v atrás do primário, relatórios no primário", atraso.Round(time.Second))
+		case !usando && agora:
+			log.Printf("✓ Réplica de leitura em dia (atraso %v), relatórios de volta à réplica", atraso.Round(time.Millisecond))
+		}
+		usando = dbLeitura.UsandoReplica()
+	}
+}
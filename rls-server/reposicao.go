@@ -0,0 +1,265 @@
+// reposicao.go - Sugestões de reposição por mínimo/máximo, consumo e prazo do fornecedor
+
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Fornecedor struct {
+	ID               int       `json:"id,omitempty"`
+	Nome             string    `json:"nome"`
+	PrazoEntregaDias int       `json:"prazo_entrega_dias"`
+	DataCriacao      time.Time `json:"data_criacao,omitempty"`
+	DataAtualizacao  time.Time `json:"data_atualizacao,omitempty"`
+}
+
+// Sugestão de compra para um produto. Quantidade é a quantidade sugerida, no
+// mesmo formato dos itens de pedido de compra.
+type SugestaoReposicao struct {
+	ProdutoID        int     `json:"produto_id"`
+	ProdutoCodigo    string  `json:"produto_codigo"`
+	ProdutoNome      string  `json:"produto_nome"`
+	Fornecedor       string  `json:"fornecedor,omitempty"`
+	QuantidadeAtual  int     `json:"quantidade_atual"`
+	EmPedido         int     `json:"em_pedido"`
+	QuantidadeMinima int     `json:"quantidade_minima"`
+	QuantidadeMaxima int     `json:"quantidade_maxima"`
+	ConsumoDiario    float64 `json:"consumo_diario"`
+	PrazoEntregaDias int     `json:"prazo_entrega_dias"`
+	PontoPedido      int     `json:"ponto_pedido"`
+	Quantidade       int     `json:"quantidade"`
+	GrupoReposicao   string  `json:"grupo_reposicao,omitempty"`
+	Politica         string  `json:"politica"`
+}
+
+// Sugestões agrupadas por fornecedor, prontas para virar um pedido de compra
+type SugestaoFornecedor struct {
+	Fornecedor       string              `json:"fornecedor"`
+	PrazoEntregaDias int                 `json:"prazo_entrega_dias"`
+	Itens            []SugestaoReposicao `json:"itens"`
+}
+
+// Função auxiliar para calcular a quantidade sugerida. O ponto de pedido cobre o
+// mínimo mais o consumo durante o prazo de entrega; ao atingi-lo, a política
+// min_max repõe até o máximo ou, sem máximo definido, até o ponto de pedido
+// mais os dias de cobertura; a política ponto_pedido compra os dias de
+// cobertura de consumo (ver grupos_reposicao.go).
+func calcularSugestao(s *SugestaoReposicao, diasCobertura int) {
+	consumoPrazo := int(math.Ceil(s.ConsumoDiario * float64(s.PrazoEntregaDias)))
+	s.PontoPedido = s.QuantidadeMinima + consumoPrazo
+
+	posicao := s.QuantidadeAtual + s.EmPedido
+	if posicao > s.PontoPedido {
+		return
+	}
+
+	if s.Politica == PoliticaPontoPedido {
+		s.Quantidade = max(int(math.Ceil(s.ConsumoDiario*float64(diasCobertura))), s.PontoPedido-posicao)
+		return
+	}
+
+	alvo := s.QuantidadeMaxima
+	if alvo == 0 {
+		alvo = s.PontoPedido + int(math.Ceil(s.ConsumoDiario*float64(diasCobertura)))
+	}
+	if alvo > posicao {
+		s.Quantidade = alvo - posicao
+	}
+}
+
+// Handlers de Reposição
+
+func getSugestoesReposicao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Janela de consumo usada para a taxa diária
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "90"))
+	if err != nil || dias <= 0 {
+		dias = 90
+	}
+	diasCobertura, err := strconv.Atoi(c.DefaultQuery("cobertura", "30"))
+	if err != nil || diasCobertura < 0 {
+		diasCobertura = 30
+	}
+	prazoPadrao, err := strconv.Atoi(lerConfiguracao(ctx, "prazo_entrega_padrao", "7"))
+	if err != nil || prazoPadrao < 0 {
+		prazoPadrao = 7
+	}
+
+	log.Printf("[DB] Calculando sugestões de reposição (consumo de %d dias, cobertura de %d dias)", dias, diasCobertura)
+
+	// Pedidos ainda não recebidos contam como estoque a caminho; o grupo de
+	// reposição atual do produto define a política e a cobertura
+	rows, err := db.Query(ctx, `
+		WITH consumo AS (
+			SELECT produto_id, SUM(quantidade) AS total
+			FROM movimentacoes
+			WHERE tipo = 'saida' AND data_movimentacao >= CURRENT_TIMESTAMP - $1 * interval '1 day'
+			GROUP BY produto_id
+		), em_pedido AS (
+			SELECT i.produto_id, SUM(i.quantidade - i.quantidade_recebida) AS quantidade
+			FROM pedidos_compra_itens i
+			JOIN pedidos_compra pc ON i.pedido_id = pc.id
+			WHERE pc.status IN ('rascunho', 'enviado', 'recebido_parcial')
+			GROUP BY i.produto_id
+		)
+		SELECT p.id, p.codigo, p.nome, COALESCE(p.fornecedor, ''), p.quantidade,
+		       COALESCE(p.quantidade_minima, 0), p.quantidade_maxima,
+		       COALESCE(c.total, 0), COALESCE(e.quantidade, 0),
+		       COALESCE(f.prazo_entrega_dias, $2),
+		       COALESCE(g.nome, ''), COALESCE(g.politica, $3), COALESCE(g.cobertura_dias, $4)
+		FROM produtos p
+		LEFT JOIN consumo c ON c.produto_id = p.id
+		LEFT JOIN em_pedido e ON e.produto_id = p.id
+		LEFT JOIN fornecedores f ON f.nome = p.fornecedor
+		LEFT JOIN grupos_reposicao_produtos gp ON gp.produto_id = p.id AND gp.fim IS NULL
+		LEFT JOIN grupos_reposicao g ON g.id = gp.grupo_id
+		ORDER BY p.nome
+	`, dias, prazoPadrao, PoliticaMinMax, diasCobertura)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar dados de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar dados de reposição"})
+		return
+	}
+	defer rows.Close()
+
+	sugestoes := []SugestaoReposicao{}
+	for rows.Next() {
+		var s SugestaoReposicao
+		var consumoTotal, cobertura int
+		err := rows.Scan(&s.ProdutoID, &s.ProdutoCodigo, &s.ProdutoNome, &s.Fornecedor, &s.QuantidadeAtual,
+			&s.QuantidadeMinima, &s.QuantidadeMaxima, &consumoTotal, &s.EmPedido, &s.PrazoEntregaDias,
+			&s.GrupoReposicao, &s.Politica, &cobertura)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar produto: %v", err)
+			continue
+		}
+
+		s.ConsumoDiario = float64(consumoTotal) / float64(dias)
+		calcularSugestao(&s, cobertura)
+		if s.Quantidade > 0 {
+			sugestoes = append(sugestoes, s)
+		}
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar dados de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar dados de reposição"})
+		return
+	}
+
+	log.Printf("[API] %d produtos com sugestão de reposição", len(sugestoes))
+
+	if c.Query("agrupar") != "fornecedor" {
+		c.JSON(http.StatusOK, sugestoes)
+		return
+	}
+
+	grupos := map[string]*SugestaoFornecedor{}
+	for _, s := range sugestoes {
+		g, ok := grupos[s.Fornecedor]
+		if !ok {
+			g = &SugestaoFornecedor{Fornecedor: s.Fornecedor, PrazoEntregaDias: s.PrazoEntregaDias}
+			grupos[s.Fornecedor] = g
+		}
+		g.Itens = append(g.Itens, s)
+	}
+
+	resultado := make([]SugestaoFornecedor, 0, len(grupos))
+	for _, g := range grupos {
+		resultado = append(resultado, *g)
+	}
+	sort.Slice(resultado, func(a, b int) bool { return resultado[a].Fornecedor < resultado[b].Fornecedor })
+
+	c.JSON(http.StatusOK, resultado)
+}
+
+// Handlers de Fornecedores
+
+func getFornecedores(c *gin.Context) {
+	log.Println("[DB] Buscando fornecedores")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, nome, prazo_entrega_dias, data_criacao, data_atualizacao
+		FROM fornecedores
+		ORDER BY nome
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar fornecedores: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar fornecedores"})
+		return
+	}
+	defer rows.Close()
+
+	fornecedores := []Fornecedor{}
+	for rows.Next() {
+		var f Fornecedor
+		var dataAtualizacao *time.Time
+		if err := rows.Scan(&f.ID, &f.Nome, &f.PrazoEntregaDias, &f.DataCriacao, &dataAtualizacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar fornecedor: %v", err)
+			continue
+		}
+		if dataAtualizacao != nil {
+			f.DataAtualizacao = *dataAtualizacao
+		}
+		fornecedores = append(fornecedores, f)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar fornecedores: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar fornecedores"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fornecedores)
+}
+
+// Cria ou atualiza o prazo de entrega de um fornecedor pelo nome
+func salvarFornecedor(c *gin.Context) {
+	nome := c.Param("nome")
+
+	var f Fornecedor
+	if err := c.ShouldBindJSON(&f); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if f.PrazoEntregaDias < 0 {
+		log.Printf("[ERROR] Prazo de entrega inválido: %d", f.PrazoEntregaDias)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Prazo de entrega não pode ser negativo"})
+		return
+	}
+	f.Nome = nome
+
+	log.Printf("[DB] Salvando fornecedor %s (prazo de %d dias)", nome, f.PrazoEntregaDias)
+
+	var dataAtualizacao *time.Time
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO fornecedores(nome, prazo_entrega_dias)
+		VALUES ($1, $2)
+		ON CONFLICT (nome) DO UPDATE SET prazo_entrega_dias = EXCLUDED.prazo_entrega_dias
+		RETURNING id, data_criacao, data_atualizacao
+	`, f.Nome, f.PrazoEntregaDias).Scan(&f.ID, &f.DataCriacao, &dataAtualizacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao salvar fornecedor: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar fornecedor"})
+		return
+	}
+	if dataAtualizacao != nil {
+		f.DataAtualizacao = *dataAtualizacao
+	}
+
+	c.JSON(http.StatusOK, f)
+}
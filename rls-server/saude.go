@@ -0,0 +1,164 @@
+// saude.go - Probes de saúde (liveness) e prontidão (readiness)
+//
+// GET /healthz responde 200 enquanto o processo atende requisições, sem tocar
+// no banco: serve ao healthcheck do Docker e à liveness probe do Kubernetes.
+//
+// GET /readyz diz se a instância pode receber tráfego: o banco responde ao
+// ping dentro de READYZ_TIMEOUT_MS, todas as migrações embutidas no binário
+// estão aplicadas e o pool de conexões não está saturado (uso acima de
+// READYZ_POOL_SATURACAO). Durante o desligamento responde 503 para o
+// balanceador tirar a instância antes de as conexões fecharem. O corpo traz o
+// detalhe de cada verificação.
+//
+// As duas rotas ficam fora de /api e antes do log de acesso, para as
+// verificações periódicas não o encherem.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração da prontidão - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	readyzTimeoutMs     = getEnvAsInt("READYZ_TIMEOUT_MS", 2000)
+	readyzPoolSaturacao = getEnvAsFloat("READYZ_POOL_SATURACAO", 0.95)
+	inicioProcesso      = time.Now()
+)
+
+type VerificacaoBanco struct {
+	OK         bool   `json:"ok"`
+	LatenciaMs int64  `json:"latencia_ms"`
+	Erro       string `json:"erro,omitempty"`
+}
+
+type VerificacaoMigracoes struct {
+	OK        bool   `json:"ok"`
+	Versao    int    `json:"versao"`   // última aplicada no banco
+	Esperada  int    `json:"esperada"` // última embutida no binário
+	Pendentes int    `json:"pendentes"`
+	Erro      string `json:"erro,omitempty"`
+}
+
+type VerificacaoPool struct {
+	OK      bool    `json:"ok"`
+	EmUso   int32   `json:"em_uso"`
+	Ociosas int32   `json:"ociosas"`
+	Maximo  int32   `json:"maximo"`
+	Uso     float64 `json:"uso"`     // em_uso / maximo
+	Esperas int64   `json:"esperas"` // aquisições que precisaram esperar, desde o início
+}
+
+type StatusSaude struct {
+	Status         string `json:"status"`
+	UptimeSegundos int64  `json:"uptime_segundos"`
+}
+
+type StatusProntidao struct {
+	Status     string               `json:"status"` // pronto ou indisponivel
+	Desligando bool                 `json:"desligando"`
+	Banco      VerificacaoBanco     `json:"banco"`
+	Migracoes  VerificacaoMigracoes `json:"migracoes"`
+	Pool       VerificacaoPool      `json:"pool"`
+}
+
+func verificarBanco(ctx context.Context) VerificacaoBanco {
+	inicio := time.Now()
+	err := db.Ping(ctx)
+	v := VerificacaoBanco{OK: err == nil, LatenciaMs: time.Since(inicio).Milliseconds()}
+	if err != nil {
+		v.Erro = err.Error()
+	}
+	return v
+}
+
+func verificarMigracoes(ctx context.Context) VerificacaoMigracoes {
+	var v VerificacaoMigracoes
+	migracoes, err := listarMigracoes()
+	if err != nil {
+		v.Erro = err.Error()
+		return v
+	}
+
+	aplicadas := map[int]bool{}
+	rows, err := db.Query(ctx, "SELECT versao FROM schema_migracoes")
+	if err != nil {
+		v.Erro = err.Error()
+		return v
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var versao int
+		if err := rows.Scan(&versao); err != nil {
+			v.Erro = err.Error()
+			return v
+		}
+		aplicadas[versao] = true
+		v.Versao = max(v.Versao, versao)
+	}
+	if err := rows.Err(); err != nil {
+		v.Erro = err.Error()
+		return v
+	}
+
+	for _, m := range migracoes {
+		v.Esperada = max(v.Esperada, m.versao)
+		if !aplicadas[m.versao] {
+			v.Pendentes++
+		}
+	}
+	v.OK = v.Pendentes == 0
+	return v
+}
+
+func verificarPool() VerificacaoPool {
+	s := db.Stat()
+	v := VerificacaoPool{
+		EmUso:   s.AcquiredConns(),
+		Ociosas: s.IdleConns(),
+		Maximo:  s.MaxConns(),
+		Esperas: s.EmptyAcquireCount(),
+	}
+	if v.Maximo > 0 {
+		v.Uso = float64(v.EmUso) / float64(v.Maximo)
+	}
+	v.OK = v.Uso < readyzPoolSaturacao
+	return v
+}
+
+// Handlers das Probes
+
+func getHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, StatusSaude{Status: "ok", UptimeSegundos: int64(time.Since(inicioProcesso).Seconds())})
+}
+
+func getReadyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(max(readyzTimeoutMs, 1))*time.Millisecond)
+	defer cancel()
+
+	s := StatusProntidao{Status: "pronto"}
+	select {
+	case <-encerrando:
+		s.Desligando = true
+	default:
+	}
+
+	s.Banco = verificarBanco(ctx)
+	if s.Banco.OK {
+		s.Migracoes = verificarMigracoes(ctx)
+	} else {
+		s.Migracoes.Erro = "banco indisponível"
+	}
+	s.Pool = verificarPool()
+
+	status := http.StatusOK
+	if s.Desligando || !s.Banco.OK || !s.Migracoes.OK || !s.Pool.OK {
+		s.Status = "indisponivel"
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, s)
+}
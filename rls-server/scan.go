@@ -0,0 +1,343 @@
+// scan.go - Resolução de leituras do scanner (EAN-13, Code 128, QR Code)
+//
+// GET /api/scan/*codigo recebe o conteúdo lido pelo scanner do app e resolve
+// o produto em uma chamada: primeiro pelo código do produto, depois pelos
+// códigos de barras adicionais (tabela codigos_barras, vários por produto) e
+// pelo EAN das embalagens de fornecedor, caso em que a quantidade padrão é o
+// fator da caixa, e por fim pelos códigos antigos (aliases_codigos). A
+// resposta traz o produto e o que o app precisa para oferecer as ações rápidas.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Origens possíveis de uma leitura resolvida
+const (
+	OrigemScanProduto      = "produto"
+	OrigemScanCodigoBarras = "codigo_barras"
+	OrigemScanEmbalagem    = "embalagem"
+	OrigemScanAlias        = "alias"
+)
+
+type CodigoBarras struct {
+	ID          int       `json:"id,omitempty"`
+	ProdutoID   int       `json:"produto_id"`
+	Codigo      string    `json:"codigo"`
+	Tipo        string    `json:"tipo,omitempty"` // ean13, code128, qrcode...
+	Descricao   string    `json:"descricao,omitempty"`
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+// Ações rápidas disponíveis para o produto lido
+type AcoesScan struct {
+	Entrada          bool   `json:"entrada"`
+	Saida            bool   `json:"saida"`
+	ExigeSeries      bool   `json:"exige_series"`
+	ExigeMotivo      bool   `json:"exige_motivo"`
+	QuantidadePadrao int    `json:"quantidade_padrao"`
+	Unidade          string `json:"unidade"`
+}
+
+type ResultadoScan struct {
+	Lido         string    `json:"lido"`
+	Origem       string    `json:"origem"`
+	Produto      Produto   `json:"produto"`
+	EstoqueBaixo bool      `json:"estoque_baixo"`
+	Acoes        AcoesScan `json:"acoes"`
+
+	// Preenchido quando a leitura é um código antigo do produto
+	CodigoObsoleto *AvisoCodigoObsoleto `json:"codigo_obsoleto,omitempty"`
+}
+
+// Função auxiliar para normalizar a leitura: remove o identificador de
+// simbologia AIM ("]C1", "]E0"...) e, em URLs, fica com o último segmento
+func normalizarLeitura(lido string) string {
+	lido = strings.TrimSpace(strings.TrimPrefix(lido, "/"))
+	if len(lido) > 3 && lido[0] == ']' {
+		lido = lido[3:]
+	}
+	if strings.Contains(lido, ":/") {
+		lido = strings.TrimRight(lido, "/")
+		lido = lido[strings.LastIndex(lido, "/")+1:]
+	}
+	return lido
+}
+
+// Confere o dígito verificador de um EAN-13
+func eanValido(codigo string) bool {
+	if len(codigo) != 13 {
+		return false
+	}
+	soma := 0
+	for i := 0; i < 12; i++ {
+		if codigo[i] < '0' || codigo[i] > '9' {
+			return false
+		}
+		peso := 1
+		if i%2 == 1 {
+			peso = 3
+		}
+		soma += int(codigo[i]-'0') * peso
+	}
+	return int(codigo[12]-'0') == (10-soma%10)%10
+}
+
+// Variantes equivalentes da leitura: UPC-A (12 dígitos) também é procurado
+// como EAN-13 com zero à esquerda e vice-versa
+func variantesLeitura(lido string) []string {
+	variantes := []string{lido}
+	if _, err := strconv.ParseUint(lido, 10, 64); err == nil {
+		if len(lido) == 12 {
+			variantes = append(variantes, "0"+lido)
+		} else if len(lido) == 13 && lido[0] == '0' {
+			variantes = append(variantes, lido[1:])
+		}
+	}
+	return variantes
+}
+
+// Resolve a leitura; devolve pgx.ErrNoRows quando nada corresponde
+func resolverLeitura(ctx context.Context, lido string) (ResultadoScan, error) {
+	r := ResultadoScan{Lido: lido}
+	variantes := variantesLeitura(lido)
+	fator := 1
+
+	p, err := scanProduto(db.QueryRow(ctx, `
+		SELECT `+produtoColunas+` FROM produtos WHERE codigo = ANY($1) LIMIT 1
+	`, variantes))
+	r.Origem = OrigemScanProduto
+	if err == pgx.ErrNoRows {
+		p, err = scanProduto(db.QueryRow(ctx, `
+			SELECT `+prefixarColunas("p", produtoColunas)+`
+			FROM codigos_barras cb
+			JOIN produtos p ON p.id = cb.produto_id
+			WHERE cb.codigo = ANY($1)
+			LIMIT 1
+		`, variantes))
+		r.Origem = OrigemScanCodigoBarras
+	}
+	if err == pgx.ErrNoRows {
+		var produtoID int
+		err = db.QueryRow(ctx, `
+			SELECT produto_id, fator FROM embalagens_fornecedor WHERE ean = ANY($1) LIMIT 1
+		`, variantes).Scan(&produtoID, &fator)
+		if err == nil {
+			p, err = scanProduto(db.QueryRow(ctx, `SELECT `+produtoColunas+` FROM produtos WHERE id = $1`, produtoID))
+		}
+		r.Origem = OrigemScanEmbalagem
+	}
+	if err == pgx.ErrNoRows {
+		// Etiqueta antiga: código renomeado ou de produto mesclado
+		p, err = buscarProdutoPorAlias(ctx, variantes)
+		if err == nil {
+			r.CodigoObsoleto = novoAvisoCodigoObsoleto(lido, p.Codigo)
+		}
+		r.Origem = OrigemScanAlias
+	}
+	if err != nil {
+		return r, err
+	}
+
+	r.Produto = p
+	r.EstoqueBaixo = p.Quantidade <= p.QuantidadeMinima
+	r.Acoes = AcoesScan{
+		Entrada:          true,
+		Saida:            p.Quantidade > 0,
+		ExigeSeries:      p.ControlaSerie,
+		ExigeMotivo:      p.Perigoso,
+		QuantidadePadrao: fator,
+		Unidade:          p.UnidadeMedida,
+	}
+	return r, nil
+}
+
+// Prefixa cada coluna da lista com o alias da tabela
+func prefixarColunas(alias, colunas string) string {
+	partes := strings.Split(colunas, ",")
+	for i, coluna := range partes {
+		partes[i] = alias + "." + strings.TrimSpace(coluna)
+	}
+	return strings.Join(partes, ", ")
+}
+
+// Handlers de Scan e Códigos de Barras
+
+func getScan(c *gin.Context) {
+	lido := normalizarLeitura(c.Param("codigo"))
+	if lido == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Leitura vazia"})
+		return
+	}
+
+	// 13 dígitos com verificador errado é leitura falha, não código desconhecido
+	if _, err := strconv.ParseUint(lido, 10, 64); err == nil && len(lido) == 13 && !eanValido(lido) {
+		log.Printf("[ERROR] EAN-13 com dígito verificador inválido: %s", lido)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "EAN-13 com dígito verificador inválido; leia novamente"})
+		return
+	}
+
+	log.Printf("[DB] Resolvendo leitura do scanner: %s", lido)
+
+	r, err := resolverLeitura(c.Request.Context(), lido)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Nenhum produto para a leitura: %s", lido)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		} else {
+			log.Printf("[ERROR] Erro ao resolver leitura: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produto"})
+		}
+		return
+	}
+
+	log.Printf("[DB] Leitura %s resolvida para o produto %s (%s)", lido, r.Produto.Codigo, r.Origem)
+	c.JSON(http.StatusOK, r)
+}
+
+func getCodigosBarrasPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, produto_id, codigo, tipo, descricao, data_criacao
+		FROM codigos_barras
+		WHERE produto_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar códigos de barras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar códigos de barras"})
+		return
+	}
+	defer rows.Close()
+
+	codigos := []CodigoBarras{}
+	for rows.Next() {
+		var cb CodigoBarras
+		var tipo, descricao *string
+		if err := rows.Scan(&cb.ID, &cb.ProdutoID, &cb.Codigo, &tipo, &descricao, &cb.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar código de barras: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if tipo != nil {
+			cb.Tipo = *tipo
+		}
+		if descricao != nil {
+			cb.Descricao = *descricao
+		}
+		codigos = append(codigos, cb)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar códigos de barras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar códigos de barras"})
+		return
+	}
+
+	c.JSON(http.StatusOK, codigos)
+}
+
+func criarCodigoBarras(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var cb CodigoBarras
+	if err := c.ShouldBindJSON(&cb); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	cb.ProdutoID = id
+	cb.Codigo = strings.TrimSpace(cb.Codigo)
+	if cb.Codigo == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "O código é obrigatório"})
+		return
+	}
+	if cb.Tipo == "ean13" && !eanValido(cb.Codigo) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "EAN-13 com dígito verificador inválido"})
+		return
+	}
+
+	// Um código só pode levar a um produto
+	var emUso bool
+	err = db.QueryRow(c.Request.Context(), `
+		SELECT EXISTS(SELECT 1 FROM produtos WHERE codigo = $1)
+			OR EXISTS(SELECT 1 FROM codigos_barras WHERE codigo = $1)
+			OR EXISTS(SELECT 1 FROM embalagens_fornecedor WHERE ean = $1)
+	`, cb.Codigo).Scan(&emUso)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar código de barras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar código de barras"})
+		return
+	}
+	if emUso {
+		log.Printf("[DB] Código de barras '%s' já está em uso", cb.Codigo)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Este código já identifica um produto ou embalagem"})
+		return
+	}
+
+	err = db.QueryRow(c.Request.Context(), `
+		INSERT INTO codigos_barras(produto_id, codigo, tipo, descricao)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING id, data_criacao
+	`, cb.ProdutoID, cb.Codigo, cb.Tipo, cb.Descricao).Scan(&cb.ID, &cb.DataCriacao)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar código de barras: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar código de barras (verifique o produto)"})
+		return
+	}
+
+	log.Printf("[DB] Código de barras %s cadastrado para o produto ID: %d", cb.Codigo, cb.ProdutoID)
+	c.JSON(http.StatusCreated, cb)
+}
+
+func deletarCodigoBarras(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM codigos_barras WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir código de barras: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir código de barras"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Código de barras não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Código de barras não encontrado"})
+		return
+	}
+
+	log.Printf("[DB] Código de barras excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Código de barras excluído com sucesso"})
+}
@@ -0,0 +1,289 @@
+// segredos.go - Credenciais do banco vindas de arquivo ou do Vault
+//
+// Por padrão a senha vem de DB_PASSWORD. Com DB_PASSWORD_FILE a senha é lida
+// de um arquivo (secret do Docker/Kubernetes, arquivo gerado pelo Vault Agent);
+// com VAULT_ADDR e VAULT_SECRET_PATH usuário e senha são buscados no Vault
+// (KV v1/v2 ou credenciais dinâmicas do engine database). As credenciais são
+// relidas a cada SEGREDOS_RECARGA_SEGUNDOS: novas conexões já usam o valor
+// novo e, quando ele muda, o pool é reciclado sem derrubar as consultas em
+// andamento (conexões em uso só fecham ao serem devolvidas).
+//
+// Credenciais dinâmicas vêm com lease, e cada leitura cria um usuário novo no
+// banco: enquanto o lease é válido ele é renovado, e as credenciais só são
+// lidas de novo quando a renovação falha ou o lease chega perto do TTL máximo.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Configuração dos segredos - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	dbPasswordFile     = getEnv("DB_PASSWORD_FILE", "")
+	vaultAddr          = strings.TrimRight(getEnv("VAULT_ADDR", ""), "/")
+	vaultToken         = getEnv("VAULT_TOKEN", "")
+	vaultTokenFile     = getEnv("VAULT_TOKEN_FILE", "")
+	vaultSecretPath    = strings.Trim(getEnv("VAULT_SECRET_PATH", ""), "/")
+	segredosRecargaSeg = getEnvAsInt("SEGREDOS_RECARGA_SEGUNDOS", 60)
+)
+
+type credenciaisBanco struct {
+	usuario string
+	senha   string
+}
+
+// Lease das credenciais dinâmicas do Vault; id vazio nos segredos do KV
+type leaseVault struct {
+	id        string
+	duracao   time.Duration
+	renovavel bool
+}
+
+// Cache das credenciais; em caso de falha na leitura continua valendo a última obtida
+var segredos struct {
+	mu       sync.Mutex
+	atual    credenciaisBanco
+	lidoEm   time.Time
+	lease    leaseVault
+	expiraEm time.Time
+}
+
+// Indica se as credenciais vêm de uma fonte externa (e portanto podem ser rotacionadas)
+func segredosExternos() bool {
+	return dbPasswordFile != "" || (vaultAddr != "" && vaultSecretPath != "")
+}
+
+// Função auxiliar para ler um arquivo de segredo, sem a quebra de linha final
+func lerArquivoSegredo(caminho string) (string, error) {
+	dados, err := os.ReadFile(caminho)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(dados), "\r\n"), nil
+}
+
+// Faz uma chamada à API do Vault com o token configurado e decodifica a resposta
+func chamarVault(ctx context.Context, metodo, caminho string, corpo any, resposta any) error {
+	token := vaultToken
+	if vaultTokenFile != "" {
+		var err error
+		if token, err = lerArquivoSegredo(vaultTokenFile); err != nil {
+			return fmt.Errorf("erro ao ler token do Vault: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var leitor io.Reader
+	if corpo != nil {
+		dados, err := json.Marshal(corpo)
+		if err != nil {
+			return err
+		}
+		leitor = bytes.NewReader(dados)
+	}
+	req, err := http.NewRequestWithContext(ctx, metodo, vaultAddr+"/v1/"+caminho, leitor)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Vault respondeu %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(resposta); err != nil {
+		return fmt.Errorf("resposta do Vault inválida: %w", err)
+	}
+	return nil
+}
+
+// Busca usuário e senha no Vault; aceita a resposta do KV v2 (data.data) e do
+// KV v1 / engine database (data)
+func lerCredenciaisVault(ctx context.Context) (credenciaisBanco, leaseVault, error) {
+	var corpo struct {
+		LeaseID       string         `json:"lease_id"`
+		LeaseDuration int            `json:"lease_duration"`
+		Renewable     bool           `json:"renewable"`
+		Data          map[string]any `json:"data"`
+	}
+	if err := chamarVault(ctx, http.MethodGet, vaultSecretPath, nil, &corpo); err != nil {
+		return credenciaisBanco{}, leaseVault{}, err
+	}
+	dados := corpo.Data
+	if interno, ok := dados["data"].(map[string]any); ok {
+		dados = interno
+	}
+
+	usuario, _ := dados["username"].(string)
+	senha, _ := dados["password"].(string)
+	if senha == "" {
+		return credenciaisBanco{}, leaseVault{}, errors.New("segredo do Vault sem o campo password")
+	}
+	if usuario == "" {
+		usuario = dbUser
+	}
+
+	// O KV v1 também informa lease_duration (intervalo sugerido de leitura),
+	// mas sem lease_id: só as credenciais dinâmicas têm lease a renovar
+	lease := leaseVault{}
+	if corpo.LeaseID != "" {
+		lease = leaseVault{id: corpo.LeaseID, duracao: time.Duration(corpo.LeaseDuration) * time.Second, renovavel: corpo.Renewable}
+	}
+	return credenciaisBanco{usuario: usuario, senha: senha}, lease, nil
+}
+
+// Renova o lease e devolve a nova duração, que o Vault limita ao TTL máximo
+func renovarLeaseVault(ctx context.Context, id string) (time.Duration, error) {
+	var corpo struct {
+		LeaseDuration int `json:"lease_duration"`
+	}
+	err := chamarVault(ctx, http.MethodPut, "sys/leases/renew", map[string]string{"lease_id": id}, &corpo)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(corpo.LeaseDuration) * time.Second, nil
+}
+
+// Margem para trocar as credenciais antes de o lease expirar: duas recargas
+func margemLease() time.Duration {
+	return 2 * time.Duration(max(segredosRecargaSeg, 1)) * time.Second
+}
+
+// Lê as credenciais na fonte configurada
+func lerCredenciaisBanco(ctx context.Context) (credenciaisBanco, leaseVault, error) {
+	switch {
+	case vaultAddr != "" && vaultSecretPath != "":
+		return lerCredenciaisVault(ctx)
+	case dbPasswordFile != "":
+		senha, err := lerArquivoSegredo(dbPasswordFile)
+		if err != nil {
+			return credenciaisBanco{}, leaseVault{}, fmt.Errorf("erro ao ler DB_PASSWORD_FILE: %w", err)
+		}
+		return credenciaisBanco{usuario: dbUser, senha: senha}, leaseVault{}, nil
+	default:
+		return credenciaisBanco{usuario: dbUser, senha: dbPassword}, leaseVault{}, nil
+	}
+}
+
+// Relê as credenciais e devolve se mudaram desde a última leitura. Com lease,
+// renova em vez de ler: uma leitura nova criaria outro usuário no banco
+func recarregarCredenciais(ctx context.Context) (credenciaisBanco, bool, error) {
+	segredos.mu.Lock()
+	atual, lidoEm, lease, expiraEm := segredos.atual, segredos.lidoEm, segredos.lease, segredos.expiraEm
+	segredos.mu.Unlock()
+
+	if !lidoEm.IsZero() && lease.id != "" {
+		// Mais da metade do lease pela frente: nada a fazer
+		if time.Until(expiraEm) > lease.duracao/2 {
+			return atual, false, nil
+		}
+		if lease.renovavel {
+			duracao, err := renovarLeaseVault(ctx, lease.id)
+			if err != nil {
+				log.Printf("[WARN] Erro ao renovar o lease das credenciais do banco: %v", err)
+			} else if duracao >= margemLease() {
+				segredos.mu.Lock()
+				segredos.lease.duracao = duracao
+				segredos.expiraEm = time.Now().Add(duracao)
+				segredos.mu.Unlock()
+				return atual, false, nil
+			}
+		}
+		// Renovação recusada ou TTL máximo próximo: credenciais novas
+		log.Println("[DB] Lease das credenciais do banco perto de expirar, buscando credenciais novas")
+	}
+
+	novas, novoLease, err := lerCredenciaisBanco(ctx)
+
+	segredos.mu.Lock()
+	defer segredos.mu.Unlock()
+
+	if err != nil {
+		return segredos.atual, false, err
+	}
+	if novoLease.id != "" && novoLease.duracao/2 < time.Duration(max(segredosRecargaSeg, 1))*time.Second {
+		log.Printf("[WARN] Lease das credenciais (%s) curto para SEGREDOS_RECARGA_SEGUNDOS=%d; reduza o intervalo", novoLease.duracao, segredosRecargaSeg)
+	}
+	mudou := !segredos.lidoEm.IsZero() && novas != segredos.atual
+	segredos.atual = novas
+	segredos.lidoEm = time.Now()
+	segredos.lease = novoLease
+	segredos.expiraEm = segredos.lidoEm.Add(novoLease.duracao)
+	return novas, mudou, nil
+}
+
+// Credenciais em cache; a primeira chamada faz a leitura e as seguintes
+// dependem de vigiarSegredos para acompanhar a rotação
+func credenciaisAtuais(ctx context.Context) (credenciaisBanco, error) {
+	segredos.mu.Lock()
+	atual, lidoEm := segredos.atual, segredos.lidoEm
+	segredos.mu.Unlock()
+
+	if !lidoEm.IsZero() {
+		return atual, nil
+	}
+	atual, _, err := recarregarCredenciais(ctx)
+	return atual, err
+}
+
+// Hook do pool: cada nova conexão usa as credenciais atuais
+func aplicarCredenciais(ctx context.Context, cc *pgx.ConnConfig) error {
+	cred, err := credenciaisAtuais(ctx)
+	if err != nil {
+		return err
+	}
+	cc.User = cred.usuario
+	cc.Password = cred.senha
+	return nil
+}
+
+// Acompanha a rotação das credenciais e recicla o pool quando elas mudam;
+// roda até o contexto ser cancelado
+func vigiarSegredos(ctx context.Context, pool *pgxpool.Pool) {
+	if !segredosExternos() || segredosRecargaSeg <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(segredosRecargaSeg) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, mudou, err := recarregarCredenciais(ctx)
+		if err != nil {
+			log.Printf("[WARN] Erro ao recarregar credenciais do banco, mantendo as anteriores: %v", err)
+			continue
+		}
+		if mudou {
+			log.Println("[DB] Credenciais do banco rotacionadas, reciclando conexões do pool")
+			pool.Reset()
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// series.go - Rastreamento de unidades individuais por número de série
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados de uma unidade serializada
+const (
+	SerieEmEstoque = "em_estoque"
+	SerieBaixada   = "baixado"
+)
+
+const msgQuantidadeSerie = "Produtos com controle de série só têm a quantidade alterada por movimentações com números de série"
+
+var (
+	errSerieNaoControlada = errors.New("o produto não possui controle de série")
+	errSerieQuantidade    = errors.New("a quantidade de números de série deve ser igual à quantidade movimentada")
+	errSerieDuplicada     = errors.New("número de série repetido na movimentação")
+	errSerieVazia         = errors.New("número de série vazio")
+	errSerieJaEmEstoque   = errors.New("unidade já está em estoque")
+	errSerieIndisponivel  = errors.New("unidade não encontrada em estoque")
+)
+
+// Função auxiliar para diferenciar erros de validação de erros de banco
+func erroSerieValidacao(err error) bool {
+	var serieErr *erroSerie
+	return errors.As(err, &serieErr)
+}
+
+// Erro de validação associado a um número de série específico
+type erroSerie struct {
+	numero string
+	err    error
+}
+
+func (e *erroSerie) Error() string {
+	if e.numero == "" {
+		return e.err.Error()
+	}
+	return e.numero + ": " + e.err.Error()
+}
+
+func (e *erroSerie) Unwrap() error { return e.err }
+
+type UnidadeSerie struct {
+	ID            int                 `json:"id"`
+	ProdutoID     int                 `json:"produto_id"`
+	ProdutoCodigo string              `json:"produto_codigo"`
+	ProdutoNome   string              `json:"produto_nome"`
+	Numero        string              `json:"numero"`
+	Status        string              `json:"status"`
+	DataCriacao   time.Time           `json:"data_criacao"`
+	Historico     []MovimentacaoSerie `json:"historico,omitempty"`
+}
+
+type MovimentacaoSerie struct {
+	MovimentacaoID   int       `json:"movimentacao_id"`
+	Tipo             string    `json:"tipo"`
+	Notas            string    `json:"notas,omitempty"`
+	DataMovimentacao time.Time `json:"data_movimentacao"`
+}
+
+// Registra as unidades movimentadas, dentro da transação. Entradas criam (ou
+// devolvem ao estoque) as unidades; saídas exigem unidades em estoque e as baixam.
+func registrarSeriesMovimentacao(ctx context.Context, tx pgx.Tx, m *Movimentacao, controlaSerie bool) error {
+	if !controlaSerie {
+		if len(m.Series) > 0 {
+			return &erroSerie{err: errSerieNaoControlada}
+		}
+		return nil
+	}
+
+	if len(m.Series) != m.Quantidade {
+		return &erroSerie{err: errSerieQuantidade}
+	}
+
+	vistos := map[string]bool{}
+	for i, numero := range m.Series {
+		numero = strings.TrimSpace(numero)
+		if numero == "" {
+			return &erroSerie{err: errSerieVazia}
+		}
+		if vistos[numero] {
+			return &erroSerie{numero: numero, err: errSerieDuplicada}
+		}
+		vistos[numero] = true
+		m.Series[i] = numero
+	}
+
+	for _, numero := range m.Series {
+		var serieID int
+		var err error
+
+		if m.Tipo == "entrada" {
+			// Unidade nova ou devolvida após baixa
+			err = tx.QueryRow(ctx, `
+				INSERT INTO numeros_serie(produto_id, numero, status)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (produto_id, numero) DO UPDATE SET status = EXCLUDED.status
+				WHERE numeros_serie.status = $4
+				RETURNING id
+			`, m.ProdutoID, numero, SerieEmEstoque, SerieBaixada).Scan(&serieID)
+			if err == pgx.ErrNoRows {
+				return &erroSerie{numero: numero, err: errSerieJaEmEstoque}
+			}
+		} else {
+			err = tx.QueryRow(ctx, `
+				UPDATE numeros_serie SET status = $1
+				WHERE produto_id = $2 AND numero = $3 AND status = $4
+				RETURNING id
+			`, SerieBaixada, m.ProdutoID, numero, SerieEmEstoque).Scan(&serieID)
+			if err == pgx.ErrNoRows {
+				return &erroSerie{numero: numero, err: errSerieIndisponivel}
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO movimentacoes_series(movimentacao_id, serie_id)
+			VALUES ($1, $2)
+		`, m.ID, serieID)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[DB] %d números de série registrados na movimentação ID: %d", len(m.Series), m.ID)
+	return nil
+}
+
+// Handlers de Números de Série
+
+func getSerie(c *gin.Context) {
+	numero := c.Param("numero")
+	log.Printf("[DB] Buscando unidade com número de série: %s", numero)
+
+	// O mesmo número pode existir em produtos de fabricantes diferentes
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT s.id, s.produto_id, p.codigo, p.nome, s.numero, s.status, s.data_criacao
+		FROM numeros_serie s
+		JOIN produtos p ON s.produto_id = p.id
+		WHERE s.numero = $1
+		ORDER BY s.id
+	`, numero)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar número de série: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar número de série"})
+		return
+	}
+
+	unidades := []UnidadeSerie{}
+	for rows.Next() {
+		var u UnidadeSerie
+		if err := rows.Scan(&u.ID, &u.ProdutoID, &u.ProdutoCodigo, &u.ProdutoNome, &u.Numero, &u.Status, &u.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar número de série: %v", err)
+			continue
+		}
+		unidades = append(unidades, u)
+	}
+	rows.Close()
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar números de série: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar números de série"})
+		return
+	}
+
+	if len(unidades) == 0 {
+		log.Printf("[DB] Número de série não encontrado: %s", numero)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Número de série não encontrado"})
+		return
+	}
+
+	// Histórico completo de movimentações de cada unidade
+	for i := range unidades {
+		hrows, err := db.Query(c.Request.Context(), `
+			SELECT m.id, m.tipo, m.notas, m.data_movimentacao
+			FROM movimentacoes_series ms
+			JOIN movimentacoes m ON ms.movimentacao_id = m.id
+			WHERE ms.serie_id = $1
+			ORDER BY m.data_movimentacao ASC, m.id
+		`, unidades[i].ID)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao buscar histórico do número de série: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar histórico do número de série"})
+			return
+		}
+
+		historico := []MovimentacaoSerie{}
+		for hrows.Next() {
+			var h MovimentacaoSerie
+			var notas *string
+			if err := hrows.Scan(&h.MovimentacaoID, &h.Tipo, &notas, &h.DataMovimentacao); err != nil {
+				log.Printf("[ERROR] Erro ao processar histórico: %v", err)
+				continue
+			}
+			if notas != nil {
+				h.Notas = *notas
+			}
+			historico = append(historico, h)
+		}
+		hrows.Close()
+
+		unidades[i].Historico = historico
+	}
+
+	log.Printf("[DB] Número de série %s encontrado em %d produto(s)", numero, len(unidades))
+	c.JSON(http.StatusOK, unidades)
+}
+
+func getSeriesPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// Por padrão lista apenas as unidades disponíveis para saída
+	status := c.DefaultQuery("status", SerieEmEstoque)
+	log.Printf("[DB] Buscando números de série do produto ID: %d (status: %s)", id, status)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT s.id, s.produto_id, p.codigo, p.nome, s.numero, s.status, s.data_criacao
+		FROM numeros_serie s
+		JOIN produtos p ON s.produto_id = p.id
+		WHERE s.produto_id = $1 AND ($2 = 'todos' OR s.status = $2)
+		ORDER BY s.numero
+	`, id, status)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar números de série: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar números de série"})
+		return
+	}
+	defer rows.Close()
+
+	unidades := []UnidadeSerie{}
+	for rows.Next() {
+		var u UnidadeSerie
+		if err := rows.Scan(&u.ID, &u.ProdutoID, &u.ProdutoCodigo, &u.ProdutoNome, &u.Numero, &u.Status, &u.DataCriacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar número de série: %v", err)
+			continue
+		}
+		unidades = append(unidades, u)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar números de série: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar números de série"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d números de série para o produto ID: %d", len(unidades), id)
+	c.JSON(http.StatusOK, unidades)
+}
@@ -0,0 +1,299 @@
+// setup.go - Assistente de primeira execução
+//
+// Enquanto o setup não foi concluído, as rotas /api/setup/* guiam a
+// configuração inicial: dados da empresa e importação do cadastro de produtos
+// com o saldo inicial, registrado como movimentação "Estoque inicial" (com
+// lote, validade e séries, quando informados). A importação só é aceita com o
+// cadastro vazio. POST /api/setup/concluir grava setup_concluido e, a partir
+// daí, as rotas de setup respondem 403. GET /api/setup (estado) continua
+// disponível.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dados da empresa guardados em configuracoes (chave empresa_<campo>)
+type EmpresaSetup struct {
+	Nome     string `json:"nome"`
+	CNPJ     string `json:"cnpj,omitempty"`
+	Endereco string `json:"endereco,omitempty"`
+	Telefone string `json:"telefone,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+type EstadoSetup struct {
+	Disponivel      bool `json:"disponivel"`
+	Concluido       bool `json:"concluido"`
+	EmpresaDefinida bool `json:"empresa_definida"`
+	Produtos        int  `json:"produtos"`
+	Movimentacoes   int  `json:"movimentacoes"`
+}
+
+// Produto da importação inicial com os dados do saldo de abertura
+type ProdutoSetup struct {
+	Produto
+	EstoqueInicialSetup
+}
+
+// Lote e validade opcionais do saldo de abertura e, nos produtos com controle
+// de série, um número de série por unidade
+type EstoqueInicialSetup struct {
+	Lote     string   `json:"lote,omitempty" binding:"max=50"`
+	Validade string   `json:"validade,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	Series   []string `json:"series,omitempty" binding:"dive,max=100"`
+}
+
+// Função auxiliar para calcular o estado do setup
+func lerEstadoSetup(ctx context.Context) (EstadoSetup, error) {
+	var e EstadoSetup
+	err := db.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM produtos), (SELECT COUNT(*) FROM movimentacoes)
+	`).Scan(&e.Produtos, &e.Movimentacoes)
+	if err != nil {
+		return e, err
+	}
+
+	e.Concluido = lerConfiguracao(ctx, "setup_concluido", "false") == "true"
+	e.EmpresaDefinida = lerConfiguracao(ctx, "empresa_nome", "") != ""
+	// O saldo inicial gera movimentações, então o que fecha o setup é a
+	// conclusão; a importação ainda exige o cadastro vazio
+	e.Disponivel = !e.Concluido
+	return e, nil
+}
+
+// Middleware que libera as rotas de setup apenas enquanto ele está disponível
+func SetupDisponivel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		estado, err := lerEstadoSetup(c.Request.Context())
+		if err != nil {
+			log.Printf("[ERROR] Erro ao verificar estado do setup: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar estado do setup"})
+			return
+		}
+		if !estado.Disponivel {
+			log.Printf("[WARN] Rota de setup bloqueada: %s", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "Setup já concluído"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Função auxiliar para gravar uma configuração, criando a chave se preciso.
+// O cache fica a cargo de quem chama, depois do commit.
+func salvarConfiguracao(ctx context.Context, q querier, chave, valor, descricao string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO configuracoes (chave, valor, descricao)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chave) DO UPDATE SET valor = EXCLUDED.valor, data_atualizacao = CURRENT_TIMESTAMP
+	`, chave, valor, descricao)
+	return err
+}
+
+// Handlers do Setup
+
+func getSetup(c *gin.Context) {
+	estado, err := lerEstadoSetup(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar estado do setup: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar estado do setup"})
+		return
+	}
+	c.JSON(http.StatusOK, estado)
+}
+
+func salvarEmpresaSetup(c *gin.Context) {
+	var e EmpresaSetup
+	if err := c.ShouldBindJSON(&e); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if strings.TrimSpace(e.Nome) == "" {
+		log.Println("[ERROR] Nome da empresa não informado")
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nome da empresa é obrigatório"})
+		return
+	}
+
+	if err := salvarEmpresa(c.Request.Context(), e); err != nil {
+		log.Printf("[ERROR] Erro ao salvar dados da empresa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao salvar dados da empresa"})
+		return
+	}
+
+	log.Printf("[DB] Dados da empresa definidos no setup: %s", e.Nome)
+	c.JSON(http.StatusOK, e)
+}
+
+// Importação inicial do cadastro; tudo ou nada
+func importarProdutosSetup(c *gin.Context) {
+	var produtos []ProdutoSetup
+	if err := decodificarLista(c, &produtos); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if len(produtos) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nenhum produto informado"})
+		return
+	}
+
+	// Todas as violações de todos os produtos, com a posição de cada um
+	campos := []ErroCampo{}
+	for i := range produtos {
+		violacoes := append(validarCampos(&produtos[i].Produto), validarCampos(&produtos[i].EstoqueInicialSetup)...)
+		for _, campo := range violacoes {
+			campo.Campo = fmt.Sprintf("[%d].%s", i, campo.Campo)
+			campos = append(campos, campo)
+		}
+	}
+	if len(campos) > 0 {
+		log.Printf("[ERROR] Importação do setup com %d campos inválidos", len(campos))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos", Codigo: CodigoDadosInvalidos, Campos: campos})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	codigos := map[string]bool{}
+	for i := range produtos {
+		if err := vincularVariante(ctx, db, 0, &produtos[i].Produto); err != nil {
+			log.Printf("[ERROR] Variante inválida na importação (código '%s'): %v", produtos[i].Codigo, err)
+			responderErroVariante(c, err)
+			return
+		}
+		if !vincularAtributos(c, db, &produtos[i].Produto) {
+			return
+		}
+		// Nos serializados o saldo vem das séries, conferidas na movimentação inicial
+		p := produtos[i].Produto
+		if p.ControlaSerie {
+			p.Quantidade = 0
+		}
+		msg := validarNovoProduto(ctx, &p)
+		produtos[i].Codigo, produtos[i].UnidadeMedida = p.Codigo, p.UnidadeMedida
+		if msg != "" {
+			log.Printf("[ERROR] Produto inválido na importação (código '%s'): %s", produtos[i].Codigo, msg)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: produtos[i].Codigo + ": " + msg})
+			return
+		}
+		if codigos[produtos[i].Codigo] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Código repetido: " + produtos[i].Codigo})
+			return
+		}
+		codigos[produtos[i].Codigo] = true
+	}
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar produtos"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	// A importação é a carga inicial: com produtos já cadastrados o saldo de
+	// abertura se misturaria ao histórico existente. O lock evita duas
+	// importações simultâneas
+	if _, err = tx.Exec(ctx, "LOCK TABLE produtos IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		log.Printf("[ERROR] Erro ao bloquear cadastro de produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar produtos"})
+		return
+	}
+	var cadastrados bool
+	if err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM produtos)").Scan(&cadastrados); err != nil {
+		log.Printf("[ERROR] Erro ao verificar produtos existentes: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produtos existentes"})
+		return
+	}
+	if cadastrados {
+		log.Println("[WARN] Importação do setup recusada: cadastro de produtos não está vazio")
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A importação inicial exige o cadastro de produtos vazio; use a importação de produtos"})
+		return
+	}
+
+	for i := range produtos {
+		p := &produtos[i]
+		if err := vincularLocalizacao(ctx, tx, &p.Produto); err != nil {
+			log.Printf("[ERROR] Localização inválida no produto %s: %v", p.Codigo, err)
+			responderErroLocalizacao(c, err)
+			return
+		}
+
+		// O produto nasce zerado e o saldo entra pela movimentação inicial,
+		// com lotes, séries e custo como qualquer entrada
+		inicial := p.Quantidade
+		if p.ControlaSerie && inicial == 0 {
+			inicial = len(p.Series)
+		}
+		p.Quantidade = 0
+		if err := inserirProduto(ctx, tx, &p.Produto); err != nil {
+			log.Printf("[ERROR] Erro ao importar produto %s: %v", p.Codigo, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar produtos"})
+			return
+		}
+		if inicial == 0 {
+			continue
+		}
+
+		m := Movimentacao{ProdutoID: p.ID, Tipo: "entrada", Quantidade: inicial, Notas: "Estoque inicial",
+			Lote: p.Lote, Validade: p.Validade, Series: p.Series}
+		if m.Quantidade < 0 {
+			m.Tipo, m.Quantidade = "saida", -m.Quantidade
+		}
+		if p.PrecoCusto > 0 && m.Tipo == "entrada" {
+			custo := p.PrecoCusto
+			m.CustoUnitario = &custo
+		}
+		if err := registrarMovimentacao(ctx, tx, &m); err != nil {
+			e, ok := err.(*erroMovimentacao)
+			if !ok {
+				log.Printf("[ERROR] Erro ao registrar estoque inicial do produto %s: %v", p.Codigo, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar produtos"})
+				return
+			}
+			log.Printf("[ERROR] Estoque inicial do produto %s recusado: %s", p.Codigo, e.msg)
+			e.msg = p.Codigo + ": " + e.msg
+			c.JSON(e.status, e.resposta())
+			return
+		}
+		p.Quantidade = inicial
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao confirmar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao importar produtos"})
+		return
+	}
+
+	log.Printf("[DB] Importação inicial do setup: %d produtos", len(produtos))
+	c.JSON(http.StatusCreated, gin.H{"importados": len(produtos)})
+}
+
+func concluirSetup(c *gin.Context) {
+	ctx := c.Request.Context()
+	if lerConfiguracao(ctx, "empresa_nome", "") == "" {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Defina os dados da empresa antes de concluir o setup"})
+		return
+	}
+
+	if err := salvarConfiguracao(ctx, db, "setup_concluido", "true", "Assistente de primeira execução concluído"); err != nil {
+		log.Printf("[ERROR] Erro ao concluir setup: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao concluir setup"})
+		return
+	}
+	guardarConfiguracao("setup_concluido", "true")
+
+	log.Println("[API] Setup inicial concluído")
+	estado, _ := lerEstadoSetup(ctx)
+	c.JSON(http.StatusOK, estado)
+}
@@ -0,0 +1,232 @@
+// smoke.go - Teste de fumaça pós-deploy
+//
+//	rls-server smoke [-url http://localhost:8080] [-espera 60s] [-token T] [-codigo SMOKE-1]
+//
+// Roda contra a instância recém-iniciada, pela API e com o cliente Go:
+// espera o /readyz (banco acessível e todas as migrações aplicadas), faz o
+// CRUD de um produto de teste com código próprio, registra uma entrada e o
+// estorno dela (saída da mesma quantidade) conferindo o saldo e consulta os
+// endpoints críticos. O produto de teste é excluído no fim, mesmo com falha.
+//
+// Cada verificação sai em uma linha "ok" ou "FALHA"; o código de saída é 0
+// quando todas passam, 1 quando alguma falha e 2 em erro de uso, para o script
+// de deploy interromper o rollout. O código padrão é SMOKE-<época>; com
+// política de códigos restritiva, informe um válido em -codigo. O produto
+// gera os eventos normais (webhooks, feed), identificáveis pelo código.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rlsautomacao/estoque/client"
+)
+
+// Endpoints de leitura que precisam responder 200 depois do deploy
+var endpointsCriticos = []string{
+	"/api/v1/produtos",
+	"/api/v1/produtos/estoque-baixo",
+	"/api/v1/movimentacoes?limit=1",
+	"/api/v1/dashboard",
+	"/api/v1/docs/openapi.json",
+}
+
+type testeFumaca struct {
+	url    string
+	token  string
+	http   *http.Client
+	falhas int
+}
+
+// Executa uma verificação e escreve o resultado
+func (t *testeFumaca) verificar(nome string, fn func() error) bool {
+	inicio := time.Now()
+	err := fn()
+	duracao := time.Since(inicio).Round(time.Millisecond)
+	if err != nil {
+		t.falhas++
+		fmt.Printf("FALHA %-32s %6s  %v\n", nome, duracao, err)
+		return false
+	}
+	fmt.Printf("ok    %-32s %6s\n", nome, duracao)
+	return true
+}
+
+// GET direto na instância; devolve o corpo quando o status é o esperado
+func (t *testeFumaca) get(ctx context.Context, caminho string, esperado int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url+caminho, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	corpo, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != esperado {
+		return corpo, fmt.Errorf("status %d (esperado %d): %s", resp.StatusCode, esperado, strings.TrimSpace(string(corpo)))
+	}
+	return corpo, nil
+}
+
+// Espera a instância ficar pronta e confere as migrações
+func (t *testeFumaca) aguardarProntidao(ctx context.Context, espera time.Duration) error {
+	limite := time.Now().Add(espera)
+	for {
+		corpo, err := t.get(ctx, "/readyz", http.StatusOK)
+		if err == nil {
+			var s StatusProntidao
+			if err = json.Unmarshal(corpo, &s); err == nil && s.Migracoes.Pendentes > 0 {
+				err = fmt.Errorf("%d migrações pendentes", s.Migracoes.Pendentes)
+			}
+			if err == nil {
+				return nil
+			}
+		}
+		if time.Now().After(limite) {
+			return fmt.Errorf("instância não ficou pronta em %s: %w", espera, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Confere o saldo do produto de teste
+func conferirSaldo(ctx context.Context, c *client.Client, id, esperado int) error {
+	p, err := c.Produtos.Obter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Quantidade != esperado {
+		return fmt.Errorf("saldo %d, esperado %d", p.Quantidade, esperado)
+	}
+	return nil
+}
+
+func executarSmoke(args []string) int {
+	fs := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	url := fs.String("url", fmt.Sprintf("http://localhost:%s", serverPort), "endereço da instância testada")
+	espera := fs.Duration("espera", 60*time.Second, "tempo máximo de espera pelo /readyz")
+	token := fs.String("token", "", "token enviado em Authorization: Bearer")
+	codigo := fs.String("codigo", fmt.Sprintf("SMOKE-%d", time.Now().Unix()), "código do produto de teste")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "uso: rls-server smoke [-url endereço] [-espera 60s] [-token T] [-codigo SMOKE-1]")
+		return 2
+	}
+
+	ctx := context.Background()
+	t := &testeFumaca{url: strings.TrimRight(*url, "/"), token: *token, http: &http.Client{Timeout: 15 * time.Second}}
+	c := client.New(t.url, client.WithToken(*token), client.WithRetries(1, 0))
+	fmt.Printf("Teste de fumaça em %s\n", t.url)
+
+	if !t.verificar("prontidão e migrações", func() error { return t.aguardarProntidao(ctx, *espera) }) {
+		// Sem a instância pronta as demais verificações só repetiriam o erro
+		fmt.Println("Teste de fumaça FALHOU: instância não ficou pronta")
+		return 1
+	}
+	t.verificar("healthz", func() error {
+		_, err := t.get(ctx, "/healthz", http.StatusOK)
+		return err
+	})
+
+	// CRUD do produto de teste
+	var produto *client.Produto
+	criado := t.verificar("criar produto "+*codigo, func() error {
+		var err error
+		produto, err = c.Produtos.Criar(ctx, client.Produto{
+			Codigo: *codigo,
+			Nome:   "Produto do teste de fumaça",
+			Notas:  "Criado e excluído por rls-server smoke",
+		})
+		return err
+	})
+	if criado {
+		t.verificar("buscar produto pelo código", func() error {
+			p, err := c.Produtos.ObterPorCodigo(ctx, produto.Codigo)
+			if err == nil && p.ID != produto.ID {
+				err = fmt.Errorf("código devolveu o produto %d, esperado %d", p.ID, produto.ID)
+			}
+			return err
+		})
+		t.verificar("atualizar produto", func() error {
+			alterado := *produto
+			alterado.Nome = "Produto do teste de fumaça (alterado)"
+			p, err := c.Produtos.Atualizar(ctx, produto.ID, alterado)
+			if err == nil && p.Nome != alterado.Nome {
+				err = fmt.Errorf("nome não foi alterado: %q", p.Nome)
+			}
+			return err
+		})
+
+		// Movimentação e estorno
+		t.verificar("registrar entrada", func() error {
+			_, err := c.Movimentacoes.Criar(ctx, client.Movimentacao{
+				ProdutoID: produto.ID, Tipo: "entrada", Quantidade: 5, Notas: "Teste de fumaça",
+			})
+			if err != nil {
+				return err
+			}
+			return conferirSaldo(ctx, c, produto.ID, 5)
+		})
+		t.verificar("estornar entrada", func() error {
+			_, err := c.Movimentacoes.Criar(ctx, client.Movimentacao{
+				ProdutoID: produto.ID, Tipo: "saida", Quantidade: 5, Notas: "Estorno do teste de fumaça",
+			})
+			if err != nil {
+				return err
+			}
+			return conferirSaldo(ctx, c, produto.ID, 0)
+		})
+	}
+
+	for _, caminho := range endpointsCriticos {
+		t.verificar("GET "+caminho, func() error {
+			_, err := t.get(ctx, caminho, http.StatusOK)
+			return err
+		})
+	}
+
+	// Remover o produto de teste mesmo que alguma verificação tenha falhado
+	if criado {
+		t.verificar("excluir produto", func() error {
+			if err := c.Produtos.Excluir(ctx, produto.ID); err != nil {
+				return err
+			}
+			_, err := c.Produtos.Obter(ctx, produto.ID)
+			var e *client.Erro
+			if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+				return fmt.Errorf("produto ainda acessível depois da exclusão: %v", err)
+			}
+			return nil
+		})
+	}
+
+	if t.falhas > 0 {
+		fmt.Printf("Teste de fumaça FALHOU: %d verificações com erro\n", t.falhas)
+		return 1
+	}
+	fmt.Println("Teste de fumaça ok")
+	return 0
+}
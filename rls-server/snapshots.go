@@ -0,0 +1,146 @@
+// snapshots.go - Fotografias diárias da quantidade de cada produto
+//
+// Um job grava todo dia, no horário SNAPSHOT_HORARIO (HH:MM, padrão 23:55), a
+// quantidade de cada produto em estoque_snapshots. A curva histórica passa a
+// ser uma leitura simples em vez de reconstruída a partir das movimentações.
+// POST /api/admin/snapshots-estoque grava (ou regrava) a fotografia do dia.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var snapshotHorario = getEnv("SNAPSHOT_HORARIO", "23:55")
+
+type PontoEstoque struct {
+	Data       time.Time `json:"data"`
+	Quantidade int       `json:"quantidade"`
+}
+
+// Grava a quantidade atual de todos os produtos na data de hoje
+func registrarSnapshotEstoque(ctx context.Context) (int64, error) {
+	tag, err := db.Exec(ctx, `
+		INSERT INTO estoque_snapshots(data, produto_id, quantidade)
+		SELECT CURRENT_DATE, id, quantidade FROM produtos
+		ON CONFLICT (produto_id, data) DO UPDATE SET
+			quantidade = EXCLUDED.quantidade,
+			data_criacao = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Função auxiliar para calcular a próxima execução a partir do horário HH:MM
+func proximoHorario(agora time.Time, horario, padrao string) time.Time {
+	h, err := time.Parse("15:04", horario)
+	if err != nil {
+		log.Printf("[WARN] Horário inválido (%s), usando %s", horario, padrao)
+		h, _ = time.Parse("15:04", padrao)
+	}
+
+	proximo := time.Date(agora.Year(), agora.Month(), agora.Day(), h.Hour(), h.Minute(), 0, 0, agora.Location())
+	if !proximo.After(agora) {
+		proximo = proximo.AddDate(0, 0, 1)
+	}
+	return proximo
+}
+
+// Agenda a fotografia diária; roda até o contexto ser cancelado
+func agendarSnapshotsEstoque(ctx context.Context) {
+	for {
+		proximo := proximoHorario(time.Now(), snapshotHorario, "23:55")
+		log.Printf("[DB] Próxima fotografia do estoque em %s", proximo.Format("2006-01-02 15:04"))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(proximo)):
+		}
+
+		n, err := registrarSnapshotEstoque(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao gravar fotografia do estoque: %v", err)
+			continue
+		}
+		log.Printf("[DB] Fotografia do estoque gravada: %d produtos", n)
+	}
+}
+
+// Handlers de Histórico de Estoque
+
+func criarSnapshotEstoque(c *gin.Context) {
+	log.Println("[API] Gravando fotografia do estoque sob demanda")
+
+	n, err := registrarSnapshotEstoque(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gravar fotografia do estoque: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gravar fotografia do estoque"})
+		return
+	}
+
+	log.Printf("[DB] Fotografia do estoque gravada: %d produtos", n)
+	c.JSON(http.StatusCreated, gin.H{"produtos": n, "data": time.Now().Format(formatoData)})
+}
+
+func getHistoricoEstoque(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	de, ate, ok := lerPeriodo(c, 90)
+	if !ok {
+		log.Printf("[ERROR] Período inválido: de=%s, ate=%s", c.Query("de"), c.Query("ate"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Período inválido, use de/ate no formato AAAA-MM-DD"})
+		return
+	}
+
+	log.Printf("[DB] Buscando histórico de estoque do produto ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT data, quantidade
+		FROM estoque_snapshots
+		WHERE produto_id = $1 AND data >= $2 AND data < $3
+		ORDER BY data
+	`, id, de, ate)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar histórico de estoque: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar histórico de estoque"})
+		return
+	}
+	defer rows.Close()
+
+	pontos := []PontoEstoque{}
+	for rows.Next() {
+		var p PontoEstoque
+		if err := rows.Scan(&p.Data, &p.Quantidade); err != nil {
+			log.Printf("[ERROR] Erro ao processar histórico de estoque: %v", err)
+			continue
+		}
+		pontos = append(pontos, p)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar histórico de estoque: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar histórico de estoque"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d pontos de histórico para o produto ID: %d", len(pontos), id)
+	c.JSON(http.StatusOK, pontos)
+}
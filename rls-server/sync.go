@@ -0,0 +1,339 @@
+// sync.go - Sincronização de lançamentos feitos offline nos coletores
+//
+// Os coletores costumam ter o relógio desregulado. Antes de enviar, o coletor
+// faz o handshake em POST /api/sync/handshake com o próprio relógio; o
+// servidor guarda a diferença (offset) para o dispositivo. Em
+// POST /api/sync/lancamentos cada movimentação traz a data do dispositivo, que
+// é ajustada pelo offset antes do registro. Mesmo ajustada, uma data mais de
+// SYNC_TOLERANCIA_SEGUNDOS no futuro é rejeitada.
+//
+// As datas são aceitas em RFC 3339, "AAAA-MM-DD HH:MM:SS" (com T ou espaço,
+// frações opcionais), "DD/MM/AAAA HH:MM:SS" ou época Unix em segundos ou
+// milissegundos (número ou texto); sem fuso, vale o fuso do servidor.
+//
+// Cada lançamento é independente: os válidos são registrados e a resposta traz
+// o resultado de cada um, para o coletor reenviar só os que falharam. O desvio
+// de cada dispositivo fica em GET /api/sync/dispositivos.
+//
+// Além de JSON, os três endpoints aceitam e respondem MessagePack e protobuf
+// (ver sync_formatos.go).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rlsautomacao/estoque/internal/syncpb"
+)
+
+// Configuração do sync - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var syncToleranciaSegundos = getEnvAsInt("SYNC_TOLERANCIA_SEGUNDOS", 300)
+
+// Formatos de data aceitos dos dispositivos, além da época Unix
+var formatosInstante = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+}
+
+// Data informada pelo dispositivo, em qualquer dos formatos aceitos
+type instanteDispositivo struct {
+	time.Time
+}
+
+// Interpreta uma data do dispositivo; números abaixo de 1e11 são segundos,
+// acima, milissegundos
+func lerInstante(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 1e11 {
+			return time.Unix(n, 0), nil
+		}
+		return time.UnixMilli(n), nil
+	}
+	for _, formato := range formatosInstante {
+		if t, err := time.ParseInLocation(formato, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("data em formato não reconhecido: %q", s)
+}
+
+func (i *instanteDispositivo) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("data deve ser texto ou número")
+		}
+		s = n.String()
+	}
+	t, err := lerInstante(s)
+	if err != nil {
+		return err
+	}
+	i.Time = t
+	return nil
+}
+
+type HandshakeSync struct {
+	Dispositivo string              `json:"dispositivo"`
+	Relogio     instanteDispositivo `json:"relogio"`
+}
+
+type RespostaHandshakeSync struct {
+	Dispositivo        string    `json:"dispositivo"`
+	Servidor           time.Time `json:"servidor"`
+	OffsetMs           int64     `json:"offset_ms"` // relógio do dispositivo - relógio do servidor
+	ToleranciaSegundos int       `json:"tolerancia_segundos"`
+}
+
+type LancamentoSync struct {
+	Movimentacao
+	DataDispositivo instanteDispositivo `json:"data_dispositivo,omitempty"` // sem data: agora
+}
+
+type RequisicaoSync struct {
+	Dispositivo string           `json:"dispositivo"`
+	Lancamentos []LancamentoSync `json:"lancamentos"`
+}
+
+type ResultadoSync struct {
+	Dispositivo string              `json:"dispositivo"`
+	OffsetMs    int64               `json:"offset_ms"`
+	Registrados int                 `json:"registrados"`
+	Rejeitados  int                 `json:"rejeitados"`
+	Itens       []ResultadoItemLote `json:"itens"`
+}
+
+type DispositivoSync struct {
+	Dispositivo     string     `json:"dispositivo"`
+	OffsetMs        int64      `json:"offset_ms"`
+	SkewMaximoMs    int64      `json:"skew_maximo_ms"`
+	UltimoHandshake time.Time  `json:"ultimo_handshake"`
+	UltimoSync      *time.Time `json:"ultimo_sync,omitempty"`
+	Lancamentos     int        `json:"lancamentos"`
+	Rejeitados      int        `json:"rejeitados"`
+}
+
+// Handlers de Sync
+
+func handshakeSync(c *gin.Context) {
+	servidor := time.Now()
+
+	var req HandshakeSync
+	if err := lerCorpoSync(c, &req, &syncpb.HandshakeSync{}); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos: " + err.Error()})
+		return
+	}
+	req.Dispositivo = strings.TrimSpace(req.Dispositivo)
+	if req.Dispositivo == "" || req.Relogio.IsZero() {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dispositivo e relógio são obrigatórios"})
+		return
+	}
+
+	offset := req.Relogio.Sub(servidor).Milliseconds()
+	_, err := db.Exec(c.Request.Context(), `
+		INSERT INTO sync_dispositivos(dispositivo, offset_ms, skew_maximo_ms, ultimo_handshake)
+		VALUES ($1, $2, ABS($2), CURRENT_TIMESTAMP)
+		ON CONFLICT (dispositivo) DO UPDATE SET
+			offset_ms = EXCLUDED.offset_ms,
+			skew_maximo_ms = GREATEST(sync_dispositivos.skew_maximo_ms, EXCLUDED.skew_maximo_ms),
+			ultimo_handshake = EXCLUDED.ultimo_handshake
+	`, req.Dispositivo, offset)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao registrar handshake: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao registrar handshake"})
+		return
+	}
+
+	if abs := max(offset, -offset); abs > int64(syncToleranciaSegundos)*1000 {
+		log.Printf("[WARN] Relógio do dispositivo %s desviado em %d ms", req.Dispositivo, offset)
+	}
+	responderSync(c, http.StatusOK, RespostaHandshakeSync{
+		Dispositivo:        req.Dispositivo,
+		Servidor:           servidor,
+		OffsetMs:           offset,
+		ToleranciaSegundos: syncToleranciaSegundos,
+	}, &syncpb.RespostaHandshakeSync{})
+}
+
+// Registra um lançamento com a data ajustada em um savepoint
+func registrarLancamentoSync(ctx context.Context, tx pgx.Tx, l *LancamentoSync, offsetMs int64) error {
+	data := time.Now()
+	if !l.DataDispositivo.IsZero() {
+		data = l.DataDispositivo.Add(-time.Duration(offsetMs) * time.Millisecond)
+	}
+	if adiantado := time.Until(data); adiantado > time.Duration(syncToleranciaSegundos)*time.Second {
+		return &erroMovimentacao{status: http.StatusBadRequest,
+			msg: fmt.Sprintf("Data %s no futuro mesmo após o ajuste do relógio (%s adiante)",
+				l.DataDispositivo.Format(time.RFC3339), adiantado.Round(time.Second))}
+	}
+
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx)
+
+	if err = registrarMovimentacao(ctx, sp, &l.Movimentacao); err != nil {
+		return err
+	}
+
+	// A data é gravada relativa ao relógio do banco, sem depender do fuso da sessão
+	err = sp.QueryRow(ctx, `
+		UPDATE movimentacoes SET data_movimentacao = CURRENT_TIMESTAMP - $1::bigint * interval '1 millisecond'
+		WHERE id = ANY($2)
+		RETURNING data_movimentacao
+	`, max(time.Since(data).Milliseconds(), 0), l.Movimentacao.idsRegistrados()).Scan(&l.Movimentacao.DataMovimentacao)
+	if err != nil {
+		return err
+	}
+	for i := range l.Movimentacao.Componentes {
+		l.Movimentacao.Componentes[i].DataMovimentacao = l.Movimentacao.DataMovimentacao
+	}
+	return sp.Commit(ctx)
+}
+
+func sincronizarLancamentos(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req RequisicaoSync
+	if err := lerCorpoSync(c, &req, &syncpb.RequisicaoSync{}); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dados inválidos: " + err.Error()})
+		return
+	}
+	req.Dispositivo = strings.TrimSpace(req.Dispositivo)
+	if req.Dispositivo == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Dispositivo é obrigatório"})
+		return
+	}
+	if len(req.Lancamentos) == 0 || len(req.Lancamentos) > movimentacoesLoteMaximo {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Envie entre 1 e %d lançamentos", movimentacoesLoteMaximo),
+		})
+		return
+	}
+
+	// Iniciar transação
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	resultado := ResultadoSync{Dispositivo: req.Dispositivo, Itens: make([]ResultadoItemLote, len(req.Lancamentos))}
+	err = tx.QueryRow(ctx, "SELECT offset_ms FROM sync_dispositivos WHERE dispositivo = $1 FOR UPDATE",
+		req.Dispositivo).Scan(&resultado.OffsetMs)
+	if err == pgx.ErrNoRows {
+		log.Printf("[WARN] Sync do dispositivo %s sem handshake", req.Dispositivo)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Dispositivo sem handshake; chame /sync/handshake antes de enviar"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar dispositivo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar dispositivo"})
+		return
+	}
+
+	var registradas []Movimentacao
+	for i := range req.Lancamentos {
+		item := &resultado.Itens[i]
+		item.Indice = i
+
+		if err := registrarLancamentoSync(ctx, tx, &req.Lancamentos[i], resultado.OffsetMs); err != nil {
+			resultado.Rejeitados++
+			item.Status = "erro"
+			if e, ok := err.(*erroMovimentacao); ok {
+				item.Erro, item.Codigo, item.Campos = e.msg, e.codigo, e.campos
+				item.Falta = e.falta
+			} else {
+				log.Printf("[ERROR] Erro ao registrar lançamento %d do dispositivo %s: %v", i, req.Dispositivo, err)
+				item.Erro = "Erro ao registrar movimentação"
+			}
+			continue
+		}
+		resultado.Registrados++
+		item.Status = "ok"
+		item.Movimentacao = &req.Lancamentos[i].Movimentacao
+		registradas = append(registradas, req.Lancamentos[i].Movimentacao)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE sync_dispositivos SET ultimo_sync = CURRENT_TIMESTAMP,
+			lancamentos = lancamentos + $1, rejeitados = rejeitados + $2
+		WHERE dispositivo = $3
+	`, resultado.Registrados, resultado.Rejeitados, req.Dispositivo)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar dispositivo: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar dispositivo"})
+		return
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Sync do dispositivo %s: %d registrados, %d rejeitados (offset %d ms)",
+		req.Dispositivo, resultado.Registrados, resultado.Rejeitados, resultado.OffsetMs)
+	publicarMovimentacoes(ctx, registradas)
+
+	responderSync(c, http.StatusOK, resultado, &syncpb.ResultadoSync{})
+}
+
+func getDispositivosSync(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT dispositivo, offset_ms, skew_maximo_ms, ultimo_handshake, ultimo_sync, lancamentos, rejeitados
+		FROM sync_dispositivos
+		ORDER BY ABS(offset_ms) DESC, dispositivo
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar dispositivos de sync: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar dispositivos de sync"})
+		return
+	}
+	defer rows.Close()
+
+	dispositivos := []DispositivoSync{}
+	for rows.Next() {
+		var d DispositivoSync
+		if err := rows.Scan(&d.Dispositivo, &d.OffsetMs, &d.SkewMaximoMs, &d.UltimoHandshake, &d.UltimoSync,
+			&d.Lancamentos, &d.Rejeitados); err != nil {
+			log.Printf("[ERROR] Erro ao processar dispositivo de sync: %v", err)
+			continue
+		}
+		dispositivos = append(dispositivos, d)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar dispositivos de sync: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar dispositivos de sync"})
+		return
+	}
+
+	responderSync(c, http.StatusOK, dispositivos, &syncpb.ListaDispositivosSync{})
+}
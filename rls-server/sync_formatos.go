@@ -0,0 +1,148 @@
+// sync_formatos.go - Formatos de payload do sync: JSON, MessagePack e protobuf
+//
+// Os endpoints de /api/sync aceitam o corpo em JSON (padrão), MessagePack
+// (application/msgpack ou application/x-msgpack) ou protobuf
+// (application/x-protobuf), conforme o Content-Type, e respondem no formato
+// pedido em Accept. O MessagePack usa os mesmos nomes de campo do JSON; o
+// protobuf segue o schema versionado em internal/syncpb/sync_v1.proto e a
+// resposta informa a mensagem em Content-Type (proto=rls.sync.v1.<Mensagem>).
+// As respostas de erro continuam em problem+json.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Formatos aceitos no sync, na ordem de preferência quando o Accept empata
+var formatosSync = []string{binding.MIMEJSON, binding.MIMEMSGPACK2, binding.MIMEMSGPACK, binding.MIMEPROTOBUF}
+
+// Lê o corpo da requisição de sync no formato do Content-Type e valida as
+// tags binding como c.ShouldBindJSON. mensagem é a mensagem protobuf
+// equivalente a destino, usada só com application/x-protobuf.
+func lerCorpoSync(c *gin.Context, destino any, mensagem proto.Message) error {
+	switch c.ContentType() {
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		dec := msgpack.NewDecoder(c.Request.Body)
+		dec.SetCustomStructTag("json")
+		if err := dec.Decode(destino); err != nil {
+			return err
+		}
+	case binding.MIMEPROTOBUF:
+		corpo, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		if err := proto.Unmarshal(corpo, mensagem); err != nil {
+			return err
+		}
+		// Os campos do schema têm os nomes do JSON: a mensagem vira o JSON
+		// equivalente e segue a decodificação de sempre
+		dados, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(mensagem)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(dados, destino); err != nil {
+			return err
+		}
+	default:
+		return c.ShouldBindJSON(destino)
+	}
+	return binding.Validator.ValidateStruct(destino)
+}
+
+// Responde no formato pedido em Accept (JSON quando nenhum formato do sync é
+// aceito). mensagem é a mensagem protobuf equivalente a corpo.
+func responderSync(c *gin.Context, status int, corpo any, mensagem proto.Message) {
+	var dados []byte
+	var err error
+	formato := c.NegotiateFormat(formatosSync...)
+	switch formato {
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		dados, err = codificarMsgpack(corpo)
+	case binding.MIMEPROTOBUF:
+		dados, err = codificarProtobuf(corpo, mensagem)
+		formato += "; proto=" + string(mensagem.ProtoReflect().Descriptor().FullName())
+	default:
+		c.JSON(status, corpo)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao codificar resposta do sync em %s: %v", formato, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao codificar resposta"})
+		return
+	}
+	c.Data(status, formato, dados)
+}
+
+func codificarMsgpack(corpo any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(corpo); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Converte o corpo pelo JSON equivalente; uma lista vai no único campo
+// repetido da mensagem (ListaDispositivosSync)
+func codificarProtobuf(corpo any, mensagem proto.Message) ([]byte, error) {
+	dados, err := json.Marshal(corpo)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(dados, []byte("[")) {
+		campos := mensagem.ProtoReflect().Descriptor().Fields()
+		if campos.Len() != 1 || campos.Get(0).Cardinality() != protoreflect.Repeated {
+			return nil, fmt.Errorf("lista sem envelope em %s", mensagem.ProtoReflect().Descriptor().FullName())
+		}
+		dados = fmt.Appendf(nil, `{%q:%s}`, campos.Get(0).Name(), dados)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(dados, mensagem); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(mensagem)
+}
+
+// Data do dispositivo em MessagePack: texto, número (época Unix) ou timestamp
+func (i *instanteDispositivo) DecodeMsgpack(dec *msgpack.Decoder) error {
+	v, err := dec.DecodeInterfaceLoose()
+	if err != nil {
+		return err
+	}
+	var s string
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		i.Time = t
+		return nil
+	case string:
+		s = t
+	case int64, uint64:
+		s = fmt.Sprint(t)
+	default:
+		return fmt.Errorf("data deve ser texto, número ou timestamp")
+	}
+	instante, err := lerInstante(s)
+	if err != nil {
+		return err
+	}
+	i.Time = instante
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rlsautomacao/estoque/internal/syncpb"
+)
+
+// Resposta de sync típica de um coletor: lançamentos registrados e alguns
+// recusados por falta de estoque
+func resultadoSyncExemplo(lancamentos int) ResultadoSync {
+	r := ResultadoSync{Dispositivo: "coletor-07", OffsetMs: -42137, Itens: make([]ResultadoItemLote, lancamentos)}
+	data := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	for i := range r.Itens {
+		item := &r.Itens[i]
+		item.Indice = i
+		if i%10 == 9 {
+			falta := novaFaltaEstoque(1000+i, fmt.Sprintf("PRD-%05d", 1000+i), 5, 2)
+			item.Status, item.Erro, item.Codigo, item.Falta = "erro", "Estoque insuficiente", CodigoEstoqueInsuficiente, &falta
+			r.Rejeitados++
+			continue
+		}
+		item.Status = "ok"
+		item.Movimentacao = &Movimentacao{
+			ID:               50000 + i,
+			ProdutoID:        1000 + i,
+			Tipo:             "saida",
+			Quantidade:       1 + i%4,
+			Notas:            "Baixa no coletor",
+			DataMovimentacao: data.Add(time.Duration(i) * time.Minute),
+		}
+		r.Registrados++
+	}
+	return r
+}
+
+func gzipTamanho(b []byte) int {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Len()
+}
+
+// Tamanho do payload de cada formato (bytes e bytes com gzip) e custo da codificação:
+//
+//	go test -run '^$' -bench FormatosSync .
+func BenchmarkFormatosSync(b *testing.B) {
+	resultado := resultadoSyncExemplo(200)
+	formatos := []struct {
+		nome      string
+		codificar func() ([]byte, error)
+	}{
+		{"json", func() ([]byte, error) { return json.Marshal(resultado) }},
+		{"msgpack", func() ([]byte, error) { return codificarMsgpack(resultado) }},
+		{"protobuf", func() ([]byte, error) { return codificarProtobuf(resultado, &syncpb.ResultadoSync{}) }},
+	}
+	for _, f := range formatos {
+		b.Run(f.nome, func(b *testing.B) {
+			dados, err := f.codificar()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for range b.N {
+				if _, err := f.codificar(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// Depois do laço: ResetTimer descarta as métricas já informadas
+			b.ReportMetric(float64(len(dados)), "bytes")
+			b.ReportMetric(float64(gzipTamanho(dados)), "bytes-gzip")
+		})
+	}
+}
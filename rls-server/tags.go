@@ -0,0 +1,451 @@
+// tags.go - Tags de produtos
+//
+// Agrupamentos livres, sem estrutura, como "obra-cliente-x" ou
+// "inventario-pendente", que antes acabavam escritos nas notas. O nome da tag
+// é normalizado (minúsculas, espaços viram hífen) e a cor é opcional, para o
+// app. Um produto tem várias tags e uma tag vários produtos.
+//
+// Além do cadastro em /api/tags, as tags do produto são trocadas em
+// PUT /api/produtos/:id/tags, acrescentadas em POST e retiradas uma a uma em
+// DELETE /api/produtos/:id/tags/:tag; tags ainda não cadastradas são criadas
+// na atribuição. A listagem de produtos filtra por ?tags=a,b (produtos com
+// todas as tags) e cada produto traz as suas tags.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Nome da tag: letras, dígitos e . _ -, começando por letra ou dígito
+var padraoNomeTag = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}._-]*$`)
+
+// Tamanho máximo do nome da tag, o da coluna
+const tamanhoMaximoTag = 50
+
+type Tag struct {
+	ID          int       `json:"id,omitempty"`
+	Nome        string    `json:"nome" binding:"required,max=50"`
+	Cor         string    `json:"cor,omitempty" binding:"omitempty,hexcolor,max=7"`
+	Produtos    int       `json:"produtos"` // produtos com a tag
+	DataCriacao time.Time `json:"data_criacao,omitempty"`
+}
+
+// Tags a atribuir a um produto, pelo nome
+type TagsProduto struct {
+	Tags []string `json:"tags"`
+}
+
+// Tags com a contagem de produtos, na ordem de scanTag
+const consultaTags = `
+	SELECT t.id, t.nome, COALESCE(t.cor, ''), t.data_criacao,
+	       (SELECT COUNT(*) FROM produtos_tags pt WHERE pt.tag_id = t.id)
+	FROM tags t
+`
+
+// Complemento de consultaTags para as tags de um produto
+const filtroTagsProduto = `
+	JOIN produtos_tags p ON p.tag_id = t.id
+	WHERE p.produto_id = $1
+	ORDER BY t.nome
+`
+
+func scanTag(row pgx.Row) (Tag, error) {
+	var t Tag
+	err := row.Scan(&t.ID, &t.Nome, &t.Cor, &t.DataCriacao, &t.Produtos)
+	return t, err
+}
+
+func listarTags(ctx context.Context, q querier, sql string, args ...any) ([]Tag, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// Normaliza o nome da tag; devolve a mensagem de erro ou "" se válido
+func normalizarTag(nome *string) string {
+	*nome = strings.ToLower(strings.Join(strings.Fields(*nome), "-"))
+	if *nome == "" {
+		return "Informe o nome da tag"
+	}
+	if len([]rune(*nome)) > tamanhoMaximoTag {
+		return "A tag " + *nome + " passa de " + strconv.Itoa(tamanhoMaximoTag) + " caracteres"
+	}
+	if !padraoNomeTag.MatchString(*nome) {
+		return "A tag " + *nome + " deve conter só letras, dígitos e . _ -"
+	}
+	return ""
+}
+
+// Tags do filtro ?tags=a,b, normalizadas e sem repetição
+func tagsConsulta(texto string) []string {
+	tags := []string{}
+	for _, nome := range strings.Split(texto, ",") {
+		if normalizarTag(&nome) == "" && !slices.Contains(tags, nome) {
+			tags = append(tags, nome)
+		}
+	}
+	return tags
+}
+
+// IDs das tags, criando as que ainda não existem
+func garantirTags(ctx context.Context, q querier, nomes []string) ([]int, error) {
+	if _, err := q.Exec(ctx, `
+		INSERT INTO tags(nome) SELECT unnest($1::text[])
+		ON CONFLICT (nome) DO NOTHING
+	`, nomes); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, "SELECT id FROM tags WHERE nome = ANY($1)", nomes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Handlers de Tags
+
+func getTags(c *gin.Context) {
+	log.Println("[DB] Buscando tags")
+
+	tags, err := listarTags(c.Request.Context(), db, consultaTags+"ORDER BY t.nome")
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tags: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tags"})
+		return
+	}
+
+	log.Printf("[DB] Retornando %d tags", len(tags))
+	c.JSON(http.StatusOK, tags)
+}
+
+func getTag(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	t, err := scanTag(db.QueryRow(c.Request.Context(), consultaTags+"WHERE t.id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Tag não encontrada com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Tag não encontrada"})
+		} else {
+			log.Printf("[ERROR] Erro ao buscar tag: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tag"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+func criarTag(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var t Tag
+	if err := c.ShouldBindJSON(&t); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := normalizarTag(&t.Nome); msg != "" {
+		log.Printf("[ERROR] Tag inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+	t.Cor = strings.ToUpper(t.Cor)
+
+	err := db.QueryRow(ctx, `
+		INSERT INTO tags(nome, cor) VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (nome) DO NOTHING
+		RETURNING id, data_criacao
+	`, t.Nome, t.Cor).Scan(&t.ID, &t.DataCriacao)
+	if err == pgx.ErrNoRows {
+		log.Printf("[DB] Tag já existe: %s", t.Nome)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe uma tag com este nome"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar tag: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar tag"})
+		return
+	}
+
+	log.Printf("[DB] Tag criada: %s (ID: %d)", t.Nome, t.ID)
+	c.JSON(http.StatusCreated, t)
+}
+
+func atualizarTag(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var t Tag
+	if err := c.ShouldBindJSON(&t); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := normalizarTag(&t.Nome); msg != "" {
+		log.Printf("[ERROR] Tag inválida: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+	t.Cor = strings.ToUpper(t.Cor)
+
+	// Verificar se o nome já está sendo usado por outra tag
+	var existente int
+	err = db.QueryRow(ctx, "SELECT id FROM tags WHERE nome = $1 AND id != $2", t.Nome, id).Scan(&existente)
+	if err == nil {
+		log.Printf("[DB] Nome de tag '%s' já usado (ID: %d)", t.Nome, existente)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Já existe outra tag com este nome"})
+		return
+	} else if err != pgx.ErrNoRows {
+		log.Printf("[ERROR] Erro ao verificar tag existente: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar tag existente"})
+		return
+	}
+
+	tag, err := db.Exec(ctx, "UPDATE tags SET nome = $1, cor = NULLIF($2, '') WHERE id = $3", t.Nome, t.Cor, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar tag: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar tag"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Tag não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Tag não encontrada"})
+		return
+	}
+
+	t, err = scanTag(db.QueryRow(ctx, consultaTags+"WHERE t.id = $1", id))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tag atualizada: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tag"})
+		return
+	}
+
+	log.Printf("[DB] Tag atualizada: %s (ID: %d)", t.Nome, id)
+	c.JSON(http.StatusOK, t)
+}
+
+func deletarTag(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	// As atribuições aos produtos saem junto (ON DELETE CASCADE)
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM tags WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir tag: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir tag"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Tag não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Tag não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Tag excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Tag excluída com sucesso"})
+}
+
+// Tags do produto
+func getTagsProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tags, err := listarTags(c.Request.Context(), db, consultaTags+filtroTagsProduto, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tags do produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tags do produto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// Troca (PUT) ou acrescenta (POST) tags do produto
+func atribuirTagsProduto(c *gin.Context) {
+	ctx := c.Request.Context()
+	substituir := c.Request.Method == http.MethodPut
+
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req TagsProduto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	nomes := []string{}
+	for _, nome := range req.Tags {
+		if msg := normalizarTag(&nome); msg != "" {
+			log.Printf("[ERROR] Tag inválida para produto ID %d: %s", id, msg)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+			return
+		}
+		if !slices.Contains(nomes, nome) {
+			nomes = append(nomes, nome)
+		}
+	}
+	if len(nomes) == 0 && !substituir {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nenhuma tag informada"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var existe bool
+	if err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM produtos WHERE id = $1)", id).Scan(&existe); err != nil {
+		log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+		return
+	}
+	if !existe {
+		log.Printf("[DB] Produto não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+		return
+	}
+
+	ids, err := garantirTags(ctx, tx, nomes)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao cadastrar tags: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atribuir tags"})
+		return
+	}
+	if substituir {
+		if _, err = tx.Exec(ctx, "DELETE FROM produtos_tags WHERE produto_id = $1 AND tag_id <> ALL($2)", id, ids); err != nil {
+			log.Printf("[ERROR] Erro ao retirar tags do produto: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atribuir tags"})
+			return
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO produtos_tags(produto_id, tag_id) SELECT $1, unnest($2::int[])
+		ON CONFLICT DO NOTHING
+	`, id, ids)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atribuir tags ao produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atribuir tags"})
+		return
+	}
+
+	tags, err := listarTags(ctx, tx, consultaTags+filtroTagsProduto, id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tags do produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atribuir tags"})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Produto ID %d com %d tags", id, len(tags))
+	c.JSON(http.StatusOK, tags)
+}
+
+// Retira uma tag do produto
+func removerTagProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+	nome := c.Param("tag")
+	normalizarTag(&nome)
+
+	tag, err := db.Exec(c.Request.Context(), `
+		DELETE FROM produtos_tags
+		WHERE produto_id = $1 AND tag_id = (SELECT id FROM tags WHERE nome = $2)
+	`, id, nome)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao retirar tag do produto: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao retirar tag do produto"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Produto ID %d sem a tag %s", id, nome)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "O produto não tem esta tag"})
+		return
+	}
+
+	log.Printf("[DB] Tag %s retirada do produto ID %d", nome, id)
+	c.JSON(http.StatusOK, gin.H{"message": "Tag retirada do produto"})
+}
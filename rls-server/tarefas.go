@@ -0,0 +1,416 @@
+// tarefas.go - Tarefas de estoque com responsável, prazo e status
+//
+// Tarefas (contar uma prateleira, reetiquetar uma categoria) são criadas
+// manualmente em POST /api/tarefas ou por regras sobre os eventos do
+// barramento: com tarefas_regra_estoque_baixo ativa, um alerta de estoque
+// baixo abre uma tarefa de conferência do produto para
+// tarefas_responsavel_padrao, se ainda não houver uma em aberto. O
+// responsável recebe uma notificação interna ao ser atribuído. Sem usuários
+// autenticados, responsável é o nome usado nas menções e notificações.
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Status da tarefa
+const (
+	TarefaPendente    = "pendente"
+	TarefaEmAndamento = "em_andamento"
+	TarefaConcluida   = "concluida"
+	TarefaCancelada   = "cancelada"
+)
+
+// Regras que geram tarefas automaticamente
+const (
+	RegraTarefaEstoqueBaixo = "estoque_baixo"
+)
+
+var statusTarefa = map[string]bool{
+	TarefaPendente:    true,
+	TarefaEmAndamento: true,
+	TarefaConcluida:   true,
+	TarefaCancelada:   true,
+}
+
+type Tarefa struct {
+	ID            int        `json:"id"`
+	Titulo        string     `json:"titulo"`
+	Descricao     string     `json:"descricao,omitempty"`
+	Responsavel   string     `json:"responsavel,omitempty"`
+	Prazo         *time.Time `json:"prazo,omitempty"`
+	Status        string     `json:"status"`
+	Regra         string     `json:"regra,omitempty"` // vazio quando criada manualmente
+	Entidade      string     `json:"entidade,omitempty"`
+	EntidadeID    int        `json:"entidade_id,omitempty"`
+	Atrasada      bool       `json:"atrasada"`
+	DataCriacao   time.Time  `json:"data_criacao"`
+	DataConclusao *time.Time `json:"data_conclusao,omitempty"`
+}
+
+// Alteração parcial de tarefa; campos ausentes ficam como estão
+type AtualizacaoTarefa struct {
+	Titulo      *string    `json:"titulo"`
+	Descricao   *string    `json:"descricao"`
+	Responsavel *string    `json:"responsavel"`
+	Prazo       *time.Time `json:"prazo"`
+	Status      *string    `json:"status"`
+}
+
+// Resumo de pendências exibido no dashboard
+type PendenciasTarefas struct {
+	Abertas   int `json:"abertas"`
+	Atrasadas int `json:"atrasadas"`
+}
+
+const tarefaColunas = `id, titulo, descricao, responsavel, prazo, status, regra, entidade, entidade_id,
+	status IN ('pendente', 'em_andamento') AND prazo IS NOT NULL AND prazo < CURRENT_TIMESTAMP,
+	data_criacao, data_conclusao`
+
+func scanTarefa(row pgx.Row) (Tarefa, error) {
+	var t Tarefa
+	var descricao, responsavel, regra, entidade *string
+	var entidadeID *int
+	err := row.Scan(&t.ID, &t.Titulo, &descricao, &responsavel, &t.Prazo, &t.Status, &regra,
+		&entidade, &entidadeID, &t.Atrasada, &t.DataCriacao, &t.DataConclusao)
+	if err != nil {
+		return t, err
+	}
+
+	// Tratar campos nulos
+	if descricao != nil {
+		t.Descricao = *descricao
+	}
+	if responsavel != nil {
+		t.Responsavel = *responsavel
+	}
+	if regra != nil {
+		t.Regra = *regra
+	}
+	if entidade != nil {
+		t.Entidade = *entidade
+	}
+	if entidadeID != nil {
+		t.EntidadeID = *entidadeID
+	}
+	return t, nil
+}
+
+// Avisa o responsável de uma tarefa atribuída a ele
+func notificarResponsavelTarefa(ctx context.Context, q querier, t Tarefa) error {
+	if t.Responsavel == "" {
+		return nil
+	}
+	texto := t.Titulo
+	if t.Prazo != nil {
+		texto += " (prazo " + t.Prazo.Format("02/01/2006 15:04") + ")"
+	}
+	_, err := q.Exec(ctx, `
+		INSERT INTO notificacoes(destinatario, tipo, titulo, texto, entidade, entidade_id)
+		VALUES ($1, $2, 'Nova tarefa atribuída', $3, 'tarefa', $4)
+	`, strings.ToLower(t.Responsavel), NotificacaoTarefa, texto, t.ID)
+	return err
+}
+
+// Abre a tarefa de conferência de um produto com estoque baixo, se a regra
+// estiver ativa e não houver outra em aberto para o mesmo produto
+func tarefaEstoqueBaixo(ctx context.Context, a AlertaEstoqueBaixo) error {
+	if lerConfiguracao(ctx, "tarefas_regra_estoque_baixo", "true") != "true" {
+		return nil
+	}
+	prazoDias, err := strconv.Atoi(lerConfiguracao(ctx, "tarefas_prazo_regra_dias", "1"))
+	if err != nil || prazoDias <= 0 {
+		prazoDias = 1
+	}
+	prazo := time.Now().AddDate(0, 0, prazoDias)
+
+	t, err := scanTarefa(db.QueryRow(ctx, `
+		INSERT INTO tarefas(titulo, descricao, responsavel, prazo, regra, entidade, entidade_id)
+		SELECT $1, $2, NULLIF($3, ''), $4, $5, 'produto', $6
+		WHERE NOT EXISTS (
+			SELECT 1 FROM tarefas
+			WHERE regra = $5 AND entidade = 'produto' AND entidade_id = $6
+				AND status IN ('pendente', 'em_andamento')
+		)
+		RETURNING `+tarefaColunas,
+		"Conferir estoque de "+a.Codigo+" "+a.Nome,
+		"Contar o saldo físico e verificar a reposição: sistema indica "+strconv.Itoa(a.Quantidade)+
+			" unidades (mínimo "+strconv.Itoa(a.QuantidadeMinima)+").",
+		strings.TrimSpace(lerConfiguracao(ctx, "tarefas_responsavel_padrao", "")), prazo,
+		RegraTarefaEstoqueBaixo, a.ProdutoID))
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DB] Tarefa %d criada pela regra %s para o produto ID: %d", t.ID, RegraTarefaEstoqueBaixo, a.ProdutoID)
+	return notificarResponsavelTarefa(ctx, db, t)
+}
+
+// Assina o barramento e aplica as regras de tarefas; roda até o contexto ser cancelado
+func iniciarRegrasTarefas(ctx context.Context) {
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			alerta, ok := e.Dados.(AlertaEstoqueBaixo)
+			if !ok {
+				continue
+			}
+			if err := tarefaEstoqueBaixo(ctx, alerta); err != nil {
+				log.Printf("[ERROR] Erro ao aplicar regra de tarefa %s: %v", RegraTarefaEstoqueBaixo, err)
+			}
+		}
+	}
+}
+
+// Widget de pendências do dashboard
+func widgetTarefasPendentes(ctx context.Context) (any, error) {
+	var p PendenciasTarefas
+	err := db.QueryRow(ctx, `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE prazo IS NOT NULL AND prazo < CURRENT_TIMESTAMP)
+		FROM tarefas
+		WHERE status IN ('pendente', 'em_andamento')
+	`).Scan(&p.Abertas, &p.Atrasadas)
+	return p, err
+}
+
+// Handlers de Tarefas
+
+func getTarefas(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !statusTarefa[status] {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Status inválido, use pendente, em_andamento, concluida ou cancelada"})
+		return
+	}
+	responsavel := strings.TrimSpace(c.Query("responsavel"))
+	atrasadas := c.Query("atrasadas") == "true"
+
+	log.Printf("[DB] Buscando tarefas (status=%s, responsavel=%s, atrasadas=%t)", status, responsavel, atrasadas)
+
+	// Sem filtro de status, as abertas vêm primeiro, ordenadas pelo prazo
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT `+tarefaColunas+`
+		FROM tarefas
+		WHERE ($1 = '' OR status = $1)
+			AND ($2 = '' OR lower(responsavel) = lower($2))
+			AND (NOT $3 OR (status IN ('pendente', 'em_andamento') AND prazo < CURRENT_TIMESTAMP))
+		ORDER BY status NOT IN ('pendente', 'em_andamento'), prazo NULLS LAST, id DESC
+	`, status, responsavel, atrasadas)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tarefas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tarefas"})
+		return
+	}
+	defer rows.Close()
+
+	tarefas := []Tarefa{}
+	for rows.Next() {
+		t, err := scanTarefa(rows)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar tarefa: %v", err)
+			continue
+		}
+		tarefas = append(tarefas, t)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar tarefas: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar tarefas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tarefas)
+}
+
+func createTarefa(c *gin.Context) {
+	log.Println("[API] Iniciando criação de tarefa")
+
+	var req struct {
+		Titulo      string     `json:"titulo"`
+		Descricao   string     `json:"descricao"`
+		Responsavel string     `json:"responsavel"`
+		Prazo       *time.Time `json:"prazo"`
+		Entidade    string     `json:"entidade"`
+		EntidadeID  int        `json:"entidade_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	req.Titulo = strings.TrimSpace(req.Titulo)
+	req.Responsavel = strings.TrimSpace(req.Responsavel)
+	if req.Titulo == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Título é obrigatório"})
+		return
+	}
+	if (req.Entidade == "") != (req.EntidadeID == 0) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe entidade e entidade_id juntos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	t, err := scanTarefa(tx.QueryRow(ctx, `
+		INSERT INTO tarefas(titulo, descricao, responsavel, prazo, entidade, entidade_id)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, 0))
+		RETURNING `+tarefaColunas,
+		req.Titulo, strings.TrimSpace(req.Descricao), req.Responsavel, req.Prazo, req.Entidade, req.EntidadeID))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar tarefa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar tarefa"})
+		return
+	}
+	if err := notificarResponsavelTarefa(ctx, tx, t); err != nil {
+		log.Printf("[ERROR] Erro ao notificar responsável da tarefa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao notificar responsável"})
+		return
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Tarefa criada com sucesso. ID: %d", t.ID)
+	c.JSON(http.StatusCreated, t)
+}
+
+func updateTarefa(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var req AtualizacaoTarefa
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if req.Titulo != nil && strings.TrimSpace(*req.Titulo) == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Título não pode ficar vazio"})
+		return
+	}
+	if req.Status != nil && !statusTarefa[*req.Status] {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Status inválido, use pendente, em_andamento, concluida ou cancelada"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao iniciar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao iniciar transação"})
+		return
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	var responsavelAnterior *string
+	err = tx.QueryRow(ctx, "SELECT responsavel FROM tarefas WHERE id = $1 FOR UPDATE", id).Scan(&responsavelAnterior)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Tarefa não encontrada"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar tarefa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar tarefa"})
+		return
+	}
+
+	// data_conclusao acompanha a passagem para concluída (e é limpa ao reabrir)
+	t, err := scanTarefa(tx.QueryRow(ctx, `
+		UPDATE tarefas SET
+			titulo = COALESCE(btrim($2), titulo),
+			descricao = CASE WHEN $3::text IS NULL THEN descricao ELSE NULLIF(btrim($3), '') END,
+			responsavel = CASE WHEN $4::text IS NULL THEN responsavel ELSE NULLIF(btrim($4), '') END,
+			prazo = COALESCE($5, prazo),
+			status = COALESCE($6, status),
+			data_conclusao = CASE
+				WHEN $6 IS NULL OR $6 = status THEN data_conclusao
+				WHEN $6 = 'concluida' THEN CURRENT_TIMESTAMP
+				ELSE NULL
+			END
+		WHERE id = $1
+		RETURNING `+tarefaColunas,
+		id, req.Titulo, req.Descricao, req.Responsavel, req.Prazo, req.Status))
+	if err != nil {
+		log.Printf("[ERROR] Erro ao atualizar tarefa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar tarefa"})
+		return
+	}
+
+	// Reatribuição avisa o novo responsável
+	if t.Responsavel != "" && (responsavelAnterior == nil || !strings.EqualFold(*responsavelAnterior, t.Responsavel)) {
+		if err := notificarResponsavelTarefa(ctx, tx, t); err != nil {
+			log.Printf("[ERROR] Erro ao notificar responsável da tarefa: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao notificar responsável"})
+			return
+		}
+	}
+
+	// Commit da transação
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("[ERROR] Erro ao finalizar transação: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao finalizar transação"})
+		return
+	}
+
+	log.Printf("[DB] Tarefa atualizada com sucesso. ID: %d, status: %s", t.ID, t.Status)
+	c.JSON(http.StatusOK, t)
+}
+
+func deleteTarefa(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM tarefas WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir tarefa: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir tarefa"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Tarefa não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Tarefa excluída com sucesso. ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Tarefa excluída com sucesso"})
+}
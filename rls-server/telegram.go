@@ -0,0 +1,90 @@
+// telegram.go - Canal de notificação por bot do Telegram
+//
+// Configurado em configuracoes: telegram_bot_token (token do BotFather) e
+// telegram_chat_id (usuário ou grupo que recebe as mensagens).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+var telegramClient = &http.Client{Timeout: 10 * time.Second}
+
+type canalTelegramBot struct {
+	metricas MetricasIntegracao
+}
+
+var canalTelegram = &canalTelegramBot{}
+
+func (t *canalTelegramBot) Nome() string                  { return "telegram" }
+func (t *canalTelegramBot) Tipo() string                  { return "mensagens" }
+func (t *canalTelegramBot) FilaPendente() int             { return 0 }
+func (t *canalTelegramBot) Metricas() *MetricasIntegracao { return &t.metricas }
+
+func (t *canalTelegramBot) Ativa() bool {
+	ctx := context.Background()
+	return lerConfiguracao(ctx, "telegram_bot_token", "") != "" && lerConfiguracao(ctx, "telegram_chat_id", "") != ""
+}
+
+// Chama um método da Bot API e verifica o campo ok da resposta
+func (t *canalTelegramBot) chamar(ctx context.Context, metodo string, corpo any) error {
+	token := lerConfiguracao(ctx, "telegram_bot_token", "")
+	if token == "" {
+		return fmt.Errorf("bot do Telegram não configurado (telegram_bot_token)")
+	}
+
+	dados, err := json.Marshal(corpo)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramAPIURL+token+"/"+metodo, bytes.NewReader(dados))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := telegramClient.Do(req)
+	if err != nil {
+		// O erro traz a URL, que contém o token
+		return fmt.Errorf("falha ao conectar na API do Telegram")
+	}
+	defer resp.Body.Close()
+
+	var resposta struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&resposta); err != nil {
+		return fmt.Errorf("resposta do Telegram inválida (HTTP %d)", resp.StatusCode)
+	}
+	if !resposta.OK {
+		return fmt.Errorf("Telegram: %s", resposta.Description)
+	}
+	return nil
+}
+
+// Confere o token com getMe, sem enviar mensagem
+func (t *canalTelegramBot) Testar(ctx context.Context) error {
+	return t.chamar(ctx, "getMe", struct{}{})
+}
+
+func (t *canalTelegramBot) Enviar(ctx context.Context, m MensagemNotificacao) error {
+	corpo := map[string]any{
+		"chat_id": lerConfiguracao(ctx, "telegram_chat_id", ""),
+		"text":    m.Titulo + "\n" + m.Texto,
+	}
+	if m.Link != "" {
+		corpo["reply_markup"] = map[string]any{
+			"inline_keyboard": [][]map[string]string{{{"text": m.LinkTexto, "url": m.Link}}},
+		}
+	}
+	return t.chamar(ctx, "sendMessage", corpo)
+}
@@ -0,0 +1,87 @@
+// timeout.go - Prazo das requisições
+//
+// Os handlers usam o contexto da requisição nas chamadas ao banco, então uma
+// consulta lenta ou um cliente que desconectou libera a conexão do pool. O
+// middleware TimeoutRequisicao põe um prazo nesse contexto:
+// REQUISICAO_TIMEOUT_SEGUNDOS para todas as rotas, com exceções por rota
+// (relatórios e rotinas administrativas mais longas; streams sem prazo) que
+// podem ser ajustadas em REQUISICAO_TIMEOUT_ROTAS=/api/rota=segundos,...
+// (valem também para a mesma rota em /api/v1).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração dos prazos - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	requisicaoTimeoutSegundos = getEnvAsInt("REQUISICAO_TIMEOUT_SEGUNDOS", 30)
+	requisicaoTimeoutRotas    = getEnv("REQUISICAO_TIMEOUT_ROTAS", "")
+)
+
+// Prazos próprios por rota, em segundos (0 = sem prazo)
+var timeoutsRotas = carregarTimeoutsRotas(map[string]int{
+	"/ws":                               0,
+	"/api/stream":                       0,
+	"/api/relatorios/estoque.pdf":       120,
+	"/api/relatorios/movimentacoes.pdf": 120,
+	"/api/admin/duplicatas/detectar":    300,
+	"/api/admin/snapshots-estoque":      120,
+	"/api/admin/anexos/verificar":       600,
+	"/api/alertas/resumo":               120,
+	"/api/treinamento/reset":            300,
+	"/api/treinamento/modelo":           300,
+})
+
+// Aplica REQUISICAO_TIMEOUT_ROTAS sobre os prazos padrão
+func carregarTimeoutsRotas(padrao map[string]int) map[string]int {
+	if requisicaoTimeoutRotas == "" {
+		return padrao
+	}
+	for _, item := range strings.Split(requisicaoTimeoutRotas, ",") {
+		rota, valor, ok := strings.Cut(strings.TrimSpace(item), "=")
+		segundos, err := strconv.Atoi(strings.TrimSpace(valor))
+		if !ok || err != nil || segundos < 0 {
+			erroConfiguracao("REQUISICAO_TIMEOUT_ROTAS", item, "rota=segundos")
+			continue
+		}
+		padrao[strings.TrimSpace(rota)] = segundos
+	}
+	return padrao
+}
+
+// Middleware que limita a duração da requisição pelo contexto
+func TimeoutRequisicao() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		segundos, ok := timeoutsRotas[rotaSemVersao(c.FullPath())]
+		if !ok {
+			segundos = requisicaoTimeoutSegundos
+		}
+		if segundos <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancelar := context.WithTimeout(c.Request.Context(), time.Duration(segundos)*time.Second)
+		defer cancelar()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("[WARN] Requisição %s %s excedeu o prazo de %ds", c.Request.Method, c.Request.URL.Path, segundos)
+			if !c.Writer.Written() {
+				c.AbortWithStatusJSON(http.StatusGatewayTimeout, ErrorResponse{Error: "Tempo limite da requisição excedido"})
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// tls.go - HTTPS direto no servidor
+//
+// Com TLS_CERT_FILE e TLS_KEY_FILE o servidor atende em HTTPS na PORT com o
+// certificado informado (recarregado do disco quando o arquivo muda, para a
+// renovação não exigir reinício). Com TLS_AUTOCERT_DOMINIOS (lista separada
+// por vírgulas) o certificado é obtido e renovado automaticamente no Let's
+// Encrypt e guardado em TLS_AUTOCERT_CACHE; o domínio precisa apontar para o
+// servidor e a porta 443 (ou a TLS_REDIRECT_PORTA, para o desafio HTTP)
+// precisa estar acessível pela internet.
+//
+// TLS_REDIRECT_PORTA (ex.: 80) abre um servidor HTTP que redireciona tudo para
+// HTTPS e, com o autocert, responde aos desafios do Let's Encrypt.
+
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Configuração do TLS - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	tlsCertFile         = getEnv("TLS_CERT_FILE", "")
+	tlsKeyFile          = getEnv("TLS_KEY_FILE", "")
+	tlsAutocertDominios = getEnv("TLS_AUTOCERT_DOMINIOS", "")
+	tlsAutocertCache    = getEnv("TLS_AUTOCERT_CACHE", "certificados")
+	tlsAutocertEmail    = getEnv("TLS_AUTOCERT_EMAIL", "")
+	tlsRedirectPorta    = getEnv("TLS_REDIRECT_PORTA", "")
+)
+
+var tlsConfigurado = validarConfigTLS()
+
+// Aponta combinações inválidas no startup; devolve se o TLS está ativo
+func validarConfigTLS() bool {
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		erroConfiguracao("TLS_CERT_FILE/TLS_KEY_FILE", tlsCertFile+tlsKeyFile, "um par completo de certificado e chave")
+		return false
+	}
+	if tlsCertFile != "" && tlsAutocertDominios != "" {
+		erroConfiguracao("TLS_AUTOCERT_DOMINIOS", tlsAutocertDominios, "compatível com TLS_CERT_FILE (use um ou outro)")
+		return false
+	}
+	return tlsCertFile != "" || tlsAutocertDominios != ""
+}
+
+// Esquema das URLs do servidor
+func esquemaServidor() string {
+	if tlsConfigurado {
+		return "https"
+	}
+	return "http"
+}
+
+// Certificado lido do disco, recarregado quando o arquivo muda
+type certificadoArquivo struct {
+	mu         sync.Mutex
+	cert       *tls.Certificate
+	modificado time.Time
+	verificado time.Time
+}
+
+func (c *certificadoArquivo) obter(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Verifica o arquivo no máximo uma vez por minuto
+	if c.cert != nil && time.Since(c.verificado) < time.Minute {
+		return c.cert, nil
+	}
+	c.verificado = time.Now()
+
+	info, err := os.Stat(tlsCertFile)
+	if err != nil {
+		if c.cert != nil {
+			log.Printf("[WARN] Erro ao verificar o certificado, mantendo o atual: %v", err)
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil && !info.ModTime().After(c.modificado) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		if c.cert != nil {
+			log.Printf("[WARN] Erro ao recarregar o certificado, mantendo o atual: %v", err)
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil {
+		log.Printf("[API] Certificado TLS recarregado de %s", tlsCertFile)
+	}
+	c.cert, c.modificado = &cert, info.ModTime()
+	return c.cert, nil
+}
+
+// Monta a configuração TLS e o handler do servidor HTTP de redirecionamento
+func configurarTLS() (*tls.Config, http.Handler, error) {
+	redirecionar := http.HandlerFunc(redirecionarHTTPS)
+
+	if tlsAutocertDominios != "" {
+		var dominios []string
+		for _, d := range strings.Split(tlsAutocertDominios, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				dominios = append(dominios, d)
+			}
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(dominios...),
+			Cache:      autocert.DirCache(tlsAutocertCache),
+			Email:      tlsAutocertEmail,
+		}
+		log.Printf("[API] Certificados automáticos (Let's Encrypt) para %s", strings.Join(dominios, ", "))
+		cfg := m.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg, m.HTTPHandler(redirecionar), nil
+	}
+
+	cert := &certificadoArquivo{}
+	// Carrega já no startup para um certificado inválido impedir a subida
+	if _, err := cert.obter(nil); err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: cert.obter}, redirecionar, nil
+}
+
+// Redireciona para o mesmo caminho em HTTPS, na PORT do servidor
+func redirecionarHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	if serverPort != "443" {
+		host = net.JoinHostPort(host, serverPort)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,165 @@
+// tracing.go - Rastreamento distribuído (OpenTelemetry) das requisições
+//
+// Com OTEL_EXPORTER_OTLP_ENDPOINT definido (ex.: http://collector:4318), o SDK
+// do OpenTelemetry envia os spans em lotes pelo protocolo OTLP/HTTP
+// (/v1/traces), aceito pelo OpenTelemetry Collector, Jaeger e Tempo. Cada
+// requisição gera um span de servidor "MÉTODO /rota" (otelgin) e cada consulta
+// ao banco feita com o contexto dela um span filho com o nome da consulta
+// (operação e tabela, ex.: "SELECT produtos"), pelo otelpgx.
+//
+// O cabeçalho traceparent (W3C) do cliente é respeitado: o span da requisição
+// continua o trace de quem chamou e as consultas às filiais repassam o trace.
+// OTEL_TRACES_SAMPLER_ARG define a fração de traces novos amostrados (0 a 1).
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exaring/otelpgx"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Configuração do rastreamento - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	otelEndpoint   = strings.TrimRight(getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""), "/")
+	otelServico    = getEnv("OTEL_SERVICE_NAME", "rls-estoque")
+	otelAmostragem = getEnvAsFloat("OTEL_TRACES_SAMPLER_ARG", 1)
+)
+
+const (
+	headerTraceparent = "traceparent"
+
+	// Spans por envio, spans aguardando envio e intervalo entre envios
+	otelLoteMaximo  = 512
+	otelFilaMaxima  = 4096
+	otelIntervaloMs = 5000
+)
+
+func rastreamentoAtivo() bool {
+	return otelEndpoint != ""
+}
+
+// Propaga o trace no formato W3C (traceparent) nas requisições de entrada e
+// nas chamadas às filiais
+var propagadorTraces = propagation.TraceContext{}
+
+// Repassa o trace do contexto nos cabeçalhos de uma requisição de saída
+func propagarTrace(ctx context.Context, h http.Header) {
+	propagadorTraces.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// ID do trace do contexto ("" sem trace), para correlacionar com os logs
+func idTrace(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// Nome curto de uma consulta: operação e primeira tabela ("SELECT produtos")
+var padraoTabelaSQL = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN)\s+([a-z_][a-z0-9_.]*)`)
+
+func nomeConsulta(sql string) string {
+	campos := strings.Fields(sql)
+	if len(campos) == 0 {
+		return "SQL"
+	}
+	operacao := strings.ToUpper(campos[0])
+	if operacao == "WITH" {
+		operacao = "SELECT"
+		for _, c := range campos {
+			if c := strings.ToUpper(c); c == "INSERT" || c == "UPDATE" || c == "DELETE" {
+				operacao = c
+			}
+		}
+	}
+	if m := padraoTabelaSQL.FindStringSubmatch(sql); m != nil {
+		return operacao + " " + m[1]
+	}
+	return operacao
+}
+
+// Tracer do pgx que abre um span por consulta no trace da requisição; sem
+// span ativo no contexto (workers, rastreamento desligado) não gera nada
+func tracerConsultasOTel() *otelpgx.Tracer {
+	return otelpgx.NewTracer(
+		otelpgx.WithSpanNameFunc(nomeConsulta),
+		otelpgx.WithDisableQuerySpanNamePrefix(),
+	)
+}
+
+// Middlewares que abrem o span de cada requisição; sem rastreamento não fazem nada
+func Rastreamento() []gin.HandlerFunc {
+	if !rastreamentoAtivo() {
+		return nil
+	}
+	return []gin.HandlerFunc{
+		otelgin.Middleware(otelServico, otelgin.WithPropagators(propagadorTraces)),
+		func(c *gin.Context) {
+			c.Next()
+			s := trace.SpanFromContext(c.Request.Context())
+			if rota := c.FullPath(); rota != "" {
+				s.SetName(c.Request.Method + " " + rota)
+			}
+			s.SetAttributes(
+				attribute.String("client.address", c.ClientIP()),
+				attribute.String("request_id", idRequisicao(c.Request.Context())),
+			)
+		},
+	}
+}
+
+// Configura o SDK e mantém o envio dos spans até o desligamento, quando envia
+// o que restou
+func iniciarExportacaoTraces(ctx context.Context) {
+	if !rastreamentoAtivo() {
+		return
+	}
+
+	exportador, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(otelEndpoint+"/v1/traces"))
+	if err != nil {
+		log.Printf("[ERROR] Rastreamento desligado, exportador OTLP inválido: %v", err)
+		return
+	}
+	provedor := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(otelServico))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(otelAmostragem))),
+		sdktrace.WithBatcher(exportador,
+			sdktrace.WithMaxExportBatchSize(otelLoteMaximo),
+			sdktrace.WithMaxQueueSize(otelFilaMaxima),
+			sdktrace.WithBatchTimeout(time.Duration(otelIntervaloMs)*time.Millisecond),
+		),
+	)
+	otel.SetTracerProvider(provedor)
+	otel.SetTextMapPropagator(propagadorTraces)
+
+	// Só o primeiro erro vai ao log para o coletor fora do ar não inundá-lo
+	var falhas sync.Once
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		falhas.Do(func() { log.Printf("[WARN] Erro ao enviar traces: %v", err) })
+	}))
+	log.Printf("[API] Rastreamento ativo: enviando traces para %s", otelEndpoint)
+
+	<-ctx.Done()
+	envio, cancelar := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelar()
+	if err := provedor.Shutdown(envio); err != nil {
+		log.Printf("[WARN] Erro ao enviar os últimos traces: %v", err)
+	}
+}
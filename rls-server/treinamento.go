@@ -0,0 +1,351 @@
+// treinamento.go - Modo treinamento com dados isolados
+//
+// O treinamento roda em uma segunda instância do servidor, iniciada com
+// TREINAMENTO_ENABLED=true e apontando para um banco próprio (DB_NAME) e um
+// ANEXOS_DIR próprio. A instância principal, com TREINAMENTO_URL configurada,
+// encaminha para ela toda requisição que chega com o header X-Training-Mode:
+// true, que o app envia enquanto o dispositivo está em treinamento. Toda
+// resposta da instância de treino leva X-Training-Mode: true para o app exibir
+// o aviso.
+//
+// A instância de treino não dispara efeitos externos (webhooks, e-mail, push,
+// Telegram) e volta todo dia em TREINAMENTO_RESET_HORARIO ao modelo guardado
+// no schema treino_modelo, criado no primeiro start a partir do conteúdo do
+// banco de treino e regravável em POST /api/treinamento/modelo.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Configuração do modo treinamento - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	treinamentoEnabled      = getEnv("TREINAMENTO_ENABLED", "false") == "true"
+	treinamentoURL          = strings.TrimRight(getEnv("TREINAMENTO_URL", ""), "/")
+	treinamentoResetHorario = getEnv("TREINAMENTO_RESET_HORARIO", "03:00")
+)
+
+// Header enviado pelo app e devolvido nas respostas do treinamento
+const headerTreinamento = "X-Training-Mode"
+
+// Schema com a cópia dos dados usada no reset
+const schemaModeloTreinamento = "treino_modelo"
+
+// Estado do reset, exposto em GET /api/treinamento
+var treinamento struct {
+	mu          sync.Mutex
+	ultimoReset time.Time
+}
+
+type EstadoTreinamento struct {
+	Ativo        bool       `json:"ativo"`
+	UltimoReset  *time.Time `json:"ultimo_reset,omitempty"`
+	ProximoReset time.Time  `json:"proximo_reset"`
+}
+
+func requisicaoTreinamento(c *gin.Context) bool {
+	v := strings.ToLower(c.GetHeader(headerTreinamento))
+	return v == "true" || v == "1"
+}
+
+// Middleware da instância principal: encaminha as requisições em treinamento
+// para a instância de treino
+func EncaminharTreinamento() gin.HandlerFunc {
+	var proxy *httputil.ReverseProxy
+	if treinamentoURL != "" {
+		destino, err := url.Parse(treinamentoURL)
+		if err != nil {
+			log.Fatalf("[ERROR] TREINAMENTO_URL inválida: %v", err)
+		}
+		proxy = httputil.NewSingleHostReverseProxy(destino)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("[ERROR] Erro ao encaminhar requisição de treinamento: %v", err)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"Instância de treinamento indisponível"}`))
+		}
+	}
+
+	return func(c *gin.Context) {
+		if !requisicaoTreinamento(c) {
+			c.Next()
+			return
+		}
+		if proxy == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{Error: "Modo treinamento não configurado"})
+			return
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+// Middleware da instância de treino: marca todas as respostas
+func MarcarTreinamento() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(headerTreinamento, "true")
+		c.Next()
+	}
+}
+
+// Tabelas do schema public em ordem de dependência (referenciadas primeiro);
+// o controle das migrações acompanha o esquema, não os dados de treino
+func tabelasTreinamento(ctx context.Context, q querier) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT c.relname,
+			COALESCE(array_agg(DISTINCT r.relname) FILTER (WHERE r.relname IS NOT NULL AND r.relname <> c.relname), '{}')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_constraint f ON f.conrelid = c.oid AND f.contype = 'f'
+		LEFT JOIN pg_class r ON r.oid = f.confrelid
+		WHERE n.nspname = 'public' AND c.relkind = 'r' AND c.relname <> $1
+		GROUP BY c.relname
+		ORDER BY c.relname
+	`, tabelaMigracoes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependencias := map[string][]string{}
+	var nomes []string
+	for rows.Next() {
+		var nome string
+		var deps []string
+		if err := rows.Scan(&nome, &deps); err != nil {
+			return nil, err
+		}
+		nomes = append(nomes, nome)
+		dependencias[nome] = deps
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Ordenação topológica simples
+	var ordem []string
+	visitada := map[string]bool{}
+	var visitar func(string)
+	visitar = func(nome string) {
+		if visitada[nome] {
+			return
+		}
+		visitada[nome] = true
+		for _, dep := range dependencias[nome] {
+			visitar(dep)
+		}
+		ordem = append(ordem, nome)
+	}
+	for _, nome := range nomes {
+		visitar(nome)
+	}
+	return ordem, nil
+}
+
+// Regrava o schema modelo com o conteúdo atual do banco de treino
+func salvarModeloTreinamento(ctx context.Context) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	tabelas, err := tabelasTreinamento(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	modelo := pgx.Identifier{schemaModeloTreinamento}.Sanitize()
+	if _, err := tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+modelo+" CASCADE"); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA "+modelo); err != nil {
+		return 0, err
+	}
+	for _, t := range tabelas {
+		_, err := tx.Exec(ctx, fmt.Sprintf("CREATE TABLE %s AS TABLE %s",
+			pgx.Identifier{schemaModeloTreinamento, t}.Sanitize(), pgx.Identifier{"public", t}.Sanitize()))
+		if err != nil {
+			return 0, fmt.Errorf("tabela %s: %w", t, err)
+		}
+	}
+
+	// Commit da transação
+	return len(tabelas), tx.Commit(ctx)
+}
+
+// Volta o banco de treino ao modelo: esvazia as tabelas, copia o modelo e
+// reposiciona as sequências
+func resetarTreinamento(ctx context.Context) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // Rollback caso ocorra algum erro
+
+	tabelas, err := tabelasTreinamento(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(tabelas) == 0 {
+		return nil
+	}
+
+	publicas := make([]string, len(tabelas))
+	for i, t := range tabelas {
+		publicas[i] = pgx.Identifier{"public", t}.Sanitize()
+	}
+	if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(publicas, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+		return err
+	}
+
+	for i, t := range tabelas {
+		var existe bool
+		err := tx.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL",
+			pgx.Identifier{schemaModeloTreinamento, t}.Sanitize()).Scan(&existe)
+		if err != nil {
+			return err
+		}
+		// Tabelas criadas depois do modelo ficam vazias
+		if !existe {
+			continue
+		}
+		_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s SELECT * FROM %s",
+			publicas[i], pgx.Identifier{schemaModeloTreinamento, t}.Sanitize()))
+		if err != nil {
+			return fmt.Errorf("tabela %s: %w", t, err)
+		}
+	}
+
+	// Sequências das colunas seriais seguem o maior valor copiado
+	rows, err := tx.Query(ctx, `
+		SELECT table_name, column_name, pg_get_serial_sequence(quote_ident(table_name), column_name)
+		FROM information_schema.columns
+		WHERE table_schema = 'public' AND pg_get_serial_sequence(quote_ident(table_name), column_name) IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	type sequencia struct{ tabela, coluna, nome string }
+	var sequencias []sequencia
+	for rows.Next() {
+		var s sequencia
+		if err := rows.Scan(&s.tabela, &s.coluna, &s.nome); err != nil {
+			rows.Close()
+			return err
+		}
+		sequencias = append(sequencias, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, s := range sequencias {
+		_, err := tx.Exec(ctx, fmt.Sprintf("SELECT setval($1, COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)",
+			pgx.Identifier{s.coluna}.Sanitize(), pgx.Identifier{"public", s.tabela}.Sanitize()), s.nome)
+		if err != nil {
+			return fmt.Errorf("sequência %s: %w", s.nome, err)
+		}
+	}
+
+	// Commit da transação
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// Configurações em cache refletem o banco anterior ao reset
+	if aquecimentoEnabled {
+		if _, err := carregarConfiguracoes(ctx); err != nil {
+			log.Printf("[WARN] Erro ao recarregar configurações após o reset: %v", err)
+		}
+	}
+
+	invalidarCache(grupoCacheConfiguracoes, grupoCacheProdutos, grupoCacheDashboard)
+
+	treinamento.mu.Lock()
+	treinamento.ultimoReset = time.Now()
+	treinamento.mu.Unlock()
+	return nil
+}
+
+// Prepara a instância de treino e agenda o reset diário; roda até o contexto ser cancelado
+func iniciarTreinamento(ctx context.Context) {
+	var existe bool
+	err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_namespace WHERE nspname = $1)", schemaModeloTreinamento).Scan(&existe)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao verificar modelo do treinamento: %v", err)
+	} else if !existe {
+		n, err := salvarModeloTreinamento(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao criar modelo do treinamento: %v", err)
+		} else {
+			log.Printf("[DB] Modelo do treinamento criado com %d tabelas", n)
+		}
+	}
+
+	for {
+		proximo := proximoHorario(time.Now(), treinamentoResetHorario, "03:00")
+		log.Printf("[DB] Próximo reset do treinamento em %s", proximo.Format("2006-01-02 15:04"))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(proximo)):
+		}
+
+		if err := resetarTreinamento(ctx); err != nil {
+			log.Printf("[ERROR] Erro ao resetar dados do treinamento: %v", err)
+			continue
+		}
+		log.Println("[DB] Dados do treinamento restaurados a partir do modelo")
+	}
+}
+
+// Handlers do Modo Treinamento
+
+func getTreinamento(c *gin.Context) {
+	estado := EstadoTreinamento{
+		Ativo:        true,
+		ProximoReset: proximoHorario(time.Now(), treinamentoResetHorario, "03:00"),
+	}
+	treinamento.mu.Lock()
+	if !treinamento.ultimoReset.IsZero() {
+		t := treinamento.ultimoReset
+		estado.UltimoReset = &t
+	}
+	treinamento.mu.Unlock()
+	c.JSON(http.StatusOK, estado)
+}
+
+func resetarTreinamentoHandler(c *gin.Context) {
+	log.Println("[API] Reset dos dados do treinamento solicitado")
+	if err := resetarTreinamento(c.Request.Context()); err != nil {
+		log.Printf("[ERROR] Erro ao resetar dados do treinamento: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao resetar dados do treinamento"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dados do treinamento restaurados"})
+}
+
+func salvarModeloTreinamentoHandler(c *gin.Context) {
+	log.Println("[API] Gravando modelo do treinamento")
+	n, err := salvarModeloTreinamento(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] Erro ao gravar modelo do treinamento: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gravar modelo do treinamento"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Modelo do treinamento gravado", "tabelas": n})
+}
@@ -0,0 +1,257 @@
+// unidades.go - Unidades de medida e conversão para a unidade base do produto
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rlsautomacao/estoque/internal/estoque"
+)
+
+// Unidade usada quando o produto não informa a sua
+const unidadePadrao = estoque.UnidadePadrao
+
+type UnidadeMedida struct {
+	Sigla     string `json:"sigla"`
+	Descricao string `json:"descricao"`
+}
+
+// Conversão: 1 unidade_origem = fator unidade_destino. Sem produto, vale para todos.
+type ConversaoUnidade struct {
+	ID             int     `json:"id,omitempty"`
+	ProdutoID      *int    `json:"produto_id,omitempty"`
+	UnidadeOrigem  string  `json:"unidade_origem"`
+	UnidadeDestino string  `json:"unidade_destino"`
+	Fator          float64 `json:"fator"`
+}
+
+var (
+	errConversaoInexistente = errors.New("não há conversão cadastrada entre as unidades")
+	errConversaoFracionada  = errors.New("a quantidade convertida não é um número inteiro na unidade base")
+)
+
+// Converte uma quantidade informada em "unidade" para a unidade base do produto.
+// Conversões específicas do produto têm prioridade sobre as globais, e uma
+// conversão cadastrada no sentido inverso também é aceita.
+func converterParaBase(ctx context.Context, q querier, produtoID int, unidadeBase, unidade string, quantidade int) (int, error) {
+	if unidade == "" || unidade == unidadeBase {
+		return quantidade, nil
+	}
+
+	var fator float64
+	err := q.QueryRow(ctx, `
+		SELECT fator FROM conversoes_unidade
+		WHERE (produto_id = $1 OR produto_id IS NULL)
+		  AND unidade_origem = $2 AND unidade_destino = $3
+		ORDER BY produto_id NULLS LAST
+		LIMIT 1
+	`, produtoID, unidade, unidadeBase).Scan(&fator)
+
+	if err == pgx.ErrNoRows {
+		var inverso float64
+		err = q.QueryRow(ctx, `
+			SELECT fator FROM conversoes_unidade
+			WHERE (produto_id = $1 OR produto_id IS NULL)
+			  AND unidade_origem = $2 AND unidade_destino = $3
+			ORDER BY produto_id NULLS LAST
+			LIMIT 1
+		`, produtoID, unidadeBase, unidade).Scan(&inverso)
+		if err == pgx.ErrNoRows {
+			return 0, errConversaoInexistente
+		}
+		if err == nil {
+			fator = 1 / inverso
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	convertida := float64(quantidade) * fator
+	arredondada := math.Round(convertida)
+	if math.Abs(convertida-arredondada) > 1e-6 {
+		return 0, errConversaoFracionada
+	}
+
+	log.Printf("[DB] Convertendo %d %s para %d %s (fator %.4f)", quantidade, unidade, int(arredondada), unidadeBase, fator)
+	return int(arredondada), nil
+}
+
+// Handlers de Unidades
+
+func getUnidades(c *gin.Context) {
+	log.Println("[DB] Buscando unidades de medida")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT sigla, descricao FROM unidades_medida ORDER BY sigla
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar unidades de medida: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar unidades de medida"})
+		return
+	}
+	defer rows.Close()
+
+	unidades := []UnidadeMedida{}
+	for rows.Next() {
+		var u UnidadeMedida
+		if err := rows.Scan(&u.Sigla, &u.Descricao); err != nil {
+			log.Printf("[ERROR] Erro ao processar unidade de medida: %v", err)
+			continue
+		}
+		unidades = append(unidades, u)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar unidades de medida: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar unidades de medida"})
+		return
+	}
+
+	c.JSON(http.StatusOK, unidades)
+}
+
+// Função auxiliar para listar conversões (globais ou de um produto)
+func listarConversoes(c *gin.Context, produtoID *int) {
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, produto_id, unidade_origem, unidade_destino, fator
+		FROM conversoes_unidade
+		WHERE ($1::int IS NULL AND produto_id IS NULL) OR produto_id = $1
+		ORDER BY unidade_origem, unidade_destino
+	`, produtoID)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar conversões: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar conversões"})
+		return
+	}
+	defer rows.Close()
+
+	conversoes := []ConversaoUnidade{}
+	for rows.Next() {
+		var cv ConversaoUnidade
+		if err := rows.Scan(&cv.ID, &cv.ProdutoID, &cv.UnidadeOrigem, &cv.UnidadeDestino, &cv.Fator); err != nil {
+			log.Printf("[ERROR] Erro ao processar conversão: %v", err)
+			continue
+		}
+		conversoes = append(conversoes, cv)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar conversões: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar conversões"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversoes)
+}
+
+func getConversoes(c *gin.Context) {
+	log.Println("[DB] Buscando conversões globais de unidade")
+	listarConversoes(c, nil)
+}
+
+func getConversoesPorProduto(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	log.Printf("[DB] Buscando conversões de unidade do produto ID: %d", id)
+	listarConversoes(c, &id)
+}
+
+func criarConversao(c *gin.Context) {
+	log.Println("[API] Iniciando criação de conversão de unidade")
+
+	var cv ConversaoUnidade
+	if err := c.ShouldBindJSON(&cv); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+
+	// Em /produtos/:id/conversoes o destino é sempre a unidade base do produto
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("[ERROR] ID inválido: %s", idStr)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+			return
+		}
+
+		err = db.QueryRow(c.Request.Context(), "SELECT unidade_medida FROM produtos WHERE id = $1", id).Scan(&cv.UnidadeDestino)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				log.Printf("[DB] Produto não encontrado com ID: %d", id)
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: "Produto não encontrado", Codigo: CodigoProdutoNaoEncontrado})
+			} else {
+				log.Printf("[ERROR] Erro ao verificar produto: %v", err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto"})
+			}
+			return
+		}
+		cv.ProdutoID = &id
+	}
+
+	if cv.UnidadeOrigem == "" || cv.UnidadeDestino == "" || cv.UnidadeOrigem == cv.UnidadeDestino || cv.Fator <= 0 {
+		log.Printf("[ERROR] Conversão inválida: %s -> %s (fator %f)", cv.UnidadeOrigem, cv.UnidadeDestino, cv.Fator)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Informe unidades de origem e destino diferentes e fator positivo"})
+		return
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO conversoes_unidade(produto_id, unidade_origem, unidade_destino, fator)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, cv.ProdutoID, cv.UnidadeOrigem, cv.UnidadeDestino, cv.Fator).Scan(&cv.ID)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar conversão: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Erro ao criar conversão (verifique as unidades e se ela já existe)"})
+		return
+	}
+
+	log.Printf("[DB] Conversão criada: 1 %s = %s %s (ID: %d)", cv.UnidadeOrigem,
+		strconv.FormatFloat(cv.Fator, 'f', -1, 64), cv.UnidadeDestino, cv.ID)
+	c.JSON(http.StatusCreated, cv)
+}
+
+func deletarConversao(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM conversoes_unidade WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir conversão: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir conversão"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Conversão não encontrada com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Conversão não encontrada"})
+		return
+	}
+
+	log.Printf("[DB] Conversão excluída com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Conversão excluída com sucesso"})
+}
@@ -0,0 +1,156 @@
+// validacao.go - Validação dos payloads da API
+//
+// Produto, Movimentacao e Configuracao declaram as regras nas tags binding
+// (go-playground/validator, o mesmo validador do gin): tamanho máximo dos
+// textos conforme as colunas, quantidades e custos não negativos e o formato
+// do código. O ShouldBindJSON já aplica as regras; erroDadosInvalidos devolve
+// todas as violações de uma vez em "campos", com o nome JSON do campo, um
+// código estável e a mensagem. Listas (lotes, importação) são decodificadas
+// sem validação e cada item é validado com validarCampos, para o erro trazer
+// a posição do item.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Código de produto: começa com letra ou dígito e usa só letras, dígitos,
+// espaço e . _ / -; o padrão da empresa é conferido à parte (politica_codigos.go)
+var padraoCodigoProduto = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} ._/-]*$`)
+
+// Registra no validador do gin os nomes JSON dos campos e as regras próprias
+func configurarValidacao() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		nome, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if nome == "-" {
+			return ""
+		}
+		return nome
+	})
+	v.RegisterValidation("codigo", func(fl validator.FieldLevel) bool {
+		return padraoCodigoProduto.MatchString(strings.TrimSpace(fl.Field().String()))
+	})
+}
+
+// Violações de validação de um payload já decodificado; vazio se válido
+func validarCampos(v any) []ErroCampo {
+	return errosDeCampo(binding.Validator.ValidateStruct(v))
+}
+
+// Lê uma lista JSON do corpo sem validar os itens (ver validarCampos)
+func decodificarLista(c *gin.Context, lista any) error {
+	return json.NewDecoder(c.Request.Body).Decode(lista)
+}
+
+// Converte erros de decodificação e de validação em erros por campo
+func errosDeCampo(err error) []ErroCampo {
+	if err == nil {
+		return nil
+	}
+	var tipo *json.UnmarshalTypeError
+	if errors.As(err, &tipo) && tipo.Field != "" {
+		return []ErroCampo{{Campo: tipo.Field, Codigo: "TIPO_INVALIDO", Mensagem: "deve ser " + nomeTipoJSON(tipo.Type)}}
+	}
+	var violacoes validator.ValidationErrors
+	if !errors.As(err, &violacoes) {
+		return nil
+	}
+	campos := make([]ErroCampo, 0, len(violacoes))
+	for _, v := range violacoes {
+		codigo, msg := mensagemValidacao(v)
+		campos = append(campos, ErroCampo{Campo: nomeCampo(v), Codigo: codigo, Mensagem: msg})
+	}
+	return campos
+}
+
+// Caminho JSON do campo sem o nome do tipo raiz (ex.: lotes[0].quantidade).
+// Structs embutidas não têm nome no JSON mas entram no namespace com o nome
+// do tipo (Produto, DadosBaixa); como os campos JSON da API são minúsculos,
+// os segmentos com inicial maiúscula são descartados
+func nomeCampo(v validator.FieldError) string {
+	_, resto, ok := strings.Cut(v.Namespace(), ".")
+	if !ok {
+		return v.Field()
+	}
+	var partes []string
+	for _, parte := range strings.Split(resto, ".") {
+		if parte != "" && unicode.IsUpper([]rune(parte)[0]) {
+			continue
+		}
+		partes = append(partes, parte)
+	}
+	return strings.Join(partes, ".")
+}
+
+// Código e mensagem de uma violação
+func mensagemValidacao(v validator.FieldError) (string, string) {
+	texto := v.Kind() == reflect.String
+	switch v.Tag() {
+	case "required", "required_without":
+		return "OBRIGATORIO", "é obrigatório"
+	case "max":
+		if texto {
+			return "TAMANHO_MAXIMO", fmt.Sprintf("deve ter no máximo %s caracteres", v.Param())
+		}
+		return "VALOR_MAXIMO", fmt.Sprintf("deve ser no máximo %s", v.Param())
+	case "min", "gte":
+		if texto {
+			return "TAMANHO_MINIMO", fmt.Sprintf("deve ter no mínimo %s caracteres", v.Param())
+		}
+		if v.Param() == "0" {
+			return "VALOR_NEGATIVO", "não pode ser negativo"
+		}
+		return "VALOR_MINIMO", fmt.Sprintf("deve ser no mínimo %s", v.Param())
+	case "len":
+		return "TAMANHO", fmt.Sprintf("deve ter %s caracteres", v.Param())
+	case "oneof":
+		return "VALOR_INVALIDO", "deve ser " + strings.Join(strings.Fields(v.Param()), " ou ")
+	case "numeric":
+		return "FORMATO_INVALIDO", "deve conter só dígitos"
+	case "excludes":
+		return "FORMATO_INVALIDO", "não pode conter " + v.Param()
+	case "hexcolor":
+		return "FORMATO_INVALIDO", "deve ser uma cor #RRGGBB"
+	case "datetime":
+		return "FORMATO_INVALIDO", "deve ser uma data AAAA-MM-DD"
+	case "codigo":
+		return "FORMATO_INVALIDO", "deve começar com letra ou dígito e conter só letras, dígitos, espaço e . _ / -"
+	}
+	return "INVALIDO", "é inválido"
+}
+
+// Nome do tipo esperado num campo JSON, para a mensagem
+func nomeTipoJSON(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "um número inteiro"
+	case reflect.Float32, reflect.Float64:
+		return "um número"
+	case reflect.Bool:
+		return "verdadeiro ou falso"
+	case reflect.String:
+		return "um texto"
+	case reflect.Slice, reflect.Array:
+		return "uma lista"
+	}
+	return "um objeto"
+}
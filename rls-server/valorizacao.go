@@ -0,0 +1,154 @@
+// valorizacao.go - Custo dos produtos e valorização do estoque (custo médio ou FIFO)
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Métodos de valorização aceitos
+const (
+	ValorizacaoCustoMedio = "custo_medio"
+	ValorizacaoFIFO       = "fifo"
+)
+
+type ItemValorizacao struct {
+	ProdutoID     int     `json:"produto_id"`
+	Codigo        string  `json:"codigo"`
+	Nome          string  `json:"nome"`
+	Quantidade    int     `json:"quantidade"`
+	CustoUnitario float64 `json:"custo_unitario"`
+	ValorTotal    float64 `json:"valor_total"`
+}
+
+type RelatorioValorizacao struct {
+	Metodo     string            `json:"metodo"`
+	Itens      []ItemValorizacao `json:"itens"`
+	ValorTotal float64           `json:"valor_total"`
+}
+
+// Recalcula o custo médio ponderado do produto com uma entrada, dentro da
+// transação. Deve ser chamada antes de somar a entrada à quantidade do produto.
+// Um custo diferente do atual fica registrado no histórico de preços.
+func atualizarCustoMedio(ctx context.Context, tx pgx.Tx, m *Movimentacao) error {
+	custo := *m.CustoUnitario
+
+	var anterior float64
+	err := tx.QueryRow(ctx, "SELECT preco_custo FROM produtos WHERE id = $1 FOR UPDATE", m.ProdutoID).Scan(&anterior)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE produtos SET preco_custo = CASE
+			WHEN quantidade <= 0 THEN $1::numeric
+			ELSE (quantidade * preco_custo + $2::int * $1::numeric) / (quantidade + $2::int)
+		END
+		WHERE id = $3
+	`, custo, m.Quantidade, m.ProdutoID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DB] Custo médio atualizado para o produto ID: %d (entrada de %d a %.4f)", m.ProdutoID, m.Quantidade, custo)
+
+	if custo != anterior {
+		return registrarHistoricoPreco(ctx, tx, m.ProdutoID, anterior, custo, OrigemPrecoEntrada, &m.ID)
+	}
+	return nil
+}
+
+// Valor do saldo de cada produto pelo FIFO, numa consulta só: o saldo restante
+// é composto pelas entradas mais recentes (a soma acumulada das entradas
+// posteriores diz quanto de cada uma ainda está em estoque) e o que não for
+// coberto por entradas usa o custo atual.
+const consultaValorizacaoFIFO = `
+	WITH entradas AS (
+		SELECT m.produto_id, m.quantidade, COALESCE(m.custo_unitario, p.preco_custo) AS custo,
+			p.quantidade - COALESCE(SUM(m.quantidade) OVER (
+				PARTITION BY m.produto_id
+				ORDER BY m.data_movimentacao DESC, m.id DESC
+				ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+			), 0) AS restante
+		FROM movimentacoes m
+		JOIN produtos p ON p.id = m.produto_id
+		WHERE m.tipo = 'entrada' AND p.quantidade > 0
+	), cobertos AS (
+		SELECT produto_id,
+			SUM(GREATEST(LEAST(quantidade, restante), 0)) AS quantidade,
+			SUM(GREATEST(LEAST(quantidade, restante), 0) * custo) AS valor
+		FROM entradas
+		GROUP BY produto_id
+	)
+	SELECT p.id, p.codigo, p.nome, p.quantidade, p.preco_custo,
+		(COALESCE(c.valor, 0) + (p.quantidade - COALESCE(c.quantidade, 0)) * p.preco_custo)::float8
+	FROM produtos p
+	LEFT JOIN cobertos c ON c.produto_id = p.id
+	WHERE p.quantidade > 0
+	ORDER BY p.nome
+`
+
+// Handler para relatório de valorização do estoque
+
+func getRelatorioValorizacao(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	metodo := c.Query("metodo")
+	if metodo == "" {
+		metodo = lerConfiguracao(ctx, "metodo_valorizacao", ValorizacaoCustoMedio)
+	}
+	if metodo != ValorizacaoCustoMedio && metodo != ValorizacaoFIFO {
+		log.Printf("[ERROR] Método de valorização inválido: %s", metodo)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Método de valorização inválido, use custo_medio ou fifo"})
+		return
+	}
+
+	log.Printf("[DB] Gerando relatório de valorização do estoque (%s)", metodo)
+
+	consulta := `
+		SELECT id, codigo, nome, quantidade, preco_custo, (quantidade * preco_custo)::float8
+		FROM produtos
+		WHERE quantidade > 0
+		ORDER BY nome
+	`
+	if metodo == ValorizacaoFIFO {
+		consulta = consultaValorizacaoFIFO
+	}
+
+	rows, err := dbLeitura.Query(ctx, consulta)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar produtos para valorização: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar produtos"})
+		return
+	}
+	defer rows.Close()
+
+	relatorio := RelatorioValorizacao{Metodo: metodo, Itens: []ItemValorizacao{}}
+	for rows.Next() {
+		var item ItemValorizacao
+		if err := rows.Scan(&item.ProdutoID, &item.Codigo, &item.Nome, &item.Quantidade, &item.CustoUnitario, &item.ValorTotal); err != nil {
+			log.Printf("[ERROR] Erro ao processar produto: %v", err)
+			continue
+		}
+		if metodo == ValorizacaoFIFO {
+			item.CustoUnitario = item.ValorTotal / float64(item.Quantidade)
+		}
+		relatorio.Itens = append(relatorio.Itens, item)
+		relatorio.ValorTotal += item.ValorTotal
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar produtos: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar produtos"})
+		return
+	}
+
+	log.Printf("[API] Valorização gerada: %d produtos, valor total %.2f", len(relatorio.Itens), relatorio.ValorTotal)
+	c.JSON(http.StatusOK, relatorio)
+}
@@ -0,0 +1,117 @@
+// variantes.go - Produtos pai e variantes
+//
+// Uma variante (tamanho, cor, voltagem...) aponta para o produto pai por
+// produto_pai_id e tem código, código de barras, saldo e demais campos
+// próprios; nome e descrição vêm sempre do pai e são copiados para a variante
+// na gravação, então buscas, etiquetas e relatórios continuam lendo as
+// colunas do próprio produto. Alterar nome ou descrição do pai atualiza as
+// variantes. Só há um nível: o pai não pode ser variante e um produto com
+// variantes não pode virar variante de outro.
+//
+// GET /api/produtos/:id/variantes lista as variantes do produto (ou as irmãs,
+// se o id for de uma variante) e o produto pai traz em "variantes" a
+// quantidade de variantes e o estoque somado delas.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Regra de variante violada; a mensagem vai para o cliente
+type erroVariante string
+
+func (e erroVariante) Error() string { return string(e) }
+
+// Confere o produto pai da variante e copia dele nome e descrição; id é o do
+// próprio produto (0 na criação). Sem produto pai a variação é descartada
+func vincularVariante(ctx context.Context, q querier, id int, p *Produto) error {
+	p.Variacao = strings.TrimSpace(p.Variacao)
+	if p.ProdutoPaiID == nil {
+		p.Variacao = ""
+		return nil
+	}
+	paiID := *p.ProdutoPaiID
+	if paiID == id {
+		return erroVariante("Um produto não pode ser variante de si mesmo")
+	}
+
+	var nome string
+	var descricao *string
+	var avo *int
+	err := q.QueryRow(ctx, "SELECT nome, descricao, produto_pai_id FROM produtos WHERE id = $1", paiID).Scan(&nome, &descricao, &avo)
+	if err == pgx.ErrNoRows {
+		return erroVariante("Produto pai não encontrado")
+	}
+	if err != nil {
+		return err
+	}
+	if avo != nil {
+		return erroVariante("O produto pai não pode ser uma variante")
+	}
+
+	if id != 0 {
+		var temVariantes bool
+		err = q.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM produtos WHERE produto_pai_id = $1)", id).Scan(&temVariantes)
+		if err != nil {
+			return err
+		}
+		if temVariantes {
+			return erroVariante("Um produto com variantes não pode ser variante de outro")
+		}
+	}
+
+	if err = validarVariacao(ctx, q, paiID, id, p.Variacao); err != nil {
+		return err
+	}
+	p.Nome, p.Descricao = nome, ""
+	if descricao != nil {
+		p.Descricao = *descricao
+	}
+	return nil
+}
+
+// Confere a variação: obrigatória e única entre as variantes do mesmo pai
+func validarVariacao(ctx context.Context, q querier, paiID, id int, variacao string) error {
+	if variacao == "" {
+		return erroVariante("Informe a variação (ex.: tamanho ou cor)")
+	}
+	var existe bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM produtos WHERE produto_pai_id = $1 AND variacao = $2 AND id <> $3)
+	`, paiID, variacao, id).Scan(&existe)
+	if err != nil {
+		return err
+	}
+	if existe {
+		return erroVariante("Já existe a variante " + variacao + " neste produto")
+	}
+	return nil
+}
+
+// Copia nome e descrição do produto pai para as variantes
+func propagarParaVariantes(ctx context.Context, q querier, paiID int, nome, descricao string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE produtos SET nome = $1, descricao = $2, data_atualizacao = CURRENT_TIMESTAMP
+		WHERE produto_pai_id = $3 AND (nome <> $1 OR descricao IS DISTINCT FROM $2)
+	`, nome, descricao, paiID)
+	return err
+}
+
+// Responde ao erro de vincularVariante/validarVariacao
+func responderErroVariante(c *gin.Context, err error) {
+	var regra erroVariante
+	if errors.As(err, &regra) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: regra.Error()})
+		return
+	}
+	log.Printf("[ERROR] Erro ao verificar produto pai: %v", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao verificar produto pai"})
+}
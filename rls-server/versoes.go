@@ -0,0 +1,91 @@
+// versoes.go - Versionamento da API
+//
+// As rotas ficam em /api/v1/...; os caminhos antigos sem versão (/api/...)
+// continuam respondendo como a v1, para não quebrar os apps já instalados,
+// mas marcados com o cabeçalho Deprecation, Link para o caminho versionado e,
+// se API_LEGADO_SUNSET estiver definido, a data de desligamento em Sunset.
+//
+// Política: a versão no caminho é o contrato. Mudanças compatíveis (campos
+// novos, rotas novas) entram na versão atual; mudanças que quebram clientes
+// (como trocar listas por um envelope de paginação) entram em /api/v2, e a
+// versão anterior segue atendida até o Sunset. Toda resposta traz o cabeçalho
+// API-Version; o cliente pode enviar API-Version na requisição para exigir
+// uma versão e recebe 400 se ela não for a servida naquele caminho.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuração do versionamento - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	// Data de desligamento dos caminhos sem versão (formato HTTP-date, vazio = sem data)
+	apiLegadoSunset = getEnv("API_LEGADO_SUNSET", "")
+)
+
+const (
+	headerVersaoAPI  = "API-Version"
+	headerDeprecacao = "Deprecation"
+	headerSunset     = "Sunset"
+
+	// Versão atual, servida em /api/v1 e nos caminhos sem versão
+	versaoAtualAPI = 1
+)
+
+// Prefixo do caminho de uma versão da API
+func prefixoVersaoAPI(versao int) string {
+	return fmt.Sprintf("/api/v%d", versao)
+}
+
+// Caminho equivalente sem versão (/api/v1/produtos -> /api/produtos), usado
+// nas tabelas indexadas por rota
+func rotaSemVersao(caminho string) string {
+	resto, ok := strings.CutPrefix(caminho, "/api/v")
+	if !ok {
+		return caminho
+	}
+	numero, depois, _ := strings.Cut(resto, "/")
+	if _, err := strconv.Atoi(numero); err != nil {
+		return caminho
+	}
+	return "/api/" + depois
+}
+
+// Middleware das rotas de uma versão: anuncia a versão servida e recusa
+// requisições que exigem outra
+func VersaoAPI(versao int) gin.HandlerFunc {
+	servida := strconv.Itoa(versao)
+	return func(c *gin.Context) {
+		c.Header(headerVersaoAPI, servida)
+
+		if pedida := strings.TrimSpace(c.GetHeader(headerVersaoAPI)); pedida != "" && strings.TrimPrefix(pedida, "v") != servida {
+			log.Printf("[WARN] Versão da API %q pedida em %s, servida %s", pedida, c.Request.URL.Path, servida)
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Versão da API %s não disponível neste caminho; use %s", pedida, prefixoVersaoAPI(versaoAtualAPI)),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Middleware dos caminhos sem versão: continuam servindo a versão atual,
+// marcados como obsoletos
+func CaminhoLegado() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sucessor := prefixoVersaoAPI(versaoAtualAPI) + strings.TrimPrefix(c.Request.URL.Path, "/api")
+		c.Header(headerDeprecacao, "true")
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", sucessor))
+		if apiLegadoSunset != "" {
+			c.Header(headerSunset, apiLegadoSunset)
+		}
+		c.Next()
+	}
+}
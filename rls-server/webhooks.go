@@ -0,0 +1,607 @@
+// webhooks.go - Webhooks de saída para eventos do estoque
+//
+// Cada webhook cadastrado em /api/webhooks assina tipos de evento
+// (movimentacao.criada, estoque.baixo, produto.atualizado). As entregas são
+// gravadas em webhook_entregas na transação que gerou o evento, sem passar
+// pelo barramento (que descarta eventos), e enviadas em segundo plano por
+// POST assinado com HMAC-SHA256 do corpo (header X-Estoque-Assinatura). Falhas
+// são repetidas com backoff exponencial até WEBHOOK_MAX_TENTATIVAS; o log das
+// entregas fica em GET /api/webhooks/:id/entregas e cada webhook aparece no
+// painel de integrações.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Status de uma entrega
+const (
+	EntregaPendente = "pendente"
+	EntregaEntregue = "entregue"
+	EntregaFalhou   = "falhou"
+)
+
+// Configuração do envio - valores padrão, podem ser sobrescritos por variáveis de ambiente
+var (
+	webhookMaxTentativas   = getEnvAsInt("WEBHOOK_MAX_TENTATIVAS", 8)
+	webhookBackoffSegundos = getEnvAsInt("WEBHOOK_BACKOFF_SEGUNDOS", 30)
+	webhookTimeoutSegundos = getEnvAsInt("WEBHOOK_TIMEOUT_SEGUNDOS", 10)
+)
+
+// Eventos que podem ser assinados
+var eventosWebhook = map[string]bool{
+	EventoProdutoAtualizado:  true,
+	EventoMovimentacaoCriada: true,
+	EventoEstoqueBaixo:       true,
+	EventoComentarioCriado:   true,
+}
+
+type Webhook struct {
+	ID              int       `json:"id,omitempty"`
+	URL             string    `json:"url"`
+	Eventos         []string  `json:"eventos"`
+	Secret          string    `json:"secret,omitempty"`
+	Ativo           bool      `json:"ativo"`
+	DataCriacao     time.Time `json:"data_criacao,omitempty"`
+	DataAtualizacao time.Time `json:"data_atualizacao,omitempty"`
+}
+
+type EntregaWebhook struct {
+	ID               int        `json:"id"`
+	WebhookID        int        `json:"webhook_id"`
+	Evento           string     `json:"evento"`
+	Status           string     `json:"status"`
+	Tentativas       int        `json:"tentativas"`
+	UltimoStatusHTTP *int       `json:"ultimo_status_http,omitempty"`
+	UltimoErro       string     `json:"ultimo_erro,omitempty"`
+	ProximaTentativa *time.Time `json:"proxima_tentativa,omitempty"`
+	DataCriacao      time.Time  `json:"data_criacao"`
+	DataEntrega      *time.Time `json:"data_entrega,omitempty"`
+}
+
+var webhookClient = &http.Client{Timeout: time.Duration(max(webhookTimeoutSegundos, 1)) * time.Second}
+
+// Sinaliza o worker para processar entregas novas sem esperar o próximo ciclo
+var webhookSinal = make(chan struct{}, 1)
+
+// Integração do painel para um webhook
+type integracaoWebhook struct {
+	webhook  Webhook
+	metricas *MetricasIntegracao
+}
+
+func nomeIntegracaoWebhook(id int) string {
+	return "webhook-" + strconv.Itoa(id)
+}
+
+func (i *integracaoWebhook) Nome() string                  { return nomeIntegracaoWebhook(i.webhook.ID) }
+func (i *integracaoWebhook) Tipo() string                  { return "webhook" }
+func (i *integracaoWebhook) Ativa() bool                   { return i.webhook.Ativo }
+func (i *integracaoWebhook) Metricas() *MetricasIntegracao { return i.metricas }
+
+func (i *integracaoWebhook) FilaPendente() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var pendentes int
+	err := db.QueryRow(ctx, "SELECT COUNT(*) FROM webhook_entregas WHERE webhook_id = $1 AND status = $2",
+		i.webhook.ID, EntregaPendente).Scan(&pendentes)
+	if err != nil {
+		log.Printf("[WARN] Erro ao contar entregas pendentes do webhook %d: %v", i.webhook.ID, err)
+	}
+	return pendentes
+}
+
+// Envia um evento "ping" assinado, sem registrar entrega
+func (i *integracaoWebhook) Testar(ctx context.Context) error {
+	w := Webhook{ID: i.webhook.ID}
+	err := db.QueryRow(ctx, "SELECT url, secret FROM webhooks WHERE id = $1", w.ID).Scan(&w.URL, &w.Secret)
+	if err != nil {
+		return err
+	}
+
+	corpo, _ := json.Marshal(Evento{Tipo: "ping", Data: time.Now()})
+	status, err := enviarWebhook(ctx, w, "ping", 0, corpo)
+	if err == nil && (status < 200 || status >= 300) {
+		err = fmt.Errorf("status HTTP %d", status)
+	}
+	return err
+}
+
+// Registra (ou atualiza) o webhook no painel mantendo as métricas acumuladas.
+// O secret não fica em memória; o teste o lê do banco.
+func registrarIntegracaoWebhook(w Webhook) {
+	metricas := &MetricasIntegracao{}
+	integracoesMutex.RLock()
+	if anterior, ok := integracoes[nomeIntegracaoWebhook(w.ID)].(*integracaoWebhook); ok {
+		metricas = anterior.metricas
+	}
+	integracoesMutex.RUnlock()
+
+	w.Secret = ""
+	registrarIntegracao(&integracaoWebhook{webhook: w, metricas: metricas})
+}
+
+func metricasWebhook(id int) *MetricasIntegracao {
+	integracoesMutex.RLock()
+	defer integracoesMutex.RUnlock()
+	if i, ok := integracoes[nomeIntegracaoWebhook(id)].(*integracaoWebhook); ok {
+		return i.metricas
+	}
+	return nil
+}
+
+// Função auxiliar para gerar o segredo de assinatura quando não informado
+func gerarSecretWebhook() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func assinarWebhook(secret string, corpo []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(corpo)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Faz o POST do corpo para o webhook e devolve o status HTTP
+func enviarWebhook(ctx context.Context, w Webhook, evento string, entregaID int, corpo []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(corpo))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rls-estoque-webhook")
+	req.Header.Set("X-Estoque-Evento", evento)
+	req.Header.Set("X-Estoque-Entrega", strconv.Itoa(entregaID))
+	req.Header.Set("X-Estoque-Assinatura", assinarWebhook(w.Secret, corpo))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
+
+// Espera antes da tentativa n (1, 2, 3...): base, 2×base, 4×base..., até 6 horas
+func backoffWebhook(tentativa int) time.Duration {
+	espera := time.Duration(max(webhookBackoffSegundos, 1)) * time.Second
+	for i := 1; i < tentativa && espera < 6*time.Hour; i++ {
+		espera *= 2
+	}
+	return min(espera, 6*time.Hour)
+}
+
+// Cria as entregas de um evento para os webhooks ativos que o assinam. Roda
+// na transação da escrita que gerou o evento (outbox): se ela for confirmada
+// as entregas existem, mesmo que o processo caia logo depois ou que o
+// barramento descarte o evento
+func enfileirarEntregasWebhook(ctx context.Context, q querier, tipo string, dados any) error {
+	if !eventosWebhook[tipo] {
+		return nil
+	}
+
+	payload, err := json.Marshal(Evento{Tipo: tipo, Dados: dados, Data: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO webhook_entregas(webhook_id, evento, payload)
+		SELECT id, $1::text, $2 FROM webhooks
+		WHERE ativo AND $1::text = ANY(eventos)
+	`, tipo, payload)
+	return err
+}
+
+// Entregas de produto.atualizado e, abaixo do mínimo, de estoque.baixo
+func enfileirarEntregasProduto(ctx context.Context, q querier, p Produto) error {
+	if err := enfileirarEntregasWebhook(ctx, q, EventoProdutoAtualizado, p); err != nil {
+		return err
+	}
+	if alerta, ok := alertaEstoqueBaixo(p); ok {
+		return enfileirarEntregasWebhook(ctx, q, EventoEstoqueBaixo, alerta)
+	}
+	return nil
+}
+
+// Acorda o worker para enviar entregas já confirmadas sem esperar o ciclo
+func sinalizarWebhooks() {
+	select {
+	case webhookSinal <- struct{}{}:
+	default:
+	}
+}
+
+// Tenta as entregas pendentes vencidas; devolve quantas foram processadas.
+// Entregas de webhooks desativados aguardam a reativação. As entregas ficam
+// travadas (SKIP LOCKED) até o resultado ser gravado, então várias réplicas
+// podem rodar o worker sem enviar a mesma entrega duas vezes.
+func processarEntregasWebhook(ctx context.Context) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT e.id, e.evento, e.payload, e.tentativas, w.id, w.url, w.secret
+		FROM webhook_entregas e
+		JOIN webhooks w ON e.webhook_id = w.id
+		WHERE e.status = $1 AND e.proxima_tentativa <= CURRENT_TIMESTAMP AND w.ativo
+		ORDER BY e.id
+		LIMIT 50
+		FOR UPDATE OF e SKIP LOCKED
+	`, EntregaPendente)
+	if err != nil {
+		return 0, err
+	}
+
+	type pendente struct {
+		id         int
+		evento     string
+		payload    []byte
+		tentativas int
+		webhook    Webhook
+	}
+	var pendentes []pendente
+	for rows.Next() {
+		var p pendente
+		err := rows.Scan(&p.id, &p.evento, &p.payload, &p.tentativas, &p.webhook.ID, &p.webhook.URL, &p.webhook.Secret)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pendentes = append(pendentes, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range pendentes {
+		status, err := enviarWebhook(ctx, p.webhook, p.evento, p.id, p.payload)
+		if err == nil && (status < 200 || status >= 300) {
+			err = fmt.Errorf("status HTTP %d", status)
+		}
+		if m := metricasWebhook(p.webhook.ID); m != nil {
+			m.Registrar(err)
+		}
+
+		var statusHTTP *int
+		if status > 0 {
+			statusHTTP = &status
+		}
+		tentativas := p.tentativas + 1
+
+		if err == nil {
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_entregas SET status = $1, tentativas = $2, ultimo_status_http = $3,
+					ultimo_erro = NULL, data_entrega = CURRENT_TIMESTAMP
+				WHERE id = $4
+			`, EntregaEntregue, tentativas, statusHTTP, p.id)
+		} else {
+			log.Printf("[WARN] Entrega %d do webhook %d falhou (tentativa %d): %v", p.id, p.webhook.ID, tentativas, err)
+			novoStatus := EntregaPendente
+			if tentativas >= webhookMaxTentativas {
+				novoStatus = EntregaFalhou
+			}
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_entregas SET status = $1, tentativas = $2, ultimo_status_http = $3,
+					ultimo_erro = $4, proxima_tentativa = CURRENT_TIMESTAMP + $5::float8 * interval '1 second'
+				WHERE id = $6
+			`, novoStatus, tentativas, statusHTTP, err.Error(), backoffWebhook(tentativas).Seconds(), p.id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("atualizar entrega %d: %w", p.id, err)
+		}
+	}
+	return len(pendentes), tx.Commit(ctx)
+}
+
+// Carrega os webhooks no painel e envia as entregas; roda até o contexto ser
+// cancelado
+func iniciarWebhooks(ctx context.Context) {
+	rows, err := db.Query(ctx, "SELECT id, url, eventos, ativo FROM webhooks")
+	if err != nil {
+		log.Printf("[ERROR] Erro ao carregar webhooks: %v", err)
+	} else {
+		for rows.Next() {
+			var w Webhook
+			if err := rows.Scan(&w.ID, &w.URL, &w.Eventos, &w.Ativo); err != nil {
+				log.Printf("[ERROR] Erro ao processar webhook: %v", err)
+				continue
+			}
+			registrarIntegracaoWebhook(w)
+		}
+		rows.Close()
+	}
+
+	// Envio das entregas pendentes, a cada ciclo ou quando há entregas novas
+	ciclo := time.NewTicker(5 * time.Second)
+	defer ciclo.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ciclo.C:
+		case <-webhookSinal:
+		}
+
+		for {
+			n, err := processarEntregasWebhook(ctx)
+			if err != nil {
+				log.Printf("[ERROR] Erro ao processar entregas de webhooks: %v", err)
+			}
+			if err != nil || n < 50 {
+				break
+			}
+		}
+	}
+}
+
+// Função auxiliar para validar URL e eventos de um webhook
+func validarWebhook(w Webhook) string {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL inválida, use http:// ou https://"
+	}
+	if len(w.Eventos) == 0 {
+		return "Informe ao menos um evento"
+	}
+	for _, e := range w.Eventos {
+		if !eventosWebhook[e] {
+			return "Evento desconhecido: " + e
+		}
+	}
+	return ""
+}
+
+// Handlers de Webhooks
+
+func getWebhooks(c *gin.Context) {
+	log.Println("[DB] Buscando webhooks")
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, url, eventos, ativo, data_criacao, data_atualizacao
+		FROM webhooks
+		ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar webhooks"})
+		return
+	}
+	defer rows.Close()
+
+	// O secret só é devolvido na criação
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var dataAtualizacao *time.Time
+		if err := rows.Scan(&w.ID, &w.URL, &w.Eventos, &w.Ativo, &w.DataCriacao, &dataAtualizacao); err != nil {
+			log.Printf("[ERROR] Erro ao processar webhook: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if dataAtualizacao != nil {
+			w.DataAtualizacao = *dataAtualizacao
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func criarWebhook(c *gin.Context) {
+	log.Println("[API] Iniciando criação de webhook")
+
+	w := Webhook{Ativo: true}
+	if err := c.ShouldBindJSON(&w); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarWebhook(w); msg != "" {
+		log.Printf("[ERROR] Webhook inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	if w.Secret == "" {
+		secret, err := gerarSecretWebhook()
+		if err != nil {
+			log.Printf("[ERROR] Erro ao gerar secret do webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao gerar secret do webhook"})
+			return
+		}
+		w.Secret = secret
+	}
+
+	err := db.QueryRow(c.Request.Context(), `
+		INSERT INTO webhooks(url, eventos, secret, ativo)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, data_criacao
+	`, w.URL, w.Eventos, w.Secret, w.Ativo).Scan(&w.ID, &w.DataCriacao)
+
+	if err != nil {
+		log.Printf("[ERROR] Erro ao criar webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao criar webhook"})
+		return
+	}
+
+	registrarIntegracaoWebhook(w)
+
+	log.Printf("[DB] Webhook criado (ID: %d, URL: %s)", w.ID, w.URL)
+	c.JSON(http.StatusCreated, w)
+}
+
+func atualizarWebhook(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	var w Webhook
+	if err := c.ShouldBindJSON(&w); err != nil {
+		log.Printf("[ERROR] Dados inválidos: %v", err)
+		c.JSON(http.StatusBadRequest, erroDadosInvalidos(err))
+		return
+	}
+	if msg := validarWebhook(w); msg != "" {
+		log.Printf("[ERROR] Webhook inválido: %s", msg)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: msg})
+		return
+	}
+
+	// Sem secret no corpo, o atual é mantido
+	err = db.QueryRow(c.Request.Context(), `
+		UPDATE webhooks SET url = $1, eventos = $2, secret = COALESCE(NULLIF($3, ''), secret), ativo = $4
+		WHERE id = $5
+		RETURNING id, data_criacao, data_atualizacao
+	`, w.URL, w.Eventos, w.Secret, w.Ativo, id).Scan(&w.ID, &w.DataCriacao, &w.DataAtualizacao)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Printf("[DB] Webhook não encontrado com ID: %d", id)
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Webhook não encontrado"})
+		} else {
+			log.Printf("[ERROR] Erro ao atualizar webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao atualizar webhook"})
+		}
+		return
+	}
+
+	registrarIntegracaoWebhook(w)
+
+	log.Printf("[DB] Webhook atualizado com sucesso! ID: %d", id)
+	w.Secret = ""
+	c.JSON(http.StatusOK, w)
+}
+
+func deletarWebhook(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao excluir webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao excluir webhook"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("[DB] Webhook não encontrado com ID: %d", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Webhook não encontrado"})
+		return
+	}
+
+	removerIntegracao(nomeIntegracaoWebhook(id))
+
+	log.Printf("[DB] Webhook excluído com sucesso! ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook excluído com sucesso"})
+}
+
+func getEntregasWebhook(c *gin.Context) {
+	// Obter ID da URL
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[ERROR] ID inválido: %s", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ID inválido"})
+		return
+	}
+
+	status := c.Query("status")
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "100"))
+	if err != nil || limite <= 0 {
+		limite = 100
+	}
+	limite = min(limite, 1000)
+
+	log.Printf("[DB] Buscando entregas do webhook ID: %d", id)
+
+	rows, err := db.Query(c.Request.Context(), `
+		SELECT id, webhook_id, evento, status, tentativas, ultimo_status_http, ultimo_erro,
+		       proxima_tentativa, data_criacao, data_entrega
+		FROM webhook_entregas
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`, id, status, limite)
+	if err != nil {
+		log.Printf("[ERROR] Erro ao buscar entregas do webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao buscar entregas do webhook"})
+		return
+	}
+	defer rows.Close()
+
+	entregas := []EntregaWebhook{}
+	for rows.Next() {
+		var e EntregaWebhook
+		var ultimoErro *string
+		err := rows.Scan(&e.ID, &e.WebhookID, &e.Evento, &e.Status, &e.Tentativas, &e.UltimoStatusHTTP,
+			&ultimoErro, &e.ProximaTentativa, &e.DataCriacao, &e.DataEntrega)
+		if err != nil {
+			log.Printf("[ERROR] Erro ao processar entrega do webhook: %v", err)
+			continue
+		}
+
+		// Tratar campos nulos
+		if ultimoErro != nil {
+			e.UltimoErro = *ultimoErro
+		}
+		// A próxima tentativa só interessa enquanto pendente
+		if e.Status != EntregaPendente {
+			e.ProximaTentativa = nil
+		}
+		entregas = append(entregas, e)
+	}
+
+	// Verificar erros durante a iteração
+	if err = rows.Err(); err != nil {
+		log.Printf("[ERROR] Erro ao processar entregas do webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Erro ao processar entregas do webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entregas)
+}
@@ -0,0 +1,474 @@
+// websocket.go - Canal WebSocket para atualização do dashboard em tempo real
+//
+// GET /ws abre uma conexão WebSocket (gorilla/websocket). O cliente escolhe os
+// tópicos em ?topicos= ou enviando {"acao": "inscrever", "topicos": [...]};
+// além dos eventos do barramento há o tópico "dashboard", que envia só os
+// contadores do dashboard que mudaram. Com ?depositos= (ou "depositos" no
+// comando) a conexão recebe apenas os eventos de produtos guardados em posições
+// desses depósitos, e os contadores somam só esses depósitos.
+//
+// Um hub único assina o barramento: resolve o depósito do produto e calcula os
+// contadores uma vez por evento, e repassa o resultado a todas as conexões.
+// Toda mensagem leva a sequência do evento: ao reconectar com ?desde=<seq> o
+// cliente recebe o que perdeu, dentro do histórico mantido pelo barramento.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Maior mensagem aceita do cliente (só comandos de inscrição)
+	wsTamanhoMaximo = 4096
+
+	TopicoDashboard = "dashboard"
+)
+
+type MensagemWebSocket struct {
+	Seq    int64     `json:"seq"`
+	Topico string    `json:"topico"`
+	Dados  any       `json:"dados"`
+	Data   time.Time `json:"data"`
+}
+
+type comandoWebSocket struct {
+	Acao      string   `json:"acao"`
+	Topicos   []string `json:"topicos"`
+	Depositos []string `json:"depositos,omitempty"`
+}
+
+// Contadores do tópico dashboard em um depósito
+type contadoresDeposito struct {
+	produtos int
+	itens    int
+	baixo    int
+}
+
+// Evento já preparado pelo hub para as conexões
+type eventoHub struct {
+	Evento
+	deposito   string                        // depósito do produto do evento; "" sem produto ou sem posição
+	contadores map[string]contadoresDeposito // por depósito, só nos eventos que alteram o estoque
+}
+
+var upgraderWebSocket = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// O navegador não aplica o CORS ao handshake: as origens aceitas são as mesmas
+	CheckOrigin: func(r *http.Request) bool { return origemPermitida(r.Header.Get("Origin")) },
+	Error: func(w http.ResponseWriter, r *http.Request, status int, motivo error) {
+		log.Printf("[ERROR] Requisição WebSocket recusada: %v", motivo)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Requisição WebSocket inválida"})
+	},
+}
+
+type conexaoWebSocket struct {
+	conn *websocket.Conn
+	fila chan eventoHub
+
+	// Sinaliza o envio do estado completo do dashboard (inscrição ou troca de depósitos)
+	recalcular chan struct{}
+
+	mu        sync.Mutex
+	topicos   map[string]bool
+	depositos map[string]bool
+	dashboard map[string]any
+	ultimaSeq int64
+}
+
+// Só a goroutine principal da conexão escreve mensagens; pings e close usam
+// WriteControl, que pode ser chamado em paralelo
+func (w *conexaoWebSocket) enviar(m MensagemWebSocket) error {
+	w.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return w.conn.WriteJSON(m)
+}
+
+func (w *conexaoWebSocket) inscrito(topico string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.topicos[topico]
+}
+
+// Indica se a conexão filtra por depósito
+func (w *conexaoWebSocket) filtraDeposito() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.depositos) > 0
+}
+
+func (w *conexaoWebSocket) doDeposito(deposito string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.depositos) == 0 || w.depositos[deposito]
+}
+
+func (w *conexaoWebSocket) aplicarComando(cmd comandoWebSocket) {
+	w.mu.Lock()
+	dashboardAntes := w.topicos[TopicoDashboard]
+	mudouDepositos := false
+	for _, t := range cmd.Topicos {
+		switch cmd.Acao {
+		case "inscrever":
+			w.topicos[strings.TrimSpace(t)] = true
+		case "cancelar":
+			delete(w.topicos, strings.TrimSpace(t))
+		}
+	}
+	for _, d := range cmd.Depositos {
+		d = strings.TrimSpace(d)
+		switch {
+		case d == "":
+		case cmd.Acao == "inscrever" && !w.depositos[d]:
+			w.depositos[d], mudouDepositos = true, true
+		case cmd.Acao == "cancelar" && w.depositos[d]:
+			delete(w.depositos, d)
+			mudouDepositos = true
+		}
+	}
+	recalcular := w.topicos[TopicoDashboard] && (!dashboardAntes || mudouDepositos)
+	if recalcular {
+		w.dashboard = map[string]any{}
+	}
+	w.mu.Unlock()
+
+	if recalcular {
+		select {
+		case w.recalcular <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Envia o evento (se inscrito e do depósito) e, nos eventos que alteram o
+// estoque, os contadores do dashboard que mudaram desde o último envio
+func (w *conexaoWebSocket) entregar(ev eventoHub) error {
+	// Ignorar eventos repetidos entre o histórico e a inscrição ao vivo
+	if ev.Seq <= w.ultimaSeq {
+		return nil
+	}
+	w.ultimaSeq = ev.Seq
+
+	if w.inscrito(ev.Tipo) && w.doDeposito(ev.deposito) {
+		if err := w.enviar(MensagemWebSocket{Seq: ev.Seq, Topico: ev.Tipo, Dados: ev.Dados, Data: ev.Data}); err != nil {
+			return err
+		}
+	}
+
+	if ev.contadores == nil || !w.inscrito(TopicoDashboard) {
+		return nil
+	}
+	delta := w.deltaDashboard(ev.contadores)
+	if len(delta) == 0 {
+		return nil
+	}
+	return w.enviar(MensagemWebSocket{Seq: ev.Seq, Topico: TopicoDashboard, Dados: delta, Data: ev.Data})
+}
+
+// Soma os contadores dos depósitos da conexão e devolve os que mudaram
+func (w *conexaoWebSocket) deltaDashboard(contadores map[string]contadoresDeposito) map[string]any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var soma contadoresDeposito
+	for deposito, c := range contadores {
+		if len(w.depositos) == 0 || w.depositos[deposito] {
+			soma.produtos += c.produtos
+			soma.itens += c.itens
+			soma.baixo += c.baixo
+		}
+	}
+	atual := map[string]any{"total_produtos": soma.produtos, "total_itens": soma.itens, "estoque_baixo": soma.baixo}
+
+	delta := map[string]any{}
+	for nome, valor := range atual {
+		if anterior, ok := w.dashboard[nome]; !ok || anterior != valor {
+			delta[nome] = valor
+			w.dashboard[nome] = valor
+		}
+	}
+	return delta
+}
+
+// Estado completo do dashboard, base para os deltas seguintes
+func (w *conexaoWebSocket) enviarDashboard(ctx context.Context) error {
+	contadores, err := contadoresDashboard(ctx)
+	if err != nil {
+		log.Printf("[WARN] Erro ao calcular contadores do dashboard: %v", err)
+		return nil
+	}
+	return w.enviar(MensagemWebSocket{Seq: eventos.sequencia(), Topico: TopicoDashboard, Dados: w.deltaDashboard(contadores), Data: time.Now()})
+}
+
+// Contadores do dashboard (como os widgets total_produtos, total_itens e
+// estoque_baixo) por depósito, numa única consulta
+func contadoresDashboard(ctx context.Context) (map[string]contadoresDeposito, error) {
+	rows, err := dbLeitura.Query(ctx, `
+		SELECT COALESCE(l.deposito, ''), COUNT(*), COALESCE(SUM(p.quantidade), 0),
+		       COUNT(*) FILTER (WHERE p.quantidade < COALESCE(p.quantidade_minima, 5) AND NOT p.kit)
+		FROM produtos p
+		LEFT JOIN localizacoes l ON l.id = p.localizacao_id
+		GROUP BY 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contadores := map[string]contadoresDeposito{}
+	for rows.Next() {
+		var deposito string
+		var c contadoresDeposito
+		if err := rows.Scan(&deposito, &c.produtos, &c.itens, &c.baixo); err != nil {
+			return nil, err
+		}
+		contadores[deposito] = c
+	}
+	return contadores, rows.Err()
+}
+
+// Produto a que o evento se refere (0 se nenhum)
+func produtoDoEvento(e Evento) int {
+	switch d := e.Dados.(type) {
+	case Movimentacao:
+		return d.ProdutoID
+	case Produto:
+		return d.ID
+	case AlertaEstoqueBaixo:
+		return d.ProdutoID
+	case Comentario:
+		if d.Entidade == "produto" {
+			return d.EntidadeID
+		}
+	}
+	return 0
+}
+
+// Depósito da posição do produto do evento
+func depositoEvento(ctx context.Context, e Evento) string {
+	id := produtoDoEvento(e)
+	if id == 0 {
+		return ""
+	}
+	var deposito string
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(l.deposito, '') FROM produtos p
+		LEFT JOIN localizacoes l ON l.id = p.localizacao_id
+		WHERE p.id = $1
+	`, id).Scan(&deposito)
+	if err != nil {
+		log.Printf("[WARN] Erro ao buscar depósito do produto %d: %v", id, err)
+	}
+	return deposito
+}
+
+// Hub das conexões WebSocket
+
+type hubWebSocket struct {
+	mu       sync.Mutex
+	conexoes map[*conexaoWebSocket]struct{}
+}
+
+var hubWs = &hubWebSocket{conexoes: map[*conexaoWebSocket]struct{}{}}
+
+func (h *hubWebSocket) registrar(w *conexaoWebSocket) {
+	h.mu.Lock()
+	h.conexoes[w] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *hubWebSocket) remover(w *conexaoWebSocket) {
+	h.mu.Lock()
+	delete(h.conexoes, w)
+	h.mu.Unlock()
+}
+
+// O que as conexões abertas precisam: depósito do evento e/ou contadores
+func (h *hubWebSocket) interesses() (deposito, dashboard bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.conexoes {
+		deposito = deposito || w.filtraDeposito()
+		dashboard = dashboard || w.inscrito(TopicoDashboard)
+	}
+	return deposito, dashboard
+}
+
+// Resolve o depósito e calcula os contadores uma vez para todas as conexões
+func (h *hubWebSocket) preparar(ctx context.Context, e Evento) eventoHub {
+	ev := eventoHub{Evento: e}
+	deposito, dashboard := h.interesses()
+	if deposito {
+		ev.deposito = depositoEvento(ctx, e)
+	}
+	if dashboard && (e.Tipo == EventoMovimentacaoCriada || e.Tipo == EventoProdutoAtualizado) {
+		contadores, err := contadoresDashboard(ctx)
+		if err != nil {
+			log.Printf("[WARN] Erro ao calcular contadores do dashboard: %v", err)
+		} else {
+			ev.contadores = contadores
+		}
+	}
+	return ev
+}
+
+// Entrega o evento às conexões sem bloquear; conexões lentas perdem eventos
+func (h *hubWebSocket) distribuir(ev eventoHub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.conexoes {
+		select {
+		case w.fila <- ev:
+		default:
+			log.Printf("[WARN] Cliente WebSocket lento, evento %s descartado", ev.Tipo)
+		}
+	}
+}
+
+// Assina o barramento e repassa os eventos às conexões; roda até o contexto ser cancelado
+func (h *hubWebSocket) executar(ctx context.Context) {
+	ch := eventos.inscrever()
+	defer eventos.cancelar(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-ch:
+			h.mu.Lock()
+			vazio := len(h.conexoes) == 0
+			h.mu.Unlock()
+			if !vazio {
+				h.distribuir(h.preparar(ctx, e))
+			}
+		}
+	}
+}
+
+// Handler para o canal WebSocket
+
+func getWebSocket(c *gin.Context) {
+	var desde int64
+	if s := c.Query("desde"); s != "" {
+		var err error
+		if desde, err = strconv.ParseInt(s, 10, 64); err != nil || desde < 0 {
+			log.Printf("[ERROR] Sequência inválida: %s", s)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Sequência inválida"})
+			return
+		}
+	}
+
+	conn, err := upgraderWebSocket.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// O upgrader já respondeu ao cliente
+		return
+	}
+	defer conn.Close()
+
+	w := &conexaoWebSocket{
+		conn:       conn,
+		fila:       make(chan eventoHub, max(streamBufferEventos, 1)),
+		recalcular: make(chan struct{}, 1),
+		topicos:    map[string]bool{},
+		depositos:  map[string]bool{},
+		dashboard:  map[string]any{},
+	}
+	w.aplicarComando(comandoWebSocket{
+		Acao:      "inscrever",
+		Topicos:   listaConfiguracao(c.Query("topicos")),
+		Depositos: listaConfiguracao(c.Query("depositos")),
+	})
+
+	hubWs.registrar(w)
+	defer hubWs.remover(w)
+
+	log.Printf("[API] Cliente conectado ao WebSocket: %s (desde seq %d)", c.ClientIP(), desde)
+
+	// Leitura: comandos de inscrição; pings do cliente são respondidos pela
+	// biblioteca e o close encerra a leitura. Sem pong a dois pings seguidos, a conexão cai.
+	intervalo := time.Duration(max(streamPingSegundos, 1)) * time.Second
+	conn.SetReadLimit(wsTamanhoMaximo)
+	conn.SetReadDeadline(time.Now().Add(2 * intervalo))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * intervalo))
+	})
+	encerrada := make(chan struct{})
+	go func() {
+		defer close(encerrada)
+		for {
+			tipo, payload, err := conn.ReadMessage()
+			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Printf("[WARN] WebSocket %s encerrado: %v", c.ClientIP(), err)
+				}
+				return
+			}
+			if tipo != websocket.TextMessage {
+				continue
+			}
+			var cmd comandoWebSocket
+			if err := json.Unmarshal(payload, &cmd); err != nil {
+				log.Printf("[WARN] Comando WebSocket inválido: %v", err)
+				continue
+			}
+			w.aplicarComando(cmd)
+		}
+	}()
+
+	ctx := c.Request.Context()
+
+	// Reenviar o que o cliente perdeu desde a última sequência recebida; o
+	// estado atual do dashboard vem em seguida, pelo sinal de recálculo
+	if desde > 0 {
+		w.ultimaSeq = desde
+		for _, e := range eventos.desde(desde) {
+			ev := eventoHub{Evento: e}
+			if w.filtraDeposito() {
+				ev.deposito = depositoEvento(ctx, e)
+			}
+			if err := w.entregar(ev); err != nil {
+				return
+			}
+		}
+	}
+
+	ping := time.NewTicker(intervalo)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-encerrada:
+			log.Printf("[API] Cliente desconectado do WebSocket: %s", c.ClientIP())
+			return
+		case <-encerrando:
+			// 1001 (going away): o cliente deve reconectar
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		case <-w.recalcular:
+			if err := w.enviarDashboard(ctx); err != nil {
+				return
+			}
+		case ev := <-w.fila:
+			if err := w.entregar(ev); err != nil {
+				log.Printf("[WARN] Erro ao enviar pelo WebSocket: %v", err)
+				return
+			}
+		}
+	}
+}